@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/database"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
+	"go.uber.org/zap"
+)
+
+var (
+	configPath    = flag.String("config", "config.yaml", "配置文件路径")
+	action        = flag.String("action", "", "操作: status, rotate-master, rotate-user, reencrypt")
+	username      = flag.String("username", "", "用户名")
+	allUsers      = flag.Bool("all", false, "对全部用户执行")
+	newMasterKey  = flag.String("new-master-key", "", "新主密钥（hex 或 base64）")
+	newMasterFile = flag.String("new-master-key-file", "", "新主密钥文件")
+	reencrypt     = flag.Bool("reencrypt", false, "rotate-user 后立即用新密钥重新加密文件")
+)
+
+func main() {
+	flag.Parse()
+
+	if *action == "" {
+		printUsage()
+		os.Exit(1)
+	}
+
+	// 加载配置
+	loader := config.NewLoader()
+	var cfg config.Config
+	if err := loader.LoadFromFile(*configPath, &cfg); err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.WebDAV.Encryption.Enabled {
+		log.Fatalf("Encryption is not enabled in config (webdav.encryption.enabled)")
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := db.Migrate(ctx); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	var userRepo user.Repository
+	var keyRepo repository.DataKeyRepository
+	var metadataRepo repository.FileMetadataRepository
+	switch d := db.(type) {
+	case *database.PostgresDB:
+		userRepo, err = repository.NewPostgresUserRepository(d)
+		keyRepo = repository.NewPostgresDataKeyRepository(d.DB)
+		metadataRepo = repository.NewPostgresFileMetadataRepository(d.DB)
+	case *database.SQLiteDB:
+		userRepo, err = repository.NewSQLiteUserRepository(d)
+		keyRepo = repository.NewSQLiteDataKeyRepository(d.DB)
+		metadataRepo = repository.NewSQLiteFileMetadataRepository(d.DB)
+	}
+	if err != nil {
+		log.Fatalf("Failed to create user repository: %v", err)
+	}
+	master, err := crypto.LoadMasterKey(cfg.WebDAV.Encryption.MasterKey, cfg.WebDAV.Encryption.MasterKeyFile)
+	if err != nil {
+		log.Fatalf("Failed to load master key: %v", err)
+	}
+
+	logger := zap.NewNop()
+	keyService := service.NewKeyService(keyRepo, master, logger)
+	contentHash := service.NewContentHashService(metadataRepo, logger)
+	var blobStore *webdavfs.BlobStore
+	if cfg.WebDAV.Dedup.Enabled {
+		if blobStore, err = webdavfs.NewBlobStore(cfg.WebDAV.Dedup.Directory, cfg.WebDAV.Directory); err != nil {
+			log.Fatalf("Failed to open blob store: %v", err)
+		}
+	}
+	storage := service.NewStorageService(contentHash, service.NewBlobService(blobStore, 0, logger), keyService)
+
+	switch *action {
+	case "status":
+		if err := showStatus(ctx, keyRepo, userRepo, keyService); err != nil {
+			log.Fatalf("Failed to show status: %v", err)
+		}
+
+	case "rotate-master":
+		if err := rotateMaster(ctx, keyService); err != nil {
+			log.Fatalf("Failed to rotate master key: %v", err)
+		}
+
+	case "rotate-user":
+		users, err := selectUsers(ctx, userRepo)
+		if err != nil {
+			log.Fatalf("Failed to select users: %v", err)
+		}
+		for _, u := range users {
+			version, err := keyService.RotateUserKey(ctx, u.ID)
+			if err != nil {
+				log.Fatalf("Failed to rotate data key for %s: %v", u.Username, err)
+			}
+			fmt.Printf("✓ %s: data key version %d is now active\n", u.Username, version)
+			if *reencrypt {
+				if err := reencryptUser(ctx, storage, &cfg, u); err != nil {
+					log.Fatalf("Failed to re-encrypt files for %s: %v", u.Username, err)
+				}
+			}
+		}
+
+	case "reencrypt":
+		users, err := selectUsers(ctx, userRepo)
+		if err != nil {
+			log.Fatalf("Failed to select users: %v", err)
+		}
+		for _, u := range users {
+			if err := reencryptUser(ctx, storage, &cfg, u); err != nil {
+				log.Fatalf("Failed to re-encrypt files for %s: %v", u.Username, err)
+			}
+		}
+
+	default:
+		log.Fatalf("Unknown action: %s", *action)
+	}
+}
+
+func printUsage() {
+	fmt.Println("Warehouse Encryption Key Tool")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  keys -action <action> [flags]")
+	fmt.Println()
+	fmt.Println("Actions:")
+	fmt.Println("  status          Show data key versions per user")
+	fmt.Println("  rotate-master   Re-wrap all data keys with a new master key (file content is unchanged)")
+	fmt.Println("  rotate-user     Create a new data key version for a user")
+	fmt.Println("  reencrypt       Re-encrypt files that are plaintext or use an old data key version")
+	fmt.Println()
+	fmt.Println("Flags:")
+	flag.PrintDefaults()
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  # Rotate the master key, then update webdav.encryption in config.yaml")
+	fmt.Println("  keys -action rotate-master -new-master-key-file /etc/warehouse/master.key.new")
+	fmt.Println()
+	fmt.Println("  # Rotate a user's data key and re-encrypt their files")
+	fmt.Println("  keys -action rotate-user -username alice -reencrypt")
+	fmt.Println()
+	fmt.Println("  # Encrypt files written before encryption was enabled")
+	fmt.Println("  keys -action reencrypt -all")
+}
+
+func showStatus(ctx context.Context, keyRepo repository.DataKeyRepository, userRepo user.Repository, keyService *service.KeyService) error {
+	keys, err := keyRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string)
+	if users, err := userRepo.List(ctx); err == nil {
+		for _, u := range users {
+			names[u.ID] = u.Username
+		}
+	}
+
+	fmt.Printf("Current master key: %s\n\n", keyService.MasterKeyID())
+	fmt.Printf("%-20s %-8s %-8s %-18s %-20s\n", "Username", "Version", "Active", "Master Key", "Created")
+	fmt.Println(strings.Repeat("-", 80))
+	for _, k := range keys {
+		name := names[k.UserID]
+		if name == "" {
+			name = k.UserID
+		}
+		active := ""
+		if k.Active {
+			active = "yes"
+		}
+		fmt.Printf("%-20s %-8d %-8s %-18s %-20s\n", name, k.Version, active, k.MasterKeyID, k.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+func rotateMaster(ctx context.Context, keyService *service.KeyService) error {
+	if *newMasterKey == "" && *newMasterFile == "" {
+		return fmt.Errorf("-new-master-key or -new-master-key-file is required")
+	}
+	next, err := crypto.LoadMasterKey(*newMasterKey, *newMasterFile)
+	if err != nil {
+		return err
+	}
+	count, err := keyService.RewrapAll(ctx, next)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Re-wrapped %d data keys with master key %s\n", count, crypto.MasterKeyID(next))
+	fmt.Println("  Update webdav.encryption.master_key (or master_key_file) before restarting the server.")
+	return nil
+}
+
+func selectUsers(ctx context.Context, repo user.Repository) ([]*user.User, error) {
+	if *allUsers {
+		return repo.List(ctx)
+	}
+	if *username == "" {
+		return nil, fmt.Errorf("-username or -all is required")
+	}
+	u, err := repo.FindByUsername(ctx, *username)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %s", *username)
+	}
+	return []*user.User{u}, nil
+}
+
+func reencryptUser(ctx context.Context, storage *service.StorageService, cfg *config.Config, u *user.User) error {
+	root := userRootDir(cfg, u)
+	fsys, err := storage.FileSystem(ctx, u, root)
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if webdavfs.IsIgnoredName(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		ok, err := fsys.Reencrypt(ctx, "/"+filepath.ToSlash(rel))
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+		if ok {
+			changed++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ %s: re-encrypted %d files\n", u.Username, changed)
+	return nil
+}
+
+func userRootDir(cfg *config.Config, u *user.User) string {
+	dir := u.Directory
+	if dir == "" {
+		dir = u.Username
+	}
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(cfg.WebDAV.Directory, dir)
+}
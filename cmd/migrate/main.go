@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/database"
+)
+
+var (
+	configPath = flag.String("config", "config.yaml", "配置文件路径")
+	action     = flag.String("action", "", "操作: up, down, status")
+	steps      = flag.Int("steps", 1, "down 回滚的迁移个数")
+	target     = flag.Int64("to", 0, "up 执行到的目标版本（0 表示最新）")
+)
+
+func main() {
+	flag.Parse()
+
+	if *action == "" {
+		printUsage()
+		os.Exit(1)
+	}
+
+	// 加载配置
+	loader := config.NewLoader()
+	var cfg config.Config
+	if err := loader.LoadFromFile(*configPath, &cfg); err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db.SQL(), db.Type())
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	switch *action {
+	case "up":
+		to := *target
+		if to == 0 {
+			to = migrator.Latest()
+		}
+		applied, err := migrator.UpTo(ctx, to)
+		for _, m := range applied {
+			fmt.Printf("✓ applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate up: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+
+	case "down":
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			fmt.Printf("✓ reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("Failed to migrate down: %v", err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No applied migrations to revert")
+		}
+
+	case "status":
+		if err := showStatus(ctx, migrator); err != nil {
+			log.Fatalf("Failed to show status: %v", err)
+		}
+
+	default:
+		log.Fatalf("Unknown action: %s", *action)
+	}
+}
+
+func printUsage() {
+	fmt.Println("Warehouse Database Migration Tool")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  migrate -action <action> [flags]")
+	fmt.Println()
+	fmt.Println("Actions:")
+	fmt.Println("  up       Apply pending migrations (up to -to when given)")
+	fmt.Println("  down     Revert the latest -steps applied migrations")
+	fmt.Println("  status   Show applied and pending migrations")
+	fmt.Println()
+	fmt.Println("Flags:")
+	flag.PrintDefaults()
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  # Apply all pending migrations")
+	fmt.Println("  migrate -action up")
+	fmt.Println()
+	fmt.Println("  # Revert the last migration")
+	fmt.Println("  migrate -action down -steps 1")
+}
+
+func showStatus(ctx context.Context, migrator *database.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Current version: %d (latest %d)\n\n", current, migrator.Latest())
+	fmt.Printf("%-8s %-32s %-8s %-20s\n", "Version", "Name", "Applied", "Applied At")
+	fmt.Println(strings.Repeat("-", 72))
+	for _, s := range statuses {
+		applied, appliedAt := "no", "-"
+		if s.Applied {
+			applied = "yes"
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%-8d %-32s %-8s %-20s\n", s.Version, s.Name, applied, appliedAt)
+	}
+	return nil
+}
@@ -9,16 +9,12 @@ import (
 
 	"github.com/spf13/pflag"
 	"github.com/yeying-community/warehouse/internal/container"
+	"github.com/yeying-community/warehouse/internal/infrastructure/buildinfo"
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/database"
 	"go.uber.org/zap"
 )
 
-var (
-	version   = "2.0.0"
-	buildTime = "unknown"
-	gitCommit = "unknown"
-)
-
 func main() {
 	// 解析命令行参数
 	flags := parseFlags()
@@ -85,7 +81,7 @@ func parseFlags() *pflag.FlagSet {
 	flags.StringP("directory", "d", "", "WebDAV directory")
 
 	// 数据库选项
-	flags.String("db-type", "", "Database type (postgres)")
+	flags.String("db-type", "", "Database type (postgres, sqlite)")
 	flags.String("db-host", "", "Database host")
 	flags.Int("db-port", 0, "Database port")
 	flags.String("db-name", "", "Database name")
@@ -138,18 +134,24 @@ func validateConfig(cfg *config.Config) error {
 	}
 
 	// 验证数据库配置
-	if !(cfg.Database.Type == "postgres" || cfg.Database.Type == "postgresql") {
-		return fmt.Errorf("unsupported database type: %s (only postgres/postgresql supported)", cfg.Database.Type)
-	}
-	if cfg.Database.Host == "" {
-		return fmt.Errorf("database host not specified")
-	}
-	if cfg.Database.Database == "" {
-		return fmt.Errorf("database name not specified")
-	}
+	switch database.NormalizeType(cfg.Database.Type) {
+	case database.TypePostgres:
+		if cfg.Database.Host == "" {
+			return fmt.Errorf("database host not specified")
+		}
+		if cfg.Database.Database == "" {
+			return fmt.Errorf("database name not specified")
+		}
 
-	if cfg.Database.Username == "" {
-		return fmt.Errorf("database username not specified")
+		if cfg.Database.Username == "" {
+			return fmt.Errorf("database username not specified")
+		}
+	case database.TypeSQLite:
+		if cfg.Database.Path == "" {
+			return fmt.Errorf("database path not specified")
+		}
+	default:
+		return fmt.Errorf("unsupported database type: %s (postgres/postgresql or sqlite supported)", cfg.Database.Type)
 	}
 
 	// 验证 Web3 配置
@@ -166,9 +168,9 @@ func validateConfig(cfg *config.Config) error {
 // printVersion 打印版本信息
 func printVersion() {
 	fmt.Printf("Warehouse Server with Web3 Authentication\n")
-	fmt.Printf("Version:    %s\n", version)
-	fmt.Printf("Build Time: %s\n", buildTime)
-	fmt.Printf("Git Commit: %s\n", gitCommit)
+	fmt.Printf("Version:    %s\n", buildinfo.Version)
+	fmt.Printf("Build Time: %s\n", buildinfo.BuildTime)
+	fmt.Printf("Git Commit: %s\n", buildinfo.Commit)
 }
 
 // printHelp 打印帮助信息
@@ -204,9 +206,9 @@ func printStartupInfo(c *container.Container) {
 	c.Logger.Info("Warehouse Server Starting")
 	c.Logger.Info("=================================")
 	c.Logger.Info("version",
-		zap.String("version", version),
-		zap.String("build_time", buildTime),
-		zap.String("git_commit", gitCommit))
+		zap.String("version", buildinfo.Version),
+		zap.String("build_time", buildinfo.BuildTime),
+		zap.String("git_commit", buildinfo.Commit))
 	c.Logger.Info("=================================")
 
 	// 服务器信息
@@ -8,22 +8,25 @@ import (
 	"os"
 	"strings"
 
+	"github.com/yeying-community/warehouse/internal/application/service"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
 	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
 	"github.com/yeying-community/warehouse/internal/infrastructure/database"
 	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"go.uber.org/zap"
 )
 
 var (
 	configPath = flag.String("config", "config.yaml", "配置文件路径")
-	action     = flag.String("action", "", "操作: add, update, delete, list, reset-password")
+	action     = flag.String("action", "", "操作: add, update, delete, list, reset-password, roles, assign-role, revoke-role")
 	username   = flag.String("username", "", "用户名")
 	password   = flag.String("password", "", "密码")
-	wallet     = flag.String("wallet", "", "钱包地址")
+	wallet     = flag.String("wallet", "", "钱包地址（以太坊地址或 CAIP-10 账户，如 solana:<chain>:<address>）")
 	directory  = flag.String("directory", "", "用户目录")
 	perms      = flag.String("permissions", "R", "权限 (C=Create, R=Read, U=Update, D=Delete)")
 	quota      = flag.Int64("quota", -1, "配额 (字节)，-1 使用默认值")
+	role       = flag.String("role", "", "管理角色（superadmin, user-manager, auditor, support 或自定义角色）")
 )
 
 func main() {
@@ -44,10 +47,11 @@ func main() {
 
 	// 创建用户仓储
 	var userRepo user.Repository
-	var db *database.PostgresDB
+	var roleRepo repository.RoleRepository
 
-	if cfg.Database.Type == "postgres" || cfg.Database.Type == "postgresql" {
-		db, err = database.NewPostgresDB(cfg.Database)
+	switch database.NormalizeType(cfg.Database.Type) {
+	case database.TypePostgres:
+		db, err := database.NewPostgresDB(cfg.Database)
 		if err != nil {
 			log.Fatalf("Failed to connect to database: %v", err)
 		}
@@ -57,8 +61,21 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to create user repository: %v", err)
 		}
-	} else {
-		log.Fatalf("User management tool only supports PostgreSQL")
+		roleRepo = repository.NewPostgresRoleRepository(db.DB)
+	case database.TypeSQLite:
+		db, err := database.NewSQLiteDB(cfg.Database)
+		if err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+
+		userRepo, err = repository.NewSQLiteUserRepository(db)
+		if err != nil {
+			log.Fatalf("Failed to create user repository: %v", err)
+		}
+		roleRepo = repository.NewSQLiteRoleRepository(db.DB)
+	default:
+		log.Fatalf("Unsupported database type: %s", cfg.Database.Type)
 	}
 
 	ctx := context.Background()
@@ -94,6 +111,23 @@ func main() {
 		}
 		fmt.Println("✓ Password reset successfully!")
 
+	case "roles":
+		if err := listRoles(ctx, newRBACService(ctx, roleRepo, cfg), userRepo); err != nil {
+			log.Fatalf("Failed to list roles: %v", err)
+		}
+
+	case "assign-role":
+		if err := assignRole(ctx, newRBACService(ctx, roleRepo, cfg), userRepo); err != nil {
+			log.Fatalf("Failed to assign role: %v", err)
+		}
+		fmt.Println("✓ Role assigned successfully!")
+
+	case "revoke-role":
+		if err := revokeRole(ctx, newRBACService(ctx, roleRepo, cfg), userRepo); err != nil {
+			log.Fatalf("Failed to revoke role: %v", err)
+		}
+		fmt.Println("✓ Role revoked successfully!")
+
 	default:
 		log.Fatalf("Unknown action: %s", *action)
 	}
@@ -111,6 +145,9 @@ func printUsage() {
 	fmt.Println("  delete           Delete a user")
 	fmt.Println("  list             List all users")
 	fmt.Println("  reset-password   Reset user password")
+	fmt.Println("  roles            List admin roles and their members")
+	fmt.Println("  assign-role      Grant an admin role to a user")
+	fmt.Println("  revoke-role      Remove an admin role from a user")
 	fmt.Println()
 	fmt.Println("Flags:")
 	flag.PrintDefaults()
@@ -120,7 +157,10 @@ func printUsage() {
 	fmt.Println("  user -action add -username alice -password secret123 -directory alice -permissions CRUD -quota 5368709120")
 	fmt.Println()
 	fmt.Println("  # Add a Web3 user")
-	fmt.Println("  user -action add -username bob -wallet 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb -directory bob -permissions CRUD")
+	fmt.Println("  user -action add -username bob -wallet 0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb0 -directory bob -permissions CRUD")
+	fmt.Println()
+	fmt.Println("  # Add a Solana user (CAIP-10 account ID)")
+	fmt.Println("  user -action add -username carol -wallet solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp:7S3P4HxJpyyigGzodYwHtCxZyUQe9JiBMHyRWXArAaKv -directory carol -permissions CRUD")
 	fmt.Println()
 	fmt.Println("  # Update user permissions")
 	fmt.Println("  user -action update -username alice -permissions RU")
@@ -133,6 +173,9 @@ func printUsage() {
 	fmt.Println()
 	fmt.Println("  # Delete a user")
 	fmt.Println("  user -action delete -username alice")
+	fmt.Println()
+	fmt.Println("  # Make a user an admin")
+	fmt.Println("  user -action assign-role -username alice -role superadmin")
 }
 
 func addUser(ctx context.Context, repo user.Repository) error {
@@ -164,7 +207,9 @@ func addUser(ctx context.Context, repo user.Repository) error {
 
 	// 设置钱包地址
 	if *wallet != "" {
-		u.SetWalletAddress(strings.ToLower(*wallet))
+		if err := u.SetWalletAddress(*wallet); err != nil {
+			return fmt.Errorf("invalid wallet address: %w", err)
+		}
 	}
 
 	// 设置权限
@@ -213,7 +258,9 @@ func updateUser(ctx context.Context, repo user.Repository) error {
 
 	// 更新钱包地址
 	if *wallet != "" {
-		u.SetWalletAddress(strings.ToLower(*wallet))
+		if err := u.SetWalletAddress(*wallet); err != nil {
+			return fmt.Errorf("invalid wallet address: %w", err)
+		}
 	}
 
 	// 保存更新
@@ -319,6 +366,77 @@ func resetPassword(ctx context.Context, repo user.Repository) error {
 	return nil
 }
 
+// newRBACService 创建管理角色服务，并确保内置角色已写入数据库
+func newRBACService(ctx context.Context, roleRepo repository.RoleRepository, cfg config.Config) *service.RBACService {
+	rbacService := service.NewRBACService(roleRepo, cfg.Security, zap.NewNop())
+	if err := rbacService.EnsureBuiltinRoles(ctx); err != nil {
+		log.Fatalf("Failed to ensure built-in roles: %v", err)
+	}
+	return rbacService
+}
+
+func listRoles(ctx context.Context, rbacService *service.RBACService, repo user.Repository) error {
+	roles, err := rbacService.ListRoles(ctx)
+	if err != nil {
+		return err
+	}
+	assignments, err := rbacService.ListAssignments(ctx)
+	if err != nil {
+		return err
+	}
+
+	members := make(map[string][]string)
+	for _, a := range assignments {
+		name := a.UserID
+		if u, err := repo.FindByID(ctx, a.UserID); err == nil {
+			name = u.Username
+		}
+		members[a.Role] = append(members[a.Role], name)
+	}
+
+	fmt.Printf("%-16s %-50s %s\n", "Role", "Permissions", "Members")
+	fmt.Println(strings.Repeat("-", 120))
+	for _, r := range roles {
+		perms := make([]string, 0, len(r.Permissions))
+		for _, p := range r.Permissions {
+			perms = append(perms, string(p))
+		}
+		assigned := "-"
+		if len(members[r.Name]) > 0 {
+			assigned = strings.Join(members[r.Name], ", ")
+		}
+		fmt.Printf("%-16s %-50s %s\n", r.Name, strings.Join(perms, ","), assigned)
+	}
+	return nil
+}
+
+func assignRole(ctx context.Context, rbacService *service.RBACService, repo user.Repository) error {
+	u, err := roleTarget(ctx, repo)
+	if err != nil {
+		return err
+	}
+	return rbacService.Assign(ctx, nil, u, strings.ToLower(strings.TrimSpace(*role)))
+}
+
+func revokeRole(ctx context.Context, rbacService *service.RBACService, repo user.Repository) error {
+	u, err := roleTarget(ctx, repo)
+	if err != nil {
+		return err
+	}
+	return rbacService.Revoke(ctx, nil, u, strings.ToLower(strings.TrimSpace(*role)))
+}
+
+func roleTarget(ctx context.Context, repo user.Repository) (*user.User, error) {
+	if *username == "" || *role == "" {
+		return nil, fmt.Errorf("username and role are required")
+	}
+	u, err := repo.FindByUsername(ctx, *username)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %s", *username)
+	}
+	return u, nil
+}
+
 func formatBytes(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
 	"go.uber.org/zap"
@@ -21,9 +22,10 @@ const (
 
 // Space 资产空间元信息
 type Space struct {
-	Key  string `json:"key"`
-	Name string `json:"name"`
-	Path string `json:"path"`
+	Key       string               `json:"key"`
+	Name      string               `json:"name"`
+	Path      string               `json:"path"`
+	Condition *tokengate.Condition `json:"condition,omitempty"` // 链上访问条件（由代币门槛配置填充）
 }
 
 // Manager 管理用户资产空间目录（personal/apps）
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/ratelimit"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// 事务邮件模板名称，对应 <template_dir>/<name>_mail_template_<locale>.html
+const (
+	MailTemplateEmailCodeLogin = "email_code_login"
+	MailTemplatePasswordReset  = "password_reset"
+	MailTemplateEmailVerify    = "email_verify"
+)
+
+// minPasswordLength 与修改密码接口的要求一致
+const minPasswordLength = 6
+
+// MailSender 发送模板邮件
+type MailSender interface {
+	SendTemplate(to, name, locale string, data map[string]any) error
+}
+
+// MailTokenIssuer 签发与验证邮件链接令牌
+type MailTokenIssuer interface {
+	GenerateMailToken(purpose, userID, email string, expiration time.Duration) (*auth.Token, error)
+	VerifyMailToken(purpose, tokenString string) (*auth.MailToken, error)
+}
+
+// AccountMailService 通过邮件链接重置密码与验证邮箱
+// 链接中的令牌是签名的 JWT，使用后记录到 used_tokens，保证只能使用一次。
+type AccountMailService struct {
+	userRepo    user.Repository
+	tokenRepo   repository.UsedTokenRepository
+	sessionRepo repository.SessionRepository
+	sender      MailSender
+	tokens      MailTokenIssuer
+	hasher      *crypto.PasswordHasher
+	lockout     *ratelimit.Lockout
+	config      config.EmailConfig
+	logger      *zap.Logger
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+// NewAccountMailService 创建邮件链接服务
+func NewAccountMailService(
+	userRepo user.Repository,
+	tokenRepo repository.UsedTokenRepository,
+	sessionRepo repository.SessionRepository,
+	sender MailSender,
+	tokens MailTokenIssuer,
+	cfg config.EmailConfig,
+	logger *zap.Logger,
+) *AccountMailService {
+	return &AccountMailService{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		sessionRepo: sessionRepo,
+		sender:      sender,
+		tokens:      tokens,
+		hasher:      crypto.NewPasswordHasher(),
+		config:      cfg,
+		logger:      logger,
+		lastSent:    make(map[string]time.Time),
+	}
+}
+
+// SetLockout 设置账户锁定器，重置密码后解除锁定
+func (s *AccountMailService) SetLockout(lockout *ratelimit.Lockout) {
+	s.lockout = lockout
+}
+
+// RequestPasswordReset 向邮箱发送重置密码链接
+// 邮箱不属于任何用户、请求过于频繁或发送失败时同样返回 nil，避免借此探测邮箱是否注册。
+func (s *AccountMailService) RequestPasswordReset(ctx context.Context, emailAddr, locale string) error {
+	emailAddr = strings.ToLower(strings.TrimSpace(emailAddr))
+	if !user.IsValidEmail(emailAddr) {
+		return user.ErrInvalidEmail
+	}
+	if !s.allowSend(auth.MailTokenPasswordReset, emailAddr) {
+		logger.Ctx(ctx, s.logger).Debug("password reset requested too frequently", zap.String("email", emailAddr))
+		return nil
+	}
+
+	u, err := s.userRepo.FindByEmail(ctx, emailAddr)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.send(u, auth.MailTokenPasswordReset, emailAddr, locale); err != nil {
+		logger.Ctx(ctx, s.logger).Error("failed to send password reset mail",
+			zap.String("username", u.Username),
+			zap.Error(err))
+		return nil
+	}
+	logger.Ctx(ctx, s.logger).Info("password reset mail sent", zap.String("username", u.Username))
+	return nil
+}
+
+// ResetPassword 使用重置链接设置新密码，成功后吊销该用户的全部会话
+func (s *AccountMailService) ResetPassword(ctx context.Context, tokenString, newPassword string) (*user.User, error) {
+	newPassword = strings.TrimSpace(newPassword)
+	if len(newPassword) < minPasswordLength {
+		return nil, user.ErrInvalidPassword
+	}
+
+	token, err := s.tokens.VerifyMailToken(auth.MailTokenPasswordReset, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	// 邮箱在签发后已解除关联或转给其他用户时，链接失效
+	u, err := s.userRepo.FindByEmail(ctx, token.Email)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, auth.ErrInvalidToken
+		}
+		return nil, err
+	}
+	if u.ID != token.UserID {
+		return nil, auth.ErrInvalidToken
+	}
+
+	hashed, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokenRepo.Consume(ctx, token); err != nil {
+		return nil, err
+	}
+
+	u.SetPassword(hashed)
+	// 能打开发到主邮箱的链接，同时证明了邮箱归属
+	if u.Email == token.Email && !u.IsEmailVerified() {
+		u.MarkEmailVerified()
+	}
+	if err := s.userRepo.Save(ctx, u); err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.sessionRepo.RevokeByUser(ctx, u.ID, "", session.ReasonPasswordReset)
+	if err != nil {
+		logger.Ctx(ctx, s.logger).Error("failed to revoke sessions after password reset",
+			zap.String("username", u.Username),
+			zap.Error(err))
+	}
+	s.lockout.Unlock(u.ID)
+
+	logger.Ctx(ctx, s.logger).Info("password reset via mail link",
+		zap.String("username", u.Username),
+		zap.Int64("revoked_sessions", revoked))
+	return u, nil
+}
+
+// RequestEmailVerification 向用户当前邮箱发送验证链接；已验证时不发送
+func (s *AccountMailService) RequestEmailVerification(ctx context.Context, u *user.User, locale string) error {
+	if u.Email == "" {
+		return user.ErrInvalidEmail
+	}
+	if u.IsEmailVerified() {
+		return nil
+	}
+	return s.requestVerification(ctx, u, u.Email, locale)
+}
+
+// RequestEmailChange 向新邮箱发送验证链接，用户打开链接后才更换邮箱
+func (s *AccountMailService) RequestEmailChange(ctx context.Context, u *user.User, newEmail, locale string) error {
+	newEmail = strings.ToLower(strings.TrimSpace(newEmail))
+	if !user.IsValidEmail(newEmail) {
+		return user.ErrInvalidEmail
+	}
+	if newEmail == u.Email {
+		return s.RequestEmailVerification(ctx, u, locale)
+	}
+	if err := s.checkEmailAvailable(ctx, u, newEmail); err != nil {
+		return err
+	}
+	return s.requestVerification(ctx, u, newEmail, locale)
+}
+
+// ConfirmEmail 使用验证链接确认邮箱；令牌中的邮箱与当前邮箱不同时更换为该邮箱
+func (s *AccountMailService) ConfirmEmail(ctx context.Context, tokenString string) (*user.User, error) {
+	token, err := s.tokens.VerifyMailToken(auth.MailTokenEmailVerify, tokenString)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.userRepo.FindByID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, auth.ErrInvalidToken
+		}
+		return nil, err
+	}
+	if token.Email != u.Email {
+		if err := s.checkEmailAvailable(ctx, u, token.Email); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.tokenRepo.Consume(ctx, token); err != nil {
+		return nil, err
+	}
+
+	if err := u.SetEmail(token.Email); err != nil {
+		return nil, err
+	}
+	u.MarkEmailVerified()
+	if err := s.userRepo.Save(ctx, u); err != nil {
+		return nil, err
+	}
+
+	logger.Ctx(ctx, s.logger).Info("email verified via mail link",
+		zap.String("username", u.Username),
+		zap.String("email", u.Email))
+	return u, nil
+}
+
+func (s *AccountMailService) requestVerification(ctx context.Context, u *user.User, emailAddr, locale string) error {
+	if !s.allowSend(auth.MailTokenEmailVerify, emailAddr) {
+		return auth.ErrMailTooFrequent
+	}
+	if err := s.send(u, auth.MailTokenEmailVerify, emailAddr, locale); err != nil {
+		s.forgetSend(auth.MailTokenEmailVerify, emailAddr)
+		return err
+	}
+	logger.Ctx(ctx, s.logger).Info("email verification mail sent",
+		zap.String("username", u.Username),
+		zap.String("email", emailAddr))
+	return nil
+}
+
+// checkEmailAvailable 邮箱已属于其他用户时返回 ErrDuplicateEmail
+func (s *AccountMailService) checkEmailAvailable(ctx context.Context, u *user.User, emailAddr string) error {
+	owner, err := s.userRepo.FindByEmail(ctx, emailAddr)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if owner.ID != u.ID {
+		return user.ErrDuplicateEmail
+	}
+	return nil
+}
+
+// send 签发令牌并发送对应用途的邮件
+func (s *AccountMailService) send(u *user.User, purpose, emailAddr, locale string) error {
+	ttl, page, template := s.config.ResetTokenTTL, "/reset-password", MailTemplatePasswordReset
+	if purpose == auth.MailTokenEmailVerify {
+		ttl, page, template = s.config.VerifyTokenTTL, "/verify-email", MailTemplateEmailVerify
+	}
+
+	token, err := s.tokens.GenerateMailToken(purpose, u.ID, emailAddr, ttl)
+	if err != nil {
+		return err
+	}
+	link := strings.TrimRight(s.config.LinkBaseURL, "/") + page + "?token=" + url.QueryEscape(token.Value)
+	return s.sender.SendTemplate(emailAddr, template, locale, map[string]any{
+		"username":  u.Username,
+		"email":     emailAddr,
+		"link":      link,
+		"expiresIn": int(ttl.Minutes()),
+	})
+}
+
+// allowSend 按 send_interval 限制同一邮箱同一用途的发送频率
+func (s *AccountMailService) allowSend(purpose, emailAddr string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, at := range s.lastSent {
+		if now.Sub(at) >= s.config.SendInterval {
+			delete(s.lastSent, key)
+		}
+	}
+	key := purpose + ":" + emailAddr
+	if _, ok := s.lastSent[key]; ok {
+		return false
+	}
+	s.lastSent[key] = now
+	return true
+}
+
+func (s *AccountMailService) forgetSend(purpose, emailAddr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.lastSent, purpose+":"+emailAddr)
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	infraAuth "github.com/yeying-community/warehouse/internal/infrastructure/auth"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository/memory"
+	"go.uber.org/zap"
+)
+
+type sentMail struct {
+	to, template string
+	data         map[string]any
+}
+
+type recordingSender struct {
+	sent []sentMail
+}
+
+func (s *recordingSender) SendTemplate(to, name, locale string, data map[string]any) error {
+	s.sent = append(s.sent, sentMail{to: to, template: name, data: data})
+	return nil
+}
+
+// lastToken 取出最近一封邮件链接中的令牌
+func (s *recordingSender) lastToken(t *testing.T) string {
+	t.Helper()
+	if len(s.sent) == 0 {
+		t.Fatalf("no mail sent")
+	}
+	link, err := url.Parse(s.sent[len(s.sent)-1].data["link"].(string))
+	if err != nil {
+		t.Fatalf("invalid link: %v", err)
+	}
+	return link.Query().Get("token")
+}
+
+func newTestAccountMailService(t *testing.T) (*AccountMailService, *memory.Store, *recordingSender) {
+	t.Helper()
+	store := memory.NewStore()
+	sender := &recordingSender{}
+	s := NewAccountMailService(
+		memory.NewUserRepository(store),
+		memory.NewUsedTokenRepository(store),
+		memory.NewSessionRepository(store),
+		sender,
+		infraAuth.NewJWTManager("account-mail-test-secret-0123456789abcdef", time.Hour),
+		config.EmailConfig{
+			LinkBaseURL:    "https://warehouse.example.com",
+			ResetTokenTTL:  30 * time.Minute,
+			VerifyTokenTTL: 24 * time.Hour,
+			SendInterval:   time.Minute,
+		},
+		zap.NewNop(),
+	)
+	return s, store, sender
+}
+
+func TestAccountMailPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	s, store, sender := newTestAccountMailService(t)
+	users := memory.NewUserRepository(store)
+	sessions := memory.NewSessionRepository(store)
+
+	u := user.NewUser("alice", "alice")
+	if err := u.SetEmail("alice@example.com"); err != nil {
+		t.Fatalf("SetEmail: %v", err)
+	}
+	if err := users.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	active := session.New(u.ID, "password", "alice", session.Client{}, time.Now().Add(time.Hour))
+	if err := sessions.Create(ctx, active); err != nil {
+		t.Fatalf("Create session: %v", err)
+	}
+
+	// 未注册的邮箱同样返回成功，但不发送邮件
+	if err := s.RequestPasswordReset(ctx, "nobody@example.com", ""); err != nil || len(sender.sent) != 0 {
+		t.Fatalf("unknown email: err=%v sent=%d", err, len(sender.sent))
+	}
+	if err := s.RequestPasswordReset(ctx, "Alice@Example.com", "en-US"); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].template != MailTemplatePasswordReset || sender.sent[0].to != "alice@example.com" {
+		t.Fatalf("unexpected mails: %+v", sender.sent)
+	}
+	// send_interval 内重复请求不再发送
+	if err := s.RequestPasswordReset(ctx, "alice@example.com", ""); err != nil || len(sender.sent) != 1 {
+		t.Fatalf("throttled request: err=%v sent=%d", err, len(sender.sent))
+	}
+	token := sender.lastToken(t)
+
+	if _, err := s.ResetPassword(ctx, token, "short"); !errors.Is(err, user.ErrInvalidPassword) {
+		t.Fatalf("expected ErrInvalidPassword, got %v", err)
+	}
+	if _, err := s.ResetPassword(ctx, token, "new-secret-pass"); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if _, err := s.ResetPassword(ctx, token, "another-pass"); !errors.Is(err, auth.ErrTokenUsed) {
+		t.Fatalf("expected ErrTokenUsed on reuse, got %v", err)
+	}
+
+	got, _ := users.FindByID(ctx, u.ID)
+	if err := crypto.NewPasswordHasher().Verify(got.Password, "new-secret-pass"); err != nil {
+		t.Fatalf("password not updated: %v", err)
+	}
+	if !got.IsEmailVerified() {
+		t.Fatalf("reset link should verify the email")
+	}
+	if list, _ := sessions.ListActiveByUser(ctx, u.ID); len(list) != 0 {
+		t.Fatalf("sessions should be revoked after reset, got %d", len(list))
+	}
+
+	// 验证邮箱的令牌不能用来重置密码
+	if err := s.RequestEmailChange(ctx, got, "alice@example.org", ""); err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+	if _, err := s.ResetPassword(ctx, sender.lastToken(t), "new-secret-pass"); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for wrong purpose, got %v", err)
+	}
+}
+
+func TestAccountMailEmailChange(t *testing.T) {
+	ctx := context.Background()
+	s, store, sender := newTestAccountMailService(t)
+	users := memory.NewUserRepository(store)
+
+	alice := user.NewUser("alice", "alice")
+	if err := alice.SetEmail("alice@example.com"); err != nil {
+		t.Fatalf("SetEmail: %v", err)
+	}
+	bob := user.NewUser("bob", "bob")
+	if err := bob.SetEmail("bob@example.com"); err != nil {
+		t.Fatalf("SetEmail: %v", err)
+	}
+	for _, u := range []*user.User{alice, bob} {
+		if err := users.Save(ctx, u); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	if err := s.RequestEmailChange(ctx, alice, "bob@example.com", ""); !errors.Is(err, user.ErrDuplicateEmail) {
+		t.Fatalf("expected ErrDuplicateEmail, got %v", err)
+	}
+	if err := s.RequestEmailChange(ctx, alice, "alice@example.org", "zh-CN"); err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+	if err := s.RequestEmailChange(ctx, alice, "alice@example.org", ""); !errors.Is(err, auth.ErrMailTooFrequent) {
+		t.Fatalf("expected ErrMailTooFrequent, got %v", err)
+	}
+	mail := sender.sent[len(sender.sent)-1]
+	if mail.to != "alice@example.org" || mail.template != MailTemplateEmailVerify {
+		t.Fatalf("verification should go to the new address: %+v", mail)
+	}
+
+	// 确认前邮箱不变
+	if got, _ := users.FindByID(ctx, alice.ID); got.Email != "alice@example.com" {
+		t.Fatalf("email changed before confirmation: %s", got.Email)
+	}
+	got, err := s.ConfirmEmail(ctx, sender.lastToken(t))
+	if err != nil {
+		t.Fatalf("ConfirmEmail: %v", err)
+	}
+	if got.Email != "alice@example.org" || !got.IsEmailVerified() {
+		t.Fatalf("unexpected user after confirm: %+v", got)
+	}
+	if found, err := users.FindByEmail(ctx, "alice@example.org"); err != nil || found.ID != alice.ID {
+		t.Fatalf("FindByEmail new address: %v", err)
+	}
+	if _, err := users.FindByEmail(ctx, "alice@example.com"); !errors.Is(err, user.ErrUserNotFound) {
+		t.Fatalf("old address should be released, got %v", err)
+	}
+}
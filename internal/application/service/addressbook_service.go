@@ -74,7 +74,7 @@ func (s *AddressBookService) UpdateContact(ctx context.Context, u *user.User, id
 		contact.Name = strings.TrimSpace(name)
 	}
 	if strings.TrimSpace(wallet) != "" {
-		contact.WalletAddress = strings.ToLower(strings.TrimSpace(wallet))
+		contact.WalletAddress = addressbook.NormalizeWallet(wallet)
 	}
 	if groupID != "" {
 		if _, err := s.repo.GetGroupByID(ctx, u.ID, groupID); err != nil {
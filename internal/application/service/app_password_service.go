@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yeying-community/warehouse/internal/domain/apppassword"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// AppPasswordService 应用专用密码服务
+type AppPasswordService struct {
+	repo   repository.AppPasswordRepository
+	logger *zap.Logger
+}
+
+// NewAppPasswordService 创建应用专用密码服务
+func NewAppPasswordService(repo repository.AppPasswordRepository, logger *zap.Logger) *AppPasswordService {
+	return &AppPasswordService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// List 获取用户的应用专用密码
+func (s *AppPasswordService) List(ctx context.Context, u *user.User) ([]*apppassword.AppPassword, error) {
+	return s.repo.ListByUser(ctx, u.ID)
+}
+
+// Create 创建应用专用密码，返回记录与明文密码（仅此一次）
+func (s *AppPasswordService) Create(ctx context.Context, u *user.User, name string, readOnly bool, paths []string) (*apppassword.AppPassword, string, error) {
+	existing, err := s.repo.ListByUser(ctx, u.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(existing) >= apppassword.MaxPerUser {
+		return nil, "", apppassword.ErrTooManyAppPasswords
+	}
+
+	p, secret, err := apppassword.New(u.ID, name, readOnly, paths)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.repo.Create(ctx, p); err != nil {
+		return nil, "", err
+	}
+	logger.Ctx(ctx, s.logger).Info("app password created",
+		zap.String("username", u.Username),
+		zap.String("app_password_id", p.ID),
+		zap.String("name", p.Name),
+		zap.Bool("read_only", p.ReadOnly),
+		zap.Strings("paths", p.Paths))
+	return p, secret, nil
+}
+
+// Revoke 吊销应用专用密码
+func (s *AppPasswordService) Revoke(ctx context.Context, u *user.User, id string) error {
+	if err := s.repo.Delete(ctx, u.ID, id); err != nil {
+		return err
+	}
+	logger.Ctx(ctx, s.logger).Info("app password revoked",
+		zap.String("username", u.Username),
+		zap.String("app_password_id", id))
+	return nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/audit"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// auditCleanupInterval 过期审计日志清理间隔
+const auditCleanupInterval = time.Hour
+
+// AuditService 审计日志服务
+type AuditService struct {
+	repo   repository.AuditRepository
+	config config.AuditConfig
+	logger *zap.Logger
+
+	worker   workerState
+	stopOnce sync.Once
+	started  bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewAuditService 创建审计日志服务
+func NewAuditService(repo repository.AuditRepository, cfg config.AuditConfig, logger *zap.Logger) *AuditService {
+	return &AuditService{
+		repo:   repo,
+		config: cfg,
+		logger: logger,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Enabled 是否启用审计日志
+func (s *AuditService) Enabled() bool {
+	return s != nil && s.config.Enabled
+}
+
+// Record 写入一条审计事件；写入失败只记录日志，不影响业务请求
+func (s *AuditService) Record(ctx context.Context, e *audit.Event) {
+	if !s.Enabled() || e == nil {
+		return
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	if e.Outcome == "" {
+		e.Outcome = audit.OutcomeFromStatus(e.Status)
+	}
+	if err := s.repo.Append(ctx, e); err != nil {
+		logger.Ctx(ctx, s.logger).Error("failed to record audit event",
+			zap.String("action", e.Action),
+			zap.String("actor", e.Actor),
+			zap.Error(err))
+	}
+}
+
+// Query 分页查询审计事件
+func (s *AuditService) Query(ctx context.Context, filter audit.Filter) ([]*audit.Event, int, error) {
+	filter.Normalize()
+	return s.repo.Query(ctx, filter)
+}
+
+// Export 按时间顺序逐条导出符合条件的审计事件
+func (s *AuditService) Export(ctx context.Context, filter audit.Filter, fn func(*audit.Event) error) error {
+	return s.repo.Iterate(ctx, filter, fn)
+}
+
+// Cleanup 删除超过保留期的审计事件；保留期为 0 时永久保留
+func (s *AuditService) Cleanup(ctx context.Context) (int64, error) {
+	if s.config.Retention <= 0 {
+		return 0, nil
+	}
+	deleted, err := s.repo.DeleteBefore(ctx, time.Now().Add(-s.config.Retention))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		logger.Ctx(ctx, s.logger).Info("expired audit events cleaned up", zap.Int64("deleted", deleted))
+	}
+	return deleted, nil
+}
+
+// Start 启动后台保留期清理
+func (s *AuditService) Start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(auditCleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				_, err := s.Cleanup(context.Background())
+				if err != nil {
+					s.logger.Error("audit cleanup failed", zap.Error(err))
+				}
+				s.worker.record(err)
+			}
+		}
+	}()
+}
+
+// Status 返回后台清理任务状态
+func (s *AuditService) Status() WorkerStatus {
+	return s.worker.status("audit_cleanup", s.Enabled(), s.started, s.done)
+}
+
+// Stop 停止后台清理
+func (s *AuditService) Stop() {
+	if s == nil || !s.started {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
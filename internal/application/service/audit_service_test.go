@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/audit"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"go.uber.org/zap"
+)
+
+func TestAuditServiceRecordAndCleanup(t *testing.T) {
+	ctx := context.Background()
+	repo := &stubAuditRepo{}
+	s := NewAuditService(repo, config.AuditConfig{Enabled: true, Retention: 24 * time.Hour}, zap.NewNop())
+
+	s.Record(ctx, &audit.Event{Action: "share.create", Actor: "alice", Status: 200})
+	s.Record(ctx, &audit.Event{Action: "auth.password_login", Actor: "bob", Status: 401})
+	s.Record(ctx, &audit.Event{Action: "webdav.delete", CreatedAt: time.Now().Add(-48 * time.Hour), Status: 204})
+	if len(repo.events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(repo.events))
+	}
+	if repo.events[0].Outcome != audit.OutcomeSuccess || repo.events[1].Outcome != audit.OutcomeDenied {
+		t.Fatalf("unexpected outcomes: %s, %s", repo.events[0].Outcome, repo.events[1].Outcome)
+	}
+
+	deleted, err := s.Cleanup(ctx)
+	if err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+	if deleted != 1 || len(repo.events) != 2 {
+		t.Fatalf("expected 1 expired event removed, deleted=%d remaining=%d", deleted, len(repo.events))
+	}
+
+	disabled := NewAuditService(repo, config.AuditConfig{}, zap.NewNop())
+	disabled.Record(ctx, &audit.Event{Action: "share.create"})
+	if len(repo.events) != 2 {
+		t.Fatalf("disabled audit service should not record events")
+	}
+}
+
+type stubAuditRepo struct {
+	events []*audit.Event
+}
+
+func (r *stubAuditRepo) Append(ctx context.Context, e *audit.Event) error {
+	e.ID = int64(len(r.events) + 1)
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *stubAuditRepo) Query(ctx context.Context, filter audit.Filter) ([]*audit.Event, int, error) {
+	return r.events, len(r.events), nil
+}
+
+func (r *stubAuditRepo) Iterate(ctx context.Context, filter audit.Filter, fn func(*audit.Event) error) error {
+	for _, e := range r.events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *stubAuditRepo) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	var kept []*audit.Event
+	for _, e := range r.events {
+		if !e.CreatedAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	deleted := int64(len(r.events) - len(kept))
+	r.events = kept
+	return deleted, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
+	"go.uber.org/zap"
+)
+
+// BlobService 去重 blob 存储服务（垃圾回收）
+type BlobService struct {
+	store      *webdavfs.BlobStore
+	gcInterval time.Duration
+	logger     *zap.Logger
+
+	worker   workerState
+	stopOnce sync.Once
+	started  bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewBlobService 创建 blob 存储服务，store 为 nil 表示未启用去重
+func NewBlobService(store *webdavfs.BlobStore, gcInterval time.Duration, logger *zap.Logger) *BlobService {
+	return &BlobService{
+		store:      store,
+		gcInterval: gcInterval,
+		logger:     logger,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Enabled 是否启用去重存储
+func (s *BlobService) Enabled() bool {
+	return s != nil && s.store != nil
+}
+
+// Store 返回底层 blob 存储，未启用时返回 nil
+func (s *BlobService) Store() *webdavfs.BlobStore {
+	if !s.Enabled() {
+		return nil
+	}
+	return s.store
+}
+
+// CollectGarbage 删除无引用的 blob
+func (s *BlobService) CollectGarbage(ctx context.Context) (*webdavfs.BlobGCResult, error) {
+	if !s.Enabled() {
+		return &webdavfs.BlobGCResult{}, nil
+	}
+	result, err := s.store.GC(ctx)
+	if err != nil {
+		return result, err
+	}
+	logger.Ctx(ctx, s.logger).Info("blob garbage collection finished",
+		zap.Int("scanned", result.Scanned),
+		zap.Int("removed", result.Removed),
+		zap.Int64("freed", result.Freed))
+	return result, nil
+}
+
+// Start 启动后台垃圾回收
+func (s *BlobService) Start() {
+	if !s.Enabled() || s.gcInterval <= 0 || s.started {
+		return
+	}
+	s.started = true
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.gcInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				_, err := s.CollectGarbage(context.Background())
+				if err != nil {
+					s.logger.Error("blob garbage collection failed", zap.Error(err))
+				}
+				s.worker.record(err)
+			}
+		}
+	}()
+}
+
+// Status 返回后台垃圾回收任务状态
+func (s *BlobService) Status() WorkerStatus {
+	return s.worker.status("blob_gc", s.Enabled() && s.gcInterval > 0, s.started, s.done)
+}
+
+// Stop 停止后台垃圾回收
+func (s *BlobService) Stop() {
+	if s == nil || !s.started {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yeying-community/warehouse/internal/domain/filemeta"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
+	"go.uber.org/zap"
+)
+
+// ContentHashService 文件内容哈希服务
+type ContentHashService struct {
+	repo   repository.FileMetadataRepository
+	logger *zap.Logger
+}
+
+// NewContentHashService 创建文件内容哈希服务
+func NewContentHashService(repo repository.FileMetadataRepository, logger *zap.Logger) *ContentHashService {
+	return &ContentHashService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Lookup 获取与磁盘文件一致的元数据，记录不存在或已过期时返回 ErrFileMetadataNotFound
+func (s *ContentHashService) Lookup(ctx context.Context, userID, relPath string, info os.FileInfo) (*filemeta.FileMetadata, error) {
+	if s == nil || s.repo == nil || info == nil || info.IsDir() {
+		return nil, filemeta.ErrFileMetadataNotFound
+	}
+	meta, err := s.repo.Get(ctx, userID, normalizeMetaPath(relPath))
+	if err != nil {
+		return nil, err
+	}
+	if !meta.Matches(info.Size(), info.ModTime()) {
+		return nil, filemeta.ErrFileMetadataNotFound
+	}
+	return meta, nil
+}
+
+// Ensure 获取元数据，缺失或过期时通过文件系统读取明文内容计算并保存
+// relPath 同时是文件在 fsys 中的路径与元数据路径。
+func (s *ContentHashService) Ensure(ctx context.Context, userID, relPath string, fsys *webdavfs.UnicodeFileSystem) (*filemeta.FileMetadata, error) {
+	if s == nil || s.repo == nil {
+		return nil, filemeta.ErrFileMetadataNotFound
+	}
+	name := filepath.FromSlash(strings.TrimPrefix(normalizeMetaPath(relPath), "/"))
+	info, err := os.Stat(fsys.FullPath(name))
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, filemeta.ErrFileMetadataNotFound
+	}
+	meta, err := s.Lookup(ctx, userID, relPath, info)
+	if err == nil {
+		return meta, nil
+	}
+	if !errors.Is(err, filemeta.ErrFileMetadataNotFound) {
+		return nil, err
+	}
+
+	sum, info, err := fsys.ContentHash(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	meta, err = filemeta.NewFileMetadata(userID, normalizeMetaPath(relPath), sum, info.Size(), info.ModTime())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Upsert(ctx, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// Record 记录写入完成后的内容哈希
+func (s *ContentHashService) Record(ctx context.Context, userID, relPath, sum string, info os.FileInfo) error {
+	if s == nil || s.repo == nil || info == nil || info.IsDir() {
+		return nil
+	}
+	meta, err := filemeta.NewFileMetadata(userID, normalizeMetaPath(relPath), sum, info.Size(), info.ModTime())
+	if err != nil {
+		return err
+	}
+	return s.repo.Upsert(ctx, meta)
+}
+
+// Move 同步移动路径（含子路径）的元数据
+func (s *ContentHashService) Move(ctx context.Context, userID, oldPath, newPath string) error {
+	if s == nil || s.repo == nil {
+		return nil
+	}
+	return s.repo.Move(ctx, userID, normalizeMetaPath(oldPath), normalizeMetaPath(newPath))
+}
+
+// Remove 删除路径（含子路径）的元数据
+func (s *ContentHashService) Remove(ctx context.Context, userID, relPath string) error {
+	if s == nil || s.repo == nil {
+		return nil
+	}
+	return s.repo.Delete(ctx, userID, normalizeMetaPath(relPath))
+}
+
+// Index 返回指定用户的 WebDAV 内容哈希索引
+func (s *ContentHashService) Index(userID string) webdavfs.ContentHashIndex {
+	if s == nil || s.repo == nil {
+		return nil
+	}
+	return &userContentHashIndex{service: s, userID: userID}
+}
+
+// normalizeMetaPath 规范化元数据路径（以 / 开头，使用 / 分隔）
+func normalizeMetaPath(relPath string) string {
+	relPath = strings.TrimSpace(filepath.ToSlash(relPath))
+	return path.Clean("/" + strings.TrimLeft(relPath, "/"))
+}
+
+// userContentHashIndex 绑定用户的内容哈希索引
+type userContentHashIndex struct {
+	service *ContentHashService
+	userID  string
+}
+
+func (i *userContentHashIndex) Lookup(ctx context.Context, name string, info os.FileInfo) string {
+	meta, err := i.service.Lookup(ctx, i.userID, name, info)
+	if err != nil {
+		if !errors.Is(err, filemeta.ErrFileMetadataNotFound) {
+			logger.Ctx(ctx, i.service.logger).Warn("failed to lookup content hash",
+				zap.String("user_id", i.userID),
+				zap.String("path", name),
+				zap.Error(err))
+		}
+		return ""
+	}
+	return meta.SHA256
+}
+
+func (i *userContentHashIndex) Record(ctx context.Context, name string, sum string, info os.FileInfo) {
+	if err := i.service.Record(ctx, i.userID, name, sum, info); err != nil {
+		logger.Ctx(ctx, i.service.logger).Warn("failed to record content hash",
+			zap.String("user_id", i.userID),
+			zap.String("path", name),
+			zap.Error(err))
+	}
+}
+
+func (i *userContentHashIndex) Rename(ctx context.Context, oldName, newName string) {
+	if err := i.service.Move(ctx, i.userID, oldName, newName); err != nil {
+		logger.Ctx(ctx, i.service.logger).Warn("failed to move content hash",
+			zap.String("user_id", i.userID),
+			zap.String("from", oldName),
+			zap.String("to", newName),
+			zap.Error(err))
+	}
+}
+
+func (i *userContentHashIndex) Remove(ctx context.Context, name string) {
+	if err := i.service.Remove(ctx, i.userID, name); err != nil {
+		logger.Ctx(ctx, i.service.logger).Warn("failed to remove content hash",
+			zap.String("user_id", i.userID),
+			zap.String("path", name),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/account"
+	"github.com/yeying-community/warehouse/internal/domain/e2ee"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// E2EEService 端到端加密目录服务
+// 目录密钥由客户端生成，并以各接收方钱包公钥加密为密钥信封；服务端只保存密文和信封。
+type E2EEService struct {
+	repo   repository.E2EERepository
+	config *config.Config
+	logger *zap.Logger
+}
+
+// NewE2EEService 创建端到端加密目录服务
+func NewE2EEService(
+	repo repository.E2EERepository,
+	cfg *config.Config,
+	logger *zap.Logger,
+) *E2EEService {
+	return &E2EEService{
+		repo:   repo,
+		config: cfg,
+		logger: logger,
+	}
+}
+
+// RecordPublicKey 记录由登录签名恢复的钱包公钥
+func (s *E2EEService) RecordPublicKey(ctx context.Context, wallet string, publicKey []byte) error {
+	address, err := crypto.PublicKeyToAddress(publicKey)
+	if err != nil {
+		return err
+	}
+	id, err := account.Parse(wallet)
+	if err != nil || !id.IsEVM() || !strings.EqualFold(address, id.Address) {
+		return fmt.Errorf("public key does not match wallet %s", wallet)
+	}
+	return s.repo.SavePublicKey(ctx, &e2ee.WalletPublicKey{
+		WalletAddress: normalizeE2EEWallet(wallet),
+		PublicKey:     publicKey,
+		UpdatedAt:     time.Now(),
+	})
+}
+
+// PublicKey 获取钱包公钥（钱包至少登录过一次才有记录）
+func (s *E2EEService) PublicKey(ctx context.Context, wallet string) (*e2ee.WalletPublicKey, error) {
+	return s.repo.GetPublicKey(ctx, normalizeE2EEWallet(wallet))
+}
+
+// CreateFolder 将空目录（或新目录）登记为端到端加密目录，并保存拥有者的密钥信封
+func (s *E2EEService) CreateFolder(ctx context.Context, owner *user.User, rawPath, ownerEnvelope string) (*e2ee.Folder, error) {
+	if strings.TrimSpace(owner.WalletAddress) == "" {
+		return nil, fmt.Errorf("a wallet address is required to own an e2ee folder")
+	}
+	cleanPath, err := normalizeSharePath(rawPath, s.webdavPrefix())
+	if err != nil {
+		return nil, err
+	}
+	if err := enforceAppScope(ctx, s.config, cleanPath, "create"); err != nil {
+		return nil, err
+	}
+
+	folders, err := s.repo.ListFolders(ctx, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+	candidate := &e2ee.Folder{Path: cleanPath}
+	for _, f := range folders {
+		if f.Path == cleanPath {
+			return nil, e2ee.ErrFolderExists
+		}
+		if f.Contains(cleanPath) || candidate.Contains(f.Path) {
+			return nil, e2ee.ErrNestedEncryptedPath
+		}
+	}
+
+	// 已有明文内容的目录不能直接转为端到端加密，避免误以为其中内容已加密
+	fullPath := filepath.Join(s.getUserRootDir(owner), filepath.FromSlash(strings.TrimPrefix(cleanPath, "/")))
+	entries, err := os.ReadDir(fullPath)
+	switch {
+	case err == nil:
+		if len(entries) > 0 {
+			return nil, e2ee.ErrFolderNotEmpty
+		}
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(fullPath, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create folder: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to read folder: %w", err)
+	}
+
+	folder := e2ee.NewFolder(owner.ID, cleanPath)
+	envelope, err := e2ee.NewKeyEnvelope(folder.ID, owner.WalletAddress, ownerEnvelope, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateFolder(ctx, folder, envelope); err != nil {
+		return nil, err
+	}
+
+	logger.Ctx(ctx, s.logger).Info("e2ee folder created",
+		zap.String("owner", owner.Username),
+		zap.String("path", cleanPath),
+		zap.String("folder_id", folder.ID))
+	return folder, nil
+}
+
+// ListFolders 获取用户的端到端加密目录
+func (s *E2EEService) ListFolders(ctx context.Context, owner *user.User) ([]*e2ee.Folder, error) {
+	return s.repo.ListFolders(ctx, owner.ID)
+}
+
+// DeleteFolder 取消目录的端到端加密登记（已上传的密文保持不变）
+func (s *E2EEService) DeleteFolder(ctx context.Context, owner *user.User, id string) error {
+	if _, err := s.ownedFolder(ctx, owner, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteFolder(ctx, id)
+}
+
+// Envelope 获取当前用户在目录中的密钥信封
+func (s *E2EEService) Envelope(ctx context.Context, u *user.User, folderID string) (*e2ee.KeyEnvelope, error) {
+	if strings.TrimSpace(u.WalletAddress) == "" {
+		return nil, e2ee.ErrEnvelopeNotFound
+	}
+	return s.repo.GetEnvelope(ctx, folderID, normalizeE2EEWallet(u.WalletAddress))
+}
+
+// ListEnvelopes 获取目录的全部密钥信封（仅拥有者）
+func (s *E2EEService) ListEnvelopes(ctx context.Context, owner *user.User, folderID string) ([]*e2ee.KeyEnvelope, error) {
+	if _, err := s.ownedFolder(ctx, owner, folderID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListEnvelopes(ctx, folderID)
+}
+
+// PutEnvelope 为接收方钱包写入密钥信封（仅拥有者）
+func (s *E2EEService) PutEnvelope(ctx context.Context, owner *user.User, folderID, wallet, envelope string) (*e2ee.KeyEnvelope, error) {
+	if _, err := s.ownedFolder(ctx, owner, folderID); err != nil {
+		return nil, err
+	}
+	return s.putEnvelope(ctx, owner, folderID, wallet, envelope)
+}
+
+// RemoveEnvelope 删除接收方的密钥信封（仅拥有者，不能删除自己的信封）
+// 删除信封不会使接收方已获取的目录密钥失效，需要时应由客户端轮换目录密钥。
+func (s *E2EEService) RemoveEnvelope(ctx context.Context, owner *user.User, folderID, wallet string) error {
+	if _, err := s.ownedFolder(ctx, owner, folderID); err != nil {
+		return err
+	}
+	if normalizeE2EEWallet(wallet) == owner.WalletAddress {
+		return fmt.Errorf("cannot remove the owner's key envelope")
+	}
+	return s.repo.DeleteEnvelope(ctx, folderID, normalizeE2EEWallet(wallet))
+}
+
+// FolderFor 返回包含该路径的端到端加密目录，不在加密目录内时返回 nil
+func (s *E2EEService) FolderFor(ctx context.Context, ownerUserID, relPath string) (*e2ee.Folder, error) {
+	if s == nil {
+		return nil, nil
+	}
+	folders, err := s.repo.ListFolders(ctx, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range folders {
+		if f.Contains(relPath) {
+			return f, nil
+		}
+	}
+	return nil, nil
+}
+
+// EnsurePlaintextAllowed 路径位于或包含端到端加密目录时拒绝依赖明文的操作（预览、搜索、公开链接等）
+func (s *E2EEService) EnsurePlaintextAllowed(ctx context.Context, ownerUserID, relPath string) error {
+	if s == nil {
+		return nil
+	}
+	folders, err := s.repo.ListFolders(ctx, ownerUserID)
+	if err != nil {
+		return err
+	}
+	scope := &e2ee.Folder{Path: cleanE2EEPath(relPath)}
+	for _, f := range folders {
+		if f.Contains(relPath) || scope.Contains(f.Path) {
+			return e2ee.ErrPlaintextOperation
+		}
+	}
+	return nil
+}
+
+// CheckWebDAV 校验 WebDAV 请求与端到端加密目录的兼容性，并返回请求路径所在的加密目录
+// SEARCH 依赖明文内容，加密目录内不可用；加密目录只能整体移动（不能复制或移入另一个加密目录）。
+// 单个条目只能在同一个加密目录内复制或移动：移入会留下客户端无法解密的明文，移出或跨目录会留下无法解密的密文。
+func (s *E2EEService) CheckWebDAV(ctx context.Context, ownerUserID, method, relPath, destPath string) (*e2ee.Folder, error) {
+	if s == nil {
+		return nil, nil
+	}
+	folders, err := s.repo.ListFolders(ctx, ownerUserID)
+	if err != nil {
+		return nil, err
+	}
+	source := &e2ee.Folder{Path: cleanE2EEPath(relPath)}
+	var current *e2ee.Folder
+	containsRoot := false
+	for _, f := range folders {
+		if f.Contains(relPath) {
+			current = f
+		}
+		if source.Contains(f.Path) {
+			containsRoot = true
+		}
+	}
+
+	switch strings.ToUpper(method) {
+	case "SEARCH":
+		if current != nil || containsRoot {
+			return nil, e2ee.ErrPlaintextOperation
+		}
+	case "COPY":
+		if containsRoot {
+			return nil, e2ee.ErrFolderCopy
+		}
+		if err := checkE2EETransfer(folders, current, destPath); err != nil {
+			return nil, err
+		}
+	case "MOVE":
+		if containsRoot && destPath != "" {
+			for _, f := range folders {
+				if !source.Contains(f.Path) && f.Contains(destPath) {
+					return nil, e2ee.ErrNestedEncryptedPath
+				}
+			}
+		}
+		if !containsRoot {
+			if err := checkE2EETransfer(folders, current, destPath); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return current, nil
+}
+
+// checkE2EETransfer 单个条目的复制或移动：源与目标必须同在一个加密目录内，或都不在加密目录内
+func checkE2EETransfer(folders []*e2ee.Folder, current *e2ee.Folder, destPath string) error {
+	if destPath == "" {
+		return nil
+	}
+	var target *e2ee.Folder
+	for _, f := range folders {
+		if f.Contains(destPath) {
+			target = f
+		}
+	}
+	if current == nil && target == nil {
+		return nil
+	}
+	if current == nil || target == nil || current.ID != target.ID {
+		return e2ee.ErrCrossFolderTransfer
+	}
+	return nil
+}
+
+// MoveFolders 路径被移动后同步更新其中的端到端加密目录
+func (s *E2EEService) MoveFolders(ctx context.Context, ownerUserID, oldPath, newPath string) error {
+	if s == nil {
+		return nil
+	}
+	folders, err := s.repo.ListFolders(ctx, ownerUserID)
+	if err != nil {
+		return err
+	}
+	oldPath, newPath = cleanE2EEPath(oldPath), cleanE2EEPath(newPath)
+	scope := &e2ee.Folder{Path: oldPath}
+	for _, f := range folders {
+		if !scope.Contains(f.Path) {
+			continue
+		}
+		moved := newPath + strings.TrimPrefix(f.Path, oldPath)
+		if err := s.repo.UpdateFolderPath(ctx, f.ID, moved); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveFolders 路径被删除后移除其中的端到端加密目录登记
+func (s *E2EEService) RemoveFolders(ctx context.Context, ownerUserID, relPath string) error {
+	if s == nil {
+		return nil
+	}
+	folders, err := s.repo.ListFolders(ctx, ownerUserID)
+	if err != nil {
+		return err
+	}
+	scope := &e2ee.Folder{Path: cleanE2EEPath(relPath)}
+	for _, f := range folders {
+		if scope.Contains(f.Path) {
+			if err := s.repo.DeleteFolder(ctx, f.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PrepareShare 为定向分享准备目标钱包的密钥信封
+// 路径不在加密目录内时返回 nil；提供 envelope 时写入（或替换）目标的信封，否则要求目标已有信封。
+func (s *E2EEService) PrepareShare(ctx context.Context, owner *user.User, sharePath, targetWallet, envelope string) (*e2ee.Folder, *e2ee.KeyEnvelope, error) {
+	if s == nil {
+		if strings.TrimSpace(envelope) != "" {
+			return nil, nil, fmt.Errorf("e2ee is not available")
+		}
+		return nil, nil, nil
+	}
+	folder, err := s.FolderFor(ctx, owner.ID, sharePath)
+	if err != nil || folder == nil {
+		return nil, nil, err
+	}
+	if strings.TrimSpace(envelope) != "" {
+		env, err := s.putEnvelope(ctx, owner, folder.ID, targetWallet, envelope)
+		return folder, env, err
+	}
+	env, err := s.repo.GetEnvelope(ctx, folder.ID, normalizeE2EEWallet(targetWallet))
+	if errors.Is(err, e2ee.ErrEnvelopeNotFound) {
+		return nil, nil, e2ee.ErrEnvelopeRequired
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return folder, env, nil
+}
+
+// ShareEnvelope 查询分享路径所在的加密目录及接收方钱包的密钥信封
+func (s *E2EEService) ShareEnvelope(ctx context.Context, ownerUserID, sharePath, wallet string) (*e2ee.Folder, *e2ee.KeyEnvelope, error) {
+	folder, err := s.FolderFor(ctx, ownerUserID, sharePath)
+	if err != nil || folder == nil {
+		return nil, nil, err
+	}
+	env, err := s.repo.GetEnvelope(ctx, folder.ID, normalizeE2EEWallet(wallet))
+	if errors.Is(err, e2ee.ErrEnvelopeNotFound) {
+		return folder, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return folder, env, nil
+}
+
+func (s *E2EEService) putEnvelope(ctx context.Context, owner *user.User, folderID, wallet, envelope string) (*e2ee.KeyEnvelope, error) {
+	if !crypto.NewEthereumSigner().IsValidAddress(strings.TrimSpace(wallet)) {
+		return nil, fmt.Errorf("invalid wallet address")
+	}
+	env, err := e2ee.NewKeyEnvelope(folderID, wallet, envelope, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.PutEnvelope(ctx, env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+func (s *E2EEService) ownedFolder(ctx context.Context, owner *user.User, id string) (*e2ee.Folder, error) {
+	folder, err := s.repo.GetFolder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if folder.OwnerUserID != owner.ID {
+		return nil, e2ee.ErrFolderNotFound
+	}
+	return folder, nil
+}
+
+func (s *E2EEService) webdavPrefix() string {
+	if s == nil || s.config == nil {
+		return ""
+	}
+	return s.config.WebDAV.Prefix
+}
+
+func (s *E2EEService) getUserRootDir(u *user.User) string {
+	userDir := u.Directory
+	if userDir == "" {
+		userDir = u.Username
+	}
+	if filepath.IsAbs(userDir) {
+		return userDir
+	}
+	return filepath.Join(s.config.WebDAV.Directory, userDir)
+}
+
+func normalizeE2EEWallet(wallet string) string {
+	return e2ee.NormalizeWallet(wallet)
+}
+
+func cleanE2EEPath(p string) string {
+	return path.Clean("/" + strings.TrimPrefix(p, "/"))
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/yeying-community/warehouse/internal/domain/e2ee"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"go.uber.org/zap"
+)
+
+type memoryE2EERepo struct {
+	keys      map[string]*e2ee.WalletPublicKey
+	folders   map[string]*e2ee.Folder
+	envelopes map[string]*e2ee.KeyEnvelope
+}
+
+func newMemoryE2EERepo() *memoryE2EERepo {
+	return &memoryE2EERepo{
+		keys:      make(map[string]*e2ee.WalletPublicKey),
+		folders:   make(map[string]*e2ee.Folder),
+		envelopes: make(map[string]*e2ee.KeyEnvelope),
+	}
+}
+
+func (m *memoryE2EERepo) SavePublicKey(ctx context.Context, key *e2ee.WalletPublicKey) error {
+	m.keys[key.WalletAddress] = key
+	return nil
+}
+
+func (m *memoryE2EERepo) GetPublicKey(ctx context.Context, wallet string) (*e2ee.WalletPublicKey, error) {
+	if key, ok := m.keys[wallet]; ok {
+		return key, nil
+	}
+	return nil, e2ee.ErrPublicKeyNotFound
+}
+
+func (m *memoryE2EERepo) CreateFolder(ctx context.Context, folder *e2ee.Folder, ownerEnvelope *e2ee.KeyEnvelope) error {
+	m.folders[folder.ID] = folder
+	if ownerEnvelope != nil {
+		return m.PutEnvelope(ctx, ownerEnvelope)
+	}
+	return nil
+}
+
+func (m *memoryE2EERepo) GetFolder(ctx context.Context, id string) (*e2ee.Folder, error) {
+	if folder, ok := m.folders[id]; ok {
+		return folder, nil
+	}
+	return nil, e2ee.ErrFolderNotFound
+}
+
+func (m *memoryE2EERepo) ListFolders(ctx context.Context, ownerUserID string) ([]*e2ee.Folder, error) {
+	var folders []*e2ee.Folder
+	for _, f := range m.folders {
+		if f.OwnerUserID == ownerUserID {
+			folders = append(folders, f)
+		}
+	}
+	return folders, nil
+}
+
+func (m *memoryE2EERepo) UpdateFolderPath(ctx context.Context, id, newPath string) error {
+	m.folders[id].Path = newPath
+	return nil
+}
+
+func (m *memoryE2EERepo) DeleteFolder(ctx context.Context, id string) error {
+	delete(m.folders, id)
+	return nil
+}
+
+func (m *memoryE2EERepo) PutEnvelope(ctx context.Context, env *e2ee.KeyEnvelope) error {
+	m.envelopes[env.FolderID+"/"+env.RecipientWallet] = env
+	return nil
+}
+
+func (m *memoryE2EERepo) GetEnvelope(ctx context.Context, folderID, wallet string) (*e2ee.KeyEnvelope, error) {
+	if env, ok := m.envelopes[folderID+"/"+wallet]; ok {
+		return env, nil
+	}
+	return nil, e2ee.ErrEnvelopeNotFound
+}
+
+func (m *memoryE2EERepo) ListEnvelopes(ctx context.Context, folderID string) ([]*e2ee.KeyEnvelope, error) {
+	return nil, nil
+}
+
+func (m *memoryE2EERepo) DeleteEnvelope(ctx context.Context, folderID, wallet string) error {
+	return nil
+}
+
+func TestRecordPublicKeyFromLoginSignature(t *testing.T) {
+	key, err := gethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	address := gethcrypto.PubkeyToAddress(key.PublicKey).Hex()
+	message := "Sign in to warehouse\nNonce: 123"
+	hash := gethcrypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)))
+	sig, err := gethcrypto.Sign(hash, key)
+	if err != nil {
+		t.Fatalf("Sign returned error: %v", err)
+	}
+	sig[64] += 27
+
+	pub, err := crypto.NewEthereumSigner().RecoverPublicKey(message, "0x"+hex.EncodeToString(sig), address)
+	if err != nil {
+		t.Fatalf("RecoverPublicKey returned error: %v", err)
+	}
+
+	repo := newMemoryE2EERepo()
+	svc := NewE2EEService(repo, &config.Config{}, zap.NewNop())
+	if err := svc.RecordPublicKey(context.Background(), address, pub); err != nil {
+		t.Fatalf("RecordPublicKey returned error: %v", err)
+	}
+	stored, err := svc.PublicKey(context.Background(), address)
+	if err != nil {
+		t.Fatalf("PublicKey returned error: %v", err)
+	}
+	if hex.EncodeToString(stored.PublicKey) != hex.EncodeToString(gethcrypto.FromECDSAPub(&key.PublicKey)) {
+		t.Fatalf("stored public key does not match wallet key")
+	}
+
+	other, _ := gethcrypto.GenerateKey()
+	if err := svc.RecordPublicKey(context.Background(), address, gethcrypto.FromECDSAPub(&other.PublicKey)); err == nil {
+		t.Fatalf("expected mismatched public key to be rejected")
+	}
+}
+
+func TestE2EEFolderRulesAndShareEnvelopes(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{}
+	cfg.WebDAV.Directory = t.TempDir()
+	repo := newMemoryE2EERepo()
+	svc := NewE2EEService(repo, cfg, zap.NewNop())
+
+	owner := &user.User{ID: "u1", Username: "alice", WalletAddress: "0x1111111111111111111111111111111111111111"}
+	envelope := base64.StdEncoding.EncodeToString([]byte("wrapped folder key"))
+	folder, err := svc.CreateFolder(ctx, owner, "/personal/secret", envelope)
+	if err != nil {
+		t.Fatalf("CreateFolder returned error: %v", err)
+	}
+	if _, err := svc.CreateFolder(ctx, owner, "/personal/secret/inner", envelope); !errors.Is(err, e2ee.ErrNestedEncryptedPath) {
+		t.Fatalf("expected nested folder to be rejected, got %v", err)
+	}
+
+	if got, err := svc.CheckWebDAV(ctx, owner.ID, "GET", "/personal/secret/a.bin", ""); err != nil || got == nil || got.ID != folder.ID {
+		t.Fatalf("GET inside e2ee folder: folder=%v err=%v", got, err)
+	}
+	if _, err := svc.CheckWebDAV(ctx, owner.ID, "SEARCH", "/personal", ""); !errors.Is(err, e2ee.ErrPlaintextOperation) {
+		t.Fatalf("expected SEARCH over e2ee folder to be rejected, got %v", err)
+	}
+	if _, err := svc.CheckWebDAV(ctx, owner.ID, "COPY", "/personal/secret", "/personal/copy"); !errors.Is(err, e2ee.ErrFolderCopy) {
+		t.Fatalf("expected COPY of e2ee folder to be rejected, got %v", err)
+	}
+	// 单个文件只能在同一个加密目录内复制或移动
+	if _, err := svc.CreateFolder(ctx, owner, "/personal/vault", envelope); err != nil {
+		t.Fatalf("CreateFolder returned error: %v", err)
+	}
+	for _, method := range []string{"COPY", "MOVE"} {
+		if _, err := svc.CheckWebDAV(ctx, owner.ID, method, "/personal/notes.txt", "/personal/secret/notes.txt"); !errors.Is(err, e2ee.ErrCrossFolderTransfer) {
+			t.Fatalf("%s of plaintext into e2ee folder: expected ErrCrossFolderTransfer, got %v", method, err)
+		}
+		if _, err := svc.CheckWebDAV(ctx, owner.ID, method, "/personal/secret/a.bin", "/personal/a.bin"); !errors.Is(err, e2ee.ErrCrossFolderTransfer) {
+			t.Fatalf("%s of ciphertext out of e2ee folder: expected ErrCrossFolderTransfer, got %v", method, err)
+		}
+		if _, err := svc.CheckWebDAV(ctx, owner.ID, method, "/personal/secret/a.bin", "/personal/vault/a.bin"); !errors.Is(err, e2ee.ErrCrossFolderTransfer) {
+			t.Fatalf("%s between e2ee folders: expected ErrCrossFolderTransfer, got %v", method, err)
+		}
+		if _, err := svc.CheckWebDAV(ctx, owner.ID, method, "/personal/secret/a.bin", "/personal/secret/b.bin"); err != nil {
+			t.Fatalf("%s within an e2ee folder should be allowed: %v", method, err)
+		}
+		if _, err := svc.CheckWebDAV(ctx, owner.ID, method, "/personal/a.txt", "/personal/b.txt"); err != nil {
+			t.Fatalf("%s outside e2ee folders should be allowed: %v", method, err)
+		}
+	}
+	if _, err := svc.CheckWebDAV(ctx, owner.ID, "MOVE", "/personal/secret", "/personal/archive/secret"); err != nil {
+		t.Fatalf("moving a whole e2ee folder should be allowed: %v", err)
+	}
+
+	if err := svc.EnsurePlaintextAllowed(ctx, owner.ID, "/personal/secret/a.bin"); !errors.Is(err, e2ee.ErrPlaintextOperation) {
+		t.Fatalf("expected public link inside e2ee folder to be rejected, got %v", err)
+	}
+
+	target := "0x2222222222222222222222222222222222222222"
+	if _, _, err := svc.PrepareShare(ctx, owner, "/personal/secret/a.bin", target, ""); !errors.Is(err, e2ee.ErrEnvelopeRequired) {
+		t.Fatalf("expected share without envelope to be rejected, got %v", err)
+	}
+	if _, env, err := svc.PrepareShare(ctx, owner, "/personal/secret/a.bin", target, envelope); err != nil || env == nil {
+		t.Fatalf("PrepareShare with envelope: env=%v err=%v", env, err)
+	}
+	if _, env, err := svc.ShareEnvelope(ctx, owner.ID, "/personal/secret", target); err != nil || env == nil || env.Envelope != envelope {
+		t.Fatalf("expected stored envelope for target, got %v (%v)", env, err)
+	}
+	if f, env, err := svc.PrepareShare(ctx, owner, "/personal/plain.txt", target, ""); err != nil || f != nil || env != nil {
+		t.Fatalf("plain share should not need an envelope: folder=%v env=%v err=%v", f, env, err)
+	}
+
+	if err := svc.MoveFolders(ctx, owner.ID, "/personal", "/archive"); err != nil {
+		t.Fatalf("MoveFolders returned error: %v", err)
+	}
+	if got, _ := svc.FolderFor(ctx, owner.ID, "/archive/secret/a.bin"); got == nil || got.Path != "/archive/secret" {
+		t.Fatalf("expected folder path to follow MOVE, got %+v", got)
+	}
+}
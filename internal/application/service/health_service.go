@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
+	"go.uber.org/zap"
+)
+
+// 检查结果状态
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusWarn = "warn" // 异常但不影响就绪
+	HealthStatusFail = "fail"
+)
+
+// HealthCheckFunc 单项就绪检查，返回附加信息；返回错误表示检查失败
+type HealthCheckFunc func(ctx context.Context) (map[string]any, error)
+
+// HealthCheckResult 单项检查结果
+type HealthCheckResult struct {
+	Name    string
+	Status  string
+	Latency time.Duration
+	Error   string
+	Detail  map[string]any
+}
+
+// healthWarning 标记只需告警、不影响就绪的检查错误
+type healthWarning struct {
+	err error
+}
+
+func (w *healthWarning) Error() string { return w.err.Error() }
+func (w *healthWarning) Unwrap() error { return w.err }
+
+type healthCheck struct {
+	name string
+	fn   HealthCheckFunc
+}
+
+// HealthService 就绪检查服务
+type HealthService struct {
+	timeout time.Duration
+	logger  *zap.Logger
+
+	mu     sync.RWMutex
+	checks []healthCheck
+}
+
+// NewHealthService 创建就绪检查服务
+func NewHealthService(cfg config.HealthConfig, logger *zap.Logger) *HealthService {
+	return &HealthService{
+		timeout: cfg.Timeout,
+		logger:  logger,
+	}
+}
+
+// Register 注册检查项，按注册顺序输出
+func (s *HealthService) Register(name string, fn HealthCheckFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks = append(s.checks, healthCheck{name: name, fn: fn})
+}
+
+// Check 并发执行全部检查；任一检查失败时 ready 为 false
+func (s *HealthService) Check(ctx context.Context) (bool, []HealthCheckResult) {
+	s.mu.RLock()
+	checks := append([]healthCheck(nil), s.checks...)
+	s.mu.RUnlock()
+
+	results := make([]HealthCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check healthCheck) {
+			defer wg.Done()
+			results[i] = s.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	ready := true
+	for _, result := range results {
+		if result.Status == HealthStatusFail {
+			ready = false
+			logger.Ctx(ctx, s.logger).Warn("readiness check failed",
+				zap.String("check", result.Name),
+				zap.String("error", result.Error))
+		}
+	}
+	return ready, results
+}
+
+// run 在超时内执行单项检查；检查函数不响应取消时按超时失败处理
+func (s *HealthService) run(ctx context.Context, check healthCheck) HealthCheckResult {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		detail map[string]any
+		err    error
+	}
+	start := time.Now()
+	ch := make(chan outcome, 1)
+	go func() {
+		detail, err := check.fn(ctx)
+		ch <- outcome{detail: detail, err: err}
+	}()
+
+	var out outcome
+	select {
+	case out = <-ch:
+	case <-ctx.Done():
+		out.err = ctx.Err()
+	}
+
+	result := HealthCheckResult{
+		Name:    check.name,
+		Status:  HealthStatusOK,
+		Latency: time.Since(start),
+		Detail:  out.detail,
+	}
+	if out.err != nil {
+		result.Error = out.err.Error()
+		result.Status = HealthStatusFail
+		var warning *healthWarning
+		if errors.As(out.err, &warning) {
+			result.Status = HealthStatusWarn
+		}
+	}
+	return result
+}
+
+// DatabaseHealthChecker 数据库就绪检查所需的能力
+type DatabaseHealthChecker interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (current, latest int64, err error)
+}
+
+// DatabaseHealthCheck 检查数据库连接与迁移版本：未迁移到最新版本时失败，
+// 数据库版本高于程序（如滚动升级中的旧实例）时告警
+func DatabaseHealthCheck(db DatabaseHealthChecker) HealthCheckFunc {
+	return func(ctx context.Context) (map[string]any, error) {
+		if err := db.Ping(ctx); err != nil {
+			return nil, fmt.Errorf("ping failed: %w", err)
+		}
+		current, latest, err := db.SchemaVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+		detail := map[string]any{
+			"schema_version": current,
+			"latest_version": latest,
+		}
+		if current < latest {
+			return detail, fmt.Errorf("schema version %d is behind %d", current, latest)
+		}
+		if current > latest {
+			return detail, &healthWarning{err: fmt.Errorf("schema version %d is newer than %d", current, latest)}
+		}
+		return detail, nil
+	}
+}
+
+// StorageHealthCheck 检查数据目录可写且可用空间不低于 minFree（0 表示不检查空间）
+func StorageHealthCheck(dir string, minFree int64) HealthCheckFunc {
+	return func(ctx context.Context) (map[string]any, error) {
+		if err := webdavfs.ProbeWritable(dir); err != nil {
+			return nil, fmt.Errorf("directory not writable: %w", err)
+		}
+		free, total, err := webdavfs.DiskSpace(dir)
+		if errors.Is(err, errors.ErrUnsupported) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat filesystem: %w", err)
+		}
+		detail := map[string]any{
+			"free_bytes":  free,
+			"total_bytes": total,
+		}
+		if minFree > 0 && free < uint64(minFree) {
+			return detail, fmt.Errorf("free space %d bytes below minimum %d bytes", free, minFree)
+		}
+		return detail, nil
+	}
+}
+
+// SMTPPinger 能够探测 SMTP 服务器的发送器
+type SMTPPinger interface {
+	Ping(ctx context.Context) error
+}
+
+// SMTPHealthCheck 检查 SMTP 服务器可连接
+func SMTPHealthCheck(sender SMTPPinger) HealthCheckFunc {
+	return func(ctx context.Context) (map[string]any, error) {
+		return nil, sender.Ping(ctx)
+	}
+}
+
+// Worker 可报告运行状态的后台任务
+type Worker interface {
+	Status() WorkerStatus
+}
+
+// WorkerHealthCheck 检查后台任务：应运行而未运行时失败，最近一次执行出错时告警
+func WorkerHealthCheck(workers ...Worker) HealthCheckFunc {
+	return func(ctx context.Context) (map[string]any, error) {
+		detail := make(map[string]any, len(workers))
+		var failed, warned []string
+		for _, w := range workers {
+			status := w.Status()
+			if !status.Enabled {
+				detail[status.Name] = map[string]any{"enabled": false}
+				continue
+			}
+			entry := map[string]any{"enabled": true, "running": status.Running}
+			if !status.LastRun.IsZero() {
+				entry["last_run"] = status.LastRun
+			}
+			if status.LastError != "" {
+				entry["last_error"] = status.LastError
+				warned = append(warned, status.Name)
+			}
+			if !status.Running {
+				failed = append(failed, status.Name)
+			}
+			detail[status.Name] = entry
+		}
+		if len(failed) > 0 {
+			return detail, fmt.Errorf("workers not running: %v", failed)
+		}
+		if len(warned) > 0 {
+			return detail, &healthWarning{err: fmt.Errorf("last run failed: %v", warned)}
+		}
+		return detail, nil
+	}
+}
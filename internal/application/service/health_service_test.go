@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"go.uber.org/zap"
+)
+
+type stubWorker struct {
+	status WorkerStatus
+}
+
+func (w stubWorker) Status() WorkerStatus { return w.status }
+
+func TestHealthServiceCheck(t *testing.T) {
+	s := NewHealthService(config.HealthConfig{Timeout: 50 * time.Millisecond}, zap.NewNop())
+	s.Register("storage", StorageHealthCheck(t.TempDir(), 0))
+	s.Register("workers", WorkerHealthCheck(
+		stubWorker{WorkerStatus{Name: "session_cleanup", Enabled: true, Running: true, LastError: "db down"}},
+		stubWorker{WorkerStatus{Name: "blob_gc"}},
+	))
+
+	ready, results := s.Check(context.Background())
+	if !ready {
+		t.Fatalf("expected ready, got %+v", results)
+	}
+	if results[0].Name != "storage" || results[0].Status != HealthStatusOK {
+		t.Fatalf("unexpected storage result: %+v", results[0])
+	}
+	if results[1].Status != HealthStatusWarn {
+		t.Fatalf("worker with failed last run should warn, got %+v", results[1])
+	}
+
+	s.Register("database", func(ctx context.Context) (map[string]any, error) {
+		return nil, errors.New("connection refused")
+	})
+	s.Register("smtp", func(ctx context.Context) (map[string]any, error) {
+		time.Sleep(time.Second)
+		return nil, nil
+	})
+	ready, results = s.Check(context.Background())
+	if ready {
+		t.Fatalf("expected not ready")
+	}
+	if results[2].Status != HealthStatusFail || results[2].Error != "connection refused" {
+		t.Fatalf("unexpected database result: %+v", results[2])
+	}
+	if results[3].Status != HealthStatusFail || results[3].Latency >= time.Second {
+		t.Fatalf("slow check should time out, got %+v", results[3])
+	}
+}
+
+func TestStorageHealthCheckMinFree(t *testing.T) {
+	detail, err := StorageHealthCheck(t.TempDir(), 1<<62)(context.Background())
+	if detail == nil {
+		t.Skip("disk space not supported on this platform")
+	}
+	if err == nil {
+		t.Fatalf("expected free space error")
+	}
+}
+
+func TestWorkerHealthCheckNotRunning(t *testing.T) {
+	_, err := WorkerHealthCheck(stubWorker{WorkerStatus{Name: "audit_cleanup", Enabled: true}})(context.Background())
+	if err == nil {
+		t.Fatalf("expected error for stopped worker")
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/yeying-community/warehouse/internal/domain/identity"
+	"github.com/yeying-community/warehouse/internal/domain/quota"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
+	"go.uber.org/zap"
+)
+
+// mergedDirName 合并账户时，被合并账户的文件放在目标账户的该目录下
+const mergedDirName = "merged"
+
+// IdentityService 登录身份服务
+// 调用方负责在关联、解绑与合并前校验对身份的控制权（签名、邮箱验证码或密码）。
+type IdentityService struct {
+	repo         repository.IdentityRepository
+	userRepo     user.Repository
+	recycleRepo  repository.RecycleRepository
+	e2eeRepo     repository.E2EERepository
+	storage      *StorageService
+	quotaService quota.Service
+	config       *config.Config
+	logger       *zap.Logger
+}
+
+// NewIdentityService 创建登录身份服务
+func NewIdentityService(
+	repo repository.IdentityRepository,
+	userRepo user.Repository,
+	recycleRepo repository.RecycleRepository,
+	e2eeRepo repository.E2EERepository,
+	storage *StorageService,
+	quotaService quota.Service,
+	cfg *config.Config,
+	logger *zap.Logger,
+) *IdentityService {
+	return &IdentityService{
+		repo:         repo,
+		userRepo:     userRepo,
+		recycleRepo:  recycleRepo,
+		e2eeRepo:     e2eeRepo,
+		storage:      storage,
+		quotaService: quotaService,
+		config:       cfg,
+		logger:       logger,
+	}
+}
+
+// List 获取用户的全部登录身份
+func (s *IdentityService) List(ctx context.Context, u *user.User) ([]*identity.Identity, error) {
+	return s.repo.ListByUser(ctx, u.ID)
+}
+
+// Link 为用户关联钱包或邮箱
+// 用户名密码通过修改密码接口设置，不在此关联。
+func (s *IdentityService) Link(ctx context.Context, u *user.User, t identity.Type, subject string) (*identity.Identity, error) {
+	if t != identity.TypeWallet && t != identity.TypeEmail {
+		return nil, identity.ErrInvalidIdentity
+	}
+	ident, err := identity.New(u.ID, t, subject)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Link(ctx, ident); err != nil {
+		return nil, err
+	}
+
+	logger.Ctx(ctx, s.logger).Info("identity linked",
+		zap.String("username", u.Username),
+		zap.String("type", string(t)),
+		zap.String("subject", ident.Subject))
+	return ident, nil
+}
+
+// Unlink 解除用户的登录身份
+func (s *IdentityService) Unlink(ctx context.Context, u *user.User, identityID string) (*identity.Identity, error) {
+	ident, err := s.repo.Unlink(ctx, u.ID, identityID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Ctx(ctx, s.logger).Info("identity unlinked",
+		zap.String("username", u.Username),
+		zap.String("type", string(ident.Type)),
+		zap.String("subject", ident.Subject))
+	return ident, nil
+}
+
+// ResolveOwner 查找登录身份所属的用户
+func (s *IdentityService) ResolveOwner(ctx context.Context, t identity.Type, subject string) (*user.User, error) {
+	if t == identity.TypePassword {
+		u, err := s.userRepo.FindByUsername(ctx, subject)
+		if err != nil {
+			return nil, identity.ErrIdentityNotFound
+		}
+		return u, nil
+	}
+	ident, err := s.repo.FindBySubject(ctx, t, subject)
+	if err != nil {
+		return nil, err
+	}
+	return s.userRepo.FindByID(ctx, ident.UserID)
+}
+
+// Merge 将 source 账户合并到 target
+// source 的文件复制到 target 的 /merged/<source 用户名> 下（按 target 的密钥重新加密），
+// 分享、地址簿与登录身份随之转移，最后删除 source 账户。返回文件的新位置。
+func (s *IdentityService) Merge(ctx context.Context, target, source *user.User) (string, error) {
+	if source.ID == target.ID {
+		return "", identity.ErrSameAccount
+	}
+
+	// 端到端加密目录的密钥信封绑定在拥有者身上，回收站条目按用户目录记录，均无法安全迁移
+	folders, err := s.e2eeRepo.ListFolders(ctx, source.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(folders) > 0 {
+		return "", fmt.Errorf("%w: the account owns end-to-end encrypted folders", identity.ErrMergeBlocked)
+	}
+	recycled, err := s.recycleRepo.GetByUserID(ctx, source.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(recycled) > 0 {
+		return "", fmt.Errorf("%w: the account's recycle bin is not empty", identity.ErrMergeBlocked)
+	}
+
+	sourceRoot := s.userRootDir(source)
+	targetRoot := s.userRootDir(target)
+	used, err := s.quotaService.CalculateUsedSpace(ctx, sourceRoot)
+	if err != nil {
+		return "", err
+	}
+	if err := s.quotaService.CheckQuota(ctx, target, used); err != nil {
+		return "", err
+	}
+
+	destPath := path.Join("/", mergedDirName, source.Username)
+	if _, err := os.Stat(filepath.Join(targetRoot, filepath.FromSlash(destPath))); err == nil {
+		destPath = fmt.Sprintf("%s-%s", destPath, shortID(source.ID))
+	}
+
+	sourceFS, err := s.storage.FileSystem(ctx, source, sourceRoot)
+	if err != nil {
+		return "", err
+	}
+	targetFS, err := s.storage.FileSystem(ctx, target, targetRoot)
+	if err != nil {
+		return "", err
+	}
+	if err := copyTree(ctx, sourceFS, "/", targetFS, destPath); err != nil {
+		targetFS.RemoveAll(ctx, destPath)
+		return "", fmt.Errorf("failed to copy files: %w", err)
+	}
+
+	if err := s.repo.Merge(ctx, source.ID, target.ID, target.Username, destPath); err != nil {
+		targetFS.RemoveAll(ctx, destPath)
+		return "", err
+	}
+
+	if err := sourceFS.RemoveAll(ctx, "/"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Ctx(ctx, s.logger).Warn("failed to remove merged account directory",
+			zap.String("username", source.Username),
+			zap.Error(err))
+	}
+
+	// 刷新 used_space
+	if used, err := s.quotaService.CalculateUsedSpace(ctx, targetRoot); err == nil {
+		if err := s.userRepo.UpdateUsedSpace(ctx, target.Username, used); err != nil {
+			logger.Ctx(ctx, s.logger).Error("failed to update used space", zap.Error(err))
+		} else {
+			target.UpdateUsedSpace(used)
+		}
+	}
+
+	logger.Ctx(ctx, s.logger).Info("account merged",
+		zap.String("username", target.Username),
+		zap.String("merged_username", source.Username),
+		zap.String("path", destPath))
+	return destPath, nil
+}
+
+func (s *IdentityService) userRootDir(u *user.User) string {
+	userDir := u.Directory
+	if userDir == "" {
+		userDir = u.Username
+	}
+	if filepath.IsAbs(userDir) {
+		return userDir
+	}
+	return filepath.Join(s.config.WebDAV.Directory, userDir)
+}
+
+// copyTree 递归复制目录
+func copyTree(ctx context.Context, src *webdavfs.UnicodeFileSystem, srcDir string, dst *webdavfs.UnicodeFileSystem, dstDir string) error {
+	if err := dst.Mkdir(ctx, dstDir, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	entries, err := src.ReadDir(ctx, srcDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		srcName := path.Join(srcDir, entry.Name())
+		dstName := path.Join(dstDir, entry.Name())
+		if entry.IsDir() {
+			if err := copyTree(ctx, src, srcName, dst, dstName); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := webdavfs.CopyFile(ctx, src, srcName, dst, dstName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/yeying-community/warehouse/internal/domain/datakey"
+	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
+	"go.uber.org/zap"
+)
+
+// KeyService 用户数据密钥服务（静态加密）
+// 每个用户持有独立的数据密钥，数据密钥以主密钥加密后保存在数据库中。
+type KeyService struct {
+	repo     repository.DataKeyRepository
+	master   []byte
+	masterID string
+	logger   *zap.Logger
+
+	mu    sync.Mutex
+	cache map[string]*webdavfs.DataKeys
+}
+
+// NewKeyService 创建数据密钥服务，master 为空表示未启用静态加密
+func NewKeyService(repo repository.DataKeyRepository, master []byte, logger *zap.Logger) *KeyService {
+	s := &KeyService{
+		repo:   repo,
+		logger: logger,
+		cache:  make(map[string]*webdavfs.DataKeys),
+	}
+	if len(master) > 0 {
+		s.master = master
+		s.masterID = crypto.MasterKeyID(master)
+	}
+	return s
+}
+
+// Enabled 是否启用静态加密
+func (s *KeyService) Enabled() bool {
+	return s != nil && len(s.master) > 0
+}
+
+// MasterKeyID 返回当前主密钥标识
+func (s *KeyService) MasterKeyID() string {
+	if s == nil {
+		return ""
+	}
+	return s.masterID
+}
+
+// DataKeys 获取用户的数据密钥，首次使用时自动创建
+func (s *KeyService) DataKeys(ctx context.Context, userID string) (*webdavfs.DataKeys, error) {
+	if !s.Enabled() {
+		return nil, webdavfs.ErrDataKeyMissing
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if keys, ok := s.cache[userID]; ok {
+		return keys, nil
+	}
+
+	records, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		record, err := s.newDataKey(userID, 1)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.Create(ctx, record); err != nil {
+			// 其他实例可能已同时创建，重新读取
+			if records, err = s.repo.ListByUser(ctx, userID); err != nil || len(records) == 0 {
+				return nil, fmt.Errorf("failed to create data key: %w", err)
+			}
+		} else {
+			records = []*datakey.DataKey{record}
+			logger.Ctx(ctx, s.logger).Info("data key created", zap.String("user_id", userID))
+		}
+	}
+
+	keys, err := s.unwrap(userID, records)
+	if err != nil {
+		return nil, err
+	}
+	s.cache[userID] = keys
+	return keys, nil
+}
+
+// RotateUserKey 为用户生成新的数据密钥版本，之后写入的文件使用新版本
+// 已有文件仍可用旧版本解密，可通过重新加密迁移到新版本。
+func (s *KeyService) RotateUserKey(ctx context.Context, userID string) (uint32, error) {
+	if !s.Enabled() {
+		return 0, webdavfs.ErrDataKeyMissing
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	var version uint32 = 1
+	for _, record := range records {
+		if record.Version >= version {
+			version = record.Version + 1
+		}
+	}
+	record, err := s.newDataKey(userID, version)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.repo.Create(ctx, record); err != nil {
+		return 0, err
+	}
+	delete(s.cache, userID)
+
+	logger.Ctx(ctx, s.logger).Info("data key rotated",
+		zap.String("user_id", userID),
+		zap.Uint32("version", version))
+	return version, nil
+}
+
+// RewrapAll 使用新的主密钥重新加密全部数据密钥（文件内容无需重新加密）
+// 已由新主密钥加密的记录会被跳过，因此中断后可以重复执行。
+func (s *KeyService) RewrapAll(ctx context.Context, newMaster []byte) (int, error) {
+	if !s.Enabled() {
+		return 0, webdavfs.ErrDataKeyMissing
+	}
+	newID := crypto.MasterKeyID(newMaster)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.repo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, record := range records {
+		if record.MasterKeyID == newID {
+			continue
+		}
+		if record.MasterKeyID != s.masterID {
+			return count, fmt.Errorf("%w: user %s version %d", datakey.ErrMasterKeyMismatch, record.UserID, record.Version)
+		}
+		key, err := crypto.UnwrapKey(s.master, record.WrappedKey, record.AAD())
+		if err != nil {
+			return count, fmt.Errorf("failed to unwrap data key for user %s: %w", record.UserID, err)
+		}
+		wrapped, err := crypto.WrapKey(newMaster, key, record.AAD())
+		if err != nil {
+			return count, err
+		}
+		record.WrappedKey = wrapped
+		record.MasterKeyID = newID
+		if err := s.repo.UpdateWrappedKey(ctx, record); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	s.master = newMaster
+	s.masterID = newID
+	s.cache = make(map[string]*webdavfs.DataKeys)
+	return count, nil
+}
+
+func (s *KeyService) newDataKey(userID string, version uint32) (*datakey.DataKey, error) {
+	key, err := crypto.GenerateDataKey()
+	if err != nil {
+		return nil, err
+	}
+	record := datakey.NewDataKey(userID, version, nil, s.masterID)
+	record.WrappedKey, err = crypto.WrapKey(s.master, key, record.AAD())
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (s *KeyService) unwrap(userID string, records []*datakey.DataKey) (*webdavfs.DataKeys, error) {
+	keys := make(map[uint32][]byte, len(records))
+	var active uint32
+	for _, record := range records {
+		if record.MasterKeyID != s.masterID {
+			return nil, fmt.Errorf("%w: user %s version %d", datakey.ErrMasterKeyMismatch, userID, record.Version)
+		}
+		key, err := crypto.UnwrapKey(s.master, record.WrappedKey, record.AAD())
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key for user %s: %w", userID, err)
+		}
+		keys[record.Version] = key
+		if record.Active || active == 0 {
+			active = record.Version
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no data key available")
+	}
+	return webdavfs.NewDataKeys(userID, active, keys)
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/outbox"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// outboxLease 领取邮件后的租期：超过该时间仍未写回结果（如进程在发送中退出）时，邮件会被再次领取
+const outboxLease = 10 * time.Minute
+
+// outboxCleanupInterval 已发送邮件的清理间隔
+const outboxCleanupInterval = time.Hour
+
+// MailTransport 渲染模板并通过 SMTP 投递
+type MailTransport interface {
+	Render(name, locale string, data map[string]any) (subject, body string, err error)
+	Send(to, subject, body string) error
+}
+
+// MailOutboxService 邮件发件箱服务
+// SendTemplate 只把邮件写入发件箱，后台任务渲染模板并发送；投递失败按指数退避重试，
+// 用尽 max_attempts 次或模板无法渲染时转为死信，管理员排查后可以手动重试。
+type MailOutboxService struct {
+	repo      repository.OutboxRepository
+	transport MailTransport
+	config    config.EmailConfig
+	logger    *zap.Logger
+
+	wake     chan struct{}
+	worker   workerState
+	stopOnce sync.Once
+	started  bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewMailOutboxService 创建邮件发件箱服务
+func NewMailOutboxService(repo repository.OutboxRepository, transport MailTransport, cfg config.EmailConfig, logger *zap.Logger) *MailOutboxService {
+	return &MailOutboxService{
+		repo:      repo,
+		transport: transport,
+		config:    cfg,
+		logger:    logger,
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Enabled 是否启用邮件
+func (s *MailOutboxService) Enabled() bool {
+	return s != nil && s.config.Enabled
+}
+
+// SendTemplate 把模板邮件写入发件箱并唤醒后台任务
+// locale 可以是 Accept-Language 请求头，发送时按它选择模板语言。
+func (s *MailOutboxService) SendTemplate(to, name, locale string, data map[string]any) error {
+	if !s.Enabled() {
+		return errors.New("email is disabled")
+	}
+	m := outbox.NewMessage(strings.ToLower(strings.TrimSpace(to)), name, locale, data)
+	if err := s.repo.Enqueue(context.Background(), m); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+// ProcessDue 发送全部到期的邮件，返回发送成功的数量
+// 单封邮件投递失败只记录到该邮件上，返回的错误仅表示发件箱读写失败。
+func (s *MailOutboxService) ProcessDue(ctx context.Context) (int, error) {
+	var sent int
+	for {
+		batch, err := s.repo.ClaimDue(ctx, time.Now(), outboxLease, s.config.Outbox.BatchSize)
+		if err != nil {
+			return sent, err
+		}
+		for _, m := range batch {
+			s.deliver(ctx, m)
+			if err := s.repo.Update(ctx, m); err != nil {
+				return sent, err
+			}
+			if m.Status == outbox.StatusSent {
+				sent++
+			}
+		}
+		if len(batch) < s.config.Outbox.BatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// deliver 渲染并投递一封邮件，把结果记录到 m
+func (s *MailOutboxService) deliver(ctx context.Context, m *outbox.Message) {
+	log := logger.Ctx(ctx, s.logger).With(
+		zap.String("id", m.ID),
+		zap.String("template", m.Template),
+		zap.String("recipient", m.Recipient))
+
+	subject, body, err := s.transport.Render(m.Template, m.Locale, m.Data)
+	if err != nil {
+		// 模板缺失或数据有误，重试也不会成功
+		m.Kill(err.Error(), time.Now())
+		log.Error("failed to render mail, moved to dead letter", zap.Error(err))
+		return
+	}
+	if err := s.transport.Send(m.Recipient, subject, body); err != nil {
+		cfg := s.config.Outbox
+		m.Fail(err.Error(), time.Now(), cfg.MaxAttempts, cfg.RetryBase, cfg.RetryMax)
+		if m.Status == outbox.StatusDead {
+			log.Error("failed to send mail, moved to dead letter", zap.Int("attempts", m.Attempts), zap.Error(err))
+		} else {
+			log.Warn("failed to send mail, will retry",
+				zap.Int("attempts", m.Attempts),
+				zap.Time("next_attempt_at", m.NextAttemptAt),
+				zap.Error(err))
+		}
+		return
+	}
+	m.MarkSent(time.Now())
+	log.Debug("mail sent")
+}
+
+// Cleanup 删除超过保留期的已发送邮件；保留期为 0 时永久保留
+func (s *MailOutboxService) Cleanup(ctx context.Context) (int64, error) {
+	if s.config.Outbox.SentRetention <= 0 {
+		return 0, nil
+	}
+	deleted, err := s.repo.DeleteSentBefore(ctx, time.Now().Add(-s.config.Outbox.SentRetention))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		logger.Ctx(ctx, s.logger).Info("sent mail cleaned up", zap.Int64("deleted", deleted))
+	}
+	return deleted, nil
+}
+
+// List 分页查询发件箱
+func (s *MailOutboxService) List(ctx context.Context, filter outbox.Filter) ([]*outbox.Message, int, error) {
+	filter.Normalize()
+	return s.repo.Query(ctx, filter)
+}
+
+// Retry 将死信重新放回发送队列
+func (s *MailOutboxService) Retry(ctx context.Context, id string) (*outbox.Message, error) {
+	m, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Retry(time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, m); err != nil {
+		return nil, err
+	}
+	s.notify()
+	logger.Ctx(ctx, s.logger).Info("dead mail requeued",
+		zap.String("id", m.ID),
+		zap.String("template", m.Template),
+		zap.String("recipient", m.Recipient))
+	return m, nil
+}
+
+// Start 启动后台发送：启动时先发送积压的邮件，之后按 poll_interval 轮询，写入新邮件时立即发送
+func (s *MailOutboxService) Start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	go func() {
+		defer close(s.done)
+		poll := time.NewTicker(s.config.Outbox.PollInterval)
+		defer poll.Stop()
+		cleanup := time.NewTicker(outboxCleanupInterval)
+		defer cleanup.Stop()
+
+		s.process()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-s.wake:
+				s.process()
+			case <-poll.C:
+				s.process()
+			case <-cleanup.C:
+				if _, err := s.Cleanup(context.Background()); err != nil {
+					s.logger.Error("mail outbox cleanup failed", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// notify 唤醒后台任务；已有未处理的唤醒时不再重复
+func (s *MailOutboxService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *MailOutboxService) process() {
+	_, err := s.ProcessDue(context.Background())
+	if err != nil {
+		s.logger.Error("mail outbox processing failed", zap.Error(err))
+	}
+	s.worker.record(err)
+}
+
+// Status 返回后台发送任务状态
+func (s *MailOutboxService) Status() WorkerStatus {
+	return s.worker.status("mail_outbox", s.Enabled(), s.started, s.done)
+}
+
+// Stop 停止后台发送，等待正在进行的一轮发送结束
+func (s *MailOutboxService) Stop() {
+	if s == nil || !s.started {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/outbox"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/email"
+	"github.com/yeying-community/warehouse/internal/infrastructure/email/emailtest"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository/memory"
+	"go.uber.org/zap"
+)
+
+func newTestMailOutbox(t *testing.T) (*MailOutboxService, *memory.OutboxRepository, *emailtest.Server) {
+	t.Helper()
+	server := emailtest.NewServer(t)
+	cfg := config.EmailConfig{
+		Enabled:       true,
+		SMTPHost:      server.Host,
+		SMTPPort:      server.Port,
+		From:          "noreply@example.com",
+		TemplateDir:   "../../../resources/email",
+		DefaultLocale: "zh-CN",
+		Outbox: config.OutboxConfig{
+			PollInterval: time.Hour,
+			BatchSize:    10,
+			MaxAttempts:  2,
+			RetryBase:    time.Minute,
+			RetryMax:     time.Hour,
+		},
+	}
+	repo := memory.NewOutboxRepository(memory.NewStore())
+	return NewMailOutboxService(repo, email.NewSender(cfg, zap.NewNop()), cfg, zap.NewNop()), repo, server
+}
+
+// lastMessage 返回最近写入发件箱的邮件
+func lastMessage(t *testing.T, s *MailOutboxService) *outbox.Message {
+	t.Helper()
+	items, _, err := s.List(context.Background(), outbox.Filter{Limit: 1})
+	if err != nil || len(items) == 0 {
+		t.Fatalf("List: %v (%d items)", err, len(items))
+	}
+	return items[0]
+}
+
+// makeDue 把等待重试的邮件提前到现在
+func makeDue(t *testing.T, repo *memory.OutboxRepository, m *outbox.Message) {
+	t.Helper()
+	m.NextAttemptAt = time.Now()
+	if err := repo.Update(context.Background(), m); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+}
+
+func TestMailOutboxDelivers(t *testing.T) {
+	ctx := context.Background()
+	s, _, server := newTestMailOutbox(t)
+
+	if err := s.SendTemplate("Alice@Example.com", MailTemplateEmailCodeLogin, "en-US,en;q=0.9", map[string]any{
+		"code":      "123456",
+		"expiresIn": 5,
+	}); err != nil {
+		t.Fatalf("SendTemplate: %v", err)
+	}
+	// 写入发件箱时不投递
+	if got := len(server.Messages()); got != 0 {
+		t.Fatalf("mail delivered before processing: %d", got)
+	}
+
+	sent, err := s.ProcessDue(ctx)
+	if err != nil || sent != 1 {
+		t.Fatalf("ProcessDue: sent=%d err=%v", sent, err)
+	}
+	msgs := server.Messages()
+	if len(msgs) != 1 || msgs[0].To[0] != "alice@example.com" || msgs[0].From != "noreply@example.com" {
+		t.Fatalf("unexpected delivery: %+v", msgs)
+	}
+	if msgs[0].Subject() != "Your login code" || !strings.Contains(msgs[0].Body(), "123456") {
+		t.Fatalf("unexpected mail: %q", msgs[0].Subject())
+	}
+
+	m := lastMessage(t, s)
+	if m.Status != outbox.StatusSent || m.Attempts != 1 || m.SentAt == nil || len(m.Data) != 0 {
+		t.Fatalf("unexpected outbox entry after send: %+v", m)
+	}
+	// 已发送的邮件不会再次领取
+	if sent, err := s.ProcessDue(ctx); err != nil || sent != 0 || len(server.Messages()) != 1 {
+		t.Fatalf("mail sent twice: sent=%d err=%v", sent, err)
+	}
+}
+
+func TestMailOutboxRetriesAndDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	s, repo, server := newTestMailOutbox(t)
+	data := map[string]any{"code": "654321", "expiresIn": 5}
+
+	// 临时错误后按退避重试并成功
+	server.FailNext(1)
+	if err := s.SendTemplate("bob@example.com", MailTemplateEmailCodeLogin, "", data); err != nil {
+		t.Fatalf("SendTemplate: %v", err)
+	}
+	if sent, err := s.ProcessDue(ctx); err != nil || sent != 0 {
+		t.Fatalf("ProcessDue: sent=%d err=%v", sent, err)
+	}
+	m := lastMessage(t, s)
+	if m.Status != outbox.StatusPending || m.Attempts != 1 || !strings.Contains(m.LastError, "451") {
+		t.Fatalf("expected pending retry, got %+v", m)
+	}
+	if !m.NextAttemptAt.After(time.Now().Add(30 * time.Second)) {
+		t.Fatalf("retry not backed off: %v", m.NextAttemptAt)
+	}
+	// 退避期内不会重试
+	if sent, _ := s.ProcessDue(ctx); sent != 0 {
+		t.Fatalf("retried before backoff elapsed")
+	}
+	makeDue(t, repo, m)
+	if sent, err := s.ProcessDue(ctx); err != nil || sent != 1 {
+		t.Fatalf("retry: sent=%d err=%v", sent, err)
+	}
+
+	// 用尽重试次数后转为死信，手动重试后发送
+	server.FailNext(2)
+	if err := s.SendTemplate("carol@example.com", MailTemplateEmailCodeLogin, "", data); err != nil {
+		t.Fatalf("SendTemplate: %v", err)
+	}
+	s.ProcessDue(ctx)
+	makeDue(t, repo, lastMessage(t, s))
+	s.ProcessDue(ctx)
+	dead, total, err := s.List(ctx, outbox.Filter{Status: outbox.StatusDead})
+	if err != nil || total != 1 || dead[0].Recipient != "carol@example.com" || dead[0].Attempts != 2 {
+		t.Fatalf("expected one dead letter, got %d (%v)", total, err)
+	}
+
+	if _, err := s.Retry(ctx, dead[0].ID); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if sent, err := s.ProcessDue(ctx); err != nil || sent != 1 {
+		t.Fatalf("after retry: sent=%d err=%v", sent, err)
+	}
+	if _, err := s.Retry(ctx, dead[0].ID); !errors.Is(err, outbox.ErrNotDead) {
+		t.Fatalf("expected ErrNotDead, got %v", err)
+	}
+	if got := len(server.Messages()); got != 2 {
+		t.Fatalf("expected 2 delivered mails, got %d", got)
+	}
+
+	// 模板无法渲染时不重试
+	if err := s.SendTemplate("dave@example.com", "missing", "", nil); err != nil {
+		t.Fatalf("SendTemplate: %v", err)
+	}
+	s.ProcessDue(ctx)
+	if m := lastMessage(t, s); m.Status != outbox.StatusDead || m.Attempts != 1 {
+		t.Fatalf("expected unrenderable mail to be dead, got %+v", m)
+	}
+}
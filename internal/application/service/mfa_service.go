@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/account"
+	"github.com/yeying-community/warehouse/internal/domain/mfa"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// MFAStatus 两步验证状态
+type MFAStatus struct {
+	Enabled            bool
+	Pending            bool // 已生成密钥但尚未确认
+	Required           bool // 管理员单独要求该用户启用
+	RequiredAsAdmin    bool // 管理员账户被全局要求启用
+	RecoveryCodesLeft  int
+	EnabledAt          *time.Time
+	LoginSecondFactor  bool // 登录时是否需要第二步
+	EnrollmentRequired bool // 登录时需要先完成登记
+}
+
+// MFAEnrollment 待确认的登记信息
+type MFAEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+type mfaFailure struct {
+	count       int
+	lockedUntil time.Time
+}
+
+// MFAService 两步验证（TOTP）服务
+// 用于用户名密码与邮箱验证码登录；Basic 认证不能进行第二步，需要两步验证的账户只能使用应用专用密码。
+// 钱包签名、UCAN 与应用专用密码不受影响。
+type MFAService struct {
+	repo     repository.MFARepository
+	userRepo user.Repository
+	config   config.MFAConfig
+	admins   map[string]struct{}
+	checker  AdminChecker
+	logger   *zap.Logger
+
+	mu       sync.Mutex
+	failures map[string]*mfaFailure
+}
+
+// NewMFAService 创建两步验证服务
+func NewMFAService(repo repository.MFARepository, userRepo user.Repository, cfg config.SecurityConfig, logger *zap.Logger) *MFAService {
+	admins := make(map[string]struct{}, len(cfg.AdminAddresses))
+	for _, raw := range cfg.AdminAddresses {
+		if key := normalizeAdminKey(raw); key != "" {
+			admins[key] = struct{}{}
+		}
+	}
+	return &MFAService{
+		repo:     repo,
+		userRepo: userRepo,
+		config:   cfg.MFA,
+		admins:   admins,
+		logger:   logger,
+		failures: make(map[string]*mfaFailure),
+	}
+}
+
+// AdminChecker 判断用户是否拥有管理权限
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, u *user.User) bool
+}
+
+// SetAdminChecker 设置管理员判断；设置后 require_for_admins 同样作用于数据库中分配了管理角色的用户
+func (s *MFAService) SetAdminChecker(checker AdminChecker) {
+	s.checker = checker
+}
+
+// ChallengeTTL 登录挑战令牌有效期
+func (s *MFAService) ChallengeTTL() time.Duration {
+	return s.config.ChallengeTTL
+}
+
+// Status 获取用户的两步验证状态
+func (s *MFAService) Status(ctx context.Context, u *user.User) (*MFAStatus, error) {
+	e, err := s.get(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	status := &MFAStatus{RequiredAsAdmin: s.config.RequireForAdmins && s.isAdmin(ctx, u)}
+	if e != nil {
+		status.Enabled = e.Enabled
+		status.Pending = !e.Enabled && e.Secret != ""
+		status.Required = e.Required
+		status.RecoveryCodesLeft = len(e.RecoveryCodes)
+		status.EnabledAt = e.EnabledAt
+	}
+	status.LoginSecondFactor = status.Enabled || status.Required || status.RequiredAsAdmin
+	status.EnrollmentRequired = status.LoginSecondFactor && !status.Enabled
+	return status, nil
+}
+
+// RequiresSecondFactor 登录时是否需要第二步验证（已启用、被单独要求或作为管理员被要求）
+func (s *MFAService) RequiresSecondFactor(ctx context.Context, u *user.User) (bool, error) {
+	status, err := s.Status(ctx, u)
+	if err != nil {
+		return false, err
+	}
+	return status.LoginSecondFactor, nil
+}
+
+// Enroll 开始登记：生成新密钥，确认前不生效；已启用时需先关闭
+func (s *MFAService) Enroll(ctx context.Context, u *user.User) (*MFAEnrollment, error) {
+	e, err := s.get(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if e != nil && e.Enabled {
+		return nil, mfa.ErrAlreadyEnabled
+	}
+	secret, err := mfa.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	pending := &mfa.Enrollment{
+		UserID:    u.ID,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.Save(ctx, pending); err != nil {
+		return nil, err
+	}
+	logger.Ctx(ctx, s.logger).Info("mfa enrollment started", zap.String("username", u.Username))
+	return &MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: mfa.ProvisioningURI(secret, s.config.Issuer, accountLabel(u)),
+	}, nil
+}
+
+// Confirm 使用认证器生成的验证码确认登记，返回恢复码明文（仅此一次）
+func (s *MFAService) Confirm(ctx context.Context, u *user.User, code string) ([]string, error) {
+	if err := s.checkLockout(u.ID); err != nil {
+		return nil, err
+	}
+	e, err := s.get(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil || e.Secret == "" {
+		return nil, mfa.ErrNotEnrolled
+	}
+	if e.Enabled {
+		return nil, mfa.ErrAlreadyEnabled
+	}
+	counter, err := mfa.Validate(e.Secret, code, time.Now(), e.LastCounter)
+	if err != nil {
+		s.recordFailure(u)
+		return nil, err
+	}
+	codes, hashes, err := mfa.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	e.Enabled = true
+	e.EnabledAt = &now
+	e.LastCounter = counter
+	e.RecoveryCodes = hashes
+	if err := s.repo.Save(ctx, e); err != nil {
+		return nil, err
+	}
+	s.resetFailures(u.ID)
+	logger.Ctx(ctx, s.logger).Info("mfa enabled", zap.String("username", u.Username))
+	return codes, nil
+}
+
+// Verify 校验登录第二步：TOTP 验证码或恢复码（二选一）
+func (s *MFAService) Verify(ctx context.Context, u *user.User, code, recoveryCode string) error {
+	if err := s.checkLockout(u.ID); err != nil {
+		return err
+	}
+	e, err := s.get(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if e == nil || !e.Enabled {
+		return mfa.ErrNotEnrolled
+	}
+	if err := s.verifyEnrollment(ctx, e, code, recoveryCode); err != nil {
+		if errors.Is(err, mfa.ErrInvalidCode) || errors.Is(err, mfa.ErrInvalidRecoveryCode) {
+			s.recordFailure(u)
+		}
+		return err
+	}
+	s.resetFailures(u.ID)
+	return nil
+}
+
+// Disable 关闭两步验证；需要有效的验证码或恢复码，被强制要求的账户不能关闭
+func (s *MFAService) Disable(ctx context.Context, u *user.User, code, recoveryCode string) error {
+	status, err := s.Status(ctx, u)
+	if err != nil {
+		return err
+	}
+	if status.Required || status.RequiredAsAdmin {
+		return mfa.ErrRequired
+	}
+	if err := s.Verify(ctx, u, code, recoveryCode); err != nil {
+		return err
+	}
+	if err := s.repo.Disable(ctx, u.ID); err != nil {
+		return err
+	}
+	logger.Ctx(ctx, s.logger).Info("mfa disabled", zap.String("username", u.Username))
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部失效
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, u *user.User, code string) ([]string, error) {
+	if err := s.Verify(ctx, u, code, ""); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := mfa.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateRecoveryCodes(ctx, u.ID, hashes); err != nil {
+		return nil, err
+	}
+	logger.Ctx(ctx, s.logger).Info("mfa recovery codes regenerated", zap.String("username", u.Username))
+	return codes, nil
+}
+
+// SetRequired 管理员要求（或取消要求）指定用户启用两步验证
+func (s *MFAService) SetRequired(ctx context.Context, username string, required bool) (*user.User, error) {
+	u, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetRequired(ctx, u.ID, required); err != nil {
+		return nil, err
+	}
+	logger.Ctx(ctx, s.logger).Info("mfa requirement updated",
+		zap.String("username", u.Username),
+		zap.Bool("required", required))
+	return u, nil
+}
+
+func (s *MFAService) verifyEnrollment(ctx context.Context, e *mfa.Enrollment, code, recoveryCode string) error {
+	if strings.TrimSpace(recoveryCode) != "" {
+		// 条件删除保证并发请求中同一恢复码只能成功一次
+		ok, err := s.repo.ConsumeRecoveryCode(ctx, e.UserID, mfa.HashRecoveryCode(recoveryCode))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return mfa.ErrInvalidRecoveryCode
+		}
+		return nil
+	}
+	counter, err := mfa.Validate(e.Secret, code, time.Now(), e.LastCounter)
+	if err != nil {
+		return err
+	}
+	// 条件更新保证并发请求中同一验证码只能成功一次
+	ok, err := s.repo.ConsumeCounter(ctx, e.UserID, counter)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return mfa.ErrInvalidCode
+	}
+	return nil
+}
+
+func (s *MFAService) get(ctx context.Context, userID string) (*mfa.Enrollment, error) {
+	e, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, mfa.ErrNotEnrolled) {
+		return nil, nil
+	}
+	return e, err
+}
+
+func (s *MFAService) isAdmin(ctx context.Context, u *user.User) bool {
+	if s.checker != nil {
+		return s.checker.IsAdmin(ctx, u)
+	}
+	key := normalizeAdminKey(u.WalletAddress)
+	if key == "" {
+		return false
+	}
+	_, ok := s.admins[key]
+	return ok
+}
+
+func (s *MFAService) checkLockout(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.failures[userID]
+	if ok && time.Now().Before(f.lockedUntil) {
+		return mfa.ErrTooManyAttempts
+	}
+	return nil
+}
+
+func (s *MFAService) recordFailure(u *user.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.failures[u.ID]
+	if !ok {
+		f = &mfaFailure{}
+		s.failures[u.ID] = f
+	}
+	f.count++
+	if f.count >= s.config.MaxAttempts {
+		f.count = 0
+		f.lockedUntil = time.Now().Add(s.config.LockoutDuration)
+		s.logger.Warn("mfa locked after repeated failures",
+			zap.String("username", u.Username),
+			zap.Duration("duration", s.config.LockoutDuration))
+	}
+}
+
+func (s *MFAService) resetFailures(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, userID)
+}
+
+// normalizeAdminKey 与管理员中间件一致的地址规范化
+func normalizeAdminKey(raw string) string {
+	if key, err := account.Normalize(raw); err == nil {
+		return key
+	}
+	return strings.ToLower(strings.TrimSpace(raw))
+}
+
+// accountLabel 认证器应用中显示的账户名
+func accountLabel(u *user.User) string {
+	if email := strings.TrimSpace(u.Email); email != "" {
+		return email
+	}
+	return u.Username
+}
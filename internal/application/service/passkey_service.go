@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/domain/webauthn"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// PasskeyService 通行密钥管理服务
+type PasskeyService struct {
+	repo   repository.WebAuthnRepository
+	logger *zap.Logger
+}
+
+// NewPasskeyService 创建通行密钥管理服务
+func NewPasskeyService(repo repository.WebAuthnRepository, logger *zap.Logger) *PasskeyService {
+	return &PasskeyService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// List 获取用户的通行密钥
+func (s *PasskeyService) List(ctx context.Context, u *user.User) ([]*webauthn.Credential, error) {
+	return s.repo.ListByUser(ctx, u.ID)
+}
+
+// Delete 删除通行密钥
+// 仅靠通行密钥登录的账户（无钱包、邮箱与密码）不能删除最后一个通行密钥。
+func (s *PasskeyService) Delete(ctx context.Context, u *user.User, id string) error {
+	if u.WalletAddress == "" && u.Email == "" && u.Password == "" {
+		creds, err := s.repo.ListByUser(ctx, u.ID)
+		if err != nil {
+			return err
+		}
+		if len(creds) <= 1 {
+			return webauthn.ErrLastCredential
+		}
+	}
+	if err := s.repo.Delete(ctx, u.ID, id); err != nil {
+		return err
+	}
+	logger.Ctx(ctx, s.logger).Info("passkey deleted",
+		zap.String("username", u.Username),
+		zap.String("credential_id", id))
+	return nil
+}
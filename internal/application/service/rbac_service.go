@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// RBACService 管理角色与权限服务
+// 数据库中的角色分配决定管理权限；security.admin_addresses 中的钱包地址始终视为 superadmin，用于引导首个管理员。
+type RBACService struct {
+	repo      repository.RoleRepository
+	bootstrap map[string]struct{}
+	logger    *zap.Logger
+}
+
+// NewRBACService 创建管理角色服务
+func NewRBACService(repo repository.RoleRepository, cfg config.SecurityConfig, logger *zap.Logger) *RBACService {
+	bootstrap := make(map[string]struct{}, len(cfg.AdminAddresses))
+	for _, raw := range cfg.AdminAddresses {
+		if key := normalizeAdminKey(raw); key != "" {
+			bootstrap[key] = struct{}{}
+		}
+	}
+	return &RBACService{
+		repo:      repo,
+		bootstrap: bootstrap,
+		logger:    logger,
+	}
+}
+
+// EnsureBuiltinRoles 将内置角色写入数据库
+func (s *RBACService) EnsureBuiltinRoles(ctx context.Context) error {
+	for _, role := range rbac.BuiltinRoles() {
+		if err := s.repo.SaveBuiltinRole(ctx, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsBootstrapAdmin 是否为配置中的引导管理员
+func (s *RBACService) IsBootstrapAdmin(u *user.User) bool {
+	key := normalizeAdminKey(u.WalletAddress)
+	if key == "" {
+		return false
+	}
+	_, ok := s.bootstrap[key]
+	return ok
+}
+
+// Permissions 获取用户的管理权限
+func (s *RBACService) Permissions(ctx context.Context, u *user.User) (rbac.PermissionSet, error) {
+	if s.IsBootstrapAdmin(u) {
+		return rbac.PermissionSet{rbac.PermAll: {}}, nil
+	}
+	roles, err := s.repo.ListUserRoles(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	return rbac.NewPermissionSet(roles...), nil
+}
+
+// Authorize 检查用户是否拥有指定权限，没有时返回 rbac.ErrPermissionDenied
+func (s *RBACService) Authorize(ctx context.Context, u *user.User, perm rbac.Permission) error {
+	perms, err := s.Permissions(ctx, u)
+	if err != nil {
+		return err
+	}
+	if !perms.Has(perm) {
+		return rbac.ErrPermissionDenied
+	}
+	return nil
+}
+
+// AuthorizeTarget 检查操作者的权限是否包含目标用户的全部管理权限
+// 修改、删除、重置密码等操作只能作用于权限不高于自己的用户；actor 为 nil 表示命令行操作，不做限制。
+func (s *RBACService) AuthorizeTarget(ctx context.Context, actor, target *user.User) error {
+	if actor == nil {
+		return nil
+	}
+	targetPerms, err := s.Permissions(ctx, target)
+	if err != nil {
+		return err
+	}
+	return s.authorizeCovers(ctx, actor, targetPerms)
+}
+
+// authorizeCovers 检查操作者的权限是否包含 perms
+func (s *RBACService) authorizeCovers(ctx context.Context, actor *user.User, perms rbac.PermissionSet) error {
+	if actor == nil {
+		return nil
+	}
+	actorPerms, err := s.Permissions(ctx, actor)
+	if err != nil {
+		return err
+	}
+	if !actorPerms.Covers(perms) {
+		return rbac.ErrPrivilegeEscalation
+	}
+	return nil
+}
+
+// IsAdmin 是否拥有任意管理权限（查询失败时按非管理员处理）
+func (s *RBACService) IsAdmin(ctx context.Context, u *user.User) bool {
+	perms, err := s.Permissions(ctx, u)
+	if err != nil {
+		logger.Ctx(ctx, s.logger).Warn("failed to load admin permissions", zap.String("username", u.Username), zap.Error(err))
+		return false
+	}
+	return !perms.Empty()
+}
+
+// ListRoles 获取全部角色
+func (s *RBACService) ListRoles(ctx context.Context) ([]*rbac.Role, error) {
+	return s.repo.ListRoles(ctx)
+}
+
+// UserRoles 获取用户在数据库中的角色（不含引导管理员身份）
+func (s *RBACService) UserRoles(ctx context.Context, u *user.User) ([]*rbac.Role, error) {
+	return s.repo.ListUserRoles(ctx, u.ID)
+}
+
+// ListAssignments 获取全部角色分配
+func (s *RBACService) ListAssignments(ctx context.Context) ([]*rbac.Assignment, error) {
+	return s.repo.ListAssignments(ctx)
+}
+
+// Assign 为用户分配角色；actor 为操作者，命令行操作为 nil
+// 只能分配权限不超出操作者自身权限的角色。
+func (s *RBACService) Assign(ctx context.Context, actor, target *user.User, role string) error {
+	r, err := s.repo.GetRole(ctx, role)
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeCovers(ctx, actor, rbac.NewPermissionSet(r)); err != nil {
+		return err
+	}
+	grantedBy := ""
+	if actor != nil {
+		grantedBy = actor.ID
+	}
+	if err := s.repo.Assign(ctx, &rbac.Assignment{
+		UserID:    target.ID,
+		Role:      role,
+		GrantedBy: grantedBy,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return err
+	}
+	logger.Ctx(ctx, s.logger).Info("role assigned",
+		zap.String("username", target.Username),
+		zap.String("role", role),
+		zap.String("granted_by", grantedBy))
+	return nil
+}
+
+// Revoke 撤销用户的角色；actor 为操作者，命令行操作为 nil，只能撤销权限不高于自己的用户的角色
+// 未配置引导管理员时，不允许撤销最后一个 superadmin，避免无人能管理角色。
+func (s *RBACService) Revoke(ctx context.Context, actor, target *user.User, role string) error {
+	if err := s.AuthorizeTarget(ctx, actor, target); err != nil {
+		return err
+	}
+	if role == rbac.RoleSuperadmin && len(s.bootstrap) == 0 {
+		count, err := s.repo.CountMembers(ctx, rbac.RoleSuperadmin)
+		if err != nil {
+			return err
+		}
+		if count <= 1 {
+			return rbac.ErrLastSuperadmin
+		}
+	}
+	if err := s.repo.Revoke(ctx, target.ID, role); err != nil {
+		return err
+	}
+	logger.Ctx(ctx, s.logger).Info("role revoked",
+		zap.String("username", target.Username),
+		zap.String("role", role))
+	return nil
+}
+
+// SaveRole 创建或更新自定义角色；角色权限不能超出操作者自身的权限，actor 为 nil 表示命令行操作
+func (s *RBACService) SaveRole(ctx context.Context, actor *user.User, name, description string, permissions []string) (*rbac.Role, error) {
+	role, err := rbac.NewRole(name, description, permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeCovers(ctx, actor, rbac.NewPermissionSet(role)); err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.GetRole(ctx, role.Name)
+	switch {
+	case errors.Is(err, rbac.ErrRoleNotFound):
+		if err := s.repo.CreateRole(ctx, role); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case existing.BuiltIn:
+		return nil, rbac.ErrBuiltInRole
+	default:
+		// 更新会改变所有持有该角色的用户的权限，原有权限同样不能超出操作者自身的权限
+		if err := s.authorizeCovers(ctx, actor, rbac.NewPermissionSet(existing)); err != nil {
+			return nil, err
+		}
+		role.CreatedAt = existing.CreatedAt
+		if err := s.repo.UpdateRole(ctx, role); err != nil {
+			return nil, err
+		}
+	}
+	logger.Ctx(ctx, s.logger).Info("role saved",
+		zap.String("role", role.Name),
+		zap.Int("permissions", len(role.Permissions)))
+	return role, nil
+}
+
+// DeleteRole 删除自定义角色；角色权限不能超出操作者自身的权限，actor 为 nil 表示命令行操作
+func (s *RBACService) DeleteRole(ctx context.Context, actor *user.User, name string) error {
+	role, err := s.repo.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role.BuiltIn {
+		return rbac.ErrBuiltInRole
+	}
+	if err := s.authorizeCovers(ctx, actor, rbac.NewPermissionSet(role)); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteRole(ctx, name); err != nil {
+		return err
+	}
+	logger.Ctx(ctx, s.logger).Info("role deleted", zap.String("role", name))
+	return nil
+}
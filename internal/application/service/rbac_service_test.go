@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"go.uber.org/zap"
+)
+
+func TestRBACServiceAuthorize(t *testing.T) {
+	ctx := context.Background()
+	repo := newStubRoleRepo()
+	const bootstrapWallet = "0x742d35cc6634c0532925a3b844bc9e7595f0beb0"
+	s := NewRBACService(repo, config.SecurityConfig{AdminAddresses: []string{bootstrapWallet}}, zap.NewNop())
+	if err := s.EnsureBuiltinRoles(ctx); err != nil {
+		t.Fatalf("EnsureBuiltinRoles failed: %v", err)
+	}
+
+	// 配置中的钱包地址始终是 superadmin
+	owner := &user.User{ID: "u-owner", Username: "owner", WalletAddress: bootstrapWallet}
+	if err := s.Authorize(ctx, owner, rbac.PermRolesManage); err != nil {
+		t.Fatalf("bootstrap admin should have every permission: %v", err)
+	}
+
+	// 邮箱用户通过数据库角色获得权限
+	support := &user.User{ID: "u-support", Username: "support", Email: "support@example.com"}
+	if s.IsAdmin(ctx, support) {
+		t.Fatalf("user without roles should not be admin")
+	}
+	if err := s.Assign(ctx, owner, support, rbac.RoleSupport); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if err := s.Authorize(ctx, support, rbac.PermUsersRead); err != nil {
+		t.Fatalf("support should read users: %v", err)
+	}
+	if err := s.Authorize(ctx, support, rbac.PermUsersWrite); !errors.Is(err, rbac.ErrPermissionDenied) {
+		t.Fatalf("support should be read-only, got %v", err)
+	}
+	if err := s.Assign(ctx, nil, support, "missing"); !errors.Is(err, rbac.ErrRoleNotFound) {
+		t.Fatalf("expected ErrRoleNotFound, got %v", err)
+	}
+
+	if _, err := s.SaveRole(ctx, nil, rbac.RoleAuditor, "", nil); !errors.Is(err, rbac.ErrBuiltInRole) {
+		t.Fatalf("built-in roles should not be editable, got %v", err)
+	}
+	if err := s.DeleteRole(ctx, nil, rbac.RoleSupport); !errors.Is(err, rbac.ErrBuiltInRole) {
+		t.Fatalf("built-in roles should not be deletable, got %v", err)
+	}
+}
+
+func TestRBACServiceKeepsLastSuperadmin(t *testing.T) {
+	ctx := context.Background()
+	s := NewRBACService(newStubRoleRepo(), config.SecurityConfig{}, zap.NewNop())
+	if err := s.EnsureBuiltinRoles(ctx); err != nil {
+		t.Fatalf("EnsureBuiltinRoles failed: %v", err)
+	}
+	alice := &user.User{ID: "u-alice", Username: "alice"}
+	bob := &user.User{ID: "u-bob", Username: "bob"}
+	_ = s.Assign(ctx, nil, alice, rbac.RoleSuperadmin)
+	_ = s.Assign(ctx, nil, bob, rbac.RoleSuperadmin)
+
+	if err := s.Revoke(ctx, nil, bob, rbac.RoleSuperadmin); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if err := s.Revoke(ctx, nil, alice, rbac.RoleSuperadmin); !errors.Is(err, rbac.ErrLastSuperadmin) {
+		t.Fatalf("expected ErrLastSuperadmin, got %v", err)
+	}
+}
+
+func TestRBACServicePreventsPrivilegeEscalation(t *testing.T) {
+	ctx := context.Background()
+	s := NewRBACService(newStubRoleRepo(), config.SecurityConfig{}, zap.NewNop())
+	if err := s.EnsureBuiltinRoles(ctx); err != nil {
+		t.Fatalf("EnsureBuiltinRoles failed: %v", err)
+	}
+	root := &user.User{ID: "u-root", Username: "root"}
+	delegator := &user.User{ID: "u-delegator", Username: "delegator"}
+	helper := &user.User{ID: "u-helper", Username: "helper"}
+	if err := s.Assign(ctx, nil, root, rbac.RoleSuperadmin); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if _, err := s.SaveRole(ctx, root, "delegator", "", []string{"users.read", "roles.read", "roles.assign", "mail.read"}); err != nil {
+		t.Fatalf("SaveRole failed: %v", err)
+	}
+	if err := s.Assign(ctx, root, delegator, "delegator"); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+
+	// 只能分配权限不超出自己的角色
+	if err := s.Assign(ctx, delegator, delegator, rbac.RoleSuperadmin); !errors.Is(err, rbac.ErrPrivilegeEscalation) {
+		t.Fatalf("granting superadmin should be refused, got %v", err)
+	}
+	if err := s.Assign(ctx, delegator, helper, rbac.RoleUserManager); !errors.Is(err, rbac.ErrPrivilegeEscalation) {
+		t.Fatalf("granting user-manager should be refused, got %v", err)
+	}
+	if err := s.Assign(ctx, delegator, helper, rbac.RoleSupport); err != nil {
+		t.Fatalf("granting a subset role should succeed: %v", err)
+	}
+	if _, err := s.SaveRole(ctx, delegator, "delegator", "", []string{"roles.assign", "users.security"}); !errors.Is(err, rbac.ErrPrivilegeEscalation) {
+		t.Fatalf("widening a role beyond own permissions should be refused, got %v", err)
+	}
+
+	// 只能操作权限不高于自己的用户
+	if err := s.AuthorizeTarget(ctx, delegator, root); !errors.Is(err, rbac.ErrPrivilegeEscalation) {
+		t.Fatalf("acting on a superadmin should be refused, got %v", err)
+	}
+	if err := s.Revoke(ctx, delegator, root, rbac.RoleSuperadmin); !errors.Is(err, rbac.ErrPrivilegeEscalation) {
+		t.Fatalf("revoking from a superadmin should be refused, got %v", err)
+	}
+	if err := s.AuthorizeTarget(ctx, delegator, helper); err != nil {
+		t.Fatalf("acting on a less privileged user should succeed: %v", err)
+	}
+	if err := s.AuthorizeTarget(ctx, root, delegator); err != nil {
+		t.Fatalf("superadmin should act on anyone: %v", err)
+	}
+
+	// 角色管理员不能删除或改写权限超出自己的角色
+	roleAdmin := &user.User{ID: "u-role-admin", Username: "role-admin"}
+	if _, err := s.SaveRole(ctx, root, "role-admin", "", []string{"roles.read", "roles.manage"}); err != nil {
+		t.Fatalf("SaveRole failed: %v", err)
+	}
+	if err := s.Assign(ctx, root, roleAdmin, "role-admin"); err != nil {
+		t.Fatalf("Assign failed: %v", err)
+	}
+	if _, err := s.SaveRole(ctx, root, "security", "", []string{"users.read", "users.security"}); err != nil {
+		t.Fatalf("SaveRole failed: %v", err)
+	}
+	if err := s.DeleteRole(ctx, roleAdmin, "security"); !errors.Is(err, rbac.ErrPrivilegeEscalation) {
+		t.Fatalf("deleting a role beyond own permissions should be refused, got %v", err)
+	}
+	if _, err := s.SaveRole(ctx, roleAdmin, "security", "", []string{"roles.read"}); !errors.Is(err, rbac.ErrPrivilegeEscalation) {
+		t.Fatalf("narrowing a role beyond own permissions should be refused, got %v", err)
+	}
+	if _, err := s.SaveRole(ctx, roleAdmin, "readers", "", []string{"roles.read"}); err != nil {
+		t.Fatalf("SaveRole failed: %v", err)
+	}
+	if err := s.DeleteRole(ctx, roleAdmin, "readers"); err != nil {
+		t.Fatalf("deleting a covered role should succeed: %v", err)
+	}
+}
+
+type stubRoleRepo struct {
+	roles       map[string]*rbac.Role
+	assignments []*rbac.Assignment
+}
+
+func newStubRoleRepo() *stubRoleRepo {
+	return &stubRoleRepo{roles: make(map[string]*rbac.Role)}
+}
+
+func (r *stubRoleRepo) ListRoles(ctx context.Context) ([]*rbac.Role, error) {
+	var out []*rbac.Role
+	for _, role := range r.roles {
+		out = append(out, role)
+	}
+	return out, nil
+}
+
+func (r *stubRoleRepo) GetRole(ctx context.Context, name string) (*rbac.Role, error) {
+	role, ok := r.roles[name]
+	if !ok {
+		return nil, rbac.ErrRoleNotFound
+	}
+	return role, nil
+}
+
+func (r *stubRoleRepo) CreateRole(ctx context.Context, role *rbac.Role) error {
+	if _, ok := r.roles[role.Name]; ok {
+		return rbac.ErrRoleExists
+	}
+	r.roles[role.Name] = role
+	return nil
+}
+
+func (r *stubRoleRepo) UpdateRole(ctx context.Context, role *rbac.Role) error {
+	r.roles[role.Name] = role
+	return nil
+}
+
+func (r *stubRoleRepo) SaveBuiltinRole(ctx context.Context, role *rbac.Role) error {
+	r.roles[role.Name] = role
+	return nil
+}
+
+func (r *stubRoleRepo) DeleteRole(ctx context.Context, name string) error {
+	delete(r.roles, name)
+	return nil
+}
+
+func (r *stubRoleRepo) ListUserRoles(ctx context.Context, userID string) ([]*rbac.Role, error) {
+	var out []*rbac.Role
+	for _, a := range r.assignments {
+		if a.UserID == userID {
+			out = append(out, r.roles[a.Role])
+		}
+	}
+	return out, nil
+}
+
+func (r *stubRoleRepo) ListAssignments(ctx context.Context) ([]*rbac.Assignment, error) {
+	return r.assignments, nil
+}
+
+func (r *stubRoleRepo) Assign(ctx context.Context, a *rbac.Assignment) error {
+	r.assignments = append(r.assignments, a)
+	return nil
+}
+
+func (r *stubRoleRepo) Revoke(ctx context.Context, userID, role string) error {
+	for i, a := range r.assignments {
+		if a.UserID == userID && a.Role == role {
+			r.assignments = append(r.assignments[:i], r.assignments[i+1:]...)
+			return nil
+		}
+	}
+	return rbac.ErrAssignmentNotFound
+}
+
+func (r *stubRoleRepo) CountMembers(ctx context.Context, role string) (int, error) {
+	count := 0
+	for _, a := range r.assignments {
+		if a.Role == role {
+			count++
+		}
+	}
+	return count, nil
+}
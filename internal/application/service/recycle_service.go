@@ -14,6 +14,7 @@ import (
 	"github.com/yeying-community/warehouse/internal/domain/recycle"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
 	"go.uber.org/zap"
 )
@@ -22,6 +23,8 @@ import (
 type RecycleService struct {
 	recycleRepo repository.RecycleRepository
 	userRepo    user.Repository
+	contentHash *ContentHashService
+	storage     *StorageService
 	config      *config.Config
 	logger      *zap.Logger
 }
@@ -30,12 +33,16 @@ type RecycleService struct {
 func NewRecycleService(
 	recycleRepo repository.RecycleRepository,
 	userRepo user.Repository,
+	contentHash *ContentHashService,
+	storage *StorageService,
 	cfg *config.Config,
 	logger *zap.Logger,
 ) *RecycleService {
 	return &RecycleService{
 		recycleRepo: recycleRepo,
 		userRepo:    userRepo,
+		contentHash: contentHash,
+		storage:     storage,
 		config:      cfg,
 		logger:      logger,
 	}
@@ -43,13 +50,14 @@ func NewRecycleService(
 
 // RecycleItemResponse 回收站项目响应
 type RecycleItemResponse struct {
-	Hash      string `json:"hash"`
-	Name      string `json:"name"`
-	Path      string `json:"path"`
-	Size      int64  `json:"size"`
-	DeletedAt string `json:"deletedAt"`
-	Directory string `json:"directory"`
-	IsDir     bool   `json:"isDir"`
+	Hash        string `json:"hash"`
+	ContentHash string `json:"contentHash,omitempty"`
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Size        int64  `json:"size"`
+	DeletedAt   string `json:"deletedAt"`
+	Directory   string `json:"directory"`
+	IsDir       bool   `json:"isDir"`
 }
 
 // ListResponse 列表响应
@@ -64,9 +72,13 @@ func (s *RecycleService) AddToRecycle(
 	filePath string, // 相对于用户目录的路径
 	directory string, // 目录名
 ) error {
-	// 获取文件信息
-	fullPath := filepath.Join(s.getUserRootDir(u), directory, filePath)
-	info, err := os.Stat(fullPath)
+	// 获取文件信息（加密文件为明文大小）
+	fsys, err := s.storage.FileSystem(ctx, u, s.getUserRootDir(u))
+	if err != nil {
+		return err
+	}
+	relPath := filepath.Join(directory, filePath)
+	info, err := fsys.Stat(ctx, relPath)
 	if err != nil {
 		return fmt.Errorf("failed to stat file: %w", err)
 	}
@@ -78,8 +90,14 @@ func (s *RecycleService) AddToRecycle(
 	// 提取文件名
 	name := filepath.Base(filePath)
 
+	// 计算内容哈希
+	contentHash := ""
+	if meta, err := s.contentHash.Ensure(ctx, u.ID, relPath, fsys); err == nil {
+		contentHash = meta.SHA256
+	}
+
 	// 创建回收站项目
-	item := recycle.NewRecycleItem(u.ID, u.Username, directory, name, filePath, info.Size())
+	item := recycle.NewRecycleItem(u.ID, u.Username, directory, name, filePath, info.Size(), contentHash)
 
 	// 保存到数据库
 	if err := s.recycleRepo.Create(ctx, item); err != nil {
@@ -121,13 +139,14 @@ func (s *RecycleService) List(ctx context.Context, u *user.User) (*ListResponse,
 			}
 		}
 		response.Items = append(response.Items, &RecycleItemResponse{
-			Hash:      item.Hash,
-			Name:      item.Name,
-			Path:      item.Path,
-			Size:      item.Size,
-			DeletedAt: item.DeletedAt.Format("2006-01-02T15:04:05Z07:00"),
-			Directory: item.Directory,
-			IsDir:     isDir,
+			Hash:        item.Hash,
+			ContentHash: item.ContentHash,
+			Name:        item.Name,
+			Path:        item.Path,
+			Size:        item.Size,
+			DeletedAt:   item.DeletedAt.Format("2006-01-02T15:04:05Z07:00"),
+			Directory:   item.Directory,
+			IsDir:       isDir,
 		})
 	}
 
@@ -176,7 +195,16 @@ func (s *RecycleService) Recover(ctx context.Context, u *user.User, hash string)
 		return fmt.Errorf("failed to restore file: %w", err)
 	}
 
-	s.logger.Info("recovering file",
+	// 重命名不改变内容与修改时间，直接恢复内容哈希记录
+	if item.ContentHash != "" {
+		if info, err := os.Stat(fullPath); err == nil {
+			if err := s.contentHash.Record(ctx, u.ID, relPath, item.ContentHash, info); err != nil {
+				logger.Ctx(ctx, s.logger).Warn("failed to restore content hash", zap.Error(err))
+			}
+		}
+	}
+
+	logger.Ctx(ctx, s.logger).Info("recovering file",
 		zap.String("username", u.Username),
 		zap.String("file", item.Path),
 		zap.String("hash", hash),
@@ -220,7 +248,7 @@ func (s *RecycleService) Remove(ctx context.Context, u *user.User, hash string)
 		return fmt.Errorf("failed to remove from recycle bin: %w", err)
 	}
 
-	s.logger.Info("file permanently deleted from recycle bin",
+	logger.Ctx(ctx, s.logger).Info("file permanently deleted from recycle bin",
 		zap.String("username", u.Username),
 		zap.String("file", item.Path),
 		zap.String("hash", hash),
@@ -350,7 +378,7 @@ func (s *RecycleService) CleanExpired(ctx context.Context, retentionDays int) (i
 	}
 
 	if deleted > 0 {
-		s.logger.Info("cleaned expired recycle items",
+		logger.Ctx(ctx, s.logger).Info("cleaned expired recycle items",
 			zap.Int64("count", deleted),
 			zap.Duration("retention_period", retentionPeriod),
 		)
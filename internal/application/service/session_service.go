@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"go.uber.org/zap"
+)
+
+// sessionCleanupInterval 过期会话清理间隔
+const sessionCleanupInterval = time.Hour
+
+// SessionService 登录会话管理服务
+type SessionService struct {
+	repo     repository.SessionRepository
+	userRepo user.Repository
+	logger   *zap.Logger
+
+	worker   workerState
+	stopOnce sync.Once
+	started  bool
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewSessionService 创建登录会话管理服务
+func NewSessionService(repo repository.SessionRepository, userRepo user.Repository, logger *zap.Logger) *SessionService {
+	return &SessionService{
+		repo:     repo,
+		userRepo: userRepo,
+		logger:   logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// List 获取用户的有效会话
+func (s *SessionService) List(ctx context.Context, u *user.User) ([]*session.Session, error) {
+	return s.repo.ListActiveByUser(ctx, u.ID)
+}
+
+// Get 获取用户自己的某个会话
+func (s *SessionService) Get(ctx context.Context, u *user.User, id string) (*session.Session, error) {
+	item, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if item.UserID != u.ID {
+		return nil, session.ErrSessionNotFound
+	}
+	return item, nil
+}
+
+// Revoke 吊销用户自己的某个会话
+func (s *SessionService) Revoke(ctx context.Context, u *user.User, id string) error {
+	item, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if item.UserID != u.ID {
+		return session.ErrSessionNotFound
+	}
+	if err := s.repo.Revoke(ctx, id, session.ReasonRevoked); err != nil {
+		return err
+	}
+	logger.Ctx(ctx, s.logger).Info("session revoked",
+		zap.String("username", u.Username),
+		zap.String("session_id", id))
+	return nil
+}
+
+// RevokeOthers 吊销用户除当前会话外的全部会话
+func (s *SessionService) RevokeOthers(ctx context.Context, u *user.User, currentID string) (int64, error) {
+	count, err := s.repo.RevokeByUser(ctx, u.ID, currentID, session.ReasonRevoked)
+	if err != nil {
+		return 0, err
+	}
+	logger.Ctx(ctx, s.logger).Info("other sessions revoked",
+		zap.String("username", u.Username),
+		zap.Int64("count", count))
+	return count, nil
+}
+
+// RevokeAll 管理员吊销指定用户的全部会话（在所有设备上退出登录）
+func (s *SessionService) RevokeAll(ctx context.Context, username string) (int64, error) {
+	u, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return 0, err
+	}
+	count, err := s.repo.RevokeByUser(ctx, u.ID, "", session.ReasonAdmin)
+	if err != nil {
+		return 0, err
+	}
+	logger.Ctx(ctx, s.logger).Info("all sessions revoked by admin",
+		zap.String("username", u.Username),
+		zap.Int64("count", count))
+	return count, nil
+}
+
+// CleanupExpired 删除已过期的会话
+func (s *SessionService) CleanupExpired(ctx context.Context) (int64, error) {
+	deleted, err := s.repo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		logger.Ctx(ctx, s.logger).Info("expired sessions cleaned up", zap.Int64("deleted", deleted))
+	}
+	return deleted, nil
+}
+
+// Start 启动后台过期会话清理
+func (s *SessionService) Start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(sessionCleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				_, err := s.CleanupExpired(context.Background())
+				if err != nil {
+					s.logger.Error("session cleanup failed", zap.Error(err))
+				}
+				s.worker.record(err)
+			}
+		}
+	}()
+}
+
+// Status 返回后台清理任务状态
+func (s *SessionService) Status() WorkerStatus {
+	return s.worker.status("session_cleanup", true, s.started, s.done)
+}
+
+// Stop 停止后台清理
+func (s *SessionService) Stop() {
+	if s == nil || !s.started {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
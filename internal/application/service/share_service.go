@@ -10,38 +10,59 @@ import (
 	"strings"
 	"time"
 
+	"github.com/yeying-community/warehouse/internal/domain/quota"
 	"github.com/yeying-community/warehouse/internal/domain/share"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
 	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
 )
 
 // ShareService 文件分享服务
 type ShareService struct {
-	shareRepo repository.ShareRepository
-	userRepo  user.Repository
-	config    *config.Config
-	logger    *zap.Logger
+	shareRepo    repository.ShareRepository
+	userRepo     user.Repository
+	contentHash  *ContentHashService
+	storage      *StorageService
+	e2ee         *E2EEService
+	tokenGate    *TokenGateService
+	quotaService quota.Service
+	config       *config.Config
+	logger       *zap.Logger
 }
 
 // NewShareService 创建分享服务
 func NewShareService(
 	shareRepo repository.ShareRepository,
 	userRepo user.Repository,
+	contentHash *ContentHashService,
+	storage *StorageService,
+	e2eeService *E2EEService,
+	tokenGate *TokenGateService,
+	quotaService quota.Service,
 	cfg *config.Config,
 	logger *zap.Logger,
 ) *ShareService {
 	return &ShareService{
-		shareRepo: shareRepo,
-		userRepo:  userRepo,
-		config:    cfg,
-		logger:    logger,
+		shareRepo:    shareRepo,
+		userRepo:     userRepo,
+		contentHash:  contentHash,
+		storage:      storage,
+		e2ee:         e2eeService,
+		tokenGate:    tokenGate,
+		quotaService: quotaService,
+		config:       cfg,
+		logger:       logger,
 	}
 }
 
 // Create 创建分享链接
-func (s *ShareService) Create(ctx context.Context, u *user.User, rawPath string, expiresIn int64) (*share.ShareItem, error) {
+// condition 非空时，访问者需以满足链上条件的钱包登录后才能访问。
+func (s *ShareService) Create(ctx context.Context, u *user.User, rawPath string, expiresIn int64, condition *tokengate.Condition) (*share.ShareItem, error) {
 	cleanPath, err := normalizeSharePath(rawPath, s.webdavPrefix())
 	if err != nil {
 		return nil, err
@@ -49,6 +70,13 @@ func (s *ShareService) Create(ctx context.Context, u *user.User, rawPath string,
 	if err := enforceAppScope(ctx, s.config, cleanPath, "create"); err != nil {
 		return nil, err
 	}
+	// 公开链接面向无密钥的访问者（浏览器内预览），不能用于端到端加密目录
+	if err := s.e2ee.EnsurePlaintextAllowed(ctx, u.ID, cleanPath); err != nil {
+		return nil, err
+	}
+	if err := s.tokenGate.ValidateCondition(condition); err != nil {
+		return nil, err
+	}
 
 	fullPath := s.resolveFullPath(u, cleanPath)
 	info, err := os.Stat(fullPath)
@@ -56,7 +84,11 @@ func (s *ShareService) Create(ctx context.Context, u *user.User, rawPath string,
 		return nil, fmt.Errorf("failed to stat file: %w", err)
 	}
 	if info.IsDir() {
-		return nil, fmt.Errorf("directory sharing not supported")
+		return nil, share.ErrDirectoryShare
+	}
+	fsys, err := s.storage.FileSystem(ctx, u, s.getUserRootDir(u))
+	if err != nil {
+		return nil, err
 	}
 
 	name := filepath.Base(fullPath)
@@ -67,11 +99,15 @@ func (s *ShareService) Create(ctx context.Context, u *user.User, rawPath string,
 	}
 
 	item := share.NewShareItem(u.ID, u.Username, cleanPath, name, expiresAt)
+	item.Condition = condition
 	if err := s.shareRepo.Create(ctx, item); err != nil {
 		return nil, err
 	}
+	if meta, err := s.contentHash.Ensure(ctx, u.ID, cleanPath, fsys); err == nil {
+		item.ContentHash = meta.SHA256
+	}
 
-	s.logger.Info("share created",
+	logger.Ctx(ctx, s.logger).Info("share created",
 		zap.String("username", u.Username),
 		zap.String("path", cleanPath),
 		zap.String("token", item.Token),
@@ -94,13 +130,14 @@ func (s *ShareService) List(ctx context.Context, u *user.User) ([]*share.ShareIt
 		for _, item := range items {
 			normalized, err := s.normalizeItemPath(item.Path)
 			if err != nil {
-				s.logger.Warn("invalid share path",
+				logger.Ctx(ctx, s.logger).Warn("invalid share path",
 					zap.String("username", u.Username),
 					zap.String("path", item.Path),
 					zap.Error(err))
 				continue
 			}
 			item.Path = normalized
+			s.fillContentHash(ctx, u, item)
 		}
 		return items, nil
 	}
@@ -108,7 +145,7 @@ func (s *ShareService) List(ctx context.Context, u *user.User) ([]*share.ShareIt
 	for _, item := range items {
 		normalized, err := s.normalizeItemPath(item.Path)
 		if err != nil {
-			s.logger.Warn("invalid share path",
+			logger.Ctx(ctx, s.logger).Warn("invalid share path",
 				zap.String("username", u.Username),
 				zap.String("path", item.Path),
 				zap.Error(err))
@@ -116,6 +153,7 @@ func (s *ShareService) List(ctx context.Context, u *user.User) ([]*share.ShareIt
 		}
 		item.Path = normalized
 		if scope.allowsAny(normalized, "read") {
+			s.fillContentHash(ctx, u, item)
 			filtered = append(filtered, item)
 		}
 	}
@@ -129,7 +167,7 @@ func (s *ShareService) Revoke(ctx context.Context, u *user.User, token string) e
 		return err
 	}
 	if item.UserID != u.ID {
-		return fmt.Errorf("permission denied: not your share")
+		return share.ErrNotOwner
 	}
 	normalized, err := s.normalizeItemPath(item.Path)
 	if err != nil {
@@ -153,7 +191,9 @@ func (s *ShareService) IncrementDownload(ctx context.Context, token string) erro
 }
 
 // Resolve 根据 token 获取分享文件
-func (s *ShareService) Resolve(ctx context.Context, token string) (*share.ShareItem, *os.File, os.FileInfo, error) {
+// 返回的文件按需解密，支持 Seek，可直接用于 http.ServeContent。
+// caller 为可选的已登录访问者，用于校验分享的链上访问条件。
+func (s *ShareService) Resolve(ctx context.Context, token string, caller *user.User) (*share.ShareItem, webdav.File, os.FileInfo, error) {
 	item, err := s.shareRepo.GetByToken(ctx, token)
 	if err != nil {
 		return nil, nil, nil, err
@@ -161,6 +201,9 @@ func (s *ShareService) Resolve(ctx context.Context, token string) (*share.ShareI
 	if item.IsExpired() {
 		return nil, nil, nil, share.ErrShareExpired
 	}
+	if err := s.checkCondition(ctx, item, caller); err != nil {
+		return nil, nil, nil, err
+	}
 
 	u, err := s.userRepo.FindByID(ctx, item.UserID)
 	if err != nil {
@@ -172,8 +215,11 @@ func (s *ShareService) Resolve(ctx context.Context, token string) (*share.ShareI
 		return nil, nil, nil, share.ErrInvalidShare
 	}
 	item.Path = normalized
-	fullPath := s.resolveFullPath(u, normalized)
-	f, err := os.Open(fullPath)
+	fsys, err := s.storage.FileSystem(ctx, u, s.getUserRootDir(u))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	f, err := fsys.OpenFile(ctx, normalized, os.O_RDONLY, 0)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -186,9 +232,133 @@ func (s *ShareService) Resolve(ctx context.Context, token string) (*share.ShareI
 		f.Close()
 		return nil, nil, nil, share.ErrInvalidShare
 	}
+	item.ContentHash = webdavfs.ContentHashOf(info)
 	return item, f, info, nil
 }
 
+// checkCondition 校验访问者是否满足分享的链上条件（分享者本人不受限制）
+func (s *ShareService) checkCondition(ctx context.Context, item *share.ShareItem, caller *user.User) error {
+	if item.Condition == nil {
+		return nil
+	}
+	if caller != nil && caller.ID == item.UserID {
+		return nil
+	}
+	if caller == nil || caller.WalletAddress == "" {
+		return tokengate.ErrWalletRequired
+	}
+	return s.tokenGate.Check(ctx, item.Condition, caller.WalletAddress)
+}
+
+// fillContentHash 填充分享文件的内容哈希（仅使用已记录且未过期的哈希）
+func (s *ShareService) fillContentHash(ctx context.Context, u *user.User, item *share.ShareItem) {
+	info, err := os.Stat(s.resolveFullPath(u, item.Path))
+	if err != nil {
+		return
+	}
+	if meta, err := s.contentHash.Lookup(ctx, u.ID, item.Path, info); err == nil {
+		item.ContentHash = meta.SHA256
+	}
+}
+
+// SaveToDrive 将分享文件保存到当前用户空间，返回保存后的路径
+// 启用去重存储时只创建内容引用，不复制文件数据；启用静态加密时以目标用户的密钥重新加密。
+func (s *ShareService) SaveToDrive(ctx context.Context, u *user.User, token string, rawTarget string) (string, error) {
+	item, err := s.shareRepo.GetByToken(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	if item.IsExpired() {
+		return "", share.ErrShareExpired
+	}
+	if err := s.checkCondition(ctx, item, u); err != nil {
+		return "", err
+	}
+	owner, err := s.userRepo.FindByID(ctx, item.UserID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+	sourcePath, err := s.normalizeItemPath(item.Path)
+	if err != nil {
+		return "", share.ErrInvalidShare
+	}
+	sourceFS, err := s.storage.FileSystem(ctx, owner, s.getUserRootDir(owner))
+	if err != nil {
+		return "", err
+	}
+	sourceInfo, err := sourceFS.Stat(ctx, sourcePath)
+	if err != nil {
+		return "", err
+	}
+	if sourceInfo.IsDir() {
+		return "", share.ErrInvalidShare
+	}
+
+	// 目标为已存在目录时，保存到该目录下并沿用分享文件名
+	targetPath, err := s.normalizeItemPath(rawTarget)
+	if err != nil {
+		return "", err
+	}
+	targetFull := s.resolveFullPath(u, targetPath)
+	if info, err := os.Stat(targetFull); err == nil {
+		if !info.IsDir() {
+			return "", share.ErrTargetExists
+		}
+		targetPath = path.Join(targetPath, item.Name)
+		targetFull = s.resolveFullPath(u, targetPath)
+		if _, err := os.Stat(targetFull); err == nil {
+			return "", share.ErrTargetExists
+		}
+	}
+
+	if err := enforceAppScope(ctx, s.config, targetPath, "create"); err != nil {
+		return "", err
+	}
+	if !u.CanAccess(path.Join("/", s.userDirName(u), targetPath), "create") {
+		return "", fmt.Errorf("%w: create operation on %s", share.ErrPermissionDenied, targetPath)
+	}
+	if !s.storage.Deduplicated() || s.config.WebDAV.Dedup.QuotaPolicy == string(quota.SharedBlobFull) {
+		if err := s.quotaService.CheckQuota(ctx, u, sourceInfo.Size()); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetFull), 0755); err != nil {
+		return "", fmt.Errorf("failed to create target directory: %w", err)
+	}
+	targetFS, err := s.storage.FileSystem(ctx, u, s.getUserRootDir(u))
+	if err != nil {
+		return "", err
+	}
+	if err := webdavfs.CopyFile(ctx, sourceFS, sourcePath, targetFS, targetPath); err != nil {
+		return "", fmt.Errorf("failed to save shared file: %w", err)
+	}
+
+	// 刷新 used_space
+	if used, err := s.quotaService.CalculateUsedSpace(ctx, s.getUserRootDir(u)); err == nil {
+		if err := s.userRepo.UpdateUsedSpace(ctx, u.Username, used); err != nil {
+			logger.Ctx(ctx, s.logger).Error("failed to update used space", zap.Error(err))
+		} else {
+			u.UpdateUsedSpace(used)
+		}
+	}
+
+	logger.Ctx(ctx, s.logger).Info("share saved to drive",
+		zap.String("username", u.Username),
+		zap.String("token", item.Token),
+		zap.String("path", targetPath),
+		zap.Bool("dedup", s.storage.Deduplicated()),
+	)
+	return targetPath, nil
+}
+
+func (s *ShareService) userDirName(u *user.User) string {
+	if u.Directory != "" {
+		return u.Directory
+	}
+	return u.Username
+}
+
 func (s *ShareService) resolveFullPath(u *user.User, sharePath string) string {
 	rel := strings.TrimPrefix(sharePath, "/")
 	rel = filepath.FromSlash(rel)
@@ -221,11 +391,11 @@ func normalizeSharePath(raw string, prefix string) (string, error) {
 	raw = stripWebdavPrefix(raw, prefix)
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
-		return "", fmt.Errorf("path is required")
+		return "", fmt.Errorf("%w: path is required", share.ErrInvalidPath)
 	}
 	clean := path.Clean("/" + strings.TrimLeft(raw, "/"))
 	if clean == "/" || strings.HasPrefix(clean, "/..") {
-		return "", fmt.Errorf("invalid path")
+		return "", share.ErrInvalidPath
 	}
 	clean = strings.TrimSuffix(clean, "/")
 	return clean, nil
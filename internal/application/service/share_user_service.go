@@ -3,18 +3,24 @@ package service
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/yeying-community/warehouse/internal/domain/account"
 	"github.com/yeying-community/warehouse/internal/domain/addressbook"
 	"github.com/yeying-community/warehouse/internal/domain/shareuser"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
 	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
 )
 
 // ShareUserService 定向分享服务
@@ -22,6 +28,10 @@ type ShareUserService struct {
 	repo               repository.UserShareRepository
 	userRepo           user.Repository
 	addressBookService *AddressBookService
+	contentHash        *ContentHashService
+	storage            *StorageService
+	e2ee               *E2EEService
+	tokenGate          *TokenGateService
 	config             *config.Config
 	logger             *zap.Logger
 }
@@ -31,6 +41,10 @@ func NewShareUserService(
 	repo repository.UserShareRepository,
 	userRepo user.Repository,
 	addressBookService *AddressBookService,
+	contentHash *ContentHashService,
+	storage *StorageService,
+	e2eeService *E2EEService,
+	tokenGate *TokenGateService,
 	cfg *config.Config,
 	logger *zap.Logger,
 ) *ShareUserService {
@@ -38,13 +52,19 @@ func NewShareUserService(
 		repo:               repo,
 		userRepo:           userRepo,
 		addressBookService: addressBookService,
+		contentHash:        contentHash,
+		storage:            storage,
+		e2ee:               e2eeService,
+		tokenGate:          tokenGate,
 		config:             cfg,
 		logger:             logger,
 	}
 }
 
 // Create 创建定向分享
-func (s *ShareUserService) Create(ctx context.Context, owner *user.User, targetWallet string, rawPath string, permissions string, expiresIn int64) (*shareuser.ShareUserItem, error) {
+// 分享路径位于端到端加密目录时，keyEnvelope 为以目标钱包公钥加密的目录密钥；目标已有信封时可省略。
+// condition 非空时，目标用户每次访问都需其钱包满足链上条件。
+func (s *ShareUserService) Create(ctx context.Context, owner *user.User, targetWallet string, rawPath string, permissions string, expiresIn int64, keyEnvelope string, condition *tokengate.Condition) (*shareuser.ShareUserItem, error) {
 	cleanPath, err := normalizeSharePath(rawPath, s.webdavPrefix())
 	if err != nil {
 		return nil, err
@@ -52,6 +72,9 @@ func (s *ShareUserService) Create(ctx context.Context, owner *user.User, targetW
 	if err := enforceAppScope(ctx, s.config, cleanPath, "create"); err != nil {
 		return nil, err
 	}
+	if err := s.tokenGate.ValidateCondition(condition); err != nil {
+		return nil, err
+	}
 
 	target, err := s.userRepo.FindByWalletAddress(ctx, targetWallet)
 	if err != nil {
@@ -67,6 +90,11 @@ func (s *ShareUserService) Create(ctx context.Context, owner *user.User, targetW
 	name := filepath.Base(cleanPath)
 	isDir := info.IsDir()
 
+	folder, envelope, err := s.e2ee.PrepareShare(ctx, owner, cleanPath, target.WalletAddress, keyEnvelope)
+	if err != nil {
+		return nil, err
+	}
+
 	var expiresAt *time.Time
 	if expiresIn > 0 {
 		t := time.Now().Add(time.Duration(expiresIn) * time.Second)
@@ -84,13 +112,18 @@ func (s *ShareUserService) Create(ctx context.Context, owner *user.User, targetW
 		permissions,
 		expiresAt,
 	)
+	item.Condition = condition
 	if err := s.repo.Create(ctx, item); err != nil {
 		return nil, err
 	}
+	if folder != nil {
+		item.E2EEFolderID = folder.ID
+		item.KeyEnvelope = envelope.Envelope
+	}
 
 	s.autoTrackAddress(ctx, owner, target)
 
-	s.logger.Info("share user created",
+	logger.Ctx(ctx, s.logger).Info("share user created",
 		zap.String("owner", owner.Username),
 		zap.String("target", target.WalletAddress),
 		zap.String("path", cleanPath),
@@ -115,7 +148,7 @@ func (s *ShareUserService) autoTrackAddress(ctx context.Context, owner *user.Use
 		if err == addressbook.ErrDuplicateWallet {
 			return
 		}
-		s.logger.Warn("failed to auto track address",
+		logger.Ctx(ctx, s.logger).Warn("failed to auto track address",
 			zap.String("owner", owner.Username),
 			zap.String("target", target.WalletAddress),
 			zap.Error(err),
@@ -123,6 +156,29 @@ func (s *ShareUserService) autoTrackAddress(ctx context.Context, owner *user.Use
 	}
 }
 
+// attachEnvelopes 为位于端到端加密目录中的分享填充目标钱包的密钥信封
+func (s *ShareUserService) attachEnvelopes(ctx context.Context, items []*shareuser.ShareUserItem) {
+	if s.e2ee == nil {
+		return
+	}
+	for _, item := range items {
+		folder, envelope, err := s.e2ee.ShareEnvelope(ctx, item.OwnerUserID, item.Path, item.TargetWalletAddress)
+		if err != nil {
+			logger.Ctx(ctx, s.logger).Warn("failed to load share key envelope",
+				zap.String("share_id", item.ID),
+				zap.Error(err))
+			continue
+		}
+		if folder == nil {
+			continue
+		}
+		item.E2EEFolderID = folder.ID
+		if envelope != nil {
+			item.KeyEnvelope = envelope.Envelope
+		}
+	}
+}
+
 func (s *ShareUserService) webdavPrefix() string {
 	if s == nil || s.config == nil {
 		return ""
@@ -136,6 +192,10 @@ func (s *ShareUserService) normalizeItemPath(raw string) (string, error) {
 
 func shortenWallet(address string) string {
 	trimmed := strings.TrimSpace(address)
+	// 存储键为 CAIP-10，展示名只取地址部分
+	if id, err := account.Parse(trimmed); err == nil {
+		trimmed = id.Address
+	}
 	if len(trimmed) <= 10 {
 		return trimmed
 	}
@@ -156,7 +216,7 @@ func (s *ShareUserService) ListByOwner(ctx context.Context, owner *user.User) ([
 		for _, item := range items {
 			normalized, err := s.normalizeItemPath(item.Path)
 			if err != nil {
-				s.logger.Warn("invalid share user path",
+				logger.Ctx(ctx, s.logger).Warn("invalid share user path",
 					zap.String("owner", owner.Username),
 					zap.String("path", item.Path),
 					zap.Error(err))
@@ -164,13 +224,14 @@ func (s *ShareUserService) ListByOwner(ctx context.Context, owner *user.User) ([
 			}
 			item.Path = normalized
 		}
+		s.attachEnvelopes(ctx, items)
 		return items, nil
 	}
 	filtered := make([]*shareuser.ShareUserItem, 0, len(items))
 	for _, item := range items {
 		normalized, err := s.normalizeItemPath(item.Path)
 		if err != nil {
-			s.logger.Warn("invalid share user path",
+			logger.Ctx(ctx, s.logger).Warn("invalid share user path",
 				zap.String("owner", owner.Username),
 				zap.String("path", item.Path),
 				zap.Error(err))
@@ -181,6 +242,7 @@ func (s *ShareUserService) ListByOwner(ctx context.Context, owner *user.User) ([
 			filtered = append(filtered, item)
 		}
 	}
+	s.attachEnvelopes(ctx, filtered)
 	return filtered, nil
 }
 
@@ -198,7 +260,7 @@ func (s *ShareUserService) ListByTarget(ctx context.Context, target *user.User)
 		for _, item := range items {
 			normalized, err := s.normalizeItemPath(item.Path)
 			if err != nil {
-				s.logger.Warn("invalid share user path",
+				logger.Ctx(ctx, s.logger).Warn("invalid share user path",
 					zap.String("target", target.Username),
 					zap.String("path", item.Path),
 					zap.Error(err))
@@ -206,13 +268,14 @@ func (s *ShareUserService) ListByTarget(ctx context.Context, target *user.User)
 			}
 			item.Path = normalized
 		}
+		s.attachEnvelopes(ctx, items)
 		return items, nil
 	}
 	filtered := make([]*shareuser.ShareUserItem, 0, len(items))
 	for _, item := range items {
 		normalized, err := s.normalizeItemPath(item.Path)
 		if err != nil {
-			s.logger.Warn("invalid share user path",
+			logger.Ctx(ctx, s.logger).Warn("invalid share user path",
 				zap.String("target", target.Username),
 				zap.String("path", item.Path),
 				zap.Error(err))
@@ -223,6 +286,7 @@ func (s *ShareUserService) ListByTarget(ctx context.Context, target *user.User)
 			filtered = append(filtered, item)
 		}
 	}
+	s.attachEnvelopes(ctx, filtered)
 	return filtered, nil
 }
 
@@ -253,6 +317,12 @@ func (s *ShareUserService) ResolveForTarget(ctx context.Context, target *user.Us
 	if item.TargetUserID != target.ID && item.OwnerUserID != target.ID {
 		return nil, nil, fmt.Errorf("permission denied: not your share")
 	}
+	// 链上访问条件只约束目标用户，分享者本人不受限制
+	if item.Condition != nil && item.OwnerUserID != target.ID {
+		if err := s.tokenGate.Check(ctx, item.Condition, target.WalletAddress); err != nil {
+			return nil, nil, err
+		}
+	}
 	normalized, err := s.normalizeItemPath(item.Path)
 	if err != nil {
 		return nil, nil, err
@@ -265,6 +335,7 @@ func (s *ShareUserService) ResolveForTarget(ctx context.Context, target *user.Us
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get owner: %w", err)
 	}
+	s.attachEnvelopes(ctx, []*shareuser.ShareUserItem{item})
 	return item, owner, nil
 }
 
@@ -310,6 +381,74 @@ func (s *ShareUserService) ResolveSharePath(owner *user.User, item *shareuser.Sh
 	return baseFull, targetFull, nil
 }
 
+// Stat 获取分享内文件信息（加密文件为明文大小，附带已记录的内容哈希）
+func (s *ShareUserService) Stat(ctx context.Context, owner *user.User, fullPath string) (os.FileInfo, error) {
+	fsys, name, err := s.ownerFileSystem(ctx, owner, fullPath)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.Stat(ctx, name)
+}
+
+// ReadDir 读取分享内目录
+func (s *ShareUserService) ReadDir(ctx context.Context, owner *user.User, fullPath string) ([]os.FileInfo, error) {
+	fsys, name, err := s.ownerFileSystem(ctx, owner, fullPath)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.ReadDir(ctx, name)
+}
+
+// OpenFile 打开分享内文件用于读取（按需解密，支持 Seek）
+func (s *ShareUserService) OpenFile(ctx context.Context, owner *user.User, fullPath string) (webdav.File, error) {
+	fsys, name, err := s.ownerFileSystem(ctx, owner, fullPath)
+	if err != nil {
+		return nil, err
+	}
+	return fsys.OpenFile(ctx, name, os.O_RDONLY, 0)
+}
+
+// WriteFile 写入分享内文件，同步记录内容哈希并按需加密
+func (s *ShareUserService) WriteFile(ctx context.Context, owner *user.User, fullPath string, r io.Reader) error {
+	fsys, name, err := s.ownerFileSystem(ctx, owner, fullPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	dst, err := fsys.OpenFile(ctx, name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// ownerFileSystem 返回所有者的存储及 fullPath 在其中的路径
+func (s *ShareUserService) ownerFileSystem(ctx context.Context, owner *user.User, fullPath string) (*webdavfs.UnicodeFileSystem, string, error) {
+	relPath, ok := s.ownerRelativePath(owner, fullPath)
+	if !ok {
+		return nil, "", fmt.Errorf("invalid share path")
+	}
+	fsys, err := s.storage.FileSystem(ctx, owner, s.getUserRootDir(owner))
+	if err != nil {
+		return nil, "", err
+	}
+	return fsys, relPath, nil
+}
+
+func (s *ShareUserService) ownerRelativePath(owner *user.User, fullPath string) (string, bool) {
+	rel, err := filepath.Rel(s.getUserRootDir(owner), fullPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return "/" + filepath.ToSlash(rel), true
+}
+
 func (s *ShareUserService) resolveFullPath(u *user.User, sharePath string) string {
 	rel := strings.TrimPrefix(sharePath, "/")
 	rel = filepath.FromSlash(rel)
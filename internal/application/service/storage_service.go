@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
+)
+
+// StorageService 用户存储服务：按用户装配文件系统（内容哈希、去重存储、静态加密）
+// WebDAV、分享下载与保存等读写用户文件的场景都应通过它访问文件内容。
+type StorageService struct {
+	contentHash *ContentHashService
+	blobService *BlobService
+	keyService  *KeyService
+}
+
+// NewStorageService 创建用户存储服务
+func NewStorageService(contentHash *ContentHashService, blobService *BlobService, keyService *KeyService) *StorageService {
+	return &StorageService{
+		contentHash: contentHash,
+		blobService: blobService,
+		keyService:  keyService,
+	}
+}
+
+// Encrypted 是否启用静态加密
+func (s *StorageService) Encrypted() bool {
+	return s != nil && s.keyService.Enabled()
+}
+
+// Deduplicated 是否启用去重存储
+func (s *StorageService) Deduplicated() bool {
+	return s != nil && s.blobService.Enabled()
+}
+
+// FileSystem 返回以 rootDir 为根目录的用户文件系统
+func (s *StorageService) FileSystem(ctx context.Context, u *user.User, rootDir string) (*webdavfs.UnicodeFileSystem, error) {
+	fsys := webdavfs.NewUnicodeFileSystem(rootDir)
+	if s == nil {
+		return fsys, nil
+	}
+	if index := s.contentHash.Index(u.ID); index != nil {
+		fsys.SetContentHashIndex(index)
+	}
+	if blobs := s.blobService.Store(); blobs != nil {
+		fsys.SetBlobStore(blobs)
+	}
+	if s.keyService.Enabled() {
+		keys, err := s.keyService.DataKeys(ctx, u.ID)
+		if err != nil {
+			return nil, err
+		}
+		fsys.SetDataKeys(keys)
+	}
+	return fsys, nil
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/application/assetspace"
+	"github.com/yeying-community/warehouse/internal/domain/account"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/chain"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"go.uber.org/zap"
+)
+
+// TokenGateService 链上代币门槛服务
+// 按配置的 JSON-RPC 节点查询钱包持有情况，并在 cache_ttl 内缓存结果。
+type TokenGateService struct {
+	enabled   bool
+	cacheTTL  time.Duration
+	clients   map[uint64]*chain.Client
+	challenge *tokengate.Condition
+	spaces    map[string]*tokengate.Condition
+	paths     map[string]string
+	logger    *zap.Logger
+
+	mu       sync.Mutex
+	verified map[uint64]bool
+	cache    map[string]tokenGateResult
+}
+
+type tokenGateResult struct {
+	met       bool
+	expiresAt time.Time
+}
+
+// NewTokenGateService 创建代币门槛服务
+func NewTokenGateService(cfg *config.Config, spaces *assetspace.Manager, logger *zap.Logger) (*TokenGateService, error) {
+	gate := cfg.Web3.TokenGate
+	s := &TokenGateService{
+		enabled:  gate.Enabled,
+		cacheTTL: gate.CacheTTL,
+		clients:  make(map[uint64]*chain.Client, len(gate.Chains)),
+		spaces:   make(map[string]*tokengate.Condition),
+		paths:    make(map[string]string),
+		logger:   logger,
+		verified: make(map[uint64]bool),
+		cache:    make(map[string]tokenGateResult),
+	}
+	if !gate.Enabled {
+		return s, nil
+	}
+
+	for _, c := range gate.Chains {
+		s.clients[c.ChainID] = chain.NewClient(strings.TrimSpace(c.RPCURL), gate.Timeout)
+	}
+
+	challenge, err := s.conditionFromConfig(gate.Challenge)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token_gate.challenge: %w", err)
+	}
+	s.challenge = challenge
+
+	for _, sp := range spaces.Spaces() {
+		s.paths[sp.Key] = sp.Path
+	}
+	for key, raw := range gate.Spaces {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if _, ok := s.paths[key]; !ok {
+			return nil, fmt.Errorf("invalid token_gate.spaces: unknown space %q", key)
+		}
+		cond, err := s.conditionFromConfig(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token_gate.spaces.%s: %w", key, err)
+		}
+		if cond != nil {
+			s.spaces[key] = cond
+		}
+	}
+	return s, nil
+}
+
+// Enabled 是否启用代币门槛
+func (s *TokenGateService) Enabled() bool {
+	return s != nil && s.enabled
+}
+
+// ValidateCondition 校验用户提交的条件（nil 表示不设门槛）
+func (s *TokenGateService) ValidateCondition(cond *tokengate.Condition) error {
+	if cond == nil {
+		return nil
+	}
+	if !s.Enabled() {
+		return tokengate.ErrGateDisabled
+	}
+	if err := cond.Validate(); err != nil {
+		return err
+	}
+	if _, ok := s.clients[cond.ChainID]; !ok {
+		return fmt.Errorf("%w: chain %d", tokengate.ErrChainNotConfigured, cond.ChainID)
+	}
+	return nil
+}
+
+// Check 检查钱包是否满足条件，不满足返回 ErrConditionNotMet
+// 门槛未启用时对已设置的条件一律拒绝（fail closed）。
+func (s *TokenGateService) Check(ctx context.Context, cond *tokengate.Condition, wallet string) error {
+	if cond == nil {
+		return nil
+	}
+	if !s.Enabled() {
+		return fmt.Errorf("%w: %w", tokengate.ErrConditionNotMet, tokengate.ErrGateDisabled)
+	}
+	wallet = strings.TrimSpace(wallet)
+	if wallet == "" {
+		return tokengate.ErrWalletRequired
+	}
+	if id, err := account.Parse(wallet); err == nil {
+		if !id.IsEVM() {
+			// 链上条件只支持 EVM 链，其他命名空间的账户一律不满足
+			return tokengate.ErrConditionNotMet
+		}
+		// 存储键为 CAIP-10，链上调用使用裸地址
+		wallet = id.Address
+	}
+	wallet = strings.ToLower(wallet)
+
+	cacheKey := cond.Key() + "|" + wallet
+	if met, ok := s.cached(cacheKey); ok {
+		if !met {
+			return tokengate.ErrConditionNotMet
+		}
+		return nil
+	}
+
+	met, err := s.evaluate(ctx, cond, wallet)
+	if err != nil {
+		return err
+	}
+	s.store(cacheKey, met)
+	if !met {
+		return tokengate.ErrConditionNotMet
+	}
+	return nil
+}
+
+// CheckChallenge 检查钱包是否满足登录门槛
+func (s *TokenGateService) CheckChallenge(ctx context.Context, wallet string) error {
+	if !s.Enabled() || s.challenge == nil {
+		return nil
+	}
+	return s.Check(ctx, s.challenge, wallet)
+}
+
+// SpaceCondition 返回资产空间的门槛条件，未设置返回 nil
+func (s *TokenGateService) SpaceCondition(key string) *tokengate.Condition {
+	if !s.Enabled() {
+		return nil
+	}
+	return s.spaces[key]
+}
+
+// CheckSpace 检查用户是否可以访问路径所在的资产空间
+func (s *TokenGateService) CheckSpace(ctx context.Context, u *user.User, relPath string) error {
+	if !s.Enabled() || len(s.spaces) == 0 || u == nil {
+		return nil
+	}
+	relPath = path.Clean("/" + strings.TrimSpace(relPath))
+	for key, cond := range s.spaces {
+		spacePath := s.paths[key]
+		if relPath != spacePath && !strings.HasPrefix(relPath, spacePath+"/") {
+			continue
+		}
+		return s.Check(ctx, cond, u.WalletAddress)
+	}
+	return nil
+}
+
+func (s *TokenGateService) evaluate(ctx context.Context, cond *tokengate.Condition, wallet string) (bool, error) {
+	client, ok := s.clients[cond.ChainID]
+	if !ok {
+		return false, fmt.Errorf("%w: chain %d", tokengate.ErrChainNotConfigured, cond.ChainID)
+	}
+	if err := s.verifyChain(ctx, cond.ChainID, client); err != nil {
+		return false, err
+	}
+
+	var (
+		balance *big.Int
+		err     error
+	)
+	switch cond.Type {
+	case tokengate.TypeNative:
+		balance, err = client.Balance(ctx, wallet)
+	case tokengate.TypeERC20:
+		balance, err = client.TokenBalance(ctx, cond.Contract, wallet)
+	case tokengate.TypeERC721:
+		tokenID := cond.Token()
+		if tokenID == nil {
+			balance, err = client.TokenBalance(ctx, cond.Contract, wallet)
+			break
+		}
+		owner, ownerErr := client.OwnerOf(ctx, cond.Contract, tokenID)
+		if errors.Is(ownerErr, chain.ErrExecutionReverted) {
+			return false, nil
+		}
+		if ownerErr != nil {
+			return false, fmt.Errorf("failed to query token owner: %w", ownerErr)
+		}
+		return owner == wallet, nil
+	case tokengate.TypeERC1155:
+		balance, err = client.MultiTokenBalance(ctx, cond.Contract, wallet, cond.Token())
+	default:
+		return false, fmt.Errorf("%w: unknown type %q", tokengate.ErrInvalidCondition, cond.Type)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query balance: %w", err)
+	}
+	return balance.Cmp(cond.Threshold()) >= 0, nil
+}
+
+// verifyChain 首次使用时确认节点的链 ID 与配置一致
+func (s *TokenGateService) verifyChain(ctx context.Context, chainID uint64, client *chain.Client) error {
+	s.mu.Lock()
+	ok := s.verified[chainID]
+	s.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	got, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to query chain id: %w", err)
+	}
+	if got != chainID {
+		logger.Ctx(ctx, s.logger).Error("token gate rpc endpoint chain id mismatch",
+			zap.Uint64("expected", chainID),
+			zap.Uint64("actual", got))
+		return fmt.Errorf("%w: rpc endpoint reports chain %d, expected %d", tokengate.ErrChainNotConfigured, got, chainID)
+	}
+
+	s.mu.Lock()
+	s.verified[chainID] = true
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *TokenGateService) cached(key string) (bool, bool) {
+	if s.cacheTTL <= 0 {
+		return false, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res, ok := s.cache[key]
+	if !ok {
+		return false, false
+	}
+	if time.Now().After(res.expiresAt) {
+		delete(s.cache, key)
+		return false, false
+	}
+	return res.met, true
+}
+
+func (s *TokenGateService) store(key string, met bool) {
+	if s.cacheTTL <= 0 {
+		return
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, res := range s.cache {
+		if now.After(res.expiresAt) {
+			delete(s.cache, k)
+		}
+	}
+	s.cache[key] = tokenGateResult{met: met, expiresAt: now.Add(s.cacheTTL)}
+}
+
+func (s *TokenGateService) conditionFromConfig(raw config.TokenConditionConfig) (*tokengate.Condition, error) {
+	if strings.TrimSpace(raw.Type) == "" {
+		return nil, nil
+	}
+	cond := &tokengate.Condition{
+		Type:       tokengate.ConditionType(raw.Type),
+		ChainID:    raw.ChainID,
+		Contract:   raw.Contract,
+		TokenID:    raw.TokenID,
+		MinBalance: raw.MinBalance,
+	}
+	if err := s.ValidateCondition(cond); err != nil {
+		return nil, err
+	}
+	return cond, nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/application/assetspace"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"go.uber.org/zap"
+)
+
+const (
+	testChainID  = 31337
+	testHolder   = "0x1111111111111111111111111111111111111111"
+	testOutsider = "0x2222222222222222222222222222222222222222"
+	testERC20    = "0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	testERC721   = "0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	testERC1155  = "0xcccccccccccccccccccccccccccccccccccccccc"
+)
+
+// fakeChain 本地 JSON-RPC 节点，按合约与调用数据返回固定结果
+type fakeChain struct {
+	calls atomic.Int64
+}
+
+func (f *fakeChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.calls.Add(1)
+	var req struct {
+		ID     uint64            `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reply := func(result any) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}
+	word := func(v int64) string {
+		return "0x" + hex.EncodeToString(big.NewInt(v).FillBytes(make([]byte, 32)))
+	}
+
+	switch req.Method {
+	case "eth_chainId":
+		reply(fmt.Sprintf("0x%x", testChainID))
+	case "eth_getBalance":
+		var account string
+		_ = json.Unmarshal(req.Params[0], &account)
+		if account == testHolder {
+			reply("0xde0b6b3a7640000") // 1 ether
+			return
+		}
+		reply("0x0")
+	case "eth_call":
+		var msg struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		_ = json.Unmarshal(req.Params[0], &msg)
+		holder := strings.Contains(msg.Data, strings.TrimPrefix(testHolder, "0x"))
+		switch {
+		case msg.To == testERC20 && holder:
+			reply(word(500))
+		case msg.To == testERC721 && strings.HasPrefix(msg.Data, "0x6352211e"):
+			tokenID := new(big.Int).SetBytes(mustHex(msg.Data[10:]))
+			if tokenID.Int64() != 7 {
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"jsonrpc": "2.0", "id": req.ID,
+					"error": map[string]any{"code": 3, "message": "execution reverted: invalid token ID"},
+				})
+				return
+			}
+			reply("0x" + strings.Repeat("0", 24) + strings.TrimPrefix(testHolder, "0x"))
+		case msg.To == testERC721 && holder:
+			reply(word(1))
+		case msg.To == testERC1155 && holder && strings.HasSuffix(msg.Data, hex.EncodeToString(big.NewInt(42).FillBytes(make([]byte, 32)))):
+			reply(word(3))
+		default:
+			reply(word(0))
+		}
+	default:
+		http.Error(w, "unsupported method", http.StatusBadRequest)
+	}
+}
+
+func mustHex(s string) []byte {
+	b, _ := hex.DecodeString(s)
+	return b
+}
+
+func newTestTokenGate(t *testing.T, rpcURL string, spaces map[string]config.TokenConditionConfig) *TokenGateService {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Web3.UCAN.AppScope.PathPrefix = "/apps"
+	cfg.Web3.TokenGate = config.TokenGateConfig{
+		Enabled:  true,
+		CacheTTL: time.Minute,
+		Timeout:  5 * time.Second,
+		Chains:   []config.ChainConfig{{ChainID: testChainID, RPCURL: rpcURL}},
+		Spaces:   spaces,
+	}
+	svc, err := NewTokenGateService(cfg, assetspace.NewManager(cfg, zap.NewNop()), zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewTokenGateService returned error: %v", err)
+	}
+	return svc
+}
+
+func TestTokenGateConditions(t *testing.T) {
+	server := httptest.NewServer(&fakeChain{})
+	defer server.Close()
+	svc := newTestTokenGate(t, server.URL, nil)
+	ctx := context.Background()
+
+	cases := []struct {
+		name   string
+		cond   tokengate.Condition
+		wallet string
+		met    bool
+	}{
+		{"native holder", tokengate.Condition{Type: tokengate.TypeNative, ChainID: testChainID, MinBalance: "1000"}, testHolder, true},
+		{"native outsider", tokengate.Condition{Type: tokengate.TypeNative, ChainID: testChainID}, testOutsider, false},
+		{"erc20 enough", tokengate.Condition{Type: tokengate.TypeERC20, ChainID: testChainID, Contract: testERC20, MinBalance: "500"}, testHolder, true},
+		{"erc20 below minimum", tokengate.Condition{Type: tokengate.TypeERC20, ChainID: testChainID, Contract: testERC20, MinBalance: "501"}, testHolder, false},
+		{"erc721 any token", tokengate.Condition{Type: tokengate.TypeERC721, ChainID: testChainID, Contract: testERC721}, testHolder, true},
+		{"erc721 owner of token", tokengate.Condition{Type: tokengate.TypeERC721, ChainID: testChainID, Contract: testERC721, TokenID: "7"}, testHolder, true},
+		{"erc721 not owner", tokengate.Condition{Type: tokengate.TypeERC721, ChainID: testChainID, Contract: testERC721, TokenID: "7"}, testOutsider, false},
+		{"erc721 nonexistent token", tokengate.Condition{Type: tokengate.TypeERC721, ChainID: testChainID, Contract: testERC721, TokenID: "8"}, testHolder, false},
+		{"erc1155 holder", tokengate.Condition{Type: tokengate.TypeERC1155, ChainID: testChainID, Contract: testERC1155, TokenID: "42", MinBalance: "3"}, testHolder, true},
+		{"erc1155 other token", tokengate.Condition{Type: tokengate.TypeERC1155, ChainID: testChainID, Contract: testERC1155, TokenID: "43"}, testHolder, false},
+		// 用户存储键为 CAIP-10，链上调用应使用其中的地址
+		{"erc20 caip-10 key", tokengate.Condition{Type: tokengate.TypeERC20, ChainID: testChainID, Contract: testERC20, MinBalance: "500"}, "eip155:1:" + testHolder, true},
+	}
+	for _, tc := range cases {
+		cond := tc.cond
+		if err := svc.ValidateCondition(&cond); err != nil {
+			t.Fatalf("%s: ValidateCondition returned error: %v", tc.name, err)
+		}
+		err := svc.Check(ctx, &cond, tc.wallet)
+		if tc.met && err != nil {
+			t.Fatalf("%s: expected condition to be met, got %v", tc.name, err)
+		}
+		if !tc.met && !errors.Is(err, tokengate.ErrConditionNotMet) {
+			t.Fatalf("%s: expected ErrConditionNotMet, got %v", tc.name, err)
+		}
+	}
+
+	unknownChain := &tokengate.Condition{Type: tokengate.TypeNative, ChainID: 1}
+	if err := svc.ValidateCondition(unknownChain); !errors.Is(err, tokengate.ErrChainNotConfigured) {
+		t.Fatalf("expected unconfigured chain to be rejected, got %v", err)
+	}
+	if err := svc.Check(ctx, &tokengate.Condition{Type: tokengate.TypeNative, ChainID: testChainID}, ""); !errors.Is(err, tokengate.ErrWalletRequired) {
+		t.Fatalf("expected missing wallet to be rejected, got %v", err)
+	}
+}
+
+func TestTokenGateCacheAndSpaces(t *testing.T) {
+	chain := &fakeChain{}
+	server := httptest.NewServer(chain)
+	defer server.Close()
+	svc := newTestTokenGate(t, server.URL, map[string]config.TokenConditionConfig{
+		"apps": {Type: "erc20", ChainID: testChainID, Contract: testERC20, MinBalance: "100"},
+	})
+	ctx := context.Background()
+
+	holder := &user.User{ID: "u1", WalletAddress: testHolder}
+	outsider := &user.User{ID: "u2", WalletAddress: testOutsider}
+	if err := svc.CheckSpace(ctx, holder, "/apps/game/save.bin"); err != nil {
+		t.Fatalf("holder should access gated space: %v", err)
+	}
+	calls := chain.calls.Load()
+	if err := svc.CheckSpace(ctx, holder, "/apps"); err != nil {
+		t.Fatalf("holder should access gated space root: %v", err)
+	}
+	if chain.calls.Load() != calls {
+		t.Fatalf("expected cached result to skip rpc, calls went from %d to %d", calls, chain.calls.Load())
+	}
+	if err := svc.CheckSpace(ctx, outsider, "/apps/game"); !errors.Is(err, tokengate.ErrConditionNotMet) {
+		t.Fatalf("expected outsider to be denied, got %v", err)
+	}
+	if err := svc.CheckSpace(ctx, outsider, "/personal/notes.txt"); err != nil {
+		t.Fatalf("ungated space should be open: %v", err)
+	}
+	if svc.SpaceCondition("apps") == nil || svc.SpaceCondition("personal") != nil {
+		t.Fatalf("unexpected space conditions")
+	}
+
+	disabled, err := NewTokenGateService(&config.Config{}, assetspace.NewManager(&config.Config{}, zap.NewNop()), zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewTokenGateService returned error: %v", err)
+	}
+	if err := disabled.Check(ctx, svc.SpaceCondition("apps"), testHolder); !errors.Is(err, tokengate.ErrConditionNotMet) {
+		t.Fatalf("disabled gate should fail closed, got %v", err)
+	}
+}
@@ -16,14 +16,23 @@ import (
 
 	"github.com/yeying-community/warehouse/internal/application/assetspace"
 	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/e2ee"
+	"github.com/yeying-community/warehouse/internal/domain/filemeta"
 	"github.com/yeying-community/warehouse/internal/domain/permission"
 	"github.com/yeying-community/warehouse/internal/domain/quota"
 	"github.com/yeying-community/warehouse/internal/domain/recycle"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/metrics"
 	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"github.com/yeying-community/warehouse/internal/infrastructure/tracing"
 	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"golang.org/x/net/webdav"
 )
@@ -35,9 +44,13 @@ type WebDAVService struct {
 	quotaService    quota.Service
 	userRepo        user.Repository
 	recycleRepo     repository.RecycleRepository
+	contentHash     *ContentHashService
+	storage         *StorageService
+	e2ee            *E2EEService
+	tokenGate       *TokenGateService
 	assetSpace      *assetspace.Manager
 	logger          *zap.Logger
-	lockSystem      webdav.LockSystem
+	lockSystem      *webdavfs.CountingLockSystem
 	recycleDir      string // 回收站目录
 }
 
@@ -59,6 +72,10 @@ func NewWebDAVService(
 	quotaService quota.Service,
 	userRepo user.Repository,
 	recycleRepo repository.RecycleRepository,
+	contentHash *ContentHashService,
+	storage *StorageService,
+	e2eeService *E2EEService,
+	tokenGate *TokenGateService,
 	logger *zap.Logger,
 ) *WebDAVService {
 	recycleDir := filepath.Join(cfg.WebDAV.Directory, ".recycle")
@@ -68,19 +85,30 @@ func NewWebDAVService(
 		quotaService:    quotaService,
 		userRepo:        userRepo,
 		recycleRepo:     recycleRepo,
+		contentHash:     contentHash,
+		storage:         storage,
+		e2ee:            e2eeService,
+		tokenGate:       tokenGate,
 		assetSpace:      assetspace.NewManager(cfg, logger),
 		logger:          logger,
-		lockSystem:      webdav.NewMemLS(),
+		lockSystem:      webdavfs.NewCountingLockSystem(webdav.NewMemLS()),
 		recycleDir:      recycleDir,
 	}
 }
 
+// ActiveLocks 当前未过期的 WebDAV 锁数量
+func (s *WebDAVService) ActiveLocks() int {
+	return s.lockSystem.Active(time.Now())
+}
+
 // ServeHTTP 处理 WebDAV 请求
 func (s *WebDAVService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log := logger.Ctx(r.Context(), s.logger)
+
 	// 从上下文获取用户
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		s.logger.Error("user not found in context")
+		log.Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -104,11 +132,11 @@ func (s *WebDAVService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// 获取用户目录
 	userDir := s.getUserDirectory(u)
-	s.logger.Debug("user directory", zap.String("username", u.Username), zap.String("directory", userDir))
+	log.Debug("user directory", zap.String("username", u.Username), zap.String("directory", userDir))
 
 	// 确保目录存在
 	if err := s.ensureDirectory(userDir); err != nil {
-		s.logger.Error("failed to ensure directory",
+		log.Error("failed to ensure directory",
 			zap.String("directory", userDir),
 			zap.Error(err))
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -117,7 +145,7 @@ func (s *WebDAVService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// 确保资产空间目录存在（personal + apps）
 	if err := s.ensureAssetSpaces(userDir); err != nil {
-		s.logger.Error("failed to ensure asset spaces",
+		log.Error("failed to ensure asset spaces",
 			zap.String("directory", userDir),
 			zap.Error(err))
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -130,8 +158,10 @@ func (s *WebDAVService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// UCAN app scope 校验
-	if err := s.checkAppScope(r.Context(), r); err != nil {
-		s.logger.Warn("ucan app scope denied",
+	if err := tracePhase(r.Context(), "webdav.app_scope", func(ctx context.Context) error {
+		return s.checkAppScope(ctx, r)
+	}); err != nil {
+		log.Warn("ucan app scope denied",
 			zap.String("username", u.Username),
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
@@ -142,9 +172,25 @@ func (s *WebDAVService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 应用专用密码的只读 / 路径限制
+	if err := tracePhase(r.Context(), "webdav.app_password_scope", func(ctx context.Context) error {
+		return s.checkAppPasswordScope(ctx, r)
+	}); err != nil {
+		log.Warn("app password scope denied",
+			zap.String("username", u.Username),
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("destination", r.Header.Get("Destination")),
+		)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	// 检查权限
-	if err := s.checkPermission(r.Context(), u, r); err != nil {
-		s.logger.Warn("permission denied",
+	if err := tracePhase(r.Context(), "webdav.permission", func(ctx context.Context) error {
+		return s.checkPermission(ctx, u, r)
+	}); err != nil {
+		log.Warn("permission denied",
 			zap.String("username", u.Username),
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
@@ -153,10 +199,67 @@ func (s *WebDAVService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	requestPath := s.normalizeWebdavRequestPath(r.URL.Path)
+	destPath := ""
+	if dest := strings.TrimSpace(r.Header.Get("Destination")); dest != "" {
+		destPath = s.normalizeWebdavRequestPath(dest)
+	}
+
+	// 资产空间的链上代币门槛
+	if err := tracePhase(r.Context(), "webdav.token_gate", func(ctx context.Context) error {
+		return s.checkTokenGate(ctx, u, requestPath, destPath)
+	}); err != nil {
+		if errors.Is(err, tokengate.ErrConditionNotMet) || errors.Is(err, tokengate.ErrWalletRequired) {
+			log.Warn("asset space token gate denied",
+				zap.String("username", u.Username),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Error(err))
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		log.Error("failed to check asset space token gate",
+			zap.String("username", u.Username),
+			zap.Error(err))
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+		return
+	}
+
+	// 端到端加密目录：拒绝依赖明文的操作，并标记请求路径所在的加密目录
+	var folder *e2ee.Folder
+	err := tracePhase(r.Context(), "webdav.e2ee", func(ctx context.Context) error {
+		var err error
+		folder, err = s.e2ee.CheckWebDAV(ctx, u.ID, r.Method, requestPath, destPath)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, e2ee.ErrPlaintextOperation) || errors.Is(err, e2ee.ErrFolderCopy) ||
+			errors.Is(err, e2ee.ErrNestedEncryptedPath) || errors.Is(err, e2ee.ErrCrossFolderTransfer) {
+			log.Warn("operation denied by e2ee folder",
+				zap.String("username", u.Username),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Error(err))
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		log.Error("failed to check e2ee folders",
+			zap.String("username", u.Username),
+			zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if folder != nil {
+		w.Header().Set("X-E2EE-Folder", folder.ID)
+	}
+
 	// 对于上传操作，检查配额
 	if isUploadMethod(r.Method) {
-		if err := s.checkQuota(r.Context(), u, r); err != nil {
-			s.logger.Warn("quota exceeded",
+		if err := tracePhase(r.Context(), "webdav.quota", func(ctx context.Context) error {
+			return s.checkQuota(ctx, u, r)
+		}); err != nil {
+			metrics.QuotaRejections.Inc("webdav")
+			log.Warn("quota exceeded",
 				zap.String("username", u.Username),
 				zap.String("method", r.Method),
 				zap.String("path", r.URL.Path),
@@ -167,7 +270,14 @@ func (s *WebDAVService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 创建 WebDAV 处理器（使用自定义的 Unicode FileSystem）
-	unicodeFS := webdavfs.NewUnicodeFileSystem(userDir)
+	unicodeFS, err := s.storage.FileSystem(r.Context(), u, userDir)
+	if err != nil {
+		log.Error("failed to open user storage",
+			zap.String("username", u.Username),
+			zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
 	handler := &webdav.Handler{
 		Prefix:     s.config.WebDAV.Prefix,
 		FileSystem: unicodeFS,
@@ -180,41 +290,84 @@ func (s *WebDAVService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 	}
 
+	// 下载时附带内容摘要，便于客户端端到端校验
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		s.setContentHashHeaders(w, r, u, userDir)
+	}
+
 	// 处理请求
 	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
 
+	handlerCtx, span := tracing.Start(r.Context(), "webdav.handler",
+		trace.WithAttributes(attribute.String("webdav.method", r.Method)))
+
 	// 处理 DELETE 请求：将文件移动到回收站
 	if r.Method == http.MethodDelete {
-		s.handleDeleteWithRecycle(w, r, u, userDir, handler, rec)
+		s.handleDeleteWithRecycle(w, r.WithContext(handlerCtx), u, userDir, unicodeFS, handler, rec)
+		endHandlerSpan(span, rec.status)
 		return
 	}
 
-	handler.ServeHTTP(rec, r)
+	handler.ServeHTTP(rec, r.WithContext(handlerCtx))
+	endHandlerSpan(span, rec.status)
+
+	// 加密目录随 MOVE 一起移动
+	if r.Method == "MOVE" && destPath != "" && rec.status >= 200 && rec.status < 300 {
+		if err := s.e2ee.MoveFolders(r.Context(), u.ID, requestPath, destPath); err != nil {
+			log.Error("failed to move e2ee folders",
+				zap.String("username", u.Username),
+				zap.String("from", requestPath),
+				zap.String("to", destPath),
+				zap.Error(err))
+		}
+	}
 
 	// 写操作成功后刷新 used_space
 	if isMutatingMethod(r.Method) && rec.status >= 200 && rec.status < 300 {
-		used, err := s.quotaService.CalculateUsedSpace(r.Context(), userDir)
+		recomputeCtx, span := tracing.Start(r.Context(), "webdav.quota_recompute")
+		defer span.End()
+		used, err := s.quotaService.CalculateUsedSpace(recomputeCtx, userDir)
 		if err != nil {
-			s.logger.Error("failed to calculate used space",
+			tracing.RecordError(span, err)
+			log.Error("failed to calculate used space",
 				zap.String("username", u.Username),
 				zap.String("directory", userDir),
 				zap.Error(err))
 			return
 		}
-		if err := s.userRepo.UpdateUsedSpace(r.Context(), u.Username, used); err != nil {
-			s.logger.Error("failed to update used space in repo",
+		if err := s.userRepo.UpdateUsedSpace(recomputeCtx, u.Username, used); err != nil {
+			tracing.RecordError(span, err)
+			log.Error("failed to update used space in repo",
 				zap.String("username", u.Username),
 				zap.Int64("used_space", used),
 				zap.Error(err))
 			return
 		}
 		u.UpdateUsedSpace(used)
-		s.logger.Debug("used space updated",
+		log.Debug("used space updated",
 			zap.String("username", u.Username),
 			zap.Int64("used_space", used))
 	}
 }
 
+// tracePhase 在子跨度中执行请求处理的一个阶段，出错时记录到跨度
+func tracePhase(ctx context.Context, name string, fn func(context.Context) error) error {
+	ctx, span := tracing.Start(ctx, name)
+	defer span.End()
+	err := fn(ctx)
+	tracing.RecordError(span, err)
+	return err
+}
+
+// endHandlerSpan 记录 WebDAV 处理结果并结束跨度
+func endHandlerSpan(span trace.Span, status int) {
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	span.End()
+}
+
 func (s *WebDAVService) clearWebDAVDeadlines(w http.ResponseWriter) {
 	controller := http.NewResponseController(w)
 	if err := controller.SetReadDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
@@ -225,6 +378,21 @@ func (s *WebDAVService) clearWebDAVDeadlines(w http.ResponseWriter) {
 	}
 }
 
+// setContentHashHeaders 为文件下载设置 Digest / OC-Checksum 头
+func (s *WebDAVService) setContentHashHeaders(w http.ResponseWriter, r *http.Request, u *user.User, userDir string) {
+	relPath := s.normalizeWebdavRequestPath(r.URL.Path)
+	info, err := os.Stat(filepath.Join(userDir, filepath.FromSlash(strings.TrimPrefix(relPath, "/"))))
+	if err != nil || info.IsDir() {
+		return
+	}
+	meta, err := s.contentHash.Lookup(r.Context(), u.ID, relPath, info)
+	if err != nil {
+		return
+	}
+	w.Header().Set("Digest", meta.Digest())
+	w.Header().Set("OC-Checksum", meta.OCChecksum())
+}
+
 func isIgnoredWebDAVPath(rawPath string) bool {
 	if rawPath == "" || rawPath == "/" {
 		return false
@@ -235,7 +403,7 @@ func isIgnoredWebDAVPath(rawPath string) bool {
 }
 
 // handleDeleteWithRecycle 处理删除请求（带回收站功能）
-func (s *WebDAVService) handleDeleteWithRecycle(w http.ResponseWriter, r *http.Request, u *user.User, userDir string, handler *webdav.Handler, rec *statusRecorder) {
+func (s *WebDAVService) handleDeleteWithRecycle(w http.ResponseWriter, r *http.Request, u *user.User, userDir string, fsys *webdavfs.UnicodeFileSystem, handler *webdav.Handler, rec *statusRecorder) {
 	// 获取文件相对路径（剥离 WebDAV 前缀）
 	normalizedPath := s.normalizeWebdavRequestPath(r.URL.Path)
 	filePath := strings.TrimPrefix(normalizedPath, "/")
@@ -249,27 +417,37 @@ func (s *WebDAVService) handleDeleteWithRecycle(w http.ResponseWriter, r *http.R
 			http.Error(w, "Not Found", http.StatusNotFound)
 			return
 		}
-		s.logger.Error("failed to stat file", zap.Error(err))
+		logger.Ctx(r.Context(), s.logger).Error("failed to stat file", zap.Error(err))
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	// 文件/目录移动到回收站目录
-	if err := s.moveToRecycle(r.Context(), u, filePath, fullPath); err != nil {
-		s.logger.Error("failed to move file to recycle", zap.Error(err))
+	if err := s.moveToRecycle(r.Context(), u, fsys, filePath, fullPath); err != nil {
+		logger.Ctx(r.Context(), s.logger).Error("failed to move file to recycle", zap.Error(err))
 		// 如果移动失败，直接删除
 		handler.ServeHTTP(rec, r)
+		if rec.status >= 200 && rec.status < 300 {
+			if err := s.e2ee.RemoveFolders(r.Context(), u.ID, normalizedPath); err != nil {
+				logger.Ctx(r.Context(), s.logger).Error("failed to remove e2ee folders", zap.Error(err))
+			}
+		}
 		return
 	}
 
+	// 移除被删除路径中的加密目录登记
+	if err := s.e2ee.RemoveFolders(r.Context(), u.ID, normalizedPath); err != nil {
+		logger.Ctx(r.Context(), s.logger).Error("failed to remove e2ee folders", zap.Error(err))
+	}
+
 	// 更新配额
 	used, err := s.quotaService.CalculateUsedSpace(r.Context(), userDir)
 	if err != nil {
-		s.logger.Error("failed to calculate used space", zap.Error(err))
+		logger.Ctx(r.Context(), s.logger).Error("failed to calculate used space", zap.Error(err))
 		return
 	}
 	if err := s.userRepo.UpdateUsedSpace(r.Context(), u.Username, used); err != nil {
-		s.logger.Error("failed to update used space", zap.Error(err))
+		logger.Ctx(r.Context(), s.logger).Error("failed to update used space", zap.Error(err))
 		return
 	}
 	u.UpdateUsedSpace(used)
@@ -279,15 +457,23 @@ func (s *WebDAVService) handleDeleteWithRecycle(w http.ResponseWriter, r *http.R
 }
 
 // moveToRecycle 将文件移动到回收站并保存记录
-func (s *WebDAVService) moveToRecycle(ctx context.Context, u *user.User, relativePath, fullPath string) error {
-	// 获取文件信息
-	info, err := os.Stat(fullPath)
+func (s *WebDAVService) moveToRecycle(ctx context.Context, u *user.User, fsys *webdavfs.UnicodeFileSystem, relativePath, fullPath string) error {
+	// 获取文件信息（加密文件为明文大小）
+	info, err := fsys.Stat(ctx, relativePath)
 	if err != nil {
 		return fmt.Errorf("failed to stat file: %w", err)
 	}
 	fileSize := info.Size()
+	contentHash := ""
 	if info.IsDir() {
 		fileSize = 0
+	} else if meta, err := s.contentHash.Ensure(ctx, u.ID, relativePath, fsys); err == nil {
+		contentHash = meta.SHA256
+	} else if !errors.Is(err, filemeta.ErrFileMetadataNotFound) {
+		logger.Ctx(ctx, s.logger).Warn("failed to compute content hash",
+			zap.String("username", u.Username),
+			zap.String("path", relativePath),
+			zap.Error(err))
 	}
 
 	// 确保回收站目录存在
@@ -311,7 +497,7 @@ func (s *WebDAVService) moveToRecycle(ctx context.Context, u *user.User, relativ
 	}
 
 	// 创建回收站记录（先生成 hash，便于文件命名）
-	item := recycle.NewRecycleItem(u.ID, u.Username, dirName, fileName, cleanRelative, fileSize)
+	item := recycle.NewRecycleItem(u.ID, u.Username, dirName, fileName, cleanRelative, fileSize, contentHash)
 
 	// 生成唯一的回收站文件名：{hash}_{原文件名}
 	recycleFileName := fmt.Sprintf("%s_%s", item.Hash, fileName)
@@ -322,17 +508,23 @@ func (s *WebDAVService) moveToRecycle(ctx context.Context, u *user.User, relativ
 		return fmt.Errorf("failed to move file to recycle: %w", err)
 	}
 
+	// 原路径已不存在，清理其内容哈希记录
+	if err := s.contentHash.Remove(ctx, u.ID, relativePath); err != nil {
+		logger.Ctx(ctx, s.logger).Warn("failed to remove content hash", zap.Error(err))
+	}
+
 	// 创建回收站记录并保存到数据库
 	if err := s.recycleRepo.Create(ctx, item); err != nil {
-		s.logger.Error("failed to save recycle item", zap.Error(err))
+		logger.Ctx(ctx, s.logger).Error("failed to save recycle item", zap.Error(err))
 		// 不返回错误，因为文件已经移动了
 	}
 
-	s.logger.Info("file moved to recycle",
+	logger.Ctx(ctx, s.logger).Info("file moved to recycle",
 		zap.String("username", u.Username),
 		zap.String("original_path", relativePath),
 		zap.String("recycle_path", recyclePath),
 		zap.String("hash", item.Hash),
+		zap.String("content_hash", item.ContentHash),
 	)
 
 	return nil
@@ -468,29 +660,29 @@ func (s *WebDAVService) createLogger(username string) func(*http.Request, error)
 		// 判断错误类型
 		if isNoSuchLockError(err) {
 			// Finder/客户端常见的无锁解锁请求，降级为 DEBUG
-			s.logger.Debug("webdav lock not found",
+			logger.Ctx(r.Context(), s.logger).Debug("webdav lock not found",
 				append(fields, zap.String("error", err.Error()))...)
 			return
 		}
 		if isNotFoundError(err) {
 			// 文件不存在 - WARN 级别，不打印堆栈
-			s.logger.Warn("resource not found",
+			logger.Ctx(r.Context(), s.logger).Warn("resource not found",
 				append(fields, zap.String("error", err.Error()))...)
 		} else if isPermissionError(err) {
 			// 权限错误 - WARN 级别
-			s.logger.Warn("permission denied",
+			logger.Ctx(r.Context(), s.logger).Warn("permission denied",
 				append(fields, zap.String("error", err.Error()))...)
 		} else if isExistsError(err) {
 			// 文件已存在 - WARN 级别
-			s.logger.Warn("resource already exists",
+			logger.Ctx(r.Context(), s.logger).Warn("resource already exists",
 				append(fields, zap.String("error", err.Error()))...)
 		} else if isClientError(err) {
 			// 客户端错误 - INFO 级别
-			s.logger.Info("client error",
+			logger.Ctx(r.Context(), s.logger).Info("client error",
 				append(fields, zap.String("error", err.Error()))...)
 		} else {
 			// 系统错误 - ERROR 级别，打印堆栈
-			s.logger.Error("webdav error", append(fields, zap.Error(err))...)
+			logger.Ctx(r.Context(), s.logger).Error("webdav error", append(fields, zap.Error(err))...)
 		}
 	}
 }
@@ -663,6 +855,17 @@ func (s *WebDAVService) checkPermission(ctx context.Context, u *user.User, r *ht
 	return s.permissionCheck.Check(ctx, u, fullPath, operation)
 }
 
+// checkTokenGate 校验请求路径与目标路径所在资产空间的链上条件
+func (s *WebDAVService) checkTokenGate(ctx context.Context, u *user.User, requestPath, destPath string) error {
+	if err := s.tokenGate.CheckSpace(ctx, u, requestPath); err != nil {
+		return err
+	}
+	if destPath == "" {
+		return nil
+	}
+	return s.tokenGate.CheckSpace(ctx, u, destPath)
+}
+
 func (s *WebDAVService) checkAppScope(ctx context.Context, r *http.Request) error {
 	scope, err := resolveAppScope(ctx, s.config)
 	if err != nil {
@@ -700,6 +903,27 @@ func (s *WebDAVService) checkAppScope(ctx context.Context, r *http.Request) erro
 	return nil
 }
 
+// checkAppPasswordScope 校验应用专用密码的访问范围；COPY 的源路径只需要读权限
+func (s *WebDAVService) checkAppPasswordScope(ctx context.Context, r *http.Request) error {
+	p, ok := middleware.GetAppPassword(ctx)
+	if !ok {
+		return nil
+	}
+	method := strings.ToUpper(r.Method)
+	write := requiredActionsForWebdavMethod(method)[0] != "read"
+	if !p.Allows(s.normalizeWebdavRequestPath(r.URL.Path), write && method != "COPY") {
+		return auth.ErrAppPasswordScopeDenied
+	}
+	if method == "MOVE" || method == "COPY" {
+		if dest := strings.TrimSpace(r.Header.Get("Destination")); dest != "" {
+			if !p.Allows(s.normalizeWebdavRequestPath(dest), true) {
+				return auth.ErrAppPasswordScopeDenied
+			}
+		}
+	}
+	return nil
+}
+
 func isAppScopeRootPath(rawPath, prefix string) bool {
 	normalizedPath := normalizeScopePath(rawPath)
 	normalizedPrefix := strings.TrimSuffix(normalizeScopePrefix(prefix), "/")
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// WorkerStatus 后台任务运行状态
+type WorkerStatus struct {
+	Name      string
+	Enabled   bool // 按配置应当运行
+	Running   bool
+	LastRun   time.Time
+	LastError string
+}
+
+// workerState 记录后台任务最近一次执行的时间与结果
+type workerState struct {
+	mu      sync.Mutex
+	lastRun time.Time
+	lastErr error
+}
+
+func (w *workerState) record(err error) {
+	w.mu.Lock()
+	w.lastRun = time.Now()
+	w.lastErr = err
+	w.mu.Unlock()
+}
+
+func (w *workerState) status(name string, enabled, started bool, done <-chan struct{}) WorkerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := WorkerStatus{
+		Name:    name,
+		Enabled: enabled,
+		Running: started && !closed(done),
+		LastRun: w.lastRun,
+	}
+	if w.lastErr != nil {
+		status.LastError = w.lastErr.Error()
+	}
+	return status
+}
+
+func closed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
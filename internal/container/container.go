@@ -3,7 +3,9 @@ package container
 import (
 	"context"
 	"fmt"
+	"math"
 	"strings"
+	"time"
 
 	"github.com/yeying-community/warehouse/internal/application/assetspace"
 	"github.com/yeying-community/warehouse/internal/application/service"
@@ -12,50 +14,96 @@ import (
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	infraAuth "github.com/yeying-community/warehouse/internal/infrastructure/auth"
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
 	"github.com/yeying-community/warehouse/internal/infrastructure/database"
 	infraEmail "github.com/yeying-community/warehouse/internal/infrastructure/email"
 	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/metrics"
 	"github.com/yeying-community/warehouse/internal/infrastructure/permission"
+	"github.com/yeying-community/warehouse/internal/infrastructure/ratelimit"
 	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository/memory"
+	"github.com/yeying-community/warehouse/internal/infrastructure/tracing"
+	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
 	"github.com/yeying-community/warehouse/internal/interface/http"
 	"github.com/yeying-community/warehouse/internal/interface/http/handler"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.uber.org/zap"
 	"golang.org/x/net/webdav"
 )
 
+// metricsQueryTimeout 采集指标时单次数据库查询的超时
+const metricsQueryTimeout = 5 * time.Second
+
+// tracerShutdownTimeout 关闭时导出剩余跨度的超时
+const tracerShutdownTimeout = 5 * time.Second
+
 // Container 依赖注入容器
 type Container struct {
-	Config *config.Config
-	Logger *zap.Logger
+	Config         *config.Config
+	Logger         *zap.Logger
+	TracerProvider *sdktrace.TracerProvider
 
 	// Database
-	DB *database.PostgresDB
+	DB database.Database
 
 	// Repositories
-	UserRepository        user.Repository
-	RecycleRepository     repository.RecycleRepository
-	ShareRepository       repository.ShareRepository
-	UserShareRepository   repository.UserShareRepository
-	AddressBookRepository repository.AddressBookRepository
+	UserRepository         user.Repository
+	RecycleRepository      repository.RecycleRepository
+	ShareRepository        repository.ShareRepository
+	UserShareRepository    repository.UserShareRepository
+	AddressBookRepository  repository.AddressBookRepository
+	FileMetadataRepository repository.FileMetadataRepository
+	DataKeyRepository      repository.DataKeyRepository
+	E2EERepository         repository.E2EERepository
+	IdentityRepository     repository.IdentityRepository
+	SessionRepository      repository.SessionRepository
+	AppPasswordRepository  repository.AppPasswordRepository
+	MFARepository          repository.MFARepository
+	WebAuthnRepository     repository.WebAuthnRepository
+	RoleRepository         repository.RoleRepository
+	AuditRepository        repository.AuditRepository
+	UsedTokenRepository    repository.UsedTokenRepository
+	OutboxRepository       repository.OutboxRepository
 
 	// Services
 	QuotaService       quota.Service
 	AssetSpaceManager  *assetspace.Manager
+	ContentHashService *service.ContentHashService
+	BlobService        *service.BlobService
+	KeyService         *service.KeyService
+	StorageService     *service.StorageService
+	E2EEService        *service.E2EEService
+	TokenGateService   *service.TokenGateService
 	WebDAVService      *service.WebDAVService
 	RecycleService     *service.RecycleService
 	ShareService       *service.ShareService
 	ShareUserService   *service.ShareUserService
 	AddressBookService *service.AddressBookService
+	IdentityService    *service.IdentityService
+	SessionService     *service.SessionService
+	AppPasswordService *service.AppPasswordService
+	MFAService         *service.MFAService
+	PasskeyService     *service.PasskeyService
+	RBACService        *service.RBACService
+	AuditService       *service.AuditService
+	HealthService      *service.HealthService
+	AccountMailService *service.AccountMailService
+	MailOutboxService  *service.MailOutboxService
 
 	// Authenticators
 	Authenticators []auth.Authenticator
 	BasicAuth      *infraAuth.BasicAuthenticator
 	Web3Auth       *infraAuth.Web3Authenticator
+	WebAuthnAuth   *infraAuth.WebAuthnAuthenticator
+	Lockout        *ratelimit.Lockout
 
 	// Handlers
 	HealthHandler      *handler.HealthHandler
+	JWKSHandler        *handler.JWKSHandler
 	Web3Handler        *handler.Web3Handler
 	EmailAuthHandler   *handler.EmailAuthHandler
+	AccountMailHandler *handler.AccountMailHandler
 	AssetsHandler      *handler.AssetsHandler
 	WebDAVHandler      *handler.WebDAVHandler
 	QuotaHandler       *handler.QuotaHandler
@@ -65,24 +113,54 @@ type Container struct {
 	ShareHandler       *handler.ShareHandler
 	ShareUserHandler   *handler.ShareUserHandler
 	AddressBookHandler *handler.AddressBookHandler
+	E2EEHandler        *handler.E2EEHandler
+	IdentityHandler    *handler.IdentityHandler
+	SessionHandler     *handler.SessionHandler
+	AppPasswordHandler *handler.AppPasswordHandler
+	MFAHandler         *handler.MFAHandler
+	WebAuthnHandler    *handler.WebAuthnHandler
+	AdminRoleHandler   *handler.AdminRoleHandler
+	AdminAuditHandler  *handler.AdminAuditHandler
+	AdminMailHandler   *handler.AdminMailHandler
+	MetricsHandler     *handler.MetricsHandler
 
 	// HTTP
 	Router *http.Router
 	Server *http.Server
+
+	// memoryStore 非空时使用内存仓储，不连接数据库
+	memoryStore *memory.Store
+}
+
+// Option 容器选项
+type Option func(*Container)
+
+// WithMemoryRepositories 使用内存仓储代替数据库（用于测试），跳过数据库连接与迁移
+func WithMemoryRepositories(store *memory.Store) Option {
+	return func(c *Container) {
+		c.memoryStore = store
+	}
 }
 
 // NewContainer 创建容器
-func NewContainer(cfg *config.Config) (*Container, error) {
+func NewContainer(cfg *config.Config, opts ...Option) (*Container, error) {
 	c := &Container{
 		Config:         cfg,
 		Authenticators: make([]auth.Authenticator, 0),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
 
 	// 初始化组件
 	if err := c.initLogger(); err != nil {
 		return nil, fmt.Errorf("failed to init logger: %w", err)
 	}
 
+	if err := c.initTracing(); err != nil {
+		return nil, fmt.Errorf("failed to init tracing: %w", err)
+	}
+
 	if err := c.initDatabase(); err != nil {
 		return nil, fmt.Errorf("failed to init database: %w", err)
 	}
@@ -125,16 +203,36 @@ func (c *Container) initLogger() error {
 	return nil
 }
 
+// initTracing 初始化链路追踪；需在数据库之前完成，以便为 SQL 调用创建跨度
+func (c *Container) initTracing() error {
+	cfg := c.Config.Tracing
+	if !cfg.Enabled {
+		tracing.Disable()
+		return nil
+	}
+	tp, err := tracing.NewTracerProvider(context.Background(), cfg)
+	if err != nil {
+		return err
+	}
+	c.TracerProvider = tp
+
+	c.Logger.Info("tracing initialized",
+		zap.String("exporter", cfg.Exporter),
+		zap.String("endpoint", cfg.Endpoint),
+		zap.Float64("sample_ratio", cfg.SampleRatio))
+	return nil
+}
+
 // initDatabase 初始化数据库
 func (c *Container) initDatabase() error {
-	// 仅支持 PostgreSQL
-	if !(c.Config.Database.Type == "postgres" || c.Config.Database.Type == "postgresql") {
-		return fmt.Errorf("unsupported database type %q: only postgres/postgresql is supported", c.Config.Database.Type)
+	if c.memoryStore != nil {
+		c.Logger.Info("database skipped, using in-memory repositories")
+		return nil
 	}
 
-	db, err := database.NewPostgresDB(c.Config.Database)
+	db, err := database.Open(c.Config.Database)
 	if err != nil {
-		return fmt.Errorf("failed to connect to postgres: %w", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 	c.DB = db
 
@@ -144,46 +242,192 @@ func (c *Container) initDatabase() error {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
-	c.Logger.Info("database initialized",
-		zap.String("type", "postgres"),
-		zap.String("host", c.Config.Database.Host),
-		zap.Int("port", c.Config.Database.Port))
+	fields := []zap.Field{zap.String("type", c.DB.Type())}
+	if c.DB.Type() == database.TypeSQLite {
+		fields = append(fields, zap.String("path", c.Config.Database.Path))
+	} else {
+		fields = append(fields,
+			zap.String("host", c.Config.Database.Host),
+			zap.Int("port", c.Config.Database.Port))
+	}
+	c.Logger.Info("database initialized", fields...)
 	return nil
 }
 
 // initRepositories 初始化仓储
 func (c *Container) initRepositories() error {
+	if c.memoryStore != nil {
+		c.initMemoryRepositories(c.memoryStore)
+		c.Logger.Info("repositories initialized", zap.String("type", "memory"))
+		return nil
+	}
 	if c.DB == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
+	switch db := c.DB.(type) {
+	case *database.PostgresDB:
+		if err := c.initPostgresRepositories(db); err != nil {
+			return err
+		}
+		c.Logger.Info("using PostgreSQL user repository")
+	case *database.SQLiteDB:
+		if err := c.initSQLiteRepositories(db); err != nil {
+			return err
+		}
+		c.Logger.Info("using SQLite user repository")
+	default:
+		return fmt.Errorf("unsupported database type: %s", c.DB.Type())
+	}
+
+	c.Logger.Info("repositories initialized")
+	return nil
+}
+
+// initPostgresRepositories 初始化 PostgreSQL 仓储
+func (c *Container) initPostgresRepositories(db *database.PostgresDB) error {
 	// 用户仓储
-	repo, err := repository.NewPostgresUserRepository(c.DB)
+	repo, err := repository.NewPostgresUserRepository(db)
 	if err != nil {
 		return fmt.Errorf("failed to create postgres repository: %w", err)
 	}
 	c.UserRepository = repo
 
 	// 回收站仓储
-	c.RecycleRepository = repository.NewPostgresRecycleRepository(c.DB.DB)
+	c.RecycleRepository = repository.NewPostgresRecycleRepository(db.DB)
 	// 分享仓储
-	c.ShareRepository = repository.NewPostgresShareRepository(c.DB.DB)
+	c.ShareRepository = repository.NewPostgresShareRepository(db.DB)
 	// 定向分享仓储
-	c.UserShareRepository = repository.NewPostgresUserShareRepository(c.DB.DB)
+	c.UserShareRepository = repository.NewPostgresUserShareRepository(db.DB)
 	// 地址簿仓储
-	c.AddressBookRepository = repository.NewPostgresAddressBookRepository(c.DB.DB)
+	c.AddressBookRepository = repository.NewPostgresAddressBookRepository(db.DB)
+	// 文件元数据仓储
+	c.FileMetadataRepository = repository.NewPostgresFileMetadataRepository(db.DB)
+	// 数据密钥仓储
+	c.DataKeyRepository = repository.NewPostgresDataKeyRepository(db.DB)
+	// 端到端加密目录仓储
+	c.E2EERepository = repository.NewPostgresE2EERepository(db.DB)
+	// 登录身份仓储
+	c.IdentityRepository = repository.NewPostgresIdentityRepository(db.DB)
+	// 登录会话仓储
+	c.SessionRepository = repository.NewPostgresSessionRepository(db.DB)
+	// 应用专用密码仓储
+	c.AppPasswordRepository = repository.NewPostgresAppPasswordRepository(db.DB)
+	// 两步验证仓储
+	c.MFARepository = repository.NewPostgresMFARepository(db.DB)
+	// 通行密钥仓储
+	c.WebAuthnRepository = repository.NewPostgresWebAuthnRepository(db.DB)
+	// 管理角色仓储
+	c.RoleRepository = repository.NewPostgresRoleRepository(db.DB)
+	// 审计日志仓储
+	c.AuditRepository = repository.NewPostgresAuditRepository(db.DB)
+	// 一次性令牌仓储
+	c.UsedTokenRepository = repository.NewPostgresUsedTokenRepository(db.DB)
+	// 邮件发件箱仓储
+	c.OutboxRepository = repository.NewPostgresOutboxRepository(db.DB)
+	return nil
+}
 
-	c.Logger.Info("using PostgreSQL user repository")
-	c.Logger.Info("repositories initialized")
+// initSQLiteRepositories 初始化 SQLite 仓储
+func (c *Container) initSQLiteRepositories(db *database.SQLiteDB) error {
+	// 用户仓储
+	repo, err := repository.NewSQLiteUserRepository(db)
+	if err != nil {
+		return fmt.Errorf("failed to create sqlite repository: %w", err)
+	}
+	c.UserRepository = repo
+
+	c.RecycleRepository = repository.NewSQLiteRecycleRepository(db.DB)
+	c.ShareRepository = repository.NewSQLiteShareRepository(db.DB)
+	c.UserShareRepository = repository.NewSQLiteUserShareRepository(db.DB)
+	c.AddressBookRepository = repository.NewSQLiteAddressBookRepository(db.DB)
+	c.FileMetadataRepository = repository.NewSQLiteFileMetadataRepository(db.DB)
+	c.DataKeyRepository = repository.NewSQLiteDataKeyRepository(db.DB)
+	c.E2EERepository = repository.NewSQLiteE2EERepository(db.DB)
+	c.IdentityRepository = repository.NewSQLiteIdentityRepository(db.DB)
+	c.SessionRepository = repository.NewSQLiteSessionRepository(db.DB)
+	c.AppPasswordRepository = repository.NewSQLiteAppPasswordRepository(db.DB)
+	c.MFARepository = repository.NewSQLiteMFARepository(db.DB)
+	c.WebAuthnRepository = repository.NewSQLiteWebAuthnRepository(db.DB)
+	c.RoleRepository = repository.NewSQLiteRoleRepository(db.DB)
+	c.AuditRepository = repository.NewSQLiteAuditRepository(db.DB)
+	c.UsedTokenRepository = repository.NewSQLiteUsedTokenRepository(db.DB)
+	c.OutboxRepository = repository.NewSQLiteOutboxRepository(db.DB)
 	return nil
 }
 
+// initMemoryRepositories 初始化内存仓储
+func (c *Container) initMemoryRepositories(store *memory.Store) {
+	c.UserRepository = memory.NewUserRepository(store)
+	c.RecycleRepository = memory.NewRecycleRepository(store)
+	c.ShareRepository = memory.NewShareRepository(store)
+	c.UserShareRepository = memory.NewUserShareRepository(store)
+	c.AddressBookRepository = memory.NewAddressBookRepository(store)
+	c.FileMetadataRepository = memory.NewFileMetadataRepository(store)
+	c.DataKeyRepository = memory.NewDataKeyRepository(store)
+	c.E2EERepository = memory.NewE2EERepository(store)
+	c.IdentityRepository = memory.NewIdentityRepository(store)
+	c.SessionRepository = memory.NewSessionRepository(store)
+	c.AppPasswordRepository = memory.NewAppPasswordRepository(store)
+	c.MFARepository = memory.NewMFARepository(store)
+	c.WebAuthnRepository = memory.NewWebAuthnRepository(store)
+	c.RoleRepository = memory.NewRoleRepository(store)
+	c.AuditRepository = memory.NewAuditRepository(store)
+	c.UsedTokenRepository = memory.NewUsedTokenRepository(store)
+	c.OutboxRepository = memory.NewOutboxRepository(store)
+}
+
 // initServices 初始化服务
 func (c *Container) initServices() error {
 	c.AssetSpaceManager = assetspace.NewManager(c.Config, c.Logger)
 
+	// 内容哈希服务
+	c.ContentHashService = service.NewContentHashService(c.FileMetadataRepository, c.Logger)
+
+	// 去重 blob 存储（可选）
+	var blobStore *webdavfs.BlobStore
+	dedup := c.Config.WebDAV.Dedup
+	if dedup.Enabled {
+		store, err := webdavfs.NewBlobStore(dedup.Directory, c.Config.WebDAV.Directory)
+		if err != nil {
+			return fmt.Errorf("failed to init blob store: %w", err)
+		}
+		blobStore = store
+	}
+	c.BlobService = service.NewBlobService(blobStore, dedup.GCInterval, c.Logger)
+	c.BlobService.Start()
+
+	// 静态加密（可选）
+	var masterKey []byte
+	encryption := c.Config.WebDAV.Encryption
+	if encryption.Enabled {
+		key, err := crypto.LoadMasterKey(encryption.MasterKey, encryption.MasterKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load master key: %w", err)
+		}
+		masterKey = key
+	}
+	c.KeyService = service.NewKeyService(c.DataKeyRepository, masterKey, c.Logger)
+
+	// 用户存储服务
+	c.StorageService = service.NewStorageService(c.ContentHashService, c.BlobService, c.KeyService)
+
+	// 端到端加密目录服务
+	c.E2EEService = service.NewE2EEService(c.E2EERepository, c.Config, c.Logger)
+
+	// 链上代币门槛服务
+	tokenGate, err := service.NewTokenGateService(c.Config, c.AssetSpaceManager, c.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to init token gate: %w", err)
+	}
+	c.TokenGateService = tokenGate
+
 	// 配额服务
-	c.QuotaService = quota.NewService(c.UserRepository)
+	if blobStore != nil {
+		c.QuotaService = quota.NewServiceWithPolicy(c.UserRepository, quota.SharedBlobPolicy(dedup.QuotaPolicy), webdavfs.LinkInfo)
+	} else {
+		c.QuotaService = quota.NewService(c.UserRepository)
+	}
 
 	// WebDAV 服务
 	fileSystem := webdav.Dir(c.Config.WebDAV.Directory)
@@ -195,6 +439,10 @@ func (c *Container) initServices() error {
 		c.QuotaService,
 		c.UserRepository,
 		c.RecycleRepository,
+		c.ContentHashService,
+		c.StorageService,
+		c.E2EEService,
+		c.TokenGateService,
 		c.Logger,
 	)
 
@@ -202,6 +450,8 @@ func (c *Container) initServices() error {
 	c.RecycleService = service.NewRecycleService(
 		c.RecycleRepository,
 		c.UserRepository,
+		c.ContentHashService,
+		c.StorageService,
 		c.Config,
 		c.Logger,
 	)
@@ -210,6 +460,11 @@ func (c *Container) initServices() error {
 	c.ShareService = service.NewShareService(
 		c.ShareRepository,
 		c.UserRepository,
+		c.ContentHashService,
+		c.StorageService,
+		c.E2EEService,
+		c.TokenGateService,
+		c.QuotaService,
 		c.Config,
 		c.Logger,
 	)
@@ -220,23 +475,124 @@ func (c *Container) initServices() error {
 		c.UserShareRepository,
 		c.UserRepository,
 		c.AddressBookService,
+		c.ContentHashService,
+		c.StorageService,
+		c.E2EEService,
+		c.TokenGateService,
+		c.Config,
+		c.Logger,
+	)
+	// 登录身份服务
+	c.IdentityService = service.NewIdentityService(
+		c.IdentityRepository,
+		c.UserRepository,
+		c.RecycleRepository,
+		c.E2EERepository,
+		c.StorageService,
+		c.QuotaService,
 		c.Config,
 		c.Logger,
 	)
+	// 登录会话服务
+	c.SessionService = service.NewSessionService(c.SessionRepository, c.UserRepository, c.Logger)
+	c.SessionService.Start()
+	// 应用专用密码服务
+	c.AppPasswordService = service.NewAppPasswordService(c.AppPasswordRepository, c.Logger)
+	// 两步验证服务
+	c.MFAService = service.NewMFAService(c.MFARepository, c.UserRepository, c.Config.Security, c.Logger)
+	// 管理角色服务：内置角色每次启动时同步到数据库
+	c.RBACService = service.NewRBACService(c.RoleRepository, c.Config.Security, c.Logger)
+	if err := c.RBACService.EnsureBuiltinRoles(context.Background()); err != nil {
+		return fmt.Errorf("failed to ensure built-in roles: %w", err)
+	}
+	c.MFAService.SetAdminChecker(c.RBACService)
+	// 审计日志服务：后台按保留期清理
+	c.AuditService = service.NewAuditService(c.AuditRepository, c.Config.Audit, c.Logger)
+	if c.AuditService.Enabled() {
+		c.AuditService.Start()
+	}
+	// 通行密钥管理服务
+	c.PasskeyService = service.NewPasskeyService(c.WebAuthnRepository, c.Logger)
+	// 邮件发件箱服务：启用邮件时后台发送并重试
+	c.MailOutboxService = service.NewMailOutboxService(
+		c.OutboxRepository,
+		infraEmail.NewSender(c.Config.Email, c.Logger),
+		c.Config.Email,
+		c.Logger,
+	)
+	if c.MailOutboxService.Enabled() {
+		c.MailOutboxService.Start()
+	}
+
+	c.registerMetrics()
+	c.registerHealthChecks()
 
-	c.Logger.Info("services initialized", zap.Bool("quota_enabled", true))
+	c.Logger.Info("services initialized",
+		zap.Bool("quota_enabled", true),
+		zap.Bool("dedup_enabled", blobStore != nil),
+		zap.Bool("encryption_enabled", c.KeyService.Enabled()),
+		zap.Bool("token_gate_enabled", c.TokenGateService.Enabled()))
 
 	return nil
 }
 
+// registerMetrics 注册采集时读取的指标：数据库连接池、WebDAV 锁与回收站
+func (c *Container) registerMetrics() {
+	if c.DB != nil {
+		metrics.RegisterDBStats(metrics.Default, c.DB.SQL())
+	}
+	metrics.Default.NewGaugeFunc("warehouse_webdav_active_locks", "Active WebDAV locks.", func() float64 {
+		return float64(c.WebDAVService.ActiveLocks())
+	})
+
+	recycleStats := func(bytes bool) func() float64 {
+		return func() float64 {
+			ctx, cancel := context.WithTimeout(context.Background(), metricsQueryTimeout)
+			defer cancel()
+			count, size, err := c.RecycleRepository.Stats(ctx)
+			if err != nil {
+				c.Logger.Warn("failed to collect recycle metrics", zap.Error(err))
+				return math.NaN()
+			}
+			if bytes {
+				return float64(size)
+			}
+			return float64(count)
+		}
+	}
+	metrics.Default.NewGaugeFunc("warehouse_recycle_items", "Items in all recycle bins.", recycleStats(false))
+	metrics.Default.NewGaugeFunc("warehouse_recycle_bytes", "Total size of items in all recycle bins.", recycleStats(true))
+}
+
+// registerHealthChecks 注册就绪检查：数据库、数据目录、SMTP（启用邮箱登录时）与后台任务
+func (c *Container) registerHealthChecks() {
+	c.HealthService = service.NewHealthService(c.Config.Health, c.Logger)
+	if c.DB != nil {
+		c.HealthService.Register("database", service.DatabaseHealthCheck(c.DB))
+	}
+	c.HealthService.Register("storage", service.StorageHealthCheck(c.Config.WebDAV.Directory, c.Config.Health.MinFreeBytes))
+	if c.Config.Email.Enabled {
+		c.HealthService.Register("smtp", service.SMTPHealthCheck(infraEmail.NewSender(c.Config.Email, c.Logger)))
+	}
+	c.HealthService.Register("workers", service.WorkerHealthCheck(c.SessionService, c.AuditService, c.BlobService, c.MailOutboxService))
+}
+
 // initAuthenticators 初始化认证器
 func (c *Container) initAuthenticators() error {
+	// 账户锁定：Basic 认证与用户名密码登录共用失败计数
+	if rl := c.Config.Security.RateLimit; rl.Enabled && rl.Lockout.Enabled {
+		c.Lockout = ratelimit.NewLockout(rl.Lockout.MaxFailures, rl.Lockout.Duration, rl.Lockout.MaxDuration)
+	}
+
 	// Basic 认证器
 	c.BasicAuth = infraAuth.NewBasicAuthenticator(
 		c.UserRepository,
 		c.Config.Security.NoPassword,
 		c.Logger,
 	)
+	c.BasicAuth.SetAppPasswordStore(c.AppPasswordRepository)
+	c.BasicAuth.SetLockout(c.Lockout)
+	c.BasicAuth.SetSecondFactorPolicy(c.MFAService)
 	c.Authenticators = append(c.Authenticators, c.BasicAuth)
 
 	// Web3 认证器
@@ -254,6 +610,8 @@ func (c *Container) initAuthenticators() error {
 		ucanCaps,
 		c.Logger,
 	)
+	signatureVerifier := crypto.NewSignatureVerifier(c.Config.Web3.SmartWallet)
+	ucanVerifier.SetSignatureVerifier(signatureVerifier)
 	c.Web3Auth = infraAuth.NewWeb3Authenticator(
 		c.UserRepository,
 		c.Config.Web3.JWTSecret,
@@ -264,8 +622,38 @@ func (c *Container) initAuthenticators() error {
 		c.Logger,
 		c.Config.Web3.AutoCreateOnUCAN,
 	)
+	if signing := c.Config.Web3.JWTSigning; signing.Algorithm != "" && signing.Algorithm != "HS256" {
+		keys, err := infraAuth.LoadJWTKeys(signing.KeysDir, signing.Algorithm, signing.ActiveKeyID)
+		if err != nil {
+			return fmt.Errorf("failed to load jwt signing keys: %w", err)
+		}
+		c.Web3Auth.SetJWTKeys(keys, signing.AcceptHS256)
+		c.Logger.Info("jwt asymmetric signing enabled",
+			zap.String("algorithm", signing.Algorithm),
+			zap.String("kid", keys.SigningKey().ID),
+			zap.Int("verification_keys", len(keys.JWKS())),
+			zap.Bool("accept_hs256", signing.AcceptHS256))
+	}
+	c.Web3Auth.SetSIWEPolicy(infraAuth.NewSIWEPolicy(c.Config.Web3.SIWE))
+	c.Web3Auth.SetSignatureVerifier(signatureVerifier)
+	c.Web3Auth.SetPublicKeyRecorder(c.E2EEService)
+	c.Web3Auth.SetSessionStore(c.SessionRepository)
 	c.Authenticators = append(c.Authenticators, c.Web3Auth)
 
+	// 通行密钥认证器：只用于登录接口，登录后使用 Web3 认证器签发的 JWT，不加入请求认证链
+	if c.Config.WebAuthn.Enabled {
+		c.WebAuthnAuth = infraAuth.NewWebAuthnAuthenticator(
+			c.UserRepository,
+			c.WebAuthnRepository,
+			c.Config.WebAuthn,
+			c.Logger,
+		)
+		c.Logger.Info("webauthn enabled",
+			zap.String("rp_id", c.Config.WebAuthn.RPID),
+			zap.Strings("origins", c.Config.WebAuthn.Origins),
+			zap.Bool("auto_create_on_register", c.Config.WebAuthn.AutoCreateOnRegister))
+	}
+
 	c.Logger.Info("authenticators initialized", zap.Int("count", len(c.Authenticators)))
 
 	return nil
@@ -274,14 +662,35 @@ func (c *Container) initAuthenticators() error {
 // initHandlers 初始化处理器
 func (c *Container) initHandlers() error {
 	// 健康检查处理器
-	c.HealthHandler = handler.NewHealthHandler(c.Logger)
+	c.HealthHandler = handler.NewHealthHandler(c.HealthService, c.Logger)
+	// Prometheus 指标处理器
+	c.MetricsHandler = handler.NewMetricsHandler(metrics.Default, c.Config.Metrics, c.Logger)
+	// JWKS 处理器
+	c.JWKSHandler = handler.NewJWKSHandler(c.Web3Auth.GetJWTManager(), c.Logger)
 
 	// 创建配额处理器
 	c.QuotaHandler = handler.NewQuotaHandler(c.QuotaService, c.Logger)
 	// 用户信息处理器
 	c.UserHandler = handler.NewUserHandler(c.Logger, c.UserRepository)
 	// 管理员用户处理器
-	c.AdminUserHandler = handler.NewAdminUserHandler(c.Logger, c.UserRepository, c.AssetSpaceManager)
+	c.AdminUserHandler = handler.NewAdminUserHandler(c.Logger, c.UserRepository, c.AssetSpaceManager, c.RBACService)
+	c.AdminUserHandler.SetLockout(c.Lockout)
+	// 管理角色处理器
+	c.AdminRoleHandler = handler.NewAdminRoleHandler(c.RBACService, c.UserRepository, c.Logger)
+	// 审计日志处理器
+	c.AdminAuditHandler = handler.NewAdminAuditHandler(c.AuditService, c.Logger)
+	// 邮件发件箱处理器
+	c.AdminMailHandler = handler.NewAdminMailHandler(c.MailOutboxService, c.Logger)
+
+	// 两步验证处理器（密码登录与邮箱登录共用）
+	c.MFAHandler = handler.NewMFAHandler(
+		c.MFAService,
+		c.Web3Auth,
+		c.UserRepository,
+		c.RBACService,
+		c.Logger,
+	)
+	c.MFAHandler.SetSessionService(c.SessionService)
 
 	// Web3 处理器
 	if c.Web3Auth != nil {
@@ -289,25 +698,55 @@ func (c *Container) initHandlers() error {
 			c.Web3Auth,
 			c.UserRepository,
 			c.AssetSpaceManager,
+			c.TokenGateService,
 			c.Logger,
 			c.Config.Web3.AutoCreateOnChallenge,
 		)
+		c.Web3Handler.SetMFAHandler(c.MFAHandler)
+		c.Web3Handler.SetLockout(c.Lockout)
 	}
 
 	// 邮箱验证码登录处理器
 	emailStore := infraAuth.NewEmailCodeStore()
-	emailSender := infraEmail.NewSender(c.Config.Email, c.Logger)
+	emailStore.SetMaxAttempts(c.Config.Email.MaxAttempts)
 	c.EmailAuthHandler = handler.NewEmailAuthHandler(
 		c.Web3Auth,
 		c.UserRepository,
 		c.AssetSpaceManager,
 		emailStore,
-		emailSender,
+		c.MailOutboxService,
+		c.Config.Email,
+		c.Logger,
+	)
+	c.EmailAuthHandler.SetMFAHandler(c.MFAHandler)
+	// 重置密码与验证邮箱：需要配置邮件链接地址，令牌由 Web3 认证器的 JWT 管理器签发
+	if c.Config.Email.Enabled && c.Config.Email.LinkBaseURL != "" && c.Web3Auth != nil {
+		c.AccountMailService = service.NewAccountMailService(
+			c.UserRepository,
+			c.UsedTokenRepository,
+			c.SessionRepository,
+			c.MailOutboxService,
+			c.Web3Auth.GetJWTManager(),
+			c.Config.Email,
+			c.Logger,
+		)
+		c.AccountMailService.SetLockout(c.Lockout)
+		c.AccountMailHandler = handler.NewAccountMailHandler(c.AccountMailService, c.Logger)
+	}
+	// 登录身份处理器（与邮箱登录共用验证码存储）
+	c.IdentityHandler = handler.NewIdentityHandler(
+		c.IdentityService,
+		c.Web3Auth,
+		c.UserRepository,
+		emailStore,
+		c.MailOutboxService,
 		c.Config.Email,
 		c.Logger,
 	)
+	c.IdentityHandler.SetMFAService(c.MFAService)
+	c.IdentityHandler.SetLockout(c.Lockout)
 
-	c.AssetsHandler = handler.NewAssetsHandler(c.AssetSpaceManager, c.Logger)
+	c.AssetsHandler = handler.NewAssetsHandler(c.AssetSpaceManager, c.TokenGateService, c.Logger)
 
 	// WebDAV 处理器
 	c.WebDAVHandler = handler.NewWebDAVHandler(
@@ -340,6 +779,33 @@ func (c *Container) initHandlers() error {
 		c.AddressBookService,
 		c.Logger,
 	)
+	// 端到端加密目录处理器
+	c.E2EEHandler = handler.NewE2EEHandler(
+		c.E2EEService,
+		c.Logger,
+	)
+	// 登录会话处理器
+	c.SessionHandler = handler.NewSessionHandler(
+		c.SessionService,
+		c.RBACService,
+		c.UserRepository,
+		c.Logger,
+	)
+	// 应用专用密码处理器
+	c.AppPasswordHandler = handler.NewAppPasswordHandler(
+		c.AppPasswordService,
+		c.Logger,
+	)
+	// 通行密钥处理器
+	if c.WebAuthnAuth != nil {
+		c.WebAuthnHandler = handler.NewWebAuthnHandler(
+			c.WebAuthnAuth,
+			c.PasskeyService,
+			c.Web3Auth,
+			c.AssetSpaceManager,
+			c.Logger,
+		)
+	}
 
 	c.Logger.Info("handlers initialized")
 
@@ -352,9 +818,13 @@ func (c *Container) initHTTP() error {
 	c.Router = http.NewRouter(
 		c.Config,
 		c.Authenticators,
+		c.RBACService,
+		c.AuditService,
 		c.HealthHandler,
+		c.JWKSHandler,
 		c.Web3Handler,
 		c.EmailAuthHandler,
+		c.AccountMailHandler,
 		c.AssetsHandler,
 		c.WebDAVHandler,
 		c.QuotaHandler,
@@ -364,6 +834,16 @@ func (c *Container) initHTTP() error {
 		c.ShareHandler,
 		c.ShareUserHandler,
 		c.AddressBookHandler,
+		c.E2EEHandler,
+		c.IdentityHandler,
+		c.SessionHandler,
+		c.AppPasswordHandler,
+		c.MFAHandler,
+		c.WebAuthnHandler,
+		c.AdminRoleHandler,
+		c.AdminAuditHandler,
+		c.AdminMailHandler,
+		c.MetricsHandler,
 		c.Logger,
 	)
 
@@ -380,6 +860,22 @@ func (c *Container) Close() error {
 		c.Logger.Info("closing container")
 	}
 
+	// 停止后台任务
+	c.BlobService.Stop()
+	c.SessionService.Stop()
+	c.AuditService.Stop()
+	c.MailOutboxService.Stop()
+
+	// 导出剩余跨度
+	if c.TracerProvider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), tracerShutdownTimeout)
+		if err := c.TracerProvider.Shutdown(ctx); err != nil {
+			c.Logger.Warn("failed to flush traces", zap.Error(err))
+		}
+		cancel()
+		tracing.Disable()
+	}
+
 	// 关闭数据库连接
 	if c.DB != nil {
 		if err := c.DB.Close(); err != nil {
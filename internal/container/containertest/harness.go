@@ -0,0 +1,181 @@
+// Package containertest 提供端到端测试用的完整 HTTP 服务
+// 使用内存仓储与临时数据目录装配容器（与生产相同的 Router.Setup()），
+// 不需要数据库，可直接用 httptest 对 WebDAV、分享、回收站与认证流程做黑盒测试。
+package containertest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yeying-community/warehouse/internal/container"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository/memory"
+)
+
+// JWTSecret 测试服务使用的 HS256 密钥
+const JWTSecret = "containertest-jwt-secret-0123456789abcdef"
+
+// Harness 运行中的测试服务
+type Harness struct {
+	Config    *config.Config
+	Store     *memory.Store
+	Container *container.Container
+	Server    *httptest.Server
+}
+
+// New 启动测试服务；configure 可在装配前修改配置。测试结束时自动关闭。
+func New(t testing.TB, configure ...func(*config.Config)) *Harness {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.WebDAV.Directory = t.TempDir()
+	cfg.Web3.JWTSecret = JWTSecret
+	cfg.Log.Level = "error"
+	cfg.Log.Colors = false
+	// 同一测试内请求密集，关闭限流避免偶发 429
+	cfg.Security.RateLimit.Enabled = false
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	store := memory.NewStore()
+	c, err := container.NewContainer(cfg, container.WithMemoryRepositories(store))
+	if err != nil {
+		t.Fatalf("failed to create container: %v", err)
+	}
+	server := httptest.NewServer(c.Router.Setup())
+	t.Cleanup(func() {
+		server.Close()
+		_ = c.Close()
+	})
+
+	return &Harness{
+		Config:    cfg,
+		Store:     store,
+		Container: c,
+		Server:    server,
+	}
+}
+
+// CreateUser 创建拥有全部权限的用户，绑定随机钱包地址；password 为空时不设置密码
+func (h *Harness) CreateUser(t testing.TB, username, password string) *user.User {
+	t.Helper()
+
+	u := user.NewUser(username, username)
+	u.Permissions = user.ParsePermissions("CRUD")
+	if password != "" {
+		hashed, err := crypto.NewPasswordHasher().Hash(password)
+		if err != nil {
+			t.Fatalf("failed to hash password: %v", err)
+		}
+		u.SetPassword(hashed)
+	}
+	if err := u.SetWalletAddress(randomWallet(t)); err != nil {
+		t.Fatalf("failed to set wallet: %v", err)
+	}
+	if err := h.Container.UserRepository.Save(context.Background(), u); err != nil {
+		t.Fatalf("failed to save user: %v", err)
+	}
+	return u
+}
+
+// URL 返回服务上 path 的完整地址
+func (h *Harness) URL(path string) string {
+	return h.Server.URL + path
+}
+
+// NewRequest 创建指向测试服务的请求
+func (h *Harness) NewRequest(t testing.TB, method, path string, body io.Reader) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(method, h.URL(path), body)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	return req
+}
+
+// Do 发送请求（不跟随重定向），读取并关闭响应体
+func (h *Harness) Do(t testing.TB, req *http.Request) (*http.Response, []byte) {
+	t.Helper()
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	return resp, body
+}
+
+// DoJSON 以 JSON 发送请求体（payload 为 nil 时不带请求体），token 非空时携带 Bearer 令牌
+func (h *Harness) DoJSON(t testing.TB, method, path, token string, payload any) (*http.Response, []byte) {
+	t.Helper()
+
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			t.Fatalf("failed to encode payload: %v", err)
+		}
+		body = strings.NewReader(string(data))
+	}
+	req := h.NewRequest(t, method, path, body)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return h.Do(t, req)
+}
+
+// Login 通过用户名密码登录，返回访问令牌
+func (h *Harness) Login(t testing.TB, username, password string) string {
+	t.Helper()
+
+	resp, body := h.DoJSON(t, http.MethodPost, "/api/v1/public/auth/password/login", "", map[string]string{
+		"username": username,
+		"password": password,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login failed: status %d: %s", resp.StatusCode, body)
+	}
+	var result struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("failed to decode login response: %v", err)
+	}
+	if result.Data.Token == "" {
+		t.Fatalf("login response has no token: %s", body)
+	}
+	return result.Data.Token
+}
+
+func randomWallet(t testing.TB) string {
+	t.Helper()
+
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("failed to generate wallet: %v", err)
+	}
+	return "0x" + hex.EncodeToString(b)
+}
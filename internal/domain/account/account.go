@@ -0,0 +1,183 @@
+package account
+
+import (
+	"errors"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 支持的 CAIP-2 命名空间
+const (
+	NamespaceEIP155 = "eip155"
+	NamespaceSolana = "solana"
+)
+
+// SolanaMainnet Solana 主网的 CAIP-2 reference（创世区块哈希前 32 个字符）
+const SolanaMainnet = "5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp"
+
+var (
+	ErrInvalidAccount       = errors.New("invalid account id")
+	ErrUnsupportedNamespace = errors.New("unsupported account namespace")
+)
+
+var (
+	namespacePattern = regexp.MustCompile(`^[-a-z0-9]{3,8}$`)
+	referencePattern = regexp.MustCompile(`^[-_a-zA-Z0-9]{1,32}$`)
+	evmPattern       = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	solanaPattern    = regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{32,44}$`)
+)
+
+// ID CAIP-10 账户标识（namespace:reference:address）
+type ID struct {
+	Namespace string
+	Reference string
+	Address   string
+}
+
+// Parse 解析账户标识
+// 支持 CAIP-10、did:pkh（含旧版 did:pkh:eth:<address>）以及裸地址：
+// 0x 开头的地址视为以太坊主网，规范 base58 编码的 32 字节公钥视为 Solana 主网，其他写法一律拒绝。
+func Parse(raw string) (ID, error) {
+	raw = strings.TrimSpace(raw)
+	if rest, ok := strings.CutPrefix(raw, "did:pkh:"); ok {
+		if addr, ok := strings.CutPrefix(rest, "eth:"); ok {
+			return newID(NamespaceEIP155, "1", addr)
+		}
+		if addr, ok := strings.CutPrefix(rest, "sol:"); ok {
+			return newID(NamespaceSolana, SolanaMainnet, addr)
+		}
+		raw = rest
+	}
+
+	parts := strings.Split(raw, ":")
+	switch len(parts) {
+	case 1:
+		if strings.HasPrefix(raw, "0x") {
+			return newID(NamespaceEIP155, "1", raw)
+		}
+		if !solanaPattern.MatchString(raw) {
+			return ID{}, ErrInvalidAccount
+		}
+		return newID(NamespaceSolana, SolanaMainnet, raw)
+	case 3:
+		return newID(parts[0], parts[1], parts[2])
+	default:
+		return ID{}, ErrInvalidAccount
+	}
+}
+
+func newID(namespace, reference, address string) (ID, error) {
+	if !namespacePattern.MatchString(namespace) || !referencePattern.MatchString(reference) {
+		return ID{}, ErrInvalidAccount
+	}
+	switch namespace {
+	case NamespaceEIP155:
+		if _, err := strconv.ParseUint(reference, 10, 64); err != nil || reference == "0" {
+			return ID{}, ErrInvalidAccount
+		}
+		if !evmPattern.MatchString(address) {
+			return ID{}, ErrInvalidAccount
+		}
+		address = strings.ToLower(address)
+	case NamespaceSolana:
+		// 必须是 32 字节公钥的规范编码，拒绝多余的前导 1 等非规范写法
+		key, err := DecodeBase58(address)
+		if err != nil || len(key) != 32 || EncodeBase58(key) != address {
+			return ID{}, ErrInvalidAccount
+		}
+	default:
+		return ID{}, ErrUnsupportedNamespace
+	}
+	return ID{Namespace: namespace, Reference: reference, Address: address}, nil
+}
+
+// String 返回 CAIP-10 格式
+func (id ID) String() string {
+	return id.Namespace + ":" + id.Reference + ":" + id.Address
+}
+
+// DID 返回 did:pkh 标识
+func (id ID) DID() string {
+	return "did:pkh:" + id.String()
+}
+
+// IsEVM 是否为 EVM 账户
+func (id ID) IsEVM() bool {
+	return id.Namespace == NamespaceEIP155
+}
+
+// ChainID EVM 链 ID，非 EVM 账户返回 0
+func (id ID) ChainID() uint64 {
+	if !id.IsEVM() {
+		return 0
+	}
+	v, _ := strconv.ParseUint(id.Reference, 10, 64)
+	return v
+}
+
+// Key 用户存储键，统一为 CAIP-10
+// EVM 地址在所有链上相同，链 ID 固定为 1（eip155:1:<小写地址>）；其他命名空间使用完整 CAIP-10。
+func (id ID) Key() string {
+	if id.IsEVM() {
+		return NamespaceEIP155 + ":1:" + id.Address
+	}
+	return id.String()
+}
+
+// Normalize 将任意支持的账户写法转换为存储键
+func Normalize(raw string) (string, error) {
+	id, err := Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	return id.Key(), nil
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// DecodeBase58 解码 Bitcoin 字母表的 base58 字符串
+func DecodeBase58(input string) ([]byte, error) {
+	if input == "" {
+		return nil, ErrInvalidAccount
+	}
+	result := new(big.Int)
+	radix := big.NewInt(58)
+	for _, r := range input {
+		index := strings.IndexRune(base58Alphabet, r)
+		if index < 0 {
+			return nil, ErrInvalidAccount
+		}
+		result.Mul(result, radix)
+		result.Add(result, big.NewInt(int64(index)))
+	}
+	decoded := result.Bytes()
+	leading := 0
+	for leading < len(input) && input[leading] == '1' {
+		leading++
+	}
+	return append(make([]byte, leading), decoded...), nil
+}
+
+// EncodeBase58 使用 Bitcoin 字母表编码
+func EncodeBase58(input []byte) string {
+	value := new(big.Int).SetBytes(input)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range input {
+		if b != 0 {
+			break
+		}
+		out = append(out, '1')
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
@@ -0,0 +1,67 @@
+package account
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+const (
+	solanaAddress = "7S3P4HxJpyyigGzodYwHtCxZyUQe9JiBMHyRWXArAaKv"
+	evmKey        = "eip155:1:0x742d35cc6634c0532925a3b844bc9e7595f0beb0"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		raw string
+		key string
+		ref string
+	}{
+		{"0x742d35Cc6634C0532925a3b844Bc9e7595f0bEb0", evmKey, "1"},
+		{"eip155:137:0x742D35CC6634C0532925A3B844BC9E7595F0BEB0", evmKey, "137"},
+		{"did:pkh:eth:0x742d35cc6634c0532925a3b844bc9e7595f0beb0", evmKey, "1"},
+		{"did:pkh:eip155:8453:0x742d35cc6634c0532925a3b844bc9e7595f0beb0", evmKey, "8453"},
+		{solanaAddress, "solana:" + SolanaMainnet + ":" + solanaAddress, SolanaMainnet},
+		{"did:pkh:sol:" + solanaAddress, "solana:" + SolanaMainnet + ":" + solanaAddress, SolanaMainnet},
+		{"did:pkh:solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1:" + solanaAddress, "solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1:" + solanaAddress, "EtWTRABZaYq6iMfeYKouRu166VU2xqa1"},
+	}
+	for _, tc := range cases {
+		id, err := Parse(tc.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", tc.raw, err)
+		}
+		if id.Key() != tc.key || id.Reference != tc.ref {
+			t.Fatalf("Parse(%q) = key %q ref %q", tc.raw, id.Key(), id.Reference)
+		}
+	}
+
+	invalid := []string{
+		"", "0x1234", "eip155:0:0x742d35cc6634c0532925a3b844bc9e7595f0beb0", "solana:" + SolanaMainnet + ":0OIl", "did:web:example.com",
+		// 裸字符串不再默认视为 Solana 地址
+		"alice", "742d35cc6634c0532925a3b844bc9e7595f0beb0", "0X742d35cc6634c0532925a3b844bc9e7595f0beb0",
+		solanaAddress[:30], "1" + solanaAddress, solanaAddress + "0",
+	}
+	for _, raw := range invalid {
+		if _, err := Parse(raw); err == nil {
+			t.Fatalf("expected %q to be rejected", raw)
+		}
+	}
+	if _, err := Parse("cosmos:cosmoshub-4:cosmos1abc"); !errors.Is(err, ErrUnsupportedNamespace) {
+		t.Fatalf("expected unsupported namespace, got %v", err)
+	}
+}
+
+func TestBase58RoundTrip(t *testing.T) {
+	raw, err := DecodeBase58(solanaAddress)
+	if err != nil || len(raw) != 32 {
+		t.Fatalf("DecodeBase58 returned %d bytes, err=%v", len(raw), err)
+	}
+	if got := EncodeBase58(raw); got != solanaAddress {
+		t.Fatalf("EncodeBase58 = %q", got)
+	}
+	padded := append([]byte{0, 0}, raw...)
+	decoded, err := DecodeBase58(EncodeBase58(padded))
+	if err != nil || !bytes.Equal(decoded, padded) {
+		t.Fatalf("leading zero bytes not preserved: %x err=%v", decoded, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yeying-community/warehouse/internal/domain/account"
 )
 
 var (
@@ -46,6 +47,14 @@ func NewGroup(userID, name string) (*Group, error) {
 	}, nil
 }
 
+// NormalizeWallet 联系人钱包地址转换为账户存储键，无法识别的写法按小写保存
+func NormalizeWallet(wallet string) string {
+	if key, err := account.Normalize(wallet); err == nil {
+		return key
+	}
+	return strings.ToLower(strings.TrimSpace(wallet))
+}
+
 func NewContact(userID, groupID, name, walletAddress string, tags []string) (*Contact, error) {
 	name = strings.TrimSpace(name)
 	if name == "" {
@@ -61,7 +70,7 @@ func NewContact(userID, groupID, name, walletAddress string, tags []string) (*Co
 		UserID:        userID,
 		GroupID:       groupID,
 		Name:          name,
-		WalletAddress: strings.ToLower(walletAddress),
+		WalletAddress: NormalizeWallet(walletAddress),
 		Tags:          tags,
 		CreatedAt:     now,
 	}, nil
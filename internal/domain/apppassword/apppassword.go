@@ -0,0 +1,147 @@
+package apppassword
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAppPasswordNotFound = errors.New("app password not found")
+	ErrInvalidName         = errors.New("app password name must be 1-64 characters")
+	ErrInvalidPath         = errors.New("app password path must be an absolute path")
+	ErrTooManyPaths        = errors.New("too many app password paths")
+	ErrTooManyAppPasswords = errors.New("too many app passwords")
+)
+
+const (
+	// MaxPerUser 每个用户最多创建的应用专用密码数
+	MaxPerUser = 50
+	// MaxPaths 单个应用专用密码最多限制的路径数
+	MaxPaths = 16
+
+	secretGroups    = 6
+	secretGroupSize = 4
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// AppPassword 应用专用密码
+// 供只能使用 Basic 认证的 WebDAV 客户端（Finder、Windows、davfs2）按设备单独登录，
+// 服务端只保存 SHA-256 哈希，明文仅在创建时返回一次。
+type AppPassword struct {
+	ID         string
+	UserID     string
+	Name       string
+	Hash       string
+	ReadOnly   bool
+	Paths      []string // 允许访问的路径前缀（相对用户根目录），为空表示不限制
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string
+}
+
+// New 创建应用专用密码，返回记录与明文密码
+func New(userID, name string, readOnly bool, paths []string) (*AppPassword, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > 64 {
+		return nil, "", ErrInvalidName
+	}
+	normalized, err := NormalizePaths(paths)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	return &AppPassword{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Name:      name,
+		Hash:      Hash(secret),
+		ReadOnly:  readOnly,
+		Paths:     normalized,
+		CreatedAt: time.Now(),
+	}, secret, nil
+}
+
+// Hash 计算密码哈希；忽略大小写、空格与分隔符，方便手动输入
+func Hash(secret string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(secret)))
+	sum := sha256.Sum256([]byte(cleaned))
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizePaths 规范化路径限制，包含 "/" 时表示不限制
+func NormalizePaths(paths []string) ([]string, error) {
+	if len(paths) > MaxPaths {
+		return nil, ErrTooManyPaths
+	}
+	seen := make(map[string]bool, len(paths))
+	out := make([]string, 0, len(paths))
+	for _, raw := range paths {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if !strings.HasPrefix(raw, "/") {
+			return nil, ErrInvalidPath
+		}
+		clean := path.Clean(raw)
+		if clean == "/" {
+			return []string{}, nil
+		}
+		if seen[clean] {
+			continue
+		}
+		seen[clean] = true
+		out = append(out, clean)
+	}
+	return out, nil
+}
+
+// Allows 是否允许访问路径；write 表示修改操作
+func (p *AppPassword) Allows(requestPath string, write bool) bool {
+	if write && p.ReadOnly {
+		return false
+	}
+	if len(p.Paths) == 0 {
+		return true
+	}
+	clean := path.Clean("/" + strings.TrimLeft(requestPath, "/"))
+	for _, prefix := range p.Paths {
+		if clean == prefix || strings.HasPrefix(clean, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// generateSecret 生成 xxxx-xxxx-xxxx-xxxx-xxxx-xxxx 形式的随机密码（120 bit）
+func generateSecret() (string, error) {
+	buf := make([]byte, secretGroups*secretGroupSize*5/8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate app password: %w", err)
+	}
+	encoded := strings.ToLower(secretEncoding.EncodeToString(buf))
+	groups := make([]string, 0, secretGroups)
+	for i := 0; i < secretGroups; i++ {
+		groups = append(groups, encoded[i*secretGroupSize:(i+1)*secretGroupSize])
+	}
+	return strings.Join(groups, "-"), nil
+}
@@ -0,0 +1,73 @@
+package apppassword
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewGeneratesHashedSecret(t *testing.T) {
+	p, secret, err := New("user-1", " Finder ", false, nil)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if p.Name != "Finder" {
+		t.Fatalf("unexpected name: %q", p.Name)
+	}
+	if len(secret) != 29 || strings.Count(secret, "-") != 5 {
+		t.Fatalf("unexpected secret format: %q", secret)
+	}
+	if p.Hash == secret || p.Hash != Hash(secret) {
+		t.Fatalf("hash should be derived from secret")
+	}
+	// 手动输入时忽略大小写与分隔符
+	if Hash(strings.ToUpper(strings.ReplaceAll(secret, "-", ""))) != p.Hash {
+		t.Fatalf("hash should ignore case and separators")
+	}
+
+	if _, _, err := New("user-1", "", false, nil); err != ErrInvalidName {
+		t.Fatalf("expected ErrInvalidName, got %v", err)
+	}
+	if _, _, err := New("user-1", "x", false, []string{"relative"}); err != ErrInvalidPath {
+		t.Fatalf("expected ErrInvalidPath, got %v", err)
+	}
+}
+
+func TestNormalizePaths(t *testing.T) {
+	got, err := NormalizePaths([]string{"/personal/photos/", "/personal/photos", " /apps/../personal/docs "})
+	if err != nil {
+		t.Fatalf("NormalizePaths failed: %v", err)
+	}
+	if len(got) != 2 || got[0] != "/personal/photos" || got[1] != "/personal/docs" {
+		t.Fatalf("unexpected paths: %v", got)
+	}
+	got, err = NormalizePaths([]string{"/personal", "/"})
+	if err != nil || len(got) != 0 {
+		t.Fatalf("root should remove the restriction: %v, %v", got, err)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	p := &AppPassword{ReadOnly: true, Paths: []string{"/personal/photos"}}
+	cases := []struct {
+		path  string
+		write bool
+		want  bool
+	}{
+		{"/personal/photos", false, true},
+		{"/personal/photos/2024/a.jpg", false, true},
+		{"/personal/photos/../docs/a.txt", false, false},
+		{"/personal/photoshop", false, false},
+		{"/personal/photos/a.jpg", true, false},
+		{"/", false, false},
+	}
+	for _, tc := range cases {
+		if got := p.Allows(tc.path, tc.write); got != tc.want {
+			t.Fatalf("Allows(%q, %v) = %v, want %v", tc.path, tc.write, got, tc.want)
+		}
+	}
+
+	unrestricted := &AppPassword{}
+	if !unrestricted.Allows("/anything", true) {
+		t.Fatalf("unrestricted app password should allow writes everywhere")
+	}
+}
@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"net/http"
+	"time"
+)
+
+// 操作结果
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// 审计查询分页限制
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Event 审计事件
+// 审计日志只追加不修改，每条记录描述一次认证、文件变更、分享、回收站或管理操作。
+type Event struct {
+	ID        int64
+	CreatedAt time.Time
+	Action    string
+	Outcome   string
+	ActorID   string
+	Actor     string
+	IP        string
+	UserAgent string
+	AppID     string
+	Target    string
+	Status    int
+	Detail    string
+}
+
+// OutcomeFromStatus 根据 HTTP 状态码推断操作结果
+func OutcomeFromStatus(status int) string {
+	switch {
+	case status == 0 || status < http.StatusBadRequest:
+		return OutcomeSuccess
+	case status == http.StatusUnauthorized, status == http.StatusForbidden, status == http.StatusTooManyRequests:
+		return OutcomeDenied
+	default:
+		return OutcomeFailure
+	}
+}
+
+// ValidOutcome 是否为合法的操作结果
+func ValidOutcome(outcome string) bool {
+	switch outcome {
+	case OutcomeSuccess, OutcomeFailure, OutcomeDenied:
+		return true
+	}
+	return false
+}
+
+// Filter 审计日志查询条件
+// Action 与 Target 按前缀匹配，其余字段精确匹配；零值表示不过滤。
+type Filter struct {
+	Actor   string
+	Action  string
+	Outcome string
+	Target  string
+	IP      string
+	From    time.Time
+	To      time.Time
+	Limit   int
+	Offset  int
+}
+
+// Normalize 修正分页参数
+func (f *Filter) Normalize() {
+	if f.Limit <= 0 {
+		f.Limit = DefaultLimit
+	}
+	if f.Limit > MaxLimit {
+		f.Limit = MaxLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+}
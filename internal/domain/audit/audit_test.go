@@ -0,0 +1,35 @@
+package audit
+
+import "testing"
+
+func TestOutcomeFromStatus(t *testing.T) {
+	cases := map[int]string{
+		0:   OutcomeSuccess,
+		200: OutcomeSuccess,
+		207: OutcomeSuccess,
+		400: OutcomeFailure,
+		401: OutcomeDenied,
+		403: OutcomeDenied,
+		404: OutcomeFailure,
+		429: OutcomeDenied,
+		500: OutcomeFailure,
+	}
+	for status, want := range cases {
+		if got := OutcomeFromStatus(status); got != want {
+			t.Fatalf("OutcomeFromStatus(%d) = %s, want %s", status, got, want)
+		}
+	}
+}
+
+func TestFilterNormalize(t *testing.T) {
+	f := Filter{Limit: 10000, Offset: -1}
+	f.Normalize()
+	if f.Limit != MaxLimit || f.Offset != 0 {
+		t.Fatalf("unexpected filter after normalize: %+v", f)
+	}
+	f = Filter{}
+	f.Normalize()
+	if f.Limit != DefaultLimit {
+		t.Fatalf("expected default limit, got %d", f.Limit)
+	}
+}
@@ -33,3 +33,14 @@ type BasicCredentials struct {
 type BearerCredentials struct {
 	Token string
 }
+
+// WebAuthnCredentials 通行密钥登录断言
+// SessionID 为获取登录参数时返回的仪式 ID，用于找回对应挑战。
+type WebAuthnCredentials struct {
+	SessionID         string
+	CredentialID      string
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
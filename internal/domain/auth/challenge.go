@@ -9,7 +9,13 @@ type Challenge struct {
 	Nonce     string
 	Message   string
 	Address   string
+	IssuedAt  time.Time
 	ExpiresAt time.Time
+
+	// EIP-4361 / SIWS 绑定信息，旧版挑战消息为空
+	Domain   string
+	ChainID  uint64 // EVM 链 ID
+	ChainRef string // 非 EVM 链的 CAIP-2 reference（如 Solana 创世哈希前缀）
 }
 
 // IsExpired 是否过期
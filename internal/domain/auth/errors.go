@@ -1,6 +1,10 @@
 package auth
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	// ErrInvalidToken 无效的 token
@@ -26,4 +30,24 @@ var (
 
 	// ErrAppScopeDenied UCAN app scope denied
 	ErrAppScopeDenied = errors.New("ucan app scope denied")
+
+	// ErrAppPasswordScopeDenied 超出应用专用密码的只读或路径限制
+	ErrAppPasswordScopeDenied = errors.New("app password scope denied")
+
+	// ErrAccountLocked 连续认证失败后账户被临时锁定
+	ErrAccountLocked = errors.New("account temporarily locked")
 )
+
+// LockedError 账户锁定错误，携带剩余锁定时间
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrAccountLocked, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap 使 errors.Is(err, ErrAccountLocked) 成立
+func (e *LockedError) Unwrap() error {
+	return ErrAccountLocked
+}
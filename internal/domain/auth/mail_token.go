@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+// 邮件链接令牌的用途，与访问令牌互不通用
+const (
+	MailTokenPasswordReset = "password_reset"
+	MailTokenEmailVerify   = "email_verify"
+)
+
+var (
+	// ErrTokenUsed 一次性令牌已被使用
+	ErrTokenUsed = errors.New("token already used")
+
+	// ErrMailTooFrequent 同一邮箱请求邮件过于频繁
+	ErrMailTooFrequent = errors.New("mail requested too frequently")
+)
+
+// MailToken 邮件链接中的一次性令牌
+// Email 为签发时的目标邮箱：重置密码时是账户邮箱，验证邮箱时是待验证（或待更换）的邮箱。
+type MailToken struct {
+	ID        string // jti，用于保证只能使用一次
+	Purpose   string
+	UserID    string
+	Email     string
+	ExpiresAt time.Time
+}
@@ -8,10 +8,18 @@ import (
 type Token struct {
 	Value     string
 	Address   string
+	ID        string // jti
+	SessionID string // 所属登录会话，无状态令牌为空
 	ExpiresAt time.Time
 	IssuedAt  time.Time
 }
 
+// TokenPair 一次登录或刷新签发的令牌
+type TokenPair struct {
+	Access  *Token
+	Refresh *Token
+}
+
 // IsExpired 是否过期
 func (t *Token) IsExpired() bool {
 	return time.Now().After(t.ExpiresAt)
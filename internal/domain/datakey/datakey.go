@@ -0,0 +1,39 @@
+package datakey
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrDataKeyNotFound   = errors.New("data key not found")
+	ErrMasterKeyMismatch = errors.New("data key wrapped by a different master key")
+)
+
+// DataKey 用户数据密钥实体（以主密钥加密保存）
+type DataKey struct {
+	UserID      string    // 所属用户 ID
+	Version     uint32    // 密钥版本（从 1 开始递增）
+	WrappedKey  []byte    // 主密钥加密后的数据密钥
+	MasterKeyID string    // 加密所用主密钥的标识
+	Active      bool      // 是否为新写入使用的版本
+	CreatedAt   time.Time // 创建时间
+}
+
+// NewDataKey 创建数据密钥记录
+func NewDataKey(userID string, version uint32, wrapped []byte, masterKeyID string) *DataKey {
+	return &DataKey{
+		UserID:      userID,
+		Version:     version,
+		WrappedKey:  wrapped,
+		MasterKeyID: masterKeyID,
+		Active:      true,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// AAD 返回加密数据密钥时绑定的附加数据（用户 ID 与版本），防止密钥被挪用到其他用户
+func (k *DataKey) AAD() []byte {
+	return []byte(k.UserID + ":" + strconv.FormatUint(uint64(k.Version), 10))
+}
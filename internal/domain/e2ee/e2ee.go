@@ -0,0 +1,98 @@
+package e2ee
+
+import (
+	"encoding/base64"
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yeying-community/warehouse/internal/domain/account"
+)
+
+// MaxEnvelopeSize 单个密钥信封（解码后）的最大字节数
+const MaxEnvelopeSize = 4096
+
+var (
+	ErrFolderNotFound      = errors.New("e2ee folder not found")
+	ErrFolderExists        = errors.New("e2ee folder already exists")
+	ErrFolderNotEmpty      = errors.New("folder must be empty to enable e2ee")
+	ErrPublicKeyNotFound   = errors.New("wallet public key not found")
+	ErrEnvelopeNotFound    = errors.New("key envelope not found")
+	ErrEnvelopeRequired    = errors.New("key envelope for the recipient is required")
+	ErrInvalidEnvelope     = errors.New("invalid key envelope")
+	ErrPlaintextOperation  = errors.New("operation not available in end-to-end encrypted folder")
+	ErrNestedEncryptedPath = errors.New("e2ee folders cannot be nested")
+	ErrFolderCopy          = errors.New("e2ee folders can be moved but not copied")
+	ErrCrossFolderTransfer = errors.New("items cannot be copied or moved into, out of or between e2ee folders")
+)
+
+// WalletPublicKey 钱包公钥（登录时由签名恢复）
+type WalletPublicKey struct {
+	WalletAddress string    // 小写钱包地址
+	PublicKey     []byte    // 65 字节未压缩 secp256k1 公钥
+	UpdatedAt     time.Time // 最近一次登录记录时间
+}
+
+// Folder 端到端加密目录
+// 目录内容由客户端加密后上传，服务端只保存密文与各接收方的密钥信封。
+type Folder struct {
+	ID          string
+	OwnerUserID string
+	Path        string // 相对用户根目录的路径，以 / 开头
+	CreatedAt   time.Time
+}
+
+// NewFolder 创建端到端加密目录记录
+func NewFolder(ownerUserID, folderPath string) *Folder {
+	return &Folder{
+		ID:          uuid.NewString(),
+		OwnerUserID: ownerUserID,
+		Path:        folderPath,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// Contains 判断路径是否位于该目录内（含目录本身）
+func (f *Folder) Contains(p string) bool {
+	p = path.Clean("/" + strings.TrimPrefix(p, "/"))
+	return p == f.Path || strings.HasPrefix(p, strings.TrimSuffix(f.Path, "/")+"/")
+}
+
+// KeyEnvelope 目录密钥信封
+// Envelope 为客户端以接收方钱包公钥加密（ECIES）的目录密钥，服务端不解析其内容。
+type KeyEnvelope struct {
+	FolderID        string
+	RecipientWallet string // 小写钱包地址
+	Envelope        string // base64 编码的密文
+	CreatedBy       string // 写入信封的用户 ID
+	CreatedAt       time.Time
+}
+
+// NewKeyEnvelope 创建密钥信封并校验编码与大小
+func NewKeyEnvelope(folderID, recipientWallet, envelope, createdBy string) (*KeyEnvelope, error) {
+	envelope = strings.TrimSpace(envelope)
+	if envelope == "" {
+		return nil, ErrInvalidEnvelope
+	}
+	raw, err := base64.StdEncoding.DecodeString(envelope)
+	if err != nil || len(raw) == 0 || len(raw) > MaxEnvelopeSize {
+		return nil, ErrInvalidEnvelope
+	}
+	return &KeyEnvelope{
+		FolderID:        folderID,
+		RecipientWallet: NormalizeWallet(recipientWallet),
+		Envelope:        envelope,
+		CreatedBy:       createdBy,
+		CreatedAt:       time.Now(),
+	}, nil
+}
+
+// NormalizeWallet 接收方钱包转换为账户存储键，无法识别的写法按小写保存
+func NormalizeWallet(wallet string) string {
+	if key, err := account.Normalize(wallet); err == nil {
+		return key
+	}
+	return strings.ToLower(strings.TrimSpace(wallet))
+}
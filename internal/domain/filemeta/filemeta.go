@@ -0,0 +1,74 @@
+package filemeta
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	ErrFileMetadataNotFound = errors.New("file metadata not found")
+	ErrInvalidContentHash   = errors.New("invalid content hash")
+)
+
+// FileMetadata 文件元数据实体（记录内容哈希）
+type FileMetadata struct {
+	UserID    string    // 所属用户 ID
+	Path      string    // 相对于用户目录的路径（以 / 开头）
+	SHA256    string    // 文件内容 SHA-256（小写十六进制）
+	Size      int64     // 计算哈希时的文件大小
+	ModTime   time.Time // 计算哈希时的文件修改时间
+	UpdatedAt time.Time // 记录更新时间
+}
+
+// NewFileMetadata 创建文件元数据
+func NewFileMetadata(userID, path, sum string, size int64, modTime time.Time) (*FileMetadata, error) {
+	if !IsValidSHA256(sum) {
+		return nil, ErrInvalidContentHash
+	}
+	return &FileMetadata{
+		UserID:    userID,
+		Path:      path,
+		SHA256:    sum,
+		Size:      size,
+		ModTime:   modTime,
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// Matches 判断元数据是否仍与磁盘上的文件一致
+func (m *FileMetadata) Matches(size int64, modTime time.Time) bool {
+	if m == nil {
+		return false
+	}
+	return m.Size == size && m.ModTime.Equal(modTime)
+}
+
+// ETag 返回强 ETag（带引号的 SHA-256）
+func (m *FileMetadata) ETag() string {
+	return `"` + m.SHA256 + `"`
+}
+
+// Digest 返回 RFC 3230 Digest 头的值
+func (m *FileMetadata) Digest() string {
+	raw, err := hex.DecodeString(m.SHA256)
+	if err != nil {
+		return ""
+	}
+	return "sha-256=" + base64.StdEncoding.EncodeToString(raw)
+}
+
+// OCChecksum 返回 OC-Checksum 头的值（ownCloud/Nextcloud 客户端格式）
+func (m *FileMetadata) OCChecksum() string {
+	return "SHA256:" + m.SHA256
+}
+
+// IsValidSHA256 判断是否为合法的十六进制 SHA-256
+func IsValidSHA256(sum string) bool {
+	if len(sum) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(sum)
+	return err == nil
+}
@@ -0,0 +1,93 @@
+package identity
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yeying-community/warehouse/internal/domain/account"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+)
+
+// Type 登录身份类型
+type Type string
+
+const (
+	// TypeWallet 钱包账户，Subject 为账户存储键（见 account.ID.Key）
+	TypeWallet Type = "wallet"
+	// TypeEmail 邮箱，Subject 为小写邮箱
+	TypeEmail Type = "email"
+	// TypePassword 用户名密码，Subject 为用户名，密码哈希仍保存在 users.password
+	TypePassword Type = "password"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrIdentityInUse    = errors.New("identity is linked to another account")
+	ErrInvalidIdentity  = errors.New("invalid identity")
+	ErrLastIdentity     = errors.New("cannot unlink the last sign-in identity")
+	ErrSameAccount      = errors.New("identity already belongs to this account")
+	ErrMergeBlocked     = errors.New("account cannot be merged")
+)
+
+// Identity 用户的一种登录身份
+// 一个用户可以关联多个钱包（跨链）、邮箱与一个用户名密码，每个身份只能属于一个用户。
+type Identity struct {
+	ID        string
+	UserID    string
+	Type      Type
+	Subject   string
+	CreatedAt time.Time
+}
+
+// New 创建身份并规范化 subject
+func New(userID string, t Type, subject string) (*Identity, error) {
+	normalized, err := NormalizeSubject(t, subject)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Type:      t,
+		Subject:   normalized,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// NormalizeSubject 按身份类型规范化 subject
+func NormalizeSubject(t Type, subject string) (string, error) {
+	subject = strings.TrimSpace(subject)
+	switch t {
+	case TypeWallet:
+		key, err := account.Normalize(subject)
+		if err != nil {
+			return "", ErrInvalidIdentity
+		}
+		return key, nil
+	case TypeEmail:
+		subject = strings.ToLower(subject)
+		if !user.IsValidEmail(subject) {
+			return "", ErrInvalidIdentity
+		}
+		return subject, nil
+	case TypePassword:
+		if subject == "" {
+			return "", ErrInvalidIdentity
+		}
+		return subject, nil
+	default:
+		return "", ErrInvalidIdentity
+	}
+}
+
+// ParseType 解析身份类型
+func ParseType(raw string) (Type, error) {
+	switch t := Type(strings.ToLower(strings.TrimSpace(raw))); t {
+	case TypeWallet, TypeEmail, TypePassword:
+		return t, nil
+	default:
+		return "", ErrInvalidIdentity
+	}
+}
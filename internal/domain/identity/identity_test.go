@@ -0,0 +1,51 @@
+package identity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeSubject(t *testing.T) {
+	cases := []struct {
+		t       Type
+		subject string
+		want    string
+	}{
+		{TypeWallet, "0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "eip155:1:0x71c7656ec7ab88b098defb751b7401b5f6d8976f"},
+		{TypeWallet, "eip155:137:0x71C7656EC7ab88b098defB751B7401B5f6d8976F", "eip155:1:0x71c7656ec7ab88b098defb751b7401b5f6d8976f"},
+		{TypeEmail, "  Alice@Example.COM ", "alice@example.com"},
+		{TypePassword, " alice ", "alice"},
+	}
+	for _, c := range cases {
+		got, err := NormalizeSubject(c.t, c.subject)
+		if err != nil {
+			t.Fatalf("NormalizeSubject(%s, %q) returned error: %v", c.t, c.subject, err)
+		}
+		if got != c.want {
+			t.Fatalf("NormalizeSubject(%s, %q) = %q, want %q", c.t, c.subject, got, c.want)
+		}
+	}
+
+	for _, c := range []struct {
+		t       Type
+		subject string
+	}{
+		{TypeWallet, "not-a-wallet"},
+		{TypeEmail, "alice"},
+		{TypePassword, " "},
+		{Type("phone"), "123"},
+	} {
+		if _, err := NormalizeSubject(c.t, c.subject); !errors.Is(err, ErrInvalidIdentity) {
+			t.Fatalf("NormalizeSubject(%s, %q) should fail, got %v", c.t, c.subject, err)
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	if got, err := ParseType(" Wallet "); err != nil || got != TypeWallet {
+		t.Fatalf("ParseType returned %q, %v", got, err)
+	}
+	if _, err := ParseType("phone"); !errors.Is(err, ErrInvalidIdentity) {
+		t.Fatalf("expected unknown type to be rejected, got %v", err)
+	}
+}
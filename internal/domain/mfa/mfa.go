@@ -0,0 +1,170 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotEnrolled         = errors.New("two-factor authentication is not enrolled")
+	ErrAlreadyEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrInvalidCode         = errors.New("invalid two-factor code")
+	ErrTooManyAttempts     = errors.New("too many two-factor attempts, try again later")
+	ErrRequired            = errors.New("two-factor authentication is required for this account")
+	ErrInvalidRecoveryCode = errors.New("invalid recovery code")
+)
+
+const (
+	// Period TOTP 时间步长
+	Period = 30 * time.Second
+	// Digits TOTP 位数
+	Digits = 6
+	// Skew 允许前后偏移的时间步数
+	Skew = 1
+	// RecoveryCodeCount 每次生成的恢复码数量
+	RecoveryCodeCount = 10
+
+	secretSize = 20
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Enrollment 用户的 TOTP 登记
+// Secret 为 base32 编码的共享密钥；确认前 Enabled 为 false，不参与登录校验。
+// Required 由管理员设置，表示该用户必须完成两步验证才能登录。
+type Enrollment struct {
+	UserID        string
+	Secret        string
+	Enabled       bool
+	Required      bool
+	LastCounter   int64    // 最近一次通过校验的时间步，防止同一验证码重放
+	RecoveryCodes []string // 恢复码的 SHA-256 哈希，使用后移除
+	CreatedAt     time.Time
+	EnabledAt     *time.Time
+}
+
+// GenerateSecret 生成新的 TOTP 共享密钥（160 bit，base32）
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	return secretEncoding.EncodeToString(buf), nil
+}
+
+// ProvisioningURI 生成认证器应用扫码使用的 otpauth:// URI
+func ProvisioningURI(secret, issuer, account string) string {
+	label := url.PathEscape(account)
+	if issuer != "" {
+		label = url.PathEscape(issuer) + ":" + label
+	}
+	q := url.Values{}
+	q.Set("secret", secret)
+	if issuer != "" {
+		q.Set("issuer", issuer)
+	}
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprintf("%d", Digits))
+	q.Set("period", fmt.Sprintf("%d", int(Period/time.Second)))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// Code 计算指定时间步的 TOTP 验证码（RFC 6238，HMAC-SHA1）
+func Code(secret string, counter int64) (string, error) {
+	key, err := secretEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret: %w", err)
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
+
+// Counter 返回时间所在的时间步
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Validate 校验验证码，成功时返回匹配的时间步
+// 只接受大于 lastCounter 的时间步，已使用过的验证码不能再次使用。
+func Validate(secret, code string, now time.Time, lastCounter int64) (int64, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != Digits {
+		return 0, ErrInvalidCode
+	}
+	current := Counter(now)
+	for c := current - Skew; c <= current+Skew; c++ {
+		if c <= lastCounter {
+			continue
+		}
+		expected, err := Code(secret, c)
+		if err != nil {
+			return 0, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return c, nil
+		}
+	}
+	return 0, ErrInvalidCode
+}
+
+// GenerateRecoveryCodes 生成一组恢复码，返回明文与对应哈希
+func GenerateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	hashes := make([]string, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		encoded := strings.ToLower(secretEncoding.EncodeToString(buf))
+		code := encoded[:4] + "-" + encoded[4:]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode 计算恢复码哈希；忽略大小写、空格与分隔符
+func HashRecoveryCode(code string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(cleaned))
+	return hex.EncodeToString(sum[:])
+}
+
+// UseRecoveryCode 消耗一个恢复码，成功时从列表中移除
+func (e *Enrollment) UseRecoveryCode(code string) error {
+	hash := HashRecoveryCode(code)
+	for i, stored := range e.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			e.RecoveryCodes = append(e.RecoveryCodes[:i:i], e.RecoveryCodes[i+1:]...)
+			return nil
+		}
+	}
+	return ErrInvalidRecoveryCode
+}
@@ -0,0 +1,81 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试向量（密钥 "12345678901234567890"，取后 6 位）
+func TestCodeRFC6238Vectors(t *testing.T) {
+	secret := secretEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range cases {
+		got, err := Code(secret, Counter(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatalf("Code failed: %v", err)
+		}
+		if got != want {
+			t.Fatalf("unexpected code at %d: got %s want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidateRejectsReplay(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret failed: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	code, err := Code(secret, Counter(now))
+	if err != nil {
+		t.Fatalf("Code failed: %v", err)
+	}
+
+	counter, err := Validate(secret, code, now.Add(20*time.Second), 0)
+	if err != nil {
+		t.Fatalf("Validate within skew failed: %v", err)
+	}
+	if _, err := Validate(secret, code, now, counter); err != ErrInvalidCode {
+		t.Fatalf("replayed code should be rejected, got %v", err)
+	}
+	if _, err := Validate(secret, code, now.Add(5*time.Minute), 0); err != ErrInvalidCode {
+		t.Fatalf("expired code should be rejected, got %v", err)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes failed: %v", err)
+	}
+	if len(codes) != RecoveryCodeCount || len(hashes) != RecoveryCodeCount {
+		t.Fatalf("unexpected recovery code count: %d", len(codes))
+	}
+	e := &Enrollment{RecoveryCodes: hashes}
+	if err := e.UseRecoveryCode(strings.ToUpper(codes[3])); err != nil {
+		t.Fatalf("UseRecoveryCode failed: %v", err)
+	}
+	if len(e.RecoveryCodes) != RecoveryCodeCount-1 {
+		t.Fatalf("recovery code should be consumed")
+	}
+	if err := e.UseRecoveryCode(codes[3]); err != ErrInvalidRecoveryCode {
+		t.Fatalf("used recovery code should be rejected, got %v", err)
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("JBSWY3DPEHPK3PXP", "Warehouse", "alice@example.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/Warehouse:alice@example.com?") {
+		t.Fatalf("unexpected uri: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=Warehouse") {
+		t.Fatalf("uri missing parameters: %s", uri)
+	}
+}
@@ -0,0 +1,159 @@
+// Package outbox 定义邮件发件箱
+// 业务代码只把邮件写入发件箱，由后台任务渲染模板并通过 SMTP 发送；
+// 发送失败按指数退避重试，超过最大次数后转为死信，等待管理员排查后手动重试。
+package outbox
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 邮件状态
+const (
+	StatusPending = "pending" // 等待发送或等待重试
+	StatusSent    = "sent"
+	StatusDead    = "dead" // 重试次数用尽或不可重试的失败
+)
+
+// 发件箱查询分页限制
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// maxErrorLength 记录的失败原因最大长度
+const maxErrorLength = 1000
+
+var (
+	ErrMessageNotFound = errors.New("mail message not found")
+	ErrNotDead         = errors.New("only dead messages can be retried")
+)
+
+// Message 发件箱中的一封邮件
+// Data 是模板数据，发送成功后清空，避免验证码、重置链接等长期留在数据库中。
+type Message struct {
+	ID            string
+	Recipient     string
+	Template      string
+	Locale        string
+	Data          map[string]any
+	Status        string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	SentAt        *time.Time
+}
+
+// NewMessage 创建待发送的邮件
+func NewMessage(recipient, template, locale string, data map[string]any) *Message {
+	now := time.Now()
+	if data == nil {
+		data = map[string]any{}
+	}
+	return &Message{
+		ID:            uuid.NewString(),
+		Recipient:     recipient,
+		Template:      template,
+		Locale:        locale,
+		Data:          data,
+		Status:        StatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// MarkSent 标记发送成功
+func (m *Message) MarkSent(now time.Time) {
+	m.Status = StatusSent
+	m.Attempts++
+	m.LastError = ""
+	m.Data = map[string]any{}
+	m.SentAt = &now
+	m.UpdatedAt = now
+}
+
+// Fail 记录一次发送失败：未达到 maxAttempts 时按指数退避安排重试，否则转为死信
+func (m *Message) Fail(reason string, now time.Time, maxAttempts int, base, maxDelay time.Duration) {
+	m.Attempts++
+	m.LastError = truncate(reason)
+	m.UpdatedAt = now
+	if m.Attempts >= maxAttempts {
+		m.Status = StatusDead
+		return
+	}
+	m.NextAttemptAt = now.Add(Backoff(base, maxDelay, m.Attempts))
+}
+
+// Kill 记录不可重试的失败（如模板缺失），直接转为死信
+func (m *Message) Kill(reason string, now time.Time) {
+	m.Attempts++
+	m.LastError = truncate(reason)
+	m.Status = StatusDead
+	m.UpdatedAt = now
+}
+
+// Retry 将死信重新放回发送队列，重新计算重试次数；失败原因保留到下次发送
+func (m *Message) Retry(now time.Time) error {
+	if m.Status != StatusDead {
+		return ErrNotDead
+	}
+	m.Status = StatusPending
+	m.Attempts = 0
+	m.NextAttemptAt = now
+	m.UpdatedAt = now
+	return nil
+}
+
+// Backoff 第 attempts 次失败后的重试间隔：base * 2^(attempts-1)，不超过 maxDelay
+func Backoff(base, maxDelay time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// ValidStatus 是否为合法的邮件状态
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusSent, StatusDead:
+		return true
+	}
+	return false
+}
+
+// Filter 发件箱查询条件，零值表示不过滤
+type Filter struct {
+	Status    string
+	Recipient string
+	Limit     int
+	Offset    int
+}
+
+// Normalize 修正分页参数
+func (f *Filter) Normalize() {
+	if f.Limit <= 0 {
+		f.Limit = DefaultLimit
+	}
+	if f.Limit > MaxLimit {
+		f.Limit = MaxLimit
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+}
+
+func truncate(reason string) string {
+	if len(reason) > maxErrorLength {
+		return reason[:maxErrorLength]
+	}
+	return reason
+}
@@ -0,0 +1,47 @@
+package outbox
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1: time.Minute,
+		2: 2 * time.Minute,
+		3: 4 * time.Minute,
+		5: 10 * time.Minute,
+		9: 10 * time.Minute,
+	}
+	for attempts, want := range cases {
+		if got := Backoff(time.Minute, 10*time.Minute, attempts); got != want {
+			t.Fatalf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestMessageLifecycle(t *testing.T) {
+	now := time.Now()
+	m := NewMessage("alice@example.com", "email_code_login", "", map[string]any{"code": "123456"})
+	if err := m.Retry(now); !errors.Is(err, ErrNotDead) {
+		t.Fatalf("expected ErrNotDead, got %v", err)
+	}
+
+	m.Fail("451 try again", now, 2, time.Minute, time.Hour)
+	if m.Status != StatusPending || m.Attempts != 1 || !m.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("unexpected message after first failure: %+v", m)
+	}
+	m.Fail("451 try again", now, 2, time.Minute, time.Hour)
+	if m.Status != StatusDead || m.LastError != "451 try again" {
+		t.Fatalf("expected dead letter, got %+v", m)
+	}
+
+	if err := m.Retry(now); err != nil || m.Status != StatusPending || m.Attempts != 0 {
+		t.Fatalf("Retry: %v %+v", err, m)
+	}
+	m.MarkSent(now)
+	if m.Status != StatusSent || m.SentAt == nil || len(m.Data) != 0 || m.LastError != "" {
+		t.Fatalf("unexpected message after send: %+v", m)
+	}
+}
@@ -33,14 +33,45 @@ type Service interface {
 	UpdateUserSpace(ctx context.Context, u *user.User, userRepository user.Repository) error
 }
 
+// SharedBlobPolicy 去重共享内容的配额计算策略
+type SharedBlobPolicy string
+
+const (
+	// SharedBlobFull 每个引用都按完整大小计入
+	SharedBlobFull SharedBlobPolicy = "full"
+	// SharedBlobSplit 按引用数平摊 blob 大小
+	SharedBlobSplit SharedBlobPolicy = "split"
+	// SharedBlobOnce 同一用户目录内引用同一 blob 的文件只计一次
+	SharedBlobOnce SharedBlobPolicy = "once"
+)
+
+// LinkInfoFunc 返回文件的唯一标识与硬链接数，不支持时 ok 为 false
+type LinkInfoFunc func(info os.FileInfo) (id string, links uint64, ok bool)
+
 type service struct {
 	userRepo user.Repository
+	policy   SharedBlobPolicy
+	linkInfo LinkInfoFunc
 }
 
 // NewService 创建配额服务
 func NewService(userRepo user.Repository) Service {
 	return &service{
 		userRepo: userRepo,
+		policy:   SharedBlobFull,
+	}
+}
+
+// NewServiceWithPolicy 创建带共享 blob 配额策略的配额服务
+// blob 存储自身持有一个硬链接，因此引用数为链接数减一
+func NewServiceWithPolicy(userRepo user.Repository, policy SharedBlobPolicy, linkInfo LinkInfoFunc) Service {
+	if policy == "" {
+		policy = SharedBlobFull
+	}
+	return &service{
+		userRepo: userRepo,
+		policy:   policy,
+		linkInfo: linkInfo,
 	}
 }
 
@@ -76,6 +107,7 @@ func (s *service) GetQuota(ctx context.Context, userID string) (*QuotaInfo, erro
 // CalculateUsedSpace 计算用户已使用空间
 func (s *service) CalculateUsedSpace(ctx context.Context, userDirectory string) (int64, error) {
 	var totalSize int64
+	seen := make(map[string]struct{})
 
 	err := filepath.WalkDir(userDirectory, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -96,7 +128,7 @@ func (s *service) CalculateUsedSpace(ctx context.Context, userDirectory string)
 				// 忽略无法获取信息的文件
 				return nil
 			}
-			totalSize += info.Size()
+			totalSize += s.chargedSize(info, seen)
 		}
 
 		return nil
@@ -109,6 +141,28 @@ func (s *service) CalculateUsedSpace(ctx context.Context, userDirectory string)
 	return totalSize, nil
 }
 
+// chargedSize 根据共享 blob 策略计算文件计入配额的大小
+func (s *service) chargedSize(info os.FileInfo, seen map[string]struct{}) int64 {
+	size := info.Size()
+	if s.policy == SharedBlobFull || s.linkInfo == nil {
+		return size
+	}
+	id, links, ok := s.linkInfo(info)
+	if !ok || links <= 1 {
+		return size
+	}
+	switch s.policy {
+	case SharedBlobSplit:
+		return size / int64(links-1)
+	case SharedBlobOnce:
+		if _, ok := seen[id]; ok {
+			return 0
+		}
+		seen[id] = struct{}{}
+	}
+	return size
+}
+
 // CheckQuota 检查用户配额
 func (s *service) CheckQuota(ctx context.Context, u *user.User, additionalSize int64) error {
 	if u == nil {
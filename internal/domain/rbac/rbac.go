@@ -0,0 +1,230 @@
+package rbac
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrRoleNotFound        = errors.New("role not found")
+	ErrRoleExists          = errors.New("role already exists")
+	ErrBuiltInRole         = errors.New("built-in roles cannot be modified")
+	ErrInvalidRoleName     = errors.New("invalid role name")
+	ErrUnknownPermission   = errors.New("unknown permission")
+	ErrAssignmentNotFound  = errors.New("role assignment not found")
+	ErrLastSuperadmin      = errors.New("cannot remove the last superadmin")
+	ErrPermissionDenied    = errors.New("permission denied")
+	ErrPrivilegeEscalation = errors.New("cannot grant or act on permissions beyond your own")
+)
+
+// Permission 管理接口权限
+type Permission string
+
+const (
+	// PermAll 全部权限，只授予 superadmin
+	PermAll Permission = "*"
+
+	// PermUsersRead 查看用户列表与锁定状态
+	PermUsersRead Permission = "users.read"
+	// PermUsersWrite 创建、修改、删除用户
+	PermUsersWrite Permission = "users.write"
+	// PermUsersSecurity 重置密码、吊销会话、设置两步验证、解除锁定
+	PermUsersSecurity Permission = "users.security"
+	// PermRolesRead 查看角色与分配情况
+	PermRolesRead Permission = "roles.read"
+	// PermRolesAssign 为用户分配或撤销角色
+	PermRolesAssign Permission = "roles.assign"
+	// PermRolesManage 创建、修改、删除自定义角色
+	PermRolesManage Permission = "roles.manage"
+	// PermAuditRead 查看审计日志
+	PermAuditRead Permission = "audit.read"
+	// PermMailRead 查看邮件发件箱与发送失败的邮件
+	PermMailRead Permission = "mail.read"
+	// PermMailWrite 重新发送死信邮件
+	PermMailWrite Permission = "mail.write"
+)
+
+// AllPermissions 全部可分配的权限（不含通配符）
+func AllPermissions() []Permission {
+	return []Permission{
+		PermUsersRead,
+		PermUsersWrite,
+		PermUsersSecurity,
+		PermRolesRead,
+		PermRolesAssign,
+		PermRolesManage,
+		PermAuditRead,
+		PermMailRead,
+		PermMailWrite,
+	}
+}
+
+// 内置角色名
+const (
+	RoleSuperadmin  = "superadmin"
+	RoleUserManager = "user-manager"
+	RoleAuditor     = "auditor"
+	RoleSupport     = "support"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,63}$`)
+
+// Role 角色：一组管理权限
+// 内置角色在启动时写入数据库，不能通过接口修改或删除。
+type Role struct {
+	Name        string
+	Description string
+	Permissions []Permission
+	BuiltIn     bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Assignment 用户的角色分配
+type Assignment struct {
+	UserID    string
+	Role      string
+	GrantedBy string // 授予者用户 ID，命令行或配置引导时为空
+	CreatedAt time.Time
+}
+
+// BuiltinRoles 内置角色
+func BuiltinRoles() []*Role {
+	return []*Role{
+		{
+			Name:        RoleSuperadmin,
+			Description: "Full access to every admin endpoint",
+			Permissions: []Permission{PermAll},
+			BuiltIn:     true,
+		},
+		{
+			Name:        RoleUserManager,
+			Description: "Manage users, their credentials, sessions and mail delivery",
+			Permissions: []Permission{PermUsersRead, PermUsersWrite, PermUsersSecurity, PermRolesRead, PermMailRead, PermMailWrite},
+			BuiltIn:     true,
+		},
+		{
+			Name:        RoleAuditor,
+			Description: "Read users, roles, the audit log and the mail outbox",
+			Permissions: []Permission{PermUsersRead, PermRolesRead, PermAuditRead, PermMailRead},
+			BuiltIn:     true,
+		},
+		{
+			Name:        RoleSupport,
+			Description: "Read-only access to users, roles and the mail outbox",
+			Permissions: []Permission{PermUsersRead, PermRolesRead, PermMailRead},
+			BuiltIn:     true,
+		},
+	}
+}
+
+// NewRole 创建自定义角色，校验名称与权限并去重排序
+func NewRole(name, description string, permissions []string) (*Role, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !roleNamePattern.MatchString(name) {
+		return nil, ErrInvalidRoleName
+	}
+	for _, builtin := range BuiltinRoles() {
+		if builtin.Name == name {
+			return nil, ErrBuiltInRole
+		}
+	}
+	perms, err := ParsePermissions(permissions)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &Role{
+		Name:        name,
+		Description: strings.TrimSpace(description),
+		Permissions: perms,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+// ParsePermissions 解析权限列表；通配符只允许内置角色使用
+func ParsePermissions(raw []string) ([]Permission, error) {
+	known := make(map[Permission]struct{})
+	for _, p := range AllPermissions() {
+		known[p] = struct{}{}
+	}
+	seen := make(map[Permission]struct{}, len(raw))
+	perms := make([]Permission, 0, len(raw))
+	for _, item := range raw {
+		p := Permission(strings.ToLower(strings.TrimSpace(item)))
+		if p == "" {
+			continue
+		}
+		if _, ok := known[p]; !ok {
+			return nil, ErrUnknownPermission
+		}
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		perms = append(perms, p)
+	}
+	sort.Slice(perms, func(i, j int) bool { return perms[i] < perms[j] })
+	return perms, nil
+}
+
+// PermissionSet 用户拥有的权限集合
+type PermissionSet map[Permission]struct{}
+
+// NewPermissionSet 合并多个角色的权限
+func NewPermissionSet(roles ...*Role) PermissionSet {
+	set := make(PermissionSet)
+	for _, r := range roles {
+		if r == nil {
+			continue
+		}
+		for _, p := range r.Permissions {
+			set[p] = struct{}{}
+		}
+	}
+	return set
+}
+
+// Has 是否拥有指定权限
+func (s PermissionSet) Has(p Permission) bool {
+	if _, ok := s[PermAll]; ok {
+		return true
+	}
+	_, ok := s[p]
+	return ok
+}
+
+// Covers 是否包含 other 的全部权限；只有拥有通配符才包含通配符
+func (s PermissionSet) Covers(other PermissionSet) bool {
+	if _, ok := s[PermAll]; ok {
+		return true
+	}
+	for p := range other {
+		if _, ok := s[p]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty 是否没有任何管理权限
+func (s PermissionSet) Empty() bool {
+	return len(s) == 0
+}
+
+// List 展开后的权限列表（通配符展开为全部权限）
+func (s PermissionSet) List() []Permission {
+	if _, ok := s[PermAll]; ok {
+		return AllPermissions()
+	}
+	out := make([]Permission, 0, len(s))
+	for p := range s {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
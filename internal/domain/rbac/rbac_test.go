@@ -0,0 +1,48 @@
+package rbac
+
+import "testing"
+
+func TestPermissionSet(t *testing.T) {
+	roles := BuiltinRoles()
+	byName := make(map[string]*Role, len(roles))
+	for _, r := range roles {
+		byName[r.Name] = r
+	}
+
+	super := NewPermissionSet(byName[RoleSuperadmin])
+	if !super.Has(PermRolesManage) || len(super.List()) != len(AllPermissions()) {
+		t.Fatalf("superadmin should have every permission")
+	}
+
+	support := NewPermissionSet(byName[RoleSupport])
+	if !support.Has(PermUsersRead) || support.Has(PermUsersWrite) {
+		t.Fatalf("support should be read-only: %v", support.List())
+	}
+
+	merged := NewPermissionSet(byName[RoleSupport], byName[RoleAuditor])
+	if !merged.Has(PermAuditRead) || merged.Has(PermUsersSecurity) {
+		t.Fatalf("unexpected merged permissions: %v", merged.List())
+	}
+	if !NewPermissionSet().Empty() {
+		t.Fatalf("no roles should yield an empty set")
+	}
+}
+
+func TestNewRole(t *testing.T) {
+	r, err := NewRole(" Helpdesk ", "first line", []string{"users.security", "users.read", "USERS.READ"})
+	if err != nil {
+		t.Fatalf("NewRole failed: %v", err)
+	}
+	if r.Name != "helpdesk" || len(r.Permissions) != 2 || r.Permissions[0] != PermUsersRead {
+		t.Fatalf("unexpected role: %+v", r)
+	}
+	if _, err := NewRole("superadmin", "", nil); err != ErrBuiltInRole {
+		t.Fatalf("expected ErrBuiltInRole, got %v", err)
+	}
+	if _, err := NewRole("x", "", nil); err != ErrInvalidRoleName {
+		t.Fatalf("expected ErrInvalidRoleName, got %v", err)
+	}
+	if _, err := NewRole("custom", "", []string{"*"}); err != ErrUnknownPermission {
+		t.Fatalf("wildcard should be rejected for custom roles, got %v", err)
+	}
+}
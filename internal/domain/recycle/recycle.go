@@ -14,32 +14,34 @@ var (
 
 // RecycleItem 回收站项目实体
 type RecycleItem struct {
-	ID        string    // 内部 ID
-	Hash      string    // 文件内容哈希（唯一标识）
-	UserID    string    // 所属用户 ID
-	Username  string    // 所属用户名
-	Directory string    // 所在目录名
-	Name      string    // 文件名
-	Path      string    // 相对路径（相对于目录根）
-	Size      int64     // 文件大小（字节）
-	DeletedAt time.Time // 删除时间
-	CreatedAt time.Time // 创建时间
+	ID          string    // 内部 ID
+	Hash        string    // 回收站项目唯一标识（用于访问与存储命名）
+	ContentHash string    // 文件内容 SHA-256（目录为空）
+	UserID      string    // 所属用户 ID
+	Username    string    // 所属用户名
+	Directory   string    // 所在目录名
+	Name        string    // 文件名
+	Path        string    // 相对路径（相对于目录根）
+	Size        int64     // 文件大小（字节）
+	DeletedAt   time.Time // 删除时间
+	CreatedAt   time.Time // 创建时间
 }
 
 // NewRecycleItem 创建新的回收站项目
-func NewRecycleItem(userID, username, directory, name, path string, size int64) *RecycleItem {
+func NewRecycleItem(userID, username, directory, name, path string, size int64, contentHash string) *RecycleItem {
 	now := time.Now()
 	return &RecycleItem{
-		ID:        generateID(),
-		Hash:      generateHash(),
-		UserID:    userID,
-		Username:  username,
-		Directory: directory,
-		Name:      name,
-		Path:      path,
-		Size:      size,
-		DeletedAt: now,
-		CreatedAt: now,
+		ID:          generateID(),
+		Hash:        generateHash(),
+		ContentHash: contentHash,
+		UserID:      userID,
+		Username:    username,
+		Directory:   directory,
+		Name:        name,
+		Path:        path,
+		Size:        size,
+		DeletedAt:   now,
+		CreatedAt:   now,
 	}
 }
 
@@ -54,7 +56,8 @@ func generateID() string {
 	return uuid.NewString()
 }
 
-// generateHash 生成文件哈希（简化版本，实际应计算文件内容哈希）
+// generateHash 生成回收站项目唯一标识
+// 同一内容可能被多次删除，因此标识与内容哈希（ContentHash）分离
 func generateHash() string {
 	return uuid.NewString()
-}
\ No newline at end of file
+}
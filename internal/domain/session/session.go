@@ -0,0 +1,89 @@
+package session
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrSessionRevoked     = errors.New("session revoked")
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// 会话吊销原因
+const (
+	ReasonLogout       = "logout"
+	ReasonRevoked      = "revoked"
+	ReasonRefreshReuse = "refresh_reuse"
+	ReasonAdmin        = "admin"
+	// ReasonPasswordReset 通过邮件链接重置密码后吊销全部会话
+	ReasonPasswordReset = "password_reset"
+)
+
+// 登录方式（会话建立时通过的认证）
+const (
+	MethodWallet   = "wallet"
+	MethodPasskey  = "passkey"
+	MethodPassword = "password"
+	MethodEmail    = "email"
+	// MethodMFA 密码或邮箱验证码加两步验证
+	MethodMFA = "mfa"
+)
+
+// Client 发起登录的客户端信息
+type Client struct {
+	DeviceName string
+	IP         string
+	UserAgent  string
+}
+
+// Session 登录会话
+// 一次登录对应一个会话，会话内的 refresh token 每次刷新都会轮换；
+// 同一会话签发的 access token 携带会话 ID，会话吊销后立即失效。
+type Session struct {
+	ID           string
+	UserID       string
+	SubjectType  string
+	Subject      string
+	AuthMethod   string
+	RefreshJTI   string
+	DeviceName   string
+	IP           string
+	UserAgent    string
+	CreatedAt    time.Time
+	LastSeenAt   time.Time
+	ExpiresAt    time.Time
+	RevokedAt    *time.Time
+	RevokeReason string
+}
+
+// New 创建会话
+func New(userID, subjectType, subject string, client Client, expiresAt time.Time) *Session {
+	now := time.Now()
+	return &Session{
+		ID:          uuid.NewString(),
+		UserID:      userID,
+		SubjectType: subjectType,
+		Subject:     subject,
+		RefreshJTI:  uuid.NewString(),
+		DeviceName:  client.DeviceName,
+		IP:          client.IP,
+		UserAgent:   client.UserAgent,
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		ExpiresAt:   expiresAt,
+	}
+}
+
+// StrongFactor 会话是否通过钱包签名或通行密钥建立（不依赖密码或邮箱验证码）
+func (s *Session) StrongFactor() bool {
+	return s.AuthMethod == MethodWallet || s.AuthMethod == MethodPasskey
+}
+
+// Active 会话是否仍然有效
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
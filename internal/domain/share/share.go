@@ -5,12 +5,19 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
 )
 
 var (
 	ErrShareNotFound = errors.New("share item not found")
 	ErrShareExpired  = errors.New("share item expired")
 	ErrInvalidShare  = errors.New("invalid share")
+	ErrTargetExists  = errors.New("target already exists")
+
+	ErrInvalidPath      = errors.New("invalid share path")
+	ErrDirectoryShare   = errors.New("directory sharing not supported")
+	ErrNotOwner         = errors.New("not your share")
+	ErrPermissionDenied = errors.New("permission denied")
 )
 
 // ShareItem 文件分享实体
@@ -25,6 +32,8 @@ type ShareItem struct {
 	ViewCount     int64
 	DownloadCount int64
 	CreatedAt     time.Time
+	Condition     *tokengate.Condition // 链上访问条件，nil 表示不设门槛
+	ContentHash   string               // 文件内容 SHA-256（运行时填充，不持久化）
 }
 
 // NewShareItem 创建分享记录
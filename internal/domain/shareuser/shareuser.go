@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
 )
 
 var (
@@ -26,6 +27,11 @@ type ShareUserItem struct {
 	Permissions         string
 	ExpiresAt           *time.Time
 	CreatedAt           time.Time
+	Condition           *tokengate.Condition // 链上访问条件，nil 表示不设门槛
+
+	// 端到端加密目录信息（不落库，查询时按分享路径与目标钱包填充）
+	E2EEFolderID string
+	KeyEnvelope  string
 }
 
 // NewShareUserItem 创建定向分享记录
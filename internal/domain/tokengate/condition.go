@@ -0,0 +1,150 @@
+package tokengate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidCondition   = errors.New("invalid token gate condition")
+	ErrConditionNotMet    = errors.New("token gate condition not met")
+	ErrWalletRequired     = errors.New("a verified wallet is required")
+	ErrChainNotConfigured = errors.New("chain not configured for token gate")
+	ErrGateDisabled       = errors.New("token gate is not enabled")
+)
+
+// ConditionType 链上条件类型
+type ConditionType string
+
+const (
+	// TypeNative 原生币余额
+	TypeNative ConditionType = "native"
+	// TypeERC20 ERC-20 代币余额
+	TypeERC20 ConditionType = "erc20"
+	// TypeERC721 ERC-721 持有（指定 tokenId 时要求持有该 token）
+	TypeERC721 ConditionType = "erc721"
+	// TypeERC1155 ERC-1155 指定 tokenId 的持有数量
+	TypeERC1155 ConditionType = "erc1155"
+)
+
+var contractPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// Condition 链上访问条件（代币门槛）
+// MinBalance 与 TokenID 为十进制字符串，MinBalance 以最小单位计，默认为 1。
+type Condition struct {
+	Type       ConditionType `json:"type"`
+	ChainID    uint64        `json:"chainId"`
+	Contract   string        `json:"contract,omitempty"`
+	TokenID    string        `json:"tokenId,omitempty"`
+	MinBalance string        `json:"minBalance,omitempty"`
+}
+
+// Validate 校验条件格式并规范化合约地址
+func (c *Condition) Validate() error {
+	if c == nil {
+		return nil
+	}
+	c.Type = ConditionType(strings.ToLower(strings.TrimSpace(string(c.Type))))
+	c.Contract = strings.ToLower(strings.TrimSpace(c.Contract))
+	c.TokenID = strings.TrimSpace(c.TokenID)
+	c.MinBalance = strings.TrimSpace(c.MinBalance)
+
+	if c.ChainID == 0 {
+		return fmt.Errorf("%w: chainId is required", ErrInvalidCondition)
+	}
+	switch c.Type {
+	case TypeNative:
+		if c.Contract != "" || c.TokenID != "" {
+			return fmt.Errorf("%w: native condition takes no contract or tokenId", ErrInvalidCondition)
+		}
+	case TypeERC20:
+		if c.TokenID != "" {
+			return fmt.Errorf("%w: erc20 condition takes no tokenId", ErrInvalidCondition)
+		}
+	case TypeERC721:
+	case TypeERC1155:
+		if c.TokenID == "" {
+			return fmt.Errorf("%w: erc1155 condition requires tokenId", ErrInvalidCondition)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidCondition, c.Type)
+	}
+	if c.Type != TypeNative && !contractPattern.MatchString(c.Contract) {
+		return fmt.Errorf("%w: invalid contract address", ErrInvalidCondition)
+	}
+	if c.TokenID != "" {
+		if _, ok := parseUint(c.TokenID); !ok {
+			return fmt.Errorf("%w: invalid tokenId", ErrInvalidCondition)
+		}
+	}
+	if c.MinBalance != "" {
+		if v, ok := parseUint(c.MinBalance); !ok || v.Sign() == 0 {
+			return fmt.Errorf("%w: minBalance must be a positive integer", ErrInvalidCondition)
+		}
+	}
+	return nil
+}
+
+// Threshold 返回最小持有量（默认 1）
+func (c *Condition) Threshold() *big.Int {
+	if v, ok := parseUint(c.MinBalance); ok && v.Sign() > 0 {
+		return v
+	}
+	return big.NewInt(1)
+}
+
+// Token 返回 tokenId，未设置时返回 nil
+func (c *Condition) Token() *big.Int {
+	if v, ok := parseUint(c.TokenID); ok {
+		return v
+	}
+	return nil
+}
+
+// Key 返回条件的规范化标识，用于缓存与比较
+func (c *Condition) Key() string {
+	return fmt.Sprintf("%d:%s:%s:%s:%s", c.ChainID, c.Type, c.Contract, c.TokenID, c.Threshold().String())
+}
+
+// Encode 序列化条件用于存储，nil 返回空字符串
+func (c *Condition) Encode() string {
+	if c == nil {
+		return ""
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// ParseCondition 解析存储的条件，空字符串返回 nil
+func ParseCondition(raw string) (*Condition, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var c Condition
+	if err := json.Unmarshal([]byte(raw), &c); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCondition, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func parseUint(s string) (*big.Int, bool) {
+	if s == "" {
+		return nil, false
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok || v.Sign() < 0 {
+		return nil, false
+	}
+	return v, true
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/yeying-community/warehouse/internal/domain/account"
 )
 
 var (
@@ -25,18 +26,19 @@ var (
 
 // User 用户领域模型
 type User struct {
-	ID            string
-	Username      string
-	Password      string // 加密后的密码
-	WalletAddress string // 以太坊钱包地址
-	Email         string
-	Directory     string
-	Permissions   *Permissions
-	Rules         []*Rule
-	Quota         int64 // 存储配额（字节），0 表示无限制
-	UsedSpace     int64 // 已使用空间（字节）
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	ID              string
+	Username        string
+	Password        string // 加密后的密码
+	WalletAddress   string // 钱包账户：CAIP-10 存储键，EVM 为 eip155:1:<小写地址>（见 account.ID.Key）
+	Email           string
+	EmailVerifiedAt *time.Time // 邮箱验证时间，nil 表示未验证；更换邮箱后清空
+	Directory       string
+	Permissions     *Permissions
+	Rules           []*Rule
+	Quota           int64 // 存储配额（字节），0 表示无限制
+	UsedSpace       int64 // 已使用空间（字节）
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 // Permissions 权限
@@ -79,12 +81,13 @@ func (u *User) SetPassword(hashedPassword string) {
 	u.UpdatedAt = time.Now()
 }
 
-// SetWalletAddress 设置钱包地址
+// SetWalletAddress 设置钱包地址（支持以太坊地址、CAIP-10 与 did:pkh）
 func (u *User) SetWalletAddress(address string) error {
-	if address == "" {
+	key, err := account.Normalize(address)
+	if err != nil {
 		return ErrInvalidAddress
 	}
-	u.WalletAddress = strings.ToLower(address)
+	u.WalletAddress = key
 	u.UpdatedAt = time.Now()
 	return nil
 }
@@ -98,11 +101,21 @@ func (u *User) SetEmail(email string) error {
 	if !IsValidEmail(normalized) {
 		return ErrInvalidEmail
 	}
+	if normalized != u.Email {
+		u.EmailVerifiedAt = nil
+	}
 	u.Email = normalized
 	u.UpdatedAt = time.Now()
 	return nil
 }
 
+// MarkEmailVerified 标记当前邮箱已验证
+func (u *User) MarkEmailVerified() {
+	now := time.Now()
+	u.EmailVerifiedAt = &now
+	u.UpdatedAt = now
+}
+
 // SetQuota 设置配额
 func (u *User) SetQuota(quota int64) error {
 	if quota < 0 {
@@ -180,6 +193,11 @@ func (u *User) HasEmail() bool {
 	return u.Email != ""
 }
 
+// IsEmailVerified 邮箱是否已验证
+func (u *User) IsEmailVerified() bool {
+	return u.Email != "" && u.EmailVerifiedAt != nil
+}
+
 // CanAccess 检查是否可以访问路径
 func (u *User) CanAccess(path string, requiredPerm string) bool {
 	// 先检查规则
@@ -0,0 +1,127 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE 算法标识（RFC 9053）
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// SupportedAlgorithms 注册时向浏览器声明的算法，按优先级排列
+var SupportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1 // EC2 / OKP 的 crv，RSA 的 n
+	coseX         = -2 // EC2 / OKP 的 x，RSA 的 e
+	coseY         = -3
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+// PublicKey 解析后的凭证公钥
+type PublicKey struct {
+	Algorithm int
+	key       crypto.PublicKey
+}
+
+// ParsePublicKey 解析 COSE_Key 编码的公钥
+func ParsePublicKey(coseKey []byte) (*PublicKey, error) {
+	value, rest, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidPublicKey)
+	}
+	m, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrInvalidPublicKey
+	}
+	kty, _ := m[int64(coseKeyType)].(int64)
+	alg, _ := m[int64(coseAlgorithm)].(int64)
+
+	switch {
+	case alg == AlgES256 && kty == coseKtyEC2:
+		crv, _ := m[int64(coseCurve)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, ErrInvalidPublicKey
+		}
+		point := append([]byte{0x04}, append(append([]byte(nil), x...), y...)...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+		}
+		return &PublicKey{Algorithm: AlgES256, key: &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}}, nil
+	case alg == AlgEdDSA && kty == coseKtyOKP:
+		crv, _ := m[int64(coseCurve)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, ErrInvalidPublicKey
+		}
+		return &PublicKey{Algorithm: AlgEdDSA, key: ed25519.PublicKey(x)}, nil
+	case alg == AlgRS256 && kty == coseKtyRSA:
+		n, _ := m[int64(coseCurve)].([]byte)
+		e, _ := m[int64(coseX)].([]byte)
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, ErrInvalidPublicKey
+		}
+		modulus := new(big.Int).SetBytes(n)
+		if modulus.BitLen() < 2048 {
+			return nil, fmt.Errorf("%w: rsa key too short", ErrInvalidPublicKey)
+		}
+		return &PublicKey{Algorithm: AlgRS256, key: &rsa.PublicKey{
+			N: modulus,
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}}, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedAlgorithm, alg)
+	}
+}
+
+// Verify 校验签名
+func (p *PublicKey) Verify(data, signature []byte) error {
+	switch key := p.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return ErrInvalidSignature
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, signature) {
+			return ErrInvalidSignature
+		}
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+	default:
+		return errors.New("unsupported public key")
+	}
+	return nil
+}
@@ -0,0 +1,400 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrCredentialNotFound   = errors.New("passkey not found")
+	ErrCredentialExists     = errors.New("passkey already registered")
+	ErrLastCredential       = errors.New("cannot remove the last sign-in method of the account")
+	ErrInvalidName          = errors.New("passkey name must be at most 64 characters")
+	ErrInvalidClientData    = errors.New("invalid client data")
+	ErrInvalidOrigin        = errors.New("origin not allowed")
+	ErrChallengeMismatch    = errors.New("challenge mismatch")
+	ErrInvalidAuthData      = errors.New("invalid authenticator data")
+	ErrRPIDMismatch         = errors.New("relying party id mismatch")
+	ErrUserNotPresent       = errors.New("user presence not asserted")
+	ErrUserNotVerified      = errors.New("user verification required")
+	ErrInvalidPublicKey     = errors.New("invalid credential public key")
+	ErrUnsupportedAlgorithm = errors.New("unsupported credential algorithm")
+	ErrInvalidSignature     = errors.New("invalid assertion signature")
+	ErrUserHandleMismatch   = errors.New("user handle mismatch")
+	ErrCloneDetected        = errors.New("signature counter did not increase, authenticator may be cloned")
+	ErrSignUpDisabled       = errors.New("passkey sign-up is disabled")
+)
+
+// 认证器数据标志位
+const (
+	FlagUserPresent    = 0x01
+	FlagUserVerified   = 0x04
+	FlagBackupEligible = 0x08
+	FlagBackupState    = 0x10
+	FlagAttestedData   = 0x40
+	FlagExtensionData  = 0x80
+)
+
+// 用户验证要求
+const (
+	UserVerificationRequired    = "required"
+	UserVerificationPreferred   = "preferred"
+	UserVerificationDiscouraged = "discouraged"
+)
+
+const (
+	challengeSize       = 32
+	maxCredentialIDSize = 1023
+)
+
+// Credential 用户登记的通行密钥
+type Credential struct {
+	ID             string // base64url 编码的凭证 ID
+	UserID         string
+	Name           string
+	PublicKey      []byte // COSE_Key
+	Algorithm      int
+	SignCount      uint32
+	AAGUID         string
+	Transports     []string
+	BackupEligible bool
+	BackupState    bool
+	CreatedAt      time.Time
+	LastUsedAt     *time.Time
+}
+
+// NormalizeName 规范化通行密钥名称，为空时使用默认名称
+func NormalizeName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > 64 {
+		return "", ErrInvalidName
+	}
+	if name == "" {
+		name = "Passkey"
+	}
+	return name, nil
+}
+
+// RelyingParty 依赖方配置
+type RelyingParty struct {
+	ID               string
+	Name             string
+	Origins          []string
+	UserVerification string
+	Timeout          time.Duration
+}
+
+// NewChallenge 生成随机挑战
+func NewChallenge() ([]byte, error) {
+	buf := make([]byte, challengeSize)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	return buf, nil
+}
+
+// EncodeID base64url（无填充）编码，与浏览器 PublicKeyCredential 的 id 一致
+func EncodeID(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeID 解码 base64url，兼容带填充与标准 base64
+func DecodeID(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "=")
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// CredentialDescriptor PublicKeyCredentialDescriptor
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// RPEntity PublicKeyCredentialRpEntity
+type RPEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity PublicKeyCredentialUserEntity
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialParameter PublicKeyCredentialParameters
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// AuthenticatorSelection AuthenticatorSelectionCriteria
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions PublicKeyCredentialCreationOptions（二进制字段为 base64url）
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RPEntity               `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions PublicKeyCredentialRequestOptions（二进制字段为 base64url）
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// CreationOptions 生成注册参数；要求可发现凭证（通行密钥），不请求证明
+func (rp *RelyingParty) CreationOptions(challenge, userHandle []byte, name, displayName string, exclude []*Credential) *CreationOptions {
+	params := make([]CredentialParameter, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	return &CreationOptions{
+		Challenge:          EncodeID(challenge),
+		RP:                 RPEntity{ID: rp.ID, Name: rp.Name},
+		User:               UserEntity{ID: EncodeID(userHandle), Name: name, DisplayName: displayName},
+		PubKeyCredParams:   params,
+		Timeout:            rp.Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   rp.UserVerification,
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions 生成登录参数；allow 为空时由浏览器列出可发现凭证
+func (rp *RelyingParty) RequestOptions(challenge []byte, allow []*Credential) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        EncodeID(challenge),
+		Timeout:          rp.Timeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: descriptors(allow),
+		UserVerification: rp.UserVerification,
+	}
+}
+
+// VerifyRegistration 校验注册响应（navigator.credentials.create），返回待保存的凭证
+// 不校验证明声明（attestation statement）：注册参数只请求 "none"，服务端不依赖认证器型号。
+func (rp *RelyingParty) VerifyRegistration(challenge, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	value, rest, err := decodeCBOR(attestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("%w: invalid attestation object", ErrInvalidAuthData)
+	}
+	obj, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid attestation object", ErrInvalidAuthData)
+	}
+	rawAuthData, ok := obj["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing authData", ErrInvalidAuthData)
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+	if authData.credentialID == nil {
+		return nil, fmt.Errorf("%w: missing attested credential", ErrInvalidAuthData)
+	}
+	key, err := ParsePublicKey(authData.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:             EncodeID(authData.credentialID),
+		PublicKey:      authData.publicKey,
+		Algorithm:      key.Algorithm,
+		SignCount:      authData.signCount,
+		AAGUID:         hex.EncodeToString(authData.aaguid),
+		BackupEligible: authData.flags&FlagBackupEligible != 0,
+		BackupState:    authData.flags&FlagBackupState != 0,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+// Assertion 登录断言校验结果
+type Assertion struct {
+	SignCount   uint32
+	BackupState bool
+}
+
+// VerifyAssertion 校验登录断言（navigator.credentials.get）
+// userHandle 非空时必须与凭证所属用户一致；签名计数器未递增视为认证器被克隆。
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, cred *Credential, clientDataJSON, rawAuthData, signature, userHandle []byte) (*Assertion, error) {
+	if len(userHandle) > 0 && !bytes.Equal(userHandle, []byte(cred.UserID)) {
+		return nil, ErrUserHandleMismatch
+	}
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return nil, err
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePublicKey(cred.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+	if err := key.Verify(signed, signature); err != nil {
+		return nil, err
+	}
+
+	if (authData.signCount != 0 || cred.SignCount != 0) && authData.signCount <= cred.SignCount {
+		return nil, ErrCloneDetected
+	}
+	return &Assertion{
+		SignCount:   authData.signCount,
+		BackupState: authData.flags&FlagBackupState != 0,
+	}, nil
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, expectedType string, challenge []byte) error {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidClientData, err)
+	}
+	if cd.Type != expectedType {
+		return fmt.Errorf("%w: unexpected type %q", ErrInvalidClientData, cd.Type)
+	}
+	got, err := DecodeID(cd.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+	if cd.CrossOrigin {
+		return ErrInvalidOrigin
+	}
+	origin := strings.TrimRight(cd.Origin, "/")
+	for _, allowed := range rp.Origins {
+		if strings.EqualFold(origin, strings.TrimRight(allowed, "/")) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidOrigin, cd.Origin)
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(authData *authenticatorData) error {
+	expected := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(authData.rpIDHash, expected[:]) != 1 {
+		return ErrRPIDMismatch
+	}
+	if authData.flags&FlagUserPresent == 0 {
+		return ErrUserNotPresent
+	}
+	if rp.UserVerification == UserVerificationRequired && authData.flags&FlagUserVerified == 0 {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// parseAuthenticatorData 解析认证器数据（WebAuthn §6.1）
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidAuthData)
+	}
+	ad := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if ad.flags&FlagAttestedData != 0 {
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("%w: truncated attested credential", ErrInvalidAuthData)
+		}
+		ad.aaguid = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > maxCredentialIDSize || len(rest) < idLen {
+			return nil, fmt.Errorf("%w: invalid credential id", ErrInvalidAuthData)
+		}
+		ad.credentialID = rest[:idLen]
+		rest = rest[idLen:]
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+		}
+		ad.publicKey = append([]byte(nil), rest[:len(rest)-len(after)]...)
+		rest = after
+	}
+	if ad.flags&FlagExtensionData != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid extensions", ErrInvalidAuthData)
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidAuthData)
+	}
+	return ad, nil
+}
+
+func descriptors(creds []*Credential) []CredentialDescriptor {
+	out := make([]CredentialDescriptor, 0, len(creds))
+	for _, c := range creds {
+		out = append(out, CredentialDescriptor{Type: "public-key", ID: c.ID, Transports: c.Transports})
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package webauthn
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/webauthn/webauthntest"
+)
+
+func testRelyingParty() *RelyingParty {
+	return &RelyingParty{
+		ID:               "example.com",
+		Name:             "Warehouse",
+		Origins:          []string{"https://example.com"},
+		UserVerification: UserVerificationPreferred,
+		Timeout:          time.Minute,
+	}
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := testRelyingParty()
+	device := webauthntest.New("example.com", "https://example.com")
+	userID := "7c4a8d09-ca37-4b6d-9b1e-1f0f5b3a2c11"
+
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge failed: %v", err)
+	}
+	clientData, attestation := device.Create(challenge, []byte(userID))
+	cred, err := rp.VerifyRegistration(challenge, clientData, attestation)
+	if err != nil {
+		t.Fatalf("VerifyRegistration failed: %v", err)
+	}
+	if cred.ID != device.ID() || cred.Algorithm != AlgES256 {
+		t.Fatalf("unexpected credential: %+v", cred)
+	}
+	cred.UserID = userID
+
+	loginChallenge, _ := NewChallenge()
+	clientData, authData, sig := device.Get(loginChallenge)
+	assertion, err := rp.VerifyAssertion(loginChallenge, cred, clientData, authData, sig, []byte(userID))
+	if err != nil {
+		t.Fatalf("VerifyAssertion failed: %v", err)
+	}
+	if assertion.SignCount != 1 {
+		t.Fatalf("unexpected sign count: %d", assertion.SignCount)
+	}
+	cred.SignCount = assertion.SignCount
+
+	// 挑战不一致
+	other, _ := NewChallenge()
+	if _, err := rp.VerifyAssertion(other, cred, clientData, authData, sig, nil); !errors.Is(err, ErrChallengeMismatch) {
+		t.Fatalf("expected challenge mismatch, got %v", err)
+	}
+
+	// 重放同一断言：计数器未递增
+	if _, err := rp.VerifyAssertion(loginChallenge, cred, clientData, authData, sig, nil); !errors.Is(err, ErrCloneDetected) {
+		t.Fatalf("expected clone detection, got %v", err)
+	}
+
+	// 签名被篡改
+	clientData, authData, sig = device.Get(loginChallenge)
+	sig[len(sig)-1] ^= 0xff
+	if _, err := rp.VerifyAssertion(loginChallenge, cred, clientData, authData, sig, nil); err == nil {
+		t.Fatalf("tampered signature should be rejected")
+	}
+
+	// 其他用户的 user handle
+	clientData, authData, sig = device.Get(loginChallenge)
+	if _, err := rp.VerifyAssertion(loginChallenge, cred, clientData, authData, sig, []byte("someone-else")); !errors.Is(err, ErrUserHandleMismatch) {
+		t.Fatalf("expected user handle mismatch, got %v", err)
+	}
+}
+
+func TestRegistrationRejectsForeignOriginAndRPID(t *testing.T) {
+	rp := testRelyingParty()
+	challenge, _ := NewChallenge()
+
+	phishing := webauthntest.New("example.com", "https://evil.example.net")
+	clientData, attestation := phishing.Create(challenge, []byte("u"))
+	if _, err := rp.VerifyRegistration(challenge, clientData, attestation); !errors.Is(err, ErrInvalidOrigin) {
+		t.Fatalf("expected origin error, got %v", err)
+	}
+
+	wrongRP := webauthntest.New("evil.example.net", "https://example.com")
+	clientData, attestation = wrongRP.Create(challenge, []byte("u"))
+	if _, err := rp.VerifyRegistration(challenge, clientData, attestation); !errors.Is(err, ErrRPIDMismatch) {
+		t.Fatalf("expected rp id mismatch, got %v", err)
+	}
+}
+
+func TestUserVerificationRequired(t *testing.T) {
+	rp := testRelyingParty()
+	rp.UserVerification = UserVerificationRequired
+	device := webauthntest.New("example.com", "https://example.com")
+	device.UserVerified = false
+
+	challenge, _ := NewChallenge()
+	clientData, attestation := device.Create(challenge, []byte("u"))
+	if _, err := rp.VerifyRegistration(challenge, clientData, attestation); !errors.Is(err, ErrUserNotVerified) {
+		t.Fatalf("expected user verification error, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+// Package webauthntest 提供用于测试的软件通行密钥认证器
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+)
+
+// Authenticator 软件认证器：P-256（ES256）密钥，模拟浏览器生成 clientDataJSON
+type Authenticator struct {
+	Origin       string
+	RPID         string
+	CredentialID []byte
+	UserHandle   []byte
+	SignCount    uint32
+	UserVerified bool
+
+	key *ecdsa.PrivateKey
+}
+
+// New 创建软件认证器
+func New(rpID, origin string) *Authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return &Authenticator{
+		Origin:       origin,
+		RPID:         rpID,
+		CredentialID: id,
+		UserVerified: true,
+		key:          key,
+	}
+}
+
+// ID 凭证 ID（base64url）
+func (a *Authenticator) ID() string {
+	return base64.RawURLEncoding.EncodeToString(a.CredentialID)
+}
+
+// Create 模拟 navigator.credentials.create，返回 clientDataJSON 与 attestationObject
+func (a *Authenticator) Create(challenge, userHandle []byte) ([]byte, []byte) {
+	a.UserHandle = userHandle
+	clientData := a.clientData("webauthn.create", challenge)
+
+	x := a.key.X.FillBytes(make([]byte, 32))
+	y := a.key.Y.FillBytes(make([]byte, 32))
+	coseKey := encodeMap(map[int64][]byte{
+		1:  encodeInt(2),  // kty: EC2
+		3:  encodeInt(-7), // alg: ES256
+		-1: encodeInt(1),  // crv: P-256
+		-2: encodeBytes(x),
+		-3: encodeBytes(y),
+	})
+
+	authData := a.authData(0x40)
+	authData = append(authData, make([]byte, 16)...) // AAGUID
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.CredentialID)))
+	authData = append(authData, a.CredentialID...)
+	authData = append(authData, coseKey...)
+
+	attestation := encodeTextMap([][2][]byte{
+		{encodeText("fmt"), encodeText("none")},
+		{encodeText("attStmt"), {0xa0}},
+		{encodeText("authData"), encodeBytes(authData)},
+	})
+	return clientData, attestation
+}
+
+// Get 模拟 navigator.credentials.get，返回 clientDataJSON、authenticatorData 与签名
+func (a *Authenticator) Get(challenge []byte) ([]byte, []byte, []byte) {
+	a.SignCount++
+	clientData := a.clientData("webauthn.get", challenge)
+	authData := a.authData(0)
+	hash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), hash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return clientData, authData, sig
+}
+
+func (a *Authenticator) clientData(typ string, challenge []byte) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":        typ,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	return data
+}
+
+func (a *Authenticator) authData(extraFlags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	flags := byte(0x01) | extraFlags
+	if a.UserVerified {
+		flags |= 0x04
+	}
+	out := append([]byte(nil), rpIDHash[:]...)
+	out = append(out, flags)
+	return binary.BigEndian.AppendUint32(out, a.SignCount)
+}
+
+func encodeHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 1<<8:
+		return []byte{major<<5 | 24, byte(n)}
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+}
+
+func encodeInt(v int64) []byte {
+	if v >= 0 {
+		return encodeHead(0, uint64(v))
+	}
+	return encodeHead(1, uint64(-1-v))
+}
+
+func encodeBytes(b []byte) []byte {
+	return append(encodeHead(2, uint64(len(b))), b...)
+}
+
+func encodeText(s string) []byte {
+	return append(encodeHead(3, uint64(len(s))), s...)
+}
+
+func encodeMap(m map[int64][]byte) []byte {
+	keys := make([]int64, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	out := encodeHead(5, uint64(len(m)))
+	for _, k := range keys {
+		out = append(out, encodeInt(k)...)
+		out = append(out, m[k]...)
+	}
+	return out
+}
+
+func encodeTextMap(pairs [][2][]byte) []byte {
+	out := encodeHead(5, uint64(len(pairs)))
+	for _, p := range pairs {
+		out = append(out, p[0]...)
+		out = append(out, p[1]...)
+	}
+	return out
+}
@@ -2,18 +2,42 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/yeying-community/warehouse/internal/domain/apppassword"
 	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/mfa"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/ratelimit"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
 
+// appPasswordTouchInterval 应用专用密码最近使用时间的最小更新间隔
+const appPasswordTouchInterval = time.Minute
+
+// AppPasswordStore 应用专用密码存储
+type AppPasswordStore interface {
+	FindByHash(ctx context.Context, hash string) (*apppassword.AppPassword, error)
+	TouchLastUsed(ctx context.Context, id string, at time.Time, ip string) error
+}
+
+// SecondFactorPolicy 判断用户登录是否需要两步验证
+type SecondFactorPolicy interface {
+	RequiresSecondFactor(ctx context.Context, u *user.User) (bool, error)
+}
+
 // BasicAuthenticator Basic 认证器
 type BasicAuthenticator struct {
 	userRepo       user.Repository
 	passwordHasher *crypto.PasswordHasher
+	appPasswords   AppPasswordStore
+	lockout        *ratelimit.Lockout
+	secondFactor   SecondFactorPolicy
 	noPassword     bool
 	logger         *zap.Logger
 }
@@ -32,6 +56,22 @@ func NewBasicAuthenticator(
 	}
 }
 
+// SetAppPasswordStore 设置应用专用密码存储；设置后 Basic 认证同时接受应用专用密码
+func (a *BasicAuthenticator) SetAppPasswordStore(store AppPasswordStore) {
+	a.appPasswords = store
+}
+
+// SetLockout 设置账户锁定器；连续密码错误达到阈值后锁定账户
+func (a *BasicAuthenticator) SetLockout(lockout *ratelimit.Lockout) {
+	a.lockout = lockout
+}
+
+// SetSecondFactorPolicy 设置两步验证策略
+// Basic 认证无法进行第二步验证，需要两步验证的账户只能使用应用专用密码，账户密码一律拒绝。
+func (a *BasicAuthenticator) SetSecondFactorPolicy(policy SecondFactorPolicy) {
+	a.secondFactor = policy
+}
+
 // Name 认证器名称
 func (a *BasicAuthenticator) Name() string {
 	return "basic"
@@ -48,7 +88,7 @@ func (a *BasicAuthenticator) Authenticate(ctx context.Context, credentials inter
 	u, err := a.userRepo.FindByUsername(ctx, creds.Username)
 	if err != nil {
 		if err == user.ErrUserNotFound {
-			a.logger.Debug("user not found",
+			logger.Ctx(ctx, a.logger).Debug("user not found",
 				zap.String("username", creds.Username))
 			return nil, user.ErrUserNotFound
 		}
@@ -57,31 +97,106 @@ func (a *BasicAuthenticator) Authenticate(ctx context.Context, credentials inter
 
 	// 如果启用了无密码模式，直接返回
 	if a.noPassword {
-		a.logger.Debug("user authenticated (no password mode)",
+		logger.Ctx(ctx, a.logger).Debug("user authenticated (no password mode)",
 			zap.String("username", u.Username))
 		return u, nil
 	}
 
+	// 锁定期内不再校验密码
+	if err := a.lockout.Check(u.ID); err != nil {
+		return nil, err
+	}
+
+	// 应用专用密码
+	if p, err := a.findAppPassword(ctx, creds.Password); err != nil {
+		return nil, err
+	} else if p != nil && p.UserID == u.ID {
+		a.touchAppPassword(ctx, p)
+		a.lockout.Success(u.ID)
+		logger.Ctx(ctx, a.logger).Debug("user authenticated via app password",
+			zap.String("username", u.Username),
+			zap.String("app_password", p.Name))
+		return u, nil
+	}
+
 	// 验证密码
 	if !u.HasPassword() {
-		a.logger.Warn("user has no password",
+		logger.Ctx(ctx, a.logger).Warn("user has no password",
 			zap.String("username", u.Username))
 		return nil, user.ErrInvalidPassword
 	}
 
+	// 启用或被要求启用两步验证的账户不接受账户密码，也不校验，避免绕过第二步
+	if a.secondFactor != nil {
+		required, err := a.secondFactor.RequiresSecondFactor(ctx, u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check two-factor status: %w", err)
+		}
+		if required {
+			logger.Ctx(ctx, a.logger).Warn("account password rejected, two-factor authentication required",
+				zap.String("username", u.Username))
+			return nil, mfa.ErrRequired
+		}
+	}
+
 	if err := a.passwordHasher.Verify(u.Password, creds.Password); err != nil {
-		a.logger.Warn("password verification failed",
+		logger.Ctx(ctx, a.logger).Warn("password verification failed",
 			zap.String("username", u.Username),
 			zap.Error(err))
+		if err := a.lockout.Failure(u.ID); err != nil {
+			logger.Ctx(ctx, a.logger).Warn("account locked after repeated failures",
+				zap.String("username", u.Username))
+			return nil, err
+		}
 		return nil, user.ErrInvalidPassword
 	}
+	a.lockout.Success(u.ID)
 
-	a.logger.Debug("user authenticated via basic auth",
+	logger.Ctx(ctx, a.logger).Debug("user authenticated via basic auth",
 		zap.String("username", u.Username))
 
 	return u, nil
 }
 
+// EnrichContext 使用应用专用密码登录时记录其访问范围
+func (a *BasicAuthenticator) EnrichContext(ctx context.Context, credentials interface{}) context.Context {
+	creds, ok := credentials.(*auth.BasicCredentials)
+	if !ok || ctx == nil || a.noPassword {
+		return ctx
+	}
+	p, err := a.findAppPassword(ctx, creds.Password)
+	if err != nil || p == nil {
+		return ctx
+	}
+	return middleware.WithAppPassword(ctx, p)
+}
+
+func (a *BasicAuthenticator) findAppPassword(ctx context.Context, password string) (*apppassword.AppPassword, error) {
+	if a.appPasswords == nil || password == "" {
+		return nil, nil
+	}
+	p, err := a.appPasswords.FindByHash(ctx, apppassword.Hash(password))
+	if err != nil {
+		if errors.Is(err, apppassword.ErrAppPasswordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find app password: %w", err)
+	}
+	return p, nil
+}
+
+func (a *BasicAuthenticator) touchAppPassword(ctx context.Context, p *apppassword.AppPassword) {
+	now := time.Now()
+	if p.LastUsedAt != nil && now.Sub(*p.LastUsedAt) < appPasswordTouchInterval {
+		return
+	}
+	if err := a.appPasswords.TouchLastUsed(ctx, p.ID, now, middleware.ClientIPFromContext(ctx)); err != nil {
+		logger.Ctx(ctx, a.logger).Warn("failed to update app password last used",
+			zap.String("app_password_id", p.ID),
+			zap.Error(err))
+	}
+}
+
 // CanHandle 是否可以处理该凭证
 func (a *BasicAuthenticator) CanHandle(credentials interface{}) bool {
 	_, ok := credentials.(*auth.BasicCredentials)
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/apppassword"
+	domainauth "github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/mfa"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+func TestBasicAuthenticatorAppPassword(t *testing.T) {
+	repo := newUserRepo()
+	u := user.NewUser("frank", "frank")
+	if err := u.SetWalletAddress("0x4444444444444444444444444444444444444444"); err != nil {
+		t.Fatalf("SetWalletAddress failed: %v", err)
+	}
+	if err := repo.Save(context.Background(), u); err != nil {
+		t.Fatalf("Save user failed: %v", err)
+	}
+
+	p, secret, err := apppassword.New(u.ID, "Finder", true, []string{"/personal"})
+	if err != nil {
+		t.Fatalf("apppassword.New failed: %v", err)
+	}
+	store := &stubAppPasswordStore{items: map[string]*apppassword.AppPassword{p.Hash: p}}
+	authenticator := NewBasicAuthenticator(repo, false, zap.NewNop())
+	authenticator.SetAppPasswordStore(store)
+	ctx := context.Background()
+
+	creds := &domainauth.BasicCredentials{Username: "frank", Password: secret}
+	got, err := authenticator.Authenticate(ctx, creds)
+	if err != nil {
+		t.Fatalf("Authenticate with app password failed: %v", err)
+	}
+	if got.ID != u.ID {
+		t.Fatalf("unexpected user: %s", got.Username)
+	}
+	if store.touched != p.ID {
+		t.Fatalf("last used should be recorded")
+	}
+	enriched, ok := middleware.GetAppPassword(authenticator.EnrichContext(ctx, creds))
+	if !ok || enriched.ID != p.ID {
+		t.Fatalf("app password scope should be attached to context")
+	}
+
+	// 钱包用户没有账户密码，错误的密码直接拒绝
+	if _, err := authenticator.Authenticate(ctx, &domainauth.BasicCredentials{Username: "frank", Password: "wrong"}); err == nil {
+		t.Fatalf("wrong password should be rejected")
+	}
+
+	// 其他用户不能使用该应用专用密码
+	other := user.NewUser("grace", "grace")
+	if err := repo.Save(ctx, other); err != nil {
+		t.Fatalf("Save user failed: %v", err)
+	}
+	if _, err := authenticator.Authenticate(ctx, &domainauth.BasicCredentials{Username: "grace", Password: secret}); err == nil {
+		t.Fatalf("app password of another user should be rejected")
+	}
+}
+
+func TestBasicAuthenticatorRejectsPasswordWhenMFARequired(t *testing.T) {
+	ctx := context.Background()
+	repo := newUserRepo()
+	hash, err := crypto.NewPasswordHasher().Hash("password123")
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	u := user.NewUser("heidi", "heidi")
+	u.SetPassword(hash)
+	if err := repo.Save(ctx, u); err != nil {
+		t.Fatalf("Save user failed: %v", err)
+	}
+	p, secret, err := apppassword.New(u.ID, "davfs2", false, nil)
+	if err != nil {
+		t.Fatalf("apppassword.New failed: %v", err)
+	}
+
+	policy := &stubSecondFactorPolicy{}
+	authenticator := NewBasicAuthenticator(repo, false, zap.NewNop())
+	authenticator.SetAppPasswordStore(&stubAppPasswordStore{items: map[string]*apppassword.AppPassword{p.Hash: p}})
+	authenticator.SetSecondFactorPolicy(policy)
+
+	password := &domainauth.BasicCredentials{Username: "heidi", Password: "password123"}
+	if _, err := authenticator.Authenticate(ctx, password); err != nil {
+		t.Fatalf("password should be accepted without two-factor: %v", err)
+	}
+
+	// 需要两步验证后账户密码被拒绝，应用专用密码仍然可用
+	policy.required = true
+	if _, err := authenticator.Authenticate(ctx, password); !errors.Is(err, mfa.ErrRequired) {
+		t.Fatalf("expected ErrRequired, got %v", err)
+	}
+	if _, err := authenticator.Authenticate(ctx, &domainauth.BasicCredentials{Username: "heidi", Password: secret}); err != nil {
+		t.Fatalf("app password should still be accepted: %v", err)
+	}
+}
+
+type stubSecondFactorPolicy struct {
+	required bool
+}
+
+func (p *stubSecondFactorPolicy) RequiresSecondFactor(ctx context.Context, u *user.User) (bool, error) {
+	return p.required, nil
+}
+
+type stubAppPasswordStore struct {
+	items   map[string]*apppassword.AppPassword
+	touched string
+}
+
+func (s *stubAppPasswordStore) FindByHash(ctx context.Context, hash string) (*apppassword.AppPassword, error) {
+	p, ok := s.items[hash]
+	if !ok {
+		return nil, apppassword.ErrAppPasswordNotFound
+	}
+	return p, nil
+}
+
+func (s *stubAppPasswordStore) TouchLastUsed(ctx context.Context, id string, at time.Time, ip string) error {
+	s.touched = id
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/yeying-community/warehouse/internal/domain/account"
 	"github.com/yeying-community/warehouse/internal/domain/auth"
 )
 
@@ -30,20 +31,31 @@ func NewChallengeStore() *ChallengeStore {
 }
 
 // Create 创建挑战
-func (s *ChallengeStore) Create(address string, expiresIn time.Duration) (*auth.Challenge, error) {
+// siwe 非空时按 EIP-4361 生成消息（填充 nonce、签发与过期时间），否则使用旧版消息格式。
+func (s *ChallengeStore) Create(address string, expiresIn time.Duration, siwe *SIWEMessage) (*auth.Challenge, error) {
 	nonce, err := generateNonce()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	now := time.Now()
-	message := buildChallengeMessage(address, nonce, now)
-
+	now := time.Now().UTC().Truncate(time.Second)
+	expiresAt := now.Add(expiresIn)
 	challenge := &auth.Challenge{
 		Nonce:     nonce,
-		Message:   message,
-		Address:   strings.ToLower(address),
-		ExpiresAt: now.Add(expiresIn),
+		Address:   challengeKey(address),
+		IssuedAt:  now,
+		ExpiresAt: expiresAt,
+	}
+	if siwe != nil {
+		siwe.Nonce = nonce
+		siwe.IssuedAt = now
+		siwe.ExpirationTime = &expiresAt
+		challenge.Message = siwe.String()
+		challenge.Domain = siwe.Domain
+		challenge.ChainID = siwe.ChainID
+		challenge.ChainRef = siwe.ChainRef
+	} else {
+		challenge.Message = buildChallengeMessage(address, nonce, now)
 	}
 
 	s.Store(challenge)
@@ -56,7 +68,7 @@ func (s *ChallengeStore) Store(challenge *auth.Challenge) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	key := strings.ToLower(challenge.Address)
+	key := challengeKey(challenge.Address)
 	s.challenges[key] = challenge
 }
 
@@ -65,7 +77,7 @@ func (s *ChallengeStore) Get(address string) (*auth.Challenge, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	key := strings.ToLower(address)
+	key := challengeKey(address)
 	challenge, ok := s.challenges[key]
 
 	if !ok {
@@ -85,10 +97,32 @@ func (s *ChallengeStore) Delete(address string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	key := strings.ToLower(address)
+	key := challengeKey(address)
 	delete(s.challenges, key)
 }
 
+// Consume 消费挑战（仅当 nonce 仍为当前挑战时删除并返回 true，保证 nonce 只能使用一次）
+func (s *ChallengeStore) Consume(address, nonce string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := challengeKey(address)
+	challenge, ok := s.challenges[key]
+	if !ok || challenge.Nonce != nonce || challenge.IsExpired() {
+		return false
+	}
+	delete(s.challenges, key)
+	return true
+}
+
+// challengeKey 挑战存储键：与用户钱包存储键一致（Solana 地址区分大小写）
+func challengeKey(address string) string {
+	if key, err := account.Normalize(address); err == nil {
+		return key
+	}
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
 // cleanupExpired 清理过期挑战
 func (s *ChallengeStore) cleanupExpired() {
 	ticker := time.NewTicker(5 * time.Minute)
@@ -115,7 +149,7 @@ func generateNonce() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-// buildChallengeMessage 构建挑战消息
+// buildChallengeMessage 构建旧版挑战消息（web3.siwe.legacy_message）
 func buildChallengeMessage(address, nonce string, timestamp time.Time) string {
 	return fmt.Sprintf(
 		"Welcome to Warehouse!\n\n"+
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"math/big"
@@ -13,22 +14,28 @@ import (
 // ErrEmailCodeTooFrequent 表示发送过于频繁
 var ErrEmailCodeTooFrequent = errors.New("email code sent too frequently")
 
+// defaultEmailCodeMaxAttempts 每个验证码默认允许的错误次数
+const defaultEmailCodeMaxAttempts = 5
+
 type EmailCode struct {
 	Code       string
 	ExpiresAt  time.Time
 	LastSentAt time.Time
+	Attempts   int // 已失败的校验次数
 }
 
 // EmailCodeStore 邮箱验证码存储
 type EmailCodeStore struct {
-	codes map[string]*EmailCode
-	mu    sync.RWMutex
+	codes       map[string]*EmailCode
+	maxAttempts int
+	mu          sync.RWMutex
 }
 
 // NewEmailCodeStore 创建邮箱验证码存储
 func NewEmailCodeStore() *EmailCodeStore {
 	store := &EmailCodeStore{
-		codes: make(map[string]*EmailCode),
+		codes:       make(map[string]*EmailCode),
+		maxAttempts: defaultEmailCodeMaxAttempts,
 	}
 
 	go store.cleanupExpired()
@@ -36,6 +43,16 @@ func NewEmailCodeStore() *EmailCodeStore {
 	return store
 }
 
+// SetMaxAttempts 设置每个验证码允许的错误次数，用尽后需要重新获取验证码
+func (s *EmailCodeStore) SetMaxAttempts(n int) {
+	if n <= 0 {
+		n = defaultEmailCodeMaxAttempts
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxAttempts = n
+}
+
 // Create 生成验证码并存储
 func (s *EmailCodeStore) Create(email string, codeLength int, ttl time.Duration, interval time.Duration) (string, time.Time, time.Duration, error) {
 	if codeLength <= 0 {
@@ -70,6 +87,7 @@ func (s *EmailCodeStore) Create(email string, codeLength int, ttl time.Duration,
 }
 
 // Verify 校验验证码（成功后即删除）
+// 错误次数用尽后验证码作废但保留记录，重新获取仍受发送间隔限制。
 func (s *EmailCodeStore) Verify(email, code string) bool {
 	key := normalizeEmail(email)
 	now := time.Now()
@@ -85,7 +103,12 @@ func (s *EmailCodeStore) Verify(email, code string) bool {
 		delete(s.codes, key)
 		return false
 	}
-	if strings.TrimSpace(code) == "" || entry.Code != strings.TrimSpace(code) {
+	if entry.Attempts >= s.maxAttempts {
+		return false
+	}
+	code = strings.TrimSpace(code)
+	if code == "" || subtle.ConstantTimeCompare([]byte(entry.Code), []byte(code)) != 1 {
+		entry.Attempts++
 		return false
 	}
 
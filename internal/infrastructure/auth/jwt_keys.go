@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	privateKeySuffix = ".pem"
+	publicKeySuffix  = ".pub.pem"
+)
+
+// JWTKey JWT 签名 / 验证密钥
+type JWTKey struct {
+	ID        string
+	Algorithm string
+	ModTime   time.Time
+	private   crypto.Signer // 退役密钥只有公钥，不能签名
+	public    crypto.PublicKey
+}
+
+// JWK JSON Web Key（RFC 7517）公钥表示
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y,omitempty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+}
+
+// JWTKeySet JWT 密钥集合：一个签名密钥与多个验证密钥
+// 轮换时放入新私钥并切换 active_key_id，旧密钥继续验证已签发的令牌，
+// 直到这些令牌过期后再从目录中移除（或只保留 <kid>.pub.pem）。
+type JWTKeySet struct {
+	signing *JWTKey
+	keys    map[string]*JWTKey
+	ordered []*JWTKey
+}
+
+// LoadJWTKeys 从目录加载密钥
+// 目录中没有指定算法的私钥时自动生成一个。activeKeyID 为空时使用最新的私钥签名。
+func LoadJWTKeys(dir, algorithm, activeKeyID string) (*JWTKeySet, error) {
+	if algorithm != jwt.SigningMethodEdDSA.Alg() && algorithm != jwt.SigningMethodES256.Alg() {
+		return nil, fmt.Errorf("unsupported jwt signing algorithm: %s", algorithm)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create jwt keys dir: %w", err)
+	}
+
+	set, err := readJWTKeys(dir)
+	if err != nil {
+		return nil, err
+	}
+	activeKeyID = strings.TrimSpace(activeKeyID)
+	if activeKeyID == "" && set.latestPrivate(algorithm) == nil {
+		key, err := generateJWTKey(dir, algorithm)
+		if err != nil {
+			return nil, err
+		}
+		set.add(key)
+	}
+
+	if activeKeyID != "" {
+		key, ok := set.keys[activeKeyID]
+		if !ok || key.private == nil {
+			return nil, fmt.Errorf("active jwt signing key %q not found in %s", activeKeyID, dir)
+		}
+		if key.Algorithm != algorithm {
+			return nil, fmt.Errorf("active jwt signing key %q is %s, expected %s", activeKeyID, key.Algorithm, algorithm)
+		}
+		set.signing = key
+	} else {
+		set.signing = set.latestPrivate(algorithm)
+	}
+	return set, nil
+}
+
+// SigningKey 当前签名密钥
+func (s *JWTKeySet) SigningKey() *JWTKey {
+	return s.signing
+}
+
+// Lookup 根据 kid 查找验证密钥
+func (s *JWTKeySet) Lookup(kid string) (*JWTKey, bool) {
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// JWKS 全部验证密钥的公钥，签名密钥排在最前
+func (s *JWTKeySet) JWKS() []JWK {
+	items := make([]JWK, 0, len(s.ordered))
+	if s.signing != nil {
+		items = append(items, s.signing.JWK())
+	}
+	for _, key := range s.ordered {
+		if key == s.signing {
+			continue
+		}
+		items = append(items, key.JWK())
+	}
+	return items
+}
+
+// JWK 公钥的 JWK 表示
+func (k *JWTKey) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Alg: k.Algorithm, Use: "sig"}
+	switch pub := k.public.(type) {
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	case *ecdsa.PublicKey:
+		// 非压缩点：0x04 || X || Y
+		point, err := pub.ECDH()
+		if err == nil {
+			raw := point.Bytes()
+			size := (len(raw) - 1) / 2
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(raw[1 : 1+size])
+			jwk.Y = base64.RawURLEncoding.EncodeToString(raw[1+size:])
+		}
+	}
+	return jwk
+}
+
+func (k *JWTKey) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+func (s *JWTKeySet) add(key *JWTKey) {
+	if s.keys == nil {
+		s.keys = make(map[string]*JWTKey)
+	}
+	if existing, ok := s.keys[key.ID]; ok {
+		// 同一 kid 同时存在私钥与公钥文件时以私钥为准
+		if existing.private != nil {
+			return
+		}
+		for i, item := range s.ordered {
+			if item == existing {
+				s.ordered = append(s.ordered[:i], s.ordered[i+1:]...)
+				break
+			}
+		}
+	}
+	s.keys[key.ID] = key
+	s.ordered = append(s.ordered, key)
+	sort.SliceStable(s.ordered, func(i, j int) bool {
+		return s.ordered[i].ModTime.After(s.ordered[j].ModTime)
+	})
+}
+
+func (s *JWTKeySet) latestPrivate(algorithm string) *JWTKey {
+	for _, key := range s.ordered {
+		if key.private != nil && key.Algorithm == algorithm {
+			return key
+		}
+	}
+	return nil
+}
+
+func readJWTKeys(dir string) (*JWTKeySet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwt keys dir: %w", err)
+	}
+	set := &JWTKeySet{keys: make(map[string]*JWTKey)}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, privateKeySuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat jwt key %s: %w", name, err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwt key %s: %w", name, err)
+		}
+
+		var key *JWTKey
+		if strings.HasSuffix(name, publicKeySuffix) {
+			key, err = parseJWTPublicKey(strings.TrimSuffix(name, publicKeySuffix), data)
+		} else {
+			key, err = parseJWTPrivateKey(strings.TrimSuffix(name, privateKeySuffix), data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwt key %s: %w", name, err)
+		}
+		key.ModTime = info.ModTime()
+		set.add(key)
+	}
+	return set, nil
+}
+
+func parseJWTPrivateKey(kid string, data []byte) (*JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	key, err := newJWTKey(kid, signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.private = signer
+	return key, nil
+}
+
+func parseJWTPublicKey(kid string, data []byte) (*JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return newJWTKey(kid, parsed)
+}
+
+func newJWTKey(kid string, public crypto.PublicKey) (*JWTKey, error) {
+	if kid == "" {
+		return nil, errors.New("empty key id")
+	}
+	switch pub := public.(type) {
+	case ed25519.PublicKey:
+		return &JWTKey{ID: kid, Algorithm: jwt.SigningMethodEdDSA.Alg(), public: pub}, nil
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 ECDSA keys are supported")
+		}
+		return &JWTKey{ID: kid, Algorithm: jwt.SigningMethodES256.Alg(), public: pub}, nil
+	default:
+		return nil, errors.New("unsupported public key type")
+	}
+}
+
+// generateJWTKey 生成新私钥并以 PKCS#8 PEM 写入目录
+func generateJWTKey(dir, algorithm string) (*JWTKey, error) {
+	var signer crypto.Signer
+	var err error
+	switch algorithm {
+	case jwt.SigningMethodEdDSA.Alg():
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate jwt key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode jwt key: %w", err)
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("failed to generate key id: %w", err)
+	}
+	kid := time.Now().UTC().Format("20060102") + "-" + hex.EncodeToString(suffix)
+	path := filepath.Join(dir, kid+privateKeySuffix)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write jwt key: %w", err)
+	}
+
+	key, err := newJWTKey(kid, signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.private = signer
+	key.ModTime = time.Now()
+	return key, nil
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testJWTSecret = "abcdefghijklmnopqrstuvwxyz012345"
+
+func TestLoadJWTKeysGeneratesSigningKey(t *testing.T) {
+	for _, alg := range []string{"EdDSA", "ES256"} {
+		dir := t.TempDir()
+		keys, err := LoadJWTKeys(dir, alg, "")
+		if err != nil {
+			t.Fatalf("LoadJWTKeys(%s) failed: %v", alg, err)
+		}
+		signing := keys.SigningKey()
+		if signing == nil || signing.Algorithm != alg {
+			t.Fatalf("unexpected signing key for %s: %+v", alg, signing)
+		}
+		if _, err := os.Stat(filepath.Join(dir, signing.ID+".pem")); err != nil {
+			t.Fatalf("generated key not written: %v", err)
+		}
+
+		// 再次加载复用已有密钥
+		again, err := LoadJWTKeys(dir, alg, "")
+		if err != nil {
+			t.Fatalf("reload failed: %v", err)
+		}
+		if again.SigningKey().ID != signing.ID {
+			t.Fatalf("reload should reuse key %s, got %s", signing.ID, again.SigningKey().ID)
+		}
+
+		jwks := keys.JWKS()
+		if len(jwks) != 1 || jwks[0].Kid != signing.ID || jwks[0].Alg != alg || jwks[0].X == "" {
+			t.Fatalf("unexpected jwks for %s: %+v", alg, jwks)
+		}
+		if alg == "ES256" && (jwks[0].Kty != "EC" || jwks[0].Crv != "P-256" || jwks[0].Y == "") {
+			t.Fatalf("unexpected EC jwk: %+v", jwks[0])
+		}
+	}
+}
+
+func TestJWTManagerKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKeys, err := LoadJWTKeys(dir, "EdDSA", "")
+	if err != nil {
+		t.Fatalf("LoadJWTKeys failed: %v", err)
+	}
+	oldManager := NewJWTManager(testJWTSecret, time.Hour)
+	oldManager.SetKeys(oldKeys, false)
+	oldToken, err := oldManager.Generate("0x1111111111111111111111111111111111111111")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	// 生成新密钥并切换为签名密钥，旧密钥仍可验证
+	newKey, err := generateJWTKey(dir, "ES256")
+	if err != nil {
+		t.Fatalf("generateJWTKey failed: %v", err)
+	}
+	rotated, err := LoadJWTKeys(dir, "ES256", newKey.ID)
+	if err != nil {
+		t.Fatalf("LoadJWTKeys after rotation failed: %v", err)
+	}
+	manager := NewJWTManager(testJWTSecret, time.Hour)
+	manager.SetKeys(rotated, false)
+
+	if _, err := manager.Verify(oldToken.Value); err != nil {
+		t.Fatalf("token signed by previous key should verify: %v", err)
+	}
+	newToken, err := manager.Generate("0x1111111111111111111111111111111111111111")
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, err := manager.Verify(newToken.Value); err != nil {
+		t.Fatalf("token signed by new key should verify: %v", err)
+	}
+	if len(manager.JWKS()) != 2 || manager.JWKS()[0].Kid != newKey.ID {
+		t.Fatalf("jwks should list the signing key first: %+v", manager.JWKS())
+	}
+
+	// 退役密钥被移除后，其签名的令牌不再有效
+	if err := os.Remove(filepath.Join(dir, oldKeys.SigningKey().ID+".pem")); err != nil {
+		t.Fatalf("remove key failed: %v", err)
+	}
+	pruned, err := LoadJWTKeys(dir, "ES256", newKey.ID)
+	if err != nil {
+		t.Fatalf("LoadJWTKeys after prune failed: %v", err)
+	}
+	manager.SetKeys(pruned, false)
+	if _, err := manager.Verify(oldToken.Value); err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Fatalf("expected unknown signing key error, got %v", err)
+	}
+}
+
+func TestJWTManagerHS256Transition(t *testing.T) {
+	legacy := NewJWTManager(testJWTSecret, time.Hour)
+	token, err := legacy.GenerateForEmail("alice@example.com")
+	if err != nil {
+		t.Fatalf("GenerateForEmail failed: %v", err)
+	}
+
+	keys, err := LoadJWTKeys(t.TempDir(), "EdDSA", "")
+	if err != nil {
+		t.Fatalf("LoadJWTKeys failed: %v", err)
+	}
+	manager := NewJWTManager(testJWTSecret, time.Hour)
+	manager.SetKeys(keys, true)
+	if _, err := manager.VerifyClaims(token.Value); err != nil {
+		t.Fatalf("HS256 token should be accepted during transition: %v", err)
+	}
+
+	manager.SetKeys(keys, false)
+	if _, err := manager.VerifyClaims(token.Value); err == nil {
+		t.Fatalf("HS256 token should be rejected after transition")
+	}
+}
@@ -7,19 +7,26 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/session"
 )
 
 // JWTManager JWT 管理器
+// 默认使用 secret 以 HS256 签名；设置密钥集合后改用非对称密钥签名，并在 header 中携带 kid。
 type JWTManager struct {
-	secret     []byte
-	expiration time.Duration
-	issuer     string
+	secret      []byte
+	keys        *JWTKeySet
+	acceptHS256 bool
+	expiration  time.Duration
+	issuer      string
 }
 
 const (
 	TokenTypeAccess  = "access"
 	TokenTypeRefresh = "refresh"
+	// TokenTypeMFA 登录第二步的挑战令牌，只能用于完成两步验证，不能访问其他接口
+	TokenTypeMFA = "mfa"
 )
 
 // Claims JWT 声明
@@ -28,6 +35,7 @@ type Claims struct {
 	Email       string `json:"email,omitempty"`
 	SubjectType string `json:"subject_type,omitempty"`
 	TokenType   string `json:"token_type,omitempty"`
+	SessionID   string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -40,30 +48,72 @@ func NewJWTManager(secret string, expiration time.Duration) *JWTManager {
 	}
 }
 
+// SetKeys 设置非对称签名密钥
+// acceptHS256 为 true 时继续接受 secret 签名的旧令牌，用于切换算法后的过渡期。
+func (m *JWTManager) SetKeys(keys *JWTKeySet, acceptHS256 bool) {
+	m.keys = keys
+	m.acceptHS256 = acceptHS256
+}
+
+// JWKS 公开的验证公钥；仅使用 HS256 时为空
+func (m *JWTManager) JWKS() []JWK {
+	if m.keys == nil {
+		return []JWK{}
+	}
+	return m.keys.JWKS()
+}
+
 // Generate 生成 JWT
 func (m *JWTManager) Generate(address string) (*auth.Token, error) {
-	return m.generate(address, "", "", TokenTypeAccess, m.expiration)
+	return m.generate(address, "", "", TokenTypeAccess, "", "", time.Now().Add(m.expiration))
 }
 
 func (m *JWTManager) GenerateRefresh(address string, expiration time.Duration) (*auth.Token, error) {
-	return m.generate(address, "", "", TokenTypeRefresh, expiration)
+	return m.generate(address, "", "", TokenTypeRefresh, "", "", time.Now().Add(expiration))
 }
 
-func (m *JWTManager) generate(address, email, subjectType, tokenType string, expiration time.Duration) (*auth.Token, error) {
+// GenerateForSession 生成绑定会话的 access / refresh token
+// refresh token 的 jti 与有效期取自会话，access token 使用新的 jti。
+func (m *JWTManager) GenerateForSession(s *session.Session) (*auth.TokenPair, error) {
+	address, email := s.Subject, ""
+	if s.SubjectType == "email" {
+		address, email = "", s.Subject
+	}
+	access, err := m.generate(address, email, s.SubjectType, TokenTypeAccess, s.ID, "", time.Now().Add(m.expiration))
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := m.generate(address, email, s.SubjectType, TokenTypeRefresh, s.ID, s.RefreshJTI, s.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.TokenPair{Access: access, Refresh: refresh}, nil
+}
+
+func (m *JWTManager) generate(address, email, subjectType, tokenType, sessionID, jti string, expiresAt time.Time) (*auth.Token, error) {
 	now := time.Now()
-	expiresAt := now.Add(expiration)
+	if jti == "" {
+		jti = uuid.NewString()
+	}
 
 	subject := address
 	if subject == "" {
 		subject = email
 	}
+	walletAddress := normalizeWalletSubject(address)
+	if subjectType == "passkey" {
+		// 通行密钥账户可能没有钱包与邮箱，主体为用户 ID，不写入 address 声明
+		walletAddress = ""
+	}
 
 	claims := Claims{
-		Address:     strings.ToLower(address),
+		Address:     walletAddress,
 		Email:       strings.ToLower(strings.TrimSpace(email)),
 		SubjectType: subjectType,
 		TokenType:   tokenType,
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
@@ -72,8 +122,7 @@ func (m *JWTManager) generate(address, email, subjectType, tokenType string, exp
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(m.secret)
+	tokenString, err := m.sign(claims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign token: %w", err)
 	}
@@ -81,19 +130,155 @@ func (m *JWTManager) generate(address, email, subjectType, tokenType string, exp
 	return &auth.Token{
 		Value:     tokenString,
 		Address:   subject,
+		ID:        jti,
+		SessionID: sessionID,
+		ExpiresAt: expiresAt,
+		IssuedAt:  now,
+	}, nil
+}
+
+func (m *JWTManager) sign(claims Claims) (string, error) {
+	if m.keys == nil || m.keys.SigningKey() == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	}
+	key := m.keys.SigningKey()
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// keyFunc 按签名算法与 kid 选择验证密钥
+func (m *JWTManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if m.keys != nil && !m.acceptHS256 {
+			return nil, fmt.Errorf("HS256 tokens are no longer accepted")
+		}
+		return m.secret, nil
+	}
+	if m.keys == nil {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := m.keys.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("signing method %v does not match key %q", token.Header["alg"], kid)
+	}
+	return key.public, nil
+}
+
+// GenerateMFAChallenge 生成两步验证挑战令牌
+// 主体为用户 ID，subjectType 记录第一步的登录方式（wallet / email），用于第二步签发正式令牌。
+func (m *JWTManager) GenerateMFAChallenge(userID, subjectType string, expiration time.Duration) (*auth.Token, error) {
+	now := time.Now()
+	expiresAt := now.Add(expiration)
+	jti := uuid.NewString()
+	claims := Claims{
+		SubjectType: subjectType,
+		TokenType:   TokenTypeMFA,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    m.issuer,
+			Subject:   userID,
+		},
+	}
+	tokenString, err := m.sign(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return &auth.Token{
+		Value:     tokenString,
+		Address:   userID,
+		ID:        jti,
+		ExpiresAt: expiresAt,
+		IssuedAt:  now,
+	}, nil
+}
+
+// VerifyMFAChallenge 验证两步验证挑战令牌并返回 Claims
+func (m *JWTManager) VerifyMFAChallenge(tokenString string) (*Claims, error) {
+	claims, err := m.verifyClaims(tokenString, TokenTypeMFA, false)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, auth.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// GenerateMailToken 生成邮件链接令牌（重置密码、验证邮箱）
+// 主体为用户 ID，token_type 为用途，因此不能当作访问令牌使用。
+func (m *JWTManager) GenerateMailToken(purpose, userID, email string, expiration time.Duration) (*auth.Token, error) {
+	now := time.Now()
+	expiresAt := now.Add(expiration)
+	jti := uuid.NewString()
+	claims := Claims{
+		Email:     strings.ToLower(strings.TrimSpace(email)),
+		TokenType: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    m.issuer,
+			Subject:   userID,
+		},
+	}
+	tokenString, err := m.sign(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return &auth.Token{
+		Value:     tokenString,
+		Address:   userID,
+		ID:        jti,
 		ExpiresAt: expiresAt,
 		IssuedAt:  now,
 	}, nil
 }
 
+// VerifyMailToken 验证指定用途的邮件链接令牌（不检查是否已使用）
+func (m *JWTManager) VerifyMailToken(purpose, tokenString string) (*auth.MailToken, error) {
+	claims, err := m.verifyClaims(tokenString, purpose, false)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" || claims.ID == "" || claims.Email == "" || claims.ExpiresAt == nil {
+		return nil, auth.ErrInvalidToken
+	}
+	return &auth.MailToken{
+		ID:        claims.ID,
+		Purpose:   purpose,
+		UserID:    claims.Subject,
+		Email:     claims.Email,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// GenerateForPasskey 生成通行密钥登录 JWT，主体为用户 ID
+func (m *JWTManager) GenerateForPasskey(userID string) (*auth.Token, error) {
+	return m.generate(userID, "", "passkey", TokenTypeAccess, "", "", time.Now().Add(m.expiration))
+}
+
+// GenerateRefreshForPasskey 生成通行密钥登录刷新 token
+func (m *JWTManager) GenerateRefreshForPasskey(userID string, expiration time.Duration) (*auth.Token, error) {
+	return m.generate(userID, "", "passkey", TokenTypeRefresh, "", "", time.Now().Add(expiration))
+}
+
 // GenerateForEmail 生成邮箱登录 JWT
 func (m *JWTManager) GenerateForEmail(email string) (*auth.Token, error) {
-	return m.generate("", email, "email", TokenTypeAccess, m.expiration)
+	return m.generate("", email, "email", TokenTypeAccess, "", "", time.Now().Add(m.expiration))
 }
 
 // GenerateRefreshForEmail 生成邮箱登录刷新 token
 func (m *JWTManager) GenerateRefreshForEmail(email string, expiration time.Duration) (*auth.Token, error) {
-	return m.generate("", email, "email", TokenTypeRefresh, expiration)
+	return m.generate("", email, "email", TokenTypeRefresh, "", "", time.Now().Add(expiration))
 }
 
 // Verify 验证 JWT
@@ -124,13 +309,8 @@ func (m *JWTManager) VerifyRefreshClaims(tokenString string) (*Claims, error) {
 }
 
 func (m *JWTManager) verifyClaims(tokenString, expectedType string, allowEmptyType bool) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// 验证签名方法
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return m.secret, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keyFunc,
+		jwt.WithValidMethods([]string{"HS256", "EdDSA", "ES256"}))
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
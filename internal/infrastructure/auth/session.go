@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"go.uber.org/zap"
+)
+
+// sessionTouchInterval 会话最后活跃时间的最小更新间隔
+const sessionTouchInterval = time.Minute
+
+// SessionStore 登录会话存储
+type SessionStore interface {
+	Create(ctx context.Context, s *session.Session) error
+	GetByID(ctx context.Context, id string) (*session.Session, error)
+	Rotate(ctx context.Context, id, previousJTI, nextJTI string, expiresAt time.Time, client session.Client) (bool, error)
+	Touch(ctx context.Context, id string, at time.Time) error
+	Revoke(ctx context.Context, id, reason string) error
+}
+
+// SetSessionStore 设置会话存储；未设置时签发不可吊销的无状态令牌
+func (a *Web3Authenticator) SetSessionStore(store SessionStore) {
+	a.sessions = store
+}
+
+// IssueTokens 登录成功后创建会话并签发 access / refresh token
+// subjectType 为 "email" 时签发邮箱令牌，为 "passkey" 时 subject 为用户 ID，否则 subject 为钱包地址；
+// method 为本次登录的认证方式（session.Method*），记录在会话上。
+func (a *Web3Authenticator) IssueTokens(ctx context.Context, u *user.User, subject, subjectType, method string, client session.Client) (*auth.TokenPair, error) {
+	if a.sessions == nil {
+		return a.issueStatelessTokens(subject, subjectType)
+	}
+
+	s := session.New(u.ID, subjectType, subject, client, time.Now().Add(a.refreshExpiration))
+	s.AuthMethod = method
+	if err := a.sessions.Create(ctx, s); err != nil {
+		return nil, err
+	}
+	pair, err := a.jwtManager.GenerateForSession(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	logger.Ctx(ctx, a.logger).Info("session created",
+		zap.String("username", u.Username),
+		zap.String("session_id", s.ID),
+		zap.String("method", method),
+		zap.String("ip", client.IP))
+	return pair, nil
+}
+
+// RefreshTokens 使用 refresh token 轮换令牌
+// 每个 refresh token 只能使用一次；已轮换的 refresh token 再次出现视为泄露，整个会话随即吊销。
+// 启用会话后不再接受无会话（升级前签发）的 refresh token：其无法记录轮换状态，可被无限重放，需重新登录。
+func (a *Web3Authenticator) RefreshTokens(ctx context.Context, refreshToken string, u *user.User, client session.Client) (*auth.TokenPair, error) {
+	claims, err := a.jwtManager.VerifyRefreshClaims(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	subject, subjectType, err := claimsSubject(claims)
+	if err != nil {
+		return nil, err
+	}
+	if a.sessions == nil {
+		return a.issueStatelessTokens(subject, subjectType)
+	}
+	if claims.SessionID == "" {
+		logger.Ctx(ctx, a.logger).Warn("refresh token without session rejected",
+			zap.String("username", u.Username),
+			zap.String("ip", client.IP))
+		return nil, auth.ErrInvalidToken
+	}
+
+	s, err := a.sessions.GetByID(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
+			return nil, auth.ErrInvalidToken
+		}
+		return nil, err
+	}
+	if s.UserID != u.ID {
+		return nil, auth.ErrInvalidToken
+	}
+	if !s.Active(time.Now()) {
+		return nil, session.ErrSessionRevoked
+	}
+
+	nextJTI := uuid.NewString()
+	expiresAt := time.Now().Add(a.refreshExpiration)
+	rotated, err := a.sessions.Rotate(ctx, s.ID, claims.ID, nextJTI, expiresAt, client)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		if err := a.sessions.Revoke(ctx, s.ID, session.ReasonRefreshReuse); err != nil {
+			logger.Ctx(ctx, a.logger).Error("failed to revoke session after refresh token reuse",
+				zap.String("session_id", s.ID),
+				zap.Error(err))
+		}
+		logger.Ctx(ctx, a.logger).Warn("refresh token reuse detected, session revoked",
+			zap.String("username", u.Username),
+			zap.String("session_id", s.ID),
+			zap.String("ip", client.IP))
+		return nil, session.ErrRefreshTokenReused
+	}
+
+	s.RefreshJTI = nextJTI
+	s.ExpiresAt = expiresAt
+	pair, err := a.jwtManager.GenerateForSession(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return pair, nil
+}
+
+// RevokeToken 吊销 access 或 refresh token 所属的会话（退出登录）
+// 无会话或已过期的令牌直接忽略。
+func (a *Web3Authenticator) RevokeToken(ctx context.Context, token string) error {
+	if a.sessions == nil || token == "" || isUcanToken(token) {
+		return nil
+	}
+	claims, err := a.jwtManager.VerifyRefreshClaims(token)
+	if err != nil {
+		claims, err = a.jwtManager.VerifyClaims(token)
+	}
+	if err != nil || claims.SessionID == "" {
+		return nil
+	}
+	if err := a.sessions.Revoke(ctx, claims.SessionID, session.ReasonLogout); err != nil {
+		return err
+	}
+	logger.Ctx(ctx, a.logger).Info("session revoked by logout", zap.String("session_id", claims.SessionID))
+	return nil
+}
+
+// checkSession 校验 access token 所属会话仍然有效
+// 启用会话后不再接受无会话的 access token：其无法被退出登录或管理员吊销。
+func (a *Web3Authenticator) checkSession(ctx context.Context, claims *Claims) error {
+	if a.sessions == nil {
+		return nil
+	}
+	if claims.SessionID == "" {
+		return auth.ErrInvalidToken
+	}
+	s, err := a.sessions.GetByID(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, session.ErrSessionNotFound) {
+			return session.ErrSessionRevoked
+		}
+		return err
+	}
+	now := time.Now()
+	if !s.Active(now) {
+		return session.ErrSessionRevoked
+	}
+	if now.Sub(s.LastSeenAt) >= sessionTouchInterval {
+		if err := a.sessions.Touch(ctx, s.ID, now); err != nil {
+			logger.Ctx(ctx, a.logger).Warn("failed to update session last seen",
+				zap.String("session_id", s.ID),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (a *Web3Authenticator) issueStatelessTokens(subject, subjectType string) (*auth.TokenPair, error) {
+	var access, refresh *auth.Token
+	var err error
+	switch subjectType {
+	case "email":
+		access, err = a.jwtManager.GenerateForEmail(subject)
+	case "passkey":
+		access, err = a.jwtManager.GenerateForPasskey(subject)
+	default:
+		access, err = a.jwtManager.Generate(subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch subjectType {
+	case "email":
+		refresh, err = a.jwtManager.GenerateRefreshForEmail(subject, a.refreshExpiration)
+	case "passkey":
+		refresh, err = a.jwtManager.GenerateRefreshForPasskey(subject, a.refreshExpiration)
+	default:
+		refresh, err = a.jwtManager.GenerateRefresh(subject, a.refreshExpiration)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &auth.TokenPair{Access: access, Refresh: refresh}, nil
+}
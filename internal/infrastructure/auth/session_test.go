@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domainauth "github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+)
+
+func TestWeb3AuthenticatorRefreshRotationAndReuse(t *testing.T) {
+	repo := newUserRepo()
+	u := user.NewUser("dave", "dave")
+	if err := u.SetWalletAddress("0x3333333333333333333333333333333333333333"); err != nil {
+		t.Fatalf("SetWalletAddress failed: %v", err)
+	}
+	if err := repo.Save(context.Background(), u); err != nil {
+		t.Fatalf("Save user failed: %v", err)
+	}
+
+	authenticator := newJWTTestAuthenticator(t, repo, t.TempDir())
+	store := newStubSessionStore()
+	authenticator.SetSessionStore(store)
+	ctx := context.Background()
+	client := session.Client{DeviceName: "laptop", IP: "127.0.0.1"}
+
+	first, err := authenticator.IssueTokens(ctx, u, u.WalletAddress, "wallet", session.MethodWallet, client)
+	if err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+	if first.Access.SessionID == "" || first.Access.SessionID != first.Refresh.SessionID {
+		t.Fatalf("tokens should share a session id: %q %q", first.Access.SessionID, first.Refresh.SessionID)
+	}
+
+	second, err := authenticator.RefreshTokens(ctx, first.Refresh.Value, u, client)
+	if err != nil {
+		t.Fatalf("RefreshTokens failed: %v", err)
+	}
+	if second.Refresh.ID == first.Refresh.ID {
+		t.Fatalf("refresh token should be rotated")
+	}
+	if _, err := authenticator.Authenticate(ctx, &domainauth.BearerCredentials{Token: second.Access.Value}); err != nil {
+		t.Fatalf("Authenticate with rotated access token failed: %v", err)
+	}
+
+	// 重放已轮换的 refresh token：整个会话被吊销
+	if _, err := authenticator.RefreshTokens(ctx, first.Refresh.Value, u, client); !errors.Is(err, session.ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+	if _, err := authenticator.RefreshTokens(ctx, second.Refresh.Value, u, client); !errors.Is(err, session.ErrSessionRevoked) {
+		t.Fatalf("expected ErrSessionRevoked, got %v", err)
+	}
+	if _, err := authenticator.Authenticate(ctx, &domainauth.BearerCredentials{Token: second.Access.Value}); !errors.Is(err, session.ErrSessionRevoked) {
+		t.Fatalf("access token of revoked session should be rejected, got %v", err)
+	}
+}
+
+func TestWeb3AuthenticatorRejectsRefreshWithoutSession(t *testing.T) {
+	repo := newUserRepo()
+	u := user.NewUser("frank", "frank")
+	if err := u.SetWalletAddress("0x4444444444444444444444444444444444444444"); err != nil {
+		t.Fatalf("SetWalletAddress failed: %v", err)
+	}
+	if err := repo.Save(context.Background(), u); err != nil {
+		t.Fatalf("Save user failed: %v", err)
+	}
+
+	authenticator := newJWTTestAuthenticator(t, repo, t.TempDir())
+	authenticator.SetSessionStore(newStubSessionStore())
+	ctx := context.Background()
+
+	// 升级前签发的 refresh token 不含 sid，无法记录轮换，重放时不能换出新令牌
+	legacy, err := authenticator.jwtManager.GenerateRefresh(u.WalletAddress, time.Hour)
+	if err != nil {
+		t.Fatalf("GenerateRefresh failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := authenticator.RefreshTokens(ctx, legacy.Value, u, session.Client{}); !errors.Is(err, domainauth.ErrInvalidToken) {
+			t.Fatalf("expected sid-less refresh token to be rejected, got %v", err)
+		}
+	}
+}
+
+func TestWeb3AuthenticatorRejectsAccessWithoutSession(t *testing.T) {
+	repo := newUserRepo()
+	u := user.NewUser("grace", "grace")
+	if err := u.SetWalletAddress("0x6666666666666666666666666666666666666666"); err != nil {
+		t.Fatalf("SetWalletAddress failed: %v", err)
+	}
+	if err := repo.Save(context.Background(), u); err != nil {
+		t.Fatalf("Save user failed: %v", err)
+	}
+
+	authenticator := newJWTTestAuthenticator(t, repo, t.TempDir())
+	authenticator.SetSessionStore(newStubSessionStore())
+	ctx := context.Background()
+
+	// 不含 sid 的 access token 无法被退出登录或管理员吊销
+	legacy, err := authenticator.jwtManager.Generate(u.WalletAddress)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if _, err := authenticator.Authenticate(ctx, &domainauth.BearerCredentials{Token: legacy.Value}); !errors.Is(err, domainauth.ErrInvalidToken) {
+		t.Fatalf("expected sid-less access token to be rejected, got %v", err)
+	}
+
+	pair, err := authenticator.IssueTokens(ctx, u, u.WalletAddress, "wallet", session.MethodWallet, session.Client{})
+	if err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+	if _, err := authenticator.Authenticate(ctx, &domainauth.BearerCredentials{Token: pair.Access.Value}); err != nil {
+		t.Fatalf("Authenticate with session token failed: %v", err)
+	}
+}
+
+func TestWeb3AuthenticatorRevokeToken(t *testing.T) {
+	repo := newUserRepo()
+	u := user.NewUser("erin", "erin")
+	if err := u.SetEmail("erin@example.com"); err != nil {
+		t.Fatalf("SetEmail failed: %v", err)
+	}
+	if err := repo.Save(context.Background(), u); err != nil {
+		t.Fatalf("Save user failed: %v", err)
+	}
+
+	authenticator := newJWTTestAuthenticator(t, repo, t.TempDir())
+	authenticator.SetSessionStore(newStubSessionStore())
+	ctx := context.Background()
+
+	pair, err := authenticator.IssueTokens(ctx, u, u.Email, "email", session.MethodEmail, session.Client{})
+	if err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+	if err := authenticator.RevokeToken(ctx, pair.Access.Value); err != nil {
+		t.Fatalf("RevokeToken failed: %v", err)
+	}
+	if _, err := authenticator.Authenticate(ctx, &domainauth.BearerCredentials{Token: pair.Access.Value}); !errors.Is(err, session.ErrSessionRevoked) {
+		t.Fatalf("access token should be rejected after logout, got %v", err)
+	}
+}
+
+type stubSessionStore struct {
+	items map[string]*session.Session
+}
+
+func newStubSessionStore() *stubSessionStore {
+	return &stubSessionStore{items: make(map[string]*session.Session)}
+}
+
+func (s *stubSessionStore) Create(ctx context.Context, item *session.Session) error {
+	clone := *item
+	s.items[item.ID] = &clone
+	return nil
+}
+
+func (s *stubSessionStore) GetByID(ctx context.Context, id string) (*session.Session, error) {
+	item, ok := s.items[id]
+	if !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	clone := *item
+	return &clone, nil
+}
+
+func (s *stubSessionStore) Rotate(ctx context.Context, id, previousJTI, nextJTI string, expiresAt time.Time, client session.Client) (bool, error) {
+	item, ok := s.items[id]
+	if !ok || item.RevokedAt != nil || item.RefreshJTI != previousJTI {
+		return false, nil
+	}
+	item.RefreshJTI = nextJTI
+	item.ExpiresAt = expiresAt
+	return true, nil
+}
+
+func (s *stubSessionStore) Touch(ctx context.Context, id string, at time.Time) error {
+	if item, ok := s.items[id]; ok {
+		item.LastSeenAt = at
+	}
+	return nil
+}
+
+func (s *stubSessionStore) Revoke(ctx context.Context, id, reason string) error {
+	if item, ok := s.items[id]; ok && item.RevokedAt == nil {
+		now := time.Now()
+		item.RevokedAt = &now
+		item.RevokeReason = reason
+	}
+	return nil
+}
@@ -0,0 +1,408 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/yeying-community/warehouse/internal/domain/account"
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+)
+
+const siweVersion = "1"
+
+// siweBlockchains 消息头中的链名称（EIP-4361 为 Ethereum，SIWS 为 Solana）
+var siweBlockchains = map[string]string{
+	account.NamespaceEIP155: "Ethereum",
+	account.NamespaceSolana: "Solana",
+}
+
+// SIWEMessage EIP-4361 消息（Solana 账户使用相同结构的 SIWS 消息）
+type SIWEMessage struct {
+	Namespace      string // CAIP-2 命名空间，空值视为 eip155
+	Scheme         string
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        uint64 // EVM 链 ID
+	ChainRef       string // 非 EVM 链的 CAIP-2 reference
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// String 按 EIP-4361 格式输出消息
+func (m *SIWEMessage) String() string {
+	var b strings.Builder
+	if m.Scheme != "" {
+		b.WriteString(m.Scheme + "://")
+	}
+	b.WriteString(m.Domain + siweHeader(m.namespace()) + "\n")
+	b.WriteString(m.Address + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString("URI: " + m.URI + "\n")
+	b.WriteString("Version: " + m.Version + "\n")
+	b.WriteString("Chain ID: " + m.chain() + "\n")
+	b.WriteString("Nonce: " + m.Nonce + "\n")
+	b.WriteString("Issued At: " + m.IssuedAt.UTC().Format(time.RFC3339))
+	if m.ExpirationTime != nil {
+		b.WriteString("\nExpiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if m.NotBefore != nil {
+		b.WriteString("\nNot Before: " + m.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if m.RequestID != "" {
+		b.WriteString("\nRequest ID: " + m.RequestID)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, r := range m.Resources {
+			b.WriteString("\n- " + r)
+		}
+	}
+	return b.String()
+}
+
+func (m *SIWEMessage) namespace() string {
+	if m.Namespace == "" {
+		return account.NamespaceEIP155
+	}
+	return m.Namespace
+}
+
+func (m *SIWEMessage) chain() string {
+	if m.namespace() == account.NamespaceEIP155 {
+		return strconv.FormatUint(m.ChainID, 10)
+	}
+	return m.ChainRef
+}
+
+func siweHeader(namespace string) string {
+	return " wants you to sign in with your " + siweBlockchains[namespace] + " account:"
+}
+
+// ParseSIWEMessage 解析 EIP-4361 / SIWS 消息
+func ParseSIWEMessage(raw string) (*SIWEMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	if len(lines) < 4 {
+		return nil, fmt.Errorf("message too short")
+	}
+
+	m := &SIWEMessage{}
+	var header string
+	for namespace := range siweBlockchains {
+		if h, ok := strings.CutSuffix(lines[0], siweHeader(namespace)); ok {
+			header, m.Namespace = h, namespace
+			break
+		}
+	}
+	if m.Namespace == "" {
+		return nil, fmt.Errorf("missing sign-in header")
+	}
+	if scheme, domain, found := strings.Cut(header, "://"); found {
+		m.Scheme, m.Domain = scheme, domain
+	} else {
+		m.Domain = header
+	}
+	if m.Domain == "" {
+		return nil, fmt.Errorf("missing domain")
+	}
+	m.Address = lines[1]
+	if lines[2] != "" {
+		return nil, fmt.Errorf("expected empty line after address")
+	}
+
+	i := 3
+	if lines[i] != "" && !strings.HasPrefix(lines[i], "URI: ") {
+		m.Statement = lines[i]
+		i++
+	}
+	if i >= len(lines) || lines[i] != "" {
+		return nil, fmt.Errorf("expected empty line before fields")
+	}
+	i++
+
+	// 字段按规范顺序出现，必填字段缺失即视为无效
+	fields := []struct {
+		key      string
+		required bool
+		set      func(string) error
+	}{
+		{"URI", true, func(v string) error { m.URI = v; return nil }},
+		{"Version", true, func(v string) error { m.Version = v; return nil }},
+		{"Chain ID", true, func(v string) error {
+			if m.Namespace != account.NamespaceEIP155 {
+				if v == "" {
+					return fmt.Errorf("invalid chain id")
+				}
+				m.ChainRef = v
+				return nil
+			}
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid chain id")
+			}
+			m.ChainID = id
+			return nil
+		}},
+		{"Nonce", true, func(v string) error { m.Nonce = v; return nil }},
+		{"Issued At", true, func(v string) error {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return fmt.Errorf("invalid issued at")
+			}
+			m.IssuedAt = t
+			return nil
+		}},
+		{"Expiration Time", false, func(v string) error {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return fmt.Errorf("invalid expiration time")
+			}
+			m.ExpirationTime = &t
+			return nil
+		}},
+		{"Not Before", false, func(v string) error {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return fmt.Errorf("invalid not before")
+			}
+			m.NotBefore = &t
+			return nil
+		}},
+		{"Request ID", false, func(v string) error { m.RequestID = v; return nil }},
+	}
+	for _, f := range fields {
+		if i < len(lines) {
+			if v, ok := strings.CutPrefix(lines[i], f.key+": "); ok {
+				if err := f.set(v); err != nil {
+					return nil, err
+				}
+				i++
+				continue
+			}
+		}
+		if f.required {
+			return nil, fmt.Errorf("missing %s", f.key)
+		}
+	}
+	if i < len(lines) && lines[i] == "Resources:" {
+		for i++; i < len(lines); i++ {
+			r, ok := strings.CutPrefix(lines[i], "- ")
+			if !ok {
+				break
+			}
+			m.Resources = append(m.Resources, r)
+		}
+	}
+	if i != len(lines) {
+		return nil, fmt.Errorf("unexpected line: %q", lines[i])
+	}
+	return m, nil
+}
+
+// SIWERequest 签发或校验挑战时的请求上下文（由 HTTP 层填充）
+type SIWERequest struct {
+	Scheme  string // http / https
+	Origin  string // 浏览器 Origin 头，存在时必须与 domain 一致
+	ChainID uint64 // 客户端请求的 EVM 链 ID，0 表示使用默认链
+}
+
+// SIWEPolicy EIP-4361 签发与校验策略
+type SIWEPolicy struct {
+	legacy       bool
+	domain       string
+	uri          string
+	statement    string
+	chainIDs     []uint64
+	solanaChains []string
+	ttl          time.Duration
+	skew         time.Duration
+}
+
+// NewSIWEPolicy 根据配置创建策略，未设置的字段使用默认值
+func NewSIWEPolicy(cfg config.SIWEConfig) *SIWEPolicy {
+	p := &SIWEPolicy{
+		legacy:       cfg.LegacyMessage,
+		domain:       strings.ToLower(strings.TrimSpace(cfg.Domain)),
+		uri:          strings.TrimSpace(cfg.URI),
+		statement:    strings.TrimSpace(cfg.Statement),
+		chainIDs:     cfg.ChainIDs,
+		solanaChains: cfg.SolanaChains,
+		ttl:          cfg.ChallengeTTL,
+		skew:         cfg.ClockSkew,
+	}
+	if len(p.chainIDs) == 0 {
+		p.chainIDs = []uint64{1}
+	}
+	if p.ttl <= 0 {
+		p.ttl = 5 * time.Minute
+	}
+	if p.skew < 0 {
+		p.skew = 0
+	}
+	return p
+}
+
+// Legacy 是否对 EVM 账户使用旧版挑战消息（非 EVM 账户始终使用 SIWS）
+func (p *SIWEPolicy) Legacy() bool {
+	return p.legacy
+}
+
+// TTL 挑战有效期
+func (p *SIWEPolicy) TTL() time.Duration {
+	return p.ttl
+}
+
+// NewMessage 为账户生成待签名的 EIP-4361 / SIWS 消息（nonce 与时间由挑战存储填充）
+func (p *SIWEPolicy) NewMessage(id account.ID, req SIWERequest) (*SIWEMessage, error) {
+	domain := p.domain
+	if domain == "" {
+		return nil, fmt.Errorf("siwe domain is not configured")
+	}
+	m := &SIWEMessage{
+		Namespace: id.Namespace,
+		Domain:    domain,
+		Address:   id.Address,
+		Statement: p.statement,
+		URI:       p.uri,
+		Version:   siweVersion,
+	}
+	if m.URI == "" {
+		scheme := req.Scheme
+		if scheme == "" {
+			scheme = "https"
+		}
+		m.URI = scheme + "://" + domain
+	}
+
+	switch id.Namespace {
+	case account.NamespaceEIP155:
+		chainID := req.ChainID
+		if chainID == 0 {
+			chainID = p.chainIDs[0]
+		}
+		if !p.chainAllowed(chainID) {
+			return nil, fmt.Errorf("%w: chain id %d not allowed", auth.ErrInvalidChallenge, chainID)
+		}
+		m.ChainID = chainID
+		m.Address = common.HexToAddress(id.Address).Hex()
+	case account.NamespaceSolana:
+		if !p.solanaAllowed(id.Reference) {
+			return nil, fmt.Errorf("%w: solana chain %s not allowed", auth.ErrInvalidChallenge, id.Reference)
+		}
+		m.ChainRef = id.Reference
+	default:
+		return nil, fmt.Errorf("%w: unsupported namespace %s", auth.ErrInvalidChallenge, id.Namespace)
+	}
+	return m, nil
+}
+
+// Verify 严格校验签名消息与签发的挑战及当前请求是否一致
+func (p *SIWEPolicy) Verify(m *SIWEMessage, challenge *auth.Challenge, id account.ID, req SIWERequest, now time.Time) error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s", auth.ErrInvalidChallenge, reason)
+	}
+
+	if m.Version != siweVersion {
+		return invalid("unsupported version")
+	}
+	domain := strings.ToLower(m.Domain)
+	if domain != p.domain || domain != challenge.Domain {
+		return invalid("domain mismatch")
+	}
+	if m.Scheme != "" && req.Scheme != "" && !strings.EqualFold(m.Scheme, req.Scheme) {
+		return invalid("scheme mismatch")
+	}
+	if req.Origin != "" {
+		origin, err := url.Parse(req.Origin)
+		if err != nil || !strings.EqualFold(origin.Host, domain) {
+			return invalid("origin mismatch")
+		}
+	}
+	uri, err := url.Parse(m.URI)
+	if err != nil || uri.Scheme == "" || !strings.EqualFold(uri.Host, domain) {
+		return invalid("uri does not match domain")
+	}
+	if p.uri != "" && !sameOrigin(m.URI, p.uri) {
+		return invalid("uri mismatch")
+	}
+
+	if m.namespace() != id.Namespace {
+		return invalid("account namespace mismatch")
+	}
+	if id.IsEVM() {
+		if !common.IsHexAddress(m.Address) || common.HexToAddress(m.Address).Hex() != m.Address {
+			return invalid("address must be EIP-55 checksummed")
+		}
+		if !strings.EqualFold(m.Address, id.Address) {
+			return invalid("address mismatch")
+		}
+		if !p.chainAllowed(m.ChainID) || m.ChainID != challenge.ChainID {
+			return invalid("chain id not allowed")
+		}
+	} else {
+		if m.Address != id.Address {
+			return invalid("address mismatch")
+		}
+		if !p.solanaAllowed(m.ChainRef) || m.ChainRef != challenge.ChainRef || m.ChainRef != id.Reference {
+			return invalid("chain not allowed")
+		}
+	}
+	if id.Key() != challenge.Address {
+		return invalid("address mismatch")
+	}
+	if m.Nonce != challenge.Nonce {
+		return invalid("nonce mismatch")
+	}
+
+	if m.IssuedAt.After(now.Add(p.skew)) || m.IssuedAt.Before(challenge.IssuedAt.Add(-p.skew)) {
+		return invalid("issued at out of range")
+	}
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return auth.ErrChallengeExpired
+	}
+	if m.NotBefore != nil && now.Add(p.skew).Before(*m.NotBefore) {
+		return invalid("message not yet valid")
+	}
+	return nil
+}
+
+func (p *SIWEPolicy) chainAllowed(id uint64) bool {
+	for _, allowed := range p.chainIDs {
+		if allowed == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *SIWEPolicy) solanaAllowed(ref string) bool {
+	for _, allowed := range p.solanaChains {
+		if allowed == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func sameOrigin(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Scheme, ub.Scheme) && strings.EqualFold(ua.Host, ub.Host)
+}
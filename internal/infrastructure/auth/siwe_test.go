@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/yeying-community/warehouse/internal/domain/account"
+	domainauth "github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+)
+
+func signPersonal(t *testing.T, key *ecdsa.PrivateKey, message string) string {
+	t.Helper()
+	hash := gethcrypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)))
+	sig, err := gethcrypto.Sign(hash, key)
+	if err != nil {
+		t.Fatalf("Sign returned error: %v", err)
+	}
+	sig[64] += 27
+	return "0x" + hex.EncodeToString(sig)
+}
+
+func TestSIWEMessageRoundTrip(t *testing.T) {
+	exp := time.Date(2026, 1, 2, 3, 9, 5, 0, time.UTC)
+	m := &SIWEMessage{
+		Scheme:         "https",
+		Domain:         "drive.example.com",
+		Address:        "0x71C7656EC7ab88b098defB751B7401B5f6d8976F",
+		Statement:      "Sign in to Warehouse.",
+		URI:            "https://drive.example.com",
+		Version:        "1",
+		ChainID:        137,
+		Nonce:          "abc123def456",
+		IssuedAt:       time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		ExpirationTime: &exp,
+		Resources:      []string{"ipfs://bafy", "https://drive.example.com/terms"},
+	}
+	parsed, err := ParseSIWEMessage(m.String())
+	if err != nil {
+		t.Fatalf("ParseSIWEMessage returned error: %v", err)
+	}
+	if parsed.String() != m.String() {
+		t.Fatalf("round trip mismatch:\n%s\n---\n%s", parsed.String(), m.String())
+	}
+
+	m.Statement = ""
+	if _, err := ParseSIWEMessage(m.String()); err != nil {
+		t.Fatalf("message without statement should parse: %v", err)
+	}
+	broken := strings.Replace(m.String(), "Version: 1\n", "", 1)
+	if _, err := ParseSIWEMessage(broken); err == nil {
+		t.Fatalf("expected message without version to be rejected")
+	}
+}
+
+func TestWeb3AuthenticatorSIWE(t *testing.T) {
+	key, err := gethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	address := strings.ToLower(gethcrypto.PubkeyToAddress(key.PublicKey).Hex())
+	authenticator := newJWTTestAuthenticator(t, newUserRepo(), t.TempDir())
+	authenticator.SetSIWEPolicy(NewSIWEPolicy(config.SIWEConfig{
+		Domain:       "drive.example.com",
+		ChainIDs:     []uint64{1, 137},
+		ChallengeTTL: time.Minute,
+		ClockSkew:    time.Minute,
+	}))
+	req := SIWERequest{Scheme: "https", Origin: "https://drive.example.com"}
+	ctx := context.Background()
+
+	if _, err := authenticator.CreateChallenge(address, SIWERequest{ChainID: 10}); !errors.Is(err, domainauth.ErrInvalidChallenge) {
+		t.Fatalf("expected disallowed chain to be rejected, got %v", err)
+	}
+
+	challenge, err := authenticator.CreateChallenge(address, SIWERequest{Scheme: req.Scheme, ChainID: 137})
+	if err != nil {
+		t.Fatalf("CreateChallenge returned error: %v", err)
+	}
+	if challenge.Domain != "drive.example.com" || challenge.ChainID != 137 {
+		t.Fatalf("unexpected challenge binding: domain=%s chain=%d", challenge.Domain, challenge.ChainID)
+	}
+	sig := signPersonal(t, key, challenge.Message)
+
+	phishing := req
+	phishing.Origin = "https://evil.example.com"
+	if _, err := authenticator.VerifyWalletControl(ctx, address, sig, "", phishing); !errors.Is(err, domainauth.ErrInvalidChallenge) {
+		t.Fatalf("expected foreign origin to be rejected, got %v", err)
+	}
+	tampered := strings.Replace(challenge.Message, "Chain ID: 137", "Chain ID: 1", 1)
+	if _, err := authenticator.VerifyWalletControl(ctx, address, signPersonal(t, key, tampered), tampered, req); !errors.Is(err, domainauth.ErrInvalidChallenge) {
+		t.Fatalf("expected chain mismatch to be rejected, got %v", err)
+	}
+
+	if _, err := authenticator.VerifyWalletControl(ctx, address, sig, challenge.Message, req); err != nil {
+		t.Fatalf("VerifyWalletControl returned error: %v", err)
+	}
+	authenticator.GetChallengeStore().Store(challenge)
+	if _, err := authenticator.VerifyWalletControl(ctx, address, sig, challenge.Message, req); err != nil {
+		t.Fatalf("re-stored challenge should verify once more: %v", err)
+	}
+	if _, err := authenticator.VerifyWalletControl(ctx, address, sig, challenge.Message, req); !errors.Is(err, domainauth.ErrChallengeExpired) {
+		t.Fatalf("expected replayed nonce to be rejected, got %v", err)
+	}
+}
+
+func TestSIWEPolicyRequiresConfiguredDomain(t *testing.T) {
+	policy := NewSIWEPolicy(config.SIWEConfig{ChainIDs: []uint64{1}})
+	id, err := account.Parse("0x0000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	// 不能退回到客户端提供的 Host 或 Origin
+	if _, err := policy.NewMessage(id, SIWERequest{Scheme: "https", Origin: "https://evil.example.com"}); err == nil {
+		t.Fatalf("expected challenge without configured domain to be refused")
+	}
+}
+
+func TestWeb3AuthenticatorLegacyChallenge(t *testing.T) {
+	key, err := gethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	address := strings.ToLower(gethcrypto.PubkeyToAddress(key.PublicKey).Hex())
+	authenticator := newJWTTestAuthenticator(t, newUserRepo(), t.TempDir())
+	authenticator.SetSIWEPolicy(NewSIWEPolicy(config.SIWEConfig{LegacyMessage: true}))
+
+	challenge, err := authenticator.CreateChallenge(address, SIWERequest{})
+	if err != nil {
+		t.Fatalf("CreateChallenge returned error: %v", err)
+	}
+	if challenge.Domain != "" || strings.Contains(challenge.Message, "wants you to sign in") {
+		t.Fatalf("legacy challenge should not use EIP-4361 format: %q", challenge.Message)
+	}
+	sig := signPersonal(t, key, challenge.Message)
+	if _, err := authenticator.VerifyWalletControl(context.Background(), address, sig, "other", SIWERequest{}); !errors.Is(err, domainauth.ErrInvalidChallenge) {
+		t.Fatalf("expected foreign legacy message to be rejected, got %v", err)
+	}
+	if _, err := authenticator.VerifyWalletControl(context.Background(), address, sig, "", SIWERequest{}); err != nil {
+		t.Fatalf("VerifyWalletControl returned error: %v", err)
+	}
+}
+
+func TestWeb3AuthenticatorSolana(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	address := account.EncodeBase58(pub)
+	const devnet = "EtWTRABZaYq6iMfeYKouRu166VU2xqa1"
+	accountID := "solana:" + devnet + ":" + address
+	authenticator := newJWTTestAuthenticator(t, newUserRepo(), t.TempDir())
+	authenticator.SetSIWEPolicy(NewSIWEPolicy(config.SIWEConfig{
+		Domain:       "drive.example.com",
+		ChainIDs:     []uint64{1},
+		SolanaChains: []string{devnet},
+		ChallengeTTL: time.Minute,
+		ClockSkew:    time.Minute,
+	}))
+	req := SIWERequest{Scheme: "https"}
+	ctx := context.Background()
+
+	if _, err := authenticator.CreateChallenge(address, req); !errors.Is(err, domainauth.ErrInvalidChallenge) {
+		t.Fatalf("expected mainnet account to be rejected, got %v", err)
+	}
+	challenge, err := authenticator.CreateChallenge("did:pkh:"+accountID, req)
+	if err != nil {
+		t.Fatalf("CreateChallenge returned error: %v", err)
+	}
+	if challenge.Address != accountID || challenge.ChainRef != devnet {
+		t.Fatalf("unexpected challenge binding: address=%s ref=%s", challenge.Address, challenge.ChainRef)
+	}
+	if !strings.Contains(challenge.Message, "sign in with your Solana account:\n"+address) {
+		t.Fatalf("challenge is not a SIWS message: %q", challenge.Message)
+	}
+	sig := ed25519.Sign(priv, []byte(challenge.Message))
+
+	if _, err := authenticator.VerifyWalletControl(ctx, accountID, account.EncodeBase58(ed25519.Sign(priv, []byte("other"))), "", req); !errors.Is(err, domainauth.ErrInvalidSignature) {
+		t.Fatalf("expected foreign signature to be rejected, got %v", err)
+	}
+	subject, err := authenticator.VerifyWalletControl(ctx, accountID, account.EncodeBase58(sig), "", req)
+	if err != nil {
+		t.Fatalf("VerifyWalletControl returned error: %v", err)
+	}
+	if subject != accountID {
+		t.Fatalf("unexpected wallet subject %q", subject)
+	}
+}
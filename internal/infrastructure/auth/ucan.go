@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/ed25519"
 	"encoding/base64"
 	"encoding/json"
@@ -11,6 +12,10 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/yeying-community/warehouse/internal/domain/account"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	infraCrypto "github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"go.uber.org/zap"
 )
 
@@ -56,6 +61,7 @@ type UcanVerifier struct {
 	enabled      bool
 	audience     string
 	requiredCaps []UcanCapability
+	signatures   *infraCrypto.SignatureVerifier
 	logger       *zap.Logger
 }
 
@@ -73,10 +79,17 @@ func NewUcanVerifier(enabled bool, audience string, requiredCaps []UcanCapabilit
 		enabled:      enabled,
 		audience:     strings.TrimSpace(audience),
 		requiredCaps: caps,
+		signatures:   infraCrypto.NewSignatureVerifier(config.SmartWalletConfig{}),
 		logger:       logger,
 	}
 }
 
+// SetSignatureVerifier sets the verifier used for root proof signatures,
+// enabling EIP-1271 / ERC-6492 smart contract wallets.
+func (v *UcanVerifier) SetSignatureVerifier(verifier *infraCrypto.SignatureVerifier) {
+	v.signatures = verifier
+}
+
 // Enabled returns true when UCAN verification is enabled.
 func (v *UcanVerifier) Enabled() bool {
 	if v == nil {
@@ -90,8 +103,9 @@ func (v *UcanVerifier) IsUcanToken(token string) bool {
 	return isUcanToken(token)
 }
 
-// VerifyInvocation verifies a UCAN invocation and returns the issuer address.
-func (v *UcanVerifier) VerifyInvocation(token string) (string, error) {
+// VerifyInvocation verifies a UCAN invocation and returns the issuer's
+// account key (see account.ID.Key).
+func (v *UcanVerifier) VerifyInvocation(ctx context.Context, token string) (string, error) {
 	if v == nil || !v.enabled {
 		return "", fmt.Errorf("UCAN verification disabled")
 	}
@@ -107,7 +121,7 @@ func (v *UcanVerifier) VerifyInvocation(token string) (string, error) {
 	}
 	if len(v.requiredCaps) > 0 && !capsAllow(payload.Cap, v.requiredCaps) {
 		if v.logger != nil {
-			v.logger.Warn("ucan capability denied",
+			logger.Ctx(ctx, v.logger).Warn("ucan capability denied",
 				zap.String("required_caps", formatCaps(v.requiredCaps)),
 				zap.String("provided_caps", formatCaps(payload.Cap)),
 				zap.String("audience", payload.Aud),
@@ -117,18 +131,35 @@ func (v *UcanVerifier) VerifyInvocation(token string) (string, error) {
 		return "", fmt.Errorf("UCAN capability denied")
 	}
 
-	iss, err := verifyProofChain(payload.Iss, payload.Cap, exp, payload.Prf)
+	iss, err := v.verifyProofChain(ctx, payload.Iss, payload.Cap, exp, payload.Prf)
 	if err != nil {
 		v.debug("ucan proof chain verification failed", zap.Error(err))
 		return "", err
 	}
 
-	const didPrefix = "did:pkh:eth:"
-	if !strings.HasPrefix(iss, didPrefix) {
-		return "", fmt.Errorf("UCAN issuer is not an ethereum DID")
+	id, err := parsePkhDID(iss)
+	if err != nil {
+		return "", fmt.Errorf("UCAN issuer is not a supported did:pkh: %w", err)
+	}
+	return id.Key(), nil
+}
+
+// parsePkhDID parses a did:pkh issuer (did:pkh:eth:<addr>, did:pkh:sol:<addr>
+// or did:pkh:<caip-10>).
+func parsePkhDID(did string) (account.ID, error) {
+	if !strings.HasPrefix(did, "did:pkh:") {
+		return account.ID{}, account.ErrInvalidAccount
+	}
+	return account.Parse(did)
+}
+
+// pkhDID returns the issuer DID for an account. EVM accounts keep the legacy
+// did:pkh:eth form so existing delegations continue to match.
+func pkhDID(id account.ID) string {
+	if id.IsEVM() {
+		return "did:pkh:eth:" + id.Address
 	}
-	address := strings.TrimPrefix(iss, didPrefix)
-	return strings.ToLower(address), nil
+	return id.DID()
 }
 
 // BuildRequiredUcanCaps builds a capability list from resource/action settings.
@@ -408,18 +439,65 @@ func recoverAddress(message string, signature string) (string, error) {
 	return strings.ToLower(crypto.PubkeyToAddress(*pubKey).Hex()), nil
 }
 
-func verifyRootProof(root ucanRootProof) (ucanStatement, string, error) {
+// rootSigner resolves the root proof signer. When the issuer (or the SIWE
+// message) names an account, the signature is checked against it so that
+// smart contract wallets can be verified on-chain and Solana wallets via
+// ed25519; otherwise the EOA signer is recovered from the signature.
+func (v *UcanVerifier) rootSigner(ctx context.Context, root ucanRootProof) (account.ID, error) {
+	var claimed *account.ID
+	if root.Iss != "" {
+		id, err := parsePkhDID(root.Iss)
+		if err != nil {
+			return account.ID{}, fmt.Errorf("unsupported root issuer: %w", err)
+		}
+		claimed = &id
+	}
+	var chainID uint64
+	if m, err := ParseSIWEMessage(root.Siwe.Message); err == nil {
+		chainID = m.ChainID
+		if claimed == nil {
+			id, err := account.Parse(m.namespace() + ":" + m.chain() + ":" + m.Address)
+			if err != nil {
+				return account.ID{}, err
+			}
+			claimed = &id
+		}
+	}
+
+	if claimed != nil && !claimed.IsEVM() {
+		if _, err := infraCrypto.VerifySolanaSignature(root.Siwe.Message, root.Siwe.Signature, claimed.Address); err != nil {
+			return account.ID{}, err
+		}
+		return *claimed, nil
+	}
+	if claimed == nil || v.signatures == nil {
+		signer, err := recoverAddress(root.Siwe.Message, root.Siwe.Signature)
+		if err != nil {
+			return account.ID{}, err
+		}
+		return account.Parse(signer)
+	}
+	if _, err := v.signatures.Verify(ctx, chainID, root.Siwe.Message, root.Siwe.Signature, claimed.Address); err != nil {
+		return account.ID{}, err
+	}
+	return *claimed, nil
+}
+
+func (v *UcanVerifier) verifyRootProof(ctx context.Context, root ucanRootProof) (ucanStatement, string, error) {
 	if root.Type != "siwe" || root.Siwe.Message == "" || root.Siwe.Signature == "" {
 		return ucanStatement{}, "", fmt.Errorf("invalid root proof")
 	}
 
-	recovered, err := recoverAddress(root.Siwe.Message, root.Siwe.Signature)
+	signer, err := v.rootSigner(ctx, root)
 	if err != nil {
 		return ucanStatement{}, "", err
 	}
-	iss := "did:pkh:eth:" + recovered
-	if root.Iss != "" && root.Iss != iss {
-		return ucanStatement{}, "", fmt.Errorf("root issuer mismatch")
+	iss := pkhDID(signer)
+	if root.Iss != "" {
+		claimed, err := parsePkhDID(root.Iss)
+		if err != nil || claimed.Key() != signer.Key() {
+			return ucanStatement{}, "", fmt.Errorf("root issuer mismatch")
+		}
 	}
 
 	statement, err := extractUcanStatement(root.Siwe.Message)
@@ -527,7 +605,7 @@ func verifyUcanJws(token string) (ucanPayload, int64, error) {
 	return payload, exp, nil
 }
 
-func verifyProofChain(currentDid string, required []UcanCapability, requiredExp int64, proofs []json.RawMessage) (string, error) {
+func (v *UcanVerifier) verifyProofChain(ctx context.Context, currentDid string, required []UcanCapability, requiredExp int64, proofs []json.RawMessage) (string, error) {
 	if len(proofs) == 0 {
 		return "", fmt.Errorf("missing UCAN proof chain")
 	}
@@ -554,14 +632,14 @@ func verifyProofChain(currentDid string, required []UcanCapability, requiredExp
 		if len(nextProofs) == 0 && len(proofs) > 1 {
 			nextProofs = proofs[1:]
 		}
-		return verifyProofChain(payload.Iss, payload.Cap, proofExp, nextProofs)
+		return v.verifyProofChain(ctx, payload.Iss, payload.Cap, proofExp, nextProofs)
 	}
 
 	var root ucanRootProof
 	if err := json.Unmarshal(first, &root); err != nil {
 		return "", err
 	}
-	statement, iss, err := verifyRootProof(root)
+	statement, iss, err := v.verifyRootProof(ctx, root)
 	if err != nil {
 		return "", err
 	}
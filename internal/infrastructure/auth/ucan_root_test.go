@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/yeying-community/warehouse/internal/domain/account"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"go.uber.org/zap"
+)
+
+const testSafeWallet = "0x5afe5afe5afe5afe5afe5afe5afe5afe5afe5afe"
+
+// safeWalletRPC 本地 JSON-RPC 节点：testSafeWallet 已部署，isValidSignature 只接受 "safe-approved"
+func safeWalletRPC(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     uint64            `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	result := "0x"
+	switch req.Method {
+	case "eth_getCode":
+		if strings.Contains(string(req.Params[0]), strings.TrimPrefix(testSafeWallet, "0x")) {
+			result = "0x6001"
+		}
+	case "eth_call":
+		result = "0x" + strings.Repeat("0", 64)
+		if strings.Contains(string(req.Params[0]), hex.EncodeToString([]byte("safe-approved"))) {
+			result = "0x1626ba7e" + strings.Repeat("0", 56)
+		}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+}
+
+func newRootProof(iss, signature string) ucanRootProof {
+	exp := time.Now().Add(time.Hour).UnixMilli()
+	var root ucanRootProof
+	root.Type = "siwe"
+	root.Iss = iss
+	root.Siwe.Message = fmt.Sprintf(`UCAN-AUTH: {"aud":"did:key:zAgent","cap":[{"resource":"app:*","action":"read"}],"exp":%d}`, exp)
+	root.Siwe.Signature = signature
+	return root
+}
+
+func TestUcanRootProofSmartWallet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(safeWalletRPC))
+	defer server.Close()
+	ctx := context.Background()
+
+	v := NewUcanVerifier(true, "did:key:zAgent", nil, zap.NewNop())
+	v.SetSignatureVerifier(crypto.NewSignatureVerifier(config.SmartWalletConfig{
+		Enabled: true,
+		Chains:  []config.ChainConfig{{ChainID: 1, RPCURL: server.URL}},
+	}))
+
+	approved := "0x" + hex.EncodeToString([]byte("safe-approved"))
+	_, iss, err := v.verifyRootProof(ctx, newRootProof("did:pkh:eth:"+testSafeWallet, approved))
+	if err != nil {
+		t.Fatalf("contract wallet root proof returned error: %v", err)
+	}
+	if iss != "did:pkh:eth:"+testSafeWallet {
+		t.Fatalf("unexpected issuer: %s", iss)
+	}
+	if _, _, err := v.verifyRootProof(ctx, newRootProof("did:pkh:eth:"+testSafeWallet, "0xdeadbeef")); err == nil {
+		t.Fatalf("expected rejected contract signature")
+	}
+
+	key, err := gethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	eoa := strings.ToLower(gethcrypto.PubkeyToAddress(key.PublicKey).Hex())
+	root := newRootProof("", "")
+	root.Siwe.Signature = signPersonal(t, key, root.Siwe.Message)
+	if _, iss, err := v.verifyRootProof(ctx, root); err != nil || iss != "did:pkh:eth:"+eoa {
+		t.Fatalf("EOA root proof: iss=%s err=%v", iss, err)
+	}
+
+	eoaOnly := NewUcanVerifier(true, "did:key:zAgent", nil, zap.NewNop())
+	if _, _, err := eoaOnly.verifyRootProof(ctx, newRootProof("did:pkh:eth:"+testSafeWallet, approved)); err == nil {
+		t.Fatalf("expected contract wallet root proof to fail without rpc")
+	}
+}
+
+func TestUcanRootProofSolana(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	ctx := context.Background()
+	v := NewUcanVerifier(true, "did:key:zAgent", nil, zap.NewNop())
+	address := account.EncodeBase58(pub)
+
+	root := newRootProof("did:pkh:sol:"+address, "")
+	root.Siwe.Signature = account.EncodeBase58(ed25519.Sign(priv, []byte(root.Siwe.Message)))
+	_, iss, err := v.verifyRootProof(ctx, root)
+	if err != nil {
+		t.Fatalf("solana root proof returned error: %v", err)
+	}
+	want := "did:pkh:solana:" + account.SolanaMainnet + ":" + address
+	if iss != want {
+		t.Fatalf("unexpected issuer: %s", iss)
+	}
+
+	forged := newRootProof(want, account.EncodeBase58(ed25519.Sign(priv, []byte("other"))))
+	if _, _, err := v.verifyRootProof(ctx, forged); err == nil {
+		t.Fatalf("expected forged solana root proof to be rejected")
+	}
+}
@@ -9,9 +9,12 @@ import (
 	"time"
 
 	"github.com/yeying-community/warehouse/internal/application/assetspace"
+	"github.com/yeying-community/warehouse/internal/domain/account"
 	"github.com/yeying-community/warehouse/internal/domain/auth"
 	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
 	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
@@ -22,11 +25,20 @@ type Web3Authenticator struct {
 	jwtManager        *JWTManager
 	ucanVerifier      *UcanVerifier
 	challengeStore    *ChallengeStore
+	siwe              *SIWEPolicy
 	ethSigner         *crypto.EthereumSigner
+	signatures        *crypto.SignatureVerifier
 	assetSpaceManager *assetspace.Manager
 	logger            *zap.Logger
 	refreshExpiration time.Duration
 	autoCreateOnUCAN  bool
+	publicKeys        PublicKeyRecorder
+	sessions          SessionStore
+}
+
+// PublicKeyRecorder 记录登录签名恢复出的钱包公钥
+type PublicKeyRecorder interface {
+	RecordPublicKey(ctx context.Context, wallet string, publicKey []byte) error
 }
 
 // NewWeb3Authenticator 创建 Web3 认证器
@@ -45,7 +57,9 @@ func NewWeb3Authenticator(
 		jwtManager:        NewJWTManager(jwtSecret, tokenExpiration),
 		ucanVerifier:      ucanVerifier,
 		challengeStore:    NewChallengeStore(),
+		siwe:              NewSIWEPolicy(config.SIWEConfig{}),
 		ethSigner:         crypto.NewEthereumSigner(),
+		signatures:        crypto.NewSignatureVerifier(config.SmartWalletConfig{}),
 		assetSpaceManager: assetSpaceManager,
 		logger:            logger,
 		refreshExpiration: refreshTokenExpiration,
@@ -53,6 +67,26 @@ func NewWeb3Authenticator(
 	}
 }
 
+// SetJWTKeys 设置非对称 JWT 签名密钥
+func (a *Web3Authenticator) SetJWTKeys(keys *JWTKeySet, acceptHS256 bool) {
+	a.jwtManager.SetKeys(keys, acceptHS256)
+}
+
+// SetPublicKeyRecorder 设置钱包公钥记录器，签名验证成功后保存恢复出的公钥
+func (a *Web3Authenticator) SetPublicKeyRecorder(recorder PublicKeyRecorder) {
+	a.publicKeys = recorder
+}
+
+// SetSIWEPolicy 设置 EIP-4361 挑战签发与校验策略
+func (a *Web3Authenticator) SetSIWEPolicy(policy *SIWEPolicy) {
+	a.siwe = policy
+}
+
+// SetSignatureVerifier 设置签名验证器（支持 EIP-1271 / ERC-6492 智能合约钱包）
+func (a *Web3Authenticator) SetSignatureVerifier(verifier *crypto.SignatureVerifier) {
+	a.signatures = verifier
+}
+
 // Name 认证器名称
 func (a *Web3Authenticator) Name() string {
 	return "web3"
@@ -66,16 +100,29 @@ func (a *Web3Authenticator) Authenticate(ctx context.Context, credentials interf
 	}
 
 	// 验证 Token (UCAN 或 JWT)
-	subject, subjectType, err := a.verifyTokenSubject(creds.Token)
+	subject, subjectType, err := a.verifyTokenSubject(ctx, creds.Token)
 	if err != nil {
 		return nil, err
 	}
 
+	if subjectType == "passkey" {
+		u, err := a.userRepo.FindByID(ctx, subject)
+		if err != nil {
+			return nil, err
+		}
+		if err := a.ensureUserAssetSpaces(u); err != nil {
+			return nil, err
+		}
+		logger.Ctx(ctx, a.logger).Debug("user authenticated via passkey token",
+			zap.String("username", u.Username))
+		return u, nil
+	}
+
 	if subjectType == "email" {
 		u, err := a.userRepo.FindByEmail(ctx, subject)
 		if err != nil {
 			if err == user.ErrUserNotFound {
-				a.logger.Debug("email not found", zap.String("email", subject))
+				logger.Ctx(ctx, a.logger).Debug("email not found", zap.String("email", subject))
 				return nil, err
 			}
 			return nil, err
@@ -83,7 +130,7 @@ func (a *Web3Authenticator) Authenticate(ctx context.Context, credentials interf
 		if err := a.ensureUserAssetSpaces(u); err != nil {
 			return nil, err
 		}
-		a.logger.Debug("user authenticated via email token",
+		logger.Ctx(ctx, a.logger).Debug("user authenticated via email token",
 			zap.String("username", u.Username),
 			zap.String("email", subject))
 		return u, nil
@@ -93,14 +140,14 @@ func (a *Web3Authenticator) Authenticate(ctx context.Context, credentials interf
 	u, err := a.EnsureUserByWallet(ctx, subject, isUcan && a.autoCreateOnUCAN)
 	if err != nil {
 		if err == user.ErrUserNotFound {
-			a.logger.Debug("wallet address not found",
+			logger.Ctx(ctx, a.logger).Debug("wallet address not found",
 				zap.String("address", subject))
 			return nil, err
 		}
 		return nil, err
 	}
 
-	a.logger.Debug("user authenticated via web3",
+	logger.Ctx(ctx, a.logger).Debug("user authenticated via web3",
 		zap.String("username", u.Username),
 		zap.String("address", subject))
 
@@ -132,9 +179,9 @@ func (a *Web3Authenticator) EnsureUserByWallet(ctx context.Context, address stri
 }
 
 func (a *Web3Authenticator) createUserFromWallet(ctx context.Context, address string) (*user.User, error) {
-	normalizedAddress := strings.ToLower(strings.TrimSpace(address))
-	if normalizedAddress == "" {
-		return nil, fmt.Errorf("invalid wallet address")
+	normalizedAddress, err := account.Normalize(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet address: %w", err)
 	}
 
 	for attempt := 0; attempt < 5; attempt++ {
@@ -153,7 +200,7 @@ func (a *Web3Authenticator) createUserFromWallet(ctx context.Context, address st
 			return nil, fmt.Errorf("failed to create user: %w", err)
 		}
 
-		a.logger.Info("user created via ucan",
+		logger.Ctx(ctx, a.logger).Info("user created via ucan",
 			zap.String("username", u.Username),
 			zap.String("address", normalizedAddress))
 
@@ -190,20 +237,26 @@ func (a *Web3Authenticator) ensureUserAssetSpaces(u *user.User) error {
 	return nil
 }
 
-// EnrichContext attaches UCAN scope info to the request context.
+// EnrichContext attaches UCAN scope info, or the session of a JWT, to the request context.
 func (a *Web3Authenticator) EnrichContext(ctx context.Context, credentials interface{}) context.Context {
 	creds, ok := credentials.(*auth.BearerCredentials)
 	if !ok || ctx == nil {
 		return ctx
 	}
 	token := strings.TrimSpace(creds.Token)
-	if token == "" || !isUcanToken(token) {
+	if token == "" {
+		return ctx
+	}
+	if !isUcanToken(token) {
+		if claims, err := a.jwtManager.VerifyClaims(token); err == nil && claims.SessionID != "" {
+			return middleware.WithSessionID(ctx, claims.SessionID)
+		}
 		return ctx
 	}
 
 	caps, err := parseUcanCaps(token)
 	if err != nil {
-		a.logger.Debug("failed to parse ucan caps", zap.Error(err))
+		logger.Ctx(ctx, a.logger).Debug("failed to parse ucan caps", zap.Error(err))
 		return middleware.WithUcanContext(ctx, &middleware.UcanContext{
 			AppCaps:        map[string][]string{},
 			HasAppCaps:     false,
@@ -213,7 +266,7 @@ func (a *Web3Authenticator) EnrichContext(ctx context.Context, credentials inter
 
 	extracted := extractAppCapsFromCaps(caps, "app:")
 	if len(extracted.InvalidAppCaps) > 0 {
-		a.logger.Warn("ucan invalid app capability detected",
+		logger.Ctx(ctx, a.logger).Warn("ucan invalid app capability detected",
 			zap.String("invalid_app_caps", strings.Join(extracted.InvalidAppCaps, ", ")),
 			zap.String("hint", "use resource `app:<appId>` without wildcard, e.g. app:dapp.example.com"),
 		)
@@ -225,7 +278,7 @@ func (a *Web3Authenticator) EnrichContext(ctx context.Context, credentials inter
 	})
 }
 
-func (a *Web3Authenticator) verifyTokenSubject(token string) (string, string, error) {
+func (a *Web3Authenticator) verifyTokenSubject(ctx context.Context, token string) (string, string, error) {
 	if token == "" {
 		return "", "", auth.ErrInvalidToken
 	}
@@ -234,9 +287,9 @@ func (a *Web3Authenticator) verifyTokenSubject(token string) (string, string, er
 		if a.ucanVerifier == nil || !a.ucanVerifier.Enabled() {
 			return "", "", auth.ErrInvalidToken
 		}
-		address, err := a.ucanVerifier.VerifyInvocation(token)
+		address, err := a.ucanVerifier.VerifyInvocation(ctx, token)
 		if err != nil {
-			a.logger.Debug("ucan verification failed", zap.Error(err))
+			logger.Ctx(ctx, a.logger).Debug("ucan verification failed", zap.Error(err))
 			return "", "", err
 		}
 		return address, "wallet", nil
@@ -244,28 +297,17 @@ func (a *Web3Authenticator) verifyTokenSubject(token string) (string, string, er
 
 	claims, err := a.jwtManager.VerifyClaims(token)
 	if err != nil {
-		a.logger.Debug("jwt verification failed", zap.Error(err))
+		logger.Ctx(ctx, a.logger).Debug("jwt verification failed", zap.Error(err))
 		return "", "", err
 	}
 
-	email := strings.TrimSpace(claims.Email)
-	if claims.SubjectType == "email" && email != "" {
-		return strings.ToLower(email), "email", nil
-	}
-
-	if claims.Address != "" {
-		return strings.ToLower(claims.Address), "wallet", nil
-	}
-
-	if email != "" {
-		return strings.ToLower(email), "email", nil
-	}
-
-	if claims.Subject != "" {
-		return strings.ToLower(claims.Subject), "wallet", nil
+	// 会话已吊销（退出登录、被踢下线或 refresh token 泄露）时 access token 立即失效
+	if err := a.checkSession(ctx, claims); err != nil {
+		logger.Ctx(ctx, a.logger).Debug("jwt session rejected", zap.String("session_id", claims.SessionID), zap.Error(err))
+		return "", "", err
 	}
 
-	return "", "", auth.ErrInvalidToken
+	return claimsSubject(claims)
 }
 
 // CanHandle 是否可以处理该凭证
@@ -274,82 +316,126 @@ func (a *Web3Authenticator) CanHandle(credentials interface{}) bool {
 	return ok
 }
 
+// normalizeWalletSubject 令牌中的钱包主体转换为存储键（Solana 地址区分大小写）
+func normalizeWalletSubject(subject string) string {
+	if key, err := account.Normalize(subject); err == nil {
+		return key
+	}
+	return strings.ToLower(strings.TrimSpace(subject))
+}
+
 // CreateChallenge 创建挑战
-func (a *Web3Authenticator) CreateChallenge(address string) (*auth.Challenge, error) {
+// address 支持以太坊地址、CAIP-10 账户与 did:pkh。默认签发 EIP-4361 消息，
+// domain / URI / 链 ID 按配置与请求上下文确定；Solana 账户始终使用 SIWS 消息。
+func (a *Web3Authenticator) CreateChallenge(address string, req SIWERequest) (*auth.Challenge, error) {
 	// 验证地址格式
-	if !a.ethSigner.IsValidAddress(address) {
-		return nil, fmt.Errorf("invalid ethereum address")
+	id, err := account.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wallet address: %w", err)
+	}
+	address = id.Key()
+
+	var message *SIWEMessage
+	if !a.siwe.Legacy() || !id.IsEVM() {
+		m, err := a.siwe.NewMessage(id, req)
+		if err != nil {
+			return nil, err
+		}
+		message = m
 	}
 
-	// 创建挑战（5分钟有效期）
-	challenge, err := a.challengeStore.Create(address, 5*time.Minute)
+	challenge, err := a.challengeStore.Create(address, a.siwe.TTL(), message)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create challenge: %w", err)
 	}
 
 	a.logger.Debug("challenge created",
 		zap.String("address", address),
+		zap.String("domain", challenge.Domain),
+		zap.Uint64("chain_id", challenge.ChainID),
 		zap.String("nonce", challenge.Nonce))
 
 	return challenge, nil
 }
 
-// VerifySignature 验证签名并生成 token
-func (a *Web3Authenticator) VerifySignature(ctx context.Context, address, signature string) (*auth.Token, error) {
+// VerifyWalletControl 校验钱包对服务端挑战的签名并作废挑战，返回账户存储键
+// 登录以及关联、解绑、合并账户时用于证明对钱包的控制权。
+func (a *Web3Authenticator) VerifyWalletControl(ctx context.Context, address, signature, message string, req SIWERequest) (string, error) {
 	// 验证地址格式
-	if !a.ethSigner.IsValidAddress(address) {
-		return nil, fmt.Errorf("invalid ethereum address")
+	id, err := account.Parse(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid wallet address: %w", err)
 	}
+	address = id.Key()
 
 	// 获取挑战
 	challenge, ok := a.challengeStore.Get(address)
 	if !ok {
-		a.logger.Warn("challenge not found or expired",
+		logger.Ctx(ctx, a.logger).Warn("challenge not found or expired",
 			zap.String("address", address))
-		return nil, auth.ErrChallengeExpired
+		return "", auth.ErrChallengeExpired
 	}
 
-	// 验证签名
-	if err := a.ethSigner.VerifySignature(challenge.Message, signature, address); err != nil {
-		a.logger.Warn("signature verification failed",
-			zap.String("address", address),
-			zap.Error(err))
-		return nil, auth.ErrInvalidSignature
+	signed := challenge.Message
+	if challenge.Domain == "" {
+		// 旧版挑战只接受服务端签发的原文
+		if message != "" && message != challenge.Message {
+			return "", auth.ErrInvalidChallenge
+		}
+	} else {
+		if message != "" {
+			signed = message
+		}
+		parsed, err := ParseSIWEMessage(signed)
+		if err != nil {
+			logger.Ctx(ctx, a.logger).Warn("invalid siwe message",
+				zap.String("address", address),
+				zap.Error(err))
+			return "", fmt.Errorf("%w: %v", auth.ErrInvalidChallenge, err)
+		}
+		if err := a.siwe.Verify(parsed, challenge, id, req, time.Now()); err != nil {
+			logger.Ctx(ctx, a.logger).Warn("siwe message rejected",
+				zap.String("address", address),
+				zap.Error(err))
+			return "", err
+		}
 	}
 
-	// 删除已使用的挑战
-	a.challengeStore.Delete(address)
-
-	// 生成 JWT
-	token, err := a.jwtManager.Generate(address)
+	// 验证签名（EOA 返回恢复出的公钥，合约钱包返回 nil）
+	var publicKey []byte
+	if id.IsEVM() {
+		publicKey, err = a.signatures.Verify(ctx, challenge.ChainID, signed, signature, id.Address)
+	} else {
+		// ed25519 公钥无法用于端到端加密的密钥信封，不做记录
+		_, err = crypto.VerifySolanaSignature(signed, signature, id.Address)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		logger.Ctx(ctx, a.logger).Warn("signature verification failed",
+			zap.String("address", address),
+			zap.Error(err))
+		if errors.Is(err, crypto.ErrSmartWalletUnavailable) {
+			return "", err
+		}
+		return "", auth.ErrInvalidSignature
 	}
 
-	a.logger.Info("signature verified, token generated",
-		zap.String("address", address))
-
-	return token, nil
-}
-
-// GenerateAccessToken 生成访问令牌
-func (a *Web3Authenticator) GenerateAccessToken(address string) (*auth.Token, error) {
-	return a.jwtManager.Generate(address)
-}
-
-// GenerateAccessTokenForEmail 生成邮箱登录访问令牌
-func (a *Web3Authenticator) GenerateAccessTokenForEmail(email string) (*auth.Token, error) {
-	return a.jwtManager.GenerateForEmail(email)
-}
+	// 作废已使用的挑战（并发请求只有一个能成功）
+	if !a.challengeStore.Consume(address, challenge.Nonce) {
+		logger.Ctx(ctx, a.logger).Warn("challenge already used",
+			zap.String("address", address))
+		return "", auth.ErrChallengeExpired
+	}
 
-// GenerateRefreshToken 生成刷新令牌
-func (a *Web3Authenticator) GenerateRefreshToken(address string) (*auth.Token, error) {
-	return a.jwtManager.GenerateRefresh(address, a.refreshExpiration)
-}
+	// 保存钱包公钥，供端到端加密目录生成密钥信封
+	if a.publicKeys != nil && publicKey != nil {
+		if err := a.publicKeys.RecordPublicKey(ctx, address, publicKey); err != nil {
+			logger.Ctx(ctx, a.logger).Warn("failed to record wallet public key",
+				zap.String("address", address),
+				zap.Error(err))
+		}
+	}
 
-// GenerateRefreshTokenForEmail 生成邮箱登录刷新令牌
-func (a *Web3Authenticator) GenerateRefreshTokenForEmail(email string) (*auth.Token, error) {
-	return a.jwtManager.GenerateRefreshForEmail(email, a.refreshExpiration)
+	return address, nil
 }
 
 // VerifyRefreshToken 验证刷新令牌
@@ -364,19 +450,29 @@ func (a *Web3Authenticator) VerifyRefreshTokenWithSubject(token string) (string,
 	if err != nil {
 		return "", "", err
 	}
+	return claimsSubject(claims)
+}
 
+// claimsSubject 从 JWT 声明中取出主体（钱包地址、邮箱或用户 ID）及其类型
+func claimsSubject(claims *Claims) (string, string, error) {
+	if claims.SubjectType == "passkey" {
+		if claims.Subject == "" {
+			return "", "", auth.ErrInvalidToken
+		}
+		return claims.Subject, "passkey", nil
+	}
 	email := strings.TrimSpace(claims.Email)
 	if claims.SubjectType == "email" && email != "" {
 		return strings.ToLower(email), "email", nil
 	}
 	if claims.Address != "" {
-		return strings.ToLower(claims.Address), "wallet", nil
+		return normalizeWalletSubject(claims.Address), "wallet", nil
 	}
 	if email != "" {
 		return strings.ToLower(email), "email", nil
 	}
 	if claims.Subject != "" {
-		return strings.ToLower(claims.Subject), "wallet", nil
+		return normalizeWalletSubject(claims.Subject), "wallet", nil
 	}
 	return "", "", auth.ErrInvalidToken
 }
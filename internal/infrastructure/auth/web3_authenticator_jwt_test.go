@@ -9,14 +9,16 @@ import (
 
 	"github.com/yeying-community/warehouse/internal/application/assetspace"
 	domainauth "github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/session"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository/memory"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
 
 func TestWeb3AuthenticatorAuthenticateByJWTWallet(t *testing.T) {
-	repo := newStubUserRepo()
+	repo := newUserRepo()
 
 	u := user.NewUser("alice", "alice")
 	if err := u.SetWalletAddress("0x1111111111111111111111111111111111111111"); err != nil {
@@ -29,10 +31,11 @@ func TestWeb3AuthenticatorAuthenticateByJWTWallet(t *testing.T) {
 	tmpDir := t.TempDir()
 	authenticator := newJWTTestAuthenticator(t, repo, tmpDir)
 
-	token, err := authenticator.GenerateAccessToken(u.WalletAddress)
+	pair, err := authenticator.IssueTokens(context.Background(), u, u.WalletAddress, "wallet", session.MethodWallet, session.Client{})
 	if err != nil {
-		t.Fatalf("GenerateAccessToken failed: %v", err)
+		t.Fatalf("IssueTokens failed: %v", err)
 	}
+	token := pair.Access
 
 	got, err := authenticator.Authenticate(context.Background(), &domainauth.BearerCredentials{Token: token.Value})
 	if err != nil {
@@ -46,8 +49,42 @@ func TestWeb3AuthenticatorAuthenticateByJWTWallet(t *testing.T) {
 	assertDirExists(t, filepath.Join(tmpDir, "alice", "apps"))
 }
 
+func TestWeb3AuthenticatorRejectsMFAChallengeToken(t *testing.T) {
+	repo := newUserRepo()
+	u := user.NewUser("mallory", "mallory")
+	if err := u.SetWalletAddress("0x5555555555555555555555555555555555555555"); err != nil {
+		t.Fatalf("SetWalletAddress failed: %v", err)
+	}
+	if err := repo.Save(context.Background(), u); err != nil {
+		t.Fatalf("Save user failed: %v", err)
+	}
+	authenticator := newJWTTestAuthenticator(t, repo, t.TempDir())
+
+	challenge, err := authenticator.GetJWTManager().GenerateMFAChallenge(u.ID, "wallet", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateMFAChallenge failed: %v", err)
+	}
+	claims, err := authenticator.GetJWTManager().VerifyMFAChallenge(challenge.Value)
+	if err != nil || claims.Subject != u.ID {
+		t.Fatalf("VerifyMFAChallenge failed: %v", err)
+	}
+
+	// 挑战令牌只能用于完成两步验证，不能当作 access token
+	if _, err := authenticator.Authenticate(context.Background(), &domainauth.BearerCredentials{Token: challenge.Value}); err == nil {
+		t.Fatalf("mfa challenge token should not authenticate")
+	}
+
+	pair, err := authenticator.IssueTokens(context.Background(), u, u.WalletAddress, "wallet", session.MethodWallet, session.Client{})
+	if err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+	if _, err := authenticator.GetJWTManager().VerifyMFAChallenge(pair.Access.Value); err == nil {
+		t.Fatalf("access token should not pass as mfa challenge")
+	}
+}
+
 func TestWeb3AuthenticatorAuthenticateByJWTEmail(t *testing.T) {
-	repo := newStubUserRepo()
+	repo := newUserRepo()
 
 	u := user.NewUser("bob", "bob")
 	if err := u.SetEmail("bob@example.com"); err != nil {
@@ -60,10 +97,11 @@ func TestWeb3AuthenticatorAuthenticateByJWTEmail(t *testing.T) {
 	tmpDir := t.TempDir()
 	authenticator := newJWTTestAuthenticator(t, repo, tmpDir)
 
-	token, err := authenticator.GenerateAccessTokenForEmail("bob@example.com")
+	pair, err := authenticator.IssueTokens(context.Background(), u, u.Email, "email", session.MethodEmail, session.Client{})
 	if err != nil {
-		t.Fatalf("GenerateAccessTokenForEmail failed: %v", err)
+		t.Fatalf("IssueTokens failed: %v", err)
 	}
+	token := pair.Access
 
 	got, err := authenticator.Authenticate(context.Background(), &domainauth.BearerCredentials{Token: token.Value})
 	if err != nil {
@@ -75,7 +113,7 @@ func TestWeb3AuthenticatorAuthenticateByJWTEmail(t *testing.T) {
 }
 
 func TestWeb3AuthenticatorEnrichContext_JWTShouldNotInjectUcanContext(t *testing.T) {
-	repo := newStubUserRepo()
+	repo := newUserRepo()
 	u := user.NewUser("charlie", "charlie")
 	if err := u.SetWalletAddress("0x2222222222222222222222222222222222222222"); err != nil {
 		t.Fatalf("SetWalletAddress failed: %v", err)
@@ -85,10 +123,11 @@ func TestWeb3AuthenticatorEnrichContext_JWTShouldNotInjectUcanContext(t *testing
 	}
 
 	authenticator := newJWTTestAuthenticator(t, repo, t.TempDir())
-	token, err := authenticator.GenerateAccessToken(u.WalletAddress)
+	pair, err := authenticator.IssueTokens(context.Background(), u, u.WalletAddress, "wallet", session.MethodWallet, session.Client{})
 	if err != nil {
-		t.Fatalf("GenerateAccessToken failed: %v", err)
+		t.Fatalf("IssueTokens failed: %v", err)
 	}
+	token := pair.Access
 
 	ctx := authenticator.EnrichContext(context.Background(), &domainauth.BearerCredentials{Token: token.Value})
 	if _, ok := middleware.GetUcanContext(ctx); ok {
@@ -123,136 +162,9 @@ func newJWTTestAuthenticator(t *testing.T, repo user.Repository, webdavRoot stri
 	)
 }
 
-type stubUserRepo struct {
-	byID       map[string]*user.User
-	byUsername map[string]*user.User
-	byWallet   map[string]*user.User
-	byEmail    map[string]*user.User
-}
-
-func newStubUserRepo() *stubUserRepo {
-	return &stubUserRepo{
-		byID:       make(map[string]*user.User),
-		byUsername: make(map[string]*user.User),
-		byWallet:   make(map[string]*user.User),
-		byEmail:    make(map[string]*user.User),
-	}
-}
-
-func (r *stubUserRepo) FindByUsername(ctx context.Context, username string) (*user.User, error) {
-	if u, ok := r.byUsername[username]; ok {
-		return cloneUser(u), nil
-	}
-	return nil, user.ErrUserNotFound
-}
-
-func (r *stubUserRepo) FindByWalletAddress(ctx context.Context, address string) (*user.User, error) {
-	if u, ok := r.byWallet[address]; ok {
-		return cloneUser(u), nil
-	}
-	return nil, user.ErrUserNotFound
-}
-
-func (r *stubUserRepo) FindByEmail(ctx context.Context, email string) (*user.User, error) {
-	if u, ok := r.byEmail[email]; ok {
-		return cloneUser(u), nil
-	}
-	return nil, user.ErrUserNotFound
-}
-
-func (r *stubUserRepo) FindByID(ctx context.Context, id string) (*user.User, error) {
-	if u, ok := r.byID[id]; ok {
-		return cloneUser(u), nil
-	}
-	return nil, user.ErrUserNotFound
-}
-
-func (r *stubUserRepo) Save(ctx context.Context, u *user.User) error {
-	if u == nil {
-		return nil
-	}
-	copy := cloneUser(u)
-	r.byID[copy.ID] = copy
-	r.byUsername[copy.Username] = copy
-	if copy.WalletAddress != "" {
-		r.byWallet[copy.WalletAddress] = copy
-	}
-	if copy.Email != "" {
-		r.byEmail[copy.Email] = copy
-	}
-	return nil
-}
-
-func (r *stubUserRepo) Delete(ctx context.Context, username string) error {
-	u, ok := r.byUsername[username]
-	if !ok {
-		return user.ErrUserNotFound
-	}
-	delete(r.byUsername, username)
-	delete(r.byID, u.ID)
-	if u.WalletAddress != "" {
-		delete(r.byWallet, u.WalletAddress)
-	}
-	if u.Email != "" {
-		delete(r.byEmail, u.Email)
-	}
-	return nil
-}
-
-func (r *stubUserRepo) List(ctx context.Context) ([]*user.User, error) {
-	out := make([]*user.User, 0, len(r.byID))
-	for _, u := range r.byID {
-		out = append(out, cloneUser(u))
-	}
-	return out, nil
-}
-
-func (r *stubUserRepo) UpdateUsedSpace(ctx context.Context, username string, usedSpace int64) error {
-	u, ok := r.byUsername[username]
-	if !ok {
-		return user.ErrUserNotFound
-	}
-	if err := u.UpdateUsedSpace(usedSpace); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (r *stubUserRepo) UpdateQuota(ctx context.Context, username string, quota int64) error {
-	u, ok := r.byUsername[username]
-	if !ok {
-		return user.ErrUserNotFound
-	}
-	if err := u.SetQuota(quota); err != nil {
-		return err
-	}
-	return nil
-}
-
-func cloneUser(in *user.User) *user.User {
-	if in == nil {
-		return nil
-	}
-	out := *in
-	if in.Permissions != nil {
-		p := *in.Permissions
-		out.Permissions = &p
-	}
-	if len(in.Rules) > 0 {
-		out.Rules = make([]*user.Rule, len(in.Rules))
-		for i, rule := range in.Rules {
-			if rule == nil {
-				continue
-			}
-			rCopy := *rule
-			if rule.Permissions != nil {
-				p := *rule.Permissions
-				rCopy.Permissions = &p
-			}
-			out.Rules[i] = &rCopy
-		}
-	}
-	return &out
+// newUserRepo 创建测试用的内存用户仓储
+func newUserRepo() *memory.UserRepository {
+	return memory.NewUserRepository(memory.NewStore())
 }
 
 func assertDirExists(t *testing.T, dir string) {
@@ -0,0 +1,334 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/domain/webauthn"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"go.uber.org/zap"
+)
+
+// WebAuthnCredentialStore 通行密钥凭证存储
+type WebAuthnCredentialStore interface {
+	Create(ctx context.Context, cred *webauthn.Credential) error
+	GetByID(ctx context.Context, id string) (*webauthn.Credential, error)
+	ListByUser(ctx context.Context, userID string) ([]*webauthn.Credential, error)
+	UpdateUsage(ctx context.Context, id string, signCount uint32, backupState bool, at time.Time) error
+}
+
+const (
+	ceremonyRegister = "register"
+	ceremonyLogin    = "login"
+)
+
+// webauthnCeremony 一次注册或登录仪式的服务端状态
+type webauthnCeremony struct {
+	kind      string
+	challenge []byte
+	userID    string // 注册：通行密钥所属用户（新账户为预分配 ID）；登录：指定用户名时的用户
+	username  string // 新账户预分配的用户名
+	newUser   bool
+	expiresAt time.Time
+}
+
+// WebAuthnAuthenticator 通行密钥认证器
+// 注册与登录均为两步：先获取参数（挑战保存在内存中，以仪式 ID 关联），再提交浏览器返回的结果。
+type WebAuthnAuthenticator struct {
+	userRepo   user.Repository
+	store      WebAuthnCredentialStore
+	rp         *webauthn.RelyingParty
+	autoCreate bool
+	logger     *zap.Logger
+
+	mu         sync.Mutex
+	ceremonies map[string]*webauthnCeremony
+}
+
+// NewWebAuthnAuthenticator 创建通行密钥认证器
+func NewWebAuthnAuthenticator(
+	userRepo user.Repository,
+	store WebAuthnCredentialStore,
+	cfg config.WebAuthnConfig,
+	logger *zap.Logger,
+) *WebAuthnAuthenticator {
+	ttl := cfg.ChallengeTTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &WebAuthnAuthenticator{
+		userRepo: userRepo,
+		store:    store,
+		rp: &webauthn.RelyingParty{
+			ID:               cfg.RPID,
+			Name:             cfg.RPName,
+			Origins:          cfg.Origins,
+			UserVerification: cfg.UserVerification,
+			Timeout:          ttl,
+		},
+		autoCreate: cfg.AutoCreateOnRegister,
+		logger:     logger,
+		ceremonies: make(map[string]*webauthnCeremony),
+	}
+}
+
+// Name 认证器名称
+func (a *WebAuthnAuthenticator) Name() string {
+	return "webauthn"
+}
+
+// CanHandle 是否可以处理该凭证
+func (a *WebAuthnAuthenticator) CanHandle(credentials interface{}) bool {
+	_, ok := credentials.(*auth.WebAuthnCredentials)
+	return ok
+}
+
+// AutoCreateEnabled 未登录时注册通行密钥是否会创建新账户
+func (a *WebAuthnAuthenticator) AutoCreateEnabled() bool {
+	return a.autoCreate
+}
+
+// BeginRegistration 生成注册参数
+// u 为空表示未登录用户注册通行密钥并创建新账户，需开启 auto_create_on_register。
+func (a *WebAuthnAuthenticator) BeginRegistration(ctx context.Context, u *user.User) (string, *webauthn.CreationOptions, error) {
+	ceremony := &webauthnCeremony{kind: ceremonyRegister}
+	var exclude []*webauthn.Credential
+	name, displayName := "", ""
+	if u != nil {
+		creds, err := a.store.ListByUser(ctx, u.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		exclude = creds
+		ceremony.userID = u.ID
+		name, displayName = u.Username, u.Username
+		if u.Email != "" {
+			name = u.Email
+		}
+	} else {
+		if !a.autoCreate {
+			return "", nil, webauthn.ErrSignUpDisabled
+		}
+		ceremony.userID = uuid.NewString()
+		ceremony.username = generateHumanReadableName()
+		ceremony.newUser = true
+		name, displayName = ceremony.username, ceremony.username
+	}
+
+	sessionID, challenge, err := a.startCeremony(ceremony)
+	if err != nil {
+		return "", nil, err
+	}
+	return sessionID, a.rp.CreationOptions(challenge, []byte(ceremony.userID), name, displayName, exclude), nil
+}
+
+// FinishRegistration 校验注册结果并保存凭证，新账户在此时创建
+// 返回凭证所属用户、保存的凭证以及是否新建了账户。
+func (a *WebAuthnAuthenticator) FinishRegistration(
+	ctx context.Context,
+	sessionID string,
+	clientDataJSON, attestationObject []byte,
+	transports []string,
+	name string,
+) (*user.User, *webauthn.Credential, bool, error) {
+	name, err := webauthn.NormalizeName(name)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	ceremony, ok := a.consumeCeremony(sessionID, ceremonyRegister)
+	if !ok {
+		return nil, nil, false, auth.ErrChallengeExpired
+	}
+	cred, err := a.rp.VerifyRegistration(ceremony.challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if _, err := a.store.GetByID(ctx, cred.ID); err == nil {
+		return nil, nil, false, webauthn.ErrCredentialExists
+	} else if !errors.Is(err, webauthn.ErrCredentialNotFound) {
+		return nil, nil, false, err
+	}
+
+	var u *user.User
+	if ceremony.newUser {
+		u, err = a.createPasskeyUser(ctx, ceremony.userID, ceremony.username)
+	} else {
+		u, err = a.userRepo.FindByID(ctx, ceremony.userID)
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	cred.UserID = u.ID
+	cred.Name = name
+	cred.Transports = filterTransports(transports)
+	if err := a.store.Create(ctx, cred); err != nil {
+		return nil, nil, false, err
+	}
+
+	a.logger.Info("passkey registered",
+		zap.String("username", u.Username),
+		zap.String("credential_id", cred.ID),
+		zap.Bool("new_user", ceremony.newUser))
+	return u, cred, ceremony.newUser, nil
+}
+
+// BeginLogin 生成登录参数
+// username 为空时使用可发现凭证（由认证器选择账户）；用户不存在时同样返回空白名单，避免泄露账户是否存在。
+func (a *WebAuthnAuthenticator) BeginLogin(ctx context.Context, username string) (string, *webauthn.RequestOptions, error) {
+	ceremony := &webauthnCeremony{kind: ceremonyLogin}
+	var allow []*webauthn.Credential
+	if username != "" {
+		u, err := a.userRepo.FindByUsername(ctx, username)
+		switch {
+		case err == nil:
+			creds, err := a.store.ListByUser(ctx, u.ID)
+			if err != nil {
+				return "", nil, err
+			}
+			if len(creds) > 0 {
+				allow = creds
+				ceremony.userID = u.ID
+			}
+		case !errors.Is(err, user.ErrUserNotFound):
+			return "", nil, err
+		}
+	}
+
+	sessionID, challenge, err := a.startCeremony(ceremony)
+	if err != nil {
+		return "", nil, err
+	}
+	return sessionID, a.rp.RequestOptions(challenge, allow), nil
+}
+
+// Authenticate 校验登录断言并返回用户
+func (a *WebAuthnAuthenticator) Authenticate(ctx context.Context, credentials interface{}) (*user.User, error) {
+	creds, ok := credentials.(*auth.WebAuthnCredentials)
+	if !ok {
+		return nil, fmt.Errorf("invalid credentials type")
+	}
+	ceremony, ok := a.consumeCeremony(creds.SessionID, ceremonyLogin)
+	if !ok {
+		return nil, auth.ErrChallengeExpired
+	}
+
+	cred, err := a.store.GetByID(ctx, creds.CredentialID)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrCredentialNotFound) {
+			return nil, auth.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	if ceremony.userID != "" && cred.UserID != ceremony.userID {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	assertion, err := a.rp.VerifyAssertion(ceremony.challenge, cred,
+		creds.ClientDataJSON, creds.AuthenticatorData, creds.Signature, creds.UserHandle)
+	if err == nil {
+		// 计数器在存储层再次比较并交换，拦截并发提交的相同断言
+		err = a.store.UpdateUsage(ctx, cred.ID, assertion.SignCount, assertion.BackupState, time.Now())
+	}
+	if err != nil {
+		if errors.Is(err, webauthn.ErrCloneDetected) {
+			logger.Ctx(ctx, a.logger).Warn("passkey signature counter did not increase",
+				zap.String("credential_id", cred.ID),
+				zap.String("user_id", cred.UserID))
+		}
+		return nil, err
+	}
+
+	u, err := a.userRepo.FindByID(ctx, cred.UserID)
+	if err != nil {
+		return nil, err
+	}
+	logger.Ctx(ctx, a.logger).Debug("user authenticated via passkey",
+		zap.String("username", u.Username),
+		zap.String("credential_id", cred.ID))
+	return u, nil
+}
+
+// createPasskeyUser 创建仅使用通行密钥登录的账户，ID 为注册时预分配的 user handle
+func (a *WebAuthnAuthenticator) createPasskeyUser(ctx context.Context, id, username string) (*user.User, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		if attempt > 0 {
+			username = generateHumanReadableName()
+		}
+		u := user.NewUser(username, username)
+		u.ID = id
+		u.Permissions = user.ParsePermissions("CRUD")
+		_ = u.SetQuota(1073741824)
+
+		if err := a.userRepo.Save(ctx, u); err != nil {
+			if errors.Is(err, user.ErrDuplicateUsername) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+
+		logger.Ctx(ctx, a.logger).Info("user created via passkey", zap.String("username", u.Username))
+		return u, nil
+	}
+	return nil, fmt.Errorf("failed to create user: duplicate username")
+}
+
+func (a *WebAuthnAuthenticator) startCeremony(ceremony *webauthnCeremony) (string, []byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", nil, err
+	}
+	ceremony.challenge = challenge
+	ceremony.expiresAt = time.Now().Add(a.rp.Timeout)
+	sessionID := uuid.NewString()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	for id, c := range a.ceremonies {
+		if now.After(c.expiresAt) {
+			delete(a.ceremonies, id)
+		}
+	}
+	a.ceremonies[sessionID] = ceremony
+	return sessionID, challenge, nil
+}
+
+// consumeCeremony 取出并删除仪式，保证每个挑战只能使用一次
+func (a *WebAuthnAuthenticator) consumeCeremony(sessionID, kind string) (*webauthnCeremony, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ceremony, ok := a.ceremonies[sessionID]
+	if !ok {
+		return nil, false
+	}
+	delete(a.ceremonies, sessionID)
+	if ceremony.kind != kind || time.Now().After(ceremony.expiresAt) {
+		return nil, false
+	}
+	return ceremony, true
+}
+
+var knownTransports = map[string]bool{
+	"usb": true, "nfc": true, "ble": true, "internal": true, "hybrid": true, "smart-card": true,
+}
+
+// filterTransports 只保留规范定义的传输方式，用于后续登录时提示浏览器
+func filterTransports(transports []string) []string {
+	out := make([]string, 0, len(transports))
+	seen := make(map[string]bool, len(transports))
+	for _, t := range transports {
+		if knownTransports[t] && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
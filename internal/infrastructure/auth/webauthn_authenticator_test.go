@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	domainauth "github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/webauthn"
+	"github.com/yeying-community/warehouse/internal/domain/webauthn/webauthntest"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"go.uber.org/zap"
+)
+
+func TestWebAuthnAuthenticatorSignUpAndLogin(t *testing.T) {
+	ctx := context.Background()
+	repo := newUserRepo()
+	store := newStubWebAuthnStore()
+	cfg := config.WebAuthnConfig{
+		RPID:                 "example.com",
+		RPName:               "Warehouse",
+		Origins:              []string{"https://example.com"},
+		UserVerification:     webauthn.UserVerificationPreferred,
+		ChallengeTTL:         time.Minute,
+		AutoCreateOnRegister: true,
+	}
+	passkeys := NewWebAuthnAuthenticator(repo, store, cfg, zap.NewNop())
+	device := webauthntest.New("example.com", "https://example.com")
+
+	// 未登录注册：创建新账户，user handle 即用户 ID
+	sessionID, options, err := passkeys.BeginRegistration(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginRegistration failed: %v", err)
+	}
+	challenge, _ := webauthn.DecodeID(options.Challenge)
+	userHandle, _ := webauthn.DecodeID(options.User.ID)
+	clientData, attestation := device.Create(challenge, userHandle)
+	u, cred, created, err := passkeys.FinishRegistration(ctx, sessionID, clientData, attestation, []string{"internal", "bogus"}, "")
+	if err != nil {
+		t.Fatalf("FinishRegistration failed: %v", err)
+	}
+	if !created || u.ID != string(userHandle) || cred.Name != "Passkey" || len(cred.Transports) != 1 {
+		t.Fatalf("unexpected registration result: created=%v user=%s cred=%+v", created, u.ID, cred)
+	}
+	if _, _, _, err := passkeys.FinishRegistration(ctx, sessionID, clientData, attestation, nil, ""); !errors.Is(err, domainauth.ErrChallengeExpired) {
+		t.Fatalf("registration ceremony should be single use, got %v", err)
+	}
+
+	// 使用可发现凭证登录
+	sessionID, request, err := passkeys.BeginLogin(ctx, "")
+	if err != nil {
+		t.Fatalf("BeginLogin failed: %v", err)
+	}
+	challenge, _ = webauthn.DecodeID(request.Challenge)
+	clientData, authData, sig := device.Get(challenge)
+	creds := &domainauth.WebAuthnCredentials{
+		SessionID:         sessionID,
+		CredentialID:      device.ID(),
+		ClientDataJSON:    clientData,
+		AuthenticatorData: authData,
+		Signature:         sig,
+		UserHandle:        device.UserHandle,
+	}
+	if !passkeys.CanHandle(creds) {
+		t.Fatalf("webauthn credentials should be handled")
+	}
+	got, err := passkeys.Authenticate(ctx, creds)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if got.ID != u.ID || store.creds[device.ID()].SignCount != 1 || store.creds[device.ID()].LastUsedAt == nil {
+		t.Fatalf("unexpected login result: %s %+v", got.ID, store.creds[device.ID()])
+	}
+	if _, err := passkeys.Authenticate(ctx, creds); !errors.Is(err, domainauth.ErrChallengeExpired) {
+		t.Fatalf("login ceremony should be single use, got %v", err)
+	}
+
+	// 通行密钥登录签发的令牌以用户 ID 为主体
+	web3 := newJWTTestAuthenticator(t, repo, t.TempDir())
+	pair, err := web3.IssueTokens(ctx, got, got.ID, "passkey", session.MethodPasskey, session.Client{})
+	if err != nil {
+		t.Fatalf("IssueTokens failed: %v", err)
+	}
+	authed, err := web3.Authenticate(ctx, &domainauth.BearerCredentials{Token: pair.Access.Value})
+	if err != nil || authed.ID != u.ID {
+		t.Fatalf("passkey access token should authenticate: %v", err)
+	}
+	subject, subjectType, err := web3.VerifyRefreshTokenWithSubject(pair.Refresh.Value)
+	if err != nil || subject != u.ID || subjectType != "passkey" {
+		t.Fatalf("unexpected refresh subject: %s %s %v", subject, subjectType, err)
+	}
+}
+
+func TestWebAuthnAuthenticatorSignUpDisabled(t *testing.T) {
+	passkeys := NewWebAuthnAuthenticator(newUserRepo(), newStubWebAuthnStore(), config.WebAuthnConfig{
+		RPID:    "example.com",
+		Origins: []string{"https://example.com"},
+	}, zap.NewNop())
+	if _, _, err := passkeys.BeginRegistration(context.Background(), nil); !errors.Is(err, webauthn.ErrSignUpDisabled) {
+		t.Fatalf("expected sign-up disabled, got %v", err)
+	}
+}
+
+type stubWebAuthnStore struct {
+	creds map[string]*webauthn.Credential
+}
+
+func newStubWebAuthnStore() *stubWebAuthnStore {
+	return &stubWebAuthnStore{creds: make(map[string]*webauthn.Credential)}
+}
+
+func (s *stubWebAuthnStore) Create(ctx context.Context, cred *webauthn.Credential) error {
+	if _, ok := s.creds[cred.ID]; ok {
+		return webauthn.ErrCredentialExists
+	}
+	copy := *cred
+	s.creds[cred.ID] = &copy
+	return nil
+}
+
+func (s *stubWebAuthnStore) GetByID(ctx context.Context, id string) (*webauthn.Credential, error) {
+	cred, ok := s.creds[id]
+	if !ok {
+		return nil, webauthn.ErrCredentialNotFound
+	}
+	copy := *cred
+	return &copy, nil
+}
+
+func (s *stubWebAuthnStore) ListByUser(ctx context.Context, userID string) ([]*webauthn.Credential, error) {
+	var out []*webauthn.Credential
+	for _, cred := range s.creds {
+		if cred.UserID == userID {
+			copy := *cred
+			out = append(out, &copy)
+		}
+	}
+	return out, nil
+}
+
+func (s *stubWebAuthnStore) UpdateUsage(ctx context.Context, id string, signCount uint32, backupState bool, at time.Time) error {
+	cred, ok := s.creds[id]
+	if !ok {
+		return webauthn.ErrCredentialNotFound
+	}
+	if !(cred.SignCount < signCount || (signCount == 0 && cred.SignCount == 0)) {
+		return webauthn.ErrCloneDetected
+	}
+	cred.SignCount = signCount
+	cred.BackupState = backupState
+	cred.LastUsedAt = &at
+	return nil
+}
@@ -0,0 +1,16 @@
+// Package buildinfo 保存构建时注入的版本信息
+//
+// 构建时通过 -ldflags 注入，例如：
+//
+//	go build -ldflags "-X github.com/yeying-community/warehouse/internal/infrastructure/buildinfo.Version=v2.1.0 \
+//	  -X github.com/yeying-community/warehouse/internal/infrastructure/buildinfo.Commit=$(git rev-parse --short HEAD)" ./cmd/server
+package buildinfo
+
+var (
+	// Version 版本号（git describe）
+	Version = "dev"
+	// Commit Git 提交哈希
+	Commit = "unknown"
+	// BuildTime 构建时间（UTC）
+	BuildTime = "unknown"
+)
@@ -0,0 +1,382 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 合约方法选择器
+var (
+	selectorBalanceOf     = []byte{0x70, 0xa0, 0x82, 0x31} // balanceOf(address)
+	selectorOwnerOf       = []byte{0x63, 0x52, 0x21, 0x1e} // ownerOf(uint256)
+	selectorBalanceOf1155 = []byte{0x00, 0xfd, 0xd5, 0x8e} // balanceOf(address,uint256)
+	selectorIsValidSig    = []byte{0x16, 0x26, 0xba, 0x7e} // isValidSignature(bytes32,bytes)
+)
+
+// erc1271MagicValue isValidSignature 校验通过时返回的魔数（即方法选择器）
+var erc1271MagicValue = selectorIsValidSig
+
+// ErrExecutionReverted 合约调用被回滚（如 ownerOf 查询不存在的 token）
+var ErrExecutionReverted = errors.New("execution reverted")
+
+// Client 以太坊 JSON-RPC 客户端（只读查询）
+type Client struct {
+	endpoint string
+	http     *http.Client
+	nextID   atomic.Uint64
+}
+
+// NewClient 创建 JSON-RPC 客户端
+func NewClient(endpoint string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Client{
+		endpoint: endpoint,
+		http:     &http.Client{Timeout: timeout},
+	}
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// ChainID 查询节点链 ID（eth_chainId）
+func (c *Client) ChainID(ctx context.Context) (uint64, error) {
+	var result string
+	if err := c.call(ctx, "eth_chainId", []interface{}{}, &result); err != nil {
+		return 0, err
+	}
+	v, err := decodeQuantity(result)
+	if err != nil {
+		return 0, err
+	}
+	if !v.IsUint64() {
+		return 0, fmt.Errorf("chain id out of range: %s", result)
+	}
+	return v.Uint64(), nil
+}
+
+// Balance 查询原生币余额（eth_getBalance）
+func (c *Client) Balance(ctx context.Context, account string) (*big.Int, error) {
+	var result string
+	if err := c.call(ctx, "eth_getBalance", []interface{}{account, "latest"}, &result); err != nil {
+		return nil, err
+	}
+	return decodeQuantity(result)
+}
+
+// TokenBalance 查询 ERC-20 / ERC-721 的 balanceOf(account)
+func (c *Client) TokenBalance(ctx context.Context, contract, account string) (*big.Int, error) {
+	data, err := encodeCall(selectorBalanceOf, addressWord(account))
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.Call(ctx, contract, data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeUint(out)
+}
+
+// OwnerOf 查询 ERC-721 token 的持有者地址（小写）
+func (c *Client) OwnerOf(ctx context.Context, contract string, tokenID *big.Int) (string, error) {
+	data, err := encodeCall(selectorOwnerOf, uintWord(tokenID))
+	if err != nil {
+		return "", err
+	}
+	out, err := c.Call(ctx, contract, data)
+	if err != nil {
+		return "", err
+	}
+	if len(out) < 32 {
+		return "", fmt.Errorf("invalid ownerOf result")
+	}
+	return "0x" + hex.EncodeToString(out[12:32]), nil
+}
+
+// MultiTokenBalance 查询 ERC-1155 的 balanceOf(account, id)
+func (c *Client) MultiTokenBalance(ctx context.Context, contract, account string, tokenID *big.Int) (*big.Int, error) {
+	data, err := encodeCall(selectorBalanceOf1155, addressWord(account), uintWord(tokenID))
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.Call(ctx, contract, data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeUint(out)
+}
+
+// Code 查询地址上部署的合约代码（eth_getCode），EOA 返回空
+func (c *Client) Code(ctx context.Context, account string) ([]byte, error) {
+	var result string
+	if err := c.call(ctx, "eth_getCode", []interface{}{account, "latest"}, &result); err != nil {
+		return nil, err
+	}
+	out, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid eth_getCode result: %w", err)
+	}
+	return out, nil
+}
+
+// IsValidSignature 调用 EIP-1271 isValidSignature(hash, signature)
+// 合约回滚视为签名无效。
+func (c *Client) IsValidSignature(ctx context.Context, contract string, hash [32]byte, signature []byte) (bool, error) {
+	out, err := c.Call(ctx, contract, isValidSignatureCall(hash, signature))
+	if errors.Is(err, ErrExecutionReverted) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(out) >= 4 && bytes.Equal(out[:4], erc1271MagicValue), nil
+}
+
+// IsValidCounterfactualSignature 校验 ERC-6492 未部署钱包的签名
+// 在一次 eth_call 合约创建模拟中先调用工厂部署钱包，再调用 isValidSignature，状态不会上链。
+func (c *Client) IsValidCounterfactualSignature(ctx context.Context, factory string, factoryCalldata []byte, signer string, hash [32]byte, signature []byte) (bool, error) {
+	initCode, err := counterfactualValidator(factory, factoryCalldata, signer, isValidSignatureCall(hash, signature))
+	if err != nil {
+		return false, err
+	}
+	out, err := c.Simulate(ctx, initCode)
+	if errors.Is(err, ErrExecutionReverted) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(out) >= 4 && bytes.Equal(out[:4], erc1271MagicValue), nil
+}
+
+// Simulate 以 eth_call 模拟合约创建（不带 to），返回构造代码的返回数据
+func (c *Client) Simulate(ctx context.Context, initCode []byte) ([]byte, error) {
+	return c.Call(ctx, "", initCode)
+}
+
+// Call 执行只读合约调用（eth_call，latest 区块），contract 为空时模拟合约创建
+func (c *Client) Call(ctx context.Context, contract string, data []byte) ([]byte, error) {
+	msg := map[string]string{
+		"data": "0x" + hex.EncodeToString(data),
+	}
+	if contract != "" {
+		msg["to"] = contract
+	}
+	var result string
+	if err := c.call(ctx, "eth_call", []interface{}{msg, "latest"}, &result); err != nil {
+		return nil, err
+	}
+	out, err := hex.DecodeString(strings.TrimPrefix(result, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid eth_call result: %w", err)
+	}
+	return out, nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextID.Add(1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode rpc request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create rpc request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("rpc %s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rpc %s failed: http status %d", method, resp.StatusCode)
+	}
+
+	var out rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return fmt.Errorf("failed to decode rpc response: %w", err)
+	}
+	if out.Error != nil {
+		if strings.Contains(strings.ToLower(out.Error.Message), "revert") {
+			return fmt.Errorf("%w: %s", ErrExecutionReverted, out.Error.Message)
+		}
+		return fmt.Errorf("rpc %s error %d: %s", method, out.Error.Code, out.Error.Message)
+	}
+	if err := json.Unmarshal(out.Result, result); err != nil {
+		return fmt.Errorf("failed to decode rpc result: %w", err)
+	}
+	return nil
+}
+
+func encodeCall(selector []byte, words ...[]byte) ([]byte, error) {
+	data := append([]byte{}, selector...)
+	for _, w := range words {
+		if w == nil {
+			return nil, fmt.Errorf("invalid call argument")
+		}
+		data = append(data, w...)
+	}
+	return data, nil
+}
+
+// isValidSignatureCall 编码 isValidSignature(bytes32,bytes) 调用数据
+func isValidSignatureCall(hash [32]byte, signature []byte) []byte {
+	data := append([]byte{}, selectorIsValidSig...)
+	data = append(data, hash[:]...)
+	data = append(data, uintWord(big.NewInt(64))...)
+	return append(data, bytesTail(signature)...)
+}
+
+// bytesTail 将动态 bytes 编码为 ABI 尾部（长度 + 32 字节对齐的数据）
+func bytesTail(b []byte) []byte {
+	out := uintWord(big.NewInt(int64(len(b))))
+	padded := make([]byte, (len(b)+31)/32*32)
+	copy(padded, b)
+	return append(out, padded...)
+}
+
+const (
+	// validatorHeaderLen counterfactualValidator 生成的指令部分长度，调用数据紧随其后
+	validatorHeaderLen = 99
+	// maxInitCodeSize EIP-3860 创建代码长度上限
+	maxInitCodeSize = 49152
+)
+
+// counterfactualValidator 生成 ERC-6492 校验用的一次性构造代码：
+//
+//	CALL(factory, factoryCalldata)                    部署钱包，失败忽略（钱包可能已部署）
+//	STATICCALL(signer, isValidSignature(...)) -> ret  校验签名
+//	RETURN(ret * success)                             失败时返回 0
+//
+// 两段调用数据附在代码之后，通过 CODECOPY 读取。
+func counterfactualValidator(factory string, factoryCalldata []byte, signer string, validateCalldata []byte) ([]byte, error) {
+	factoryAddr := addressWord(factory)
+	signerAddr := addressWord(signer)
+	if factoryAddr == nil || signerAddr == nil {
+		return nil, fmt.Errorf("invalid call argument")
+	}
+	// 节点拒绝超过 EIP-3860 上限的创建代码；该上限也保证代码内用 PUSH2 编码的偏移与长度不会被截断
+	if validatorHeaderLen+len(factoryCalldata)+len(validateCalldata) > maxInitCodeSize {
+		return nil, fmt.Errorf("call data too large")
+	}
+
+	const (
+		opMul        = 0x02
+		opCodeCopy   = 0x39
+		opPop        = 0x50
+		opMLoad      = 0x51
+		opMStore     = 0x52
+		opGas        = 0x5a
+		opPush1      = 0x60
+		opPush2      = 0x61
+		opPush20     = 0x73
+		opCall       = 0xf1
+		opReturn     = 0xf3
+		opStaticCall = 0xfa
+	)
+	push2 := func(v int) []byte { return []byte{opPush2, byte(v >> 8), byte(v)} }
+	factoryOffset := validatorHeaderLen
+	validateOffset := factoryOffset + len(factoryCalldata)
+	// 返回值写入两段调用数据之后的空白内存，避免短返回时读到残留的调用数据
+	retOffset := (max(len(factoryCalldata), len(validateCalldata)) + 31) / 32 * 32
+
+	var code []byte
+	// memory[0:] = factoryCalldata; CALL(gas, factory, 0, 0, len, 0, 0); POP
+	code = append(code, push2(len(factoryCalldata))...)
+	code = append(code, push2(factoryOffset)...)
+	code = append(code, opPush1, 0, opCodeCopy)
+	code = append(code, opPush1, 0, opPush1, 0)
+	code = append(code, push2(len(factoryCalldata))...)
+	code = append(code, opPush1, 0, opPush1, 0, opPush20)
+	code = append(code, factoryAddr[12:]...)
+	code = append(code, opGas, opCall, opPop)
+	// memory[0:] = validateCalldata; STATICCALL(gas, signer, 0, len, ret, 32)
+	code = append(code, push2(len(validateCalldata))...)
+	code = append(code, push2(validateOffset)...)
+	code = append(code, opPush1, 0, opCodeCopy)
+	code = append(code, opPush1, 32)
+	code = append(code, push2(retOffset)...)
+	code = append(code, push2(len(validateCalldata))...)
+	code = append(code, opPush1, 0, opPush20)
+	code = append(code, signerAddr[12:]...)
+	code = append(code, opGas, opStaticCall)
+	// memory[0:32] = memory[ret] * success; RETURN(0, 32)
+	code = append(code, push2(retOffset)...)
+	code = append(code, opMLoad, opMul, opPush1, 0, opMStore)
+	code = append(code, opPush1, 32, opPush1, 0, opReturn)
+	if len(code) != validatorHeaderLen {
+		return nil, fmt.Errorf("unexpected validator header length %d", len(code))
+	}
+
+	code = append(code, factoryCalldata...)
+	return append(code, validateCalldata...), nil
+}
+
+// addressWord 将地址编码为 32 字节 ABI 参数
+func addressWord(address string) []byte {
+	raw, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(address), "0x"))
+	if err != nil || len(raw) != 20 {
+		return nil
+	}
+	word := make([]byte, 32)
+	copy(word[12:], raw)
+	return word
+}
+
+// uintWord 将无符号整数编码为 32 字节 ABI 参数
+func uintWord(v *big.Int) []byte {
+	if v == nil || v.Sign() < 0 || v.BitLen() > 256 {
+		return nil
+	}
+	return v.FillBytes(make([]byte, 32))
+}
+
+func decodeUint(out []byte) (*big.Int, error) {
+	if len(out) < 32 {
+		return nil, fmt.Errorf("invalid uint256 result")
+	}
+	return new(big.Int).SetBytes(out[:32]), nil
+}
+
+func decodeQuantity(s string) (*big.Int, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "0x")
+	if s == "" {
+		return new(big.Int), nil
+	}
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid quantity: %q", s)
+	}
+	return v, nil
+}
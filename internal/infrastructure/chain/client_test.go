@@ -0,0 +1,93 @@
+package chain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+var (
+	// stubFactory 以调用数据为初始化代码执行 CREATE2(salt=0)
+	stubFactory = common.FromHex("366000600037" + "6000366000600" + "0f55000")
+	// stubWalletRuntime 签名首字节为 0x01 时返回 EIP-1271 魔数，否则返回 0
+	stubWalletRuntime = common.FromHex("60643560f81c600114631626ba7e60e01b0260005260206000f3")
+	factoryAddress    = common.HexToAddress("0xfac7fac7fac7fac7fac7fac7fac7fac7fac7fac7")
+)
+
+// walletInitCode 返回部署 stubWalletRuntime 的初始化代码，padding 个零字节附在末尾用于放大调用数据
+func walletInitCode(padding int) []byte {
+	code := []byte{0x60, byte(len(stubWalletRuntime)), 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, byte(len(stubWalletRuntime)), 0x60, 0x00, 0xf3}
+	code = append(code, stubWalletRuntime...)
+	return append(code, make([]byte, padding)...)
+}
+
+// runValidator 在内存 EVM 中以合约创建方式执行校验代码，返回其返回数据
+func runValidator(t *testing.T, initCode []byte) []byte {
+	t.Helper()
+	statedb, err := state.New(types.EmptyRootHash, state.NewDatabase(triedb.NewDatabase(rawdb.NewMemoryDatabase(), nil), nil))
+	if err != nil {
+		t.Fatalf("state.New returned error: %v", err)
+	}
+	statedb.SetCode(factoryAddress, stubFactory, 0)
+	ret, _, _, err := runtime.Create(initCode, &runtime.Config{State: statedb, GasLimit: 30_000_000})
+	if err != nil {
+		t.Fatalf("validator execution failed: %v", err)
+	}
+	return ret
+}
+
+func TestCounterfactualValidatorExecutes(t *testing.T) {
+	var hash [32]byte
+	for _, padding := range []int{0, 300, maxInitCodeSize - validatorHeaderLen - 200} {
+		factoryCalldata := walletInitCode(padding)
+		wallet := gethcrypto.CreateAddress2(factoryAddress, [32]byte{}, gethcrypto.Keccak256(factoryCalldata))
+
+		for _, tc := range []struct {
+			signature []byte
+			valid     bool
+		}{
+			{[]byte{0x01, 0xaa}, true},
+			{[]byte{0x02, 0xaa}, false},
+		} {
+			code, err := counterfactualValidator(factoryAddress.Hex(), factoryCalldata, wallet.Hex(), isValidSignatureCall(hash, tc.signature))
+			if err != nil {
+				t.Fatalf("padding %d: counterfactualValidator returned error: %v", padding, err)
+			}
+			ret := runValidator(t, code)
+			valid := len(ret) >= 4 && bytes.Equal(ret[:4], erc1271MagicValue)
+			if valid != tc.valid {
+				t.Fatalf("padding %d, signature %x: got valid=%v (ret %x)", padding, tc.signature, valid, ret)
+			}
+		}
+	}
+
+	// 工厂不存在时钱包没有被部署，STATICCALL 空账户成功但无返回数据，结果必须为 0
+	factoryCalldata := walletInitCode(0)
+	wallet := gethcrypto.CreateAddress2(factoryAddress, [32]byte{}, gethcrypto.Keccak256(factoryCalldata))
+	code, err := counterfactualValidator("0x000000000000000000000000000000000000dead", factoryCalldata, wallet.Hex(), isValidSignatureCall(hash, []byte{0x01}))
+	if err != nil {
+		t.Fatalf("counterfactualValidator returned error: %v", err)
+	}
+	if ret := runValidator(t, code); len(ret) != 32 || !bytes.Equal(ret, make([]byte, 32)) {
+		t.Fatalf("validator without a deployed wallet returned %x", ret)
+	}
+}
+
+func TestCounterfactualValidatorRejectsOversizedCode(t *testing.T) {
+	validate := isValidSignatureCall([32]byte{}, []byte{0x01})
+	limit := maxInitCodeSize - validatorHeaderLen - len(validate)
+	if _, err := counterfactualValidator(factoryAddress.Hex(), make([]byte, limit), factoryAddress.Hex(), validate); err != nil {
+		t.Fatalf("code at the size limit should be accepted: %v", err)
+	}
+	// 只限制单段长度时 validateOffset 可以超过 0xffff，被 PUSH2 截断
+	if _, err := counterfactualValidator(factoryAddress.Hex(), make([]byte, limit+1), factoryAddress.Hex(), validate); err == nil {
+		t.Fatalf("expected code beyond the init code limit to be rejected")
+	}
+}
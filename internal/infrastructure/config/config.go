@@ -11,14 +11,20 @@ type Config struct {
 	WebDAV   WebDAVConfig   `yaml:"webdav"`
 	Web3     Web3Config     `yaml:"web3"`
 	Email    EmailConfig    `yaml:"email"`
+	WebAuthn WebAuthnConfig `yaml:"webauthn"`
 	Security SecurityConfig `yaml:"security"`
+	Audit    AuditConfig    `yaml:"audit"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Health   HealthConfig   `yaml:"health"`
+	Tracing  TracingConfig  `yaml:"tracing"`
 	CORS     CORSConfig     `yaml:"cors"`
 	Log      LogConfig      `yaml:"log"`
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	Type         string        `yaml:"type"` // 仅支持 "postgres"/"postgresql"
+	Type         string        `yaml:"type"` // "postgres"/"postgresql" 或 "sqlite"
+	Path         string        `yaml:"path"` // SQLite 数据库文件路径
 	Host         string        `yaml:"host"`
 	Port         int           `yaml:"port"`
 	Database     string        `yaml:"database"`
@@ -45,23 +51,98 @@ type ServerConfig struct {
 
 // WebDAVConfig WebDAV 配置
 type WebDAVConfig struct {
-	Prefix      string `yaml:"prefix"`
-	Directory   string `yaml:"directory"`
-	NoSniff     bool   `yaml:"no_sniff"`
-	Permissions string `yaml:"permissions"`
+	Prefix      string           `yaml:"prefix"`
+	Directory   string           `yaml:"directory"`
+	NoSniff     bool             `yaml:"no_sniff"`
+	Permissions string           `yaml:"permissions"`
+	Dedup       DedupConfig      `yaml:"dedup"`
+	Encryption  EncryptionConfig `yaml:"encryption"`
+}
+
+// DedupConfig 内容寻址去重存储配置
+type DedupConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Directory   string        `yaml:"directory"`    // blob 目录，为空时使用 {webdav.directory}/.blobs，须与用户目录位于同一文件系统
+	QuotaPolicy string        `yaml:"quota_policy"` // full: 每个引用计完整大小; split: 按引用数平摊; once: 同一用户内相同内容只计一次
+	GCInterval  time.Duration `yaml:"gc_interval"`  // 无引用 blob 的回收间隔，0 表示不自动回收
+}
+
+// EncryptionConfig 静态加密配置
+type EncryptionConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	MasterKey     string `yaml:"master_key"`      // 主密钥（32 字节，hex 或 base64 编码）
+	MasterKeyFile string `yaml:"master_key_file"` // 主密钥文件，master_key 为空时使用
 }
 
 // Web3Config Web3 配置
 type Web3Config struct {
-	JWTSecret              string        `yaml:"jwt_secret"`
-	TokenExpiration        time.Duration `yaml:"token_expiration"`
-	RefreshTokenExpiration time.Duration `yaml:"refresh_token_expiration"`
-	AutoCreateOnChallenge  bool          `yaml:"auto_create_on_challenge"`
-	AutoCreateOnUCAN       bool          `yaml:"auto_create_on_ucan"`
-	UCAN                   UCANConfig    `yaml:"ucan"`
+	JWTSecret              string            `yaml:"jwt_secret"`
+	JWTSigning             JWTSigningConfig  `yaml:"jwt_signing"`
+	TokenExpiration        time.Duration     `yaml:"token_expiration"`
+	RefreshTokenExpiration time.Duration     `yaml:"refresh_token_expiration"`
+	AutoCreateOnChallenge  bool              `yaml:"auto_create_on_challenge"`
+	AutoCreateOnUCAN       bool              `yaml:"auto_create_on_ucan"`
+	UCAN                   UCANConfig        `yaml:"ucan"`
+	SIWE                   SIWEConfig        `yaml:"siwe"`
+	SmartWallet            SmartWalletConfig `yaml:"smart_wallet"`
+	TokenGate              TokenGateConfig   `yaml:"token_gate"`
+}
+
+// JWTSigningConfig JWT 签名配置
+// 非对称算法的公钥通过 /.well-known/jwks.json 公开，其他服务无需共享密钥即可验证令牌。
+type JWTSigningConfig struct {
+	Algorithm   string `yaml:"algorithm"`     // HS256（使用 jwt_secret）/ EdDSA / ES256
+	KeysDir     string `yaml:"keys_dir"`      // 密钥目录：<kid>.pem 为私钥，<kid>.pub.pem 为仅用于验证的退役公钥
+	ActiveKeyID string `yaml:"active_key_id"` // 签名使用的 kid，为空时使用目录中最新的私钥
+	AcceptHS256 bool   `yaml:"accept_hs256"`  // 切换算法后的过渡期内继续接受 jwt_secret 签名的令牌
+}
+
+// SIWEConfig EIP-4361（Sign-In with Ethereum）挑战配置
+type SIWEConfig struct {
+	LegacyMessage bool          `yaml:"legacy_message"` // 使用旧版自定义挑战消息（仅用于迁移，不校验 domain / 链 ID）
+	Domain        string        `yaml:"domain"`         // 消息中的 domain，为空时使用请求 Host
+	URI           string        `yaml:"uri"`            // 消息中的 URI，为空时使用 {scheme}://{domain}
+	Statement     string        `yaml:"statement"`
+	ChainIDs      []uint64      `yaml:"chain_ids"`     // 允许的链 ID，第一个为默认值
+	SolanaChains  []string      `yaml:"solana_chains"` // 允许 Solana 登录（SIWS）的 CAIP-2 reference，为空表示不支持 Solana
+	ChallengeTTL  time.Duration `yaml:"challenge_ttl"` // 挑战有效期（即消息的 Expiration Time）
+	ClockSkew     time.Duration `yaml:"clock_skew"`    // 校验 Issued At / Not Before 时允许的时钟偏差
+}
+
+// SmartWalletConfig 智能合约钱包签名校验配置
+// ECDSA 恢复失败时通过 JSON-RPC 调用 EIP-1271 isValidSignature，并支持 ERC-6492 包装的未部署钱包签名。
+type SmartWalletConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Timeout time.Duration `yaml:"timeout"` // 单次 JSON-RPC 请求超时
+	Chains  []ChainConfig `yaml:"chains"`  // 第一个为默认链（消息未携带链 ID 时使用）
+}
+
+// TokenGateConfig 链上代币门槛配置
+type TokenGateConfig struct {
+	Enabled   bool                            `yaml:"enabled"`
+	CacheTTL  time.Duration                   `yaml:"cache_ttl"` // 链上查询结果缓存时间，0 表示不缓存
+	Timeout   time.Duration                   `yaml:"timeout"`   // 单次 JSON-RPC 请求超时
+	Chains    []ChainConfig                   `yaml:"chains"`
+	Challenge TokenConditionConfig            `yaml:"challenge"` // 钱包登录（challenge）门槛，type 为空表示不限制
+	Spaces    map[string]TokenConditionConfig `yaml:"spaces"`    // 资产空间门槛，key 为空间标识（personal / apps）
+}
+
+// ChainConfig 链 JSON-RPC 节点配置
+type ChainConfig struct {
+	ChainID uint64 `yaml:"chain_id"`
+	RPCURL  string `yaml:"rpc_url"`
 }
 
-// EmailConfig 邮箱验证码登录配置
+// TokenConditionConfig 链上条件配置
+type TokenConditionConfig struct {
+	Type       string `yaml:"type"` // native / erc20 / erc721 / erc1155
+	ChainID    uint64 `yaml:"chain_id"`
+	Contract   string `yaml:"contract"`
+	TokenID    string `yaml:"token_id"`
+	MinBalance string `yaml:"min_balance"` // 最小持有量（最小单位），默认 1
+}
+
+// EmailConfig 邮件配置（验证码登录、重置密码与验证邮箱）
 type EmailConfig struct {
 	Enabled            bool          `yaml:"enabled"`
 	SMTPHost           string        `yaml:"smtp_host"`
@@ -70,13 +151,41 @@ type EmailConfig struct {
 	SMTPPassword       string        `yaml:"smtp_password"`
 	From               string        `yaml:"from"`
 	FromName           string        `yaml:"from_name"`
-	TemplatePath       string        `yaml:"template_path"`
+	TemplatePath       string        `yaml:"template_path"` // 旧版登录验证码模板文件；为空时使用 template_dir 中的 email_code_login 模板
 	CodeTTL            time.Duration `yaml:"code_ttl"`
 	SendInterval       time.Duration `yaml:"send_interval"`
 	CodeLength         int           `yaml:"code_length"`
+	MaxAttempts        int           `yaml:"max_attempts"` // 每个验证码允许的错误次数，用尽后需重新获取
 	AutoCreateOnLogin  bool          `yaml:"auto_create_on_login"`
 	UseTLS             bool          `yaml:"use_tls"`
 	InsecureSkipVerify bool          `yaml:"insecure_skip_verify"`
+	TemplateDir        string        `yaml:"template_dir"`     // 事务邮件模板目录，文件名为 <name>_mail_template_<locale>.html
+	DefaultLocale      string        `yaml:"default_locale"`   // 收件人语言没有对应模板时使用的语言
+	LinkBaseURL        string        `yaml:"link_base_url"`    // 邮件链接指向的前端地址；为空时不开放重置密码与验证邮箱
+	ResetTokenTTL      time.Duration `yaml:"reset_token_ttl"`  // 重置密码链接有效期
+	VerifyTokenTTL     time.Duration `yaml:"verify_token_ttl"` // 验证邮箱链接有效期
+	Outbox             OutboxConfig  `yaml:"outbox"`
+}
+
+// OutboxConfig 邮件发件箱配置：邮件先写入数据库，由后台任务发送并重试
+type OutboxConfig struct {
+	PollInterval  time.Duration `yaml:"poll_interval"`  // 检查到期邮件的间隔；写入新邮件时会立即唤醒
+	BatchSize     int           `yaml:"batch_size"`     // 每次最多领取的邮件数
+	MaxAttempts   int           `yaml:"max_attempts"`   // 最多发送次数，用尽后转为死信
+	RetryBase     time.Duration `yaml:"retry_base"`     // 首次重试间隔，之后每次翻倍
+	RetryMax      time.Duration `yaml:"retry_max"`      // 重试间隔上限
+	SentRetention time.Duration `yaml:"sent_retention"` // 发送成功的邮件保留时长，0 表示永久保留
+}
+
+// WebAuthnConfig 通行密钥（WebAuthn）登录配置
+type WebAuthnConfig struct {
+	Enabled              bool          `yaml:"enabled"`
+	RPID                 string        `yaml:"rp_id"`                   // 依赖方 ID，通常为站点域名
+	RPName               string        `yaml:"rp_name"`                 // 浏览器提示中显示的名称
+	Origins              []string      `yaml:"origins"`                 // 允许发起注册与登录的前端 origin
+	UserVerification     string        `yaml:"user_verification"`       // required / preferred / discouraged
+	ChallengeTTL         time.Duration `yaml:"challenge_ttl"`           // 注册与登录挑战有效期
+	AutoCreateOnRegister bool          `yaml:"auto_create_on_register"` // 未登录注册通行密钥时自动创建账户
 }
 
 // UCANConfig UCAN authentication configuration
@@ -95,9 +204,47 @@ type AppScopeConfig struct {
 
 // SecurityConfig 安全配置
 type SecurityConfig struct {
-	NoPassword     bool     `yaml:"no_password"`
-	BehindProxy    bool     `yaml:"behind_proxy"`
-	AdminAddresses []string `yaml:"admin_addresses"`
+	NoPassword     bool            `yaml:"no_password"`
+	BehindProxy    bool            `yaml:"behind_proxy"`
+	TrustedProxies []string        `yaml:"trusted_proxies"` // 连接的对端之外的可信代理（IP 或 CIDR），从 X-Forwarded-For 右侧跳过
+	AdminAddresses []string        `yaml:"admin_addresses"`
+	MFA            MFAConfig       `yaml:"mfa"`
+	RateLimit      RateLimitConfig `yaml:"rate_limit"`
+}
+
+// MFAConfig 两步验证（TOTP）配置
+type MFAConfig struct {
+	Issuer           string        `yaml:"issuer"`             // 认证器应用中显示的发行方
+	ChallengeTTL     time.Duration `yaml:"challenge_ttl"`      // 登录第二步挑战令牌有效期
+	RequireForAdmins bool          `yaml:"require_for_admins"` // admin_addresses 中的账户必须启用两步验证
+	MaxAttempts      int           `yaml:"max_attempts"`       // 锁定前允许连续失败的次数
+	LockoutDuration  time.Duration `yaml:"lockout_duration"`   // 连续失败后的锁定时长
+}
+
+// RateLimitConfig 限流与防暴力破解配置
+type RateLimitConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Auth    RateLimitRule `yaml:"auth"`   // /api/v1/public/auth/* 登录相关接口
+	API     RateLimitRule `yaml:"api"`    // 其他 /api/* 接口
+	WebDAV  RateLimitRule `yaml:"webdav"` // WebDAV 前缀下的请求
+	Lockout LockoutConfig `yaml:"lockout"`
+}
+
+// RateLimitRule 一组路由的令牌桶规则，per_minute 为 0 表示不限制
+// 账户维度：登录接口按提交的用户名 / 邮箱 / 地址，其余接口按认证后的用户。
+type RateLimitRule struct {
+	IPPerMinute      int `yaml:"ip_per_minute"`
+	IPBurst          int `yaml:"ip_burst"`
+	AccountPerMinute int `yaml:"account_per_minute"`
+	AccountBurst     int `yaml:"account_burst"`
+}
+
+// LockoutConfig 密码连续错误后的账户锁定，每次再触发锁定时长翻倍
+type LockoutConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	MaxFailures int           `yaml:"max_failures"` // 触发锁定的连续失败次数
+	Duration    time.Duration `yaml:"duration"`     // 首次锁定时长
+	MaxDuration time.Duration `yaml:"max_duration"` // 锁定时长上限
 }
 
 // CORSConfig CORS 配置
@@ -110,6 +257,38 @@ type CORSConfig struct {
 	ExposedHeaders []string `yaml:"exposed_headers"`
 }
 
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Retention time.Duration `yaml:"retention"` // 审计记录保留时长，0 表示永久保留
+}
+
+// MetricsConfig Prometheus 指标配置
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+	// Address 非空时在独立地址上提供指标（如 127.0.0.1:9100），不再挂载到主服务
+	Address  string `yaml:"address"`
+	Username string `yaml:"username"` // 设置后需要 Basic 认证
+	Password string `yaml:"password"`
+}
+
+// HealthConfig 就绪检查配置
+type HealthConfig struct {
+	Timeout      time.Duration `yaml:"timeout"`        // 单项检查超时
+	MinFreeBytes int64         `yaml:"min_free_bytes"` // WebDAV 数据目录最小可用空间，0 表示不检查
+}
+
+// TracingConfig 链路追踪配置
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	Exporter    string            `yaml:"exporter"`     // otlp / stdout
+	Endpoint    string            `yaml:"endpoint"`     // OTLP/HTTP collector 地址，如 http://localhost:4318
+	Headers     map[string]string `yaml:"headers"`      // 上报时附加的请求头（如认证令牌）
+	ServiceName string            `yaml:"service_name"` // 上报的 service.name
+	SampleRatio float64           `yaml:"sample_ratio"` // 新链路的采样比例 0~1，上游传入 traceparent 时沿用其决定
+}
+
 // LogConfig 日志配置
 type LogConfig struct {
 	Level   string   `yaml:"level"`
@@ -147,17 +326,43 @@ func DefaultConfig() *Config {
 			Directory:   "/data",
 			NoSniff:     true,
 			Permissions: "R",
+			Dedup: DedupConfig{
+				Enabled:     false,
+				QuotaPolicy: "full",
+				GCInterval:  time.Hour,
+			},
 		},
 		Web3: Web3Config{
 			TokenExpiration:        24 * time.Hour,
 			RefreshTokenExpiration: 30 * 24 * time.Hour,
 			AutoCreateOnChallenge:  true,
 			AutoCreateOnUCAN:       true,
+			JWTSigning: JWTSigningConfig{
+				Algorithm:   "HS256",
+				KeysDir:     "./keys/jwt",
+				AcceptHS256: true,
+			},
 			UCAN: UCANConfig{
 				AppScope: AppScopeConfig{
 					PathPrefix: "/apps",
 				},
 			},
+			SIWE: SIWEConfig{
+				LegacyMessage: false,
+				Statement:     "Sign in to Warehouse. This request will not trigger a blockchain transaction or cost any gas fees.",
+				ChainIDs:      []uint64{1},
+				ChallengeTTL:  5 * time.Minute,
+				ClockSkew:     time.Minute,
+			},
+			SmartWallet: SmartWalletConfig{
+				Enabled: false,
+				Timeout: 10 * time.Second,
+			},
+			TokenGate: TokenGateConfig{
+				Enabled:  false,
+				CacheTTL: time.Minute,
+				Timeout:  10 * time.Second,
+			},
 		},
 		Email: EmailConfig{
 			Enabled:            false,
@@ -167,18 +372,92 @@ func DefaultConfig() *Config {
 			SMTPPassword:       "",
 			From:               "",
 			FromName:           "Warehouse",
-			TemplatePath:       "resources/email/email_code_login_mail_template_zh-CN.html",
+			TemplatePath:       "",
 			CodeTTL:            5 * time.Minute,
 			SendInterval:       60 * time.Second,
 			CodeLength:         6,
+			MaxAttempts:        5,
 			AutoCreateOnLogin:  true,
 			UseTLS:             false,
 			InsecureSkipVerify: false,
+			TemplateDir:        "resources/email",
+			DefaultLocale:      "zh-CN",
+			LinkBaseURL:        "",
+			ResetTokenTTL:      30 * time.Minute,
+			VerifyTokenTTL:     24 * time.Hour,
+			Outbox: OutboxConfig{
+				PollInterval:  10 * time.Second,
+				BatchSize:     20,
+				MaxAttempts:   8,
+				RetryBase:     30 * time.Second,
+				RetryMax:      time.Hour,
+				SentRetention: 7 * 24 * time.Hour,
+			},
+		},
+		WebAuthn: WebAuthnConfig{
+			Enabled:              false,
+			RPName:               "Warehouse",
+			UserVerification:     "preferred",
+			ChallengeTTL:         5 * time.Minute,
+			AutoCreateOnRegister: false,
 		},
 		Security: SecurityConfig{
 			NoPassword:     false,
 			BehindProxy:    false,
 			AdminAddresses: []string{},
+			MFA: MFAConfig{
+				Issuer:           "Warehouse",
+				ChallengeTTL:     5 * time.Minute,
+				RequireForAdmins: false,
+				MaxAttempts:      5,
+				LockoutDuration:  15 * time.Minute,
+			},
+			RateLimit: RateLimitConfig{
+				Enabled: true,
+				Auth: RateLimitRule{
+					IPPerMinute:      30,
+					IPBurst:          10,
+					AccountPerMinute: 10,
+					AccountBurst:     5,
+				},
+				API: RateLimitRule{
+					IPPerMinute:      600,
+					IPBurst:          120,
+					AccountPerMinute: 600,
+					AccountBurst:     120,
+				},
+				WebDAV: RateLimitRule{
+					IPPerMinute:      1200,
+					IPBurst:          300,
+					AccountPerMinute: 1200,
+					AccountBurst:     300,
+				},
+				Lockout: LockoutConfig{
+					Enabled:     true,
+					MaxFailures: 5,
+					Duration:    time.Minute,
+					MaxDuration: time.Hour,
+				},
+			},
+		},
+		Audit: AuditConfig{
+			Enabled:   true,
+			Retention: 90 * 24 * time.Hour,
+		},
+		Metrics: MetricsConfig{
+			Enabled: false,
+			Path:    "/metrics",
+		},
+		Health: HealthConfig{
+			Timeout:      3 * time.Second,
+			MinFreeBytes: 100 * 1024 * 1024,
+		},
+		Tracing: TracingConfig{
+			Enabled:     false,
+			Exporter:    "otlp",
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "warehouse",
+			SampleRatio: 1,
 		},
 		CORS: CORSConfig{
 			Enabled:     false,
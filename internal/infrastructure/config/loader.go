@@ -3,12 +3,16 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
+	"github.com/yeying-community/warehouse/internal/domain/account"
 	"gopkg.in/yaml.v3"
 )
 
@@ -96,9 +100,27 @@ func (l *Loader) overrideFromEnv(config *Config) {
 			config.Server.Port = port
 		}
 	}
+	if v := os.Getenv("WEBDAV_DATABASE_TYPE"); v != "" {
+		config.Database.Type = v
+	}
+	if v := os.Getenv("WEBDAV_DATABASE_PATH"); v != "" {
+		config.Database.Path = v
+	}
 	if v := os.Getenv("WEBDAV_JWT_SECRET"); v != "" {
 		config.Web3.JWTSecret = v
 	}
+	if v := os.Getenv("WEBDAV_JWT_ALGORITHM"); v != "" {
+		config.Web3.JWTSigning.Algorithm = v
+	}
+	if v := os.Getenv("WEBDAV_JWT_KEYS_DIR"); v != "" {
+		config.Web3.JWTSigning.KeysDir = v
+	}
+	if v := os.Getenv("WEBDAV_JWT_ACTIVE_KEY_ID"); v != "" {
+		config.Web3.JWTSigning.ActiveKeyID = v
+	}
+	if v := os.Getenv("WEBDAV_JWT_ACCEPT_HS256"); v != "" {
+		config.Web3.JWTSigning.AcceptHS256 = parseEnvBool(v)
+	}
 	if v := os.Getenv("WEBDAV_UCAN_ENABLED"); v != "" {
 		switch strings.ToLower(strings.TrimSpace(v)) {
 		case "1", "true", "yes", "on":
@@ -119,9 +141,117 @@ func (l *Loader) overrideFromEnv(config *Config) {
 	if v := os.Getenv("WEBDAV_UCAN_APP_SCOPE_PATH_PREFIX"); v != "" {
 		config.Web3.UCAN.AppScope.PathPrefix = v
 	}
+	if v := os.Getenv("WEBDAV_SIWE_DOMAIN"); v != "" {
+		config.Web3.SIWE.Domain = v
+	}
+	if v := os.Getenv("WEBDAV_SIWE_URI"); v != "" {
+		config.Web3.SIWE.URI = v
+	}
+	if v := os.Getenv("WEBDAV_SIWE_SOLANA_CHAINS"); v != "" {
+		config.Web3.SIWE.SolanaChains = strings.Split(v, ",")
+	}
+	if v := os.Getenv("WEBDAV_SIWE_LEGACY_MESSAGE"); v != "" {
+		config.Web3.SIWE.LegacyMessage = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_SMART_WALLET_ENABLED"); v != "" {
+		config.Web3.SmartWallet.Enabled = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_TOKEN_GATE_ENABLED"); v != "" {
+		config.Web3.TokenGate.Enabled = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_TRUSTED_PROXIES"); v != "" {
+		config.Security.TrustedProxies = strings.Split(v, ",")
+	}
 	if v := os.Getenv("WEBDAV_ADMIN_ADDRESSES"); v != "" {
 		config.Security.AdminAddresses = strings.Split(v, ",")
 	}
+	if v := os.Getenv("WEBDAV_WEBAUTHN_ENABLED"); v != "" {
+		config.WebAuthn.Enabled = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_WEBAUTHN_RP_ID"); v != "" {
+		config.WebAuthn.RPID = v
+	}
+	if v := os.Getenv("WEBDAV_WEBAUTHN_ORIGINS"); v != "" {
+		config.WebAuthn.Origins = strings.Split(v, ",")
+	}
+	if v := os.Getenv("WEBDAV_WEBAUTHN_AUTO_CREATE_ON_REGISTER"); v != "" {
+		config.WebAuthn.AutoCreateOnRegister = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_MFA_ISSUER"); v != "" {
+		config.Security.MFA.Issuer = v
+	}
+	if v := os.Getenv("WEBDAV_MFA_REQUIRE_FOR_ADMINS"); v != "" {
+		config.Security.MFA.RequireForAdmins = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_RATE_LIMIT_ENABLED"); v != "" {
+		config.Security.RateLimit.Enabled = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_LOCKOUT_ENABLED"); v != "" {
+		config.Security.RateLimit.Lockout.Enabled = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_AUDIT_ENABLED"); v != "" {
+		config.Audit.Enabled = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_AUDIT_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Audit.Retention = d
+		}
+	}
+	if v := os.Getenv("WEBDAV_METRICS_ENABLED"); v != "" {
+		config.Metrics.Enabled = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_METRICS_ADDRESS"); v != "" {
+		config.Metrics.Address = v
+	}
+	if v := os.Getenv("WEBDAV_METRICS_USERNAME"); v != "" {
+		config.Metrics.Username = v
+	}
+	if v := os.Getenv("WEBDAV_METRICS_PASSWORD"); v != "" {
+		config.Metrics.Password = v
+	}
+	if v := os.Getenv("WEBDAV_TRACING_ENABLED"); v != "" {
+		config.Tracing.Enabled = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_TRACING_EXPORTER"); v != "" {
+		config.Tracing.Exporter = v
+	}
+	if v := os.Getenv("WEBDAV_TRACING_ENDPOINT"); v != "" {
+		config.Tracing.Endpoint = v
+	}
+	if v := os.Getenv("WEBDAV_TRACING_SAMPLE_RATIO"); v != "" {
+		if ratio, err := strconv.ParseFloat(v, 64); err == nil {
+			config.Tracing.SampleRatio = ratio
+		}
+	}
+	if v := os.Getenv("WEBDAV_HEALTH_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Health.Timeout = d
+		}
+	}
+	if v := os.Getenv("WEBDAV_HEALTH_MIN_FREE_BYTES"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			config.Health.MinFreeBytes = n
+		}
+	}
+
+	if v := os.Getenv("WEBDAV_DEDUP_ENABLED"); v != "" {
+		config.WebDAV.Dedup.Enabled = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_DEDUP_DIRECTORY"); v != "" {
+		config.WebDAV.Dedup.Directory = v
+	}
+	if v := os.Getenv("WEBDAV_DEDUP_QUOTA_POLICY"); v != "" {
+		config.WebDAV.Dedup.QuotaPolicy = v
+	}
+	if v := os.Getenv("WEBDAV_ENCRYPTION_ENABLED"); v != "" {
+		config.WebDAV.Encryption.Enabled = parseEnvBool(v)
+	}
+	if v := os.Getenv("WEBDAV_ENCRYPTION_MASTER_KEY"); v != "" {
+		config.WebDAV.Encryption.MasterKey = v
+	}
+	if v := os.Getenv("WEBDAV_ENCRYPTION_MASTER_KEY_FILE"); v != "" {
+		config.WebDAV.Encryption.MasterKeyFile = v
+	}
 
 	if v := os.Getenv("WEBDAV_EMAIL_ENABLED"); v != "" {
 		config.Email.Enabled = parseEnvBool(v)
@@ -173,6 +303,55 @@ func (l *Loader) overrideFromEnv(config *Config) {
 	if v := os.Getenv("WEBDAV_EMAIL_INSECURE_SKIP_VERIFY"); v != "" {
 		config.Email.InsecureSkipVerify = parseEnvBool(v)
 	}
+	if v := os.Getenv("WEBDAV_EMAIL_TEMPLATE_DIR"); v != "" {
+		config.Email.TemplateDir = v
+	}
+	if v := os.Getenv("WEBDAV_EMAIL_DEFAULT_LOCALE"); v != "" {
+		config.Email.DefaultLocale = v
+	}
+	if v := os.Getenv("WEBDAV_EMAIL_LINK_BASE_URL"); v != "" {
+		config.Email.LinkBaseURL = v
+	}
+	if v := os.Getenv("WEBDAV_EMAIL_RESET_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Email.ResetTokenTTL = d
+		}
+	}
+	if v := os.Getenv("WEBDAV_EMAIL_VERIFY_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Email.VerifyTokenTTL = d
+		}
+	}
+	if v := os.Getenv("WEBDAV_EMAIL_OUTBOX_POLL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Email.Outbox.PollInterval = d
+		}
+	}
+	if v := os.Getenv("WEBDAV_EMAIL_OUTBOX_BATCH_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Email.Outbox.BatchSize = n
+		}
+	}
+	if v := os.Getenv("WEBDAV_EMAIL_OUTBOX_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			config.Email.Outbox.MaxAttempts = n
+		}
+	}
+	if v := os.Getenv("WEBDAV_EMAIL_OUTBOX_RETRY_BASE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Email.Outbox.RetryBase = d
+		}
+	}
+	if v := os.Getenv("WEBDAV_EMAIL_OUTBOX_RETRY_MAX"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Email.Outbox.RetryMax = d
+		}
+	}
+	if v := os.Getenv("WEBDAV_EMAIL_OUTBOX_SENT_RETENTION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			config.Email.Outbox.SentRetention = d
+		}
+	}
 }
 
 func parseEnvBool(value string) bool {
@@ -200,9 +379,147 @@ func (l *Loader) validate(config *Config) error {
 	if err := l.validateEmail(config); err != nil {
 		return fmt.Errorf("email config: %w", err)
 	}
+	if err := l.validateWebAuthn(config); err != nil {
+		return fmt.Errorf("webauthn config: %w", err)
+	}
 	if err := l.validateDatabase(config); err != nil {
 		return fmt.Errorf("database config: %w", err)
 	}
+	if err := l.validateMFA(config); err != nil {
+		return fmt.Errorf("mfa config: %w", err)
+	}
+	if err := l.validateRateLimit(config); err != nil {
+		return fmt.Errorf("rate limit config: %w", err)
+	}
+	if err := l.validateTrustedProxies(config); err != nil {
+		return fmt.Errorf("security config: %w", err)
+	}
+	if config.Audit.Retention < 0 {
+		return fmt.Errorf("audit config: retention must not be negative")
+	}
+	if err := l.validateMetrics(config); err != nil {
+		return fmt.Errorf("metrics config: %w", err)
+	}
+	if err := l.validateTracing(config); err != nil {
+		return fmt.Errorf("tracing config: %w", err)
+	}
+	if config.Health.Timeout <= 0 {
+		config.Health.Timeout = 3 * time.Second
+	}
+	if config.Health.MinFreeBytes < 0 {
+		return fmt.Errorf("health config: min_free_bytes must not be negative")
+	}
+	if config.Email.MaxAttempts <= 0 {
+		config.Email.MaxAttempts = 5
+	}
+	return nil
+}
+
+// validateMFA 验证两步验证配置
+func (l *Loader) validateMFA(config *Config) error {
+	cfg := &config.Security.MFA
+	cfg.Issuer = strings.TrimSpace(cfg.Issuer)
+	if cfg.Issuer == "" {
+		cfg.Issuer = "Warehouse"
+	}
+	if cfg.ChallengeTTL <= 0 {
+		cfg.ChallengeTTL = 5 * time.Minute
+	}
+	if cfg.ChallengeTTL > time.Hour {
+		return errors.New("challenge_ttl must not exceed 1h")
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.LockoutDuration <= 0 {
+		cfg.LockoutDuration = 15 * time.Minute
+	}
+	return nil
+}
+
+// validateMetrics 校验指标配置
+func (l *Loader) validateMetrics(config *Config) error {
+	if !config.Metrics.Enabled {
+		return nil
+	}
+	if config.Metrics.Path == "" {
+		config.Metrics.Path = "/metrics"
+	}
+	if !strings.HasPrefix(config.Metrics.Path, "/") {
+		return fmt.Errorf("path must start with /")
+	}
+	if (config.Metrics.Username == "") != (config.Metrics.Password == "") {
+		return fmt.Errorf("username and password must be set together")
+	}
+	return nil
+}
+
+// validateTracing 校验链路追踪配置
+func (l *Loader) validateTracing(config *Config) error {
+	cfg := &config.Tracing
+	if !cfg.Enabled {
+		return nil
+	}
+	cfg.Exporter = strings.ToLower(strings.TrimSpace(cfg.Exporter))
+	if cfg.Exporter == "" {
+		cfg.Exporter = "otlp"
+	}
+	switch cfg.Exporter {
+	case "otlp":
+		u, err := url.Parse(cfg.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("endpoint must be an http(s) URL")
+		}
+	case "stdout":
+	default:
+		return fmt.Errorf("exporter must be otlp or stdout")
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return fmt.Errorf("sample_ratio must be between 0 and 1")
+	}
+	if strings.TrimSpace(cfg.ServiceName) == "" {
+		cfg.ServiceName = "warehouse"
+	}
+	return nil
+}
+
+// validateTrustedProxies 校验可信代理列表，每项为 IP 或 CIDR
+func (l *Loader) validateTrustedProxies(config *Config) error {
+	entries := make([]string, 0, len(config.Security.TrustedProxies))
+	for _, raw := range config.Security.TrustedProxies {
+		entry := strings.TrimSpace(raw)
+		if entry == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			return fmt.Errorf("invalid trusted_proxies entry %q", raw)
+		}
+		entries = append(entries, entry)
+	}
+	config.Security.TrustedProxies = entries
+	return nil
+}
+
+// validateRateLimit 验证限流与账户锁定配置
+func (l *Loader) validateRateLimit(config *Config) error {
+	cfg := &config.Security.RateLimit
+	rules := map[string]RateLimitRule{"auth": cfg.Auth, "api": cfg.API, "webdav": cfg.WebDAV}
+	for name, rule := range rules {
+		if rule.IPPerMinute < 0 || rule.IPBurst < 0 || rule.AccountPerMinute < 0 || rule.AccountBurst < 0 {
+			return fmt.Errorf("%s: values must not be negative", name)
+		}
+	}
+	if cfg.Lockout.Enabled {
+		if cfg.Lockout.MaxFailures <= 0 {
+			return errors.New("lockout.max_failures must be positive")
+		}
+		if cfg.Lockout.Duration <= 0 {
+			return errors.New("lockout.duration must be positive")
+		}
+		if cfg.Lockout.MaxDuration < cfg.Lockout.Duration {
+			cfg.Lockout.MaxDuration = cfg.Lockout.Duration
+		}
+	}
 	return nil
 }
 
@@ -251,6 +568,31 @@ func (l *Loader) validateWebDAV(config *Config) error {
 		return errors.New("directory is not a directory")
 	}
 
+	if config.WebDAV.Dedup.Enabled {
+		if config.WebDAV.Dedup.Directory == "" {
+			config.WebDAV.Dedup.Directory = filepath.Join(config.WebDAV.Directory, ".blobs")
+		}
+		policy := strings.ToLower(strings.TrimSpace(config.WebDAV.Dedup.QuotaPolicy))
+		if policy == "" {
+			policy = "full"
+		}
+		switch policy {
+		case "full", "split", "once":
+		default:
+			return fmt.Errorf("dedup.quota_policy must be one of full, split, once")
+		}
+		config.WebDAV.Dedup.QuotaPolicy = policy
+		if config.WebDAV.Dedup.GCInterval < 0 {
+			return errors.New("dedup.gc_interval must be non-negative")
+		}
+	}
+
+	if config.WebDAV.Encryption.Enabled {
+		if strings.TrimSpace(config.WebDAV.Encryption.MasterKey) == "" && config.WebDAV.Encryption.MasterKeyFile == "" {
+			return errors.New("encryption.master_key or encryption.master_key_file is required when encryption is enabled")
+		}
+	}
+
 	return nil
 }
 
@@ -263,6 +605,97 @@ func (l *Loader) validateWeb3(config *Config) error {
 		return errors.New("jwt_secret must be at least 32 characters")
 	}
 
+	signing := &config.Web3.JWTSigning
+	switch strings.ToUpper(strings.TrimSpace(signing.Algorithm)) {
+	case "", "HS256":
+		signing.Algorithm = "HS256"
+	case "EDDSA", "ED25519":
+		signing.Algorithm = "EdDSA"
+	case "ES256":
+		signing.Algorithm = "ES256"
+	default:
+		return fmt.Errorf("jwt_signing.algorithm must be one of HS256, EdDSA, ES256")
+	}
+	if signing.Algorithm != "HS256" && strings.TrimSpace(signing.KeysDir) == "" {
+		return errors.New("jwt_signing.keys_dir is required for asymmetric algorithms")
+	}
+
+	siwe := config.Web3.SIWE
+	for i, ref := range siwe.SolanaChains {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || len(ref) > 32 || strings.ContainsAny(ref, ": ") {
+			return fmt.Errorf("siwe.solana_chains contains invalid reference %q", ref)
+		}
+		config.Web3.SIWE.SolanaChains[i] = ref
+	}
+	// 请求 Host 由客户端控制，不能作为期望的 domain，否则签名可以在其他站点骗取后重放
+	if (!siwe.LegacyMessage || len(siwe.SolanaChains) > 0) && strings.TrimSpace(siwe.Domain) == "" {
+		return errors.New("siwe.domain is required unless legacy_message is enabled")
+	}
+	if !siwe.LegacyMessage {
+		if len(siwe.ChainIDs) == 0 {
+			return errors.New("siwe.chain_ids must not be empty")
+		}
+		for _, id := range siwe.ChainIDs {
+			if id == 0 {
+				return errors.New("siwe.chain_ids must be positive")
+			}
+		}
+		if strings.Contains(siwe.Domain, "/") {
+			return errors.New("siwe.domain must be a host[:port] without scheme or path")
+		}
+		if siwe.URI != "" {
+			u, err := url.Parse(siwe.URI)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return errors.New("siwe.uri must be an absolute URI")
+			}
+		}
+		if siwe.ChallengeTTL <= 0 {
+			return errors.New("siwe.challenge_ttl must be positive")
+		}
+		if siwe.ClockSkew < 0 {
+			return errors.New("siwe.clock_skew must be non-negative")
+		}
+	}
+
+	wallet := config.Web3.SmartWallet
+	if wallet.Enabled {
+		if len(wallet.Chains) == 0 {
+			return errors.New("smart_wallet.chains must not be empty when smart_wallet is enabled")
+		}
+		if err := validateChains("smart_wallet", wallet.Chains); err != nil {
+			return err
+		}
+	}
+
+	gate := config.Web3.TokenGate
+	if gate.Enabled {
+		if gate.CacheTTL < 0 {
+			return errors.New("token_gate.cache_ttl must be non-negative")
+		}
+		if err := validateChains("token_gate", gate.Chains); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateChains 验证链 JSON-RPC 节点列表
+func validateChains(section string, chains []ChainConfig) error {
+	seen := make(map[uint64]struct{}, len(chains))
+	for _, c := range chains {
+		if c.ChainID == 0 {
+			return fmt.Errorf("%s.chains[].chain_id is required", section)
+		}
+		if strings.TrimSpace(c.RPCURL) == "" {
+			return fmt.Errorf("%s.chains[].rpc_url is required for chain %d", section, c.ChainID)
+		}
+		if _, ok := seen[c.ChainID]; ok {
+			return fmt.Errorf("%s.chains has duplicate chain_id %d", section, c.ChainID)
+		}
+		seen[c.ChainID] = struct{}{}
+	}
 	return nil
 }
 
@@ -289,11 +722,87 @@ func (l *Loader) validateEmail(config *Config) error {
 	if config.Email.CodeLength < 4 || config.Email.CodeLength > 10 {
 		return errors.New("code_length must be between 4 and 10")
 	}
-	if config.Email.TemplatePath == "" {
-		return errors.New("template_path is required when email login is enabled")
+	if config.Email.TemplatePath != "" {
+		if _, err := os.Stat(config.Email.TemplatePath); err != nil {
+			return fmt.Errorf("template_path not found: %w", err)
+		}
+	}
+	if config.Email.DefaultLocale == "" {
+		return errors.New("default_locale is required when email is enabled")
+	}
+	if info, err := os.Stat(config.Email.TemplateDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("template_dir not found: %s", config.Email.TemplateDir)
+	}
+	outbox := config.Email.Outbox
+	if outbox.PollInterval <= 0 || outbox.BatchSize <= 0 || outbox.MaxAttempts <= 0 {
+		return errors.New("outbox poll_interval, batch_size and max_attempts must be positive")
 	}
-	if _, err := os.Stat(config.Email.TemplatePath); err != nil {
-		return fmt.Errorf("template_path not found: %w", err)
+	if outbox.RetryBase <= 0 || outbox.RetryMax < outbox.RetryBase {
+		return errors.New("outbox retry_base must be positive and not greater than retry_max")
+	}
+	if outbox.SentRetention < 0 {
+		return errors.New("outbox sent_retention must not be negative")
+	}
+	if config.Email.LinkBaseURL == "" {
+		return nil
+	}
+	// 重置密码与验证邮箱的链接只使用配置的地址，不信任请求的 Host 头
+	u, err := url.Parse(config.Email.LinkBaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("link_base_url must be an absolute http(s) URL: %q", config.Email.LinkBaseURL)
+	}
+	config.Email.LinkBaseURL = strings.TrimRight(config.Email.LinkBaseURL, "/")
+	if config.Email.ResetTokenTTL <= 0 || config.Email.VerifyTokenTTL <= 0 {
+		return errors.New("reset_token_ttl and verify_token_ttl must be positive")
+	}
+	return nil
+}
+
+// validateWebAuthn 验证通行密钥配置
+func (l *Loader) validateWebAuthn(config *Config) error {
+	cfg := &config.WebAuthn
+	if !cfg.Enabled {
+		return nil
+	}
+	cfg.RPID = strings.ToLower(strings.TrimSpace(cfg.RPID))
+	if cfg.RPID == "" || strings.ContainsAny(cfg.RPID, ":/") {
+		return errors.New("rp_id must be a bare domain when webauthn is enabled")
+	}
+	if strings.TrimSpace(cfg.RPName) == "" {
+		cfg.RPName = "Warehouse"
+	}
+	origins := make([]string, 0, len(cfg.Origins))
+	for _, raw := range cfg.Origins {
+		raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("invalid origin: %s", raw)
+		}
+		host := strings.ToLower(u.Hostname())
+		if host != cfg.RPID && !strings.HasSuffix(host, "."+cfg.RPID) {
+			return fmt.Errorf("origin %s is not within rp_id %s", raw, cfg.RPID)
+		}
+		origins = append(origins, u.Scheme+"://"+strings.ToLower(u.Host))
+	}
+	if len(origins) == 0 {
+		return errors.New("origins is required when webauthn is enabled")
+	}
+	cfg.Origins = origins
+	switch strings.ToLower(strings.TrimSpace(cfg.UserVerification)) {
+	case "", "preferred":
+		cfg.UserVerification = "preferred"
+	case "required":
+		cfg.UserVerification = "required"
+	case "discouraged":
+		cfg.UserVerification = "discouraged"
+	default:
+		return fmt.Errorf("invalid user_verification: %s", cfg.UserVerification)
+	}
+	if cfg.ChallengeTTL <= 0 {
+		return errors.New("challenge_ttl must be positive")
 	}
 	return nil
 }
@@ -308,6 +817,10 @@ func (l *Loader) normalizeAdminAddresses(config *Config) {
 
 	for _, raw := range config.Security.AdminAddresses {
 		addr := strings.ToLower(strings.TrimSpace(raw))
+		if key, err := account.Normalize(raw); err == nil {
+			// 支持 CAIP-10 / did:pkh 写法，Solana 地址保留大小写
+			addr = key
+		}
 		if addr == "" {
 			continue
 		}
@@ -321,11 +834,18 @@ func (l *Loader) normalizeAdminAddresses(config *Config) {
 	config.Security.AdminAddresses = normalized
 }
 
-// validateDatabase 验证数据库配置（仅支持 PostgreSQL）
+// validateDatabase 验证数据库配置（PostgreSQL 或 SQLite）
 func (l *Loader) validateDatabase(config *Config) error {
 	t := strings.ToLower(strings.TrimSpace(config.Database.Type))
+	if t == "sqlite" || t == "sqlite3" {
+		config.Database.Type = "sqlite"
+		if strings.TrimSpace(config.Database.Path) == "" {
+			return errors.New("path is required for sqlite")
+		}
+		return nil
+	}
 	if t != "postgres" && t != "postgresql" {
-		return fmt.Errorf("database.type must be 'postgres' or 'postgresql'")
+		return fmt.Errorf("database.type must be 'postgres', 'postgresql' or 'sqlite'")
 	}
 	if config.Database.Host == "" {
 		return errors.New("host is required")
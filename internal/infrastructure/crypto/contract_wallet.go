@@ -0,0 +1,166 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/yeying-community/warehouse/internal/infrastructure/chain"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+)
+
+// erc6492MagicSuffix ERC-6492 包装签名的固定后缀
+var erc6492MagicSuffix = bytes.Repeat([]byte{0x64, 0x92}, 16)
+
+// ErrSmartWalletUnavailable 需要链上校验但未配置对应链的 JSON-RPC 节点
+var ErrSmartWalletUnavailable = errors.New("smart wallet verification unavailable")
+
+// SignatureVerifier 钱包签名验证器
+// 先按 EOA 做 ECDSA 恢复，失败时对已部署的合约钱包调用 EIP-1271 isValidSignature；
+// ERC-6492 包装的签名会在模拟部署后校验，适用于尚未部署的预计算钱包。
+type SignatureVerifier struct {
+	signer       *EthereumSigner
+	clients      map[uint64]*chain.Client
+	defaultChain uint64
+}
+
+// NewSignatureVerifier 创建签名验证器，未启用智能合约钱包时只支持 EOA
+func NewSignatureVerifier(cfg config.SmartWalletConfig) *SignatureVerifier {
+	v := &SignatureVerifier{
+		signer:  NewEthereumSigner(),
+		clients: make(map[uint64]*chain.Client),
+	}
+	if !cfg.Enabled {
+		return v
+	}
+	for _, c := range cfg.Chains {
+		v.clients[c.ChainID] = chain.NewClient(strings.TrimSpace(c.RPCURL), cfg.Timeout)
+		if v.defaultChain == 0 {
+			v.defaultChain = c.ChainID
+		}
+	}
+	return v
+}
+
+// Verify 校验 address 对 message 的个人签名（EIP-191）
+// chainID 为 0 时使用默认链。EOA 签名返回恢复出的公钥，合约钱包返回 nil。
+func (v *SignatureVerifier) Verify(ctx context.Context, chainID uint64, message, signatureHex, address string) ([]byte, error) {
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("%w: invalid address", ErrInvalidSignature)
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(signatureHex, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	hash := v.signer.hashMessage(message)
+
+	if bytes.HasSuffix(signature, erc6492MagicSuffix) {
+		return nil, v.verifyCounterfactual(ctx, chainID, address, hash, signature[:len(signature)-len(erc6492MagicSuffix)])
+	}
+
+	publicKey, eoaErr := v.signer.RecoverPublicKey(message, signatureHex, address)
+	if eoaErr == nil {
+		return publicKey, nil
+	}
+	client := v.client(chainID)
+	if client == nil {
+		return nil, eoaErr
+	}
+	code, err := client.Code(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSmartWalletUnavailable, err)
+	}
+	if len(code) == 0 {
+		return nil, eoaErr
+	}
+	ok, err := client.IsValidSignature(ctx, address, hash, signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSmartWalletUnavailable, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: rejected by wallet contract", ErrInvalidSignature)
+	}
+	return nil, nil
+}
+
+// verifyCounterfactual 校验 ERC-6492 签名：abi.encode(factory, factoryCalldata, signature)
+func (v *SignatureVerifier) verifyCounterfactual(ctx context.Context, chainID uint64, address string, hash common.Hash, wrapped []byte) error {
+	client := v.client(chainID)
+	if client == nil {
+		return fmt.Errorf("%w: no rpc endpoint for chain %d", ErrSmartWalletUnavailable, v.resolveChain(chainID))
+	}
+	factory, factoryCalldata, signature, err := decodeERC6492(wrapped)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	code, err := client.Code(ctx, address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSmartWalletUnavailable, err)
+	}
+	var ok bool
+	if len(code) > 0 {
+		// 钱包已部署，直接使用内层签名
+		ok, err = client.IsValidSignature(ctx, address, hash, signature)
+	} else {
+		ok, err = client.IsValidCounterfactualSignature(ctx, factory, factoryCalldata, address, hash, signature)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSmartWalletUnavailable, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: rejected by wallet contract", ErrInvalidSignature)
+	}
+	return nil
+}
+
+func (v *SignatureVerifier) resolveChain(chainID uint64) uint64 {
+	if chainID == 0 {
+		return v.defaultChain
+	}
+	return chainID
+}
+
+func (v *SignatureVerifier) client(chainID uint64) *chain.Client {
+	if v == nil {
+		return nil
+	}
+	return v.clients[v.resolveChain(chainID)]
+}
+
+// decodeERC6492 解码 (address, bytes, bytes) ABI 元组
+func decodeERC6492(data []byte) (string, []byte, []byte, error) {
+	if len(data) < 96 {
+		return "", nil, nil, fmt.Errorf("erc-6492 payload too short")
+	}
+	factory := "0x" + hex.EncodeToString(data[12:32])
+	calldata, err := abiBytesAt(data, data[32:64])
+	if err != nil {
+		return "", nil, nil, err
+	}
+	signature, err := abiBytesAt(data, data[64:96])
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return factory, calldata, signature, nil
+}
+
+// abiBytesAt 按偏移量读取 ABI 编码的动态 bytes
+func abiBytesAt(data, offsetWord []byte) ([]byte, error) {
+	// 偏移与长度均由调用方控制，只与剩余长度比较，不做可能溢出的加法
+	offset := new(big.Int).SetBytes(offsetWord)
+	if !offset.IsInt64() || offset.Int64() > int64(len(data)-32) {
+		return nil, fmt.Errorf("erc-6492 offset out of range")
+	}
+	start := int(offset.Int64())
+	length := new(big.Int).SetBytes(data[start : start+32])
+	if !length.IsInt64() || length.Int64() > int64(len(data)-start-32) {
+		return nil, fmt.Errorf("erc-6492 length out of range")
+	}
+	return data[start+32 : start+32+int(length.Int64())], nil
+}
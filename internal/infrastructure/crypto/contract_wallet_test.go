@@ -0,0 +1,189 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	gethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+)
+
+const (
+	testWallet         = "0x5afe5afe5afe5afe5afe5afe5afe5afe5afe5afe"
+	testCounterfactual = "0xc0ffeec0ffeec0ffeec0ffeec0ffeec0ffeec0ff"
+	testFactory        = "0xfac7fac7fac7fac7fac7fac7fac7fac7fac7fac7"
+)
+
+var (
+	walletSignature  = []byte("owner-approved-signature")
+	factoryCalldata  = []byte("deploy-wallet-calldata")
+	erc1271Selector  = "1626ba7e"
+	erc1271MagicWord = erc1271Selector + strings.Repeat("0", 56)
+)
+
+// walletChain 本地 JSON-RPC 节点：testWallet 已部署且只接受 walletSignature，
+// 模拟部署 testFactory 的钱包时同样只接受 walletSignature
+type walletChain struct{}
+
+func (walletChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     uint64            `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	reply := func(result string) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}
+
+	switch req.Method {
+	case "eth_getCode":
+		var account string
+		_ = json.Unmarshal(req.Params[0], &account)
+		if strings.EqualFold(account, testWallet) {
+			reply("0x6001")
+			return
+		}
+		reply("0x")
+	case "eth_call":
+		var msg struct {
+			To   string `json:"to"`
+			Data string `json:"data"`
+		}
+		_ = json.Unmarshal(req.Params[0], &msg)
+		data, _ := hex.DecodeString(strings.TrimPrefix(msg.Data, "0x"))
+		accepted := bytes.Contains(data, walletSignature)
+		switch {
+		case strings.EqualFold(msg.To, testWallet) && strings.HasPrefix(msg.Data, "0x"+erc1271Selector) && accepted:
+			reply("0x" + erc1271MagicWord)
+		case msg.To == "" && bytes.Contains(data, factoryCalldata) && accepted:
+			reply("0x" + erc1271MagicWord)
+		default:
+			reply("0x" + strings.Repeat("0", 64))
+		}
+	default:
+		http.Error(w, "unsupported method", http.StatusBadRequest)
+	}
+}
+
+func wrapERC6492(factory string, calldata, signature []byte) string {
+	word := func(v int) []byte { return big.NewInt(int64(v)).FillBytes(make([]byte, 32)) }
+	tail := func(b []byte) []byte {
+		return append(word(len(b)), append(b, make([]byte, (32-len(b)%32)%32)...)...)
+	}
+	addr, _ := hex.DecodeString(strings.TrimPrefix(factory, "0x"))
+	out := append(make([]byte, 12), addr...)
+	first := tail(calldata)
+	out = append(out, word(96)...)
+	out = append(out, word(96+len(first))...)
+	out = append(out, first...)
+	out = append(out, tail(signature)...)
+	out = append(out, erc6492MagicSuffix...)
+	return "0x" + hex.EncodeToString(out)
+}
+
+func TestSignatureVerifierSmartWallets(t *testing.T) {
+	server := httptest.NewServer(walletChain{})
+	defer server.Close()
+	v := NewSignatureVerifier(config.SmartWalletConfig{
+		Enabled: true,
+		Chains:  []config.ChainConfig{{ChainID: 8453, RPCURL: server.URL}},
+	})
+	ctx := context.Background()
+	message := "Sign in to warehouse"
+
+	key, err := gethcrypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	eoa := gethcrypto.PubkeyToAddress(key.PublicKey).Hex()
+	sig, err := gethcrypto.Sign(v.signer.hashMessage(message).Bytes(), key)
+	if err != nil {
+		t.Fatalf("Sign returned error: %v", err)
+	}
+	sig[64] += 27
+	if pub, err := v.Verify(ctx, 0, message, "0x"+hex.EncodeToString(sig), eoa); err != nil || len(pub) != 65 {
+		t.Fatalf("EOA signature: pub=%x err=%v", pub, err)
+	}
+
+	good := "0x" + hex.EncodeToString(walletSignature)
+	if pub, err := v.Verify(ctx, 8453, message, good, testWallet); err != nil || pub != nil {
+		t.Fatalf("EIP-1271 signature: pub=%x err=%v", pub, err)
+	}
+	if _, err := v.Verify(ctx, 8453, message, "0xdeadbeef", testWallet); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected rejected contract signature, got %v", err)
+	}
+	if _, err := v.Verify(ctx, 8453, message, good, testCounterfactual); err == nil {
+		t.Fatalf("expected unwrapped signature for undeployed wallet to be rejected")
+	}
+
+	wrapped := wrapERC6492(testFactory, factoryCalldata, walletSignature)
+	if _, err := v.Verify(ctx, 0, message, wrapped, testCounterfactual); err != nil {
+		t.Fatalf("ERC-6492 signature returned error: %v", err)
+	}
+	if _, err := v.Verify(ctx, 0, message, wrapERC6492(testFactory, factoryCalldata, []byte("forged")), testCounterfactual); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected forged ERC-6492 signature to be rejected, got %v", err)
+	}
+	if _, err := v.Verify(ctx, 0, message, wrapped, testWallet); err != nil {
+		t.Fatalf("ERC-6492 signature for deployed wallet returned error: %v", err)
+	}
+
+	if _, err := v.Verify(ctx, 1, message, wrapped, testCounterfactual); !errors.Is(err, ErrSmartWalletUnavailable) {
+		t.Fatalf("expected unconfigured chain to be unavailable, got %v", err)
+	}
+	eoaOnly := NewSignatureVerifier(config.SmartWalletConfig{})
+	if _, err := eoaOnly.Verify(ctx, 0, message, good, testWallet); err == nil {
+		t.Fatalf("expected contract signature without rpc to be rejected")
+	}
+}
+
+func TestDecodeERC6492RejectsHostileWords(t *testing.T) {
+	word := func(hexValue string) []byte {
+		b, _ := hex.DecodeString(strings.Repeat("0", 64-len(hexValue)) + hexValue)
+		return b
+	}
+	payload := func(words ...[]byte) []byte {
+		return bytes.Join(words, nil)
+	}
+	factory := word(strings.TrimPrefix(testFactory, "0x"))
+	maxInt64 := "7fffffffffffffff"
+
+	cases := map[string][]byte{
+		"length near MaxInt64":   payload(factory, word("60"), word("60"), word(maxInt64)),
+		"length beyond data":     payload(factory, word("60"), word("60"), word("21")),
+		"length above 2^63":      payload(factory, word("60"), word("60"), word("ffffffffffffffff")),
+		"offset near MaxInt64":   payload(factory, word(maxInt64), word("60"), word("0")),
+		"offset past last word":  payload(factory, word("61"), word("60"), word("0")),
+		"offset above 2^256-1":   payload(factory, word(strings.Repeat("f", 64)), word("60"), word("0")),
+		"signature length wraps": payload(factory, word("60"), word("80"), word("0"), word("7fffffffffffffe0")),
+	}
+	for name, data := range cases {
+		if _, _, _, err := decodeERC6492(data); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	factoryAddr, calldata, signature, err := decodeERC6492(payload(factory, word("60"), word("80"), word("0"), word("0")))
+	if err != nil || factoryAddr != testFactory || len(calldata) != 0 || len(signature) != 0 {
+		t.Fatalf("valid empty payload rejected: %v", err)
+	}
+}
+
+func FuzzDecodeERC6492(f *testing.F) {
+	raw, _ := hex.DecodeString(strings.TrimPrefix(wrapERC6492(testFactory, []byte{1, 2, 3}, []byte{4, 5}), "0x"))
+	f.Add(raw)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _, _, _ = decodeERC6492(data)
+	})
+}
@@ -26,17 +26,23 @@ func NewEthereumSigner() *EthereumSigner {
 
 // VerifySignature 验证以太坊签名
 func (s *EthereumSigner) VerifySignature(message, signatureHex, expectedAddress string) error {
+	_, err := s.RecoverPublicKey(message, signatureHex, expectedAddress)
+	return err
+}
+
+// RecoverPublicKey 验证以太坊签名并返回恢复出的公钥（65 字节未压缩格式）
+func (s *EthereumSigner) RecoverPublicKey(message, signatureHex, expectedAddress string) ([]byte, error) {
 	// 移除 0x 前缀
 	signatureHex = strings.TrimPrefix(signatureHex, "0x")
 	
 	// 解码签名
 	signature, err := hex.DecodeString(signatureHex)
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
 	}
 	
 	if len(signature) != 65 {
-		return ErrInvalidSignatureLength
+		return nil, ErrInvalidSignatureLength
 	}
 	
 	// 调整 v 值（MetaMask 等钱包会加 27）
@@ -50,7 +56,7 @@ func (s *EthereumSigner) VerifySignature(message, signatureHex, expectedAddress
 	// 恢复公钥
 	pubKey, err := crypto.SigToPub(hash.Bytes(), signature)
 	if err != nil {
-		return fmt.Errorf("failed to recover public key: %w", err)
+		return nil, fmt.Errorf("failed to recover public key: %w", err)
 	}
 	
 	// 从公钥生成地址
@@ -58,11 +64,11 @@ func (s *EthereumSigner) VerifySignature(message, signatureHex, expectedAddress
 	
 	// 比较地址
 	if !strings.EqualFold(recoveredAddress.Hex(), expectedAddress) {
-		return fmt.Errorf("%w: expected %s, got %s", 
+		return nil, fmt.Errorf("%w: expected %s, got %s", 
 			ErrSignatureMismatch, expectedAddress, recoveredAddress.Hex())
 	}
 	
-	return nil
+	return crypto.FromECDSAPub(pubKey), nil
 }
 
 // hashMessage 哈希消息（以太坊签名消息格式）
@@ -76,3 +82,12 @@ func (s *EthereumSigner) IsValidAddress(address string) bool {
 	return common.IsHexAddress(address)
 }
 
+
+// PublicKeyToAddress 由未压缩公钥计算以太坊地址
+func PublicKeyToAddress(publicKey []byte) (string, error) {
+	pubKey, err := crypto.UnmarshalPubkey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pubKey).Hex(), nil
+}
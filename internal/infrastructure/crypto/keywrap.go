@@ -0,0 +1,110 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DataKeySize 数据密钥与主密钥长度（AES-256）
+const DataKeySize = 32
+
+var (
+	ErrInvalidMasterKey = errors.New("invalid master key")
+	ErrKeyUnwrapFailed  = errors.New("failed to unwrap key")
+)
+
+// GenerateDataKey 生成随机数据密钥
+func GenerateDataKey() ([]byte, error) {
+	key := make([]byte, DataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	return key, nil
+}
+
+// WrapKey 使用主密钥加密数据密钥（AES-GCM），aad 绑定密钥归属
+// 输出格式：nonce || ciphertext
+func WrapKey(master, key, aad []byte) ([]byte, error) {
+	aead, err := newKeyAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, key, aad), nil
+}
+
+// UnwrapKey 使用主密钥解密数据密钥
+func UnwrapKey(master, wrapped, aad []byte) ([]byte, error) {
+	aead, err := newKeyAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrKeyUnwrapFailed
+	}
+	nonce, ciphertext := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	key, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrKeyUnwrapFailed
+	}
+	return key, nil
+}
+
+// MasterKeyID 返回主密钥标识（SHA-256 前 8 字节），用于识别数据密钥由哪个主密钥加密
+func MasterKeyID(master []byte) string {
+	sum := sha256.Sum256(master)
+	return hex.EncodeToString(sum[:8])
+}
+
+// LoadMasterKey 从配置值或密钥文件加载主密钥
+// 密钥为 32 字节，使用十六进制或 base64 编码。
+func LoadMasterKey(value, file string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if value == "" && file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read master key file: %w", err)
+		}
+		value = strings.TrimSpace(string(data))
+	}
+	if value == "" {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidMasterKey)
+	}
+	return ParseMasterKey(value)
+}
+
+// ParseMasterKey 解析十六进制或 base64 编码的主密钥
+func ParseMasterKey(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if key, err := hex.DecodeString(value); err == nil && len(key) == DataKeySize {
+		return key, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := enc.DecodeString(value); err == nil && len(key) == DataKeySize {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: must be %d bytes encoded as hex or base64", ErrInvalidMasterKey, DataKeySize)
+}
+
+func newKeyAEAD(master []byte) (cipher.AEAD, error) {
+	if len(master) != DataKeySize {
+		return nil, ErrInvalidMasterKey
+	}
+	block, err := aes.NewCipher(master)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,35 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/yeying-community/warehouse/internal/domain/account"
+)
+
+// VerifySolanaSignature 校验 Solana 钱包对 message 原文的 ed25519 签名
+// address 为 base58 公钥；签名支持 base58（钱包默认输出）或 0x 开头的十六进制。
+// 成功时返回公钥。
+func VerifySolanaSignature(message, signature, address string) ([]byte, error) {
+	publicKey, err := account.DecodeBase58(address)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: invalid solana address", ErrInvalidSignature)
+	}
+
+	var sig []byte
+	if rest, ok := strings.CutPrefix(signature, "0x"); ok {
+		sig, err = hex.DecodeString(rest)
+	} else {
+		sig, err = account.DecodeBase58(signature)
+	}
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: malformed ed25519 signature", ErrInvalidSignature)
+	}
+
+	if !ed25519.Verify(publicKey, []byte(message), sig) {
+		return nil, ErrInvalidSignature
+	}
+	return publicKey, nil
+}
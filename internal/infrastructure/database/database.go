@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+)
+
+// 数据库类型
+const (
+	TypePostgres = "postgres"
+	TypeSQLite   = "sqlite"
+)
+
+// Database 已打开的数据库连接，PostgreSQL 与 SQLite 共用迁移、就绪检查与连接池指标
+type Database interface {
+	// SQL 返回底层连接池
+	SQL() *sql.DB
+	// Type 返回数据库类型（TypePostgres / TypeSQLite）
+	Type() string
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (current, latest int64, err error)
+	Migrate(ctx context.Context) error
+	Close() error
+}
+
+// NormalizeType 返回配置中数据库类型对应的 TypePostgres / TypeSQLite，不支持时返回空字符串
+func NormalizeType(t string) string {
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case "postgres", "postgresql":
+		return TypePostgres
+	case "sqlite", "sqlite3":
+		return TypeSQLite
+	}
+	return ""
+}
+
+// Open 按 database.type 打开数据库
+func Open(cfg config.DatabaseConfig) (Database, error) {
+	switch NormalizeType(cfg.Type) {
+	case TypePostgres:
+		db, err := NewPostgresDB(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	case TypeSQLite:
+		db, err := NewSQLiteDB(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+	return nil, fmt.Errorf("unsupported database type %q: postgres/postgresql or sqlite is supported", cfg.Type)
+}
+
+// schemaVersion 返回数据库当前迁移版本与程序内嵌的最新版本
+func schemaVersion(ctx context.Context, db *sql.DB, dbType string) (current, latest int64, err error) {
+	migrator, err := NewMigrator(db, dbType)
+	if err != nil {
+		return 0, 0, err
+	}
+	current, err = migrator.Version(ctx)
+	return current, migrator.Latest(), err
+}
+
+// migrate 执行全部未执行的迁移
+func migrate(ctx context.Context, db *sql.DB, dbType string) error {
+	migrator, err := NewMigrator(db, dbType)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(ctx)
+	return err
+}
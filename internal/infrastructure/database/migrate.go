@@ -0,0 +1,349 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 每种数据库一套迁移目录，版本号与名称保持一致
+//
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var embeddedMigrations embed.FS
+
+// migrationLockID 迁移使用的 PostgreSQL 会话级咨询锁，多个实例同时启动时串行执行
+const migrationLockID int64 = 0x77617265686f7573 // "warehous"
+
+// migrationDialect 迁移在不同数据库上的差异
+type migrationDialect struct {
+	dir         string
+	tableExists string // 查询 schema_migrations 是否存在
+	// advisoryLock 是否使用 PostgreSQL 咨询锁；SQLite 只支持单节点，
+	// 依赖 BEGIN IMMEDIATE 写锁与事务内的版本复查避免重复执行
+	advisoryLock bool
+}
+
+var migrationDialects = map[string]migrationDialect{
+	TypePostgres: {
+		dir:          "migrations/postgres",
+		tableExists:  `SELECT to_regclass('schema_migrations') IS NOT NULL`,
+		advisoryLock: true,
+	},
+	TypeSQLite: {
+		dir:         "migrations/sqlite",
+		tableExists: `SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`,
+	},
+}
+
+// migrationFilePattern 迁移文件名：<版本>_<名称>.<up|down>.sql，如 0002_add_column.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration 一个版本的迁移脚本
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus 迁移执行状态
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// LoadMigrations 读取目录下的迁移文件并按版本排序；每个版本必须同时有 up 与 down 脚本
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version: %s", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names: %s, %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down scripts", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrator 版本化迁移执行器，已执行的版本记录在 schema_migrations 表
+type Migrator struct {
+	db         *sql.DB
+	dialect    migrationDialect
+	migrations []Migration
+}
+
+// NewMigrator 使用 dbType（TypePostgres / TypeSQLite）对应的内嵌迁移脚本创建执行器
+func NewMigrator(db *sql.DB, dbType string) (*Migrator, error) {
+	dialect, ok := migrationDialects[dbType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database type for migrations: %s", dbType)
+	}
+	migrations, err := LoadMigrations(embeddedMigrations, dialect.dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// Latest 返回内嵌迁移的最新版本
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version 返回数据库当前版本（已执行的最大版本），未迁移时为 0
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	exists, err := m.tableExists(ctx)
+	if err != nil || !exists {
+		return 0, err
+	}
+	var version sql.NullInt64
+	if err := m.db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to query schema version: %w", err)
+	}
+	return version.Int64, nil
+}
+
+func (m *Migrator) tableExists(ctx context.Context) (bool, error) {
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, m.dialect.tableExists).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check schema_migrations: %w", err)
+	}
+	return exists, nil
+}
+
+// Status 返回每个内嵌迁移的执行状态
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied := make(map[int64]time.Time)
+	exists, err := m.tableExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var version int64
+			var appliedAt time.Time
+			if err := rows.Scan(&version, &appliedAt); err != nil {
+				return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+			}
+			applied[version] = appliedAt
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+		}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Up 依次执行全部未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.UpTo(ctx, m.Latest())
+}
+
+// UpTo 执行版本不大于 target 的未执行迁移
+func (m *Migrator) UpTo(ctx context.Context, target int64) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if migration.Version > target || applied[migration.Version] {
+				continue
+			}
+			ok, err := m.apply(ctx, conn, migration, true)
+			if err != nil {
+				return err
+			}
+			if ok {
+				done = append(done, migration)
+			}
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 按版本倒序回滚最近 steps 个已执行的迁移，返回本次回滚的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, errors.New("steps must be positive")
+	}
+	var done []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if !applied[migration.Version] {
+				continue
+			}
+			ok, err := m.apply(ctx, conn, migration, false)
+			if err != nil {
+				return err
+			}
+			if ok {
+				done = append(done, migration)
+			}
+		}
+		return nil
+	})
+	return done, err
+}
+
+// withLock 在持有咨询锁的专用连接上执行 fn；会话级锁须在同一连接上加锁与释放
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.dialect.advisoryLock {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+// apply 在单独事务中执行一个迁移并更新版本记录，失败时整体回滚；
+// 版本状态已被其他实例改变时跳过并返回 false
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool) (bool, error) {
+	direction, script := "up", migration.Up
+	if !up {
+		direction, script = "down", migration.Down
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 其他实例可能已在等待锁期间执行过该版本
+	var applied bool
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) > 0 FROM schema_migrations WHERE version = $1`,
+		migration.Version).Scan(&applied); err != nil {
+		return false, fmt.Errorf("failed to check migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	if applied == up {
+		return false, nil
+	}
+
+	// 不带参数的 Exec 一次执行脚本中的多条语句（PostgreSQL 走简单查询协议）
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return false, fmt.Errorf("migration %04d_%s %s failed: %w", migration.Version, migration.Name, direction, err)
+	}
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, time.Now())
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit migration %04d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return true, nil
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
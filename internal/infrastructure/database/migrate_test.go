@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+)
+
+func TestLoadMigrationsSortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0010_add_index.up.sql":   {Data: []byte("CREATE INDEX i ON t(c);")},
+		"m/0010_add_index.down.sql": {Data: []byte("DROP INDEX i;")},
+		"m/0002_create_t.up.sql":    {Data: []byte("CREATE TABLE t (c INT);")},
+		"m/0002_create_t.down.sql":  {Data: []byte("DROP TABLE t;")},
+	}
+	migrations, err := LoadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 2 || migrations[0].Name != "create_t" || migrations[1].Version != 10 {
+		t.Fatalf("unexpected order: %+v", migrations)
+	}
+	if migrations[1].Down != "DROP INDEX i;" {
+		t.Fatalf("unexpected down script: %q", migrations[1].Down)
+	}
+}
+
+func TestLoadMigrationsRejectsInvalidFiles(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"m/0001_a.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"bad name": {
+			"m/first.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"conflicting names": {
+			"m/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/0001_b.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range cases {
+		if _, err := LoadMigrations(fsys, "m"); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, dbType := range []string{TypePostgres, TypeSQLite} {
+		migrator, err := NewMigrator(nil, dbType)
+		if err != nil {
+			t.Fatalf("%s: NewMigrator: %v", dbType, err)
+		}
+		if migrator.migrations[0].Version != 1 || migrator.migrations[0].Name != "baseline" {
+			t.Fatalf("%s: first migration must be the baseline, got %04d_%s", dbType, migrator.migrations[0].Version, migrator.migrations[0].Name)
+		}
+	}
+
+	// 两种数据库的迁移版本与名称必须一一对应
+	pg, _ := NewMigrator(nil, TypePostgres)
+	lite, _ := NewMigrator(nil, TypeSQLite)
+	if len(pg.migrations) != len(lite.migrations) {
+		t.Fatalf("postgres has %d migrations, sqlite has %d", len(pg.migrations), len(lite.migrations))
+	}
+	for i := range pg.migrations {
+		if pg.migrations[i].Version != lite.migrations[i].Version || pg.migrations[i].Name != lite.migrations[i].Name {
+			t.Fatalf("migration mismatch: postgres %04d_%s, sqlite %04d_%s",
+				pg.migrations[i].Version, pg.migrations[i].Name, lite.migrations[i].Version, lite.migrations[i].Name)
+		}
+	}
+}
+
+func TestSQLiteMigrateUpDown(t *testing.T) {
+	db, err := NewSQLiteDB(config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "warehouse.db")})
+	if err != nil {
+		t.Fatalf("NewSQLiteDB: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator, err := NewMigrator(db.DB, TypeSQLite)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != len(migrator.migrations) {
+		t.Fatalf("expected %d applied migrations, got %d", len(migrator.migrations), len(applied))
+	}
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("second Up should be a no-op, got %d applied, err=%v", len(applied), err)
+	}
+	current, latest, err := db.SchemaVersion(ctx)
+	if err != nil || current != latest {
+		t.Fatalf("SchemaVersion = %d/%d, err=%v", current, latest, err)
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range statuses {
+		if !s.Applied || s.AppliedAt.IsZero() {
+			t.Fatalf("migration %04d_%s should be applied: %+v", s.Version, s.Name, s)
+		}
+	}
+
+	reverted, err := migrator.Down(ctx, len(statuses))
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if len(reverted) != len(statuses) {
+		t.Fatalf("expected %d reverted migrations, got %d", len(statuses), len(reverted))
+	}
+	if current, err := migrator.Version(ctx); err != nil || current != 0 {
+		t.Fatalf("Version after Down = %d, err=%v", current, err)
+	}
+	var tables int
+	if err := db.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'`).Scan(&tables); err != nil || tables != 0 {
+		t.Fatalf("users table should be dropped, count=%d err=%v", tables, err)
+	}
+}
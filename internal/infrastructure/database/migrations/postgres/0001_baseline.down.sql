@@ -0,0 +1,25 @@
+-- 回滚基线迁移：删除全部业务表（数据不可恢复）
+
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+DROP FUNCTION IF EXISTS update_updated_at_column();
+
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS user_webauthn_credentials;
+DROP TABLE IF EXISTS user_mfa;
+DROP TABLE IF EXISTS user_app_passwords;
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS e2ee_key_envelopes;
+DROP TABLE IF EXISTS e2ee_folders;
+DROP TABLE IF EXISTS wallet_public_keys;
+DROP TABLE IF EXISTS user_data_keys;
+DROP TABLE IF EXISTS file_metadata;
+DROP TABLE IF EXISTS address_contacts;
+DROP TABLE IF EXISTS address_groups;
+DROP TABLE IF EXISTS share_user_items;
+DROP TABLE IF EXISTS share_items;
+DROP TABLE IF EXISTS recycle_items;
+DROP TABLE IF EXISTS user_rules;
+DROP TABLE IF EXISTS users;
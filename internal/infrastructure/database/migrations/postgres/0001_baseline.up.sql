@@ -0,0 +1,365 @@
+-- 基线迁移：版本化迁移引入前 Migrate 中的全部语句。
+-- 语句均可重复执行，已有数据库首次迁移时会被直接登记为版本 1。
+
+-- 创建用户表
+CREATE TABLE IF NOT EXISTS users (
+	id VARCHAR(50) PRIMARY KEY,
+	username VARCHAR(255) UNIQUE NOT NULL,
+	password TEXT,
+	wallet_address VARCHAR(255) UNIQUE,
+	email VARCHAR(255) UNIQUE,
+	directory TEXT NOT NULL,
+	permissions VARCHAR(10) NOT NULL DEFAULT 'R',
+	quota BIGINT NOT NULL DEFAULT 1073741824,
+	used_space BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 兼容旧表结构：新增 email 字段
+ALTER TABLE IF EXISTS users
+	ADD COLUMN IF NOT EXISTS email VARCHAR(255);
+
+-- 创建用户规则表
+CREATE TABLE IF NOT EXISTS user_rules (
+	id SERIAL PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	path TEXT NOT NULL,
+	permissions VARCHAR(10) NOT NULL,
+	regex BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 创建回收站表
+CREATE TABLE IF NOT EXISTS recycle_items (
+	id VARCHAR(50) PRIMARY KEY,
+	hash VARCHAR(50) UNIQUE NOT NULL,
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	username VARCHAR(255) NOT NULL,
+	directory TEXT NOT NULL,
+	name TEXT NOT NULL,
+	path TEXT NOT NULL,
+	size BIGINT NOT NULL DEFAULT 0,
+	deleted_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 创建分享表
+CREATE TABLE IF NOT EXISTS share_items (
+	id VARCHAR(50) PRIMARY KEY,
+	token VARCHAR(50) UNIQUE NOT NULL,
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	username VARCHAR(255) NOT NULL,
+	name TEXT NOT NULL,
+	path TEXT NOT NULL,
+	expires_at TIMESTAMP NULL,
+	view_count BIGINT NOT NULL DEFAULT 0,
+	download_count BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 创建定向分享表（分享给指定用户）
+CREATE TABLE IF NOT EXISTS share_user_items (
+	id VARCHAR(50) PRIMARY KEY,
+	owner_user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	owner_username VARCHAR(255) NOT NULL,
+	target_user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	target_wallet_address VARCHAR(255) NOT NULL,
+	name TEXT NOT NULL,
+	path TEXT NOT NULL,
+	is_dir BOOLEAN NOT NULL DEFAULT FALSE,
+	permissions VARCHAR(10) NOT NULL,
+	expires_at TIMESTAMP NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 好友地址分组
+CREATE TABLE IF NOT EXISTS address_groups (
+	id VARCHAR(50) PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 好友地址
+CREATE TABLE IF NOT EXISTS address_contacts (
+	id VARCHAR(50) PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	group_id VARCHAR(50) NULL REFERENCES address_groups(id) ON DELETE SET NULL,
+	name VARCHAR(255) NOT NULL,
+	wallet_address VARCHAR(255) NOT NULL,
+	tags TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 文件元数据（内容哈希）
+CREATE TABLE IF NOT EXISTS file_metadata (
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	path TEXT NOT NULL,
+	sha256 CHAR(64) NOT NULL,
+	size BIGINT NOT NULL DEFAULT 0,
+	mod_time_ns BIGINT NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (user_id, path)
+);
+
+-- 创建用户数据密钥表（静态加密）
+CREATE TABLE IF NOT EXISTS user_data_keys (
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	version INTEGER NOT NULL,
+	wrapped_key BYTEA NOT NULL,
+	master_key_id VARCHAR(32) NOT NULL,
+	active BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (user_id, version)
+);
+
+-- 钱包公钥（登录签名恢复，供端到端加密使用）
+CREATE TABLE IF NOT EXISTS wallet_public_keys (
+	wallet_address VARCHAR(255) PRIMARY KEY,
+	public_key BYTEA NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 端到端加密目录
+CREATE TABLE IF NOT EXISTS e2ee_folders (
+	id VARCHAR(50) PRIMARY KEY,
+	owner_user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	path TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 端到端加密目录的密钥信封（每个接收方钱包一份）
+CREATE TABLE IF NOT EXISTS e2ee_key_envelopes (
+	folder_id VARCHAR(50) NOT NULL REFERENCES e2ee_folders(id) ON DELETE CASCADE,
+	recipient_wallet VARCHAR(255) NOT NULL,
+	envelope TEXT NOT NULL,
+	created_by VARCHAR(50) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (folder_id, recipient_wallet)
+);
+
+-- 用户登录身份（钱包、邮箱、用户名密码），每个身份只能属于一个用户
+CREATE TABLE IF NOT EXISTS user_identities (
+	id VARCHAR(50) PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	type VARCHAR(20) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (type, subject)
+);
+
+-- 登录会话：一次登录一个会话，refresh token 每次刷新轮换
+CREATE TABLE IF NOT EXISTS user_sessions (
+	id VARCHAR(50) PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	subject_type VARCHAR(20) NOT NULL DEFAULT 'wallet',
+	subject VARCHAR(255) NOT NULL,
+	refresh_jti VARCHAR(64) NOT NULL,
+	device_name VARCHAR(255) NOT NULL DEFAULT '',
+	ip VARCHAR(64) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP,
+	revoke_reason VARCHAR(32) NOT NULL DEFAULT ''
+);
+
+-- 应用专用密码：供 WebDAV 客户端按设备登录，只保存 SHA-256 哈希
+CREATE TABLE IF NOT EXISTS user_app_passwords (
+	id VARCHAR(50) PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(64) NOT NULL,
+	password_hash VARCHAR(64) NOT NULL UNIQUE,
+	read_only BOOLEAN NOT NULL DEFAULT FALSE,
+	paths TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	last_used_at TIMESTAMP,
+	last_used_ip VARCHAR(64) NOT NULL DEFAULT ''
+);
+
+-- 两步验证（TOTP）登记表
+CREATE TABLE IF NOT EXISTS user_mfa (
+	user_id VARCHAR(50) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	secret VARCHAR(64) NOT NULL DEFAULT '',
+	enabled BOOLEAN NOT NULL DEFAULT FALSE,
+	required BOOLEAN NOT NULL DEFAULT FALSE,
+	last_counter BIGINT NOT NULL DEFAULT 0,
+	recovery_codes TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	enabled_at TIMESTAMP
+);
+
+-- 通行密钥（WebAuthn）凭证表
+CREATE TABLE IF NOT EXISTS user_webauthn_credentials (
+	id VARCHAR(1400) PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(64) NOT NULL,
+	public_key BYTEA NOT NULL,
+	algorithm INTEGER NOT NULL,
+	sign_count BIGINT NOT NULL DEFAULT 0,
+	aaguid VARCHAR(36) NOT NULL DEFAULT '',
+	transports TEXT[] NOT NULL DEFAULT '{}',
+	backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+	backup_state BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	last_used_at TIMESTAMP
+);
+
+-- 管理角色表（内置角色启动时写入）
+CREATE TABLE IF NOT EXISTS roles (
+	name VARCHAR(64) PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT '',
+	permissions TEXT[] NOT NULL DEFAULT '{}',
+	built_in BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 用户角色分配表
+CREATE TABLE IF NOT EXISTS user_roles (
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	role VARCHAR(64) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+	granted_by VARCHAR(50) NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (user_id, role)
+);
+
+-- 审计日志表（只追加；不关联 users，账户删除后记录仍保留）
+CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	action VARCHAR(64) NOT NULL,
+	outcome VARCHAR(16) NOT NULL,
+	actor_id VARCHAR(50) NOT NULL DEFAULT '',
+	actor VARCHAR(255) NOT NULL DEFAULT '',
+	ip VARCHAR(64) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	app_id VARCHAR(255) NOT NULL DEFAULT '',
+	target TEXT NOT NULL DEFAULT '',
+	status INT NOT NULL DEFAULT 0,
+	detail TEXT NOT NULL DEFAULT ''
+);
+
+-- 补充回收站内容哈希字段（兼容已存在表）
+ALTER TABLE recycle_items ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64) NOT NULL DEFAULT '';
+
+-- 补充分享表字段（兼容已存在表）
+ALTER TABLE share_items ADD COLUMN IF NOT EXISTS view_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE share_items ADD COLUMN IF NOT EXISTS download_count BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE share_items ADD COLUMN IF NOT EXISTS access_condition TEXT NOT NULL DEFAULT '';
+
+-- 补充定向分享表字段（兼容已存在表）
+ALTER TABLE share_user_items ADD COLUMN IF NOT EXISTS is_dir BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE share_user_items ADD COLUMN IF NOT EXISTS permissions VARCHAR(10) NOT NULL DEFAULT 'R';
+ALTER TABLE share_user_items ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP NULL;
+ALTER TABLE share_user_items ADD COLUMN IF NOT EXISTS access_condition TEXT NOT NULL DEFAULT '';
+
+-- 创建回收站的哈希索引
+CREATE INDEX IF NOT EXISTS idx_recycle_items_hash ON recycle_items(hash);
+
+-- 创建回收站的用户ID索引
+CREATE INDEX IF NOT EXISTS idx_recycle_items_user_id ON recycle_items(user_id);
+
+-- 文件元数据的内容哈希索引
+CREATE INDEX IF NOT EXISTS idx_file_metadata_sha256 ON file_metadata(sha256);
+
+-- 创建分享的 token 索引
+CREATE INDEX IF NOT EXISTS idx_share_items_token ON share_items(token);
+
+-- 创建分享的用户ID索引
+CREATE INDEX IF NOT EXISTS idx_share_items_user_id ON share_items(user_id);
+
+-- 创建定向分享的用户索引
+CREATE INDEX IF NOT EXISTS idx_share_user_items_owner_id ON share_user_items(owner_user_id);
+CREATE INDEX IF NOT EXISTS idx_share_user_items_target_id ON share_user_items(target_user_id);
+CREATE INDEX IF NOT EXISTS idx_share_user_items_target_wallet ON share_user_items(target_wallet_address);
+
+-- 好友地址分组索引
+CREATE INDEX IF NOT EXISTS idx_address_groups_user_id ON address_groups(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_address_groups_user_name ON address_groups(user_id, name);
+
+-- 好友地址索引
+CREATE INDEX IF NOT EXISTS idx_address_contacts_user_id ON address_contacts(user_id);
+CREATE INDEX IF NOT EXISTS idx_address_contacts_group_id ON address_contacts(group_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_address_contacts_user_wallet ON address_contacts(user_id, wallet_address);
+
+-- 兼容已有地址簿表
+ALTER TABLE address_contacts ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- 钱包账户支持 CAIP-10（非 EVM 账户长于 42 字符），EVM 地址统一为小写
+DO $$
+BEGIN
+	IF EXISTS (
+		SELECT 1 FROM information_schema.columns
+		WHERE table_name = 'users' AND column_name = 'wallet_address' AND character_maximum_length < 255
+	) THEN
+		ALTER TABLE users ALTER COLUMN wallet_address TYPE VARCHAR(255);
+	END IF;
+END $$;
+UPDATE users SET wallet_address = LOWER(wallet_address)
+WHERE wallet_address LIKE '0x%' AND wallet_address <> LOWER(wallet_address);
+
+-- 已有账户的钱包、邮箱与密码登记为登录身份
+INSERT INTO user_identities (id, user_id, type, subject, created_at)
+SELECT md5('wallet:' || wallet_address), id, 'wallet', wallet_address, created_at
+FROM users WHERE wallet_address IS NOT NULL AND wallet_address <> ''
+ON CONFLICT (type, subject) DO NOTHING;
+INSERT INTO user_identities (id, user_id, type, subject, created_at)
+SELECT md5('email:' || LOWER(email)), id, 'email', LOWER(email), created_at
+FROM users WHERE email IS NOT NULL AND email <> ''
+ON CONFLICT (type, subject) DO NOTHING;
+INSERT INTO user_identities (id, user_id, type, subject, created_at)
+SELECT md5('password:' || username), id, 'password', username, created_at
+FROM users WHERE password IS NOT NULL AND password <> ''
+ON CONFLICT (type, subject) DO NOTHING;
+
+-- 登录身份索引（每个用户最多一个用户名密码身份）
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_user_password ON user_identities(user_id) WHERE type = 'password';
+
+-- 登录会话索引
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user_id ON user_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_expires_at ON user_sessions(expires_at);
+
+-- 应用专用密码索引
+CREATE INDEX IF NOT EXISTS idx_user_app_passwords_user_id ON user_app_passwords(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_webauthn_credentials_user_id ON user_webauthn_credentials(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_roles_role ON user_roles(role);
+
+-- 审计日志索引
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor_created_at ON audit_log(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
+
+-- 创建钱包地址索引
+CREATE INDEX IF NOT EXISTS idx_users_wallet_address ON users(wallet_address) WHERE wallet_address IS NOT NULL;
+
+-- 创建邮箱索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE email IS NOT NULL;
+
+-- 创建用户名索引
+CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
+
+-- 创建用户规则的用户ID索引
+CREATE INDEX IF NOT EXISTS idx_user_rules_user_id ON user_rules(user_id);
+
+-- 端到端加密目录索引（同一用户同一路径只能登记一次）
+CREATE UNIQUE INDEX IF NOT EXISTS idx_e2ee_folders_owner_path ON e2ee_folders(owner_user_id, path);
+
+-- 创建更新时间触发器函数
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+	NEW.updated_at = NOW();
+	RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+-- 创建用户表的更新时间触发器
+DROP TRIGGER IF EXISTS update_users_updated_at ON users;
+CREATE TRIGGER update_users_updated_at
+BEFORE UPDATE ON users
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,4 @@
+-- 回滚邮箱验证：删除令牌表与验证时间列
+
+DROP TABLE IF EXISTS used_tokens;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,15 @@
+-- 邮箱验证与一次性邮件链接令牌
+
+-- 邮箱验证时间，NULL 表示未验证
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+-- 已使用的一次性令牌（重置密码、验证邮箱），过期后可删除
+CREATE TABLE IF NOT EXISTS used_tokens (
+	jti VARCHAR(64) PRIMARY KEY,
+	purpose VARCHAR(32) NOT NULL,
+	user_id VARCHAR(50) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	expires_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_used_tokens_expires_at ON used_tokens(expires_at);
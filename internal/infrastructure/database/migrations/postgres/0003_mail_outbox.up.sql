@@ -0,0 +1,19 @@
+-- 邮件发件箱：邮件先写入此表，由后台任务发送并按指数退避重试
+
+CREATE TABLE IF NOT EXISTS mail_outbox (
+	id VARCHAR(50) PRIMARY KEY,
+	recipient VARCHAR(255) NOT NULL,
+	template VARCHAR(64) NOT NULL,
+	locale VARCHAR(64) NOT NULL DEFAULT '',
+	data TEXT NOT NULL DEFAULT '{}',
+	status VARCHAR(16) NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	sent_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mail_outbox_due ON mail_outbox(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_mail_outbox_created_at ON mail_outbox(created_at);
@@ -0,0 +1,22 @@
+-- 回滚 CAIP-10 钱包存储键：EVM 账户恢复为小写裸地址
+
+UPDATE users SET wallet_address = SUBSTR(wallet_address, 10)
+WHERE wallet_address LIKE 'eip155:1:0x%';
+
+UPDATE user_identities SET subject = SUBSTR(subject, 10)
+WHERE type = 'wallet' AND subject LIKE 'eip155:1:0x%';
+
+UPDATE user_sessions SET subject = SUBSTR(subject, 10)
+WHERE subject_type = 'wallet' AND subject LIKE 'eip155:1:0x%';
+
+UPDATE share_user_items SET target_wallet_address = SUBSTR(target_wallet_address, 10)
+WHERE target_wallet_address LIKE 'eip155:1:0x%';
+
+UPDATE address_contacts SET wallet_address = SUBSTR(wallet_address, 10)
+WHERE wallet_address LIKE 'eip155:1:0x%';
+
+UPDATE wallet_public_keys SET wallet_address = SUBSTR(wallet_address, 10)
+WHERE wallet_address LIKE 'eip155:1:0x%';
+
+UPDATE e2ee_key_envelopes SET recipient_wallet = SUBSTR(recipient_wallet, 10)
+WHERE recipient_wallet LIKE 'eip155:1:0x%';
@@ -0,0 +1,22 @@
+-- 钱包存储键统一为 CAIP-10：历史数据中的 EVM 裸地址改写为 eip155:1:<小写地址>
+
+UPDATE users SET wallet_address = 'eip155:1:' || LOWER(wallet_address)
+WHERE wallet_address LIKE '0x%' AND LENGTH(wallet_address) = 42;
+
+UPDATE user_identities SET subject = 'eip155:1:' || LOWER(subject)
+WHERE type = 'wallet' AND subject LIKE '0x%' AND LENGTH(subject) = 42;
+
+UPDATE user_sessions SET subject = 'eip155:1:' || LOWER(subject)
+WHERE subject_type = 'wallet' AND subject LIKE '0x%' AND LENGTH(subject) = 42;
+
+UPDATE share_user_items SET target_wallet_address = 'eip155:1:' || LOWER(target_wallet_address)
+WHERE target_wallet_address LIKE '0x%' AND LENGTH(target_wallet_address) = 42;
+
+UPDATE address_contacts SET wallet_address = 'eip155:1:' || LOWER(wallet_address)
+WHERE wallet_address LIKE '0x%' AND LENGTH(wallet_address) = 42;
+
+UPDATE wallet_public_keys SET wallet_address = 'eip155:1:' || LOWER(wallet_address)
+WHERE wallet_address LIKE '0x%' AND LENGTH(wallet_address) = 42;
+
+UPDATE e2ee_key_envelopes SET recipient_wallet = 'eip155:1:' || LOWER(recipient_wallet)
+WHERE recipient_wallet LIKE '0x%' AND LENGTH(recipient_wallet) = 42;
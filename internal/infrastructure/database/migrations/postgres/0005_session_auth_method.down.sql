@@ -0,0 +1,3 @@
+-- 回滚会话登录方式
+
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS auth_method;
@@ -0,0 +1,3 @@
+-- 记录会话的登录方式，被要求启用两步验证的账户只能在钱包或通行密钥会话中登记
+
+ALTER TABLE user_sessions ADD COLUMN auth_method VARCHAR(20) NOT NULL DEFAULT '';
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/tracing"
+	"modernc.org/sqlite"
+)
+
+// sqliteParams SQLite 连接参数：
+//   - 启用外键（ON DELETE CASCADE 依赖）与 WAL，写锁等待最长 5 秒
+//   - 事务以 BEGIN IMMEDIATE 开始，读后写的事务不会因升级写锁失败
+//   - 时间按毫秒时间戳存储，比较与排序与时区无关
+//   - LIKE 区分大小写，与 PostgreSQL 一致
+const sqliteParams = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)" +
+	"&_txlock=immediate&_time_integer_format=unix_milli&_inttotime=1&_pragma=case_sensitive_like(1)"
+
+// SQLiteDB SQLite 数据库连接（单节点部署与测试）
+type SQLiteDB struct {
+	DB *sql.DB
+}
+
+// NewSQLiteDB 打开 cfg.Path 指定的 SQLite 数据库文件，不存在时创建
+func NewSQLiteDB(cfg config.DatabaseConfig) (*SQLiteDB, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("sqlite database path is required")
+	}
+	if dir := filepath.Dir(cfg.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	// 启用链路追踪时为每次 SQL 调用创建跨度
+	connector := &sqliteConnector{dsn: cfg.Path + "?" + sqliteParams, driver: &sqlite.Driver{}}
+	db := tracing.OpenDB(connector, "sqlite")
+
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.MaxLifetime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	return &SQLiteDB{DB: db}, nil
+}
+
+// sqliteConnector 以固定 DSN 打开连接，便于包装追踪
+type sqliteConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c *sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close 关闭数据库连接
+func (s *SQLiteDB) Close() error {
+	return s.DB.Close()
+}
+
+// SQL 返回底层连接池
+func (s *SQLiteDB) SQL() *sql.DB {
+	return s.DB
+}
+
+// Type 返回数据库类型
+func (s *SQLiteDB) Type() string {
+	return TypeSQLite
+}
+
+// Ping 检查数据库连接
+func (s *SQLiteDB) Ping(ctx context.Context) error {
+	return s.DB.PingContext(ctx)
+}
+
+// SchemaVersion 返回数据库当前迁移版本与程序内嵌的最新版本
+func (s *SQLiteDB) SchemaVersion(ctx context.Context) (current, latest int64, err error) {
+	return schemaVersion(ctx, s.DB, TypeSQLite)
+}
+
+// Migrate 执行全部未执行的迁移
+func (s *SQLiteDB) Migrate(ctx context.Context) error {
+	return migrate(ctx, s.DB, TypeSQLite)
+}
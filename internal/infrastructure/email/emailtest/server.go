@@ -0,0 +1,207 @@
+// Package emailtest 提供测试用的本地 SMTP 服务
+// 只实现发送邮件所需的最小命令集（不支持 STARTTLS 与认证），收到的邮件保存在内存中，
+// 可以让接下来的若干次投递返回临时错误，用于测试重试与死信。
+package emailtest
+
+import (
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Message 收到的一封邮件
+type Message struct {
+	From string
+	To   []string
+	Data string // 原始邮件（头与正文）
+}
+
+// Subject 返回解码后的邮件标题
+func (m Message) Subject() string {
+	msg, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		return ""
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return msg.Header.Get("Subject")
+	}
+	return subject
+}
+
+// Body 返回邮件正文
+func (m Message) Body() string {
+	msg, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		return ""
+	}
+	body, _ := io.ReadAll(msg.Body)
+	return string(body)
+}
+
+// Server 本地 SMTP 服务
+type Server struct {
+	Host string
+	Port int
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	messages []Message
+	failures int
+}
+
+// NewServer 在 127.0.0.1 的随机端口上启动 SMTP 服务，测试结束时自动关闭
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start smtp server: %v", err)
+	}
+	addr := listener.Addr().(*net.TCPAddr)
+	s := &Server{
+		Host:     addr.IP.String(),
+		Port:     addr.Port,
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// FailNext 让接下来的 n 次投递在 RCPT 阶段返回 451 临时错误
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	s.failures = n
+	s.mu.Unlock()
+}
+
+// Messages 返回已收到的邮件
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Close 关闭服务与未结束的连接，并等待处理结束
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			s.handle(textproto.NewConn(conn))
+		}()
+	}
+}
+
+// handle 处理一个 SMTP 会话
+func (s *Server) handle(conn *textproto.Conn) {
+	reply := func(format string, args ...any) bool {
+		return conn.PrintfLine(format, args...) == nil
+	}
+	if !reply("220 emailtest ESMTP ready") {
+		return
+	}
+
+	var current Message
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 emailtest")
+		case "MAIL":
+			current = Message{From: addressArg(arg)}
+			reply("250 OK")
+		case "RCPT":
+			if s.takeFailure() {
+				reply("451 4.3.0 temporary failure, try again later")
+				continue
+			}
+			current.To = append(current.To, addressArg(arg))
+			reply("250 OK")
+		case "DATA":
+			if len(current.To) == 0 {
+				reply("503 5.5.1 no valid recipients")
+				continue
+			}
+			if !reply("354 end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := io.ReadAll(conn.DotReader())
+			if err != nil {
+				return
+			}
+			current.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, current)
+			s.mu.Unlock()
+			current = Message{}
+			reply("250 OK")
+		case "RSET":
+			current = Message{}
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 5.5.2 command not implemented")
+		}
+	}
+}
+
+func (s *Server) takeFailure() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return true
+	}
+	return false
+}
+
+// addressArg 从 "FROM:<a@b>" 或 "TO:<a@b>" 中取出地址
+func addressArg(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(strings.TrimSpace(addr), " ")
+	return strings.Trim(addr, "<>")
+}
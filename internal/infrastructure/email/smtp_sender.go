@@ -2,106 +2,243 @@ package email
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"html"
 	"html/template"
 	"mime"
+	"net"
 	"net/smtp"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
 	"go.uber.org/zap"
 )
 
+// codeLoginTemplate 登录验证码模板名称；配置了 template_path 时使用该文件代替模板目录中的模板
+const codeLoginTemplate = "email_code_login"
+
 // Sender SMTP 邮件发送器
 type Sender struct {
-	cfg     config.EmailConfig
-	logger  *zap.Logger
-	tplOnce sync.Once
-	tpl     *template.Template
-	tplErr  error
+	cfg    config.EmailConfig
+	logger *zap.Logger
+	// subject template_path 指定的旧版模板没有定义标题时使用
 	subject string
+
+	// 事务邮件模板缓存，key 为模板文件路径
+	mu        sync.Mutex
+	templates map[string]*template.Template
 }
 
 // NewSender 创建邮件发送器
 func NewSender(cfg config.EmailConfig, logger *zap.Logger) *Sender {
 	return &Sender{
-		cfg:     cfg,
-		logger:  logger,
-		subject: "登录验证码",
+		cfg:       cfg,
+		logger:    logger,
+		subject:   "登录验证码",
+		templates: make(map[string]*template.Template),
+	}
+}
+
+// SendTemplate 渲染事务邮件模板并发送
+// 模板文件为 <template_dir>/<name>_mail_template_<locale>.html，用 {{define "subject"}} 定义邮件标题；
+// locale 可以是 Accept-Language 请求头，找不到对应语言时使用 default_locale。
+func (s *Sender) SendTemplate(to, name, locale string, data map[string]any) error {
+	subject, body, err := s.Render(name, locale, data)
+	if err != nil {
+		return err
+	}
+	return s.Send(to, subject, body)
+}
+
+// Render 渲染事务邮件模板，返回标题与 HTML 正文
+func (s *Sender) Render(name, locale string, data map[string]any) (string, string, error) {
+	legacy := name == codeLoginTemplate && s.cfg.TemplatePath != ""
+	path := filepath.Clean(s.cfg.TemplatePath)
+	if !legacy {
+		var err error
+		if path, err = s.resolveTemplate(name, locale); err != nil {
+			return "", "", err
+		}
+	}
+	tpl, err := s.loadTemplate(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	var subject, body bytes.Buffer
+	switch {
+	case tpl.Lookup("subject") != nil:
+		if err := tpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+			return "", "", err
+		}
+	case legacy:
+		subject.WriteString(s.subject)
+	default:
+		return "", "", fmt.Errorf("template %s does not define a subject", filepath.Base(path))
+	}
+	if err := tpl.Execute(&body, data); err != nil {
+		return "", "", err
 	}
+	return strings.TrimSpace(html.UnescapeString(subject.String())), body.String(), nil
 }
 
-// SendCode 发送登录验证码
-func (s *Sender) SendCode(to string, code string, ttl time.Duration) error {
+// Send 发送 HTML 邮件
+func (s *Sender) Send(to, subject, body string) error {
 	if !s.cfg.Enabled {
-		return errors.New("email login is disabled")
+		return errors.New("email is disabled")
 	}
 	if s.cfg.SMTPHost == "" || s.cfg.From == "" {
 		return errors.New("smtp configuration is incomplete")
 	}
 
-	body, err := s.renderTemplate(map[string]any{
-		"code":      code,
-		"expiresIn": int(ttl.Minutes()),
-	})
+	msg, err := s.buildMessage(to, subject, body)
 	if err != nil {
 		return err
 	}
+	return s.sendSMTP(to, msg)
+}
+
+// Ping 连接 SMTP 服务器并完成握手（不发送邮件），用于就绪检查
+func (s *Sender) Ping(ctx context.Context) error {
+	if s.cfg.SMTPHost == "" {
+		return errors.New("smtp configuration is incomplete")
+	}
+	addr := fmt.Sprintf("%s:%d", s.cfg.SMTPHost, s.cfg.SMTPPort)
 
-	msg, err := s.buildMessage(to, body)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
 		return err
 	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
 
-	return s.sendSMTP(to, msg)
+	if s.cfg.UseTLS {
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         s.cfg.SMTPHost,
+			InsecureSkipVerify: s.cfg.InsecureSkipVerify,
+		})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return err
+		}
+		conn = tlsConn
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.SMTPHost)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Hello("localhost"); err != nil {
+		return err
+	}
+	return client.Quit()
 }
 
-func (s *Sender) renderTemplate(data map[string]any) (string, error) {
-	s.tplOnce.Do(func() {
-		if s.cfg.TemplatePath == "" {
-			s.tplErr = errors.New("template_path is empty")
-			return
+// resolveTemplate 按语言偏好依次查找模板文件：每种语言先完全匹配再按语言前缀匹配，最后使用默认语言
+func (s *Sender) resolveTemplate(name, locale string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.cfg.TemplateDir, name+"_mail_template_*.html"))
+	if err != nil {
+		return "", err
+	}
+	available := make(map[string]string, len(matches))
+	for _, path := range matches {
+		base := strings.TrimSuffix(filepath.Base(path), ".html")
+		available[strings.ToLower(strings.TrimPrefix(base, name+"_mail_template_"))] = path
+	}
+	if len(available) == 0 {
+		return "", fmt.Errorf("no template found for %s in %s", name, s.cfg.TemplateDir)
+	}
+
+	for _, tag := range append(preferredLocales(locale), strings.ToLower(s.cfg.DefaultLocale)) {
+		if path, ok := available[tag]; ok {
+			return path, nil
+		}
+		lang, _, _ := strings.Cut(tag, "-")
+		if path, ok := available[lang]; ok {
+			return path, nil
 		}
-		path := filepath.Clean(s.cfg.TemplatePath)
-		tpl, err := template.ParseFiles(path)
-		if err != nil {
-			s.tplErr = err
-			return
+		// 同一语言的其他地区，取排序最前的一个保证结果稳定
+		var match string
+		for candidate := range available {
+			if candidateLang, _, _ := strings.Cut(candidate, "-"); candidateLang == lang && (match == "" || candidate < match) {
+				match = candidate
+			}
+		}
+		if match != "" {
+			return available[match], nil
 		}
-		s.tpl = tpl
-	})
+	}
+	return "", fmt.Errorf("no %s template for locale %q", name, locale)
+}
 
-	if s.tplErr != nil {
-		return "", s.tplErr
+func (s *Sender) loadTemplate(path string) (*template.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tpl, ok := s.templates[path]; ok {
+		return tpl, nil
 	}
-	if s.tpl == nil {
-		return "", errors.New("template not loaded")
+	tpl, err := template.ParseFiles(path)
+	if err != nil {
+		return nil, err
 	}
+	s.templates[path] = tpl
+	return tpl, nil
+}
 
-	var buf bytes.Buffer
-	if err := s.tpl.Execute(&buf, data); err != nil {
-		return "", err
+// preferredLocales 解析 Accept-Language（如 "en-US,en;q=0.9,zh;q=0.8"），按权重从高到低返回小写的语言标签
+func preferredLocales(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
 	}
-	return buf.String(), nil
+	var items []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(tag, "_", "-")))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			items = append(items, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+
+	tags := make([]string, 0, len(items))
+	for _, item := range items {
+		tags = append(tags, item.tag)
+	}
+	return tags
 }
 
-func (s *Sender) buildMessage(to, body string) ([]byte, error) {
+func (s *Sender) buildMessage(to, subject, body string) ([]byte, error) {
 	from := s.cfg.From
 	if strings.TrimSpace(s.cfg.FromName) != "" {
 		encodedName := mime.QEncoding.Encode("UTF-8", s.cfg.FromName)
 		from = fmt.Sprintf("%s <%s>", encodedName, s.cfg.From)
 	}
-	subject := mime.QEncoding.Encode("UTF-8", s.subject)
-
 	headers := []string{
 		fmt.Sprintf("From: %s", from),
 		fmt.Sprintf("To: %s", to),
-		fmt.Sprintf("Subject: %s", subject),
+		fmt.Sprintf("Subject: %s", mime.QEncoding.Encode("UTF-8", subject)),
 		"MIME-Version: 1.0",
 		"Content-Type: text/html; charset=UTF-8",
 	}
@@ -0,0 +1,68 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"go.uber.org/zap"
+)
+
+func TestRenderResolvesLocale(t *testing.T) {
+	s := NewSender(config.EmailConfig{TemplateDir: "../../../resources/email", DefaultLocale: "zh-CN"}, zap.NewNop())
+	data := map[string]any{"username": "alice", "email": "alice@example.com", "link": "https://example.com/reset?token=a&b", "expiresIn": 30}
+
+	cases := []struct {
+		locale, subject string
+	}{
+		{"en-US,en;q=0.9", "Reset your password"},
+		{"zh;q=0.5, en-GB;q=0.8", "Reset your password"},
+		{"fr-FR", "重置密码"},
+		{"", "重置密码"},
+	}
+	for _, tc := range cases {
+		subject, body, err := s.Render("password_reset", tc.locale, data)
+		if err != nil {
+			t.Fatalf("Render(%q): %v", tc.locale, err)
+		}
+		if subject != tc.subject {
+			t.Fatalf("Render(%q) subject = %q, want %q", tc.locale, subject, tc.subject)
+		}
+		if !strings.Contains(body, "token=a&amp;b") {
+			t.Fatalf("Render(%q) body does not contain the escaped link", tc.locale)
+		}
+	}
+
+	if _, _, err := s.Render("missing", "", data); err == nil {
+		t.Fatalf("expected error for unknown template")
+	}
+}
+
+func TestLoginTemplate(t *testing.T) {
+	data := map[string]any{"code": "123456", "expiresIn": 5}
+
+	s := NewSender(config.EmailConfig{TemplateDir: "../../../resources/email", DefaultLocale: "zh-CN"}, zap.NewNop())
+	subject, body, err := s.Render("email_code_login", "en", data)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if subject != "Your login code" || !strings.Contains(body, "123456") || !strings.Contains(body, "5 minutes") {
+		t.Fatalf("unexpected login mail: %q", subject)
+	}
+
+	// template_path 指定的旧版模板优先，没有定义标题时使用默认标题
+	legacy := filepath.Join(t.TempDir(), "login.html")
+	if err := os.WriteFile(legacy, []byte("<p>{{.code}}</p>"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	s = NewSender(config.EmailConfig{TemplatePath: legacy, TemplateDir: "../../../resources/email", DefaultLocale: "zh-CN"}, zap.NewNop())
+	subject, body, err = s.Render("email_code_login", "en", data)
+	if err != nil {
+		t.Fatalf("Render legacy: %v", err)
+	}
+	if subject != "登录验证码" || body != "<p>123456</p>" {
+		t.Fatalf("unexpected legacy login mail: %q %q", subject, body)
+	}
+}
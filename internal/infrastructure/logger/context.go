@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID 将请求 ID 放入上下文
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID 返回上下文中的请求 ID，没有时返回空字符串
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Ctx 返回附带请求 ID 与链路标识（trace_id / span_id）的日志器，上下文中都没有时原样返回
+func Ctx(ctx context.Context, l *zap.Logger) *zap.Logger {
+	if ctx == nil {
+		return l
+	}
+	fields := make([]zap.Field, 0, 3)
+	if id := RequestID(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields,
+			zap.String("trace_id", sc.TraceID().String()),
+			zap.String("span_id", sc.SpanID().String()))
+	}
+	if len(fields) == 0 {
+		return l
+	}
+	return l.With(fields...)
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"database/sql"
+	"runtime"
+	"time"
+)
+
+// Default 进程级指标注册表，/metrics 接口输出其中的全部指标
+var Default = NewRegistry()
+
+// 应用指标
+var (
+	// HTTPRequests 请求数（route 为路由模式，WebDAV 请求为 webdav）
+	HTTPRequests = Default.NewCounterVec("warehouse_http_requests_total",
+		"HTTP requests by route, method and status code.", "route", "method", "code")
+
+	// HTTPDuration 请求耗时
+	HTTPDuration = Default.NewHistogramVec("warehouse_http_request_duration_seconds",
+		"HTTP request latency by route and method.", nil, "route", "method")
+
+	// UploadBytes 请求体字节数
+	UploadBytes = Default.NewCounterVec("warehouse_http_upload_bytes_total",
+		"Request body bytes received by route.", "route")
+
+	// DownloadBytes 响应体字节数
+	DownloadBytes = Default.NewCounterVec("warehouse_http_download_bytes_total",
+		"Response body bytes sent by route.", "route")
+
+	// AuthAttempts 认证结果（result 为 success / failure）
+	AuthAttempts = Default.NewCounterVec("warehouse_auth_attempts_total",
+		"Authentication attempts by authenticator and result.", "authenticator", "result")
+
+	// QuotaRejections 因配额不足被拒绝的写入
+	QuotaRejections = Default.NewCounterVec("warehouse_quota_rejections_total",
+		"Writes rejected because the storage quota is exhausted.", "source")
+
+	// ShareAccesses 分享访问（type 为 public / user）
+	ShareAccesses = Default.NewCounterVec("warehouse_share_accesses_total",
+		"Successful share downloads by share type.", "type")
+)
+
+var startTime = time.Now()
+
+func init() {
+	Default.NewGaugeFunc("warehouse_uptime_seconds", "Seconds since the process started.", func() float64 {
+		return time.Since(startTime).Seconds()
+	})
+	Default.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+}
+
+// RegisterDBStats 注册数据库连接池指标
+func RegisterDBStats(r *Registry, db *sql.DB) {
+	stats := func(fn func(sql.DBStats) float64) func() float64 {
+		return func() float64 { return fn(db.Stats()) }
+	}
+	r.NewGaugeFunc("warehouse_db_max_open_connections", "Maximum number of open database connections.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	r.NewGaugeFunc("warehouse_db_open_connections", "Established database connections, in use and idle.",
+		stats(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	r.NewGaugeFunc("warehouse_db_in_use_connections", "Database connections currently in use.",
+		stats(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	r.NewGaugeFunc("warehouse_db_idle_connections", "Idle database connections.",
+		stats(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	r.NewCounterFunc("warehouse_db_wait_count_total", "Connections waited for because the pool was exhausted.",
+		stats(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	r.NewCounterFunc("warehouse_db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.",
+		stats(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+}
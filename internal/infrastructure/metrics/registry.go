@@ -0,0 +1,371 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType Prometheus 文本格式的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets 默认的请求耗时直方图桶（秒）
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// collector 指标族
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册表，按注册顺序输出 Prometheus 文本格式
+type Registry struct {
+	mu         sync.Mutex
+	names      map[string]int // 指标名 -> collectors 下标
+	collectors []collector
+}
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]int)}
+}
+
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.names[name]; ok {
+		panic(fmt.Sprintf("metrics: duplicate metric %s", name))
+	}
+	r.names[name] = len(r.collectors)
+	r.collectors = append(r.collectors, c)
+}
+
+// replace 注册或替换同名指标（用于采集函数，组件重建时重新绑定数据源）
+func (r *Registry) replace(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i, ok := r.names[name]; ok {
+		r.collectors[i] = c
+		return
+	}
+	r.names[name] = len(r.collectors)
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo 以 Prometheus 文本格式输出全部指标
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// vec 按标签值组合保存的一组序列
+type vec[T any] struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	newFn  func() T
+
+	mu     sync.RWMutex
+	series map[string]*entry[T]
+}
+
+type entry[T any] struct {
+	values []string
+	metric T
+}
+
+func newVec[T any](name, help, kind string, labels []string, newFn func() T) *vec[T] {
+	return &vec[T]{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		newFn:  newFn,
+		series: make(map[string]*entry[T]),
+	}
+}
+
+func (v *vec[T]) get(values []string) T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.RLock()
+	e, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return e.metric
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if e, ok := v.series[key]; ok {
+		return e.metric
+	}
+	e = &entry[T]{values: append([]string(nil), values...), metric: v.newFn()}
+	v.series[key] = e
+	return e.metric
+}
+
+// sorted 返回按标签值排序的序列，保证输出稳定
+func (v *vec[T]) sorted() []*entry[T] {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]*entry[T], 0, len(keys))
+	for _, k := range keys {
+		out = append(out, v.series[k])
+	}
+	return out
+}
+
+func (v *vec[T]) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, v.kind)
+}
+
+// value 原子更新的浮点数
+type value struct {
+	mu sync.Mutex
+	v  float64
+}
+
+func (x *value) add(delta float64) {
+	x.mu.Lock()
+	x.v += delta
+	x.mu.Unlock()
+}
+
+func (x *value) set(v float64) {
+	x.mu.Lock()
+	x.v = v
+	x.mu.Unlock()
+}
+
+func (x *value) get() float64 {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.v
+}
+
+// CounterVec 只增计数器
+type CounterVec struct {
+	vec *vec[*value]
+}
+
+// NewCounterVec 注册计数器
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, "counter", labels, func() *value { return &value{} })}
+	r.register(name, c)
+	return c
+}
+
+// Inc 计数加一
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.vec.get(labelValues).add(1)
+}
+
+// Add 计数增加 delta（负数被忽略）
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta <= 0 {
+		return
+	}
+	c.vec.get(labelValues).add(delta)
+}
+
+// Value 读取当前计数
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	return c.vec.get(labelValues).get()
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.vec.writeHeader(w)
+	for _, e := range c.vec.sorted() {
+		writeSample(w, c.vec.name, c.vec.labels, e.values, "", "", e.metric.get())
+	}
+}
+
+// GaugeVec 可增可减的仪表
+type GaugeVec struct {
+	vec *vec[*value]
+}
+
+// NewGaugeVec 注册仪表
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{vec: newVec(name, help, "gauge", labels, func() *value { return &value{} })}
+	r.register(name, g)
+	return g
+}
+
+// Set 设置仪表值
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	g.vec.get(labelValues).set(v)
+}
+
+// Add 仪表值增加 delta
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.vec.get(labelValues).add(delta)
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.vec.writeHeader(w)
+	for _, e := range g.vec.sorted() {
+		writeSample(w, g.vec.name, g.vec.labels, e.values, "", "", e.metric.get())
+	}
+}
+
+// gaugeFunc 采集时调用函数取值的仪表
+type gaugeFunc struct {
+	name string
+	help string
+	kind string
+	fn   func() float64
+}
+
+// NewGaugeFunc 注册采集时取值的仪表；同名指标已存在时替换其取值函数
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.replace(name, &gaugeFunc{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+// NewCounterFunc 注册采集时取值的计数器（如连接池累计等待次数）；同名指标已存在时替换其取值函数
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.replace(name, &gaugeFunc{name: name, help: help, kind: "counter", fn: fn})
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", g.name, escapeHelp(g.help), g.name, g.kind)
+	writeSample(w, g.name, nil, nil, "", "", g.fn())
+}
+
+// histogram 单个直方图序列
+type histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// HistogramVec 直方图
+type HistogramVec struct {
+	vec     *vec[*histogram]
+	buckets []float64
+}
+
+// NewHistogramVec 注册直方图；buckets 为空时使用 DefaultBuckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{buckets: buckets}
+	h.vec = newVec(name, help, "histogram", labels, func() *histogram {
+		return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+	})
+	r.register(name, h)
+	return h
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	s := h.vec.get(labelValues)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, upper := range s.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.vec.writeHeader(w)
+	for _, e := range h.vec.sorted() {
+		s := e.metric
+		s.mu.Lock()
+		counts := append([]uint64(nil), s.counts...)
+		sum, count := s.sum, s.count
+		s.mu.Unlock()
+
+		for i, upper := range h.buckets {
+			writeSample(w, h.vec.name+"_bucket", h.vec.labels, e.values, "le", formatFloat(upper), float64(counts[i]))
+		}
+		writeSample(w, h.vec.name+"_bucket", h.vec.labels, e.values, "le", "+Inf", float64(count))
+		writeSample(w, h.vec.name+"_sum", h.vec.labels, e.values, "", "", sum)
+		writeSample(w, h.vec.name+"_count", h.vec.labels, e.values, "", "", float64(count))
+	}
+}
+
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraLabel, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraLabel != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, label, escapeLabel(values[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, extraLabel, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
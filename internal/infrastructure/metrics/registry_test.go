@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("test_requests_total", "Requests.", "route", "code")
+	latency := r.NewHistogramVec("test_duration_seconds", "Latency.", []float64{0.1, 1}, "route")
+	r.NewGaugeFunc("test_locks", "Active locks.", func() float64 { return 3 })
+
+	requests.Inc("/a", "200")
+	requests.Inc("/a", "200")
+	requests.Add(5, `/b"c`, "500")
+	latency.Observe(0.05, "/a")
+	latency.Observe(0.5, "/a")
+	latency.Observe(2, "/a")
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	out := sb.String()
+	for _, want := range []string{
+		"# TYPE test_requests_total counter\n",
+		`test_requests_total{route="/a",code="200"} 2` + "\n",
+		`test_requests_total{route="/b\"c",code="500"} 5` + "\n",
+		"# TYPE test_duration_seconds histogram\n",
+		`test_duration_seconds_bucket{route="/a",le="0.1"} 1` + "\n",
+		`test_duration_seconds_bucket{route="/a",le="1"} 2` + "\n",
+		`test_duration_seconds_bucket{route="/a",le="+Inf"} 3` + "\n",
+		`test_duration_seconds_sum{route="/a"} 2.55` + "\n",
+		`test_duration_seconds_count{route="/a"} 3` + "\n",
+		"test_locks 3\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+)
+
+// Lockout 账户锁定：连续失败达到阈值后锁定，再次触发时锁定时间翻倍，直到上限
+// 认证成功或管理员解锁后清零；nil Lockout 不做限制。
+type Lockout struct {
+	maxFailures int
+	duration    time.Duration
+	maxDuration time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*lockEntry
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type lockEntry struct {
+	failures    int
+	lockouts    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// LockStatus 账户锁定状态
+type LockStatus struct {
+	Account     string
+	Failures    int
+	Lockouts    int
+	LockedUntil time.Time
+}
+
+// NewLockout 创建账户锁定器；maxFailures <= 0 时返回 nil，表示不锁定
+func NewLockout(maxFailures int, duration, maxDuration time.Duration) *Lockout {
+	if maxFailures <= 0 || duration <= 0 {
+		return nil
+	}
+	if maxDuration < duration {
+		maxDuration = duration
+	}
+	return &Lockout{
+		maxFailures: maxFailures,
+		duration:    duration,
+		maxDuration: maxDuration,
+		entries:     make(map[string]*lockEntry),
+		now:         time.Now,
+	}
+}
+
+// Check 账户处于锁定期时返回 *auth.LockedError
+func (l *Lockout) Check(account string) error {
+	if l == nil {
+		return nil
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if e, ok := l.entries[lockKey(account)]; ok && now.Before(e.lockedUntil) {
+		return &auth.LockedError{RetryAfter: e.lockedUntil.Sub(now)}
+	}
+	return nil
+}
+
+// Failure 记录一次认证失败；本次失败触发锁定时返回 *auth.LockedError
+func (l *Lockout) Failure(account string) error {
+	if l == nil {
+		return nil
+	}
+	now := l.now()
+	key := lockKey(account)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+
+	e, ok := l.entries[key]
+	if !ok {
+		e = &lockEntry{}
+		l.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+	if e.failures < l.maxFailures {
+		return nil
+	}
+
+	lockFor := l.duration << e.lockouts
+	if lockFor <= 0 || lockFor > l.maxDuration {
+		lockFor = l.maxDuration
+	}
+	e.failures = 0
+	e.lockouts++
+	e.lockedUntil = now.Add(lockFor)
+	return &auth.LockedError{RetryAfter: lockFor}
+}
+
+// Success 认证成功，清除失败记录
+func (l *Lockout) Success(account string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, lockKey(account))
+}
+
+// Unlock 管理员解锁账户，返回账户此前是否有失败或锁定记录
+func (l *Lockout) Unlock(account string) bool {
+	if l == nil {
+		return false
+	}
+	key := lockKey(account)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.entries[key]
+	delete(l.entries, key)
+	return ok
+}
+
+// Locked 当前处于锁定期的账户，按解锁时间排序
+func (l *Lockout) Locked() []LockStatus {
+	if l == nil {
+		return nil
+	}
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var out []LockStatus
+	for key, e := range l.entries {
+		if now.Before(e.lockedUntil) {
+			out = append(out, LockStatus{
+				Account:     key,
+				Failures:    e.failures,
+				Lockouts:    e.lockouts,
+				LockedUntil: e.lockedUntil,
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LockedUntil.Before(out[j].LockedUntil) })
+	return out
+}
+
+// prune 删除锁定已过期且长时间没有失败的记录，之后再失败重新从最短锁定时间开始；调用方需持有锁
+func (l *Lockout) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+	for key, e := range l.entries {
+		if now.After(e.lockedUntil) && now.Sub(e.lastFailure) > l.maxDuration {
+			delete(l.entries, key)
+		}
+	}
+}
+
+func lockKey(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+)
+
+func TestLimiterBurstAndRefill(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewLimiter(60, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("1.2.3.4"); !ok {
+			t.Fatalf("request %d within burst should pass", i+1)
+		}
+	}
+	ok, wait := l.Allow("1.2.3.4")
+	if ok || wait <= 0 || wait > time.Second {
+		t.Fatalf("expected throttling with ~1s wait, got ok=%v wait=%s", ok, wait)
+	}
+	if ok, _ := l.Allow("5.6.7.8"); !ok {
+		t.Fatalf("other keys should have their own bucket")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := l.Allow("1.2.3.4"); !ok {
+		t.Fatalf("one token should be refilled after a second")
+	}
+
+	if ok, _ := NewLimiter(0, 0).Allow("x"); !ok {
+		t.Fatalf("disabled limiter should allow everything")
+	}
+}
+
+func TestLockoutProgression(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewLockout(3, time.Minute, 5*time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := l.Failure("Alice"); err != nil {
+			t.Fatalf("failure %d should not lock: %v", i+1, err)
+		}
+	}
+	err := l.Failure("alice")
+	var locked *auth.LockedError
+	if !errors.As(err, &locked) || locked.RetryAfter != time.Minute || !errors.Is(err, auth.ErrAccountLocked) {
+		t.Fatalf("third failure should lock for 1m, got %v", err)
+	}
+	if err := l.Check("ALICE"); err == nil {
+		t.Fatalf("account should be locked")
+	}
+
+	// 第二次锁定时间翻倍，第三次达到上限
+	now = now.Add(time.Minute + time.Second)
+	if err := l.Check("alice"); err != nil {
+		t.Fatalf("lock should expire: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		err = l.Failure("alice")
+	}
+	if !errors.As(err, &locked) || locked.RetryAfter != 2*time.Minute {
+		t.Fatalf("second lock should last 2m, got %v", err)
+	}
+	now = now.Add(3 * time.Minute)
+	l.Failure("alice")
+	l.Failure("alice")
+	if err = l.Failure("alice"); !errors.As(err, &locked) || locked.RetryAfter != 4*time.Minute {
+		t.Fatalf("third lock should last 4m, got %v", err)
+	}
+	if len(l.Locked()) != 1 {
+		t.Fatalf("expected one locked account")
+	}
+
+	if !l.Unlock("alice") || l.Check("alice") != nil {
+		t.Fatalf("admin unlock should clear the lock")
+	}
+	l.Failure("alice")
+	l.Success("alice")
+	l.Failure("alice")
+	if err := l.Failure("alice"); err != nil {
+		t.Fatalf("success should reset the failure count: %v", err)
+	}
+}
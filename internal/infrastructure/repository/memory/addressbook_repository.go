@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/addressbook"
+)
+
+// AddressBookRepository 内存地址簿仓储
+type AddressBookRepository struct {
+	store *Store
+}
+
+// NewAddressBookRepository 创建内存地址簿仓储
+func NewAddressBookRepository(store *Store) *AddressBookRepository {
+	return &AddressBookRepository{store: store}
+}
+
+// CreateGroup 创建分组，同一用户下分组名唯一
+func (r *AddressBookRepository) CreateGroup(ctx context.Context, group *addressbook.Group) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.groupNameTakenLocked(group.UserID, group.Name, "") {
+		return addressbook.ErrDuplicateGroupName
+	}
+	c := *group
+	r.store.groups[group.ID] = &c
+	return nil
+}
+
+// GetGroupByID 获取用户的分组
+func (r *AddressBookRepository) GetGroupByID(ctx context.Context, userID, groupID string) (*addressbook.Group, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	group, ok := r.store.groups[groupID]
+	if !ok || group.UserID != userID {
+		return nil, addressbook.ErrGroupNotFound
+	}
+	c := *group
+	return &c, nil
+}
+
+// ListGroupsByUser 获取用户的全部分组（按创建时间倒序）
+func (r *AddressBookRepository) ListGroupsByUser(ctx context.Context, userID string) ([]*addressbook.Group, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var groups []*addressbook.Group
+	for _, group := range r.store.groups {
+		if group.UserID == userID {
+			c := *group
+			groups = append(groups, &c)
+		}
+	}
+	sortByTime(groups, func(g *addressbook.Group) time.Time { return g.CreatedAt }, true)
+	return groups, nil
+}
+
+// UpdateGroupName 修改分组名
+func (r *AddressBookRepository) UpdateGroupName(ctx context.Context, userID, groupID, name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.groupNameTakenLocked(userID, name, groupID) {
+		return addressbook.ErrDuplicateGroupName
+	}
+	group, ok := r.store.groups[groupID]
+	if !ok || group.UserID != userID {
+		return addressbook.ErrGroupNotFound
+	}
+	group.Name = name
+	return nil
+}
+
+// DeleteGroup 删除分组，组内联系人变为未分组
+func (r *AddressBookRepository) DeleteGroup(ctx context.Context, userID, groupID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	group, ok := r.store.groups[groupID]
+	if !ok || group.UserID != userID {
+		return addressbook.ErrGroupNotFound
+	}
+	r.store.deleteGroupLocked(groupID)
+	return nil
+}
+
+// CreateContact 创建联系人，同一用户下钱包地址唯一
+func (r *AddressBookRepository) CreateContact(ctx context.Context, contact *addressbook.Contact) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.contacts[contact.ID]; ok || r.walletTakenLocked(contact.UserID, contact.WalletAddress, "") {
+		return addressbook.ErrDuplicateWallet
+	}
+	r.store.contacts[contact.ID] = cloneContact(contact)
+	return nil
+}
+
+// GetContactByID 获取用户的联系人
+func (r *AddressBookRepository) GetContactByID(ctx context.Context, userID, contactID string) (*addressbook.Contact, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	contact, ok := r.store.contacts[contactID]
+	if !ok || contact.UserID != userID {
+		return nil, addressbook.ErrContactNotFound
+	}
+	return cloneContact(contact), nil
+}
+
+// ListContactsByUser 获取用户的全部联系人（按创建时间倒序）
+func (r *AddressBookRepository) ListContactsByUser(ctx context.Context, userID string) ([]*addressbook.Contact, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var contacts []*addressbook.Contact
+	for _, contact := range r.store.contacts {
+		if contact.UserID == userID {
+			contacts = append(contacts, cloneContact(contact))
+		}
+	}
+	sortByTime(contacts, func(c *addressbook.Contact) time.Time { return c.CreatedAt }, true)
+	return contacts, nil
+}
+
+// UpdateContact 更新联系人的分组、名称、钱包地址与标签
+func (r *AddressBookRepository) UpdateContact(ctx context.Context, contact *addressbook.Contact) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.walletTakenLocked(contact.UserID, contact.WalletAddress, contact.ID) {
+		return addressbook.ErrDuplicateWallet
+	}
+	existing, ok := r.store.contacts[contact.ID]
+	if !ok || existing.UserID != contact.UserID {
+		return addressbook.ErrContactNotFound
+	}
+	updated := cloneContact(contact)
+	existing.GroupID = updated.GroupID
+	existing.Name = updated.Name
+	existing.WalletAddress = updated.WalletAddress
+	existing.Tags = updated.Tags
+	return nil
+}
+
+// DeleteContact 删除联系人
+func (r *AddressBookRepository) DeleteContact(ctx context.Context, userID, contactID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	contact, ok := r.store.contacts[contactID]
+	if !ok || contact.UserID != userID {
+		return addressbook.ErrContactNotFound
+	}
+	delete(r.store.contacts, contactID)
+	return nil
+}
+
+func (r *AddressBookRepository) groupNameTakenLocked(userID, name, exceptID string) bool {
+	for id, group := range r.store.groups {
+		if id != exceptID && group.UserID == userID && group.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *AddressBookRepository) walletTakenLocked(userID, wallet, exceptID string) bool {
+	for id, contact := range r.store.contacts {
+		if id != exceptID && contact.UserID == userID && contact.WalletAddress == wallet {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneContact 复制联系人；空白分组 ID 按未分组保存
+func cloneContact(contact *addressbook.Contact) *addressbook.Contact {
+	c := *contact
+	if strings.TrimSpace(c.GroupID) == "" {
+		c.GroupID = ""
+	}
+	c.Tags = cloneStrings(contact.Tags)
+	if c.Tags == nil {
+		c.Tags = []string{}
+	}
+	return &c
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/apppassword"
+)
+
+// AppPasswordRepository 内存应用专用密码仓储
+type AppPasswordRepository struct {
+	store *Store
+}
+
+// NewAppPasswordRepository 创建内存应用专用密码仓储
+func NewAppPasswordRepository(store *Store) *AppPasswordRepository {
+	return &AppPasswordRepository{store: store}
+}
+
+// Create 创建应用专用密码，密码哈希全局唯一
+func (r *AppPasswordRepository) Create(ctx context.Context, p *apppassword.AppPassword) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, existing := range r.store.appPasswords {
+		if id == p.ID || existing.Hash == p.Hash {
+			return fmt.Errorf("failed to create app password: duplicate id or hash")
+		}
+	}
+	c := cloneAppPassword(p)
+	c.LastUsedAt, c.LastUsedIP = nil, ""
+	r.store.appPasswords[p.ID] = c
+	return nil
+}
+
+// ListByUser 获取用户的全部应用专用密码（按创建时间倒序）
+func (r *AppPasswordRepository) ListByUser(ctx context.Context, userID string) ([]*apppassword.AppPassword, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var items []*apppassword.AppPassword
+	for _, p := range r.store.appPasswords {
+		if p.UserID == userID {
+			items = append(items, cloneAppPassword(p))
+		}
+	}
+	sortByTime(items, func(p *apppassword.AppPassword) time.Time { return p.CreatedAt }, true)
+	return items, nil
+}
+
+// FindByHash 根据密码哈希查找
+func (r *AppPasswordRepository) FindByHash(ctx context.Context, hash string) (*apppassword.AppPassword, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, p := range r.store.appPasswords {
+		if p.Hash == hash {
+			return cloneAppPassword(p), nil
+		}
+	}
+	return nil, apppassword.ErrAppPasswordNotFound
+}
+
+// Delete 吊销应用专用密码
+func (r *AppPasswordRepository) Delete(ctx context.Context, userID, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.appPasswords[id]
+	if !ok || p.UserID != userID {
+		return apppassword.ErrAppPasswordNotFound
+	}
+	delete(r.store.appPasswords, id)
+	return nil
+}
+
+// TouchLastUsed 记录最近使用时间与 IP
+func (r *AppPasswordRepository) TouchLastUsed(ctx context.Context, id string, at time.Time, ip string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if p, ok := r.store.appPasswords[id]; ok {
+		p.LastUsedAt = &at
+		p.LastUsedIP = ip
+	}
+	return nil
+}
+
+func cloneAppPassword(p *apppassword.AppPassword) *apppassword.AppPassword {
+	c := *p
+	c.Paths = cloneStrings(p.Paths)
+	if c.Paths == nil {
+		c.Paths = []string{}
+	}
+	c.LastUsedAt = cloneTime(p.LastUsedAt)
+	return &c
+}
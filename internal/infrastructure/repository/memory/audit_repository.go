@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/audit"
+)
+
+// AuditRepository 内存审计日志仓储（只追加）
+type AuditRepository struct {
+	store *Store
+}
+
+// NewAuditRepository 创建内存审计日志仓储
+func NewAuditRepository(store *Store) *AuditRepository {
+	return &AuditRepository{store: store}
+}
+
+// Append 追加一条审计事件并分配自增 ID
+func (r *AuditRepository) Append(ctx context.Context, e *audit.Event) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.auditSeq++
+	e.ID = r.store.auditSeq
+	c := *e
+	r.store.auditEvents = append(r.store.auditEvents, &c)
+	return nil
+}
+
+// Query 按条件分页查询审计事件（按时间倒序），同时返回符合条件的总数
+func (r *AuditRepository) Query(ctx context.Context, filter audit.Filter) ([]*audit.Event, int, error) {
+	events := r.match(filter)
+	total := len(events)
+	sort.SliceStable(events, func(i, j int) bool { return eventBefore(events[j], events[i]) })
+
+	items := make([]*audit.Event, 0)
+	if filter.Offset < len(events) {
+		end := len(events)
+		if filter.Limit >= 0 && filter.Offset+filter.Limit < end {
+			end = filter.Offset + filter.Limit
+		}
+		items = append(items, events[max(filter.Offset, 0):end]...)
+	}
+	return items, total, nil
+}
+
+// Iterate 按时间顺序遍历符合条件的全部审计事件（忽略分页）
+func (r *AuditRepository) Iterate(ctx context.Context, filter audit.Filter, fn func(*audit.Event) error) error {
+	events := r.match(filter)
+	sort.SliceStable(events, func(i, j int) bool { return eventBefore(events[i], events[j]) })
+	for _, e := range events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteBefore 删除 before 之前的审计事件
+func (r *AuditRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	kept := r.store.auditEvents[:0]
+	for _, e := range r.store.auditEvents {
+		if !e.CreatedAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	deleted := int64(len(r.store.auditEvents) - len(kept))
+	r.store.auditEvents = kept
+	return deleted, nil
+}
+
+// match 返回符合条件的事件副本
+func (r *AuditRepository) match(filter audit.Filter) []*audit.Event {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var events []*audit.Event
+	for _, e := range r.store.auditEvents {
+		if filter.Actor != "" && e.ActorID != filter.Actor && e.Actor != filter.Actor {
+			continue
+		}
+		if filter.Action != "" && !strings.HasPrefix(e.Action, filter.Action) {
+			continue
+		}
+		if filter.Outcome != "" && e.Outcome != filter.Outcome {
+			continue
+		}
+		if filter.Target != "" && !strings.HasPrefix(e.Target, filter.Target) {
+			continue
+		}
+		if filter.IP != "" && e.IP != filter.IP {
+			continue
+		}
+		if !filter.From.IsZero() && e.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !e.CreatedAt.Before(filter.To) {
+			continue
+		}
+		c := *e
+		events = append(events, &c)
+	}
+	return events
+}
+
+// eventBefore 按时间与 ID 排序
+func eventBefore(a, b *audit.Event) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
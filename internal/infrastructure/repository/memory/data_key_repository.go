@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/yeying-community/warehouse/internal/domain/datakey"
+)
+
+// DataKeyRepository 内存数据密钥仓储
+type DataKeyRepository struct {
+	store *Store
+}
+
+// NewDataKeyRepository 创建内存数据密钥仓储
+func NewDataKeyRepository(store *Store) *DataKeyRepository {
+	return &DataKeyRepository{store: store}
+}
+
+// ListByUser 获取用户的全部密钥版本（按版本升序）
+func (r *DataKeyRepository) ListByUser(ctx context.Context, userID string) ([]*datakey.DataKey, error) {
+	return r.list(func(key *datakey.DataKey) bool { return key.UserID == userID }), nil
+}
+
+// List 获取全部用户的密钥（按用户与版本升序）
+func (r *DataKeyRepository) List(ctx context.Context) ([]*datakey.DataKey, error) {
+	return r.list(func(*datakey.DataKey) bool { return true }), nil
+}
+
+// Create 创建密钥版本，Active 为 true 时其他版本同时置为非活动
+func (r *DataKeyRepository) Create(ctx context.Context, key *datakey.DataKey) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	k := dataKeyKey{key.UserID, key.Version}
+	if _, ok := r.store.dataKeys[k]; ok {
+		return fmt.Errorf("failed to create data key: version %d already exists", key.Version)
+	}
+	if key.Active {
+		for _, existing := range r.store.dataKeys {
+			if existing.UserID == key.UserID {
+				existing.Active = false
+			}
+		}
+	}
+	r.store.dataKeys[k] = cloneDataKey(key)
+	return nil
+}
+
+// UpdateWrappedKey 更新加密后的密钥与主密钥标识
+func (r *DataKeyRepository) UpdateWrappedKey(ctx context.Context, key *datakey.DataKey) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.dataKeys[dataKeyKey{key.UserID, key.Version}]
+	if !ok {
+		return datakey.ErrDataKeyNotFound
+	}
+	existing.WrappedKey = append([]byte(nil), key.WrappedKey...)
+	existing.MasterKeyID = key.MasterKeyID
+	return nil
+}
+
+func (r *DataKeyRepository) list(match func(*datakey.DataKey) bool) []*datakey.DataKey {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var keys []*datakey.DataKey
+	for _, key := range r.store.dataKeys {
+		if match(key) {
+			keys = append(keys, cloneDataKey(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].UserID != keys[j].UserID {
+			return keys[i].UserID < keys[j].UserID
+		}
+		return keys[i].Version < keys[j].Version
+	})
+	return keys
+}
+
+func cloneDataKey(key *datakey.DataKey) *datakey.DataKey {
+	c := *key
+	c.WrappedKey = append([]byte(nil), key.WrappedKey...)
+	return &c
+}
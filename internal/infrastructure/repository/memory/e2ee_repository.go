@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/e2ee"
+)
+
+// E2EERepository 内存端到端加密目录仓储
+type E2EERepository struct {
+	store *Store
+}
+
+// NewE2EERepository 创建内存端到端加密仓储
+func NewE2EERepository(store *Store) *E2EERepository {
+	return &E2EERepository{store: store}
+}
+
+// SavePublicKey 保存（或更新）钱包公钥
+func (r *E2EERepository) SavePublicKey(ctx context.Context, key *e2ee.WalletPublicKey) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c := *key
+	c.WalletAddress = normalizeWallet(key.WalletAddress)
+	c.PublicKey = append([]byte(nil), key.PublicKey...)
+	r.store.publicKeys[c.WalletAddress] = &c
+	return nil
+}
+
+// GetPublicKey 获取钱包公钥
+func (r *E2EERepository) GetPublicKey(ctx context.Context, wallet string) (*e2ee.WalletPublicKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key, ok := r.store.publicKeys[normalizeWallet(wallet)]
+	if !ok {
+		return nil, e2ee.ErrPublicKeyNotFound
+	}
+	c := *key
+	c.PublicKey = append([]byte(nil), key.PublicKey...)
+	return &c, nil
+}
+
+// CreateFolder 创建加密目录，并同时写入拥有者的密钥信封
+func (r *E2EERepository) CreateFolder(ctx context.Context, folder *e2ee.Folder, ownerEnvelope *e2ee.KeyEnvelope) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, existing := range r.store.folders {
+		if id == folder.ID || (existing.OwnerUserID == folder.OwnerUserID && existing.Path == folder.Path) {
+			return e2ee.ErrFolderExists
+		}
+	}
+	c := *folder
+	r.store.folders[folder.ID] = &c
+	if ownerEnvelope != nil {
+		r.putEnvelopeLocked(ownerEnvelope)
+	}
+	return nil
+}
+
+// GetFolder 根据 ID 获取加密目录
+func (r *E2EERepository) GetFolder(ctx context.Context, id string) (*e2ee.Folder, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	folder, ok := r.store.folders[id]
+	if !ok {
+		return nil, e2ee.ErrFolderNotFound
+	}
+	c := *folder
+	return &c, nil
+}
+
+// ListFolders 获取用户的全部加密目录（按路径升序）
+func (r *E2EERepository) ListFolders(ctx context.Context, ownerUserID string) ([]*e2ee.Folder, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var folders []*e2ee.Folder
+	for _, folder := range r.store.folders {
+		if folder.OwnerUserID == ownerUserID {
+			c := *folder
+			folders = append(folders, &c)
+		}
+	}
+	sort.Slice(folders, func(i, j int) bool { return folders[i].Path < folders[j].Path })
+	return folders, nil
+}
+
+// UpdateFolderPath 更新加密目录路径
+func (r *E2EERepository) UpdateFolderPath(ctx context.Context, id, newPath string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	folder, ok := r.store.folders[id]
+	if !ok {
+		return e2ee.ErrFolderNotFound
+	}
+	folder.Path = newPath
+	return nil
+}
+
+// DeleteFolder 删除加密目录及其全部密钥信封
+func (r *E2EERepository) DeleteFolder(ctx context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.deleteFolderLocked(id)
+	return nil
+}
+
+// PutEnvelope 写入（或替换）接收方的密钥信封
+func (r *E2EERepository) PutEnvelope(ctx context.Context, envelope *e2ee.KeyEnvelope) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.putEnvelopeLocked(envelope)
+	return nil
+}
+
+// GetEnvelope 获取接收方的密钥信封
+func (r *E2EERepository) GetEnvelope(ctx context.Context, folderID, wallet string) (*e2ee.KeyEnvelope, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	env, ok := r.store.envelopes[envelopeKey{folderID, normalizeWallet(wallet)}]
+	if !ok {
+		return nil, e2ee.ErrEnvelopeNotFound
+	}
+	c := *env
+	return &c, nil
+}
+
+// ListEnvelopes 获取目录的全部密钥信封（按写入时间升序）
+func (r *E2EERepository) ListEnvelopes(ctx context.Context, folderID string) ([]*e2ee.KeyEnvelope, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var envelopes []*e2ee.KeyEnvelope
+	for key, env := range r.store.envelopes {
+		if key.folderID == folderID {
+			c := *env
+			envelopes = append(envelopes, &c)
+		}
+	}
+	sortByTime(envelopes, func(env *e2ee.KeyEnvelope) time.Time { return env.CreatedAt }, false)
+	return envelopes, nil
+}
+
+// DeleteEnvelope 删除接收方的密钥信封
+func (r *E2EERepository) DeleteEnvelope(ctx context.Context, folderID, wallet string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := envelopeKey{folderID, normalizeWallet(wallet)}
+	if _, ok := r.store.envelopes[key]; !ok {
+		return e2ee.ErrEnvelopeNotFound
+	}
+	delete(r.store.envelopes, key)
+	return nil
+}
+
+func (r *E2EERepository) putEnvelopeLocked(envelope *e2ee.KeyEnvelope) {
+	c := *envelope
+	c.RecipientWallet = normalizeWallet(envelope.RecipientWallet)
+	r.store.envelopes[envelopeKey{c.FolderID, c.RecipientWallet}] = &c
+}
+
+func normalizeWallet(wallet string) string {
+	return e2ee.NormalizeWallet(wallet)
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/filemeta"
+)
+
+// FileMetadataRepository 内存文件元数据仓储
+type FileMetadataRepository struct {
+	store *Store
+}
+
+// NewFileMetadataRepository 创建内存文件元数据仓储
+func NewFileMetadataRepository(store *Store) *FileMetadataRepository {
+	return &FileMetadataRepository{store: store}
+}
+
+// Get 获取指定路径的元数据
+func (r *FileMetadataRepository) Get(ctx context.Context, userID, path string) (*filemeta.FileMetadata, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	meta, ok := r.store.fileMetadata[fileKey{userID, path}]
+	if !ok {
+		return nil, filemeta.ErrFileMetadataNotFound
+	}
+	c := *meta
+	return &c, nil
+}
+
+// Upsert 创建或更新元数据
+func (r *FileMetadataRepository) Upsert(ctx context.Context, meta *filemeta.FileMetadata) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	c := *meta
+	r.store.fileMetadata[fileKey{meta.UserID, meta.Path}] = &c
+	return nil
+}
+
+// Move 将路径（含子路径）的元数据移动到新路径，目标路径已有的记录会被覆盖
+func (r *FileMetadataRepository) Move(ctx context.Context, userID, oldPath, newPath string) error {
+	if oldPath == newPath {
+		return nil
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.deleteLocked(userID, newPath)
+
+	now := time.Now()
+	var moved []*filemeta.FileMetadata
+	for key, meta := range r.store.fileMetadata {
+		if key.userID == userID && isSameOrChild(key.path, oldPath) {
+			delete(r.store.fileMetadata, key)
+			meta.Path = newPath + strings.TrimPrefix(key.path, oldPath)
+			meta.UpdatedAt = now
+			moved = append(moved, meta)
+		}
+	}
+	for _, meta := range moved {
+		r.store.fileMetadata[fileKey{userID, meta.Path}] = meta
+	}
+	return nil
+}
+
+// Delete 删除路径（含子路径）的元数据
+func (r *FileMetadataRepository) Delete(ctx context.Context, userID, path string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.deleteLocked(userID, path)
+	return nil
+}
+
+func (r *FileMetadataRepository) deleteLocked(userID, path string) {
+	for key := range r.store.fileMetadata {
+		if key.userID == userID && isSameOrChild(key.path, path) {
+			delete(r.store.fileMetadata, key)
+		}
+	}
+}
+
+// isSameOrChild 判断 path 是否为 parent 本身或其子路径
+func isSameOrChild(path, parent string) bool {
+	return path == parent || strings.HasPrefix(path, strings.TrimSuffix(parent, "/")+"/")
+}
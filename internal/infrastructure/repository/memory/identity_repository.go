@@ -0,0 +1,250 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/identity"
+)
+
+// IdentityRepository 内存登录身份仓储
+type IdentityRepository struct {
+	store *Store
+}
+
+// NewIdentityRepository 创建内存登录身份仓储
+func NewIdentityRepository(store *Store) *IdentityRepository {
+	return &IdentityRepository{store: store}
+}
+
+// ListByUser 获取用户的全部登录身份（按关联时间升序）
+func (r *IdentityRepository) ListByUser(ctx context.Context, userID string) ([]*identity.Identity, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	items := r.store.identitiesOfLocked(userID, "")
+	for i, ident := range items {
+		items[i] = cloneIdentity(ident)
+	}
+	return items, nil
+}
+
+// FindBySubject 根据类型与 subject 查找登录身份
+func (r *IdentityRepository) FindBySubject(ctx context.Context, t identity.Type, subject string) (*identity.Identity, error) {
+	normalized, err := identity.NormalizeSubject(t, subject)
+	if err != nil {
+		return nil, identity.ErrIdentityNotFound
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	ident := r.store.findIdentityLocked(t, normalized)
+	if ident == nil {
+		return nil, identity.ErrIdentityNotFound
+	}
+	return cloneIdentity(ident), nil
+}
+
+// Link 关联登录身份
+func (r *IdentityRepository) Link(ctx context.Context, ident *identity.Identity) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if existing := r.store.findIdentityLocked(ident.Type, ident.Subject); existing != nil {
+		if existing.UserID != ident.UserID {
+			return identity.ErrIdentityInUse
+		}
+	} else {
+		r.store.identities[ident.ID] = cloneIdentity(ident)
+	}
+
+	u, ok := r.store.users[ident.UserID]
+	if !ok {
+		return nil
+	}
+	switch {
+	case ident.Type == identity.TypeWallet && u.WalletAddress == "":
+		u.WalletAddress = ident.Subject
+		u.UpdatedAt = time.Now()
+	case ident.Type == identity.TypeEmail && u.Email == "":
+		// 关联邮箱需要验证码，视为已验证
+		verifiedAt := ident.CreatedAt
+		u.Email, u.EmailVerifiedAt = ident.Subject, &verifiedAt
+		u.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// Unlink 解除登录身份
+// 解除的是主钱包或主邮箱时，由最早关联的同类身份接替；解除密码身份会清空密码。
+func (r *IdentityRepository) Unlink(ctx context.Context, userID, identityID string) (*identity.Identity, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	ident, ok := r.store.identities[identityID]
+	if !ok || ident.UserID != userID {
+		return nil, identity.ErrIdentityNotFound
+	}
+	if len(r.store.identitiesOfLocked(userID, "")) <= 1 {
+		return nil, identity.ErrLastIdentity
+	}
+	delete(r.store.identities, identityID)
+
+	u, ok := r.store.users[userID]
+	if !ok {
+		return cloneIdentity(ident), nil
+	}
+	successor := func(t identity.Type) string {
+		if rest := r.store.identitiesOfLocked(userID, t); len(rest) > 0 {
+			return rest[0].Subject
+		}
+		return ""
+	}
+	switch ident.Type {
+	case identity.TypeWallet:
+		if u.WalletAddress == ident.Subject {
+			u.WalletAddress = successor(identity.TypeWallet)
+			u.UpdatedAt = time.Now()
+		}
+	case identity.TypeEmail:
+		if normalizeSubject(identity.TypeEmail, u.Email) == ident.Subject {
+			u.Email, u.EmailVerifiedAt = successor(identity.TypeEmail), nil
+			if u.Email != "" {
+				now := time.Now()
+				u.EmailVerifiedAt = &now
+			}
+			u.UpdatedAt = time.Now()
+		}
+	case identity.TypePassword:
+		u.Password = ""
+		u.UpdatedAt = time.Now()
+	}
+	return cloneIdentity(ident), nil
+}
+
+// Merge 合并账户
+func (r *IdentityRepository) Merge(ctx context.Context, sourceID, targetID, targetUsername, pathPrefix string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	s := r.store
+	source, ok := s.users[sourceID]
+	if !ok {
+		return identity.ErrIdentityNotFound
+	}
+	sourceWallet, sourceEmail := source.WalletAddress, source.Email
+	sourceEmailVerifiedAt := cloneTime(source.EmailVerifiedAt)
+
+	// 分享链接随文件移动到 pathPrefix 下
+	for _, item := range s.shares {
+		if item.UserID == sourceID {
+			item.UserID, item.Username, item.Path = targetID, targetUsername, pathPrefix+item.Path
+		}
+	}
+	for id, item := range s.userShares {
+		switch {
+		// 两个账户之间的定向分享在合并后没有意义
+		case (item.OwnerUserID == sourceID && item.TargetUserID == targetID) ||
+			(item.OwnerUserID == targetID && item.TargetUserID == sourceID):
+			delete(s.userShares, id)
+		case item.OwnerUserID == sourceID:
+			item.OwnerUserID, item.OwnerUsername, item.Path = targetID, targetUsername, pathPrefix+item.Path
+		case item.TargetUserID == sourceID:
+			item.TargetUserID = targetID
+		}
+	}
+
+	// 同名分组合并到 target 已有分组
+	targetGroups := make(map[string]string)
+	for _, group := range s.groups {
+		if group.UserID == targetID {
+			targetGroups[group.Name] = group.ID
+		}
+	}
+	for id, group := range s.groups {
+		if group.UserID != sourceID {
+			continue
+		}
+		if targetGroupID, ok := targetGroups[group.Name]; ok {
+			for _, contact := range s.contacts {
+				if contact.GroupID == id {
+					contact.GroupID = targetGroupID
+				}
+			}
+			delete(s.groups, id)
+			continue
+		}
+		group.UserID = targetID
+	}
+
+	// target 已有的联系人保留 target 的记录
+	targetWallets := make(map[string]bool)
+	for _, contact := range s.contacts {
+		if contact.UserID == targetID {
+			targetWallets[contact.WalletAddress] = true
+		}
+	}
+	for id, contact := range s.contacts {
+		if contact.UserID != sourceID {
+			continue
+		}
+		if targetWallets[contact.WalletAddress] {
+			delete(s.contacts, id)
+			continue
+		}
+		contact.UserID = targetID
+	}
+
+	// 用户名随 source 删除，密码身份不再有效；其余身份转给 target
+	for id, ident := range s.identities {
+		if ident.UserID != sourceID {
+			continue
+		}
+		if ident.Type == identity.TypePassword {
+			delete(s.identities, id)
+			continue
+		}
+		ident.UserID = targetID
+	}
+
+	s.deleteUserLocked(sourceID)
+
+	if target, ok := s.users[targetID]; ok {
+		if target.WalletAddress == "" {
+			target.WalletAddress = sourceWallet
+		}
+		if target.Email == "" {
+			target.Email, target.EmailVerifiedAt = sourceEmail, sourceEmailVerifiedAt
+		}
+		target.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// findIdentityLocked 根据类型与规范化后的 subject 查找身份；调用方需持有锁
+func (s *Store) findIdentityLocked(t identity.Type, subject string) *identity.Identity {
+	for _, ident := range s.identities {
+		if ident.Type == t && ident.Subject == subject {
+			return ident
+		}
+	}
+	return nil
+}
+
+// identitiesOfLocked 返回用户的登录身份（按关联时间升序），t 非空时只返回该类型；调用方需持有锁
+func (s *Store) identitiesOfLocked(userID string, t identity.Type) []*identity.Identity {
+	var items []*identity.Identity
+	for _, ident := range s.identities {
+		if ident.UserID == userID && (t == "" || ident.Type == t) {
+			items = append(items, ident)
+		}
+	}
+	sortByTime(items, func(i *identity.Identity) time.Time { return i.CreatedAt }, false)
+	return items
+}
+
+func cloneIdentity(ident *identity.Identity) *identity.Identity {
+	c := *ident
+	return &c
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/mfa"
+)
+
+// MFARepository 内存两步验证仓储
+type MFARepository struct {
+	store *Store
+}
+
+// NewMFARepository 创建内存两步验证仓储
+func NewMFARepository(store *Store) *MFARepository {
+	return &MFARepository{store: store}
+}
+
+// Get 获取用户的两步验证登记，不存在时返回 mfa.ErrNotEnrolled
+func (r *MFARepository) Get(ctx context.Context, userID string) (*mfa.Enrollment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	e, ok := r.store.mfa[userID]
+	if !ok {
+		return nil, mfa.ErrNotEnrolled
+	}
+	return cloneEnrollment(e), nil
+}
+
+// Save 保存登记（不存在则创建）；不修改管理员设置的 required 标记
+func (r *MFARepository) Save(ctx context.Context, e *mfa.Enrollment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored := cloneEnrollment(e)
+	stored.Required = false
+	if existing, ok := r.store.mfa[e.UserID]; ok {
+		stored.Required = existing.Required
+	}
+	r.store.mfa[e.UserID] = stored
+	return nil
+}
+
+// ConsumeCounter 记录通过校验的时间步；时间步不大于已记录值时返回 false
+func (r *MFARepository) ConsumeCounter(ctx context.Context, userID string, counter int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	e, ok := r.store.mfa[userID]
+	if !ok || e.LastCounter >= counter {
+		return false, nil
+	}
+	e.LastCounter = counter
+	return true, nil
+}
+
+// UpdateRecoveryCodes 更新恢复码哈希
+func (r *MFARepository) UpdateRecoveryCodes(ctx context.Context, userID string, hashes []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if e, ok := r.store.mfa[userID]; ok {
+		e.RecoveryCodes = cloneStrings(hashes)
+		if e.RecoveryCodes == nil {
+			e.RecoveryCodes = []string{}
+		}
+	}
+	return nil
+}
+
+// ConsumeRecoveryCode 移除一个恢复码哈希；哈希不存在时返回 false
+func (r *MFARepository) ConsumeRecoveryCode(ctx context.Context, userID, hash string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	e, ok := r.store.mfa[userID]
+	if !ok {
+		return false, nil
+	}
+	i := slices.Index(e.RecoveryCodes, hash)
+	if i < 0 {
+		return false, nil
+	}
+	e.RecoveryCodes = slices.Delete(e.RecoveryCodes, i, i+1)
+	return true, nil
+}
+
+// Disable 关闭两步验证并清除密钥与恢复码，保留 required 标记
+func (r *MFARepository) Disable(ctx context.Context, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if e, ok := r.store.mfa[userID]; ok {
+		e.Secret = ""
+		e.Enabled = false
+		e.LastCounter = 0
+		e.RecoveryCodes = []string{}
+		e.EnabledAt = nil
+	}
+	return nil
+}
+
+// SetRequired 设置管理员强制两步验证标记
+func (r *MFARepository) SetRequired(ctx context.Context, userID string, required bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	e, ok := r.store.mfa[userID]
+	if !ok {
+		e = &mfa.Enrollment{UserID: userID, RecoveryCodes: []string{}, CreatedAt: time.Now()}
+		r.store.mfa[userID] = e
+	}
+	e.Required = required
+	return nil
+}
+
+func cloneEnrollment(e *mfa.Enrollment) *mfa.Enrollment {
+	c := *e
+	c.RecoveryCodes = cloneStrings(e.RecoveryCodes)
+	if c.RecoveryCodes == nil {
+		c.RecoveryCodes = []string{}
+	}
+	c.EnabledAt = cloneTime(e.EnabledAt)
+	return &c
+}
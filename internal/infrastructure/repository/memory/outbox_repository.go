@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/outbox"
+)
+
+// OutboxRepository 内存邮件发件箱仓储
+type OutboxRepository struct {
+	store *Store
+}
+
+// NewOutboxRepository 创建内存邮件发件箱仓储
+func NewOutboxRepository(store *Store) *OutboxRepository {
+	return &OutboxRepository{store: store}
+}
+
+// Enqueue 写入一封待发送的邮件
+func (r *OutboxRepository) Enqueue(ctx context.Context, m *outbox.Message) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.outbox[m.ID] = cloneMessage(m)
+	return nil
+}
+
+// ClaimDue 领取到期待发送的邮件，并把下次发送时间推迟 lease
+func (r *OutboxRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*outbox.Message, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var due []*outbox.Message
+	for _, m := range r.store.outbox {
+		if m.Status == outbox.StatusPending && !m.NextAttemptAt.After(now) {
+			due = append(due, m)
+		}
+	}
+	sortByTime(due, func(m *outbox.Message) time.Time { return m.NextAttemptAt }, false)
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	items := make([]*outbox.Message, 0, len(due))
+	for _, m := range due {
+		m.NextAttemptAt = now.Add(lease)
+		m.UpdatedAt = now
+		items = append(items, cloneMessage(m))
+	}
+	return items, nil
+}
+
+// Update 写回邮件状态
+func (r *OutboxRepository) Update(ctx context.Context, m *outbox.Message) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.outbox[m.ID]; !ok {
+		return outbox.ErrMessageNotFound
+	}
+	r.store.outbox[m.ID] = cloneMessage(m)
+	return nil
+}
+
+// GetByID 根据 ID 获取邮件
+func (r *OutboxRepository) GetByID(ctx context.Context, id string) (*outbox.Message, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, ok := r.store.outbox[id]
+	if !ok {
+		return nil, outbox.ErrMessageNotFound
+	}
+	return cloneMessage(m), nil
+}
+
+// Query 按条件分页查询邮件（按创建时间倒序），同时返回符合条件的总数
+func (r *OutboxRepository) Query(ctx context.Context, filter outbox.Filter) ([]*outbox.Message, int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	recipient := strings.ToLower(filter.Recipient)
+	var matched []*outbox.Message
+	for _, m := range r.store.outbox {
+		if filter.Status != "" && m.Status != filter.Status {
+			continue
+		}
+		if recipient != "" && m.Recipient != recipient {
+			continue
+		}
+		matched = append(matched, m)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+
+	items := make([]*outbox.Message, 0)
+	for i := max(filter.Offset, 0); i < len(matched) && len(items) < filter.Limit; i++ {
+		items = append(items, cloneMessage(matched[i]))
+	}
+	return items, len(matched), nil
+}
+
+// DeleteSentBefore 删除 before 之前发送成功的邮件
+func (r *OutboxRepository) DeleteSentBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var deleted int64
+	for id, m := range r.store.outbox {
+		if m.Status == outbox.StatusSent && m.SentAt != nil && m.SentAt.Before(before) {
+			delete(r.store.outbox, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func cloneMessage(m *outbox.Message) *outbox.Message {
+	c := *m
+	c.Data = make(map[string]any, len(m.Data))
+	for k, v := range m.Data {
+		c.Data[k] = v
+	}
+	c.SentAt = cloneTime(m.SentAt)
+	return &c
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/recycle"
+)
+
+// RecycleRepository 内存回收站仓储
+type RecycleRepository struct {
+	store *Store
+}
+
+// NewRecycleRepository 创建内存回收站仓储
+func NewRecycleRepository(store *Store) *RecycleRepository {
+	return &RecycleRepository{store: store}
+}
+
+// Create 创建回收站项目
+func (r *RecycleRepository) Create(ctx context.Context, item *recycle.RecycleItem) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.recycle[item.Hash]; ok {
+		return fmt.Errorf("failed to create recycle item: duplicate hash %s", item.Hash)
+	}
+	c := *item
+	r.store.recycle[item.Hash] = &c
+	return nil
+}
+
+// GetByHash 根据哈希获取项目
+func (r *RecycleRepository) GetByHash(ctx context.Context, hash string) (*recycle.RecycleItem, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	item, ok := r.store.recycle[hash]
+	if !ok {
+		return nil, recycle.ErrRecycleItemNotFound
+	}
+	c := *item
+	return &c, nil
+}
+
+// GetByUserID 获取用户的所有回收站项目（按删除时间倒序）
+func (r *RecycleRepository) GetByUserID(ctx context.Context, userID string) ([]*recycle.RecycleItem, error) {
+	return r.list(func(item *recycle.RecycleItem) bool { return item.UserID == userID }, true), nil
+}
+
+// DeleteByHash 根据哈希删除项目
+func (r *RecycleRepository) DeleteByHash(ctx context.Context, hash string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.recycle[hash]; !ok {
+		return recycle.ErrRecycleItemNotFound
+	}
+	delete(r.store.recycle, hash)
+	return nil
+}
+
+// DeleteByUserID 删除用户的所有回收站项目
+func (r *RecycleRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for hash, item := range r.store.recycle {
+		if item.UserID == userID {
+			delete(r.store.recycle, hash)
+		}
+	}
+	return nil
+}
+
+// GetDeletedItemsOlderThan 获取指定时间之前删除的项目（按删除时间升序）
+func (r *RecycleRepository) GetDeletedItemsOlderThan(ctx context.Context, before time.Time) ([]*recycle.RecycleItem, error) {
+	return r.list(func(item *recycle.RecycleItem) bool { return item.DeletedAt.Before(before) }, false), nil
+}
+
+// DeleteExpiredItems 删除过期项目
+func (r *RecycleRepository) DeleteExpiredItems(ctx context.Context, retentionPeriod time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-retentionPeriod)
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var deleted int64
+	for hash, item := range r.store.recycle {
+		if item.DeletedAt.Before(cutoff) {
+			delete(r.store.recycle, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// Stats 统计回收站项目总数与总大小
+func (r *RecycleRepository) Stats(ctx context.Context) (int64, int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var size int64
+	for _, item := range r.store.recycle {
+		size += item.Size
+	}
+	return int64(len(r.store.recycle)), size, nil
+}
+
+func (r *RecycleRepository) list(match func(*recycle.RecycleItem) bool, desc bool) []*recycle.RecycleItem {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var items []*recycle.RecycleItem
+	for _, item := range r.store.recycle {
+		if match(item) {
+			c := *item
+			items = append(items, &c)
+		}
+	}
+	sortByTime(items, func(item *recycle.RecycleItem) time.Time { return item.DeletedAt }, desc)
+	return items
+}
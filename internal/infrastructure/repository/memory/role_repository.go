@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
+)
+
+// RoleRepository 内存管理角色仓储
+type RoleRepository struct {
+	store *Store
+}
+
+// NewRoleRepository 创建内存角色仓储
+func NewRoleRepository(store *Store) *RoleRepository {
+	return &RoleRepository{store: store}
+}
+
+// ListRoles 获取全部角色，内置角色在前
+func (r *RoleRepository) ListRoles(ctx context.Context) ([]*rbac.Role, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var roles []*rbac.Role
+	for _, role := range r.store.roles {
+		roles = append(roles, cloneRole(role))
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].BuiltIn != roles[j].BuiltIn {
+			return roles[i].BuiltIn
+		}
+		return roles[i].Name < roles[j].Name
+	})
+	return roles, nil
+}
+
+// GetRole 根据名称获取角色
+func (r *RoleRepository) GetRole(ctx context.Context, name string) (*rbac.Role, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	role, ok := r.store.roles[name]
+	if !ok {
+		return nil, rbac.ErrRoleNotFound
+	}
+	return cloneRole(role), nil
+}
+
+// CreateRole 创建自定义角色
+func (r *RoleRepository) CreateRole(ctx context.Context, role *rbac.Role) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.roles[role.Name]; ok {
+		return rbac.ErrRoleExists
+	}
+	c := cloneRole(role)
+	c.BuiltIn = false
+	r.store.roles[role.Name] = c
+	return nil
+}
+
+// UpdateRole 更新自定义角色的描述与权限
+func (r *RoleRepository) UpdateRole(ctx context.Context, role *rbac.Role) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.roles[role.Name]
+	if !ok || existing.BuiltIn {
+		return rbac.ErrRoleNotFound
+	}
+	existing.Description = role.Description
+	existing.Permissions = append([]rbac.Permission{}, role.Permissions...)
+	existing.UpdatedAt = role.UpdatedAt
+	return nil
+}
+
+// SaveBuiltinRole 写入或覆盖内置角色
+func (r *RoleRepository) SaveBuiltinRole(ctx context.Context, role *rbac.Role) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	c := cloneRole(role)
+	c.BuiltIn = true
+	c.CreatedAt, c.UpdatedAt = now, now
+	if existing, ok := r.store.roles[role.Name]; ok {
+		c.CreatedAt = existing.CreatedAt
+	}
+	r.store.roles[role.Name] = c
+	return nil
+}
+
+// DeleteRole 删除自定义角色（同时删除其分配）
+func (r *RoleRepository) DeleteRole(ctx context.Context, name string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	role, ok := r.store.roles[name]
+	if !ok || role.BuiltIn {
+		return rbac.ErrRoleNotFound
+	}
+	delete(r.store.roles, name)
+	for key := range r.store.userRoles {
+		if key.role == name {
+			delete(r.store.userRoles, key)
+		}
+	}
+	return nil
+}
+
+// ListUserRoles 获取用户拥有的角色（按名称排序）
+func (r *RoleRepository) ListUserRoles(ctx context.Context, userID string) ([]*rbac.Role, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var roles []*rbac.Role
+	for key := range r.store.userRoles {
+		if key.userID != userID {
+			continue
+		}
+		if role, ok := r.store.roles[key.role]; ok {
+			roles = append(roles, cloneRole(role))
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+// ListAssignments 获取全部角色分配（按角色名与分配时间排序）
+func (r *RoleRepository) ListAssignments(ctx context.Context) ([]*rbac.Assignment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var items []*rbac.Assignment
+	for _, a := range r.store.userRoles {
+		c := *a
+		items = append(items, &c)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Role != items[j].Role {
+			return items[i].Role < items[j].Role
+		}
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	return items, nil
+}
+
+// Assign 为用户分配角色（已分配时忽略）
+func (r *RoleRepository) Assign(ctx context.Context, a *rbac.Assignment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.roles[a.Role]; !ok {
+		return rbac.ErrRoleNotFound
+	}
+	key := assignmentKey{a.UserID, a.Role}
+	if _, ok := r.store.userRoles[key]; !ok {
+		c := *a
+		r.store.userRoles[key] = &c
+	}
+	return nil
+}
+
+// Revoke 撤销用户的角色
+func (r *RoleRepository) Revoke(ctx context.Context, userID, role string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := assignmentKey{userID, role}
+	if _, ok := r.store.userRoles[key]; !ok {
+		return rbac.ErrAssignmentNotFound
+	}
+	delete(r.store.userRoles, key)
+	return nil
+}
+
+// CountMembers 统计拥有某角色的用户数
+func (r *RoleRepository) CountMembers(ctx context.Context, role string) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	count := 0
+	for key := range r.store.userRoles {
+		if key.role == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func cloneRole(role *rbac.Role) *rbac.Role {
+	c := *role
+	c.Permissions = append([]rbac.Permission{}, role.Permissions...)
+	return &c
+}
@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/session"
+)
+
+// SessionRepository 内存登录会话仓储
+type SessionRepository struct {
+	store *Store
+}
+
+// NewSessionRepository 创建内存会话仓储
+func NewSessionRepository(store *Store) *SessionRepository {
+	return &SessionRepository{store: store}
+}
+
+// Create 创建会话
+func (r *SessionRepository) Create(ctx context.Context, s *session.Session) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.sessions[s.ID]; ok {
+		return fmt.Errorf("failed to create session: duplicate id %s", s.ID)
+	}
+	r.store.sessions[s.ID] = cloneSession(s)
+	return nil
+}
+
+// GetByID 根据 ID 获取会话（包含已吊销的会话）
+func (r *SessionRepository) GetByID(ctx context.Context, id string) (*session.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	s, ok := r.store.sessions[id]
+	if !ok {
+		return nil, session.ErrSessionNotFound
+	}
+	return cloneSession(s), nil
+}
+
+// ListActiveByUser 获取用户未吊销且未过期的会话（按最后活跃时间倒序）
+func (r *SessionRepository) ListActiveByUser(ctx context.Context, userID string) ([]*session.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	var items []*session.Session
+	for _, s := range r.store.sessions {
+		if s.UserID == userID && s.RevokedAt == nil && s.ExpiresAt.After(now) {
+			items = append(items, cloneSession(s))
+		}
+	}
+	sortByTime(items, func(s *session.Session) time.Time { return s.LastSeenAt }, true)
+	return items, nil
+}
+
+// Rotate 轮换 refresh token：仅当当前 jti 为 previousJTI 时更新，返回是否成功
+func (r *SessionRepository) Rotate(ctx context.Context, id, previousJTI, nextJTI string, expiresAt time.Time, client session.Client) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	s, ok := r.store.sessions[id]
+	if !ok || s.RefreshJTI != previousJTI || s.RevokedAt != nil {
+		return false, nil
+	}
+	s.RefreshJTI = nextJTI
+	s.ExpiresAt = expiresAt
+	s.LastSeenAt = time.Now()
+	s.IP = client.IP
+	s.UserAgent = client.UserAgent
+	return true, nil
+}
+
+// Touch 更新最后活跃时间
+func (r *SessionRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if s, ok := r.store.sessions[id]; ok {
+		s.LastSeenAt = at
+	}
+	return nil
+}
+
+// Revoke 吊销会话
+func (r *SessionRepository) Revoke(ctx context.Context, id, reason string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if s, ok := r.store.sessions[id]; ok && s.RevokedAt == nil {
+		now := time.Now()
+		s.RevokedAt = &now
+		s.RevokeReason = reason
+	}
+	return nil
+}
+
+// RevokeByUser 吊销用户的全部会话，exceptID 非空时保留该会话
+func (r *SessionRepository) RevokeByUser(ctx context.Context, userID, exceptID, reason string) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	var revoked int64
+	for id, s := range r.store.sessions {
+		if s.UserID == userID && id != exceptID && s.RevokedAt == nil {
+			revokedAt := now
+			s.RevokedAt = &revokedAt
+			s.RevokeReason = reason
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// DeleteExpired 删除在 before 之前过期的会话
+func (r *SessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var deleted int64
+	for id, s := range r.store.sessions {
+		if s.ExpiresAt.Before(before) {
+			delete(r.store.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func cloneSession(s *session.Session) *session.Session {
+	c := *s
+	c.RevokedAt = cloneTime(s.RevokedAt)
+	return &c
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/share"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
+)
+
+// ShareRepository 内存分享仓储
+type ShareRepository struct {
+	store *Store
+}
+
+// NewShareRepository 创建内存分享仓储
+func NewShareRepository(store *Store) *ShareRepository {
+	return &ShareRepository{store: store}
+}
+
+// Create 创建分享记录
+func (r *ShareRepository) Create(ctx context.Context, item *share.ShareItem) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.shares[item.Token]; ok {
+		return fmt.Errorf("failed to create share item: duplicate token")
+	}
+	r.store.shares[item.Token] = cloneShare(item)
+	return nil
+}
+
+// GetByToken 根据 token 获取分享记录
+func (r *ShareRepository) GetByToken(ctx context.Context, token string) (*share.ShareItem, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	item, ok := r.store.shares[token]
+	if !ok {
+		return nil, share.ErrShareNotFound
+	}
+	return cloneShare(item), nil
+}
+
+// GetByUserID 获取用户的分享记录（按创建时间倒序）
+func (r *ShareRepository) GetByUserID(ctx context.Context, userID string) ([]*share.ShareItem, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var items []*share.ShareItem
+	for _, item := range r.store.shares {
+		if item.UserID == userID {
+			items = append(items, cloneShare(item))
+		}
+	}
+	sortByTime(items, func(item *share.ShareItem) time.Time { return item.CreatedAt }, true)
+	return items, nil
+}
+
+// DeleteByToken 删除分享记录
+func (r *ShareRepository) DeleteByToken(ctx context.Context, token string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.shares[token]; !ok {
+		return share.ErrShareNotFound
+	}
+	delete(r.store.shares, token)
+	return nil
+}
+
+// IncrementView 增加访问次数
+func (r *ShareRepository) IncrementView(ctx context.Context, token string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	item, ok := r.store.shares[token]
+	if !ok {
+		return share.ErrShareNotFound
+	}
+	item.ViewCount++
+	return nil
+}
+
+// IncrementDownload 增加下载次数
+func (r *ShareRepository) IncrementDownload(ctx context.Context, token string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	item, ok := r.store.shares[token]
+	if !ok {
+		return share.ErrShareNotFound
+	}
+	item.DownloadCount++
+	return nil
+}
+
+// cloneShare 复制分享记录；ContentHash 不持久化，访问条件按存储格式编解码复制
+func cloneShare(item *share.ShareItem) *share.ShareItem {
+	c := *item
+	c.ExpiresAt = cloneTime(item.ExpiresAt)
+	c.Condition = cloneCondition(item.Condition)
+	c.ContentHash = ""
+	return &c
+}
+
+func cloneCondition(cond *tokengate.Condition) *tokengate.Condition {
+	c, err := tokengate.ParseCondition(cond.Encode())
+	if err != nil {
+		return nil
+	}
+	return c
+}
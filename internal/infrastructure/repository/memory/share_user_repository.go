@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/shareuser"
+)
+
+// UserShareRepository 内存定向分享仓储
+type UserShareRepository struct {
+	store *Store
+}
+
+// NewUserShareRepository 创建内存定向分享仓储
+func NewUserShareRepository(store *Store) *UserShareRepository {
+	return &UserShareRepository{store: store}
+}
+
+// Create 创建定向分享
+func (r *UserShareRepository) Create(ctx context.Context, item *shareuser.ShareUserItem) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.userShares[item.ID]; ok {
+		return fmt.Errorf("failed to create share user item: duplicate id %s", item.ID)
+	}
+	r.store.userShares[item.ID] = cloneUserShare(item)
+	return nil
+}
+
+// GetByID 根据 ID 获取定向分享
+func (r *UserShareRepository) GetByID(ctx context.Context, id string) (*shareuser.ShareUserItem, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	item, ok := r.store.userShares[id]
+	if !ok {
+		return nil, shareuser.ErrShareNotFound
+	}
+	return cloneUserShare(item), nil
+}
+
+// GetByOwnerID 获取用户发出的定向分享（按创建时间倒序）
+func (r *UserShareRepository) GetByOwnerID(ctx context.Context, ownerID string) ([]*shareuser.ShareUserItem, error) {
+	return r.list(func(item *shareuser.ShareUserItem) bool { return item.OwnerUserID == ownerID }), nil
+}
+
+// GetByTargetID 获取用户收到的定向分享（按创建时间倒序）
+func (r *UserShareRepository) GetByTargetID(ctx context.Context, targetID string) ([]*shareuser.ShareUserItem, error) {
+	return r.list(func(item *shareuser.ShareUserItem) bool { return item.TargetUserID == targetID }), nil
+}
+
+// DeleteByID 删除定向分享
+func (r *UserShareRepository) DeleteByID(ctx context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.userShares[id]; !ok {
+		return shareuser.ErrShareNotFound
+	}
+	delete(r.store.userShares, id)
+	return nil
+}
+
+func (r *UserShareRepository) list(match func(*shareuser.ShareUserItem) bool) []*shareuser.ShareUserItem {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var items []*shareuser.ShareUserItem
+	for _, item := range r.store.userShares {
+		if match(item) {
+			items = append(items, cloneUserShare(item))
+		}
+	}
+	sortByTime(items, func(item *shareuser.ShareUserItem) time.Time { return item.CreatedAt }, true)
+	return items
+}
+
+// cloneUserShare 复制定向分享；端到端加密信息不持久化
+func cloneUserShare(item *shareuser.ShareUserItem) *shareuser.ShareUserItem {
+	c := *item
+	c.ExpiresAt = cloneTime(item.ExpiresAt)
+	c.Condition = cloneCondition(item.Condition)
+	c.E2EEFolderID = ""
+	c.KeyEnvelope = ""
+	return &c
+}
@@ -0,0 +1,246 @@
+// Package memory 提供全部仓储接口的内存实现
+// 行为（错误、排序、唯一约束与删除用户时的级联）与 PostgreSQL 实现一致，
+// 用于测试与不需要持久化的场景；进程退出后数据丢失。
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/addressbook"
+	"github.com/yeying-community/warehouse/internal/domain/apppassword"
+	"github.com/yeying-community/warehouse/internal/domain/audit"
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/datakey"
+	"github.com/yeying-community/warehouse/internal/domain/e2ee"
+	"github.com/yeying-community/warehouse/internal/domain/filemeta"
+	"github.com/yeying-community/warehouse/internal/domain/identity"
+	"github.com/yeying-community/warehouse/internal/domain/mfa"
+	"github.com/yeying-community/warehouse/internal/domain/outbox"
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
+	"github.com/yeying-community/warehouse/internal/domain/recycle"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/share"
+	"github.com/yeying-community/warehouse/internal/domain/shareuser"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/domain/webauthn"
+	"github.com/yeying-community/warehouse/internal/infrastructure/repository"
+)
+
+// Store 内存数据存储，同一 Store 上创建的仓储共享数据（相当于同一个数据库）
+// 所有读写由一把互斥锁串行化；仓储读写的都是副本，调用方修改返回值不会影响存储。
+type Store struct {
+	mu sync.Mutex
+
+	users        map[string]*user.User
+	identities   map[string]*identity.Identity
+	recycle      map[string]*recycle.RecycleItem
+	shares       map[string]*share.ShareItem
+	userShares   map[string]*shareuser.ShareUserItem
+	groups       map[string]*addressbook.Group
+	contacts     map[string]*addressbook.Contact
+	fileMetadata map[fileKey]*filemeta.FileMetadata
+	dataKeys     map[dataKeyKey]*datakey.DataKey
+	publicKeys   map[string]*e2ee.WalletPublicKey
+	folders      map[string]*e2ee.Folder
+	envelopes    map[envelopeKey]*e2ee.KeyEnvelope
+	sessions     map[string]*session.Session
+	appPasswords map[string]*apppassword.AppPassword
+	mfa          map[string]*mfa.Enrollment
+	credentials  map[string]*webauthn.Credential
+	roles        map[string]*rbac.Role
+	userRoles    map[assignmentKey]*rbac.Assignment
+	usedTokens   map[string]*auth.MailToken
+	outbox       map[string]*outbox.Message
+	auditEvents  []*audit.Event
+	auditSeq     int64
+}
+
+type fileKey struct {
+	userID string
+	path   string
+}
+
+type dataKeyKey struct {
+	userID  string
+	version uint32
+}
+
+type envelopeKey struct {
+	folderID string
+	wallet   string
+}
+
+type assignmentKey struct {
+	userID string
+	role   string
+}
+
+// NewStore 创建空的内存存储
+func NewStore() *Store {
+	return &Store{
+		users:        make(map[string]*user.User),
+		identities:   make(map[string]*identity.Identity),
+		recycle:      make(map[string]*recycle.RecycleItem),
+		shares:       make(map[string]*share.ShareItem),
+		userShares:   make(map[string]*shareuser.ShareUserItem),
+		groups:       make(map[string]*addressbook.Group),
+		contacts:     make(map[string]*addressbook.Contact),
+		fileMetadata: make(map[fileKey]*filemeta.FileMetadata),
+		dataKeys:     make(map[dataKeyKey]*datakey.DataKey),
+		publicKeys:   make(map[string]*e2ee.WalletPublicKey),
+		folders:      make(map[string]*e2ee.Folder),
+		envelopes:    make(map[envelopeKey]*e2ee.KeyEnvelope),
+		sessions:     make(map[string]*session.Session),
+		appPasswords: make(map[string]*apppassword.AppPassword),
+		mfa:          make(map[string]*mfa.Enrollment),
+		credentials:  make(map[string]*webauthn.Credential),
+		roles:        make(map[string]*rbac.Role),
+		userRoles:    make(map[assignmentKey]*rbac.Assignment),
+		usedTokens:   make(map[string]*auth.MailToken),
+		outbox:       make(map[string]*outbox.Message),
+	}
+}
+
+// deleteUserLocked 删除用户及其全部数据，对应数据库外键的 ON DELETE CASCADE；调用方需持有锁
+func (s *Store) deleteUserLocked(userID string) {
+	delete(s.users, userID)
+	for id, ident := range s.identities {
+		if ident.UserID == userID {
+			delete(s.identities, id)
+		}
+	}
+	for hash, item := range s.recycle {
+		if item.UserID == userID {
+			delete(s.recycle, hash)
+		}
+	}
+	for token, item := range s.shares {
+		if item.UserID == userID {
+			delete(s.shares, token)
+		}
+	}
+	for id, item := range s.userShares {
+		if item.OwnerUserID == userID || item.TargetUserID == userID {
+			delete(s.userShares, id)
+		}
+	}
+	for id, contact := range s.contacts {
+		if contact.UserID == userID {
+			delete(s.contacts, id)
+		}
+	}
+	for id, group := range s.groups {
+		if group.UserID == userID {
+			s.deleteGroupLocked(id)
+		}
+	}
+	for key := range s.fileMetadata {
+		if key.userID == userID {
+			delete(s.fileMetadata, key)
+		}
+	}
+	for key := range s.dataKeys {
+		if key.userID == userID {
+			delete(s.dataKeys, key)
+		}
+	}
+	for id, folder := range s.folders {
+		if folder.OwnerUserID == userID {
+			s.deleteFolderLocked(id)
+		}
+	}
+	for id, sess := range s.sessions {
+		if sess.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+	for id, p := range s.appPasswords {
+		if p.UserID == userID {
+			delete(s.appPasswords, id)
+		}
+	}
+	delete(s.mfa, userID)
+	for id, cred := range s.credentials {
+		if cred.UserID == userID {
+			delete(s.credentials, id)
+		}
+	}
+	for key := range s.userRoles {
+		if key.userID == userID {
+			delete(s.userRoles, key)
+		}
+	}
+	for jti, token := range s.usedTokens {
+		if token.UserID == userID {
+			delete(s.usedTokens, jti)
+		}
+	}
+}
+
+// deleteGroupLocked 删除分组，组内联系人的分组置空（ON DELETE SET NULL）
+func (s *Store) deleteGroupLocked(groupID string) {
+	delete(s.groups, groupID)
+	for _, contact := range s.contacts {
+		if contact.GroupID == groupID {
+			contact.GroupID = ""
+		}
+	}
+}
+
+// deleteFolderLocked 删除加密目录及其密钥信封
+func (s *Store) deleteFolderLocked(folderID string) {
+	delete(s.folders, folderID)
+	for key := range s.envelopes {
+		if key.folderID == folderID {
+			delete(s.envelopes, key)
+		}
+	}
+}
+
+// sortByTime 按时间排序，desc 为 true 时倒序
+func sortByTime[T any](items []T, at func(T) time.Time, desc bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if desc {
+			return at(items[i]).After(at(items[j]))
+		}
+		return at(items[i]).Before(at(items[j]))
+	})
+}
+
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string(nil), values...)
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := *t
+	return &v
+}
+
+// 编译期确认内存实现满足全部仓储接口
+var (
+	_ user.Repository                   = (*UserRepository)(nil)
+	_ repository.RecycleRepository      = (*RecycleRepository)(nil)
+	_ repository.ShareRepository        = (*ShareRepository)(nil)
+	_ repository.UserShareRepository    = (*UserShareRepository)(nil)
+	_ repository.AddressBookRepository  = (*AddressBookRepository)(nil)
+	_ repository.FileMetadataRepository = (*FileMetadataRepository)(nil)
+	_ repository.DataKeyRepository      = (*DataKeyRepository)(nil)
+	_ repository.E2EERepository         = (*E2EERepository)(nil)
+	_ repository.IdentityRepository     = (*IdentityRepository)(nil)
+	_ repository.SessionRepository      = (*SessionRepository)(nil)
+	_ repository.AppPasswordRepository  = (*AppPasswordRepository)(nil)
+	_ repository.MFARepository          = (*MFARepository)(nil)
+	_ repository.WebAuthnRepository     = (*WebAuthnRepository)(nil)
+	_ repository.RoleRepository         = (*RoleRepository)(nil)
+	_ repository.AuditRepository        = (*AuditRepository)(nil)
+	_ repository.UsedTokenRepository    = (*UsedTokenRepository)(nil)
+	_ repository.OutboxRepository       = (*OutboxRepository)(nil)
+)
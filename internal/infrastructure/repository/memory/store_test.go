@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yeying-community/warehouse/internal/domain/addressbook"
+	"github.com/yeying-community/warehouse/internal/domain/share"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+)
+
+func TestUserRepositoryConstraints(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(NewStore())
+
+	alice := user.NewUser("alice", "alice")
+	if err := alice.SetWalletAddress("0x1111111111111111111111111111111111111111"); err != nil {
+		t.Fatalf("SetWalletAddress: %v", err)
+	}
+	if err := repo.Save(ctx, alice); err != nil {
+		t.Fatalf("Save alice: %v", err)
+	}
+
+	dup := user.NewUser("alice", "other")
+	if err := repo.Save(ctx, dup); !errors.Is(err, user.ErrDuplicateUsername) {
+		t.Fatalf("expected ErrDuplicateUsername, got %v", err)
+	}
+
+	bob := user.NewUser("bob", "bob")
+	if err := bob.SetWalletAddress("0x1111111111111111111111111111111111111111"); err != nil {
+		t.Fatalf("SetWalletAddress: %v", err)
+	}
+	if err := repo.Save(ctx, bob); !errors.Is(err, user.ErrDuplicateAddress) {
+		t.Fatalf("expected ErrDuplicateAddress, got %v", err)
+	}
+
+	found, err := repo.FindByWalletAddress(ctx, "0x1111111111111111111111111111111111111111")
+	if err != nil || found.ID != alice.ID {
+		t.Fatalf("FindByWalletAddress: %v %+v", err, found)
+	}
+
+	// 返回值是副本，修改不影响存储
+	found.Username = "mallory"
+	again, _ := repo.FindByID(ctx, alice.ID)
+	if again.Username != "alice" {
+		t.Fatalf("stored user was modified through returned copy: %s", again.Username)
+	}
+}
+
+func TestDeleteUserCascades(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	users := NewUserRepository(store)
+	shares := NewShareRepository(store)
+	book := NewAddressBookRepository(store)
+
+	u := user.NewUser("alice", "alice")
+	if err := users.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	item := share.NewShareItem(u.ID, u.Username, "/personal/a.txt", "a.txt", nil)
+	if err := shares.Create(ctx, item); err != nil {
+		t.Fatalf("Create share: %v", err)
+	}
+
+	group, err := addressbook.NewGroup(u.ID, "friends")
+	if err != nil {
+		t.Fatalf("NewGroup: %v", err)
+	}
+	if err := book.CreateGroup(ctx, group); err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	contact, err := addressbook.NewContact(u.ID, group.ID, "bob", "0x2222222222222222222222222222222222222222", nil)
+	if err != nil {
+		t.Fatalf("NewContact: %v", err)
+	}
+	if err := book.CreateContact(ctx, contact); err != nil {
+		t.Fatalf("CreateContact: %v", err)
+	}
+
+	// 删除分组时联系人保留，分组置空
+	if err := book.DeleteGroup(ctx, u.ID, group.ID); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	kept, err := book.GetContactByID(ctx, u.ID, contact.ID)
+	if err != nil || kept.GroupID != "" {
+		t.Fatalf("expected contact without group, got %+v (%v)", kept, err)
+	}
+
+	if err := users.Delete(ctx, u.Username); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := shares.GetByToken(ctx, item.Token); !errors.Is(err, share.ErrShareNotFound) {
+		t.Fatalf("expected share removed with user, got %v", err)
+	}
+	if _, err := book.GetContactByID(ctx, u.ID, contact.ID); !errors.Is(err, addressbook.ErrContactNotFound) {
+		t.Fatalf("expected contact removed with user, got %v", err)
+	}
+}
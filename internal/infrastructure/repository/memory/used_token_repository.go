@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+)
+
+// UsedTokenRepository 内存一次性令牌仓储
+type UsedTokenRepository struct {
+	store *Store
+}
+
+// NewUsedTokenRepository 创建内存一次性令牌仓储
+func NewUsedTokenRepository(store *Store) *UsedTokenRepository {
+	return &UsedTokenRepository{store: store}
+}
+
+// Consume 记录令牌已使用，顺带删除过期的记录
+func (r *UsedTokenRepository) Consume(ctx context.Context, token *auth.MailToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for jti, used := range r.store.usedTokens {
+		if used.ExpiresAt.Before(now) {
+			delete(r.store.usedTokens, jti)
+		}
+	}
+	if _, ok := r.store.usedTokens[token.ID]; ok {
+		return auth.ErrTokenUsed
+	}
+	c := *token
+	r.store.usedTokens[token.ID] = &c
+	return nil
+}
@@ -0,0 +1,289 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yeying-community/warehouse/internal/domain/account"
+	"github.com/yeying-community/warehouse/internal/domain/identity"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+)
+
+// UserRepository 内存用户仓储
+type UserRepository struct {
+	store *Store
+}
+
+// NewUserRepository 创建内存用户仓储
+func NewUserRepository(store *Store) *UserRepository {
+	return &UserRepository{store: store}
+}
+
+// FindByUsername 根据用户名查找用户
+func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*user.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, u := range r.store.users {
+		if u.Username == username {
+			return cloneUser(u), nil
+		}
+	}
+	return nil, user.ErrUserNotFound
+}
+
+// FindByWalletAddress 根据钱包账户查找用户（按登录身份匹配，包含关联的其他钱包）
+func (r *UserRepository) FindByWalletAddress(ctx context.Context, address string) (*user.User, error) {
+	key, err := account.Normalize(address)
+	if err != nil {
+		return nil, user.ErrUserNotFound
+	}
+	return r.findByIdentity(identity.TypeWallet, key)
+}
+
+// FindByEmail 根据邮箱查找用户（按登录身份匹配，包含关联的其他邮箱）
+func (r *UserRepository) FindByEmail(ctx context.Context, emailAddress string) (*user.User, error) {
+	subject, err := identity.NormalizeSubject(identity.TypeEmail, emailAddress)
+	if err != nil {
+		return nil, user.ErrUserNotFound
+	}
+	return r.findByIdentity(identity.TypeEmail, subject)
+}
+
+// FindByID 根据 ID 查找用户
+func (r *UserRepository) FindByID(ctx context.Context, id string) (*user.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u, ok := r.store.users[id]
+	if !ok {
+		return nil, user.ErrUserNotFound
+	}
+	return cloneUser(u), nil
+}
+
+// Save 保存用户，并同步主钱包、邮箱与密码对应的登录身份
+func (r *UserRepository) Save(ctx context.Context, u *user.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var previousWallet, previousEmail string
+	if existing, ok := r.store.users[u.ID]; ok {
+		previousWallet, previousEmail = existing.WalletAddress, existing.Email
+	}
+
+	for id, other := range r.store.users {
+		if id == u.ID {
+			continue
+		}
+		if other.Username == u.Username {
+			return user.ErrDuplicateUsername
+		}
+		if u.WalletAddress != "" && other.WalletAddress == u.WalletAddress {
+			return user.ErrDuplicateAddress
+		}
+		if u.Email != "" && other.Email == u.Email {
+			return user.ErrDuplicateEmail
+		}
+	}
+
+	// 先检查再写入，身份冲突时用户与身份都保持原样（对应事务回滚）
+	wallet, err := r.checkIdentity(u.ID, identity.TypeWallet, u.WalletAddress, user.ErrDuplicateAddress)
+	if err != nil {
+		return err
+	}
+	email, err := r.checkIdentity(u.ID, identity.TypeEmail, u.Email, user.ErrDuplicateEmail)
+	if err != nil {
+		return err
+	}
+	if u.Password != "" {
+		if _, err := r.checkIdentity(u.ID, identity.TypePassword, u.Username, user.ErrDuplicateUsername); err != nil {
+			return err
+		}
+	}
+
+	stored := cloneUser(u)
+	if stored.Email == "" {
+		stored.EmailVerifiedAt = nil
+	}
+	if existing, ok := r.store.users[u.ID]; ok {
+		// 更新保留创建时间，更新时间取当前时间（对应 users 表的触发器）
+		stored.CreatedAt, stored.UpdatedAt = existing.CreatedAt, time.Now()
+	}
+	r.store.users[u.ID] = stored
+
+	r.syncPrimaryIdentity(u.ID, identity.TypeWallet, normalizeSubject(identity.TypeWallet, previousWallet), wallet)
+	r.syncPrimaryIdentity(u.ID, identity.TypeEmail, normalizeSubject(identity.TypeEmail, previousEmail), email)
+	for id, ident := range r.store.identities {
+		if ident.UserID == u.ID && ident.Type == identity.TypePassword && (u.Password == "" || ident.Subject != u.Username) {
+			delete(r.store.identities, id)
+		}
+	}
+	if u.Password != "" {
+		r.insertIdentity(u.ID, identity.TypePassword, u.Username)
+	}
+	return nil
+}
+
+// Delete 删除用户（级联删除其全部数据）
+func (r *UserRepository) Delete(ctx context.Context, username string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u := r.findByUsernameLocked(username)
+	if u == nil {
+		return user.ErrUserNotFound
+	}
+	r.store.deleteUserLocked(u.ID)
+	return nil
+}
+
+// List 列出所有用户（按创建时间倒序）
+func (r *UserRepository) List(ctx context.Context) ([]*user.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var users []*user.User
+	for _, u := range r.store.users {
+		users = append(users, cloneUser(u))
+	}
+	sortByTime(users, func(u *user.User) time.Time { return u.CreatedAt }, true)
+	return users, nil
+}
+
+// UpdateUsedSpace 更新用户已使用空间
+func (r *UserRepository) UpdateUsedSpace(ctx context.Context, username string, usedSpace int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u := r.findByUsernameLocked(username)
+	if u == nil {
+		return user.ErrUserNotFound
+	}
+	u.UsedSpace = usedSpace
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// UpdateQuota 更新用户配额
+func (r *UserRepository) UpdateQuota(ctx context.Context, username string, quota int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	u := r.findByUsernameLocked(username)
+	if u == nil {
+		return user.ErrUserNotFound
+	}
+	u.Quota = quota
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *UserRepository) findByIdentity(t identity.Type, subject string) (*user.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	ident := r.store.findIdentityLocked(t, subject)
+	if ident == nil {
+		return nil, user.ErrUserNotFound
+	}
+	u, ok := r.store.users[ident.UserID]
+	if !ok {
+		return nil, user.ErrUserNotFound
+	}
+	return cloneUser(u), nil
+}
+
+func (r *UserRepository) findByUsernameLocked(username string) *user.User {
+	for _, u := range r.store.users {
+		if u.Username == username {
+			return u
+		}
+	}
+	return nil
+}
+
+// checkIdentity 规范化身份 subject，身份已属于其他用户时返回 duplicate
+func (r *UserRepository) checkIdentity(userID string, t identity.Type, subject string, duplicate error) (string, error) {
+	if subject == "" {
+		return "", nil
+	}
+	normalized, err := identity.NormalizeSubject(t, subject)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s identity: %w", t, err)
+	}
+	if ident := r.store.findIdentityLocked(t, normalized); ident != nil && ident.UserID != userID {
+		return "", duplicate
+	}
+	return normalized, nil
+}
+
+// syncPrimaryIdentity 主钱包或邮箱变化时替换对应的登录身份
+func (r *UserRepository) syncPrimaryIdentity(userID string, t identity.Type, previous, current string) {
+	if previous != "" && previous != current {
+		for id, ident := range r.store.identities {
+			if ident.UserID == userID && ident.Type == t && ident.Subject == previous {
+				delete(r.store.identities, id)
+			}
+		}
+	}
+	if current != "" {
+		r.insertIdentity(userID, t, current)
+	}
+}
+
+func (r *UserRepository) insertIdentity(userID string, t identity.Type, subject string) {
+	if r.store.findIdentityLocked(t, subject) != nil {
+		return
+	}
+	id := uuid.NewString()
+	r.store.identities[id] = &identity.Identity{
+		ID:        id,
+		UserID:    userID,
+		Type:      t,
+		Subject:   subject,
+		CreatedAt: time.Now(),
+	}
+}
+
+// normalizeSubject 规范化身份 subject，无法规范化时原样返回
+func normalizeSubject(t identity.Type, subject string) string {
+	if normalized, err := identity.NormalizeSubject(t, subject); err == nil {
+		return normalized
+	}
+	return subject
+}
+
+func cloneUser(u *user.User) *user.User {
+	c := &user.User{
+		ID:              u.ID,
+		Username:        u.Username,
+		Password:        u.Password,
+		WalletAddress:   u.WalletAddress,
+		Email:           u.Email,
+		EmailVerifiedAt: cloneTime(u.EmailVerifiedAt),
+		Directory:       u.Directory,
+		Quota:           u.Quota,
+		UsedSpace:       u.UsedSpace,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+	}
+	if u.Permissions != nil {
+		p := *u.Permissions
+		c.Permissions = &p
+	} else {
+		c.Permissions = user.ParsePermissions("")
+	}
+	for _, rule := range u.Rules {
+		// Rule 内含正则缓存，不能按值复制
+		clone := &user.Rule{Path: rule.Path, Regex: rule.Regex}
+		if rule.Permissions != nil {
+			p := *rule.Permissions
+			clone.Permissions = &p
+		}
+		c.Rules = append(c.Rules, clone)
+	}
+	return c
+}
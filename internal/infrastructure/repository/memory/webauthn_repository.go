@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/webauthn"
+)
+
+// WebAuthnRepository 内存通行密钥凭证仓储
+type WebAuthnRepository struct {
+	store *Store
+}
+
+// NewWebAuthnRepository 创建内存通行密钥仓储
+func NewWebAuthnRepository(store *Store) *WebAuthnRepository {
+	return &WebAuthnRepository{store: store}
+}
+
+// Create 保存新注册的凭证，凭证 ID 全局唯一
+func (r *WebAuthnRepository) Create(ctx context.Context, cred *webauthn.Credential) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.credentials[cred.ID]; ok {
+		return webauthn.ErrCredentialExists
+	}
+	c := cloneCredential(cred)
+	c.LastUsedAt = nil
+	r.store.credentials[cred.ID] = c
+	return nil
+}
+
+// GetByID 根据凭证 ID 查找
+func (r *WebAuthnRepository) GetByID(ctx context.Context, id string) (*webauthn.Credential, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	cred, ok := r.store.credentials[id]
+	if !ok {
+		return nil, webauthn.ErrCredentialNotFound
+	}
+	return cloneCredential(cred), nil
+}
+
+// ListByUser 获取用户的全部凭证（按注册时间倒序）
+func (r *WebAuthnRepository) ListByUser(ctx context.Context, userID string) ([]*webauthn.Credential, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var items []*webauthn.Credential
+	for _, cred := range r.store.credentials {
+		if cred.UserID == userID {
+			items = append(items, cloneCredential(cred))
+		}
+	}
+	sortByTime(items, func(c *webauthn.Credential) time.Time { return c.CreatedAt }, true)
+	return items, nil
+}
+
+// UpdateUsage 以比较并交换的方式更新签名计数器、备份状态与最近使用时间
+func (r *WebAuthnRepository) UpdateUsage(ctx context.Context, id string, signCount uint32, backupState bool, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	cred, ok := r.store.credentials[id]
+	if !ok || !(cred.SignCount < signCount || (signCount == 0 && cred.SignCount == 0)) {
+		return webauthn.ErrCloneDetected
+	}
+	cred.SignCount = signCount
+	cred.BackupState = backupState
+	cred.LastUsedAt = &at
+	return nil
+}
+
+// Delete 删除用户的凭证
+func (r *WebAuthnRepository) Delete(ctx context.Context, userID, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	cred, ok := r.store.credentials[id]
+	if !ok || cred.UserID != userID {
+		return webauthn.ErrCredentialNotFound
+	}
+	delete(r.store.credentials, id)
+	return nil
+}
+
+func cloneCredential(cred *webauthn.Credential) *webauthn.Credential {
+	c := *cred
+	c.PublicKey = append([]byte(nil), cred.PublicKey...)
+	c.Transports = cloneStrings(cred.Transports)
+	if c.Transports == nil {
+		c.Transports = []string{}
+	}
+	c.LastUsedAt = cloneTime(cred.LastUsedAt)
+	return &c
+}
@@ -39,7 +39,7 @@ func (r *PostgresAddressBookRepository) CreateGroup(ctx context.Context, group *
 	`
 	_, err := r.db.ExecContext(ctx, query, group.ID, group.UserID, group.Name, group.CreatedAt)
 	if err != nil {
-		if strings.Contains(err.Error(), "idx_address_groups_user_name") {
+		if isUniqueViolation(err) {
 			return addressbook.ErrDuplicateGroupName
 		}
 		return fmt.Errorf("failed to create group: %w", err)
@@ -100,7 +100,7 @@ func (r *PostgresAddressBookRepository) UpdateGroupName(ctx context.Context, use
 	query := `UPDATE address_groups SET name = $1 WHERE id = $2 AND user_id = $3`
 	result, err := r.db.ExecContext(ctx, query, name, groupID, userID)
 	if err != nil {
-		if strings.Contains(err.Error(), "idx_address_groups_user_name") {
+		if isUniqueViolation(err) {
 			return addressbook.ErrDuplicateGroupName
 		}
 		return fmt.Errorf("failed to update group: %w", err)
@@ -152,7 +152,7 @@ func (r *PostgresAddressBookRepository) CreateContact(ctx context.Context, conta
 		contact.CreatedAt,
 	)
 	if err != nil {
-		if strings.Contains(err.Error(), "idx_address_contacts_user_wallet") {
+		if isUniqueViolation(err) {
 			return addressbook.ErrDuplicateWallet
 		}
 		return fmt.Errorf("failed to create contact: %w", err)
@@ -246,7 +246,7 @@ func (r *PostgresAddressBookRepository) UpdateContact(ctx context.Context, conta
 	}
 	result, err := r.db.ExecContext(ctx, query, groupID, contact.Name, contact.WalletAddress, pq.Array(contact.Tags), contact.ID, contact.UserID)
 	if err != nil {
-		if strings.Contains(err.Error(), "idx_address_contacts_user_wallet") {
+		if isUniqueViolation(err) {
 			return addressbook.ErrDuplicateWallet
 		}
 		return fmt.Errorf("failed to update contact: %w", err)
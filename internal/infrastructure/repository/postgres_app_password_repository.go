@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yeying-community/warehouse/internal/domain/apppassword"
+)
+
+// AppPasswordRepository 应用专用密码仓储接口
+type AppPasswordRepository interface {
+	// Create 创建应用专用密码
+	Create(ctx context.Context, p *apppassword.AppPassword) error
+
+	// ListByUser 获取用户的全部应用专用密码
+	ListByUser(ctx context.Context, userID string) ([]*apppassword.AppPassword, error)
+
+	// FindByHash 根据密码哈希查找应用专用密码（哈希全局唯一）
+	FindByHash(ctx context.Context, hash string) (*apppassword.AppPassword, error)
+
+	// Delete 吊销应用专用密码
+	Delete(ctx context.Context, userID, id string) error
+
+	// TouchLastUsed 记录最近使用时间与 IP
+	TouchLastUsed(ctx context.Context, id string, at time.Time, ip string) error
+}
+
+// PostgresAppPasswordRepository PostgreSQL 实现
+type PostgresAppPasswordRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresAppPasswordRepository 创建 PostgreSQL 应用专用密码仓储
+func NewPostgresAppPasswordRepository(db *sql.DB) *PostgresAppPasswordRepository {
+	return &PostgresAppPasswordRepository{db: db}
+}
+
+const appPasswordColumns = `id, user_id, name, password_hash, read_only, paths, created_at, last_used_at, last_used_ip`
+
+// Create 创建应用专用密码
+func (r *PostgresAppPasswordRepository) Create(ctx context.Context, p *apppassword.AppPassword) error {
+	query := `
+		INSERT INTO user_app_passwords (id, user_id, name, password_hash, read_only, paths, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		p.ID, p.UserID, p.Name, p.Hash, p.ReadOnly, pq.Array(p.Paths), p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create app password: %w", err)
+	}
+	return nil
+}
+
+// ListByUser 获取用户的全部应用专用密码
+func (r *PostgresAppPasswordRepository) ListByUser(ctx context.Context, userID string) ([]*apppassword.AppPassword, error) {
+	query := `
+		SELECT ` + appPasswordColumns + `
+		FROM user_app_passwords
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list app passwords: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*apppassword.AppPassword
+	for rows.Next() {
+		p, err := scanAppPassword(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate app passwords: %w", err)
+	}
+	return items, nil
+}
+
+// FindByHash 根据密码哈希查找
+func (r *PostgresAppPasswordRepository) FindByHash(ctx context.Context, hash string) (*apppassword.AppPassword, error) {
+	query := `SELECT ` + appPasswordColumns + ` FROM user_app_passwords WHERE password_hash = $1`
+	p, err := scanAppPassword(r.db.QueryRowContext(ctx, query, hash))
+	if err == sql.ErrNoRows {
+		return nil, apppassword.ErrAppPasswordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Delete 吊销应用专用密码
+func (r *PostgresAppPasswordRepository) Delete(ctx context.Context, userID, id string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM user_app_passwords WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete app password: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return apppassword.ErrAppPasswordNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed 记录最近使用时间与 IP
+func (r *PostgresAppPasswordRepository) TouchLastUsed(ctx context.Context, id string, at time.Time, ip string) error {
+	query := `UPDATE user_app_passwords SET last_used_at = $2, last_used_ip = $3 WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, id, at, ip); err != nil {
+		return fmt.Errorf("failed to update app password last used: %w", err)
+	}
+	return nil
+}
+
+func scanAppPassword(row rowScanner) (*apppassword.AppPassword, error) {
+	p := &apppassword.AppPassword{}
+	var lastUsedAt sql.NullTime
+	err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.Hash, &p.ReadOnly, pq.Array(&p.Paths),
+		&p.CreatedAt, &lastUsedAt, &p.LastUsedIP)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan app password: %w", err)
+	}
+	if lastUsedAt.Valid {
+		p.LastUsedAt = &lastUsedAt.Time
+	}
+	return p, nil
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/audit"
+)
+
+// AuditRepository 审计日志仓储接口（只追加，不提供修改）
+type AuditRepository interface {
+	// Append 追加一条审计事件
+	Append(ctx context.Context, e *audit.Event) error
+
+	// Query 按条件分页查询审计事件（按时间倒序），同时返回符合条件的总数
+	Query(ctx context.Context, filter audit.Filter) ([]*audit.Event, int, error)
+
+	// Iterate 按时间顺序遍历符合条件的全部审计事件（忽略分页），用于导出
+	Iterate(ctx context.Context, filter audit.Filter, fn func(*audit.Event) error) error
+
+	// DeleteBefore 删除 before 之前的审计事件（保留期清理）
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PostgresAuditRepository PostgreSQL 实现
+type PostgresAuditRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresAuditRepository 创建 PostgreSQL 审计日志仓储
+func NewPostgresAuditRepository(db *sql.DB) *PostgresAuditRepository {
+	return &PostgresAuditRepository{db: db}
+}
+
+const auditColumns = `id, created_at, action, outcome, actor_id, actor, ip, user_agent, app_id, target, status, detail`
+
+// Append 追加一条审计事件
+func (r *PostgresAuditRepository) Append(ctx context.Context, e *audit.Event) error {
+	query := `
+		INSERT INTO audit_log (created_at, action, outcome, actor_id, actor, ip, user_agent, app_id, target, status, detail)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		e.CreatedAt, e.Action, e.Outcome, e.ActorID, e.Actor, e.IP, e.UserAgent, e.AppID, e.Target, e.Status, e.Detail,
+	).Scan(&e.ID)
+	if err != nil {
+		return fmt.Errorf("failed to append audit event: %w", err)
+	}
+	return nil
+}
+
+// Query 按条件分页查询审计事件
+func (r *PostgresAuditRepository) Query(ctx context.Context, filter audit.Filter) ([]*audit.Event, int, error) {
+	where, args := auditWhere(filter)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM audit_log%s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`,
+		auditColumns, where, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	items := make([]*audit.Event, 0)
+	err := r.scanEvents(ctx, query, args, func(e *audit.Event) error {
+		items = append(items, e)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// Iterate 按时间顺序遍历符合条件的全部审计事件
+func (r *PostgresAuditRepository) Iterate(ctx context.Context, filter audit.Filter, fn func(*audit.Event) error) error {
+	where, args := auditWhere(filter)
+	query := `SELECT ` + auditColumns + ` FROM audit_log` + where + ` ORDER BY created_at, id`
+	return r.scanEvents(ctx, query, args, fn)
+}
+
+// DeleteBefore 删除 before 之前的审计事件
+func (r *PostgresAuditRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM audit_log WHERE created_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete audit events: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return affected, nil
+}
+
+func (r *PostgresAuditRepository) scanEvents(ctx context.Context, query string, args []interface{}, fn func(*audit.Event) error) error {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e := &audit.Event{}
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Action, &e.Outcome, &e.ActorID, &e.Actor, &e.IP,
+			&e.UserAgent, &e.AppID, &e.Target, &e.Status, &e.Detail); err != nil {
+			return fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate audit events: %w", err)
+	}
+	return nil
+}
+
+// auditWhere 根据查询条件构造 WHERE 子句
+func auditWhere(filter audit.Filter) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", fmt.Sprintf("$%d", len(args))))
+	}
+
+	if filter.Actor != "" {
+		add("(actor_id = ? OR actor = ?)", filter.Actor)
+	}
+	if filter.Action != "" {
+		add("action LIKE ? ESCAPE '\\'", likePrefix(filter.Action))
+	}
+	if filter.Outcome != "" {
+		add("outcome = ?", filter.Outcome)
+	}
+	if filter.Target != "" {
+		add("target LIKE ? ESCAPE '\\'", likePrefix(filter.Target))
+	}
+	if filter.IP != "" {
+		add("ip = ?", filter.IP)
+	}
+	if !filter.From.IsZero() {
+		add("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("created_at < ?", filter.To)
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// likePrefix 转义 LIKE 通配符并构造前缀匹配模式
+func likePrefix(prefix string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(prefix) + "%"
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yeying-community/warehouse/internal/domain/datakey"
+)
+
+// DataKeyRepository 用户数据密钥仓储接口
+type DataKeyRepository interface {
+	// ListByUser 获取用户的全部密钥版本（按版本升序）
+	ListByUser(ctx context.Context, userID string) ([]*datakey.DataKey, error)
+
+	// List 获取全部用户的密钥
+	List(ctx context.Context) ([]*datakey.DataKey, error)
+
+	// Create 创建密钥版本，Active 为 true 时其他版本同时置为非活动
+	Create(ctx context.Context, key *datakey.DataKey) error
+
+	// UpdateWrappedKey 更新加密后的密钥与主密钥标识（主密钥轮换）
+	UpdateWrappedKey(ctx context.Context, key *datakey.DataKey) error
+}
+
+// PostgresDataKeyRepository PostgreSQL 实现
+type PostgresDataKeyRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresDataKeyRepository 创建 PostgreSQL 数据密钥仓储
+func NewPostgresDataKeyRepository(db *sql.DB) *PostgresDataKeyRepository {
+	return &PostgresDataKeyRepository{db: db}
+}
+
+// ListByUser 获取用户的全部密钥版本
+func (r *PostgresDataKeyRepository) ListByUser(ctx context.Context, userID string) ([]*datakey.DataKey, error) {
+	query := `
+		SELECT user_id, version, wrapped_key, master_key_id, active, created_at
+		FROM user_data_keys
+		WHERE user_id = $1
+		ORDER BY version ASC
+	`
+	return r.query(ctx, query, userID)
+}
+
+// List 获取全部用户的密钥
+func (r *PostgresDataKeyRepository) List(ctx context.Context) ([]*datakey.DataKey, error) {
+	query := `
+		SELECT user_id, version, wrapped_key, master_key_id, active, created_at
+		FROM user_data_keys
+		ORDER BY user_id ASC, version ASC
+	`
+	return r.query(ctx, query)
+}
+
+// Create 创建密钥版本
+func (r *PostgresDataKeyRepository) Create(ctx context.Context, key *datakey.DataKey) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if key.Active {
+		if _, err := tx.ExecContext(ctx, `UPDATE user_data_keys SET active = FALSE WHERE user_id = $1`, key.UserID); err != nil {
+			return fmt.Errorf("failed to deactivate data keys: %w", err)
+		}
+	}
+	query := `
+		INSERT INTO user_data_keys (user_id, version, wrapped_key, master_key_id, active, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	if _, err := tx.ExecContext(ctx, query,
+		key.UserID,
+		int64(key.Version),
+		key.WrappedKey,
+		key.MasterKeyID,
+		key.Active,
+		key.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to create data key: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UpdateWrappedKey 更新加密后的密钥
+func (r *PostgresDataKeyRepository) UpdateWrappedKey(ctx context.Context, key *datakey.DataKey) error {
+	query := `
+		UPDATE user_data_keys
+		SET wrapped_key = $1, master_key_id = $2
+		WHERE user_id = $3 AND version = $4
+	`
+	result, err := r.db.ExecContext(ctx, query, key.WrappedKey, key.MasterKeyID, key.UserID, int64(key.Version))
+	if err != nil {
+		return fmt.Errorf("failed to update data key: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return datakey.ErrDataKeyNotFound
+	}
+	return nil
+}
+
+func (r *PostgresDataKeyRepository) query(ctx context.Context, query string, args ...interface{}) ([]*datakey.DataKey, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*datakey.DataKey
+	for rows.Next() {
+		key := &datakey.DataKey{}
+		var version int64
+		if err := rows.Scan(
+			&key.UserID,
+			&version,
+			&key.WrappedKey,
+			&key.MasterKeyID,
+			&key.Active,
+			&key.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan data key: %w", err)
+		}
+		key.Version = uint32(version)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate data keys: %w", err)
+	}
+	return keys, nil
+}
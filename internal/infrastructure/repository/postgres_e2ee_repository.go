@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/yeying-community/warehouse/internal/domain/e2ee"
+)
+
+// E2EERepository 端到端加密目录仓储接口
+type E2EERepository interface {
+	// SavePublicKey 保存（或更新）钱包公钥
+	SavePublicKey(ctx context.Context, key *e2ee.WalletPublicKey) error
+
+	// GetPublicKey 获取钱包公钥
+	GetPublicKey(ctx context.Context, wallet string) (*e2ee.WalletPublicKey, error)
+
+	// CreateFolder 创建加密目录，并同时写入拥有者的密钥信封
+	CreateFolder(ctx context.Context, folder *e2ee.Folder, ownerEnvelope *e2ee.KeyEnvelope) error
+
+	// GetFolder 根据 ID 获取加密目录
+	GetFolder(ctx context.Context, id string) (*e2ee.Folder, error)
+
+	// ListFolders 获取用户的全部加密目录
+	ListFolders(ctx context.Context, ownerUserID string) ([]*e2ee.Folder, error)
+
+	// UpdateFolderPath 更新加密目录路径（目录被移动时）
+	UpdateFolderPath(ctx context.Context, id, newPath string) error
+
+	// DeleteFolder 删除加密目录及其全部密钥信封
+	DeleteFolder(ctx context.Context, id string) error
+
+	// PutEnvelope 写入（或替换）接收方的密钥信封
+	PutEnvelope(ctx context.Context, envelope *e2ee.KeyEnvelope) error
+
+	// GetEnvelope 获取接收方的密钥信封
+	GetEnvelope(ctx context.Context, folderID, wallet string) (*e2ee.KeyEnvelope, error)
+
+	// ListEnvelopes 获取目录的全部密钥信封
+	ListEnvelopes(ctx context.Context, folderID string) ([]*e2ee.KeyEnvelope, error)
+
+	// DeleteEnvelope 删除接收方的密钥信封
+	DeleteEnvelope(ctx context.Context, folderID, wallet string) error
+}
+
+// PostgresE2EERepository PostgreSQL 实现
+type PostgresE2EERepository struct {
+	db *sql.DB
+}
+
+// NewPostgresE2EERepository 创建 PostgreSQL 端到端加密仓储
+func NewPostgresE2EERepository(db *sql.DB) *PostgresE2EERepository {
+	return &PostgresE2EERepository{db: db}
+}
+
+// SavePublicKey 保存钱包公钥
+func (r *PostgresE2EERepository) SavePublicKey(ctx context.Context, key *e2ee.WalletPublicKey) error {
+	query := `
+		INSERT INTO wallet_public_keys (wallet_address, public_key, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (wallet_address) DO UPDATE
+		SET public_key = EXCLUDED.public_key, updated_at = EXCLUDED.updated_at
+	`
+	if _, err := r.db.ExecContext(ctx, query, normalizeWallet(key.WalletAddress), key.PublicKey, key.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save wallet public key: %w", err)
+	}
+	return nil
+}
+
+// GetPublicKey 获取钱包公钥
+func (r *PostgresE2EERepository) GetPublicKey(ctx context.Context, wallet string) (*e2ee.WalletPublicKey, error) {
+	query := `
+		SELECT wallet_address, public_key, updated_at
+		FROM wallet_public_keys
+		WHERE wallet_address = $1
+	`
+	key := &e2ee.WalletPublicKey{}
+	err := r.db.QueryRowContext(ctx, query, normalizeWallet(wallet)).Scan(&key.WalletAddress, &key.PublicKey, &key.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, e2ee.ErrPublicKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to get wallet public key: %w", err)
+	}
+	return key, nil
+}
+
+// CreateFolder 创建加密目录
+func (r *PostgresE2EERepository) CreateFolder(ctx context.Context, folder *e2ee.Folder, ownerEnvelope *e2ee.KeyEnvelope) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO e2ee_folders (id, owner_user_id, path, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := tx.ExecContext(ctx, query, folder.ID, folder.OwnerUserID, folder.Path, folder.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return e2ee.ErrFolderExists
+		}
+		return fmt.Errorf("failed to create e2ee folder: %w", err)
+	}
+	if ownerEnvelope != nil {
+		if err := putEnvelope(ctx, tx, ownerEnvelope); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetFolder 根据 ID 获取加密目录
+func (r *PostgresE2EERepository) GetFolder(ctx context.Context, id string) (*e2ee.Folder, error) {
+	query := `
+		SELECT id, owner_user_id, path, created_at
+		FROM e2ee_folders
+		WHERE id = $1
+	`
+	folder := &e2ee.Folder{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&folder.ID, &folder.OwnerUserID, &folder.Path, &folder.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, e2ee.ErrFolderNotFound
+		}
+		return nil, fmt.Errorf("failed to get e2ee folder: %w", err)
+	}
+	return folder, nil
+}
+
+// ListFolders 获取用户的全部加密目录
+func (r *PostgresE2EERepository) ListFolders(ctx context.Context, ownerUserID string) ([]*e2ee.Folder, error) {
+	query := `
+		SELECT id, owner_user_id, path, created_at
+		FROM e2ee_folders
+		WHERE owner_user_id = $1
+		ORDER BY path ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, ownerUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list e2ee folders: %w", err)
+	}
+	defer rows.Close()
+
+	var folders []*e2ee.Folder
+	for rows.Next() {
+		folder := &e2ee.Folder{}
+		if err := rows.Scan(&folder.ID, &folder.OwnerUserID, &folder.Path, &folder.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan e2ee folder: %w", err)
+		}
+		folders = append(folders, folder)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate e2ee folders: %w", err)
+	}
+	return folders, nil
+}
+
+// UpdateFolderPath 更新加密目录路径
+func (r *PostgresE2EERepository) UpdateFolderPath(ctx context.Context, id, newPath string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE e2ee_folders SET path = $1 WHERE id = $2`, newPath, id)
+	if err != nil {
+		return fmt.Errorf("failed to update e2ee folder: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return e2ee.ErrFolderNotFound
+	}
+	return nil
+}
+
+// DeleteFolder 删除加密目录（密钥信封级联删除）
+func (r *PostgresE2EERepository) DeleteFolder(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM e2ee_folders WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete e2ee folder: %w", err)
+	}
+	return nil
+}
+
+// PutEnvelope 写入接收方的密钥信封
+func (r *PostgresE2EERepository) PutEnvelope(ctx context.Context, envelope *e2ee.KeyEnvelope) error {
+	return putEnvelope(ctx, r.db, envelope)
+}
+
+// GetEnvelope 获取接收方的密钥信封
+func (r *PostgresE2EERepository) GetEnvelope(ctx context.Context, folderID, wallet string) (*e2ee.KeyEnvelope, error) {
+	query := `
+		SELECT folder_id, recipient_wallet, envelope, created_by, created_at
+		FROM e2ee_key_envelopes
+		WHERE folder_id = $1 AND recipient_wallet = $2
+	`
+	env := &e2ee.KeyEnvelope{}
+	err := r.db.QueryRowContext(ctx, query, folderID, normalizeWallet(wallet)).Scan(
+		&env.FolderID,
+		&env.RecipientWallet,
+		&env.Envelope,
+		&env.CreatedBy,
+		&env.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, e2ee.ErrEnvelopeNotFound
+		}
+		return nil, fmt.Errorf("failed to get key envelope: %w", err)
+	}
+	return env, nil
+}
+
+// ListEnvelopes 获取目录的全部密钥信封
+func (r *PostgresE2EERepository) ListEnvelopes(ctx context.Context, folderID string) ([]*e2ee.KeyEnvelope, error) {
+	query := `
+		SELECT folder_id, recipient_wallet, envelope, created_by, created_at
+		FROM e2ee_key_envelopes
+		WHERE folder_id = $1
+		ORDER BY created_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key envelopes: %w", err)
+	}
+	defer rows.Close()
+
+	var envelopes []*e2ee.KeyEnvelope
+	for rows.Next() {
+		env := &e2ee.KeyEnvelope{}
+		if err := rows.Scan(&env.FolderID, &env.RecipientWallet, &env.Envelope, &env.CreatedBy, &env.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan key envelope: %w", err)
+		}
+		envelopes = append(envelopes, env)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate key envelopes: %w", err)
+	}
+	return envelopes, nil
+}
+
+// DeleteEnvelope 删除接收方的密钥信封
+func (r *PostgresE2EERepository) DeleteEnvelope(ctx context.Context, folderID, wallet string) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM e2ee_key_envelopes WHERE folder_id = $1 AND recipient_wallet = $2`,
+		folderID, normalizeWallet(wallet))
+	if err != nil {
+		return fmt.Errorf("failed to delete key envelope: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return e2ee.ErrEnvelopeNotFound
+	}
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func putEnvelope(ctx context.Context, db execer, envelope *e2ee.KeyEnvelope) error {
+	query := `
+		INSERT INTO e2ee_key_envelopes (folder_id, recipient_wallet, envelope, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (folder_id, recipient_wallet) DO UPDATE
+		SET envelope = EXCLUDED.envelope, created_by = EXCLUDED.created_by, created_at = EXCLUDED.created_at
+	`
+	if _, err := db.ExecContext(ctx, query,
+		envelope.FolderID,
+		normalizeWallet(envelope.RecipientWallet),
+		envelope.Envelope,
+		envelope.CreatedBy,
+		envelope.CreatedAt,
+	); err != nil {
+		return fmt.Errorf("failed to save key envelope: %w", err)
+	}
+	return nil
+}
+
+func normalizeWallet(wallet string) string {
+	return e2ee.NormalizeWallet(wallet)
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/filemeta"
+)
+
+// FileMetadataRepository 文件元数据仓储接口
+type FileMetadataRepository interface {
+	// Get 获取指定路径的元数据
+	Get(ctx context.Context, userID, path string) (*filemeta.FileMetadata, error)
+
+	// Upsert 创建或更新元数据
+	Upsert(ctx context.Context, meta *filemeta.FileMetadata) error
+
+	// Move 将路径（含子路径）的元数据移动到新路径，目标路径已有的记录会被覆盖
+	Move(ctx context.Context, userID, oldPath, newPath string) error
+
+	// Delete 删除路径（含子路径）的元数据
+	Delete(ctx context.Context, userID, path string) error
+}
+
+// PostgresFileMetadataRepository PostgreSQL 实现
+type PostgresFileMetadataRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresFileMetadataRepository 创建 PostgreSQL 文件元数据仓储
+func NewPostgresFileMetadataRepository(db *sql.DB) *PostgresFileMetadataRepository {
+	return &PostgresFileMetadataRepository{db: db}
+}
+
+// Get 获取指定路径的元数据
+func (r *PostgresFileMetadataRepository) Get(ctx context.Context, userID, path string) (*filemeta.FileMetadata, error) {
+	query := `
+		SELECT user_id, path, sha256, size, mod_time_ns, updated_at
+		FROM file_metadata
+		WHERE user_id = $1 AND path = $2
+	`
+	meta := &filemeta.FileMetadata{}
+	var modTimeNs int64
+	err := r.db.QueryRowContext(ctx, query, userID, path).Scan(
+		&meta.UserID,
+		&meta.Path,
+		&meta.SHA256,
+		&meta.Size,
+		&modTimeNs,
+		&meta.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, filemeta.ErrFileMetadataNotFound
+		}
+		return nil, fmt.Errorf("failed to get file metadata: %w", err)
+	}
+	meta.ModTime = time.Unix(0, modTimeNs)
+	return meta, nil
+}
+
+// Upsert 创建或更新元数据
+func (r *PostgresFileMetadataRepository) Upsert(ctx context.Context, meta *filemeta.FileMetadata) error {
+	query := `
+		INSERT INTO file_metadata (user_id, path, sha256, size, mod_time_ns, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, path) DO UPDATE SET
+			sha256 = EXCLUDED.sha256,
+			size = EXCLUDED.size,
+			mod_time_ns = EXCLUDED.mod_time_ns,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		meta.UserID,
+		meta.Path,
+		meta.SHA256,
+		meta.Size,
+		meta.ModTime.UnixNano(),
+		meta.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert file metadata: %w", err)
+	}
+	return nil
+}
+
+// Move 将路径（含子路径）的元数据移动到新路径
+func (r *PostgresFileMetadataRepository) Move(ctx context.Context, userID, oldPath, newPath string) error {
+	if oldPath == newPath {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 目标路径被覆盖，旧记录作废
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM file_metadata WHERE user_id = $1 AND (path = $2 OR path LIKE $3 ESCAPE '\')`,
+		userID, newPath, childPattern(newPath),
+	); err != nil {
+		return fmt.Errorf("failed to clear target file metadata: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE file_metadata
+		SET path = $3 || SUBSTR(path, LENGTH($2) + 1), updated_at = $5
+		WHERE user_id = $1 AND (path = $2 OR path LIKE $4 ESCAPE '\')`,
+		userID, oldPath, newPath, childPattern(oldPath), time.Now(),
+	); err != nil {
+		return fmt.Errorf("failed to move file metadata: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除路径（含子路径）的元数据
+func (r *PostgresFileMetadataRepository) Delete(ctx context.Context, userID, path string) error {
+	query := `DELETE FROM file_metadata WHERE user_id = $1 AND (path = $2 OR path LIKE $3 ESCAPE '\')`
+	if _, err := r.db.ExecContext(ctx, query, userID, path, childPattern(path)); err != nil {
+		return fmt.Errorf("failed to delete file metadata: %w", err)
+	}
+	return nil
+}
+
+// childPattern 生成匹配子路径的 LIKE 模式
+func childPattern(path string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.TrimSuffix(path, "/"))
+	return escaped + "/%"
+}
@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/identity"
+)
+
+// IdentityRepository 登录身份仓储接口
+type IdentityRepository interface {
+	// ListByUser 获取用户的全部登录身份
+	ListByUser(ctx context.Context, userID string) ([]*identity.Identity, error)
+
+	// FindBySubject 根据类型与 subject 查找登录身份
+	FindBySubject(ctx context.Context, t identity.Type, subject string) (*identity.Identity, error)
+
+	// Link 关联登录身份；已属于其他用户时返回 ErrIdentityInUse
+	// 用户还没有主钱包或主邮箱时，新关联的身份同时成为主钱包或主邮箱。
+	Link(ctx context.Context, ident *identity.Identity) error
+
+	// Unlink 解除登录身份；不能解除用户的最后一个身份
+	Unlink(ctx context.Context, userID, identityID string) (*identity.Identity, error)
+
+	// Merge 将 source 用户的分享、地址簿与登录身份转移给 target 并删除 source
+	// pathPrefix 为 source 文件在 target 目录下的新位置，分享路径会加上该前缀。
+	Merge(ctx context.Context, sourceID, targetID, targetUsername, pathPrefix string) error
+}
+
+// PostgresIdentityRepository PostgreSQL 实现
+type PostgresIdentityRepository struct {
+	db        *sql.DB
+	forUpdate string // 读后写时的行锁子句
+}
+
+// NewPostgresIdentityRepository 创建 PostgreSQL 登录身份仓储
+func NewPostgresIdentityRepository(db *sql.DB) *PostgresIdentityRepository {
+	return &PostgresIdentityRepository{db: db, forUpdate: " FOR UPDATE"}
+}
+
+// ListByUser 获取用户的全部登录身份
+func (r *PostgresIdentityRepository) ListByUser(ctx context.Context, userID string) ([]*identity.Identity, error) {
+	query := `
+		SELECT id, user_id, type, subject, created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*identity.Identity
+	for rows.Next() {
+		ident, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, ident)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate identities: %w", err)
+	}
+	return items, nil
+}
+
+// FindBySubject 根据类型与 subject 查找登录身份
+func (r *PostgresIdentityRepository) FindBySubject(ctx context.Context, t identity.Type, subject string) (*identity.Identity, error) {
+	normalized, err := identity.NormalizeSubject(t, subject)
+	if err != nil {
+		return nil, identity.ErrIdentityNotFound
+	}
+	query := `
+		SELECT id, user_id, type, subject, created_at
+		FROM user_identities
+		WHERE type = $1 AND subject = $2
+	`
+	ident, err := scanIdentity(r.db.QueryRowContext(ctx, query, string(t), normalized))
+	if err == sql.ErrNoRows {
+		return nil, identity.ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ident, nil
+}
+
+// Link 关联登录身份
+func (r *PostgresIdentityRepository) Link(ctx context.Context, ident *identity.Identity) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_identities (id, user_id, type, subject, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (type, subject) DO NOTHING
+	`, ident.ID, ident.UserID, string(ident.Type), ident.Subject, ident.CreatedAt); err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	var owner string
+	if err := tx.QueryRowContext(ctx,
+		"SELECT user_id FROM user_identities WHERE type = $1 AND subject = $2",
+		string(ident.Type), ident.Subject).Scan(&owner); err != nil {
+		return fmt.Errorf("failed to check identity owner: %w", err)
+	}
+	if owner != ident.UserID {
+		return identity.ErrIdentityInUse
+	}
+
+	switch ident.Type {
+	case identity.TypeWallet:
+		_, err = tx.ExecContext(ctx, "UPDATE users SET wallet_address = $2 WHERE id = $1 AND wallet_address IS NULL", ident.UserID, ident.Subject)
+	case identity.TypeEmail:
+		// 关联邮箱需要验证码，因此成为主邮箱时即为已验证
+		_, err = tx.ExecContext(ctx, "UPDATE users SET email = $2, email_verified_at = $3 WHERE id = $1 AND email IS NULL", ident.UserID, ident.Subject, ident.CreatedAt)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update primary identity: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Unlink 解除登录身份
+// 解除的是主钱包或主邮箱时，由最早关联的同类身份接替；解除密码身份会清空密码。
+func (r *PostgresIdentityRepository) Unlink(ctx context.Context, userID, identityID string) (*identity.Identity, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	ident, err := scanIdentity(tx.QueryRowContext(ctx, `
+		SELECT id, user_id, type, subject, created_at
+		FROM user_identities
+		WHERE id = $1 AND user_id = $2`+r.forUpdate, identityID, userID))
+	if err == sql.ErrNoRows {
+		return nil, identity.ErrIdentityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_identities WHERE user_id = $1", userID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count identities: %w", err)
+	}
+	if count <= 1 {
+		return nil, identity.ErrLastIdentity
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_identities WHERE id = $1", identityID); err != nil {
+		return nil, fmt.Errorf("failed to unlink identity: %w", err)
+	}
+
+	switch ident.Type {
+	case identity.TypeWallet:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE users SET wallet_address = (
+				SELECT subject FROM user_identities
+				WHERE user_id = $1 AND type = 'wallet'
+				ORDER BY created_at ASC LIMIT 1
+			)
+			WHERE id = $1 AND wallet_address = $2
+		`, userID, ident.Subject)
+	case identity.TypeEmail:
+		var result sql.Result
+		result, err = tx.ExecContext(ctx, `
+			UPDATE users SET email = (
+				SELECT subject FROM user_identities
+				WHERE user_id = $1 AND type = 'email'
+				ORDER BY created_at ASC LIMIT 1
+			)
+			WHERE id = $1 AND LOWER(email) = $2
+		`, userID, ident.Subject)
+		if err == nil {
+			err = r.resetEmailVerification(ctx, tx, userID, result)
+		}
+	case identity.TypePassword:
+		_, err = tx.ExecContext(ctx, "UPDATE users SET password = NULL WHERE id = $1", userID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update primary identity: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return ident, nil
+}
+
+// resetEmailVerification 主邮箱被接替后更新验证时间
+// 接替的邮箱是通过验证码关联的，视为已验证；没有可接替的邮箱时清空。
+func (r *PostgresIdentityRepository) resetEmailVerification(ctx context.Context, tx *sql.Tx, userID string, result sql.Result) error {
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET email_verified_at = $2 WHERE id = $1 AND email IS NOT NULL", userID, time.Now()); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "UPDATE users SET email_verified_at = NULL WHERE id = $1 AND email IS NULL", userID)
+	return err
+}
+
+// Merge 合并账户
+func (r *PostgresIdentityRepository) Merge(ctx context.Context, sourceID, targetID, targetUsername, pathPrefix string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var sourceWallet, sourceEmail sql.NullString
+	var sourceEmailVerifiedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, "SELECT wallet_address, email, email_verified_at FROM users WHERE id = $1"+r.forUpdate, sourceID).
+		Scan(&sourceWallet, &sourceEmail, &sourceEmailVerifiedAt)
+	if err == sql.ErrNoRows {
+		return identity.ErrIdentityNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load merged account: %w", err)
+	}
+
+	statements := []struct {
+		name  string
+		query string
+		args  []any
+	}{
+		// 分享链接随文件移动到 pathPrefix 下
+		{"share links", `UPDATE share_items SET user_id = $2, username = $3, path = $4 || path WHERE user_id = $1`,
+			[]any{sourceID, targetID, targetUsername, pathPrefix}},
+		// 两个账户之间的定向分享在合并后没有意义
+		{"mutual shares", `DELETE FROM share_user_items
+			WHERE (owner_user_id = $1 AND target_user_id = $2) OR (owner_user_id = $2 AND target_user_id = $1)`,
+			[]any{sourceID, targetID}},
+		{"owned shares", `UPDATE share_user_items SET owner_user_id = $2, owner_username = $3, path = $4 || path WHERE owner_user_id = $1`,
+			[]any{sourceID, targetID, targetUsername, pathPrefix}},
+		{"received shares", `UPDATE share_user_items SET target_user_id = $2 WHERE target_user_id = $1`,
+			[]any{sourceID, targetID}},
+		// 同名分组合并到 target 已有分组
+		{"address groups", `UPDATE address_contacts AS c SET group_id = t.id
+			FROM address_groups AS s, address_groups AS t
+			WHERE c.group_id = s.id AND s.user_id = $1 AND t.user_id = $2 AND t.name = s.name`,
+			[]any{sourceID, targetID}},
+		{"duplicate groups", `DELETE FROM address_groups AS s
+			WHERE s.user_id = $1 AND EXISTS (SELECT 1 FROM address_groups t WHERE t.user_id = $2 AND t.name = s.name)`,
+			[]any{sourceID, targetID}},
+		{"moved groups", `UPDATE address_groups SET user_id = $2 WHERE user_id = $1`,
+			[]any{sourceID, targetID}},
+		// target 已有的联系人保留 target 的记录
+		{"duplicate contacts", `DELETE FROM address_contacts AS s
+			WHERE s.user_id = $1 AND EXISTS (SELECT 1 FROM address_contacts t WHERE t.user_id = $2 AND t.wallet_address = s.wallet_address)`,
+			[]any{sourceID, targetID}},
+		{"moved contacts", `UPDATE address_contacts SET user_id = $2 WHERE user_id = $1`,
+			[]any{sourceID, targetID}},
+		// 用户名随 source 删除，密码身份不再有效；其余身份转给 target
+		{"password identity", `DELETE FROM user_identities WHERE user_id = $1 AND type = 'password'`,
+			[]any{sourceID}},
+		{"identities", `UPDATE user_identities SET user_id = $2 WHERE user_id = $1`,
+			[]any{sourceID, targetID}},
+		{"account", `DELETE FROM users WHERE id = $1`,
+			[]any{sourceID}},
+		// target 没有主邮箱时连同验证状态接过 source 的主邮箱
+		{"primary identities", `UPDATE users SET wallet_address = COALESCE(wallet_address, $2), email = COALESCE(email, $3),
+			email_verified_at = CASE WHEN email IS NULL THEN $4 ELSE email_verified_at END WHERE id = $1`,
+			[]any{targetID, nullString(sourceWallet), nullString(sourceEmail), nullTime(sourceEmailVerifiedAt)}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return fmt.Errorf("failed to merge %s: %w", stmt.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// rowScanner 兼容 *sql.Row 与 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanIdentity(row rowScanner) (*identity.Identity, error) {
+	ident := &identity.Identity{}
+	var t string
+	if err := row.Scan(&ident.ID, &ident.UserID, &t, &ident.Subject, &ident.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan identity: %w", err)
+	}
+	ident.Type = identity.Type(t)
+	return ident, nil
+}
+
+func nullString(v sql.NullString) any {
+	if !v.Valid || v.String == "" {
+		return nil
+	}
+	return v.String
+}
+
+func nullTime(v sql.NullTime) any {
+	if !v.Valid {
+		return nil
+	}
+	return v.Time
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/yeying-community/warehouse/internal/domain/mfa"
+)
+
+// MFARepository 两步验证登记仓储接口
+type MFARepository interface {
+	// Get 获取用户的两步验证登记，不存在时返回 mfa.ErrNotEnrolled
+	Get(ctx context.Context, userID string) (*mfa.Enrollment, error)
+
+	// Save 保存登记（不存在则创建）；不修改管理员设置的 required 标记
+	Save(ctx context.Context, e *mfa.Enrollment) error
+
+	// ConsumeCounter 记录通过校验的时间步；时间步不大于已记录值时返回 false（验证码已被使用）
+	ConsumeCounter(ctx context.Context, userID string, counter int64) (bool, error)
+
+	// UpdateRecoveryCodes 更新恢复码哈希
+	UpdateRecoveryCodes(ctx context.Context, userID string, hashes []string) error
+
+	// ConsumeRecoveryCode 原子地移除一个恢复码哈希；哈希不存在（未登记或已被使用）时返回 false
+	ConsumeRecoveryCode(ctx context.Context, userID, hash string) (bool, error)
+
+	// Disable 关闭两步验证并清除密钥与恢复码，保留 required 标记
+	Disable(ctx context.Context, userID string) error
+
+	// SetRequired 设置管理员强制两步验证标记
+	SetRequired(ctx context.Context, userID string, required bool) error
+}
+
+// PostgresMFARepository PostgreSQL 实现
+type PostgresMFARepository struct {
+	db *sql.DB
+}
+
+// NewPostgresMFARepository 创建 PostgreSQL 两步验证仓储
+func NewPostgresMFARepository(db *sql.DB) *PostgresMFARepository {
+	return &PostgresMFARepository{db: db}
+}
+
+// Get 获取用户的两步验证登记
+func (r *PostgresMFARepository) Get(ctx context.Context, userID string) (*mfa.Enrollment, error) {
+	query := `
+		SELECT user_id, secret, enabled, required, last_counter, recovery_codes, created_at, enabled_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+	e := &mfa.Enrollment{}
+	var enabledAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&e.UserID, &e.Secret, &e.Enabled, &e.Required, &e.LastCounter,
+		pq.Array(&e.RecoveryCodes), &e.CreatedAt, &enabledAt)
+	if err == sql.ErrNoRows {
+		return nil, mfa.ErrNotEnrolled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mfa enrollment: %w", err)
+	}
+	if enabledAt.Valid {
+		e.EnabledAt = &enabledAt.Time
+	}
+	return e, nil
+}
+
+// Save 保存登记
+func (r *PostgresMFARepository) Save(ctx context.Context, e *mfa.Enrollment) error {
+	query := `
+		INSERT INTO user_mfa (user_id, secret, enabled, last_counter, recovery_codes, created_at, enabled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			enabled = EXCLUDED.enabled,
+			last_counter = EXCLUDED.last_counter,
+			recovery_codes = EXCLUDED.recovery_codes,
+			created_at = EXCLUDED.created_at,
+			enabled_at = EXCLUDED.enabled_at
+	`
+	var enabledAt interface{}
+	if e.EnabledAt != nil {
+		enabledAt = *e.EnabledAt
+	}
+	codes := e.RecoveryCodes
+	if codes == nil {
+		codes = []string{}
+	}
+	_, err := r.db.ExecContext(ctx, query,
+		e.UserID, e.Secret, e.Enabled, e.LastCounter, pq.Array(codes), e.CreatedAt, enabledAt)
+	if err != nil {
+		return fmt.Errorf("failed to save mfa enrollment: %w", err)
+	}
+	return nil
+}
+
+// ConsumeCounter 记录通过校验的时间步
+func (r *PostgresMFARepository) ConsumeCounter(ctx context.Context, userID string, counter int64) (bool, error) {
+	query := `UPDATE user_mfa SET last_counter = $2 WHERE user_id = $1 AND last_counter < $2`
+	result, err := r.db.ExecContext(ctx, query, userID, counter)
+	if err != nil {
+		return false, fmt.Errorf("failed to update mfa counter: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// UpdateRecoveryCodes 更新恢复码哈希
+func (r *PostgresMFARepository) UpdateRecoveryCodes(ctx context.Context, userID string, hashes []string) error {
+	if hashes == nil {
+		hashes = []string{}
+	}
+	query := `UPDATE user_mfa SET recovery_codes = $2 WHERE user_id = $1`
+	if _, err := r.db.ExecContext(ctx, query, userID, pq.Array(hashes)); err != nil {
+		return fmt.Errorf("failed to update recovery codes: %w", err)
+	}
+	return nil
+}
+
+// ConsumeRecoveryCode 原子地移除一个恢复码哈希
+// 条件更新保证并发请求中同一恢复码只能成功一次。
+func (r *PostgresMFARepository) ConsumeRecoveryCode(ctx context.Context, userID, hash string) (bool, error) {
+	query := `
+		UPDATE user_mfa SET recovery_codes = array_remove(recovery_codes, $2)
+		WHERE user_id = $1 AND $2 = ANY(recovery_codes)
+	`
+	result, err := r.db.ExecContext(ctx, query, userID, hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// Disable 关闭两步验证
+func (r *PostgresMFARepository) Disable(ctx context.Context, userID string) error {
+	query := `
+		UPDATE user_mfa
+		SET secret = '', enabled = FALSE, last_counter = 0, recovery_codes = '{}', enabled_at = NULL
+		WHERE user_id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to disable mfa: %w", err)
+	}
+	return nil
+}
+
+// SetRequired 设置管理员强制两步验证标记
+func (r *PostgresMFARepository) SetRequired(ctx context.Context, userID string, required bool) error {
+	query := `
+		INSERT INTO user_mfa (user_id, required)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET required = EXCLUDED.required
+	`
+	if _, err := r.db.ExecContext(ctx, query, userID, required); err != nil {
+		return fmt.Errorf("failed to set mfa required: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/outbox"
+)
+
+// OutboxRepository 邮件发件箱仓储接口
+type OutboxRepository interface {
+	// Enqueue 写入一封待发送的邮件
+	Enqueue(ctx context.Context, m *outbox.Message) error
+
+	// ClaimDue 领取最多 limit 封到期待发送的邮件，并把它们的下次发送时间推迟 lease，
+	// 避免多个实例或发送超时后重复领取；领取后需通过 Update 写回发送结果
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*outbox.Message, error)
+
+	// Update 写回邮件状态
+	Update(ctx context.Context, m *outbox.Message) error
+
+	// GetByID 根据 ID 获取邮件
+	GetByID(ctx context.Context, id string) (*outbox.Message, error)
+
+	// Query 按条件分页查询邮件（按创建时间倒序），同时返回符合条件的总数
+	Query(ctx context.Context, filter outbox.Filter) ([]*outbox.Message, int, error)
+
+	// DeleteSentBefore 删除 before 之前发送成功的邮件
+	DeleteSentBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PostgresOutboxRepository PostgreSQL 实现
+type PostgresOutboxRepository struct {
+	db        *sql.DB
+	forUpdate string // 领取邮件时的行锁子句
+}
+
+// NewPostgresOutboxRepository 创建 PostgreSQL 邮件发件箱仓储
+func NewPostgresOutboxRepository(db *sql.DB) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{db: db, forUpdate: " FOR UPDATE SKIP LOCKED"}
+}
+
+const outboxColumns = `id, recipient, template, locale, data, status, attempts, last_error,
+	next_attempt_at, created_at, updated_at, sent_at`
+
+// Enqueue 写入一封待发送的邮件
+func (r *PostgresOutboxRepository) Enqueue(ctx context.Context, m *outbox.Message) error {
+	data, err := json.Marshal(m.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode mail data: %w", err)
+	}
+	query := `
+		INSERT INTO mail_outbox (` + outboxColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	_, err = r.db.ExecContext(ctx, query,
+		m.ID, m.Recipient, m.Template, m.Locale, string(data), m.Status, m.Attempts, m.LastError,
+		m.NextAttemptAt, m.CreatedAt, m.UpdatedAt, m.SentAt)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue mail: %w", err)
+	}
+	return nil
+}
+
+// ClaimDue 领取到期待发送的邮件
+func (r *PostgresOutboxRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*outbox.Message, error) {
+	query := `
+		UPDATE mail_outbox SET next_attempt_at = $1, updated_at = $2
+		WHERE id IN (
+			SELECT id FROM mail_outbox
+			WHERE status = $3 AND next_attempt_at <= $2
+			ORDER BY next_attempt_at
+			LIMIT $4` + r.forUpdate + `
+		)
+		RETURNING ` + outboxColumns
+	rows, err := r.db.QueryContext(ctx, query, now.Add(lease), now, outbox.StatusPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim mail: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*outbox.Message, 0)
+	for rows.Next() {
+		m, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate claimed mail: %w", err)
+	}
+	return items, nil
+}
+
+// Update 写回邮件状态
+func (r *PostgresOutboxRepository) Update(ctx context.Context, m *outbox.Message) error {
+	data, err := json.Marshal(m.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode mail data: %w", err)
+	}
+	query := `
+		UPDATE mail_outbox
+		SET data = $2, status = $3, attempts = $4, last_error = $5, next_attempt_at = $6, updated_at = $7, sent_at = $8
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		m.ID, string(data), m.Status, m.Attempts, m.LastError, m.NextAttemptAt, m.UpdatedAt, m.SentAt)
+	if err != nil {
+		return fmt.Errorf("failed to update mail: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return outbox.ErrMessageNotFound
+	}
+	return nil
+}
+
+// GetByID 根据 ID 获取邮件
+func (r *PostgresOutboxRepository) GetByID(ctx context.Context, id string) (*outbox.Message, error) {
+	query := `SELECT ` + outboxColumns + ` FROM mail_outbox WHERE id = $1`
+	m, err := scanOutboxMessage(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, outbox.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Query 按条件分页查询邮件
+func (r *PostgresOutboxRepository) Query(ctx context.Context, filter outbox.Filter) ([]*outbox.Message, int, error) {
+	var conds []string
+	var args []interface{}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conds = append(conds, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.Recipient != "" {
+		args = append(args, strings.ToLower(filter.Recipient))
+		conds = append(conds, fmt.Sprintf("recipient = $%d", len(args)))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM mail_outbox`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count mail: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM mail_outbox%s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`,
+		outboxColumns, where, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query mail: %w", err)
+	}
+	defer rows.Close()
+
+	items := make([]*outbox.Message, 0)
+	for rows.Next() {
+		m, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate mail: %w", err)
+	}
+	return items, total, nil
+}
+
+// DeleteSentBefore 删除 before 之前发送成功的邮件
+func (r *PostgresOutboxRepository) DeleteSentBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM mail_outbox WHERE status = $1 AND sent_at < $2", outbox.StatusSent, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sent mail: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return affected, nil
+}
+
+func scanOutboxMessage(row rowScanner) (*outbox.Message, error) {
+	m := &outbox.Message{}
+	var data string
+	var sentAt sql.NullTime
+	err := row.Scan(&m.ID, &m.Recipient, &m.Template, &m.Locale, &data, &m.Status, &m.Attempts, &m.LastError,
+		&m.NextAttemptAt, &m.CreatedAt, &m.UpdatedAt, &sentAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan mail: %w", err)
+	}
+	if err := json.Unmarshal([]byte(data), &m.Data); err != nil {
+		return nil, fmt.Errorf("failed to decode mail data: %w", err)
+	}
+	if sentAt.Valid {
+		m.SentAt = &sentAt.Time
+	}
+	return m, nil
+}
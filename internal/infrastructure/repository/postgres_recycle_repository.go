@@ -28,6 +28,9 @@ type RecycleRepository interface {
 
 	// DeleteExpiredItems 删除过期项目
 	DeleteExpiredItems(ctx context.Context, retentionPeriod time.Duration) (int64, error)
+
+	// Stats 统计回收站项目总数与总大小
+	Stats(ctx context.Context) (count int64, size int64, err error)
 }
 
 // PostgresRecycleRepository PostgreSQL 实现
@@ -43,12 +46,13 @@ func NewPostgresRecycleRepository(db *sql.DB) *PostgresRecycleRepository {
 // Create 创建回收站项目
 func (r *PostgresRecycleRepository) Create(ctx context.Context, item *recycle.RecycleItem) error {
 	query := `
-		INSERT INTO recycle_items (id, hash, user_id, username, directory, name, path, size, deleted_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO recycle_items (id, hash, content_hash, user_id, username, directory, name, path, size, deleted_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		item.ID,
 		item.Hash,
+		item.ContentHash,
 		item.UserID,
 		item.Username,
 		item.Directory,
@@ -67,7 +71,7 @@ func (r *PostgresRecycleRepository) Create(ctx context.Context, item *recycle.Re
 // GetByHash 根据哈希获取项目
 func (r *PostgresRecycleRepository) GetByHash(ctx context.Context, hash string) (*recycle.RecycleItem, error) {
 	query := `
-		SELECT id, hash, user_id, username, directory, name, path, size, deleted_at, created_at
+		SELECT id, hash, content_hash, user_id, username, directory, name, path, size, deleted_at, created_at
 		FROM recycle_items
 		WHERE hash = $1
 	`
@@ -75,6 +79,7 @@ func (r *PostgresRecycleRepository) GetByHash(ctx context.Context, hash string)
 	err := r.db.QueryRowContext(ctx, query, hash).Scan(
 		&item.ID,
 		&item.Hash,
+		&item.ContentHash,
 		&item.UserID,
 		&item.Username,
 		&item.Directory,
@@ -96,7 +101,7 @@ func (r *PostgresRecycleRepository) GetByHash(ctx context.Context, hash string)
 // GetByUserID 获取用户的所有回收站项目
 func (r *PostgresRecycleRepository) GetByUserID(ctx context.Context, userID string) ([]*recycle.RecycleItem, error) {
 	query := `
-		SELECT id, hash, user_id, username, directory, name, path, size, deleted_at, created_at
+		SELECT id, hash, content_hash, user_id, username, directory, name, path, size, deleted_at, created_at
 		FROM recycle_items
 		WHERE user_id = $1
 		ORDER BY deleted_at DESC
@@ -113,6 +118,7 @@ func (r *PostgresRecycleRepository) GetByUserID(ctx context.Context, userID stri
 		if err := rows.Scan(
 			&item.ID,
 			&item.Hash,
+			&item.ContentHash,
 			&item.UserID,
 			&item.Username,
 			&item.Directory,
@@ -164,7 +170,7 @@ func (r *PostgresRecycleRepository) DeleteByUserID(ctx context.Context, userID s
 // GetDeletedItemsOlderThan 获取指定时间之前删除的项目
 func (r *PostgresRecycleRepository) GetDeletedItemsOlderThan(ctx context.Context, before time.Time) ([]*recycle.RecycleItem, error) {
 	query := `
-		SELECT id, hash, user_id, username, directory, name, path, size, deleted_at, created_at
+		SELECT id, hash, content_hash, user_id, username, directory, name, path, size, deleted_at, created_at
 		FROM recycle_items
 		WHERE deleted_at < $1
 		ORDER BY deleted_at ASC
@@ -181,6 +187,7 @@ func (r *PostgresRecycleRepository) GetDeletedItemsOlderThan(ctx context.Context
 		if err := rows.Scan(
 			&item.ID,
 			&item.Hash,
+			&item.ContentHash,
 			&item.UserID,
 			&item.Username,
 			&item.Directory,
@@ -211,4 +218,14 @@ func (r *PostgresRecycleRepository) DeleteExpiredItems(ctx context.Context, rete
 		return 0, fmt.Errorf("failed to delete expired items: %w", err)
 	}
 	return result.RowsAffected()
-}
\ No newline at end of file
+}
+
+// Stats 统计回收站项目总数与总大小
+func (r *PostgresRecycleRepository) Stats(ctx context.Context) (int64, int64, error) {
+	var count, size int64
+	query := `SELECT COUNT(*), COALESCE(SUM(size), 0) FROM recycle_items`
+	if err := r.db.QueryRowContext(ctx, query).Scan(&count, &size); err != nil {
+		return 0, 0, fmt.Errorf("failed to get recycle stats: %w", err)
+	}
+	return count, size, nil
+}
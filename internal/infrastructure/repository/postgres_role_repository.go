@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
+)
+
+// RoleRepository 管理角色与角色分配仓储接口
+type RoleRepository interface {
+	// ListRoles 获取全部角色
+	ListRoles(ctx context.Context) ([]*rbac.Role, error)
+
+	// GetRole 根据名称获取角色
+	GetRole(ctx context.Context, name string) (*rbac.Role, error)
+
+	// CreateRole 创建自定义角色
+	CreateRole(ctx context.Context, role *rbac.Role) error
+
+	// UpdateRole 更新自定义角色的描述与权限
+	UpdateRole(ctx context.Context, role *rbac.Role) error
+
+	// SaveBuiltinRole 写入或覆盖内置角色
+	SaveBuiltinRole(ctx context.Context, role *rbac.Role) error
+
+	// DeleteRole 删除自定义角色（同时删除其分配）
+	DeleteRole(ctx context.Context, name string) error
+
+	// ListUserRoles 获取用户拥有的角色
+	ListUserRoles(ctx context.Context, userID string) ([]*rbac.Role, error)
+
+	// ListAssignments 获取全部角色分配
+	ListAssignments(ctx context.Context) ([]*rbac.Assignment, error)
+
+	// Assign 为用户分配角色（已分配时忽略）
+	Assign(ctx context.Context, a *rbac.Assignment) error
+
+	// Revoke 撤销用户的角色
+	Revoke(ctx context.Context, userID, role string) error
+
+	// CountMembers 统计拥有某角色的用户数
+	CountMembers(ctx context.Context, role string) (int, error)
+}
+
+// PostgresRoleRepository PostgreSQL 实现
+type PostgresRoleRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRoleRepository 创建 PostgreSQL 角色仓储
+func NewPostgresRoleRepository(db *sql.DB) *PostgresRoleRepository {
+	return &PostgresRoleRepository{db: db}
+}
+
+const roleColumns = `name, description, permissions, built_in, created_at, updated_at`
+
+// ListRoles 获取全部角色，内置角色在前
+func (r *PostgresRoleRepository) ListRoles(ctx context.Context) ([]*rbac.Role, error) {
+	query := `SELECT ` + roleColumns + ` FROM roles ORDER BY built_in DESC, name`
+	return r.queryRoles(ctx, query)
+}
+
+// GetRole 根据名称获取角色
+func (r *PostgresRoleRepository) GetRole(ctx context.Context, name string) (*rbac.Role, error) {
+	query := `SELECT ` + roleColumns + ` FROM roles WHERE name = $1`
+	role, err := scanRole(r.db.QueryRowContext(ctx, query, name))
+	if err == sql.ErrNoRows {
+		return nil, rbac.ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// CreateRole 创建自定义角色
+func (r *PostgresRoleRepository) CreateRole(ctx context.Context, role *rbac.Role) error {
+	query := `
+		INSERT INTO roles (name, description, permissions, built_in, created_at, updated_at)
+		VALUES ($1, $2, $3, FALSE, $4, $5)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		role.Name, role.Description, pq.Array(permissionStrings(role.Permissions)), role.CreatedAt, role.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return rbac.ErrRoleExists
+		}
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+	return nil
+}
+
+// UpdateRole 更新自定义角色
+func (r *PostgresRoleRepository) UpdateRole(ctx context.Context, role *rbac.Role) error {
+	query := `
+		UPDATE roles SET description = $2, permissions = $3, updated_at = $4
+		WHERE name = $1 AND built_in = FALSE
+	`
+	result, err := r.db.ExecContext(ctx, query,
+		role.Name, role.Description, pq.Array(permissionStrings(role.Permissions)), role.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return rbac.ErrRoleNotFound
+	}
+	return nil
+}
+
+// SaveBuiltinRole 写入或覆盖内置角色，保证数据库与代码定义一致
+func (r *PostgresRoleRepository) SaveBuiltinRole(ctx context.Context, role *rbac.Role) error {
+	query := `
+		INSERT INTO roles (name, description, permissions, built_in, created_at, updated_at)
+		VALUES ($1, $2, $3, TRUE, $4, $4)
+		ON CONFLICT (name) DO UPDATE SET
+			description = EXCLUDED.description,
+			permissions = EXCLUDED.permissions,
+			built_in = TRUE,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.db.ExecContext(ctx, query, role.Name, role.Description, pq.Array(permissionStrings(role.Permissions)), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save built-in role %s: %w", role.Name, err)
+	}
+	return nil
+}
+
+// DeleteRole 删除自定义角色
+func (r *PostgresRoleRepository) DeleteRole(ctx context.Context, name string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM roles WHERE name = $1 AND built_in = FALSE", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return rbac.ErrRoleNotFound
+	}
+	return nil
+}
+
+// ListUserRoles 获取用户拥有的角色
+func (r *PostgresRoleRepository) ListUserRoles(ctx context.Context, userID string) ([]*rbac.Role, error) {
+	query := `
+		SELECT r.name, r.description, r.permissions, r.built_in, r.created_at, r.updated_at
+		FROM user_roles ur
+		JOIN roles r ON r.name = ur.role
+		WHERE ur.user_id = $1
+		ORDER BY r.name
+	`
+	return r.queryRoles(ctx, query, userID)
+}
+
+// ListAssignments 获取全部角色分配
+func (r *PostgresRoleRepository) ListAssignments(ctx context.Context) ([]*rbac.Assignment, error) {
+	query := `SELECT user_id, role, granted_by, created_at FROM user_roles ORDER BY role, created_at`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list role assignments: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*rbac.Assignment
+	for rows.Next() {
+		a := &rbac.Assignment{}
+		if err := rows.Scan(&a.UserID, &a.Role, &a.GrantedBy, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan role assignment: %w", err)
+		}
+		items = append(items, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate role assignments: %w", err)
+	}
+	return items, nil
+}
+
+// Assign 为用户分配角色
+func (r *PostgresRoleRepository) Assign(ctx context.Context, a *rbac.Assignment) error {
+	query := `
+		INSERT INTO user_roles (user_id, role, granted_by, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, role) DO NOTHING
+	`
+	if _, err := r.db.ExecContext(ctx, query, a.UserID, a.Role, a.GrantedBy, a.CreatedAt); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	return nil
+}
+
+// Revoke 撤销用户的角色
+func (r *PostgresRoleRepository) Revoke(ctx context.Context, userID, role string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM user_roles WHERE user_id = $1 AND role = $2", userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to revoke role: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return rbac.ErrAssignmentNotFound
+	}
+	return nil
+}
+
+// CountMembers 统计拥有某角色的用户数
+func (r *PostgresRoleRepository) CountMembers(ctx context.Context, role string) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM user_roles WHERE role = $1", role).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count role members: %w", err)
+	}
+	return count, nil
+}
+
+func (r *PostgresRoleRepository) queryRoles(ctx context.Context, query string, args ...interface{}) ([]*rbac.Role, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*rbac.Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate roles: %w", err)
+	}
+	return items, nil
+}
+
+func scanRole(row rowScanner) (*rbac.Role, error) {
+	role := &rbac.Role{}
+	var permissions []string
+	err := row.Scan(&role.Name, &role.Description, pq.Array(&permissions), &role.BuiltIn, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan role: %w", err)
+	}
+	role.Permissions = make([]rbac.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		role.Permissions = append(role.Permissions, rbac.Permission(p))
+	}
+	return role, nil
+}
+
+func permissionStrings(perms []rbac.Permission) []string {
+	out := make([]string, 0, len(perms))
+	for _, p := range perms {
+		out = append(out, string(p))
+	}
+	return out
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/session"
+)
+
+// SessionRepository 登录会话仓储接口
+type SessionRepository interface {
+	// Create 创建会话
+	Create(ctx context.Context, s *session.Session) error
+
+	// GetByID 根据 ID 获取会话（包含已吊销的会话）
+	GetByID(ctx context.Context, id string) (*session.Session, error)
+
+	// ListActiveByUser 获取用户未吊销且未过期的会话
+	ListActiveByUser(ctx context.Context, userID string) ([]*session.Session, error)
+
+	// Rotate 轮换 refresh token：仅当当前 jti 为 previousJTI 时更新，返回是否成功
+	Rotate(ctx context.Context, id, previousJTI, nextJTI string, expiresAt time.Time, client session.Client) (bool, error)
+
+	// Touch 更新最后活跃时间
+	Touch(ctx context.Context, id string, at time.Time) error
+
+	// Revoke 吊销会话
+	Revoke(ctx context.Context, id, reason string) error
+
+	// RevokeByUser 吊销用户的全部会话，exceptID 非空时保留该会话
+	RevokeByUser(ctx context.Context, userID, exceptID, reason string) (int64, error)
+
+	// DeleteExpired 删除在 before 之前过期的会话
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PostgresSessionRepository PostgreSQL 实现
+type PostgresSessionRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresSessionRepository 创建 PostgreSQL 会话仓储
+func NewPostgresSessionRepository(db *sql.DB) *PostgresSessionRepository {
+	return &PostgresSessionRepository{db: db}
+}
+
+const sessionColumns = `id, user_id, subject_type, subject, auth_method, refresh_jti, device_name, ip, user_agent,
+	created_at, last_seen_at, expires_at, revoked_at, revoke_reason`
+
+// Create 创建会话
+func (r *PostgresSessionRepository) Create(ctx context.Context, s *session.Session) error {
+	query := `
+		INSERT INTO user_sessions (` + sessionColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		s.ID, s.UserID, s.SubjectType, s.Subject, s.AuthMethod, s.RefreshJTI, s.DeviceName, s.IP, s.UserAgent,
+		s.CreatedAt, s.LastSeenAt, s.ExpiresAt, s.RevokedAt, s.RevokeReason)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据 ID 获取会话
+func (r *PostgresSessionRepository) GetByID(ctx context.Context, id string) (*session.Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM user_sessions WHERE id = $1`
+	s, err := scanSession(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, session.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListActiveByUser 获取用户的有效会话
+func (r *PostgresSessionRepository) ListActiveByUser(ctx context.Context, userID string) ([]*session.Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*session.Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sessions: %w", err)
+	}
+	return items, nil
+}
+
+// Rotate 轮换 refresh token
+func (r *PostgresSessionRepository) Rotate(ctx context.Context, id, previousJTI, nextJTI string, expiresAt time.Time, client session.Client) (bool, error) {
+	query := `
+		UPDATE user_sessions
+		SET refresh_jti = $3, expires_at = $4, last_seen_at = $7, ip = $5, user_agent = $6
+		WHERE id = $1 AND refresh_jti = $2 AND revoked_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, id, previousJTI, nextJTI, expiresAt, client.IP, client.UserAgent, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate session: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// Touch 更新最后活跃时间
+func (r *PostgresSessionRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	if _, err := r.db.ExecContext(ctx, "UPDATE user_sessions SET last_seen_at = $2 WHERE id = $1", id, at); err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+	return nil
+}
+
+// Revoke 吊销会话
+func (r *PostgresSessionRepository) Revoke(ctx context.Context, id, reason string) error {
+	query := `UPDATE user_sessions SET revoked_at = $3, revoke_reason = $2 WHERE id = $1 AND revoked_at IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, id, reason, time.Now()); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// RevokeByUser 吊销用户的全部会话
+func (r *PostgresSessionRepository) RevokeByUser(ctx context.Context, userID, exceptID, reason string) (int64, error) {
+	query := `
+		UPDATE user_sessions SET revoked_at = $4, revoke_reason = $3
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, userID, exceptID, reason, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return affected, nil
+}
+
+// DeleteExpired 删除过期会话
+func (r *PostgresSessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM user_sessions WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return affected, nil
+}
+
+func scanSession(row rowScanner) (*session.Session, error) {
+	s := &session.Session{}
+	var revokedAt sql.NullTime
+	err := row.Scan(&s.ID, &s.UserID, &s.SubjectType, &s.Subject, &s.AuthMethod, &s.RefreshJTI, &s.DeviceName, &s.IP, &s.UserAgent,
+		&s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &revokedAt, &s.RevokeReason)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan session: %w", err)
+	}
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.Time
+	}
+	return s, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/yeying-community/warehouse/internal/domain/share"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
 )
 
 // ShareRepository 文件分享仓储接口
@@ -31,8 +32,8 @@ func NewPostgresShareRepository(db *sql.DB) *PostgresShareRepository {
 // Create 创建分享记录
 func (r *PostgresShareRepository) Create(ctx context.Context, item *share.ShareItem) error {
 	query := `
-		INSERT INTO share_items (id, token, user_id, username, name, path, expires_at, view_count, download_count, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO share_items (id, token, user_id, username, name, path, expires_at, view_count, download_count, created_at, access_condition)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		item.ID,
@@ -45,6 +46,7 @@ func (r *PostgresShareRepository) Create(ctx context.Context, item *share.ShareI
 		item.ViewCount,
 		item.DownloadCount,
 		item.CreatedAt,
+		item.Condition.Encode(),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create share item: %w", err)
@@ -55,12 +57,13 @@ func (r *PostgresShareRepository) Create(ctx context.Context, item *share.ShareI
 // GetByToken 根据 token 获取分享记录
 func (r *PostgresShareRepository) GetByToken(ctx context.Context, token string) (*share.ShareItem, error) {
 	query := `
-		SELECT id, token, user_id, username, name, path, expires_at, view_count, download_count, created_at
+		SELECT id, token, user_id, username, name, path, expires_at, view_count, download_count, created_at, access_condition
 		FROM share_items
 		WHERE token = $1
 	`
 	item := &share.ShareItem{}
 	var expiresAt sql.NullTime
+	var condition string
 	err := r.db.QueryRowContext(ctx, query, token).Scan(
 		&item.ID,
 		&item.Token,
@@ -72,6 +75,7 @@ func (r *PostgresShareRepository) GetByToken(ctx context.Context, token string)
 		&item.ViewCount,
 		&item.DownloadCount,
 		&item.CreatedAt,
+		&condition,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -82,13 +86,16 @@ func (r *PostgresShareRepository) GetByToken(ctx context.Context, token string)
 	if expiresAt.Valid {
 		item.ExpiresAt = &expiresAt.Time
 	}
+	if item.Condition, err = tokengate.ParseCondition(condition); err != nil {
+		return nil, fmt.Errorf("failed to parse share condition: %w", err)
+	}
 	return item, nil
 }
 
 // GetByUserID 获取用户的分享列表
 func (r *PostgresShareRepository) GetByUserID(ctx context.Context, userID string) ([]*share.ShareItem, error) {
 	query := `
-		SELECT id, token, user_id, username, name, path, expires_at, view_count, download_count, created_at
+		SELECT id, token, user_id, username, name, path, expires_at, view_count, download_count, created_at, access_condition
 		FROM share_items
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -103,6 +110,7 @@ func (r *PostgresShareRepository) GetByUserID(ctx context.Context, userID string
 	for rows.Next() {
 		item := &share.ShareItem{}
 		var expiresAt sql.NullTime
+		var condition string
 		if err := rows.Scan(
 			&item.ID,
 			&item.Token,
@@ -114,12 +122,16 @@ func (r *PostgresShareRepository) GetByUserID(ctx context.Context, userID string
 			&item.ViewCount,
 			&item.DownloadCount,
 			&item.CreatedAt,
+			&condition,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan share item: %w", err)
 		}
 		if expiresAt.Valid {
 			item.ExpiresAt = &expiresAt.Time
 		}
+		if item.Condition, err = tokengate.ParseCondition(condition); err != nil {
+			return nil, fmt.Errorf("failed to parse share condition: %w", err)
+		}
 		items = append(items, item)
 	}
 	if err := rows.Err(); err != nil {
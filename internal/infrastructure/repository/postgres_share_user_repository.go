@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/yeying-community/warehouse/internal/domain/shareuser"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
 )
 
 // UserShareRepository 定向分享仓储接口
@@ -30,8 +31,8 @@ func NewPostgresUserShareRepository(db *sql.DB) *PostgresUserShareRepository {
 func (r *PostgresUserShareRepository) Create(ctx context.Context, item *shareuser.ShareUserItem) error {
 	query := `
 		INSERT INTO share_user_items (id, owner_user_id, owner_username, target_user_id, target_wallet_address,
-			name, path, is_dir, permissions, expires_at, created_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+			name, path, is_dir, permissions, expires_at, created_at, access_condition)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		item.ID,
@@ -45,6 +46,7 @@ func (r *PostgresUserShareRepository) Create(ctx context.Context, item *shareuse
 		item.Permissions,
 		item.ExpiresAt,
 		item.CreatedAt,
+		item.Condition.Encode(),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create share user item: %w", err)
@@ -55,13 +57,14 @@ func (r *PostgresUserShareRepository) Create(ctx context.Context, item *shareuse
 func (r *PostgresUserShareRepository) GetByID(ctx context.Context, id string) (*shareuser.ShareUserItem, error) {
 	query := `
 		SELECT id, owner_user_id, owner_username, target_user_id, target_wallet_address,
-		       name, path, is_dir, permissions, expires_at, created_at
+		       name, path, is_dir, permissions, expires_at, created_at, access_condition
 		FROM share_user_items
 		WHERE id = $1
 	`
 
 	item := &shareuser.ShareUserItem{}
 	var expiresAt sql.NullTime
+	var condition string
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&item.ID,
 		&item.OwnerUserID,
@@ -74,6 +77,7 @@ func (r *PostgresUserShareRepository) GetByID(ctx context.Context, id string) (*
 		&item.Permissions,
 		&expiresAt,
 		&item.CreatedAt,
+		&condition,
 	)
 	if err == sql.ErrNoRows {
 		return nil, shareuser.ErrShareNotFound
@@ -84,13 +88,16 @@ func (r *PostgresUserShareRepository) GetByID(ctx context.Context, id string) (*
 	if expiresAt.Valid {
 		item.ExpiresAt = &expiresAt.Time
 	}
+	if item.Condition, err = tokengate.ParseCondition(condition); err != nil {
+		return nil, fmt.Errorf("failed to parse share user condition: %w", err)
+	}
 	return item, nil
 }
 
 func (r *PostgresUserShareRepository) GetByOwnerID(ctx context.Context, ownerID string) ([]*shareuser.ShareUserItem, error) {
 	query := `
 		SELECT id, owner_user_id, owner_username, target_user_id, target_wallet_address,
-		       name, path, is_dir, permissions, expires_at, created_at
+		       name, path, is_dir, permissions, expires_at, created_at, access_condition
 		FROM share_user_items
 		WHERE owner_user_id = $1
 		ORDER BY created_at DESC
@@ -106,6 +113,7 @@ func (r *PostgresUserShareRepository) GetByOwnerID(ctx context.Context, ownerID
 	for rows.Next() {
 		item := &shareuser.ShareUserItem{}
 		var expiresAt sql.NullTime
+		var condition string
 		if err := rows.Scan(
 			&item.ID,
 			&item.OwnerUserID,
@@ -118,12 +126,16 @@ func (r *PostgresUserShareRepository) GetByOwnerID(ctx context.Context, ownerID
 			&item.Permissions,
 			&expiresAt,
 			&item.CreatedAt,
+			&condition,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan share user item: %w", err)
 		}
 		if expiresAt.Valid {
 			item.ExpiresAt = &expiresAt.Time
 		}
+		if item.Condition, err = tokengate.ParseCondition(condition); err != nil {
+			return nil, fmt.Errorf("failed to parse share user condition: %w", err)
+		}
 		items = append(items, item)
 	}
 	if err := rows.Err(); err != nil {
@@ -135,7 +147,7 @@ func (r *PostgresUserShareRepository) GetByOwnerID(ctx context.Context, ownerID
 func (r *PostgresUserShareRepository) GetByTargetID(ctx context.Context, targetID string) ([]*shareuser.ShareUserItem, error) {
 	query := `
 		SELECT id, owner_user_id, owner_username, target_user_id, target_wallet_address,
-		       name, path, is_dir, permissions, expires_at, created_at
+		       name, path, is_dir, permissions, expires_at, created_at, access_condition
 		FROM share_user_items
 		WHERE target_user_id = $1
 		ORDER BY created_at DESC
@@ -151,6 +163,7 @@ func (r *PostgresUserShareRepository) GetByTargetID(ctx context.Context, targetI
 	for rows.Next() {
 		item := &shareuser.ShareUserItem{}
 		var expiresAt sql.NullTime
+		var condition string
 		if err := rows.Scan(
 			&item.ID,
 			&item.OwnerUserID,
@@ -163,12 +176,16 @@ func (r *PostgresUserShareRepository) GetByTargetID(ctx context.Context, targetI
 			&item.Permissions,
 			&expiresAt,
 			&item.CreatedAt,
+			&condition,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan share user item: %w", err)
 		}
 		if expiresAt.Valid {
 			item.ExpiresAt = &expiresAt.Time
 		}
+		if item.Condition, err = tokengate.ParseCondition(condition); err != nil {
+			return nil, fmt.Errorf("failed to parse share user condition: %w", err)
+		}
 		items = append(items, item)
 	}
 	if err := rows.Err(); err != nil {
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+)
+
+// UsedTokenRepository 一次性令牌使用记录仓储接口
+type UsedTokenRepository interface {
+	// Consume 记录令牌已使用；令牌此前已使用过时返回 auth.ErrTokenUsed
+	Consume(ctx context.Context, token *auth.MailToken) error
+}
+
+// PostgresUsedTokenRepository PostgreSQL 实现
+type PostgresUsedTokenRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresUsedTokenRepository 创建 PostgreSQL 一次性令牌仓储
+func NewPostgresUsedTokenRepository(db *sql.DB) *PostgresUsedTokenRepository {
+	return &PostgresUsedTokenRepository{db: db}
+}
+
+// Consume 记录令牌已使用
+// 过期的记录已不可能再被使用（令牌本身已失效），顺带删除，不需要单独的清理任务。
+func (r *PostgresUsedTokenRepository) Consume(ctx context.Context, token *auth.MailToken) error {
+	now := time.Now()
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM used_tokens WHERE expires_at < $1", now); err != nil {
+		return fmt.Errorf("failed to delete expired tokens: %w", err)
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO used_tokens (jti, purpose, user_id, expires_at, used_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, token.ID, token.Purpose, token.UserID, token.ExpiresAt, now)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return auth.ErrTokenUsed
+		}
+		return fmt.Errorf("failed to consume token: %w", err)
+	}
+	return nil
+}
@@ -5,25 +5,28 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/yeying-community/warehouse/internal/domain/account"
+	"github.com/yeying-community/warehouse/internal/domain/identity"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/database"
 )
 
 // PostgresUserRepository PostgreSQL 用户仓储
 type PostgresUserRepository struct {
-	db *database.PostgresDB
+	db *sql.DB
 }
 
 // NewPostgresUserRepository 创建 PostgreSQL 用户仓储
 func NewPostgresUserRepository(db *database.PostgresDB) (*PostgresUserRepository, error) {
-	return &PostgresUserRepository{db: db}, nil
+	return &PostgresUserRepository{db: db.DB}, nil
 }
 
 // FindByUsername 根据用户名查找用户
 func (r *PostgresUserRepository) FindByUsername(ctx context.Context, username string) (*user.User, error) {
 	query := `
-		SELECT id, username, password, wallet_address, email, directory, permissions,
+		SELECT id, username, password, wallet_address, email, email_verified_at, directory, permissions,
 		       quota, used_space, created_at, updated_at
 		FROM users
 		WHERE username = $1
@@ -33,14 +36,16 @@ func (r *PostgresUserRepository) FindByUsername(ctx context.Context, username st
 	var walletAddress sql.NullString
 	var password sql.NullString
 	var email sql.NullString
+	var emailVerifiedAt sql.NullTime
 	var permissionsStr string
 
-	err := r.db.DB.QueryRowContext(ctx, query, username).Scan(
+	err := r.db.QueryRowContext(ctx, query, username).Scan(
 		&u.ID,
 		&u.Username,
 		&password,
 		&walletAddress,
 		&email,
+		&emailVerifiedAt,
 		&u.Directory,
 		&permissionsStr,
 		&u.Quota,
@@ -59,6 +64,9 @@ func (r *PostgresUserRepository) FindByUsername(ctx context.Context, username st
 	u.Password = password.String
 	u.WalletAddress = walletAddress.String
 	u.Email = email.String
+	if emailVerifiedAt.Valid {
+		u.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
 	u.Permissions = user.ParsePermissions(permissionsStr)
 
 	// 加载用户规则
@@ -72,26 +80,36 @@ func (r *PostgresUserRepository) FindByUsername(ctx context.Context, username st
 }
 
 // FindByWalletAddress 根据钱包地址查找用户
+// address 支持以太坊地址、CAIP-10 与 did:pkh，统一转换为存储键后按登录身份精确匹配（Solana 地址区分大小写），
+// 因此关联到同一用户的任意钱包都会解析到该用户。
 func (r *PostgresUserRepository) FindByWalletAddress(ctx context.Context, address string) (*user.User, error) {
+	key, err := account.Normalize(address)
+	if err != nil {
+		return nil, user.ErrUserNotFound
+	}
+
 	query := `
-		SELECT id, username, password, wallet_address, email, directory, permissions,
-		       quota, used_space, created_at, updated_at
-		FROM users
-		WHERE LOWER(wallet_address) = LOWER($1)
+		SELECT u.id, u.username, u.password, u.wallet_address, u.email, u.email_verified_at, u.directory, u.permissions,
+		       u.quota, u.used_space, u.created_at, u.updated_at
+		FROM users u
+		JOIN user_identities i ON i.user_id = u.id
+		WHERE i.type = 'wallet' AND i.subject = $1
 	`
 
 	u := &user.User{}
 	var walletAddress sql.NullString
 	var password sql.NullString
 	var email sql.NullString
+	var emailVerifiedAt sql.NullTime
 	var permissionsStr string
 
-	err := r.db.DB.QueryRowContext(ctx, query, address).Scan(
+	err = r.db.QueryRowContext(ctx, query, key).Scan(
 		&u.ID,
 		&u.Username,
 		&password,
 		&walletAddress,
 		&email,
+		&emailVerifiedAt,
 		&u.Directory,
 		&permissionsStr,
 		&u.Quota,
@@ -111,6 +129,9 @@ func (r *PostgresUserRepository) FindByWalletAddress(ctx context.Context, addres
 	u.Password = password.String
 	u.WalletAddress = walletAddress.String
 	u.Email = email.String
+	if emailVerifiedAt.Valid {
+		u.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
 	u.Permissions = user.ParsePermissions(permissionsStr)
 
 	// 加载用户规则
@@ -123,27 +144,30 @@ func (r *PostgresUserRepository) FindByWalletAddress(ctx context.Context, addres
 	return u, nil
 }
 
-// FindByEmail 根据邮箱查找用户
+// FindByEmail 根据邮箱查找用户（按登录身份匹配，包含关联的其他邮箱）
 func (r *PostgresUserRepository) FindByEmail(ctx context.Context, emailAddress string) (*user.User, error) {
 	query := `
-		SELECT id, username, password, wallet_address, email, directory, permissions,
-		       quota, used_space, created_at, updated_at
-		FROM users
-		WHERE LOWER(email) = LOWER($1)
+		SELECT u.id, u.username, u.password, u.wallet_address, u.email, u.email_verified_at, u.directory, u.permissions,
+		       u.quota, u.used_space, u.created_at, u.updated_at
+		FROM users u
+		JOIN user_identities i ON i.user_id = u.id
+		WHERE i.type = 'email' AND i.subject = LOWER(TRIM($1))
 	`
 
 	u := &user.User{}
 	var walletAddress sql.NullString
 	var password sql.NullString
 	var email sql.NullString
+	var emailVerifiedAt sql.NullTime
 	var permissionsStr string
 
-	err := r.db.DB.QueryRowContext(ctx, query, emailAddress).Scan(
+	err := r.db.QueryRowContext(ctx, query, emailAddress).Scan(
 		&u.ID,
 		&u.Username,
 		&password,
 		&walletAddress,
 		&email,
+		&emailVerifiedAt,
 		&u.Directory,
 		&permissionsStr,
 		&u.Quota,
@@ -162,6 +186,9 @@ func (r *PostgresUserRepository) FindByEmail(ctx context.Context, emailAddress s
 	u.Password = password.String
 	u.WalletAddress = walletAddress.String
 	u.Email = email.String
+	if emailVerifiedAt.Valid {
+		u.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
 	u.Permissions = user.ParsePermissions(permissionsStr)
 
 	rules, err := r.loadUserRules(ctx, u.ID)
@@ -176,7 +203,7 @@ func (r *PostgresUserRepository) FindByEmail(ctx context.Context, emailAddress s
 // FindByID 根据ID查找用户
 func (r *PostgresUserRepository) FindByID(ctx context.Context, id string) (*user.User, error) {
 	query := `
-		SELECT id, username, password, wallet_address, email, directory, permissions,
+		SELECT id, username, password, wallet_address, email, email_verified_at, directory, permissions,
 		       quota, used_space, created_at, updated_at
 		FROM users
 		WHERE id = $1
@@ -186,14 +213,16 @@ func (r *PostgresUserRepository) FindByID(ctx context.Context, id string) (*user
 	var walletAddress sql.NullString
 	var password sql.NullString
 	var email sql.NullString
+	var emailVerifiedAt sql.NullTime
 	var permissionsStr string
 
-	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&u.ID,
 		&u.Username,
 		&password,
 		&walletAddress,
 		&email,
+		&emailVerifiedAt,
 		&u.Directory,
 		&permissionsStr,
 		&u.Quota,
@@ -212,6 +241,9 @@ func (r *PostgresUserRepository) FindByID(ctx context.Context, id string) (*user
 	u.Password = password.String
 	u.WalletAddress = walletAddress.String
 	u.Email = email.String
+	if emailVerifiedAt.Valid {
+		u.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
 	u.Permissions = user.ParsePermissions(permissionsStr)
 
 	// 加载用户规则
@@ -226,16 +258,19 @@ func (r *PostgresUserRepository) FindByID(ctx context.Context, id string) (*user
 
 // Save 保存用户
 func (r *PostgresUserRepository) Save(ctx context.Context, u *user.User) error {
-	tx, err := r.db.DB.BeginTx(ctx, nil)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// 检查用户是否存在
-	var exists bool
-	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", u.ID).Scan(&exists)
-	if err != nil {
+	// 检查用户是否存在，并取出原有的主钱包与邮箱
+	exists := true
+	var previousWallet, previousEmail sql.NullString
+	err = tx.QueryRowContext(ctx, "SELECT wallet_address, email FROM users WHERE id = $1", u.ID).Scan(&previousWallet, &previousEmail)
+	if err == sql.ErrNoRows {
+		exists = false
+	} else if err != nil {
 		return fmt.Errorf("failed to check user existence: %w", err)
 	}
 
@@ -254,13 +289,19 @@ func (r *PostgresUserRepository) Save(ctx context.Context, u *user.User) error {
 		password = &u.Password
 	}
 
+	// 邮箱为空时不保留验证时间
+	var emailVerifiedAt *time.Time
+	if u.Email != "" {
+		emailVerifiedAt = u.EmailVerifiedAt
+	}
+
 	if exists {
 		// 更新用户
 		query := `
 			UPDATE users
 			SET username = $1, password = $2, wallet_address = $3, email = $4, directory = $5,
-			    permissions = $6, quota = $7, used_space = $8
-			WHERE id = $9
+			    permissions = $6, quota = $7, used_space = $8, email_verified_at = $9
+			WHERE id = $10
 		`
 		_, err = tx.ExecContext(ctx, query,
 			u.Username,
@@ -271,13 +312,14 @@ func (r *PostgresUserRepository) Save(ctx context.Context, u *user.User) error {
 			u.Permissions.String(),
 			u.Quota,
 			u.UsedSpace,
+			emailVerifiedAt,
 			u.ID,
 		)
 	} else {
 		// 插入新用户
 		query := `
-			INSERT INTO users (id, username, password, wallet_address, email, directory, permissions, quota, used_space, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			INSERT INTO users (id, username, password, wallet_address, email, directory, permissions, quota, used_space, created_at, updated_at, email_verified_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		`
 		_, err = tx.ExecContext(ctx, query,
 			u.ID,
@@ -291,11 +333,12 @@ func (r *PostgresUserRepository) Save(ctx context.Context, u *user.User) error {
 			u.UsedSpace,
 			u.CreatedAt,
 			u.UpdatedAt,
+			emailVerifiedAt,
 		)
 	}
 
 	if err != nil {
-		if strings.Contains(err.Error(), "unique") {
+		if isUniqueViolation(err) {
 			if strings.Contains(err.Error(), "username") {
 				return user.ErrDuplicateUsername
 			}
@@ -309,6 +352,17 @@ func (r *PostgresUserRepository) Save(ctx context.Context, u *user.User) error {
 		return fmt.Errorf("failed to save user: %w", err)
 	}
 
+	// 同步登录身份：主钱包、邮箱与密码都是用户的登录身份
+	if err := syncPrimaryIdentity(ctx, tx, u.ID, identity.TypeWallet, previousWallet.String, u.WalletAddress, user.ErrDuplicateAddress); err != nil {
+		return err
+	}
+	if err := syncPrimaryIdentity(ctx, tx, u.ID, identity.TypeEmail, previousEmail.String, u.Email, user.ErrDuplicateEmail); err != nil {
+		return err
+	}
+	if err := syncPasswordIdentity(ctx, tx, u); err != nil {
+		return err
+	}
+
 	// 删除旧规则
 	_, err = tx.ExecContext(ctx, "DELETE FROM user_rules WHERE user_id = $1", u.ID)
 	if err != nil {
@@ -339,7 +393,7 @@ func (r *PostgresUserRepository) Save(ctx context.Context, u *user.User) error {
 // Delete 删除用户
 func (r *PostgresUserRepository) Delete(ctx context.Context, username string) error {
 	query := "DELETE FROM users WHERE username = $1"
-	result, err := r.db.DB.ExecContext(ctx, query, username)
+	result, err := r.db.ExecContext(ctx, query, username)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -359,13 +413,13 @@ func (r *PostgresUserRepository) Delete(ctx context.Context, username string) er
 // List 列出所有用户
 func (r *PostgresUserRepository) List(ctx context.Context) ([]*user.User, error) {
 	query := `
-		SELECT id, username, password, wallet_address, email, directory, permissions,
+		SELECT id, username, password, wallet_address, email, email_verified_at, directory, permissions,
 		       quota, used_space, created_at, updated_at
 		FROM users
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.DB.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query users: %w", err)
 	}
@@ -378,6 +432,7 @@ func (r *PostgresUserRepository) List(ctx context.Context) ([]*user.User, error)
 		var walletAddress sql.NullString
 		var password sql.NullString
 		var email sql.NullString
+		var emailVerifiedAt sql.NullTime
 		var permissionsStr string
 
 		err := rows.Scan(
@@ -386,6 +441,7 @@ func (r *PostgresUserRepository) List(ctx context.Context) ([]*user.User, error)
 			&password,
 			&walletAddress,
 			&email,
+			&emailVerifiedAt,
 			&u.Directory,
 			&permissionsStr,
 			&u.Quota,
@@ -400,6 +456,9 @@ func (r *PostgresUserRepository) List(ctx context.Context) ([]*user.User, error)
 		u.Password = password.String
 		u.WalletAddress = walletAddress.String
 		u.Email = email.String
+		if emailVerifiedAt.Valid {
+			u.EmailVerifiedAt = &emailVerifiedAt.Time
+		}
 		u.Permissions = user.ParsePermissions(permissionsStr)
 
 		// 加载用户规则
@@ -422,7 +481,7 @@ func (r *PostgresUserRepository) List(ctx context.Context) ([]*user.User, error)
 // UpdateUsedSpace 更新用户已使用空间
 func (r *PostgresUserRepository) UpdateUsedSpace(ctx context.Context, username string, usedSpace int64) error {
 	query := "UPDATE users SET used_space = $1 WHERE username = $2"
-	result, err := r.db.DB.ExecContext(ctx, query, usedSpace, username)
+	result, err := r.db.ExecContext(ctx, query, usedSpace, username)
 	if err != nil {
 		return fmt.Errorf("failed to update used space: %w", err)
 	}
@@ -442,7 +501,7 @@ func (r *PostgresUserRepository) UpdateUsedSpace(ctx context.Context, username s
 // UpdateQuota 更新用户配额
 func (r *PostgresUserRepository) UpdateQuota(ctx context.Context, username string, quota int64) error {
 	query := "UPDATE users SET quota = $1 WHERE username = $2"
-	result, err := r.db.DB.ExecContext(ctx, query, quota, username)
+	result, err := r.db.ExecContext(ctx, query, quota, username)
 	if err != nil {
 		return fmt.Errorf("failed to update quota: %w", err)
 	}
@@ -463,7 +522,7 @@ func (r *PostgresUserRepository) UpdateQuota(ctx context.Context, username strin
 func (r *PostgresUserRepository) loadUserRules(ctx context.Context, userID string) ([]*user.Rule, error) {
 	query := "SELECT path, permissions, regex FROM user_rules WHERE user_id = $1 ORDER BY id"
 
-	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query user rules: %w", err)
 	}
@@ -489,3 +548,69 @@ func (r *PostgresUserRepository) loadUserRules(ctx context.Context, userID strin
 
 	return rules, nil
 }
+
+// syncPrimaryIdentity 主钱包或邮箱变化时替换对应的登录身份
+// 新值已关联到其他用户时返回 duplicate 错误（事务回滚）。
+func syncPrimaryIdentity(ctx context.Context, tx *sql.Tx, userID string, t identity.Type, previous, current string, duplicate error) error {
+	if normalized, err := identity.NormalizeSubject(t, previous); err == nil {
+		previous = normalized
+	}
+	if normalized, err := identity.NormalizeSubject(t, current); err == nil {
+		current = normalized
+	}
+	if previous != "" && previous != current {
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM user_identities WHERE user_id = $1 AND type = $2 AND subject = $3",
+			userID, string(t), previous); err != nil {
+			return fmt.Errorf("failed to replace %s identity: %w", t, err)
+		}
+	}
+	if current == "" {
+		return nil
+	}
+	return insertIdentity(ctx, tx, userID, t, current, duplicate)
+}
+
+// syncPasswordIdentity 设置密码的用户拥有以用户名为 subject 的密码身份
+func syncPasswordIdentity(ctx context.Context, tx *sql.Tx, u *user.User) error {
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM user_identities WHERE user_id = $1 AND type = $2 AND ($3 = '' OR subject <> $4)",
+		u.ID, string(identity.TypePassword), u.Password, u.Username); err != nil {
+		return fmt.Errorf("failed to replace password identity: %w", err)
+	}
+	if u.Password == "" {
+		return nil
+	}
+	return insertIdentity(ctx, tx, u.ID, identity.TypePassword, u.Username, user.ErrDuplicateUsername)
+}
+
+func insertIdentity(ctx context.Context, tx *sql.Tx, userID string, t identity.Type, subject string, duplicate error) error {
+	ident, err := identity.New(userID, t, subject)
+	if err != nil {
+		return fmt.Errorf("invalid %s identity: %w", t, err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_identities (id, user_id, type, subject, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (type, subject) DO NOTHING
+	`, ident.ID, ident.UserID, string(ident.Type), ident.Subject, ident.CreatedAt); err != nil {
+		return fmt.Errorf("failed to save %s identity: %w", t, err)
+	}
+	var owner string
+	if err := tx.QueryRowContext(ctx,
+		"SELECT user_id FROM user_identities WHERE type = $1 AND subject = $2",
+		string(ident.Type), ident.Subject).Scan(&owner); err != nil {
+		return fmt.Errorf("failed to check %s identity: %w", t, err)
+	}
+	if owner != userID {
+		return duplicate
+	}
+	return nil
+}
+
+// isUniqueViolation 判断是否违反唯一约束：PostgreSQL 报 "duplicate key value violates unique constraint"，
+// SQLite 报 "UNIQUE constraint failed"
+func isUniqueViolation(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique") || strings.Contains(msg, "duplicate key")
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yeying-community/warehouse/internal/domain/webauthn"
+)
+
+// WebAuthnRepository 通行密钥凭证仓储接口
+type WebAuthnRepository interface {
+	// Create 保存新注册的凭证
+	Create(ctx context.Context, cred *webauthn.Credential) error
+
+	// GetByID 根据凭证 ID 查找（凭证 ID 全局唯一）
+	GetByID(ctx context.Context, id string) (*webauthn.Credential, error)
+
+	// ListByUser 获取用户的全部凭证
+	ListByUser(ctx context.Context, userID string) ([]*webauthn.Credential, error)
+
+	// UpdateUsage 登录成功后更新签名计数器、备份状态与最近使用时间；
+	// 计数器未增加时（并发重放或克隆的认证器）返回 webauthn.ErrCloneDetected
+	UpdateUsage(ctx context.Context, id string, signCount uint32, backupState bool, at time.Time) error
+
+	// Delete 删除用户的凭证
+	Delete(ctx context.Context, userID, id string) error
+}
+
+// PostgresWebAuthnRepository PostgreSQL 实现
+type PostgresWebAuthnRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresWebAuthnRepository 创建 PostgreSQL 通行密钥仓储
+func NewPostgresWebAuthnRepository(db *sql.DB) *PostgresWebAuthnRepository {
+	return &PostgresWebAuthnRepository{db: db}
+}
+
+const webauthnColumns = `id, user_id, name, public_key, algorithm, sign_count, aaguid, transports,
+	backup_eligible, backup_state, created_at, last_used_at`
+
+// Create 保存新注册的凭证
+func (r *PostgresWebAuthnRepository) Create(ctx context.Context, cred *webauthn.Credential) error {
+	query := `
+		INSERT INTO user_webauthn_credentials (id, user_id, name, public_key, algorithm, sign_count,
+			aaguid, transports, backup_eligible, backup_state, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	transports := cred.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+	_, err := r.db.ExecContext(ctx, query,
+		cred.ID, cred.UserID, cred.Name, cred.PublicKey, cred.Algorithm, int64(cred.SignCount),
+		cred.AAGUID, pq.Array(transports), cred.BackupEligible, cred.BackupState, cred.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return webauthn.ErrCredentialExists
+		}
+		return fmt.Errorf("failed to create webauthn credential: %w", err)
+	}
+	return nil
+}
+
+// GetByID 根据凭证 ID 查找
+func (r *PostgresWebAuthnRepository) GetByID(ctx context.Context, id string) (*webauthn.Credential, error) {
+	query := `SELECT ` + webauthnColumns + ` FROM user_webauthn_credentials WHERE id = $1`
+	cred, err := scanWebAuthnCredential(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, webauthn.ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// ListByUser 获取用户的全部凭证
+func (r *PostgresWebAuthnRepository) ListByUser(ctx context.Context, userID string) ([]*webauthn.Credential, error) {
+	query := `
+		SELECT ` + webauthnColumns + `
+		FROM user_webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webauthn credentials: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*webauthn.Credential
+	for rows.Next() {
+		cred, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, cred)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate webauthn credentials: %w", err)
+	}
+	return items, nil
+}
+
+// UpdateUsage 以比较并交换的方式更新签名计数器、备份状态与最近使用时间，
+// 两个携带相同计数器的并发断言只有一个能成功
+func (r *PostgresWebAuthnRepository) UpdateUsage(ctx context.Context, id string, signCount uint32, backupState bool, at time.Time) error {
+	query := `
+		UPDATE user_webauthn_credentials
+		SET sign_count = $2, backup_state = $3, last_used_at = $4
+		WHERE id = $1 AND (sign_count < $2 OR ($2 = 0 AND sign_count = 0))
+	`
+	result, err := r.db.ExecContext(ctx, query, id, int64(signCount), backupState, at)
+	if err != nil {
+		return fmt.Errorf("failed to update webauthn credential usage: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return webauthn.ErrCloneDetected
+	}
+	return nil
+}
+
+// Delete 删除用户的凭证
+func (r *PostgresWebAuthnRepository) Delete(ctx context.Context, userID, id string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM user_webauthn_credentials WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webauthn credential: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return webauthn.ErrCredentialNotFound
+	}
+	return nil
+}
+
+func scanWebAuthnCredential(row rowScanner) (*webauthn.Credential, error) {
+	cred := &webauthn.Credential{}
+	var signCount int64
+	var lastUsedAt sql.NullTime
+	err := row.Scan(&cred.ID, &cred.UserID, &cred.Name, &cred.PublicKey, &cred.Algorithm, &signCount,
+		&cred.AAGUID, pq.Array(&cred.Transports), &cred.BackupEligible, &cred.BackupState,
+		&cred.CreatedAt, &lastUsedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan webauthn credential: %w", err)
+	}
+	cred.SignCount = uint32(signCount)
+	if lastUsedAt.Valid {
+		cred.LastUsedAt = &lastUsedAt.Time
+	}
+	return cred, nil
+}
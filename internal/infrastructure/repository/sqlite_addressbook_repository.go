@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteAddressBookRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteAddressBookRepository struct {
+	*PostgresAddressBookRepository
+}
+
+// NewSQLiteAddressBookRepository 创建 SQLite 地址簿仓储
+func NewSQLiteAddressBookRepository(db *sql.DB) *SQLiteAddressBookRepository {
+	return &SQLiteAddressBookRepository{PostgresAddressBookRepository: NewPostgresAddressBookRepository(db)}
+}
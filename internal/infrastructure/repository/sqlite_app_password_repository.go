@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteAppPasswordRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteAppPasswordRepository struct {
+	*PostgresAppPasswordRepository
+}
+
+// NewSQLiteAppPasswordRepository 创建 SQLite 应用专用密码仓储
+func NewSQLiteAppPasswordRepository(db *sql.DB) *SQLiteAppPasswordRepository {
+	return &SQLiteAppPasswordRepository{PostgresAppPasswordRepository: NewPostgresAppPasswordRepository(db)}
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteAuditRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteAuditRepository struct {
+	*PostgresAuditRepository
+}
+
+// NewSQLiteAuditRepository 创建 SQLite 审计日志仓储
+func NewSQLiteAuditRepository(db *sql.DB) *SQLiteAuditRepository {
+	return &SQLiteAuditRepository{PostgresAuditRepository: NewPostgresAuditRepository(db)}
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteDataKeyRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteDataKeyRepository struct {
+	*PostgresDataKeyRepository
+}
+
+// NewSQLiteDataKeyRepository 创建 SQLite 数据密钥仓储
+func NewSQLiteDataKeyRepository(db *sql.DB) *SQLiteDataKeyRepository {
+	return &SQLiteDataKeyRepository{PostgresDataKeyRepository: NewPostgresDataKeyRepository(db)}
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteE2EERepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteE2EERepository struct {
+	*PostgresE2EERepository
+}
+
+// NewSQLiteE2EERepository 创建 SQLite 端到端加密仓储
+func NewSQLiteE2EERepository(db *sql.DB) *SQLiteE2EERepository {
+	return &SQLiteE2EERepository{PostgresE2EERepository: NewPostgresE2EERepository(db)}
+}
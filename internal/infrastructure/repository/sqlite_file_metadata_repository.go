@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteFileMetadataRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteFileMetadataRepository struct {
+	*PostgresFileMetadataRepository
+}
+
+// NewSQLiteFileMetadataRepository 创建 SQLite 文件元数据仓储
+func NewSQLiteFileMetadataRepository(db *sql.DB) *SQLiteFileMetadataRepository {
+	return &SQLiteFileMetadataRepository{PostgresFileMetadataRepository: NewPostgresFileMetadataRepository(db)}
+}
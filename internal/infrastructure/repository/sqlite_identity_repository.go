@@ -0,0 +1,14 @@
+package repository
+
+import "database/sql"
+
+// SQLiteIdentityRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteIdentityRepository struct {
+	*PostgresIdentityRepository
+}
+
+// NewSQLiteIdentityRepository 创建 SQLite 登录身份仓储
+// SQLite 不支持行锁，写事务以 BEGIN IMMEDIATE 开始，已与其他写操作串行。
+func NewSQLiteIdentityRepository(db *sql.DB) *SQLiteIdentityRepository {
+	return &SQLiteIdentityRepository{PostgresIdentityRepository: &PostgresIdentityRepository{db: db}}
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+// SQLiteMFARepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteMFARepository struct {
+	*PostgresMFARepository
+}
+
+// NewSQLiteMFARepository 创建 SQLite 两步验证仓储
+func NewSQLiteMFARepository(db *sql.DB) *SQLiteMFARepository {
+	return &SQLiteMFARepository{PostgresMFARepository: NewPostgresMFARepository(db)}
+}
+
+// ConsumeRecoveryCode 原子地移除一个恢复码哈希
+// SQLite 中恢复码为数组文本，没有数组函数，改用比较并交换：仅当列表未被并发修改时才写入。
+func (r *SQLiteMFARepository) ConsumeRecoveryCode(ctx context.Context, userID, hash string) (bool, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		e, err := r.Get(ctx, userID)
+		if err != nil {
+			return false, err
+		}
+		i := slices.Index(e.RecoveryCodes, hash)
+		if i < 0 {
+			return false, nil
+		}
+		remaining := slices.Delete(slices.Clone(e.RecoveryCodes), i, i+1)
+		query := `UPDATE user_mfa SET recovery_codes = $3 WHERE user_id = $1 AND recovery_codes = $2`
+		result, err := r.db.ExecContext(ctx, query, userID, pq.Array(e.RecoveryCodes), pq.Array(remaining))
+		if err != nil {
+			return false, fmt.Errorf("failed to consume recovery code: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if affected > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
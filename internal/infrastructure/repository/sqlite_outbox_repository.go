@@ -0,0 +1,14 @@
+package repository
+
+import "database/sql"
+
+// SQLiteOutboxRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteOutboxRepository struct {
+	*PostgresOutboxRepository
+}
+
+// NewSQLiteOutboxRepository 创建 SQLite 邮件发件箱仓储
+// SQLite 不支持行锁，领取邮件的 UPDATE 本身是单条写语句，已与其他写操作串行。
+func NewSQLiteOutboxRepository(db *sql.DB) *SQLiteOutboxRepository {
+	return &SQLiteOutboxRepository{PostgresOutboxRepository: &PostgresOutboxRepository{db: db}}
+}
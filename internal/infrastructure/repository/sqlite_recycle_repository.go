@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteRecycleRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteRecycleRepository struct {
+	*PostgresRecycleRepository
+}
+
+// NewSQLiteRecycleRepository 创建 SQLite 回收站仓储
+func NewSQLiteRecycleRepository(db *sql.DB) *SQLiteRecycleRepository {
+	return &SQLiteRecycleRepository{PostgresRecycleRepository: NewPostgresRecycleRepository(db)}
+}
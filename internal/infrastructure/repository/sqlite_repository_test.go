@@ -0,0 +1,443 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/addressbook"
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/filemeta"
+	"github.com/yeying-community/warehouse/internal/domain/identity"
+	"github.com/yeying-community/warehouse/internal/domain/mfa"
+	"github.com/yeying-community/warehouse/internal/domain/outbox"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/share"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/domain/webauthn"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/database"
+)
+
+func newTestSQLiteDB(t *testing.T) *database.SQLiteDB {
+	t.Helper()
+	db, err := database.NewSQLiteDB(config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "warehouse.db")})
+	if err != nil {
+		t.Fatalf("NewSQLiteDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return db
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	repo, err := NewSQLiteUserRepository(db)
+	if err != nil {
+		t.Fatalf("NewSQLiteUserRepository: %v", err)
+	}
+	ctx := context.Background()
+
+	u := user.NewUser("alice", "alice")
+	u.SetPassword("hashed")
+	u.Email = "alice@example.com"
+	u.Rules = []*user.Rule{{Path: "/docs", Permissions: user.ParsePermissions("CR")}}
+	if err := repo.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := repo.FindByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatalf("FindByUsername: %v", err)
+	}
+	if got.ID != u.ID || got.Email != u.Email || got.Password != "hashed" || len(got.Rules) != 1 {
+		t.Fatalf("unexpected user: %+v", got)
+	}
+	if got.CreatedAt.IsZero() || got.CreatedAt.Unix() != u.CreatedAt.Unix() {
+		t.Fatalf("created_at = %v, want %v", got.CreatedAt, u.CreatedAt)
+	}
+
+	dup := user.NewUser("alice", "alice2")
+	if err := repo.Save(ctx, dup); !errors.Is(err, user.ErrDuplicateUsername) {
+		t.Fatalf("Save duplicate username: got %v, want %v", err, user.ErrDuplicateUsername)
+	}
+	if _, err := repo.FindByUsername(ctx, "bob"); !errors.Is(err, user.ErrUserNotFound) {
+		t.Fatalf("FindByUsername missing: got %v, want %v", err, user.ErrUserNotFound)
+	}
+}
+
+func TestSQLiteAddressBookTags(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	ctx := context.Background()
+	users, _ := NewSQLiteUserRepository(db)
+	u := user.NewUser("alice", "alice")
+	if err := users.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	repo := NewSQLiteAddressBookRepository(db.DB)
+	contact, err := addressbook.NewContact(u.ID, "", "Bob", "0x1111111111111111111111111111111111111111", []string{"friend", "a,b"})
+	if err != nil {
+		t.Fatalf("NewContact: %v", err)
+	}
+	if err := repo.CreateContact(ctx, contact); err != nil {
+		t.Fatalf("CreateContact: %v", err)
+	}
+	got, err := repo.GetContactByID(ctx, u.ID, contact.ID)
+	if err != nil {
+		t.Fatalf("GetContactByID: %v", err)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "friend" || got.Tags[1] != "a,b" {
+		t.Fatalf("tags = %q", got.Tags)
+	}
+	if err := repo.CreateContact(ctx, contact); !errors.Is(err, addressbook.ErrDuplicateWallet) {
+		t.Fatalf("CreateContact duplicate: got %v, want %v", err, addressbook.ErrDuplicateWallet)
+	}
+
+	for i := 0; i < 2; i++ {
+		group, err := addressbook.NewGroup(u.ID, "friends")
+		if err != nil {
+			t.Fatalf("NewGroup: %v", err)
+		}
+		err = repo.CreateGroup(ctx, group)
+		if i == 0 && err != nil {
+			t.Fatalf("CreateGroup: %v", err)
+		}
+		if i == 1 && !errors.Is(err, addressbook.ErrDuplicateGroupName) {
+			t.Fatalf("CreateGroup duplicate: got %v, want %v", err, addressbook.ErrDuplicateGroupName)
+		}
+	}
+}
+
+func TestSQLiteSessionAndFileMetadata(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	ctx := context.Background()
+	users, _ := NewSQLiteUserRepository(db)
+	u := user.NewUser("alice", "alice")
+	if err := users.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	sessions := NewSQLiteSessionRepository(db.DB)
+	active := session.New(u.ID, "password", "alice", session.Client{}, time.Now().Add(time.Hour))
+	expired := session.New(u.ID, "password", "alice", session.Client{}, time.Now().Add(-time.Hour))
+	for _, s := range []*session.Session{active, expired} {
+		if err := sessions.Create(ctx, s); err != nil {
+			t.Fatalf("Create session: %v", err)
+		}
+	}
+	list, err := sessions.ListActiveByUser(ctx, u.ID)
+	if err != nil || len(list) != 1 || list[0].ID != active.ID {
+		t.Fatalf("ListActiveByUser = %d sessions, err=%v", len(list), err)
+	}
+	if err := sessions.Revoke(ctx, active.ID, "logout"); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if got, err := sessions.GetByID(ctx, active.ID); err != nil || got.RevokedAt == nil {
+		t.Fatalf("session should be revoked: %+v, err=%v", got, err)
+	}
+
+	metadata := NewSQLiteFileMetadataRepository(db.DB)
+	for _, p := range []string{"/a/x.txt", "/a_b/y.txt"} {
+		meta, err := filemeta.NewFileMetadata(u.ID, p, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", 0, time.Now())
+		if err != nil {
+			t.Fatalf("NewFileMetadata: %v", err)
+		}
+		if err := metadata.Upsert(ctx, meta); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+	if err := metadata.Move(ctx, u.ID, "/a", "/c"); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if _, err := metadata.Get(ctx, u.ID, "/c/x.txt"); err != nil {
+		t.Fatalf("moved metadata missing: %v", err)
+	}
+	// LIKE 通配符已转义，/a_b 不属于 /a 的子路径
+	if _, err := metadata.Get(ctx, u.ID, "/a_b/y.txt"); err != nil {
+		t.Fatalf("sibling metadata should stay: %v", err)
+	}
+}
+
+func TestSQLiteEmailVerificationAndUsedTokens(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	ctx := context.Background()
+	users, _ := NewSQLiteUserRepository(db)
+	u := user.NewUser("alice", "alice")
+	u.SetEmail("alice@example.com")
+	u.MarkEmailVerified()
+	if err := users.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := users.FindByID(ctx, u.ID)
+	if err != nil || !got.IsEmailVerified() {
+		t.Fatalf("email should be verified: %+v, err=%v", got, err)
+	}
+
+	// 关联的邮箱经过验证码确认，接替主邮箱时仍视为已验证
+	identities := NewSQLiteIdentityRepository(db.DB)
+	second, err := identity.New(u.ID, identity.TypeEmail, "alice@example.org")
+	if err != nil {
+		t.Fatalf("identity.New: %v", err)
+	}
+	if err := identities.Link(ctx, second); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	primary, err := identities.FindBySubject(ctx, identity.TypeEmail, "alice@example.com")
+	if err != nil {
+		t.Fatalf("FindBySubject: %v", err)
+	}
+	if _, err := identities.Unlink(ctx, u.ID, primary.ID); err != nil {
+		t.Fatalf("Unlink: %v", err)
+	}
+	got, err = users.FindByID(ctx, u.ID)
+	if err != nil || got.Email != "alice@example.org" || !got.IsEmailVerified() {
+		t.Fatalf("successor email should be verified: %+v, err=%v", got, err)
+	}
+
+	tokens := NewSQLiteUsedTokenRepository(db.DB)
+	token := &auth.MailToken{ID: "jti-1", Purpose: auth.MailTokenPasswordReset, UserID: u.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := tokens.Consume(ctx, token); err != nil {
+		t.Fatalf("Consume: %v", err)
+	}
+	if err := tokens.Consume(ctx, token); !errors.Is(err, auth.ErrTokenUsed) {
+		t.Fatalf("Consume twice: got %v, want %v", err, auth.ErrTokenUsed)
+	}
+}
+
+func TestSQLiteMFARecoveryCodeConsumedOnce(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	ctx := context.Background()
+	users, _ := NewSQLiteUserRepository(db)
+	u := user.NewUser("alice", "alice")
+	if err := users.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	repo := NewSQLiteMFARepository(db.DB)
+	hashes := []string{mfa.HashRecoveryCode("aaaa-1111"), mfa.HashRecoveryCode("bbbb-2222"), mfa.HashRecoveryCode("cccc-3333")}
+	if err := repo.Save(ctx, &mfa.Enrollment{UserID: u.ID, Secret: "secret", Enabled: true, RecoveryCodes: hashes, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Save enrollment: %v", err)
+	}
+
+	// 并发使用同一恢复码只能成功一次
+	var wg sync.WaitGroup
+	var used atomic.Int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repo.ConsumeRecoveryCode(ctx, u.ID, hashes[1])
+			if err != nil {
+				t.Errorf("ConsumeRecoveryCode: %v", err)
+			}
+			if ok {
+				used.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if used.Load() != 1 {
+		t.Fatalf("recovery code consumed %d times, want 1", used.Load())
+	}
+
+	if ok, err := repo.ConsumeRecoveryCode(ctx, u.ID, hashes[0]); err != nil || !ok {
+		t.Fatalf("ConsumeRecoveryCode = %v, err=%v", ok, err)
+	}
+	e, err := repo.Get(ctx, u.ID)
+	if err != nil || len(e.RecoveryCodes) != 1 || e.RecoveryCodes[0] != hashes[2] {
+		t.Fatalf("unexpected remaining codes: %+v, err=%v", e, err)
+	}
+}
+
+func TestSQLiteWebAuthnUpdateUsageRejectsStaleCounter(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	ctx := context.Background()
+	users, _ := NewSQLiteUserRepository(db)
+	u := user.NewUser("alice", "alice")
+	if err := users.Save(ctx, u); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	repo := NewSQLiteWebAuthnRepository(db.DB)
+	cred := &webauthn.Credential{ID: "cred-1", UserID: u.ID, Name: "key", PublicKey: []byte{1}, Algorithm: webauthn.AlgES256, SignCount: 5, Transports: []string{}, CreatedAt: time.Now()}
+	if err := repo.Create(ctx, cred); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 并发提交同一计数器的断言只能成功一次
+	var wg sync.WaitGroup
+	var used atomic.Int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.UpdateUsage(ctx, cred.ID, 6, false, time.Now())
+			switch {
+			case err == nil:
+				used.Add(1)
+			case !errors.Is(err, webauthn.ErrCloneDetected):
+				t.Errorf("UpdateUsage: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if used.Load() != 1 {
+		t.Fatalf("counter 6 accepted %d times, want 1", used.Load())
+	}
+
+	if err := repo.UpdateUsage(ctx, cred.ID, 0, false, time.Now()); !errors.Is(err, webauthn.ErrCloneDetected) {
+		t.Fatalf("counter reset to 0 should be rejected, got %v", err)
+	}
+	if err := repo.UpdateUsage(ctx, cred.ID, 7, true, time.Now()); err != nil {
+		t.Fatalf("UpdateUsage: %v", err)
+	}
+	got, err := repo.GetByID(ctx, cred.ID)
+	if err != nil || got.SignCount != 7 || !got.BackupState || got.LastUsedAt == nil {
+		t.Fatalf("unexpected credential after update: %+v, err=%v", got, err)
+	}
+}
+
+func TestSQLiteMailOutbox(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	ctx := context.Background()
+	repo := NewSQLiteOutboxRepository(db.DB)
+
+	first := outbox.NewMessage("alice@example.com", "email_code_login", "en-US", map[string]any{"code": "123456"})
+	later := outbox.NewMessage("bob@example.com", "email_code_login", "", nil)
+	later.NextAttemptAt = time.Now().Add(time.Hour)
+	for _, m := range []*outbox.Message{first, later} {
+		if err := repo.Enqueue(ctx, m); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	claimed, err := repo.ClaimDue(ctx, time.Now(), time.Minute, 10)
+	if err != nil || len(claimed) != 1 || claimed[0].ID != first.ID || claimed[0].Data["code"] != "123456" {
+		t.Fatalf("ClaimDue: %+v, err=%v", claimed, err)
+	}
+	// 租期内不会被再次领取
+	if again, err := repo.ClaimDue(ctx, time.Now(), time.Minute, 10); err != nil || len(again) != 0 {
+		t.Fatalf("claimed twice: %d, err=%v", len(again), err)
+	}
+
+	m := claimed[0]
+	m.MarkSent(time.Now().Add(-2 * time.Hour))
+	if err := repo.Update(ctx, m); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, err := repo.GetByID(ctx, m.ID)
+	if err != nil || got.Status != outbox.StatusSent || got.SentAt == nil || len(got.Data) != 0 || got.Attempts != 1 {
+		t.Fatalf("GetByID: %+v, err=%v", got, err)
+	}
+
+	items, total, err := repo.Query(ctx, outbox.Filter{Status: outbox.StatusPending, Limit: 10})
+	if err != nil || total != 1 || items[0].ID != later.ID {
+		t.Fatalf("Query pending: %d, err=%v", total, err)
+	}
+	if deleted, err := repo.DeleteSentBefore(ctx, time.Now().Add(-time.Hour)); err != nil || deleted != 1 {
+		t.Fatalf("DeleteSentBefore: %d, err=%v", deleted, err)
+	}
+	if _, err := repo.GetByID(ctx, m.ID); !errors.Is(err, outbox.ErrMessageNotFound) {
+		t.Fatalf("expected ErrMessageNotFound, got %v", err)
+	}
+}
+
+func TestSQLiteIdentityMerge(t *testing.T) {
+	db := newTestSQLiteDB(t)
+	ctx := context.Background()
+	users, _ := NewSQLiteUserRepository(db)
+	alice := user.NewUser("alice", "alice")
+	bob := user.NewUser("bob", "bob")
+	for _, u := range []*user.User{alice, bob} {
+		if err := users.Save(ctx, u); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	identities := NewSQLiteIdentityRepository(db.DB)
+	wallet, err := identity.New(bob.ID, identity.TypeWallet, "0x2222222222222222222222222222222222222222")
+	if err != nil {
+		t.Fatalf("identity.New: %v", err)
+	}
+	if err := identities.Link(ctx, wallet); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	shares := NewSQLiteShareRepository(db.DB)
+	link := share.NewShareItem(bob.ID, "bob", "/personal/notes.txt", "notes.txt", nil)
+	if err := shares.Create(ctx, link); err != nil {
+		t.Fatalf("Create share: %v", err)
+	}
+	book := NewSQLiteAddressBookRepository(db.DB)
+	groupOf := func(u *user.User) *addressbook.Group {
+		g, err := addressbook.NewGroup(u.ID, "friends")
+		if err != nil {
+			t.Fatalf("NewGroup: %v", err)
+		}
+		if err := book.CreateGroup(ctx, g); err != nil {
+			t.Fatalf("CreateGroup: %v", err)
+		}
+		return g
+	}
+	aliceGroup, bobGroup := groupOf(alice), groupOf(bob)
+	for _, c := range []struct {
+		owner   *user.User
+		group   string
+		name    string
+		address string
+	}{
+		{alice, "", "Carol", "0x3333333333333333333333333333333333333333"},
+		{bob, bobGroup.ID, "Carol (bob)", "0x3333333333333333333333333333333333333333"},
+		{bob, bobGroup.ID, "Dave", "0x4444444444444444444444444444444444444444"},
+	} {
+		contact, err := addressbook.NewContact(c.owner.ID, c.group, c.name, c.address, []string{})
+		if err != nil {
+			t.Fatalf("NewContact: %v", err)
+		}
+		if err := book.CreateContact(ctx, contact); err != nil {
+			t.Fatalf("CreateContact: %v", err)
+		}
+	}
+
+	if err := identities.Merge(ctx, bob.ID, alice.ID, "alice", "/merged/bob"); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	if _, err := users.FindByID(ctx, bob.ID); !errors.Is(err, user.ErrUserNotFound) {
+		t.Fatalf("source account should be deleted, got %v", err)
+	}
+	merged, err := users.FindByID(ctx, alice.ID)
+	if err != nil || merged.WalletAddress != wallet.Subject {
+		t.Fatalf("target should take over the source wallet: %+v %v", merged, err)
+	}
+	owned, err := identities.FindBySubject(ctx, identity.TypeWallet, wallet.Subject)
+	if err != nil || owned.UserID != alice.ID {
+		t.Fatalf("wallet identity should move to target: %+v %v", owned, err)
+	}
+	moved, err := shares.GetByToken(ctx, link.Token)
+	if err != nil || moved.UserID != alice.ID || moved.Username != "alice" || moved.Path != "/merged/bob/personal/notes.txt" {
+		t.Fatalf("share should move with rewritten path: %+v %v", moved, err)
+	}
+	groups, err := book.ListGroupsByUser(ctx, alice.ID)
+	if err != nil || len(groups) != 1 || groups[0].ID != aliceGroup.ID {
+		t.Fatalf("same-name groups should merge into the target group: %v %v", groups, err)
+	}
+	contacts, err := book.ListContactsByUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("ListContactsByUser: %v", err)
+	}
+	byName := make(map[string]string)
+	for _, c := range contacts {
+		byName[c.Name] = c.GroupID
+	}
+	// 同一钱包的联系人保留 target 的记录
+	if len(byName) != 2 || byName["Carol"] != "" || byName["Dave"] != aliceGroup.ID {
+		t.Fatalf("unexpected contacts after merge: %v", byName)
+	}
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteRoleRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteRoleRepository struct {
+	*PostgresRoleRepository
+}
+
+// NewSQLiteRoleRepository 创建 SQLite 角色仓储
+func NewSQLiteRoleRepository(db *sql.DB) *SQLiteRoleRepository {
+	return &SQLiteRoleRepository{PostgresRoleRepository: NewPostgresRoleRepository(db)}
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteSessionRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteSessionRepository struct {
+	*PostgresSessionRepository
+}
+
+// NewSQLiteSessionRepository 创建 SQLite 会话仓储
+func NewSQLiteSessionRepository(db *sql.DB) *SQLiteSessionRepository {
+	return &SQLiteSessionRepository{PostgresSessionRepository: NewPostgresSessionRepository(db)}
+}
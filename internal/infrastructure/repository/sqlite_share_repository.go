@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteShareRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteShareRepository struct {
+	*PostgresShareRepository
+}
+
+// NewSQLiteShareRepository 创建 SQLite 分享仓储
+func NewSQLiteShareRepository(db *sql.DB) *SQLiteShareRepository {
+	return &SQLiteShareRepository{PostgresShareRepository: NewPostgresShareRepository(db)}
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteUserShareRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteUserShareRepository struct {
+	*PostgresUserShareRepository
+}
+
+// NewSQLiteUserShareRepository 创建 SQLite 定向分享仓储
+func NewSQLiteUserShareRepository(db *sql.DB) *SQLiteUserShareRepository {
+	return &SQLiteUserShareRepository{PostgresUserShareRepository: NewPostgresUserShareRepository(db)}
+}
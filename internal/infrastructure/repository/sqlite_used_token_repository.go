@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteUsedTokenRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteUsedTokenRepository struct {
+	*PostgresUsedTokenRepository
+}
+
+// NewSQLiteUsedTokenRepository 创建 SQLite 一次性令牌仓储
+func NewSQLiteUsedTokenRepository(db *sql.DB) *SQLiteUsedTokenRepository {
+	return &SQLiteUsedTokenRepository{PostgresUsedTokenRepository: NewPostgresUsedTokenRepository(db)}
+}
@@ -0,0 +1,13 @@
+package repository
+
+import "github.com/yeying-community/warehouse/internal/infrastructure/database"
+
+// SQLiteUserRepository SQLite 用户仓储，与 PostgreSQL 实现共用查询语句
+type SQLiteUserRepository struct {
+	*PostgresUserRepository
+}
+
+// NewSQLiteUserRepository 创建 SQLite 用户仓储
+func NewSQLiteUserRepository(db *database.SQLiteDB) (*SQLiteUserRepository, error) {
+	return &SQLiteUserRepository{PostgresUserRepository: &PostgresUserRepository{db: db.DB}}, nil
+}
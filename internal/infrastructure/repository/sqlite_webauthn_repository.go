@@ -0,0 +1,13 @@
+package repository
+
+import "database/sql"
+
+// SQLiteWebAuthnRepository SQLite 实现，与 PostgreSQL 实现共用查询语句
+type SQLiteWebAuthnRepository struct {
+	*PostgresWebAuthnRepository
+}
+
+// NewSQLiteWebAuthnRepository 创建 SQLite 通行密钥仓储
+func NewSQLiteWebAuthnRepository(db *sql.DB) *SQLiteWebAuthnRepository {
+	return &SQLiteWebAuthnRepository{PostgresWebAuthnRepository: NewPostgresWebAuthnRepository(db)}
+}
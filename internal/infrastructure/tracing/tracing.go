@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/XSAM/otelsql"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// 导出器类型
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// instrumentationScope 上报的埋点名称
+const instrumentationScope = "github.com/yeying-community/warehouse"
+
+// NewTracerProvider 按配置创建 OpenTelemetry TracerProvider 并设为全局
+// 新链路按 sample_ratio 采样，上游传入 traceparent 时沿用其采样决定。
+func NewTracerProvider(ctx context.Context, cfg config.TracingConfig) (*sdktrace.TracerProvider, error) {
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	tp := newTracerProvider(exporter, cfg)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return tp, nil
+}
+
+func newTracerProvider(exporter sdktrace.SpanExporter, cfg config.TracingConfig) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))),
+	)
+}
+
+// newExporter 按配置创建导出器；OTLP 使用 HTTP 协议，endpoint 未以 /v1/traces 结尾时自动补全
+func newExporter(ctx context.Context, cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP, "":
+		url := strings.TrimRight(cfg.Endpoint, "/")
+		if !strings.HasSuffix(url, "/v1/traces") {
+			url += "/v1/traces"
+		}
+		return otlptracehttp.New(ctx,
+			otlptracehttp.WithEndpointURL(url),
+			otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	return nil, fmt.Errorf("unsupported trace exporter: %s", cfg.Exporter)
+}
+
+// Disable 将全局 TracerProvider 恢复为空实现
+func Disable() {
+	otel.SetTracerProvider(noop.NewTracerProvider())
+}
+
+// Enabled 全局 TracerProvider 是否为 SDK 实现（已启用追踪）
+func Enabled() bool {
+	_, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	return ok
+}
+
+// Start 使用全局 TracerProvider 创建跨度；未启用追踪时返回不记录的跨度
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationScope).Start(ctx, name, opts...)
+}
+
+// RecordError 记录错误并将跨度标记为失败，err 为 nil 时不做任何事
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// OpenDB 打开数据库；启用追踪时为每次 SQL 调用创建 Client 跨度
+// 只在上下文中已有跨度（即处于被追踪的请求中）时创建，避免后台任务产生大量孤立链路。
+func OpenDB(c driver.Connector, system string) *sql.DB {
+	if !Enabled() {
+		return sql.OpenDB(c)
+	}
+	return otelsql.OpenDB(c,
+		otelsql.WithAttributes(attribute.String("db.system.name", system)),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitRows:             true,
+			OmitConnectorConnect: true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}))
+}
@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracerProviderParentAndSampling(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := newTracerProvider(exporter, config.TracingConfig{ServiceName: "warehouse", SampleRatio: 0})
+	tracer := tp.Tracer(instrumentationScope)
+
+	// 上游已采样：即使本地比例为 0 也沿用上游决定
+	const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": header})
+	remote := trace.SpanContextFromContext(ctx)
+	ctx, server := tracer.Start(ctx, "GET /", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := tracer.Start(ctx, "auth.authenticate")
+	RecordError(child, errors.New("boom"))
+	child.End()
+	server.End()
+
+	// 新链路按比例 0 不采样
+	_, local := tracer.Start(context.Background(), "background")
+	local.End()
+
+	// 内存导出器关闭时会清空记录，这里只刷新
+	if err := tp.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush failed: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 exported spans, got %d", len(spans))
+	}
+	childData, serverData := spans[0], spans[1]
+	if serverData.SpanContext.TraceID() != remote.TraceID() || serverData.Parent.SpanID() != remote.SpanID() {
+		t.Fatalf("server span should continue remote trace: %+v", serverData)
+	}
+	if childData.Parent.SpanID() != serverData.SpanContext.SpanID() || childData.Status.Code != codes.Error {
+		t.Fatalf("unexpected child span: %+v", childData)
+	}
+}
+
+func TestNewTracerProviderOTLP(t *testing.T) {
+	var received atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Authorization") != "Bearer t" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		received.Add(1)
+	}))
+	defer srv.Close()
+	defer Disable()
+
+	tp, err := NewTracerProvider(context.Background(), config.TracingConfig{
+		Exporter:    ExporterOTLP,
+		Endpoint:    srv.URL,
+		Headers:     map[string]string{"Authorization": "Bearer t"},
+		ServiceName: "warehouse",
+		SampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewTracerProvider failed: %v", err)
+	}
+	if !Enabled() {
+		t.Fatalf("tracing should be enabled after NewTracerProvider")
+	}
+	_, span := Start(context.Background(), "PROPFIND webdav")
+	span.End()
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if received.Load() == 0 {
+		t.Fatalf("collector did not receive any spans")
+	}
+
+	Disable()
+	if Enabled() {
+		t.Fatalf("tracing should be disabled")
+	}
+	if _, err := NewTracerProvider(context.Background(), config.TracingConfig{Exporter: "zipkin"}); err == nil {
+		t.Fatalf("expected unsupported exporter to be rejected")
+	}
+}
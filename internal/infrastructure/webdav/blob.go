@@ -0,0 +1,260 @@
+package webdavfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/google/uuid"
+)
+
+// ErrBlobStoreUnsupported 当前平台无法统计硬链接引用
+var ErrBlobStoreUnsupported = errors.New("blob store requires hard link support")
+
+// ErrBlobStoreCrossDevice blob 目录与数据目录不在同一文件系统，无法创建硬链接
+var ErrBlobStoreCrossDevice = errors.New("blob directory must be on the same filesystem as the webdav directory")
+
+// BlobStore 内容寻址的去重存储
+// 文件内容按 SHA-256 只保存一份，用户路径以硬链接引用 blob；
+// blob 自身占用一个链接，因此链接数为 1 的 blob 即无人引用。
+type BlobStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// BlobGCResult 垃圾回收结果
+type BlobGCResult struct {
+	Scanned int   `json:"scanned"`
+	Removed int   `json:"removed"`
+	Freed   int64 `json:"freed"`
+}
+
+// NewBlobStore 创建 blob 存储
+// dataDir 为用户文件所在目录，启动时在两者之间试建硬链接，
+// 以便跨文件系统的配置在启动阶段而不是首次写入时报错。
+func NewBlobStore(dir, dataDir string) (*BlobStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, ok := LinkInfo(info); !ok {
+		return nil, ErrBlobStoreUnsupported
+	}
+	if err := probeLink(dataDir, dir); err != nil {
+		return nil, err
+	}
+	return &BlobStore{dir: dir}, nil
+}
+
+// probeLink 在 dataDir 创建临时文件并尝试硬链接到 blobDir
+func probeLink(dataDir, blobDir string) error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create webdav directory: %w", err)
+	}
+	probe, err := os.CreateTemp(dataDir, ".blob-probe-*")
+	if err != nil {
+		return fmt.Errorf("failed to create link probe: %w", err)
+	}
+	src := probe.Name()
+	probe.Close()
+	defer os.Remove(src)
+
+	dst := filepath.Join(blobDir, filepath.Base(src))
+	if err := os.Link(src, dst); err != nil {
+		if errors.Is(err, syscall.EXDEV) {
+			return fmt.Errorf("%w: %s and %s", ErrBlobStoreCrossDevice, blobDir, dataDir)
+		}
+		return fmt.Errorf("failed to link %s into %s: %w", dataDir, blobDir, err)
+	}
+	return os.Remove(dst)
+}
+
+// Dir 返回 blob 目录
+func (b *BlobStore) Dir() string {
+	return b.dir
+}
+
+// Path 返回 blob 的存储路径：{dir}/ab/cd/{sha256}
+func (b *BlobStore) Path(sum string) string {
+	return filepath.Join(b.dir, sum[0:2], sum[2:4], sum)
+}
+
+// Has 判断 blob 是否存在
+func (b *BlobStore) Has(sum string) bool {
+	_, err := os.Stat(b.Path(sum))
+	return err == nil
+}
+
+// Adopt 将已写入的文件纳入 blob 存储
+// 内容已存在时，文件被替换为指向已有 blob 的引用；否则文件本身成为该 blob。
+// 引用已有 blob 前先比较大小，无人引用的 blob 还会经 idOf 重新计算标识；
+// 不一致说明 blob 已损坏或被篡改，由新文件取而代之，避免坏内容扩散到后续上传。
+func (b *BlobStore) Adopt(fullPath, sum string, idOf func(fullPath string) (string, error)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file: %s", fullPath)
+	}
+
+	blobPath := b.Path(sum)
+	blobInfo, err := os.Stat(blobPath)
+	if err == nil {
+		if os.SameFile(info, blobInfo) {
+			return nil
+		}
+		if blobIntact(blobPath, blobInfo, info.Size(), sum, idOf) {
+			return replaceWithLink(blobPath, fullPath)
+		}
+		return replaceWithLink(fullPath, blobPath)
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	if err := os.Link(fullPath, blobPath); err != nil {
+		return fmt.Errorf("failed to link blob: %w", err)
+	}
+	return nil
+}
+
+// blobIntact 校验已有 blob 与标识相符
+// 仍被引用的 blob 只比较大小，重新计算标识的开销留给无人引用、无从察觉损坏的 blob。
+func blobIntact(blobPath string, blobInfo os.FileInfo, size int64, sum string, idOf func(string) (string, error)) bool {
+	if blobInfo.Size() != size {
+		return false
+	}
+	if _, links, ok := LinkInfo(blobInfo); !ok || links > 1 {
+		return true
+	}
+	got, err := idOf(blobPath)
+	return err == nil && got == sum
+}
+
+// LinkTo 在 fullPath 创建对 blob 的引用（覆盖已存在的文件）
+func (b *BlobStore) LinkTo(sum, fullPath string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	blobPath := b.Path(sum)
+	if _, err := os.Stat(blobPath); err != nil {
+		return err
+	}
+	return replaceWithLink(blobPath, fullPath)
+}
+
+// GC 删除无引用的 blob
+func (b *BlobStore) GC(ctx context.Context) (*BlobGCResult, error) {
+	result := &BlobGCResult{}
+	err := filepath.WalkDir(b.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		result.Scanned++
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil
+		}
+		_, links, ok := LinkInfo(info)
+		if !ok || links > 1 {
+			return nil
+		}
+		if err := os.Remove(p); err != nil {
+			return nil
+		}
+		result.Removed++
+		result.Freed += info.Size()
+		return nil
+	})
+	return result, err
+}
+
+// DetachShared 写入前断开共享引用，避免修改其他路径引用的同一份内容
+// truncate 为 true 时直接删除引用（随后会重新创建），否则复制为独立文件。
+func DetachShared(fullPath string, truncate bool) error {
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	if _, links, ok := LinkInfo(info); !ok || links <= 1 {
+		return nil
+	}
+	if truncate {
+		return os.Remove(fullPath)
+	}
+
+	src, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := tempSiblingPath(fullPath)
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, fullPath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// replaceWithLink 以原子重命名的方式将 target 替换为 source 的硬链接
+func replaceWithLink(source, target string) error {
+	tmp := tempSiblingPath(target)
+	if err := os.Link(source, tmp); err != nil {
+		return fmt.Errorf("failed to link blob: %w", err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace file with blob link: %w", err)
+	}
+	return nil
+}
+
+func tempSiblingPath(fullPath string) string {
+	return filepath.Join(filepath.Dir(fullPath), "._blob-"+uuid.NewString())
+}
@@ -0,0 +1,225 @@
+//go:build unix
+
+package webdavfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+func TestCopyWithBlobStoreSharesContent(t *testing.T) {
+	root := t.TempDir()
+	userDir := filepath.Join(root, "alice")
+	if err := os.MkdirAll(userDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	blobs, err := NewBlobStore(filepath.Join(root, ".blobs"), root)
+	if err != nil {
+		t.Fatalf("NewBlobStore returned error: %v", err)
+	}
+
+	fsys := NewUnicodeFileSystem(userDir)
+	fsys.SetBlobStore(blobs)
+	handler := &webdav.Handler{FileSystem: fsys, LockSystem: webdav.NewMemLS()}
+
+	put := httptest.NewRecorder()
+	handler.ServeHTTP(put, httptest.NewRequest(http.MethodPut, "/a.bin", strings.NewReader("artifact")))
+	if put.Code != http.StatusCreated {
+		t.Fatalf("unexpected PUT status: %d", put.Code)
+	}
+
+	copyReq := httptest.NewRequest("COPY", "/a.bin", nil)
+	copyReq.Header.Set("Destination", "/b.bin")
+	copyRec := httptest.NewRecorder()
+	handler.ServeHTTP(copyRec, copyReq)
+	if copyRec.Code != http.StatusCreated {
+		t.Fatalf("unexpected COPY status: %d", copyRec.Code)
+	}
+
+	a, _ := os.Stat(filepath.Join(userDir, "a.bin"))
+	b, _ := os.Stat(filepath.Join(userDir, "b.bin"))
+	if !os.SameFile(a, b) {
+		t.Fatalf("expected COPY to reference the same blob")
+	}
+	if _, links, _ := LinkInfo(a); links != 3 {
+		t.Fatalf("expected 3 links (blob + 2 paths), got %d", links)
+	}
+
+	// 覆盖写入不能影响另一个引用
+	overwrite := httptest.NewRecorder()
+	handler.ServeHTTP(overwrite, httptest.NewRequest(http.MethodPut, "/b.bin", strings.NewReader("changed")))
+	data, err := os.ReadFile(filepath.Join(userDir, "a.bin"))
+	if err != nil || string(data) != "artifact" {
+		t.Fatalf("shared content modified: %q, %v", data, err)
+	}
+
+	// 删除所有引用后 blob 被回收
+	if err := os.Remove(filepath.Join(userDir, "a.bin")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	result, err := blobs.GC(context.Background())
+	if err != nil {
+		t.Fatalf("GC returned error: %v", err)
+	}
+	if result.Removed != 1 {
+		t.Fatalf("expected 1 blob removed, got %d", result.Removed)
+	}
+	if !blobs.Has(mustHash(t, filepath.Join(userDir, "b.bin"))) {
+		t.Fatalf("referenced blob should be kept")
+	}
+}
+
+func mustHash(t *testing.T, fullPath string) string {
+	t.Helper()
+	sum, _, err := HashFile(fullPath)
+	if err != nil {
+		t.Fatalf("HashFile returned error: %v", err)
+	}
+	return sum
+}
+
+func TestCopyFileAcrossEncryptedUsersReencrypts(t *testing.T) {
+	root := t.TempDir()
+	blobs, err := NewBlobStore(filepath.Join(root, ".blobs"), root)
+	if err != nil {
+		t.Fatalf("NewBlobStore returned error: %v", err)
+	}
+	newFS := func(name string, key byte) *UnicodeFileSystem {
+		dir := filepath.Join(root, name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		keys, err := NewDataKeys(name, 1, map[uint32][]byte{1: []byte(strings.Repeat(string(key), 32))})
+		if err != nil {
+			t.Fatalf("NewDataKeys returned error: %v", err)
+		}
+		fsys := NewUnicodeFileSystem(dir)
+		fsys.SetBlobStore(blobs)
+		fsys.SetDataKeys(keys)
+		return fsys
+	}
+	alice := newFS("alice", 'a')
+	bob := newFS("bob", 'b')
+	ctx := context.Background()
+
+	handler := &webdav.Handler{FileSystem: alice, LockSystem: webdav.NewMemLS()}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/a.bin", strings.NewReader("artifact")))
+
+	// 同一用户内复制共享密文
+	if err := CopyFile(ctx, alice, "/a.bin", alice, "/copy.bin"); err != nil {
+		t.Fatalf("CopyFile returned error: %v", err)
+	}
+	a, _ := os.Stat(filepath.Join(root, "alice", "a.bin"))
+	c, _ := os.Stat(filepath.Join(root, "alice", "copy.bin"))
+	if !os.SameFile(a, c) {
+		t.Fatalf("expected same-user copy to reference the same blob")
+	}
+
+	// 跨用户复制以目标用户的密钥重新加密
+	if err := CopyFile(ctx, alice, "/a.bin", bob, "/saved.bin"); err != nil {
+		t.Fatalf("CopyFile returned error: %v", err)
+	}
+	b, _ := os.Stat(filepath.Join(root, "bob", "saved.bin"))
+	if os.SameFile(a, b) {
+		t.Fatalf("cross-user copy must not share ciphertext")
+	}
+	f, err := bob.OpenFile(ctx, "/saved.bin", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	defer f.Close()
+	buf := new(strings.Builder)
+	if _, err := io.Copy(buf, f); err != nil || buf.String() != "artifact" {
+		t.Fatalf("unexpected content %q (%v)", buf.String(), err)
+	}
+}
+
+func TestAdoptReplacesCorruptBlob(t *testing.T) {
+	root := t.TempDir()
+	userDir := filepath.Join(root, "alice")
+	if err := os.MkdirAll(userDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	blobs, err := NewBlobStore(filepath.Join(root, ".blobs"), root)
+	if err != nil {
+		t.Fatalf("NewBlobStore returned error: %v", err)
+	}
+	fsys := NewUnicodeFileSystem(userDir)
+	fsys.SetBlobStore(blobs)
+	handler := &webdav.Handler{FileSystem: fsys, LockSystem: webdav.NewMemLS()}
+	put := func(name, content string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, name, strings.NewReader(content)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("unexpected PUT %s status: %d", name, rec.Code)
+		}
+	}
+	assertAdopted := func(name, content string) {
+		t.Helper()
+		fullPath := filepath.Join(userDir, name)
+		data, err := os.ReadFile(fullPath)
+		if err != nil || string(data) != content {
+			t.Fatalf("%s content = %q, %v", name, data, err)
+		}
+		info, _ := os.Stat(fullPath)
+		blobInfo, err := os.Stat(blobs.Path(mustHash(t, fullPath)))
+		if err != nil || !os.SameFile(info, blobInfo) {
+			t.Fatalf("%s should reference a repaired blob: %v", name, err)
+		}
+	}
+
+	// 无人引用的 blob 被同长度内容篡改，只有重新计算哈希才能发现
+	put("/a.bin", "artifact")
+	blobPath := blobs.Path(mustHash(t, filepath.Join(userDir, "a.bin")))
+	if err := os.Remove(filepath.Join(userDir, "a.bin")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := os.WriteFile(blobPath, []byte("tampered"), 0644); err != nil {
+		t.Fatalf("tamper blob: %v", err)
+	}
+	put("/b.bin", "artifact")
+	assertAdopted("b.bin", "artifact")
+
+	// 仍被引用的 blob 长度与内容不符
+	put("/c.bin", "hello")
+	blobPath = blobs.Path(mustHash(t, filepath.Join(userDir, "c.bin")))
+	if err := os.WriteFile(blobPath, []byte("hello, corrupted"), 0644); err != nil {
+		t.Fatalf("tamper blob: %v", err)
+	}
+	put("/d.bin", "hello")
+	assertAdopted("d.bin", "hello")
+}
+
+func TestNewBlobStoreRejectsCrossDevice(t *testing.T) {
+	root := t.TempDir()
+	other, err := os.MkdirTemp("/dev/shm", "blobs-")
+	if err != nil {
+		t.Skipf("no second filesystem available: %v", err)
+	}
+	defer os.RemoveAll(other)
+	rootInfo, _ := os.Stat(root)
+	otherInfo, _ := os.Stat(other)
+	rootID, _, _ := LinkInfo(rootInfo)
+	otherID, _, _ := LinkInfo(otherInfo)
+	if strings.SplitN(rootID, ":", 2)[0] == strings.SplitN(otherID, ":", 2)[0] {
+		t.Skip("temp dir and /dev/shm share a filesystem")
+	}
+
+	if _, err := NewBlobStore(other, root); !errors.Is(err, ErrBlobStoreCrossDevice) {
+		t.Fatalf("expected ErrBlobStoreCrossDevice, got %v", err)
+	}
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 0 {
+		t.Fatalf("link probe left files behind: %v", entries)
+	}
+}
@@ -0,0 +1,815 @@
+package webdavfs
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/net/webdav"
+)
+
+// 静态加密文件格式：
+//
+//	header: magic(8) | 密钥版本(4) | 分块大小(4) | nonce 前缀(8)
+//	chunks: AES-256-GCM 密文，每块 chunkSize 字节明文加 16 字节认证标签
+//
+// 每块的 nonce 为 nonce 前缀 || 块序号，附加数据为 header || 块序号 || 是否末块，
+// 因此可以按块随机读取（支持 HTTP Range），截断或重排分块都会导致认证失败。
+// 文件至少包含一个末块（空文件为长度 0 的末块），只剩文件头的文件视为损坏。
+// 文件是否加密由 magic 判断，明文写入不允许以 magic 开头（见 plainFile）。
+const (
+	encMagic      = "WHENC\x00\x00\x01"
+	encHeaderSize = 24
+	encTagSize    = 16
+
+	// EncryptionChunkSize 加密分块的明文大小
+	EncryptionChunkSize = 64 * 1024
+)
+
+var (
+	// ErrDataKeyMissing 缺少解密所需的数据密钥
+	ErrDataKeyMissing = errors.New("data key not available")
+	// ErrEncryptedWrite 加密文件只支持整体覆盖写入
+	ErrEncryptedWrite = errors.New("encrypted files only support sequential overwrite")
+	// ErrCorruptEncryptedFile 加密文件损坏或被篡改
+	ErrCorruptEncryptedFile = errors.New("corrupt encrypted file")
+	// ErrReservedSignature 明文内容以加密文件头开头，保存后会被误判为密文
+	ErrReservedSignature = errors.New("content starts with the encrypted file signature")
+)
+
+// DataKeys 用户数据密钥（按版本保存），新写入的文件使用当前版本
+type DataKeys struct {
+	scope  string
+	active uint32
+	keys   map[uint32][]byte
+}
+
+// NewDataKeys 创建数据密钥集合，scope 标识密钥归属（如用户 ID）
+func NewDataKeys(scope string, active uint32, keys map[uint32][]byte) (*DataKeys, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("%w: version %d", ErrDataKeyMissing, active)
+	}
+	copied := make(map[uint32][]byte, len(keys))
+	for version, key := range keys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid data key length for version %d", version)
+		}
+		copied[version] = append([]byte(nil), key...)
+	}
+	return &DataKeys{scope: scope, active: active, keys: copied}, nil
+}
+
+// Scope 返回密钥归属
+func (k *DataKeys) Scope() string {
+	return k.scope
+}
+
+// ActiveVersion 返回新写入使用的密钥版本
+func (k *DataKeys) ActiveVersion() uint32 {
+	return k.active
+}
+
+// Has 判断是否持有指定版本的密钥
+func (k *DataKeys) Has(version uint32) bool {
+	_, ok := k.keys[version]
+	return ok
+}
+
+func (k *DataKeys) aead(version uint32) (cipher.AEAD, error) {
+	if k == nil {
+		return nil, ErrDataKeyMissing
+	}
+	key, ok := k.keys[version]
+	if !ok {
+		return nil, fmt.Errorf("%w: version %d", ErrDataKeyMissing, version)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encHeader 加密文件头
+type encHeader struct {
+	raw       [encHeaderSize]byte
+	version   uint32
+	chunkSize int64
+}
+
+func newEncHeader(version uint32) (*encHeader, error) {
+	h := &encHeader{version: version, chunkSize: EncryptionChunkSize}
+	copy(h.raw[0:8], encMagic)
+	binary.BigEndian.PutUint32(h.raw[8:12], version)
+	binary.BigEndian.PutUint32(h.raw[12:16], EncryptionChunkSize)
+	if _, err := rand.Read(h.raw[16:24]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce prefix: %w", err)
+	}
+	return h, nil
+}
+
+// readEncHeader 读取加密文件头，文件未加密时返回 nil
+func readEncHeader(r io.ReaderAt) (*encHeader, error) {
+	h := &encHeader{}
+	n, err := r.ReadAt(h.raw[:], 0)
+	if n < encHeaderSize {
+		if err == nil || err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if string(h.raw[0:8]) != encMagic {
+		return nil, nil
+	}
+	h.version = binary.BigEndian.Uint32(h.raw[8:12])
+	h.chunkSize = int64(binary.BigEndian.Uint32(h.raw[12:16]))
+	// 分块大小决定读取时的缓冲区大小，超过写入端使用的分块大小视为损坏，避免按篡改值分配内存
+	if h.chunkSize <= 0 || h.chunkSize > EncryptionChunkSize {
+		return nil, fmt.Errorf("%w: chunk size %d", ErrCorruptEncryptedFile, h.chunkSize)
+	}
+	return h, nil
+}
+
+// readEncHeaderFile 读取磁盘文件的加密文件头
+func readEncHeaderFile(fullPath string) (*encHeader, error) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readEncHeader(f)
+}
+
+// chunkCount 返回密文分块数
+// 写入端总会写出认证末块（空文件为长度 0 的末块），返回 0 说明文件被截断到只剩文件头。
+func (h *encHeader) chunkCount(rawSize int64) int64 {
+	body := rawSize - encHeaderSize
+	if body <= 0 {
+		return 0
+	}
+	unit := h.chunkSize + encTagSize
+	return (body + unit - 1) / unit
+}
+
+// plaintextSize 根据磁盘大小计算明文大小
+func (h *encHeader) plaintextSize(rawSize int64) int64 {
+	size := rawSize - encHeaderSize - h.chunkCount(rawSize)*encTagSize
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+func (h *encHeader) nonce(index uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, h.raw[16:24])
+	binary.BigEndian.PutUint32(nonce[8:], index)
+	return nonce
+}
+
+func (h *encHeader) aad(index uint32, final bool) []byte {
+	aad := make([]byte, encHeaderSize+5)
+	copy(aad, h.raw[:])
+	binary.BigEndian.PutUint32(aad[encHeaderSize:], index)
+	if final {
+		aad[encHeaderSize+4] = 1
+	}
+	return aad
+}
+
+// sizedInfo 加密文件信息，Size 返回明文大小
+type sizedInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (fi *sizedInfo) Size() int64 {
+	return fi.size
+}
+
+// rawInfo 返回磁盘文件信息（去除明文大小包装）
+func rawInfo(info os.FileInfo) os.FileInfo {
+	if sized, ok := info.(*sizedInfo); ok {
+		return sized.FileInfo
+	}
+	return info
+}
+
+// encryptedReader 按块解密读取，支持 Seek 与 ReadAt
+type encryptedReader struct {
+	f       *os.File
+	aead    cipher.AEAD
+	header  *encHeader
+	rawSize int64
+	size    int64
+	chunks  int64
+	pos     int64
+	cached  int64
+	buf     []byte
+	ct      []byte
+}
+
+func newEncryptedReader(f *os.File, header *encHeader, aead cipher.AEAD, rawSize int64) *encryptedReader {
+	return &encryptedReader{
+		f:       f,
+		aead:    aead,
+		header:  header,
+		rawSize: rawSize,
+		size:    header.plaintextSize(rawSize),
+		chunks:  header.chunkCount(rawSize),
+		cached:  -1,
+	}
+}
+
+// chunk 解密第 index 块
+func (r *encryptedReader) chunk(index int64) ([]byte, error) {
+	if index == r.cached {
+		return r.buf, nil
+	}
+	if index >= r.chunks || index > math.MaxUint32 {
+		return nil, ErrCorruptEncryptedFile
+	}
+	unit := r.header.chunkSize + encTagSize
+	off := encHeaderSize + index*unit
+	n := r.rawSize - off
+	if n > unit {
+		n = unit
+	}
+	if cap(r.ct) < int(n) {
+		r.ct = make([]byte, unit)
+	}
+	ct := r.ct[:n]
+	if _, err := r.f.ReadAt(ct, off); err != nil {
+		return nil, err
+	}
+	plain, err := r.aead.Open(r.buf[:0], r.header.nonce(uint32(index)), ct, r.header.aad(uint32(index), index == r.chunks-1))
+	if err != nil {
+		r.cached = -1
+		return nil, ErrCorruptEncryptedFile
+	}
+	r.buf = plain
+	r.cached = index
+	return plain, nil
+}
+
+func (r *encryptedReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if r.size == 0 {
+		// 空文件只有一个认证末块，读到结尾前先校验，否则截断后的文件会被当作空文件
+		if _, err := r.chunk(0); err != nil {
+			return 0, err
+		}
+	}
+	n := 0
+	for n < len(p) {
+		if off >= r.size {
+			return n, io.EOF
+		}
+		index := off / r.header.chunkSize
+		plain, err := r.chunk(index)
+		if err != nil {
+			return n, err
+		}
+		k := copy(p[n:], plain[off-index*r.header.chunkSize:])
+		n += k
+		off += int64(k)
+	}
+	return n, nil
+}
+
+func (r *encryptedReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, err := r.ReadAt(p, r.pos)
+	r.pos += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *encryptedReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if pos < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.pos = pos
+	return pos, nil
+}
+
+func (r *encryptedReader) Write(p []byte) (int, error) {
+	return 0, ErrEncryptedWrite
+}
+
+func (r *encryptedReader) Readdir(count int) ([]os.FileInfo, error) {
+	return r.f.Readdir(count)
+}
+
+func (r *encryptedReader) Stat() (os.FileInfo, error) {
+	info, err := r.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &sizedInfo{FileInfo: info, size: r.size}, nil
+}
+
+func (r *encryptedReader) Close() error {
+	return r.f.Close()
+}
+
+// encryptedWriter 顺序写入并按块加密，关闭时写入末块
+type encryptedWriter struct {
+	f       *os.File
+	aead    cipher.AEAD
+	header  *encHeader
+	buf     []byte
+	ct      []byte
+	index   uint32
+	written int64
+	closed  bool
+}
+
+func newEncryptedWriter(f *os.File, keys *DataKeys) (*encryptedWriter, error) {
+	header, err := newEncHeader(keys.ActiveVersion())
+	if err != nil {
+		return nil, err
+	}
+	aead, err := keys.aead(header.version)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(header.raw[:]); err != nil {
+		return nil, err
+	}
+	return &encryptedWriter{
+		f:      f,
+		aead:   aead,
+		header: header,
+		buf:    make([]byte, 0, header.chunkSize),
+	}, nil
+}
+
+func (w *encryptedWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	n := 0
+	for len(p) > 0 {
+		if int64(len(w.buf)) == w.header.chunkSize {
+			if err := w.flush(false); err != nil {
+				return n, err
+			}
+		}
+		k := copy(w.buf[len(w.buf):w.header.chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+k]
+		p = p[k:]
+		n += k
+		w.written += int64(k)
+	}
+	return n, nil
+}
+
+func (w *encryptedWriter) flush(final bool) error {
+	if w.index == math.MaxUint32 && !final {
+		return errors.New("encrypted file too large")
+	}
+	w.ct = w.aead.Seal(w.ct[:0], w.header.nonce(w.index), w.buf, w.header.aad(w.index, final))
+	if _, err := w.f.Write(w.ct); err != nil {
+		return err
+	}
+	w.index++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Seek 只支持查询当前位置
+func (w *encryptedWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && (whence == io.SeekCurrent || whence == io.SeekEnd) {
+		return w.written, nil
+	}
+	if whence == io.SeekStart && offset == w.written {
+		return w.written, nil
+	}
+	return 0, ErrEncryptedWrite
+}
+
+func (w *encryptedWriter) Read(p []byte) (int, error) {
+	return 0, ErrEncryptedWrite
+}
+
+func (w *encryptedWriter) Readdir(count int) ([]os.FileInfo, error) {
+	return w.f.Readdir(count)
+}
+
+func (w *encryptedWriter) Stat() (os.FileInfo, error) {
+	info, err := w.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &sizedInfo{FileInfo: info, size: w.written}, nil
+}
+
+func (w *encryptedWriter) Close() error {
+	if w.closed {
+		return os.ErrClosed
+	}
+	w.closed = true
+	if err := w.flush(true); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
+
+// SetDataKeys 设置数据密钥，之后写入的文件都会加密保存
+func (fsys *UnicodeFileSystem) SetDataKeys(keys *DataKeys) {
+	fsys.keys = keys
+}
+
+// openHandle 打开底层文件：加密文件按块解密读取，启用加密时覆盖写入会加密保存
+func (fsys *UnicodeFileSystem) openHandle(fullPath string, flag int, perm os.FileMode) (webdav.File, error) {
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	truncate := flag&os.O_TRUNC != 0
+	if writable && !truncate && flag&os.O_WRONLY != 0 {
+		// 只写打开无法读取文件头，提前检查，避免在密文中间写入明文
+		header, err := readEncHeaderFile(fullPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if header != nil {
+			return nil, ErrEncryptedWrite
+		}
+	}
+
+	f, err := os.OpenFile(fullPath, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return f, nil
+	}
+	if writable && truncate {
+		if fsys.keys == nil {
+			return newPlainFile(f, fullPath, flag)
+		}
+		w, err := newEncryptedWriter(f, fsys.keys)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return w, nil
+	}
+	if flag&os.O_WRONLY != 0 {
+		return newPlainFile(f, fullPath, flag)
+	}
+
+	header, err := readEncHeader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if header == nil {
+		if writable {
+			return newPlainFile(f, fullPath, flag)
+		}
+		return f, nil
+	}
+	if header.chunkCount(info.Size()) == 0 {
+		f.Close()
+		return nil, ErrCorruptEncryptedFile
+	}
+	aead, err := fsys.keys.aead(header.version)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return newEncryptedReader(f, header, aead, info.Size()), nil
+}
+
+// plainFile 可写的明文文件：拒绝使文件以加密文件头 magic 开头的写入，
+// 文件是否加密由文件头判断，这样的明文保存后会在读取时被当作密文解密失败。
+type plainFile struct {
+	f      *os.File
+	head   []byte // 文件开头 len(encMagic) 字节内的当前内容
+	pos    int64
+	append bool
+}
+
+func newPlainFile(f *os.File, fullPath string, flag int) (webdav.File, error) {
+	head := make([]byte, len(encMagic))
+	n, err := f.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		// 只写打开无法读取，单独打开读取文件头
+		r, openErr := os.Open(fullPath)
+		if openErr != nil {
+			f.Close()
+			return nil, openErr
+		}
+		n, err = r.ReadAt(head, 0)
+		r.Close()
+		if err != nil && err != io.EOF {
+			f.Close()
+			return nil, err
+		}
+	}
+	return &plainFile{f: f, head: head[:n], append: flag&os.O_APPEND != 0}, nil
+}
+
+// checkHead 计算在 off 处写入 p 之后的文件头，命中加密文件头时返回错误
+func (w *plainFile) checkHead(p []byte, off int64) ([]byte, error) {
+	if w.append {
+		info, err := w.f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		off = info.Size()
+	}
+	limit := int64(len(encMagic))
+	if off >= limit || len(p) == 0 {
+		return w.head, nil
+	}
+	end := off + int64(len(p))
+	if end > limit {
+		end = limit
+	}
+	if size := int64(len(w.head)); size > end {
+		end = size
+	}
+	head := make([]byte, end)
+	copy(head, w.head)
+	copy(head[off:], p)
+	if string(head) == encMagic {
+		return nil, ErrReservedSignature
+	}
+	return head, nil
+}
+
+func (w *plainFile) Write(p []byte) (int, error) {
+	head, err := w.checkHead(p, w.pos)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.f.Write(p)
+	if n > 0 {
+		w.head = head
+	}
+	w.pos += int64(n)
+	return n, err
+}
+
+func (w *plainFile) WriteAt(p []byte, off int64) (int, error) {
+	head, err := w.checkHead(p, off)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.f.WriteAt(p, off)
+	if n > 0 {
+		w.head = head
+	}
+	return n, err
+}
+
+func (w *plainFile) Truncate(size int64) error {
+	if err := w.f.Truncate(size); err != nil {
+		return err
+	}
+	if size < int64(len(w.head)) {
+		w.head = w.head[:size]
+	}
+	return nil
+}
+
+func (w *plainFile) Read(p []byte) (int, error) {
+	n, err := w.f.Read(p)
+	w.pos += int64(n)
+	return n, err
+}
+
+func (w *plainFile) ReadAt(p []byte, off int64) (int, error) {
+	return w.f.ReadAt(p, off)
+}
+
+func (w *plainFile) Seek(offset int64, whence int) (int64, error) {
+	pos, err := w.f.Seek(offset, whence)
+	if err == nil {
+		w.pos = pos
+	}
+	return pos, err
+}
+
+func (w *plainFile) Readdir(count int) ([]os.FileInfo, error) {
+	return w.f.Readdir(count)
+}
+
+func (w *plainFile) Stat() (os.FileInfo, error) {
+	return w.f.Stat()
+}
+
+func (w *plainFile) Close() error {
+	return w.f.Close()
+}
+
+// plainInfo 将磁盘文件信息转换为明文大小的信息（仅启用加密时需要读取文件头）
+func (fsys *UnicodeFileSystem) plainInfo(fullPath string, raw os.FileInfo) os.FileInfo {
+	if fsys.keys == nil || !raw.Mode().IsRegular() || raw.Size() < encHeaderSize {
+		return raw
+	}
+	header, err := readEncHeaderFile(fullPath)
+	if err != nil || header == nil {
+		return raw
+	}
+	return &sizedInfo{FileInfo: raw, size: header.plaintextSize(raw.Size())}
+}
+
+// blobID 返回文件在 blob 存储中的标识
+// 明文文件直接使用内容哈希；加密文件的密文只能由同一密钥解密，标识同时绑定密钥归属与版本。
+func (fsys *UnicodeFileSystem) blobID(fullPath, sum string) (string, error) {
+	header, err := readEncHeaderFile(fullPath)
+	if err != nil {
+		return "", err
+	}
+	if header == nil {
+		return sum, nil
+	}
+	if fsys.keys == nil {
+		return "", ErrDataKeyMissing
+	}
+	h := sha256.New()
+	h.Write([]byte(fsys.keys.Scope()))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatUint(uint64(header.version), 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(sum))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// blobIDOf 重新计算磁盘文件的 blob 标识，用于校验已有 blob 的内容
+func (fsys *UnicodeFileSystem) blobIDOf(fullPath string) (string, error) {
+	sum, err := fsys.hashFullPath(fullPath)
+	if err != nil {
+		return "", err
+	}
+	return fsys.blobID(fullPath, sum)
+}
+
+// canShareBlob 判断目标文件系统能否直接引用源文件的磁盘内容
+func (fsys *UnicodeFileSystem) canShareBlob(src *UnicodeFileSystem, srcPath string) bool {
+	header, err := readEncHeaderFile(srcPath)
+	if err != nil {
+		return false
+	}
+	if header == nil {
+		// 启用加密后新写入的内容必须加密保存
+		return fsys.keys == nil
+	}
+	return fsys.keys != nil && src.keys != nil &&
+		fsys.keys.Scope() == src.keys.Scope() && fsys.keys.Has(header.version)
+}
+
+// hashFullPath 计算文件明文内容的 SHA-256
+func (fsys *UnicodeFileSystem) hashFullPath(fullPath string) (string, error) {
+	h, err := fsys.openHandle(fullPath, os.O_RDONLY, 0)
+	if err != nil {
+		return "", err
+	}
+	defer h.Close()
+	return hashReader(h)
+}
+
+func hashReader(r io.Reader) (string, error) {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, struct{ io.Reader }{r}); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// FullPath 返回文件在磁盘上的完整路径
+func (fsys *UnicodeFileSystem) FullPath(name string) string {
+	return filepath.Join(fsys.dir, name)
+}
+
+// ContentHash 计算文件明文内容的 SHA-256，同时返回计算时的磁盘文件信息
+func (fsys *UnicodeFileSystem) ContentHash(ctx context.Context, name string) (string, os.FileInfo, error) {
+	fullPath := fsys.FullPath(name)
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return "", nil, err
+	}
+	if info.IsDir() {
+		return "", nil, fmt.Errorf("cannot hash directory: %s", fullPath)
+	}
+	sum, err := fsys.hashFullPath(fullPath)
+	if err != nil {
+		return "", nil, err
+	}
+	return sum, info, nil
+}
+
+// Reencrypt 使用当前密钥版本重新加密文件（明文文件会被加密）
+// 文件已使用当前版本加密时返回 false。
+func (fsys *UnicodeFileSystem) Reencrypt(ctx context.Context, name string) (bool, error) {
+	if fsys.keys == nil {
+		return false, ErrDataKeyMissing
+	}
+	fullPath := fsys.FullPath(name)
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return false, err
+	}
+	if !info.Mode().IsRegular() {
+		return false, nil
+	}
+	header, err := readEncHeaderFile(fullPath)
+	if err != nil {
+		return false, err
+	}
+	if header != nil && header.version == fsys.keys.ActiveVersion() {
+		return false, nil
+	}
+
+	src, err := fsys.openHandle(fullPath, os.O_RDONLY, 0)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+
+	// 写入临时文件后原子替换，原路径若为共享 blob 引用也不会被修改
+	tmp := tempSiblingPath(fullPath)
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return false, err
+	}
+	dst, err := newEncryptedWriter(out, fsys.keys)
+	if err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return false, err
+	}
+	hasher := sha256.New()
+	if err := copyAndHash(dst, src, hasher); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return false, err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	if err := os.Chtimes(tmp, info.ModTime(), info.ModTime()); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+	if err := os.Rename(tmp, fullPath); err != nil {
+		os.Remove(tmp)
+		return false, err
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if fsys.blobs != nil {
+		if id, err := fsys.blobID(fullPath, sum); err == nil {
+			_ = fsys.blobs.Adopt(fullPath, id, fsys.blobIDOf)
+		}
+	}
+	if fsys.hashes != nil {
+		if info, err := os.Stat(fullPath); err == nil {
+			fsys.hashes.Record(ctx, filepath.ToSlash(name), sum, info)
+		}
+	}
+	return true, nil
+}
+
+func copyAndHash(dst io.Writer, src io.Reader, hasher hash.Hash) error {
+	_, err := io.Copy(io.MultiWriter(dst, hasher), struct{ io.Reader }{src})
+	return err
+}
@@ -0,0 +1,275 @@
+package webdavfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+func testDataKeys(t *testing.T, active uint32) *DataKeys {
+	t.Helper()
+	keys := make(map[uint32][]byte)
+	for v := uint32(1); v <= active; v++ {
+		keys[v] = bytes.Repeat([]byte{byte(v)}, 32)
+	}
+	dk, err := NewDataKeys("user-1", active, keys)
+	if err != nil {
+		t.Fatalf("NewDataKeys returned error: %v", err)
+	}
+	return dk
+}
+
+func TestEncryptedPutServesPlaintextWithRanges(t *testing.T) {
+	dir := t.TempDir()
+	index := &memoryHashIndex{sums: make(map[string]string)}
+	fsys := NewUnicodeFileSystem(dir)
+	fsys.SetContentHashIndex(index)
+	fsys.SetDataKeys(testDataKeys(t, 1))
+	handler := &webdav.Handler{FileSystem: fsys, LockSystem: webdav.NewMemLS()}
+
+	// 跨越多个分块，末块不满
+	body := make([]byte, 2*EncryptionChunkSize+1234)
+	for i := range body {
+		body[i] = byte(i % 251)
+	}
+	sum := sha256.Sum256(body)
+
+	put := httptest.NewRecorder()
+	handler.ServeHTTP(put, httptest.NewRequest(http.MethodPut, "/big.bin", bytes.NewReader(body)))
+	if put.Code != http.StatusCreated {
+		t.Fatalf("unexpected PUT status: %d", put.Code)
+	}
+	if got := index.sums["/big.bin"]; got != hex.EncodeToString(sum[:]) {
+		t.Fatalf("content hash must be computed over plaintext, got %q", got)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, "big.bin"))
+	if err != nil {
+		t.Fatalf("failed to read raw file: %v", err)
+	}
+	if bytes.Contains(raw, body[:1024]) {
+		t.Fatalf("file stored in plaintext")
+	}
+
+	info, err := fsys.Stat(context.Background(), "/big.bin")
+	if err != nil {
+		t.Fatalf("Stat returned error: %v", err)
+	}
+	if info.Size() != int64(len(body)) {
+		t.Fatalf("unexpected plaintext size: %d", info.Size())
+	}
+
+	get := httptest.NewRecorder()
+	handler.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/big.bin", nil))
+	if !bytes.Equal(get.Body.Bytes(), body) {
+		t.Fatalf("GET returned different content (%d bytes)", get.Body.Len())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/big.bin", nil)
+	req.Header.Set("Range", "bytes=65530-65545")
+	ranged := httptest.NewRecorder()
+	handler.ServeHTTP(ranged, req)
+	if ranged.Code != http.StatusPartialContent {
+		t.Fatalf("unexpected range status: %d", ranged.Code)
+	}
+	if !bytes.Equal(ranged.Body.Bytes(), body[65530:65546]) {
+		t.Fatalf("range across chunk boundary returned wrong bytes")
+	}
+}
+
+func TestEncryptedFileDetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	fsys := NewUnicodeFileSystem(dir)
+	fsys.SetDataKeys(testDataKeys(t, 1))
+	ctx := context.Background()
+
+	f, err := fsys.OpenFile(ctx, "/a.txt", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	if _, err := f.Write([]byte("secret content")); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	fullPath := filepath.Join(dir, "a.txt")
+	raw, err := os.ReadFile(fullPath)
+	if err != nil {
+		t.Fatalf("failed to read raw file: %v", err)
+	}
+	raw[len(raw)-1] ^= 0xff
+	if err := os.WriteFile(fullPath, raw, 0666); err != nil {
+		t.Fatalf("failed to write raw file: %v", err)
+	}
+
+	r, err := fsys.OpenFile(ctx, "/a.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	defer r.Close()
+	if _, err := io.ReadAll(r); err != ErrCorruptEncryptedFile {
+		t.Fatalf("expected ErrCorruptEncryptedFile, got %v", err)
+	}
+}
+
+func TestEncryptedFileDetectsTruncation(t *testing.T) {
+	dir := t.TempDir()
+	fsys := NewUnicodeFileSystem(dir)
+	fsys.SetDataKeys(testDataKeys(t, 1))
+	ctx := context.Background()
+
+	write := func(name string, content []byte) string {
+		f, err := fsys.OpenFile(ctx, name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+		if err != nil {
+			t.Fatalf("OpenFile returned error: %v", err)
+		}
+		if _, err := f.Write(content); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatalf("Close returned error: %v", err)
+		}
+		return filepath.Join(dir, name)
+	}
+	read := func(name string) ([]byte, error) {
+		r, err := fsys.OpenFile(ctx, name, os.O_RDONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+
+	// 空明文也写出认证末块
+	emptyPath := write("/empty.txt", nil)
+	if info, err := os.Stat(emptyPath); err != nil || info.Size() != encHeaderSize+encTagSize {
+		t.Fatalf("empty plaintext should be stored as header plus final chunk, got %v %v", info, err)
+	}
+	if got, err := read("/empty.txt"); err != nil || len(got) != 0 {
+		t.Fatalf("empty file should read back empty, got %q %v", got, err)
+	}
+
+	fullPath := write("/a.txt", []byte("secret content"))
+	for _, size := range []int64{encHeaderSize, encHeaderSize + encTagSize} {
+		if err := os.Truncate(fullPath, size); err != nil {
+			t.Fatalf("Truncate returned error: %v", err)
+		}
+		if _, err := read("/a.txt"); !errors.Is(err, ErrCorruptEncryptedFile) {
+			t.Fatalf("truncated to %d bytes: expected ErrCorruptEncryptedFile, got %v", size, err)
+		}
+	}
+	if err := os.Truncate(emptyPath, encHeaderSize); err != nil {
+		t.Fatalf("Truncate returned error: %v", err)
+	}
+	if _, err := read("/empty.txt"); !errors.Is(err, ErrCorruptEncryptedFile) {
+		t.Fatalf("header-only file: expected ErrCorruptEncryptedFile, got %v", err)
+	}
+}
+
+func TestReencryptMovesFileToActiveKey(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	fullPath := filepath.Join(dir, "legacy.txt")
+	if err := os.WriteFile(fullPath, []byte("plaintext before encryption"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	fsys := NewUnicodeFileSystem(dir)
+	fsys.SetDataKeys(testDataKeys(t, 1))
+	if changed, err := fsys.Reencrypt(ctx, "/legacy.txt"); err != nil || !changed {
+		t.Fatalf("Reencrypt of plaintext file: changed=%v err=%v", changed, err)
+	}
+
+	rotated := NewUnicodeFileSystem(dir)
+	rotated.SetDataKeys(testDataKeys(t, 2))
+	if changed, err := rotated.Reencrypt(ctx, "/legacy.txt"); err != nil || !changed {
+		t.Fatalf("Reencrypt after rotation: changed=%v err=%v", changed, err)
+	}
+	if changed, err := rotated.Reencrypt(ctx, "/legacy.txt"); err != nil || changed {
+		t.Fatalf("Reencrypt with active key should be a no-op: changed=%v err=%v", changed, err)
+	}
+
+	header, err := readEncHeaderFile(fullPath)
+	if err != nil || header == nil || header.version != 2 {
+		t.Fatalf("expected file encrypted with version 2, got %+v (%v)", header, err)
+	}
+	f, err := rotated.OpenFile(ctx, "/legacy.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != "plaintext before encryption" {
+		t.Fatalf("unexpected content %q (%v)", data, err)
+	}
+}
+
+func TestReadEncHeaderRejectsOversizedChunks(t *testing.T) {
+	header, err := newEncHeader(1)
+	if err != nil {
+		t.Fatalf("newEncHeader failed: %v", err)
+	}
+	raw := header.raw
+	if got, err := readEncHeader(bytes.NewReader(raw[:])); err != nil || got.chunkSize != EncryptionChunkSize {
+		t.Fatalf("readEncHeader = %+v, err=%v", got, err)
+	}
+	for _, size := range []uint32{0, EncryptionChunkSize + 1, 1 << 31} {
+		binary.BigEndian.PutUint32(raw[12:16], size)
+		if _, err := readEncHeader(bytes.NewReader(raw[:])); !errors.Is(err, ErrCorruptEncryptedFile) {
+			t.Fatalf("chunk size %d: expected ErrCorruptEncryptedFile, got %v", size, err)
+		}
+	}
+}
+
+func TestPlaintextWriteRejectsEncryptedSignature(t *testing.T) {
+	dir := t.TempDir()
+	fsys := NewUnicodeFileSystem(dir)
+	ctx := context.Background()
+
+	// 分两次写入拼出完整 magic 也要拒绝
+	f, err := fsys.OpenFile(ctx, "/fake.bin", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile returned error: %v", err)
+	}
+	if _, err := f.Write([]byte(encMagic[:3])); err != nil {
+		t.Fatalf("partial signature should be accepted: %v", err)
+	}
+	payload := append([]byte(encMagic[3:]), bytes.Repeat([]byte{0}, 32)...)
+	if _, err := f.Write(payload); !errors.Is(err, ErrReservedSignature) {
+		t.Fatalf("expected ErrReservedSignature, got %v", err)
+	}
+	f.Close()
+
+	handler := &webdav.Handler{FileSystem: fsys, LockSystem: webdav.NewMemLS()}
+	body := append([]byte(encMagic), bytes.Repeat([]byte{1}, 32)...)
+	put := httptest.NewRecorder()
+	handler.ServeHTTP(put, httptest.NewRequest(http.MethodPut, "/put.bin", bytes.NewReader(body)))
+	if put.Code == http.StatusCreated || put.Code == http.StatusNoContent {
+		t.Fatalf("expected PUT with encrypted signature to fail, got %d", put.Code)
+	}
+
+	// 其他内容不受影响
+	ok := httptest.NewRecorder()
+	handler.ServeHTTP(ok, httptest.NewRequest(http.MethodPut, "/ok.bin", bytes.NewReader(body[1:])))
+	if ok.Code != http.StatusCreated {
+		t.Fatalf("unexpected PUT status: %d", ok.Code)
+	}
+	get := httptest.NewRecorder()
+	handler.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/ok.bin", nil))
+	if !bytes.Equal(get.Body.Bytes(), body[1:]) {
+		t.Fatalf("unexpected GET body")
+	}
+}
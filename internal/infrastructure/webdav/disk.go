@@ -0,0 +1,29 @@
+package webdavfs
+
+import (
+	"fmt"
+	"os"
+)
+
+// ProbeWritable 在目录下创建、写入并删除临时文件，确认目录可写
+func ProbeWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".warehouse-probe-*")
+	if err != nil {
+		return fmt.Errorf("create probe file: %w", err)
+	}
+	name := f.Name()
+	defer os.Remove(name)
+
+	if _, err := f.Write([]byte("ok")); err != nil {
+		f.Close()
+		return fmt.Errorf("write probe file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync probe file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close probe file: %w", err)
+	}
+	return os.Remove(name)
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd
+
+package webdavfs
+
+import "errors"
+
+// DiskSpace 当前平台不支持查询可用空间
+func DiskSpace(path string) (free, total uint64, err error) {
+	return 0, 0, errors.ErrUnsupported
+}
@@ -0,0 +1,14 @@
+//go:build linux || darwin || freebsd
+
+package webdavfs
+
+import "syscall"
+
+// DiskSpace 返回路径所在文件系统对非特权用户可用的字节数与总字节数
+func DiskSpace(path string) (free, total uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), uint64(st.Blocks) * uint64(st.Bsize), nil
+}
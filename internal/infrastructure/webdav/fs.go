@@ -2,6 +2,8 @@ package webdavfs
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -12,7 +14,10 @@ import (
 
 // UnicodeFileSystem 包装 webdav.Dir 以正确支持 Unicode 路径
 type UnicodeFileSystem struct {
-	dir string
+	dir    string
+	hashes ContentHashIndex
+	blobs  *BlobStore
+	keys   *DataKeys
 }
 
 // NewUnicodeFileSystem 创建一个支持 Unicode 路径的 FileSystem
@@ -20,6 +25,11 @@ func NewUnicodeFileSystem(dir string) *UnicodeFileSystem {
 	return &UnicodeFileSystem{dir: dir}
 }
 
+// SetContentHashIndex 设置内容哈希索引，写入文件时同步计算 SHA-256
+func (fsys *UnicodeFileSystem) SetContentHashIndex(index ContentHashIndex) {
+	fsys.hashes = index
+}
+
 // Stat 返回文件信息
 func (fsys *UnicodeFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
 	fullPath := filepath.Join(fsys.dir, name)
@@ -31,7 +41,12 @@ func (fsys *UnicodeFileSystem) Stat(ctx context.Context, name string) (os.FileIn
 	if IsIgnoredName(baseName) {
 		return nil, os.ErrNotExist
 	}
-	return &fileInfo{FileInfo: info, name: baseName}, nil
+	return fsys.newFileInfo(ctx, name, fullPath, info, baseName), nil
+}
+
+// SetBlobStore 设置去重 blob 存储，写入完成的文件会被纳入 blob 并以硬链接引用
+func (fsys *UnicodeFileSystem) SetBlobStore(blobs *BlobStore) {
+	fsys.blobs = blobs
 }
 
 // OpenFile 打开或创建文件
@@ -41,11 +56,22 @@ func (fsys *UnicodeFileSystem) OpenFile(ctx context.Context, name string, flag i
 		return nil, os.ErrNotExist
 	}
 	fullPath := filepath.Join(fsys.dir, name)
-	f, err := os.OpenFile(fullPath, flag, perm)
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if writable {
+		// 共享的 blob 引用不能原地修改
+		if err := DetachShared(fullPath, flag&os.O_TRUNC != 0); err != nil {
+			return nil, err
+		}
+	}
+	f, err := fsys.openHandle(fullPath, flag, perm)
 	if err != nil {
 		return nil, err
 	}
-	return &file{File: f, name: filepath.ToSlash(name)}, nil
+	wrapped := &file{File: f, name: filepath.ToSlash(name), fullPath: fullPath, ctx: ctx, fsys: fsys}
+	if writable && (fsys.hashes != nil || fsys.blobs != nil) {
+		return newHashingFile(ctx, wrapped, fsys.hashes, fullPath), nil
+	}
+	return wrapped, nil
 }
 
 // Create 新建文件
@@ -72,7 +98,13 @@ func (fsys *UnicodeFileSystem) Rename(ctx context.Context, oldName, newName stri
 	}
 	oldPath := filepath.Join(fsys.dir, oldName)
 	newPath := filepath.Join(fsys.dir, newName)
-	return os.Rename(oldPath, newPath)
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+	if fsys.hashes != nil {
+		fsys.hashes.Rename(ctx, filepath.ToSlash(oldName), filepath.ToSlash(newName))
+	}
+	return nil
 }
 
 // RemoveAll 删除文件或目录
@@ -82,7 +114,13 @@ func (fsys *UnicodeFileSystem) RemoveAll(ctx context.Context, name string) error
 		return os.ErrNotExist
 	}
 	fullPath := filepath.Join(fsys.dir, name)
-	return os.RemoveAll(fullPath)
+	if err := os.RemoveAll(fullPath); err != nil {
+		return err
+	}
+	if fsys.hashes != nil {
+		fsys.hashes.Remove(ctx, filepath.ToSlash(name))
+	}
+	return nil
 }
 
 // ReadDir 读取目录内容
@@ -102,31 +140,102 @@ func (fsys *UnicodeFileSystem) ReadDir(ctx context.Context, name string) ([]os.F
 		if IsIgnoredName(entry.Name()) {
 			continue
 		}
-		infos = append(infos, &fileInfo{FileInfo: info, name: entry.Name()})
+		entryPath := filepath.Join(fullPath, entry.Name())
+		infos = append(infos, fsys.newFileInfo(ctx, path.Join(filepath.ToSlash(name), entry.Name()), entryPath, info, entry.Name()))
 	}
 	return infos, nil
 }
 
+// newFileInfo 构造文件信息（加密文件返回明文大小），并附带已记录的内容哈希
+func (fsys *UnicodeFileSystem) newFileInfo(ctx context.Context, name, fullPath string, info os.FileInfo, baseName string) *fileInfo {
+	raw := rawInfo(info)
+	if raw == info {
+		info = fsys.plainInfo(fullPath, raw)
+	}
+	fi := &fileInfo{FileInfo: info, name: baseName}
+	if fsys.hashes != nil && !raw.IsDir() {
+		fi.sum = fsys.hashes.Lookup(ctx, filepath.ToSlash(name), raw)
+	}
+	return fi
+}
+
 // fileInfo 实现 os.FileInfo 并添加自定义名称
 type fileInfo struct {
 	os.FileInfo
 	name string
+	sum  string // 内容 SHA-256，未知时为空
 }
 
 func (fi *fileInfo) Name() string {
 	return fi.name
 }
 
-// file 包装 os.File
+// ETag 实现 webdav.ETager，已知内容哈希时返回强 ETag
+func (fi *fileInfo) ETag(ctx context.Context) (string, error) {
+	if fi.sum == "" || fi.IsDir() {
+		return "", webdav.ErrNotImplemented
+	}
+	return `"` + fi.sum + `"`, nil
+}
+
+// file 包装底层文件句柄（*os.File 或加密读写器）
 type file struct {
-	*os.File
-	name string
+	webdav.File
+	name     string
+	fullPath string
+	ctx      context.Context
+	fsys     *UnicodeFileSystem
 }
 
 func (f *file) Name() string {
 	return f.name
 }
 
+// WriteTo 目标为可写包装文件时交由其 ReadFrom 处理，使 COPY 可以只复制引用
+func (f *file) WriteTo(w io.Writer) (int64, error) {
+	if dst, ok := w.(*hashingFile); ok {
+		return dst.ReadFrom(f)
+	}
+	return io.Copy(w, struct{ io.Reader }{f.File})
+}
+
+// Stat 返回带有内容哈希的文件信息，供 GET/HEAD 生成 ETag
+func (f *file) Stat() (os.FileInfo, error) {
+	info, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return f.fsys.newFileInfo(f.ctx, f.name, f.fullPath, info, info.Name()), nil
+}
+
+// CopyFile 在两个文件系统之间复制文件
+// 内容按需解密后再以目标的密钥加密；启用 blob 存储且目标可直接引用源内容时只创建引用。
+func CopyFile(ctx context.Context, src *UnicodeFileSystem, srcName string, dst *UnicodeFileSystem, dstName string) error {
+	in, err := src.OpenFile(ctx, srcName, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("cannot copy directory: %s", srcName)
+	}
+
+	out, err := dst.OpenFile(ctx, dstName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
 // ResolvePath 解析并规范化路径
 func ResolvePath(path string) string {
 	path = filepath.Clean(path)
@@ -153,3 +262,6 @@ func IsIgnoredName(name string) bool {
 
 // 确保 UnicodeFileSystem 实现 webdav.FileSystem
 var _ webdav.FileSystem = (*UnicodeFileSystem)(nil)
+
+// 确保 fileInfo 实现 webdav.ETager
+var _ webdav.ETager = (*fileInfo)(nil)
@@ -0,0 +1,248 @@
+package webdavfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// ContentHashIndex 文件内容哈希索引（name 为文件系统内的相对路径）
+type ContentHashIndex interface {
+	// Lookup 返回与 info 一致的 SHA-256（十六进制），不存在或已过期返回空字符串
+	Lookup(ctx context.Context, name string, info os.FileInfo) string
+
+	// Record 记录写入完成后的内容哈希
+	Record(ctx context.Context, name string, sum string, info os.FileInfo)
+
+	// Rename 同步重命名/移动后的路径
+	Rename(ctx context.Context, oldName, newName string)
+
+	// Remove 删除路径（含子路径）的哈希记录
+	Remove(ctx context.Context, name string)
+}
+
+// HashFile 计算磁盘文件内容的 SHA-256（不解密，加密文件请使用 UnicodeFileSystem.ContentHash）
+func HashFile(fullPath string) (string, os.FileInfo, error) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", nil, err
+	}
+	if info.IsDir() {
+		return "", nil, fmt.Errorf("cannot hash directory: %s", fullPath)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", nil, fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), info, nil
+}
+
+// ContentHashOf 返回文件信息附带的内容 SHA-256（来自 UnicodeFileSystem），未知时返回空字符串
+func ContentHashOf(info os.FileInfo) string {
+	if fi, ok := info.(*fileInfo); ok {
+		return fi.sum
+	}
+	return ""
+}
+
+// errReplacedByBlobLink 文件已替换为 blob 引用，不能继续写入
+var errReplacedByBlobLink = errors.New("file replaced by blob link")
+
+// hashingFile 在顺序写入时同步计算 SHA-256，关闭时写入索引并纳入 blob 存储
+type hashingFile struct {
+	*file
+	ctx       context.Context
+	index     ContentHashIndex
+	fullPath  string
+	hasher    hash.Hash
+	offset    int64
+	dirty     bool   // 出现非顺序写入，关闭时需重新读取文件计算
+	linkedSum string // 通过 blob 引用复制时的内容哈希
+}
+
+func newHashingFile(ctx context.Context, f *file, index ContentHashIndex, fullPath string) *hashingFile {
+	return &hashingFile{
+		file:     f,
+		ctx:      ctx,
+		index:    index,
+		fullPath: fullPath,
+		hasher:   sha256.New(),
+	}
+}
+
+func (f *hashingFile) Write(p []byte) (int, error) {
+	if f.linkedSum != "" {
+		return 0, errReplacedByBlobLink
+	}
+	n, err := f.file.File.Write(p)
+	if n > 0 && !f.dirty {
+		f.hasher.Write(p[:n])
+	}
+	f.offset += int64(n)
+	return n, err
+}
+
+// ReadFrom 覆盖 *os.File 的实现，确保 io.Copy 经过 Write 计算哈希；
+// 源为同一文件系统的文件且启用了 blob 存储时，只创建引用而不复制内容
+func (f *hashingFile) ReadFrom(r io.Reader) (int64, error) {
+	if src, ok := r.(*file); ok {
+		if n, ok := f.linkFrom(src); ok {
+			return n, nil
+		}
+		return io.Copy(struct{ io.Writer }{f}, struct{ io.Reader }{src.File})
+	}
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+// linkFrom 将目标替换为源文件内容 blob 的引用
+func (f *hashingFile) linkFrom(src *file) (int64, bool) {
+	blobs := f.fsys.blobs
+	if blobs == nil || src.fsys == nil || f.offset != 0 || f.dirty {
+		return 0, false
+	}
+	srcPath := src.fullPath
+	info, err := src.File.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return 0, false
+	}
+	if !f.fsys.canShareBlob(src.fsys, srcPath) {
+		return 0, false
+	}
+
+	sum := ""
+	if src.fsys.hashes != nil {
+		sum = src.fsys.hashes.Lookup(src.ctx, src.name, rawInfo(info))
+	}
+	if sum == "" {
+		if sum, err = src.fsys.hashFullPath(srcPath); err != nil {
+			return 0, false
+		}
+	}
+	id, err := src.fsys.blobID(srcPath, sum)
+	if err != nil {
+		return 0, false
+	}
+	if err := blobs.Adopt(srcPath, id, src.fsys.blobIDOf); err != nil {
+		return 0, false
+	}
+	// 源文件可能被替换为 blob 引用，修改时间随之变化，重新记录哈希
+	if src.fsys.hashes != nil {
+		if srcInfo, err := os.Stat(srcPath); err == nil {
+			src.fsys.hashes.Record(src.ctx, src.name, sum, srcInfo)
+		}
+	}
+	if err := blobs.LinkTo(id, f.fullPath); err != nil {
+		return 0, false
+	}
+	f.linkedSum = sum
+	return info.Size(), true
+}
+
+func (f *hashingFile) WriteAt(p []byte, off int64) (int, error) {
+	w, ok := f.file.File.(io.WriterAt)
+	if !ok {
+		return 0, ErrEncryptedWrite
+	}
+	f.dirty = true
+	return w.WriteAt(p, off)
+}
+
+func (f *hashingFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *hashingFile) Read(p []byte) (int, error) {
+	n, err := f.file.File.Read(p)
+	if n > 0 {
+		f.dirty = true
+	}
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *hashingFile) Seek(offset int64, whence int) (int64, error) {
+	pos, err := f.file.File.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+	if pos != f.offset {
+		f.dirty = true
+	}
+	f.offset = pos
+	return pos, nil
+}
+
+func (f *hashingFile) Truncate(size int64) error {
+	t, ok := f.file.File.(interface{ Truncate(int64) error })
+	if !ok {
+		return ErrEncryptedWrite
+	}
+	f.dirty = true
+	return t.Truncate(size)
+}
+
+// Stat 返回带有当前内容哈希的文件信息，供 PUT 响应生成 ETag
+func (f *hashingFile) Stat() (os.FileInfo, error) {
+	if f.linkedSum != "" {
+		info, err := os.Stat(f.fullPath)
+		if err != nil {
+			return nil, err
+		}
+		return &fileInfo{FileInfo: f.fsys.plainInfo(f.fullPath, info), name: info.Name(), sum: f.linkedSum}, nil
+	}
+	info, err := f.file.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	fi := &fileInfo{FileInfo: info, name: info.Name()}
+	if !f.dirty && info.Size() == f.offset {
+		fi.sum = hex.EncodeToString(f.hasher.Sum(nil))
+	}
+	return fi, nil
+}
+
+func (f *hashingFile) Close() error {
+	// 关闭前获取已写入的大小（加密文件为明文大小）
+	written, statErr := f.file.File.Stat()
+	if err := f.file.File.Close(); err != nil {
+		return err
+	}
+
+	sum := f.linkedSum
+	if sum == "" {
+		if statErr != nil {
+			return nil
+		}
+		var err error
+		if !f.dirty && written.Size() == f.offset {
+			sum = hex.EncodeToString(f.hasher.Sum(nil))
+		} else if sum, err = f.fsys.hashFullPath(f.fullPath); err != nil {
+			return nil
+		}
+		// 纳入 blob 存储失败时文件保持独立存储，不影响写入结果
+		if f.fsys.blobs != nil {
+			if id, err := f.fsys.blobID(f.fullPath, sum); err == nil {
+				_ = f.fsys.blobs.Adopt(f.fullPath, id, f.fsys.blobIDOf)
+			}
+		}
+	}
+
+	if f.index != nil {
+		if info, err := os.Stat(f.fullPath); err == nil {
+			f.index.Record(f.ctx, f.name, sum, info)
+		}
+	}
+	return nil
+}
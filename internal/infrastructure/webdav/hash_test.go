@@ -0,0 +1,99 @@
+package webdavfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+type memoryHashIndex struct {
+	mu   sync.Mutex
+	sums map[string]string
+}
+
+func (i *memoryHashIndex) Lookup(ctx context.Context, name string, info os.FileInfo) string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.sums[name]
+}
+
+func (i *memoryHashIndex) Record(ctx context.Context, name string, sum string, info os.FileInfo) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.sums[name] = sum
+}
+
+func (i *memoryHashIndex) Rename(ctx context.Context, oldName, newName string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.sums[newName] = i.sums[oldName]
+	delete(i.sums, oldName)
+}
+
+func (i *memoryHashIndex) Remove(ctx context.Context, name string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.sums, name)
+}
+
+func TestPutRecordsContentHashAndServesStrongETag(t *testing.T) {
+	index := &memoryHashIndex{sums: make(map[string]string)}
+	fsys := NewUnicodeFileSystem(t.TempDir())
+	fsys.SetContentHashIndex(index)
+	handler := &webdav.Handler{FileSystem: fsys, LockSystem: webdav.NewMemLS()}
+
+	body := "hello warehouse"
+	sum := sha256.Sum256([]byte(body))
+	want := hex.EncodeToString(sum[:])
+
+	put := httptest.NewRecorder()
+	handler.ServeHTTP(put, httptest.NewRequest(http.MethodPut, "/docs.txt", strings.NewReader(body)))
+	if put.Code != http.StatusCreated {
+		t.Fatalf("unexpected PUT status: %d", put.Code)
+	}
+	if got := put.Header().Get("ETag"); got != `"`+want+`"` {
+		t.Fatalf("unexpected PUT ETag: %q", got)
+	}
+	if got := index.sums["/docs.txt"]; got != want {
+		t.Fatalf("unexpected recorded hash: %q", got)
+	}
+
+	get := httptest.NewRecorder()
+	handler.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/docs.txt", nil))
+	if got := get.Header().Get("ETag"); got != `"`+want+`"` {
+		t.Fatalf("unexpected GET ETag: %q", got)
+	}
+
+	move := httptest.NewRequest("MOVE", "/docs.txt", nil)
+	move.Header.Set("Destination", "/moved.txt")
+	handler.ServeHTTP(httptest.NewRecorder(), move)
+	if got := index.sums["/moved.txt"]; got != want {
+		t.Fatalf("hash not moved with file: %q", got)
+	}
+}
+
+func TestHashFileMatchesSequentialHash(t *testing.T) {
+	dir := t.TempDir()
+	fullPath := dir + "/data.bin"
+	if err := os.WriteFile(fullPath, []byte("abc"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	sum, info, err := HashFile(fullPath)
+	if err != nil {
+		t.Fatalf("HashFile returned error: %v", err)
+	}
+	if sum != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Fatalf("unexpected sum: %s", sum)
+	}
+	if info.Size() != 3 {
+		t.Fatalf("unexpected size: %d", info.Size())
+	}
+}
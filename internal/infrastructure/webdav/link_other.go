@@ -0,0 +1,10 @@
+//go:build !unix
+
+package webdavfs
+
+import "os"
+
+// LinkInfo 当前平台不支持硬链接计数
+func LinkInfo(info os.FileInfo) (string, uint64, bool) {
+	return "", 0, false
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package webdavfs
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// LinkInfo 返回文件的 inode 标识与硬链接数
+func LinkInfo(info os.FileInfo) (string, uint64, bool) {
+	if info == nil {
+		return "", 0, false
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", 0, false
+	}
+	return fmt.Sprintf("%d:%d", uint64(st.Dev), uint64(st.Ino)), uint64(st.Nlink), true
+}
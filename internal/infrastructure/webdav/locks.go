@@ -0,0 +1,79 @@
+package webdavfs
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// CountingLockSystem 记录活跃锁数量的 LockSystem 包装
+// 内部 LockSystem 负责锁语义，这里只按令牌跟踪过期时间，用于监控指标。
+type CountingLockSystem struct {
+	webdav.LockSystem
+
+	mu     sync.Mutex
+	tokens map[string]time.Time // 零值表示永不过期
+}
+
+// NewCountingLockSystem 包装 LockSystem
+func NewCountingLockSystem(ls webdav.LockSystem) *CountingLockSystem {
+	return &CountingLockSystem{
+		LockSystem: ls,
+		tokens:     make(map[string]time.Time),
+	}
+}
+
+// Create 创建锁
+func (c *CountingLockSystem) Create(now time.Time, details webdav.LockDetails) (string, error) {
+	token, err := c.LockSystem.Create(now, details)
+	if err == nil {
+		c.mu.Lock()
+		c.tokens[token] = lockExpiry(now, details.Duration)
+		c.mu.Unlock()
+	}
+	return token, err
+}
+
+// Refresh 刷新锁
+func (c *CountingLockSystem) Refresh(now time.Time, token string, duration time.Duration) (webdav.LockDetails, error) {
+	details, err := c.LockSystem.Refresh(now, token, duration)
+	c.mu.Lock()
+	if err == nil {
+		c.tokens[token] = lockExpiry(now, details.Duration)
+	} else if err == webdav.ErrNoSuchLock {
+		delete(c.tokens, token)
+	}
+	c.mu.Unlock()
+	return details, err
+}
+
+// Unlock 释放锁
+func (c *CountingLockSystem) Unlock(now time.Time, token string) error {
+	err := c.LockSystem.Unlock(now, token)
+	if err == nil || err == webdav.ErrNoSuchLock {
+		c.mu.Lock()
+		delete(c.tokens, token)
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// Active 返回未过期的锁数量，同时清理已过期的令牌
+func (c *CountingLockSystem) Active(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for token, expiry := range c.tokens {
+		if !expiry.IsZero() && !now.Before(expiry) {
+			delete(c.tokens, token)
+		}
+	}
+	return len(c.tokens)
+}
+
+func lockExpiry(now time.Time, duration time.Duration) time.Time {
+	if duration < 0 {
+		return time.Time{}
+	}
+	return now.Add(duration)
+}
@@ -5,6 +5,7 @@ import "time"
 // ChallengeRequest 挑战请求
 type ChallengeRequest struct {
 	Address string `json:"address"`
+	ChainID uint64 `json:"chainId,omitempty"`
 }
 
 // ChallengeResponse 挑战响应
@@ -18,6 +19,7 @@ type ChallengeResponse struct {
 type VerifyRequest struct {
 	Address   string `json:"address"`
 	Signature string `json:"signature"`
+	Message   string `json:"message,omitempty"` // 签名的 EIP-4361 原文，为空时使用服务端签发的挑战
 }
 
 // VerifyResponse 验证响应
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// AccountMailHandler 重置密码与验证邮箱处理器
+// 邮件语言取自请求的 Accept-Language。
+type AccountMailHandler struct {
+	service *service.AccountMailService
+	logger  *zap.Logger
+}
+
+// NewAccountMailHandler 创建重置密码与验证邮箱处理器
+func NewAccountMailHandler(service *service.AccountMailService, logger *zap.Logger) *AccountMailHandler {
+	return &AccountMailHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// HandleForgotPassword 发送重置密码邮件；无论邮箱是否注册都返回成功
+func (h *AccountMailHandler) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(r.Context(), req.Email, r.Header.Get("Accept-Language")); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	h.sendSuccess(w, map[string]any{"sent": true})
+}
+
+// HandleResetPassword 使用重置链接中的令牌设置新密码
+func (h *AccountMailHandler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	u, err := h.service.ResetPassword(r.Context(), req.Token, req.Password)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	middleware.SetAuditActor(r.Context(), u)
+	h.sendSuccess(w, map[string]any{"username": u.Username})
+}
+
+// HandleConfirmEmail 使用验证链接中的令牌确认（或更换）邮箱
+func (h *AccountMailHandler) HandleConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	u, err := h.service.ConfirmEmail(r.Context(), req.Token)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	middleware.SetAuditActor(r.Context(), u)
+	h.sendSuccess(w, map[string]any{
+		"email":          u.Email,
+		"email_verified": u.IsEmailVerified(),
+	})
+}
+
+// HandleChangeEmail 请求更换邮箱：向新邮箱发送验证链接，确认后才生效
+func (h *AccountMailHandler) HandleChangeEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
+		h.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.RequestEmailChange(r.Context(), u, req.Email, r.Header.Get("Accept-Language")); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	h.sendSuccess(w, map[string]any{"sent": true})
+}
+
+// HandleSendVerification 重新发送当前邮箱的验证链接
+func (h *AccountMailHandler) HandleSendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "Only POST method is allowed")
+		return
+	}
+
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.service.RequestEmailVerification(r.Context(), u, r.Header.Get("Accept-Language")); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	h.sendSuccess(w, map[string]any{
+		"sent":           !u.IsEmailVerified(),
+		"email_verified": u.IsEmailVerified(),
+	})
+}
+
+func (h *AccountMailHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, user.ErrInvalidEmail):
+		h.sendError(w, http.StatusBadRequest, "Invalid email address")
+	case errors.Is(err, user.ErrInvalidPassword):
+		h.sendError(w, http.StatusBadRequest, "Password must be at least 6 characters")
+	case errors.Is(err, user.ErrDuplicateEmail):
+		h.sendError(w, http.StatusConflict, "Email is already used by another account")
+	case errors.Is(err, auth.ErrMailTooFrequent):
+		h.sendError(w, http.StatusTooManyRequests, "Too many requests")
+	case errors.Is(err, auth.ErrTokenUsed):
+		h.sendError(w, http.StatusGone, "Link has already been used")
+	case errors.Is(err, auth.ErrInvalidToken), errors.Is(err, auth.ErrTokenExpired):
+		h.sendError(w, http.StatusBadRequest, "Invalid or expired link")
+	default:
+		logger.Ctx(r.Context(), h.logger).Error("account mail request failed", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "Failed to process request")
+	}
+}
+
+func (h *AccountMailHandler) sendSuccess(w http.ResponseWriter, data interface{}) {
+	h.sendResponse(w, http.StatusOK, 0, "ok", data)
+}
+
+func (h *AccountMailHandler) sendError(w http.ResponseWriter, status int, message string) {
+	h.sendResponse(w, status, status, message, nil)
+}
+
+func (h *AccountMailHandler) sendResponse(w http.ResponseWriter, status int, code int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	response := sdkResponse{
+		Code:      code,
+		Message:   message,
+		Data:      data,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
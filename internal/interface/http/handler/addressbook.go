@@ -6,6 +6,7 @@ import (
 
 	"github.com/yeying-community/warehouse/internal/application/service"
 	"github.com/yeying-community/warehouse/internal/domain/addressbook"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
@@ -34,7 +35,7 @@ func (h *AddressBookHandler) HandleGroupList(w http.ResponseWriter, r *http.Requ
 	}
 	groups, err := h.service.ListGroups(r.Context(), u)
 	if err != nil {
-		h.logger.Error("failed to list groups", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to list groups", zap.Error(err))
 		http.Error(w, "Failed to list groups", http.StatusInternalServerError)
 		return
 	}
@@ -172,7 +173,7 @@ func (h *AddressBookHandler) HandleContactList(w http.ResponseWriter, r *http.Re
 	}
 	contacts, err := h.service.ListContacts(r.Context(), u)
 	if err != nil {
-		h.logger.Error("failed to list contacts", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to list contacts", zap.Error(err))
 		http.Error(w, "Failed to list contacts", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/audit"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"go.uber.org/zap"
+)
+
+// AdminAuditHandler serves the audit log query and export API.
+type AdminAuditHandler struct {
+	auditService *service.AuditService
+	logger       *zap.Logger
+}
+
+// NewAdminAuditHandler creates a new AdminAuditHandler.
+func NewAdminAuditHandler(auditService *service.AuditService, logger *zap.Logger) *AdminAuditHandler {
+	return &AdminAuditHandler{
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+type adminAuditEventResponse struct {
+	ID        int64  `json:"id"`
+	CreatedAt string `json:"created_at"`
+	Action    string `json:"action"`
+	Outcome   string `json:"outcome"`
+	ActorID   string `json:"actor_id,omitempty"`
+	Actor     string `json:"actor,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	AppID     string `json:"app_id,omitempty"`
+	Target    string `json:"target,omitempty"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// HandleList returns a filtered, paginated page of audit events, newest first.
+func (h *AdminAuditHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	events, total, err := h.auditService.Query(r.Context(), filter)
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to query audit log", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to query audit log")
+		return
+	}
+	filter.Normalize()
+
+	items := make([]adminAuditEventResponse, 0, len(events))
+	for _, e := range events {
+		items = append(items, buildAdminAuditEventResponse(e))
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"items":  items,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// HandleExport streams every matching audit event as NDJSON, oldest first.
+func (h *AdminAuditHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.ndjson", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	err = h.auditService.Export(r.Context(), filter, func(e *audit.Event) error {
+		return encoder.Encode(buildAdminAuditEventResponse(e))
+	})
+	if err != nil {
+		// Headers are already sent; the truncated stream is the only signal left.
+		logger.Ctx(r.Context(), h.logger).Error("failed to export audit log", zap.Error(err))
+	}
+}
+
+// parseAuditFilter reads the filter from query parameters.
+// from/to accept RFC 3339 timestamps or YYYY-MM-DD dates; a date-only "to" includes the whole day.
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Actor:   strings.TrimSpace(query.Get("actor")),
+		Action:  strings.TrimSpace(query.Get("action")),
+		Outcome: strings.ToLower(strings.TrimSpace(query.Get("outcome"))),
+		Target:  strings.TrimSpace(query.Get("target")),
+		IP:      strings.TrimSpace(query.Get("ip")),
+	}
+	if filter.Outcome != "" && !audit.ValidOutcome(filter.Outcome) {
+		return filter, fmt.Errorf("invalid outcome: %s", filter.Outcome)
+	}
+
+	var err error
+	if filter.From, err = parseAuditTime(query.Get("from"), false); err != nil {
+		return filter, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = parseAuditTime(query.Get("to"), true); err != nil {
+		return filter, fmt.Errorf("invalid to: %w", err)
+	}
+
+	for name, dst := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		raw := strings.TrimSpace(query.Get(name))
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return filter, fmt.Errorf("invalid %s", name)
+		}
+		*dst = n
+	}
+	return filter, nil
+}
+
+func parseAuditTime(raw string, endOfDay bool) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func buildAdminAuditEventResponse(e *audit.Event) adminAuditEventResponse {
+	return adminAuditEventResponse{
+		ID:        e.ID,
+		CreatedAt: e.CreatedAt.Format(time.RFC3339),
+		Action:    e.Action,
+		Outcome:   e.Outcome,
+		ActorID:   e.ActorID,
+		Actor:     e.Actor,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+		AppID:     e.AppID,
+		Target:    e.Target,
+		Status:    e.Status,
+		Detail:    e.Detail,
+	}
+}
+
+func (h *AdminAuditHandler) writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *AdminAuditHandler) writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":   message,
+		"code":    code,
+		"success": false,
+	})
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/outbox"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"go.uber.org/zap"
+)
+
+// AdminMailHandler serves the mail outbox inspection and retry API.
+type AdminMailHandler struct {
+	outboxService *service.MailOutboxService
+	logger        *zap.Logger
+}
+
+// NewAdminMailHandler creates a new AdminMailHandler.
+func NewAdminMailHandler(outboxService *service.MailOutboxService, logger *zap.Logger) *AdminMailHandler {
+	return &AdminMailHandler{
+		outboxService: outboxService,
+		logger:        logger,
+	}
+}
+
+// adminMailResponse omits the template data: it may hold login codes or reset links.
+type adminMailResponse struct {
+	ID            string `json:"id"`
+	Recipient     string `json:"recipient"`
+	Template      string `json:"template"`
+	Locale        string `json:"locale,omitempty"`
+	Status        string `json:"status"`
+	Attempts      int    `json:"attempts"`
+	LastError     string `json:"last_error,omitempty"`
+	NextAttemptAt string `json:"next_attempt_at,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+	SentAt        string `json:"sent_at,omitempty"`
+}
+
+// HandleList returns a filtered, paginated page of outbox messages, newest first.
+func (h *AdminMailHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	filter, err := parseOutboxFilter(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	messages, total, err := h.outboxService.List(r.Context(), filter)
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to query mail outbox", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to query mail outbox")
+		return
+	}
+	filter.Normalize()
+
+	items := make([]adminMailResponse, 0, len(messages))
+	for _, m := range messages {
+		items = append(items, buildAdminMailResponse(m))
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"items":  items,
+		"total":  total,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+}
+
+// HandleRetry puts a dead message back into the send queue.
+// body: {"id": "..."}
+func (h *AdminMailHandler) HandleRetry(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.ID) == "" {
+		h.writeError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	m, err := h.outboxService.Retry(r.Context(), strings.TrimSpace(req.ID))
+	if err != nil {
+		switch {
+		case errors.Is(err, outbox.ErrMessageNotFound):
+			h.writeError(w, http.StatusNotFound, "Message not found")
+		case errors.Is(err, outbox.ErrNotDead):
+			h.writeError(w, http.StatusConflict, "Only dead messages can be retried")
+		default:
+			logger.Ctx(r.Context(), h.logger).Error("failed to retry mail", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "Failed to retry mail")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, buildAdminMailResponse(m))
+}
+
+// parseOutboxFilter reads the filter from query parameters.
+func parseOutboxFilter(r *http.Request) (outbox.Filter, error) {
+	query := r.URL.Query()
+	filter := outbox.Filter{
+		Status:    strings.ToLower(strings.TrimSpace(query.Get("status"))),
+		Recipient: strings.TrimSpace(query.Get("recipient")),
+	}
+	if filter.Status != "" && !outbox.ValidStatus(filter.Status) {
+		return filter, fmt.Errorf("invalid status: %s", filter.Status)
+	}
+
+	for name, dst := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		raw := strings.TrimSpace(query.Get(name))
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return filter, fmt.Errorf("invalid %s", name)
+		}
+		*dst = n
+	}
+	return filter, nil
+}
+
+func buildAdminMailResponse(m *outbox.Message) adminMailResponse {
+	resp := adminMailResponse{
+		ID:        m.ID,
+		Recipient: m.Recipient,
+		Template:  m.Template,
+		Locale:    m.Locale,
+		Status:    m.Status,
+		Attempts:  m.Attempts,
+		LastError: m.LastError,
+		CreatedAt: m.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: m.UpdatedAt.Format(time.RFC3339),
+	}
+	if m.Status == outbox.StatusPending {
+		resp.NextAttemptAt = m.NextAttemptAt.Format(time.RFC3339)
+	}
+	if m.SentAt != nil {
+		resp.SentAt = m.SentAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+func (h *AdminMailHandler) writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *AdminMailHandler) writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":   message,
+		"code":    code,
+		"success": false,
+	})
+}
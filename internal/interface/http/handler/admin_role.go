@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// AdminRoleHandler manages admin roles and role assignments.
+type AdminRoleHandler struct {
+	rbacService    *service.RBACService
+	userRepository user.Repository
+	logger         *zap.Logger
+}
+
+// NewAdminRoleHandler creates a new AdminRoleHandler.
+func NewAdminRoleHandler(rbacService *service.RBACService, userRepo user.Repository, logger *zap.Logger) *AdminRoleHandler {
+	return &AdminRoleHandler{
+		rbacService:    rbacService,
+		userRepository: userRepo,
+		logger:         logger,
+	}
+}
+
+type adminRoleResponse struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	BuiltIn     bool     `json:"built_in"`
+}
+
+type adminRoleAssignmentResponse struct {
+	UserID    string `json:"user_id"`
+	Username  string `json:"username,omitempty"`
+	Role      string `json:"role"`
+	GrantedBy string `json:"granted_by,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+type adminRoleAssignRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+type adminRoleSaveRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// HandleMe returns the roles and effective admin permissions of the current user.
+func (h *AdminRoleHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	roles, err := h.rbacService.UserRoles(r.Context(), u)
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to list user roles", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to load roles")
+		return
+	}
+	perms, err := h.rbacService.Permissions(r.Context(), u)
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to load permissions", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to load roles")
+		return
+	}
+
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, role.Name)
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"roles":       names,
+		"permissions": permissionNames(perms.List()),
+		"bootstrap":   h.rbacService.IsBootstrapAdmin(u),
+	})
+}
+
+// HandleList lists all roles.
+func (h *AdminRoleHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	roles, err := h.rbacService.ListRoles(r.Context())
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to list roles", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to list roles")
+		return
+	}
+	items := make([]adminRoleResponse, 0, len(roles))
+	for _, role := range roles {
+		items = append(items, buildAdminRoleResponse(role))
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"items":       items,
+		"permissions": permissionNames(rbac.AllPermissions()),
+	})
+}
+
+// HandleAssignments lists all role assignments.
+func (h *AdminRoleHandler) HandleAssignments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	assignments, err := h.rbacService.ListAssignments(r.Context())
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to list role assignments", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to list role assignments")
+		return
+	}
+
+	usernames := make(map[string]string)
+	items := make([]adminRoleAssignmentResponse, 0, len(assignments))
+	for _, a := range assignments {
+		name, ok := usernames[a.UserID]
+		if !ok {
+			if u, err := h.userRepository.FindByID(r.Context(), a.UserID); err == nil {
+				name = u.Username
+			}
+			usernames[a.UserID] = name
+		}
+		items = append(items, adminRoleAssignmentResponse{
+			UserID:    a.UserID,
+			Username:  name,
+			Role:      a.Role,
+			GrantedBy: a.GrantedBy,
+			CreatedAt: a.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// HandleAssign grants a role to a user.
+func (h *AdminRoleHandler) HandleAssign(w http.ResponseWriter, r *http.Request) {
+	actor, target, role, ok := h.decodeAssignment(w, r)
+	if !ok {
+		return
+	}
+	if err := h.rbacService.Assign(r.Context(), actor, target, role); err != nil {
+		h.writeRoleError(w, err, "Failed to assign role")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"username": target.Username, "role": role, "assigned": true})
+}
+
+// HandleRevoke removes a role from a user.
+func (h *AdminRoleHandler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	actor, target, role, ok := h.decodeAssignment(w, r)
+	if !ok {
+		return
+	}
+	if err := h.rbacService.Revoke(r.Context(), actor, target, role); err != nil {
+		h.writeRoleError(w, err, "Failed to revoke role")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"username": target.Username, "role": role, "revoked": true})
+}
+
+// HandleSave creates or updates a custom role.
+func (h *AdminRoleHandler) HandleSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	actor, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req adminRoleSaveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	role, err := h.rbacService.SaveRole(r.Context(), actor, req.Name, req.Description, req.Permissions)
+	if err != nil {
+		h.writeRoleError(w, err, "Failed to save role")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, buildAdminRoleResponse(role))
+}
+
+// HandleDelete deletes a custom role and its assignments.
+func (h *AdminRoleHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	actor, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if name == "" {
+		h.writeError(w, http.StatusBadRequest, "Role name is required")
+		return
+	}
+	if err := h.rbacService.DeleteRole(r.Context(), actor, name); err != nil {
+		h.writeRoleError(w, err, "Failed to delete role")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"deleted": true})
+}
+
+func (h *AdminRoleHandler) decodeAssignment(w http.ResponseWriter, r *http.Request) (*user.User, *user.User, string, bool) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return nil, nil, "", false
+	}
+	actor, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, nil, "", false
+	}
+	var req adminRoleAssignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, nil, "", false
+	}
+	username := strings.TrimSpace(req.Username)
+	role := strings.ToLower(strings.TrimSpace(req.Role))
+	if username == "" || role == "" {
+		h.writeError(w, http.StatusBadRequest, "Username and role are required")
+		return nil, nil, "", false
+	}
+	target, err := h.userRepository.FindByUsername(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			h.writeError(w, http.StatusNotFound, "User not found")
+			return nil, nil, "", false
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to find user")
+		return nil, nil, "", false
+	}
+	return actor, target, role, true
+}
+
+func (h *AdminRoleHandler) writeRoleError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, rbac.ErrPrivilegeEscalation):
+		h.writeError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, rbac.ErrRoleNotFound), errors.Is(err, rbac.ErrAssignmentNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, rbac.ErrInvalidRoleName), errors.Is(err, rbac.ErrUnknownPermission):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, rbac.ErrBuiltInRole), errors.Is(err, rbac.ErrLastSuperadmin), errors.Is(err, rbac.ErrRoleExists):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error(strings.ToLower(fallback), zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func (h *AdminRoleHandler) writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *AdminRoleHandler) writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":   message,
+		"code":    code,
+		"success": false,
+	})
+}
+
+func buildAdminRoleResponse(role *rbac.Role) adminRoleResponse {
+	return adminRoleResponse{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: permissionNames(role.Permissions),
+		BuiltIn:     role.BuiltIn,
+	}
+}
+
+func permissionNames(perms []rbac.Permission) []string {
+	out := make([]string, 0, len(perms))
+	for _, p := range perms {
+		out = append(out, string(p))
+	}
+	return out
+}
@@ -2,13 +2,19 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/yeying-community/warehouse/internal/application/assetspace"
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/ratelimit"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
 
@@ -18,18 +24,26 @@ type AdminUserHandler struct {
 	userRepository    user.Repository
 	passwordHasher    *crypto.PasswordHasher
 	assetSpaceManager *assetspace.Manager
+	rbacService       *service.RBACService
+	lockout           *ratelimit.Lockout
 }
 
 // NewAdminUserHandler creates a new AdminUserHandler.
-func NewAdminUserHandler(logger *zap.Logger, userRepo user.Repository, assetSpaceManager *assetspace.Manager) *AdminUserHandler {
+func NewAdminUserHandler(logger *zap.Logger, userRepo user.Repository, assetSpaceManager *assetspace.Manager, rbacService *service.RBACService) *AdminUserHandler {
 	return &AdminUserHandler{
 		logger:            logger,
 		userRepository:    userRepo,
 		passwordHasher:    crypto.NewPasswordHasher(),
 		assetSpaceManager: assetSpaceManager,
+		rbacService:       rbacService,
 	}
 }
 
+// SetLockout sets the account lockout used by the unlock endpoints.
+func (h *AdminUserHandler) SetLockout(lockout *ratelimit.Lockout) {
+	h.lockout = lockout
+}
+
 type adminRuleRequest struct {
 	Path        string   `json:"path"`
 	Permissions []string `json:"permissions"`
@@ -67,6 +81,17 @@ type adminUserResetPasswordRequest struct {
 	Password string `json:"password"`
 }
 
+type adminUserUnlockRequest struct {
+	Username string `json:"username"`
+}
+
+type adminLockedUserResponse struct {
+	ID          string `json:"id"`
+	Username    string `json:"username,omitempty"`
+	Lockouts    int    `json:"lockouts"`
+	LockedUntil string `json:"locked_until"`
+}
+
 type adminRuleResponse struct {
 	Path        string   `json:"path"`
 	Permissions []string `json:"permissions"`
@@ -78,6 +103,7 @@ type adminUserResponse struct {
 	Username      string              `json:"username"`
 	WalletAddress string              `json:"wallet_address,omitempty"`
 	Email         string              `json:"email,omitempty"`
+	EmailVerified bool                `json:"email_verified"`
 	Directory     string              `json:"directory"`
 	Permissions   []string            `json:"permissions"`
 	Quota         int64               `json:"quota"`
@@ -97,7 +123,7 @@ func (h *AdminUserHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 
 	users, err := h.userRepository.List(r.Context())
 	if err != nil {
-		h.logger.Error("failed to list users", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to list users", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to list users")
 		return
 	}
@@ -119,7 +145,7 @@ func (h *AdminUserHandler) HandleCreate(w http.ResponseWriter, r *http.Request)
 
 	var req adminUserCreateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		h.writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -139,7 +165,7 @@ func (h *AdminUserHandler) HandleCreate(w http.ResponseWriter, r *http.Request)
 		h.writeError(w, http.StatusConflict, "Username already exists")
 		return
 	} else if err != nil && err != user.ErrUserNotFound {
-		h.logger.Error("failed to check user", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to check user", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to check user")
 		return
 	}
@@ -154,7 +180,7 @@ func (h *AdminUserHandler) HandleCreate(w http.ResponseWriter, r *http.Request)
 	if strings.TrimSpace(req.Password) != "" {
 		hashed, err := h.passwordHasher.Hash(req.Password)
 		if err != nil {
-			h.logger.Error("failed to hash password", zap.Error(err))
+			logger.Ctx(r.Context(), h.logger).Error("failed to hash password", zap.Error(err))
 			h.writeError(w, http.StatusInternalServerError, "Failed to hash password")
 			return
 		}
@@ -205,7 +231,7 @@ func (h *AdminUserHandler) HandleCreate(w http.ResponseWriter, r *http.Request)
 			h.writeError(w, http.StatusConflict, err.Error())
 			return
 		}
-		h.logger.Error("failed to create user", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to create user", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to create user")
 		return
 	}
@@ -227,7 +253,7 @@ func (h *AdminUserHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 
 	var req adminUserUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		h.writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -244,10 +270,13 @@ func (h *AdminUserHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 			h.writeError(w, http.StatusNotFound, "User not found")
 			return
 		}
-		h.logger.Error("failed to find user", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to find user")
 		return
 	}
+	if !h.authorizeTarget(w, r, u) {
+		return
+	}
 
 	if req.NewUsername != nil {
 		newName := strings.TrimSpace(*req.NewUsername)
@@ -327,7 +356,7 @@ func (h *AdminUserHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 			h.writeError(w, http.StatusConflict, err.Error())
 			return
 		}
-		h.logger.Error("failed to update user", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to update user", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to update user")
 		return
 	}
@@ -344,7 +373,7 @@ func (h *AdminUserHandler) HandleDelete(w http.ResponseWriter, r *http.Request)
 
 	var req adminUserDeleteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		h.writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -355,12 +384,26 @@ func (h *AdminUserHandler) HandleDelete(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.userRepository.Delete(r.Context(), username); err != nil {
+	u, err := h.userRepository.FindByUsername(r.Context(), username)
+	if err != nil {
+		if err == user.ErrUserNotFound {
+			h.writeError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to find user")
+		return
+	}
+	if !h.authorizeTarget(w, r, u) {
+		return
+	}
+
+	if err := h.userRepository.Delete(r.Context(), u.Username); err != nil {
 		if err == user.ErrUserNotFound {
 			h.writeError(w, http.StatusNotFound, "User not found")
 			return
 		}
-		h.logger.Error("failed to delete user", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to delete user", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to delete user")
 		return
 	}
@@ -377,7 +420,7 @@ func (h *AdminUserHandler) HandleResetPassword(w http.ResponseWriter, r *http.Re
 
 	var req adminUserResetPasswordRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		h.writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -394,14 +437,17 @@ func (h *AdminUserHandler) HandleResetPassword(w http.ResponseWriter, r *http.Re
 			h.writeError(w, http.StatusNotFound, "User not found")
 			return
 		}
-		h.logger.Error("failed to find user", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to find user")
 		return
 	}
+	if !h.authorizeTarget(w, r, u) {
+		return
+	}
 
 	hashed, err := h.passwordHasher.Hash(req.Password)
 	if err != nil {
-		h.logger.Error("failed to hash password", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to hash password", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to hash password")
 		return
 	}
@@ -410,7 +456,7 @@ func (h *AdminUserHandler) HandleResetPassword(w http.ResponseWriter, r *http.Re
 	u.UpdatedAt = time.Now()
 
 	if err := h.userRepository.Save(r.Context(), u); err != nil {
-		h.logger.Error("failed to reset password", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to reset password", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to reset password")
 		return
 	}
@@ -418,6 +464,26 @@ func (h *AdminUserHandler) HandleResetPassword(w http.ResponseWriter, r *http.Re
 	h.writeJSON(w, http.StatusOK, map[string]any{"success": true})
 }
 
+// authorizeTarget rejects the request unless the caller holds every admin permission of the target,
+// so a user manager cannot take over a superadmin by resetting their password or changing their email.
+func (h *AdminUserHandler) authorizeTarget(w http.ResponseWriter, r *http.Request, target *user.User) bool {
+	actor, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+	if err := h.rbacService.AuthorizeTarget(r.Context(), actor, target); err != nil {
+		if errors.Is(err, rbac.ErrPrivilegeEscalation) {
+			h.writeError(w, http.StatusForbidden, "Cannot modify a user with more permissions than your own")
+			return false
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to load admin permissions", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to load admin permissions")
+		return false
+	}
+	return true
+}
+
 func buildAdminRules(items []adminRuleRequest) ([]*user.Rule, error) {
 	rules := make([]*user.Rule, 0, len(items))
 	for _, item := range items {
@@ -444,6 +510,7 @@ func buildAdminUserResponse(u *user.User) adminUserResponse {
 		Username:      u.Username,
 		WalletAddress: u.WalletAddress,
 		Email:         u.Email,
+		EmailVerified: u.IsEmailVerified(),
 		Directory:     u.Directory,
 		Permissions:   permissionsToStrings(u.Permissions),
 		Quota:         u.Quota,
@@ -515,3 +582,67 @@ func (h *AdminUserHandler) writeError(w http.ResponseWriter, code int, message s
 		"success": false,
 	})
 }
+
+// HandleLocked lists accounts currently locked after repeated failed logins.
+func (h *AdminUserHandler) HandleLocked(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	items := make([]adminLockedUserResponse, 0)
+	for _, status := range h.lockout.Locked() {
+		item := adminLockedUserResponse{
+			ID:          status.Account,
+			Lockouts:    status.Lockouts,
+			LockedUntil: status.LockedUntil.Format(time.RFC3339),
+		}
+		if u, err := h.userRepository.FindByID(r.Context(), status.Account); err == nil {
+			item.Username = u.Username
+		}
+		items = append(items, item)
+	}
+	h.writeJSON(w, http.StatusOK, map[string]any{"items": items})
+}
+
+// HandleUnlock clears the failed login counter and lock of a user.
+func (h *AdminUserHandler) HandleUnlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req adminUserUnlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		h.writeError(w, http.StatusBadRequest, "Username is required")
+		return
+	}
+
+	u, err := h.userRepository.FindByUsername(r.Context(), username)
+	if err != nil {
+		if err == user.ErrUserNotFound {
+			h.writeError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to find user")
+		return
+	}
+
+	if !h.authorizeTarget(w, r, u) {
+		return
+	}
+
+	unlocked := h.lockout.Unlock(u.ID)
+	logger.Ctx(r.Context(), h.logger).Info("account unlocked by admin",
+		zap.String("username", u.Username),
+		zap.Bool("was_locked", unlocked))
+	h.writeJSON(w, http.StatusOK, map[string]any{"unlocked": unlocked})
+}
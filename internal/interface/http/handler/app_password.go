@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/apppassword"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// AppPasswordHandler 应用专用密码处理器
+type AppPasswordHandler struct {
+	appPasswordService *service.AppPasswordService
+	logger             *zap.Logger
+}
+
+// NewAppPasswordHandler 创建应用专用密码处理器
+func NewAppPasswordHandler(appPasswordService *service.AppPasswordService, logger *zap.Logger) *AppPasswordHandler {
+	return &AppPasswordHandler{
+		appPasswordService: appPasswordService,
+		logger:             logger,
+	}
+}
+
+// AppPasswordResponse 应用专用密码响应
+type AppPasswordResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	ReadOnly   bool     `json:"read_only"`
+	Paths      []string `json:"paths"`
+	CreatedAt  string   `json:"created_at"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+	LastUsedIP string   `json:"last_used_ip,omitempty"`
+	Password   string   `json:"password,omitempty"`
+}
+
+func toAppPasswordResponse(p *apppassword.AppPassword) AppPasswordResponse {
+	resp := AppPasswordResponse{
+		ID:         p.ID,
+		Name:       p.Name,
+		ReadOnly:   p.ReadOnly,
+		Paths:      p.Paths,
+		CreatedAt:  p.CreatedAt.Format(timeLayout),
+		LastUsedIP: p.LastUsedIP,
+	}
+	if resp.Paths == nil {
+		resp.Paths = []string{}
+	}
+	if p.LastUsedAt != nil {
+		resp.LastUsedAt = p.LastUsedAt.Format(timeLayout)
+	}
+	return resp
+}
+
+// HandleList 获取我的应用专用密码
+func (h *AppPasswordHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	items, err := h.appPasswordService.List(r.Context(), u)
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to list app passwords", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to list app passwords")
+		return
+	}
+	resp := make([]AppPasswordResponse, 0, len(items))
+	for _, p := range items {
+		resp = append(resp, toAppPasswordResponse(p))
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"items": resp})
+}
+
+// HandleCreate 创建应用专用密码
+// body: {"name": "MacBook Finder", "read_only": false, "paths": ["/personal"]}，明文密码只在响应中返回一次。
+func (h *AppPasswordHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req struct {
+		Name     string   `json:"name"`
+		ReadOnly bool     `json:"read_only"`
+		Paths    []string `json:"paths"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	p, secret, err := h.appPasswordService.Create(r.Context(), u, req.Name, req.ReadOnly, req.Paths)
+	if err != nil {
+		switch {
+		case errors.Is(err, apppassword.ErrInvalidName),
+			errors.Is(err, apppassword.ErrInvalidPath),
+			errors.Is(err, apppassword.ErrTooManyPaths):
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, apppassword.ErrTooManyAppPasswords):
+			h.writeError(w, http.StatusConflict, err.Error())
+		default:
+			logger.Ctx(r.Context(), h.logger).Error("failed to create app password", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "Failed to create app password")
+		}
+		return
+	}
+	resp := toAppPasswordResponse(p)
+	resp.Password = secret
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleRevoke 吊销应用专用密码
+// body: {"id": "..."}
+func (h *AppPasswordHandler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	id := strings.TrimSpace(req.ID)
+	if id == "" {
+		h.writeError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	if err := h.appPasswordService.Revoke(r.Context(), u, id); err != nil {
+		if errors.Is(err, apppassword.ErrAppPasswordNotFound) {
+			h.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to revoke app password", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to revoke app password")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// writeJSON 写入 JSON 响应
+func (h *AppPasswordHandler) writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// writeError 写入错误响应
+func (h *AppPasswordHandler) writeError(w http.ResponseWriter, code int, message string) {
+	h.writeJSON(w, code, map[string]interface{}{
+		"error":   message,
+		"code":    code,
+		"success": false,
+	})
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/yeying-community/warehouse/internal/application/assetspace"
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
@@ -13,13 +15,15 @@ import (
 // AssetsHandler 提供资产空间元信息接口
 type AssetsHandler struct {
 	assetSpaceManager *assetspace.Manager
+	tokenGate         *service.TokenGateService
 	logger            *zap.Logger
 }
 
 // NewAssetsHandler 创建资产空间处理器
-func NewAssetsHandler(assetSpaceManager *assetspace.Manager, logger *zap.Logger) *AssetsHandler {
+func NewAssetsHandler(assetSpaceManager *assetspace.Manager, tokenGate *service.TokenGateService, logger *zap.Logger) *AssetsHandler {
 	return &AssetsHandler{
 		assetSpaceManager: assetSpaceManager,
+		tokenGate:         tokenGate,
 		logger:            logger,
 	}
 }
@@ -34,14 +38,14 @@ func (h *AssetsHandler) GetSpaces(w http.ResponseWriter, r *http.Request) {
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		h.sendError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Unauthorized")
 		return
 	}
 
 	if h.assetSpaceManager != nil {
 		if err := h.assetSpaceManager.EnsureForUser(u); err != nil {
-			h.logger.Error("failed to ensure user asset spaces",
+			logger.Ctx(r.Context(), h.logger).Error("failed to ensure user asset spaces",
 				zap.String("username", u.Username),
 				zap.String("directory", u.Directory),
 				zap.Error(err))
@@ -59,6 +63,9 @@ func (h *AssetsHandler) GetSpaces(w http.ResponseWriter, r *http.Request) {
 		defaultSpace = h.assetSpaceManager.DefaultSpace()
 		spaces = h.assetSpaceManager.Spaces()
 	}
+	for i := range spaces {
+		spaces[i].Condition = h.tokenGate.SpaceCondition(spaces[i].Key)
+	}
 
 	h.sendSDKSuccess(w, map[string]interface{}{
 		"defaultSpace": defaultSpace,
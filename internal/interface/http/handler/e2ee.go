@@ -0,0 +1,318 @@
+package handler
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/e2ee"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// E2EEHandler 端到端加密目录处理器
+type E2EEHandler struct {
+	e2eeService *service.E2EEService
+	logger      *zap.Logger
+}
+
+// NewE2EEHandler 创建端到端加密目录处理器
+func NewE2EEHandler(e2eeService *service.E2EEService, logger *zap.Logger) *E2EEHandler {
+	return &E2EEHandler{
+		e2eeService: e2eeService,
+		logger:      logger,
+	}
+}
+
+type e2eeFolderResp struct {
+	ID          string `json:"id"`
+	Path        string `json:"path"`
+	KeyEnvelope string `json:"keyEnvelope,omitempty"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+type e2eeEnvelopeResp struct {
+	FolderID  string `json:"folderId"`
+	Wallet    string `json:"wallet"`
+	Envelope  string `json:"envelope"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// HandlePublicKey 获取钱包公钥（用于为该钱包生成密钥信封）
+func (h *E2EEHandler) HandlePublicKey(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := middleware.GetUserFromContext(r.Context()); !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	wallet := strings.TrimSpace(r.URL.Query().Get("wallet"))
+	if wallet == "" {
+		http.Error(w, "wallet is required", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.e2eeService.PublicKey(r.Context(), wallet)
+	if err != nil {
+		h.writeError(w, "failed to get wallet public key", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"wallet":    key.WalletAddress,
+		"publicKey": "0x" + hex.EncodeToString(key.PublicKey),
+		"updatedAt": key.UpdatedAt.Format(timeLayout),
+	})
+}
+
+// HandleFolderList 获取我的端到端加密目录
+func (h *E2EEHandler) HandleFolderList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	folders, err := h.e2eeService.ListFolders(r.Context(), u)
+	if err != nil {
+		h.writeError(w, "failed to list e2ee folders", err)
+		return
+	}
+
+	resp := struct {
+		Items []e2eeFolderResp `json:"items"`
+	}{Items: make([]e2eeFolderResp, 0, len(folders))}
+	for _, f := range folders {
+		row := e2eeFolderResp{
+			ID:        f.ID,
+			Path:      f.Path,
+			CreatedAt: f.CreatedAt.Format(timeLayout),
+		}
+		if env, err := h.e2eeService.Envelope(r.Context(), u, f.ID); err == nil {
+			row.KeyEnvelope = env.Envelope
+		}
+		resp.Items = append(resp.Items, row)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// HandleFolderCreate 将空目录登记为端到端加密目录
+func (h *E2EEHandler) HandleFolderCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Path        string `json:"path"`
+		KeyEnvelope string `json:"keyEnvelope"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Path) == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	folder, err := h.e2eeService.CreateFolder(r.Context(), u, req.Path, req.KeyEnvelope)
+	if err != nil {
+		h.writeError(w, "failed to create e2ee folder", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(e2eeFolderResp{
+		ID:          folder.ID,
+		Path:        folder.Path,
+		KeyEnvelope: strings.TrimSpace(req.KeyEnvelope),
+		CreatedAt:   folder.CreatedAt.Format(timeLayout),
+	})
+}
+
+// HandleFolderDelete 取消目录的端到端加密登记
+func (h *E2EEHandler) HandleFolderDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.ID) == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.e2eeService.DeleteFolder(r.Context(), u, req.ID); err != nil {
+		h.writeError(w, "failed to delete e2ee folder", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"message":"deleted successfully"}`))
+}
+
+// HandleEnvelopeList 获取目录的密钥信封
+// 拥有者获取全部接收方的信封，其他用户只能获取自己的信封。
+func (h *E2EEHandler) HandleEnvelopeList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	folderID := strings.TrimSpace(r.URL.Query().Get("folderId"))
+	if folderID == "" {
+		http.Error(w, "folderId is required", http.StatusBadRequest)
+		return
+	}
+
+	envelopes, err := h.e2eeService.ListEnvelopes(r.Context(), u, folderID)
+	if errors.Is(err, e2ee.ErrFolderNotFound) {
+		env, envErr := h.e2eeService.Envelope(r.Context(), u, folderID)
+		if envErr != nil {
+			h.writeError(w, "failed to get key envelope", envErr)
+			return
+		}
+		envelopes, err = []*e2ee.KeyEnvelope{env}, nil
+	}
+	if err != nil {
+		h.writeError(w, "failed to list key envelopes", err)
+		return
+	}
+
+	resp := struct {
+		Items []e2eeEnvelopeResp `json:"items"`
+	}{Items: make([]e2eeEnvelopeResp, 0, len(envelopes))}
+	for _, env := range envelopes {
+		resp.Items = append(resp.Items, e2eeEnvelopeResp{
+			FolderID:  env.FolderID,
+			Wallet:    env.RecipientWallet,
+			Envelope:  env.Envelope,
+			CreatedAt: env.CreatedAt.Format(timeLayout),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// HandleEnvelopePut 为接收方钱包写入密钥信封
+func (h *E2EEHandler) HandleEnvelopePut(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		FolderID string `json:"folderId"`
+		Wallet   string `json:"wallet"`
+		Envelope string `json:"envelope"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.FolderID) == "" || strings.TrimSpace(req.Wallet) == "" {
+		http.Error(w, "folderId and wallet are required", http.StatusBadRequest)
+		return
+	}
+
+	env, err := h.e2eeService.PutEnvelope(r.Context(), u, req.FolderID, req.Wallet, req.Envelope)
+	if err != nil {
+		h.writeError(w, "failed to put key envelope", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(e2eeEnvelopeResp{
+		FolderID:  env.FolderID,
+		Wallet:    env.RecipientWallet,
+		Envelope:  env.Envelope,
+		CreatedAt: env.CreatedAt.Format(timeLayout),
+	})
+}
+
+// HandleEnvelopeDelete 删除接收方钱包的密钥信封
+func (h *E2EEHandler) HandleEnvelopeDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		FolderID string `json:"folderId"`
+		Wallet   string `json:"wallet"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.FolderID) == "" || strings.TrimSpace(req.Wallet) == "" {
+		http.Error(w, "folderId and wallet are required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.e2eeService.RemoveEnvelope(r.Context(), u, req.FolderID, req.Wallet); err != nil {
+		h.writeError(w, "failed to delete key envelope", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"message":"deleted successfully"}`))
+}
+
+// writeError 将端到端加密相关错误映射为 HTTP 状态码
+func (h *E2EEHandler) writeError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, auth.ErrAppScopeDenied), errors.Is(err, auth.ErrAppScopeRequired):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	case errors.Is(err, e2ee.ErrFolderNotFound),
+		errors.Is(err, e2ee.ErrEnvelopeNotFound),
+		errors.Is(err, e2ee.ErrPublicKeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, e2ee.ErrFolderExists),
+		errors.Is(err, e2ee.ErrFolderNotEmpty),
+		errors.Is(err, e2ee.ErrNestedEncryptedPath):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, e2ee.ErrInvalidEnvelope):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		h.logger.Error(msg, zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
@@ -11,10 +11,13 @@ import (
 	"time"
 
 	"github.com/yeying-community/warehouse/internal/application/assetspace"
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/session"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	infraAuth "github.com/yeying-community/warehouse/internal/infrastructure/auth"
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
-	"github.com/yeying-community/warehouse/internal/infrastructure/email"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
 
@@ -24,8 +27,9 @@ type EmailAuthHandler struct {
 	userRepo          user.Repository
 	assetSpaceManager *assetspace.Manager
 	store             *infraAuth.EmailCodeStore
-	sender            *email.Sender
+	mailer            service.MailSender
 	config            config.EmailConfig
+	mfaHandler        *MFAHandler
 	logger            *zap.Logger
 }
 
@@ -35,7 +39,7 @@ func NewEmailAuthHandler(
 	userRepo user.Repository,
 	assetSpaceManager *assetspace.Manager,
 	store *infraAuth.EmailCodeStore,
-	sender *email.Sender,
+	mailer service.MailSender,
 	cfg config.EmailConfig,
 	logger *zap.Logger,
 ) *EmailAuthHandler {
@@ -44,13 +48,19 @@ func NewEmailAuthHandler(
 		userRepo:          userRepo,
 		assetSpaceManager: assetSpaceManager,
 		store:             store,
-		sender:            sender,
+		mailer:            mailer,
 		config:            cfg,
 		logger:            logger,
 	}
 }
 
+// SetMFAHandler 设置两步验证处理器；未设置时邮箱登录直接签发令牌
+func (h *EmailAuthHandler) SetMFAHandler(mfaHandler *MFAHandler) {
+	h.mfaHandler = mfaHandler
+}
+
 // HandleSendCode 发送邮箱验证码
+// 验证码邮件写入发件箱后即返回，由后台任务发送并在失败时重试；邮件语言取自 Accept-Language。
 func (h *EmailAuthHandler) HandleSendCode(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
@@ -61,7 +71,7 @@ func (h *EmailAuthHandler) HandleSendCode(w http.ResponseWriter, r *http.Request
 		Email string `json:"email"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
 		return
 	}
@@ -85,13 +95,17 @@ func (h *EmailAuthHandler) HandleSendCode(w http.ResponseWriter, r *http.Request
 			})
 			return
 		}
-		h.logger.Error("failed to create email code", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to create email code", zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to send code")
 		return
 	}
 
-	if err := h.sender.SendCode(emailAddr, code, h.config.CodeTTL); err != nil {
-		h.logger.Error("failed to send email code", zap.Error(err))
+	err = h.mailer.SendTemplate(emailAddr, service.MailTemplateEmailCodeLogin, r.Header.Get("Accept-Language"), map[string]any{
+		"code":      code,
+		"expiresIn": int(h.config.CodeTTL.Minutes()),
+	})
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to queue email code", zap.Error(err))
 		h.store.Delete(emailAddr)
 		h.sendError(w, http.StatusInternalServerError, "SEND_FAILED", "Failed to send code")
 		return
@@ -117,7 +131,7 @@ func (h *EmailAuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		Code  string `json:"code"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
 		return
 	}
@@ -142,7 +156,7 @@ func (h *EmailAuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	u, err := h.userRepo.FindByEmail(ctx, emailAddr)
 	if err != nil {
 		if err != user.ErrUserNotFound {
-			h.logger.Error("failed to find user", zap.Error(err))
+			logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.Error(err))
 			h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
 			return
 		}
@@ -152,39 +166,53 @@ func (h *EmailAuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		}
 		u, err = h.createUserFromEmail(ctx, emailAddr)
 		if err != nil {
-			h.logger.Error("failed to create user from email", zap.Error(err))
+			logger.Ctx(r.Context(), h.logger).Error("failed to create user from email", zap.Error(err))
 			h.sendError(w, http.StatusInternalServerError, "USER_CREATE_FAILED", "Failed to create user")
 			return
 		}
 	}
 
+	// 验证码证明了邮箱归属，主邮箱尚未验证时顺带标记
+	if u.Email == emailAddr && !u.IsEmailVerified() {
+		u.MarkEmailVerified()
+		if err := h.userRepo.Save(ctx, u); err != nil {
+			logger.Ctx(ctx, h.logger).Warn("failed to mark email verified", zap.String("username", u.Username), zap.Error(err))
+		}
+	}
+
 	if err := h.ensureAssetSpaces(u); err != nil {
 		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to initialize user spaces")
 		return
 	}
 
-	accessToken, err := h.web3Auth.GenerateAccessTokenForEmail(emailAddr)
+	// 启用或被要求启用两步验证时只返回挑战令牌，由 /auth/mfa/verify 完成登录
+	challenge, err := h.mfaHandler.Challenge(ctx, u, "email")
 	if err != nil {
-		h.logger.Error("failed to generate access token", zap.Error(err))
-		h.sendError(w, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED", "Failed to generate token")
+		logger.Ctx(r.Context(), h.logger).Error("failed to check mfa", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return
+	}
+	if challenge != nil {
+		h.sendSDKSuccess(w, challenge)
 		return
 	}
 
-	refreshToken, err := h.web3Auth.GenerateRefreshTokenForEmail(emailAddr)
+	middleware.SetAuditActor(ctx, u)
+	tokens, err := h.web3Auth.IssueTokens(ctx, u, emailAddr, "email", session.MethodEmail, sessionClientFrom(r))
 	if err != nil {
-		h.logger.Error("failed to generate refresh token", zap.Error(err))
-		h.sendError(w, http.StatusInternalServerError, "REFRESH_TOKEN_FAILED", "Failed to generate refresh token")
+		logger.Ctx(r.Context(), h.logger).Error("failed to issue tokens", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED", "Failed to generate token")
 		return
 	}
 
-	h.setRefreshCookie(w, r, refreshToken.Value, refreshToken.ExpiresAt)
+	h.setRefreshCookie(w, r, tokens.Refresh.Value, tokens.Refresh.ExpiresAt)
 
 	data := map[string]any{
 		"email":            emailAddr,
 		"username":         u.Username,
-		"token":            accessToken.Value,
-		"expiresAt":        accessToken.ExpiresAt.UnixMilli(),
-		"refreshExpiresAt": refreshToken.ExpiresAt.UnixMilli(),
+		"token":            tokens.Access.Value,
+		"expiresAt":        tokens.Access.ExpiresAt.UnixMilli(),
+		"refreshExpiresAt": tokens.Refresh.ExpiresAt.UnixMilli(),
 	}
 
 	h.sendSDKSuccess(w, data)
@@ -208,6 +236,7 @@ func (h *EmailAuthHandler) createUserFromEmail(ctx context.Context, emailAddr st
 		if err := u.SetEmail(emailAddr); err != nil {
 			return nil, err
 		}
+		u.MarkEmailVerified()
 		u.Permissions = user.ParsePermissions("CRUD")
 		_ = u.SetQuota(1073741824)
 
@@ -221,7 +250,7 @@ func (h *EmailAuthHandler) createUserFromEmail(ctx context.Context, emailAddr st
 			return nil, err
 		}
 
-		h.logger.Info("user created via email",
+		logger.Ctx(ctx, h.logger).Info("user created via email",
 			zap.String("username", u.Username),
 			zap.String("email", emailAddr))
 
@@ -4,44 +4,102 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
-	
+
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/infrastructure/buildinfo"
 	"go.uber.org/zap"
 )
 
 // HealthHandler 健康检查处理器
 type HealthHandler struct {
-	logger    *zap.Logger
-	startTime time.Time
+	healthService *service.HealthService
+	logger        *zap.Logger
+	startTime     time.Time
 }
 
 // NewHealthHandler 创建健康检查处理器
-func NewHealthHandler(logger *zap.Logger) *HealthHandler {
+func NewHealthHandler(healthService *service.HealthService, logger *zap.Logger) *HealthHandler {
 	return &HealthHandler{
-		logger:    logger,
-		startTime: time.Now(),
+		healthService: healthService,
+		logger:        logger,
+		startTime:     time.Now(),
 	}
 }
 
-// HealthResponse 健康检查响应
+// HealthResponse 存活检查响应
 type HealthResponse struct {
 	Status  string        `json:"status"`
 	Uptime  time.Duration `json:"uptime"`
 	Version string        `json:"version"`
+	Commit  string        `json:"commit"`
+}
+
+// ReadinessResponse 就绪检查响应
+type ReadinessResponse struct {
+	Status  string                `json:"status"`
+	Version string                `json:"version"`
+	Commit  string                `json:"commit"`
+	Checks  []HealthCheckResponse `json:"checks"`
+}
+
+// HealthCheckResponse 单项检查结果
+// 就绪检查无需认证，错误信息与详情可能包含数据库地址、数据目录等部署细节，只写入日志。
+type HealthCheckResponse struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
 }
 
-// Handle 处理健康检查请求
+// Handle 处理存活检查请求（heartbeat 与 live 共用），进程能响应即返回 200
 func (h *HealthHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	response := HealthResponse{
 		Status:  "healthy",
 		Uptime:  time.Since(h.startTime),
-		Version: "2.0.0",
+		Version: buildinfo.Version,
+		Commit:  buildinfo.Commit,
+	}
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// HandleReady 处理就绪检查请求，任一检查失败返回 503
+func (h *HealthHandler) HandleReady(w http.ResponseWriter, r *http.Request) {
+	ready, results := h.healthService.Check(r.Context())
+
+	response := ReadinessResponse{
+		Status:  "ready",
+		Version: buildinfo.Version,
+		Commit:  buildinfo.Commit,
+		Checks:  make([]HealthCheckResponse, 0, len(results)),
+	}
+	for _, result := range results {
+		response.Checks = append(response.Checks, HealthCheckResponse{
+			Name:      result.Name,
+			Status:    result.Status,
+			LatencyMs: float64(result.Latency.Microseconds()) / 1000,
+		})
+		if result.Status != service.HealthStatusOK {
+			h.logger.Warn("readiness check not ok",
+				zap.String("check", result.Name),
+				zap.String("status", result.Status),
+				zap.String("error", result.Error),
+				zap.Any("detail", result.Detail))
+		}
+	}
+
+	status := http.StatusOK
+	if !ready {
+		response.Status = "not_ready"
+		status = http.StatusServiceUnavailable
 	}
-	
+	h.writeJSON(w, status, response)
+}
+
+func (h *HealthHandler) writeJSON(w http.ResponseWriter, status int, response any) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		h.logger.Error("failed to encode health response", zap.Error(err))
 	}
 }
-
@@ -0,0 +1,481 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/yeying-community/warehouse/internal/application/service"
+	authDomain "github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/identity"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	infraAuth "github.com/yeying-community/warehouse/internal/infrastructure/auth"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/ratelimit"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// IdentityHandler 登录身份处理器
+// 关联、解绑与合并都需要附带身份控制权证明：钱包签名、邮箱验证码或用户名密码。
+// 用户名密码证明与密码登录一致：计入账户锁定，需要两步验证的账户还须提供验证码或恢复码。
+type IdentityHandler struct {
+	identityService *service.IdentityService
+	web3Auth        *infraAuth.Web3Authenticator
+	userRepo        user.Repository
+	store           *infraAuth.EmailCodeStore
+	mailer          service.MailSender
+	emailConfig     config.EmailConfig
+	mfaService      *service.MFAService
+	lockout         *ratelimit.Lockout
+	logger          *zap.Logger
+}
+
+// NewIdentityHandler 创建登录身份处理器
+func NewIdentityHandler(
+	identityService *service.IdentityService,
+	web3Auth *infraAuth.Web3Authenticator,
+	userRepo user.Repository,
+	store *infraAuth.EmailCodeStore,
+	mailer service.MailSender,
+	emailConfig config.EmailConfig,
+	logger *zap.Logger,
+) *IdentityHandler {
+	return &IdentityHandler{
+		identityService: identityService,
+		web3Auth:        web3Auth,
+		userRepo:        userRepo,
+		store:           store,
+		mailer:          mailer,
+		emailConfig:     emailConfig,
+		logger:          logger,
+	}
+}
+
+// SetMFAService 设置两步验证服务，需要两步验证的账户不能只凭密码证明控制权
+func (h *IdentityHandler) SetMFAService(mfaService *service.MFAService) {
+	h.mfaService = mfaService
+}
+
+// SetLockout 设置账户锁定器，密码证明失败与密码登录共用失败计数
+func (h *IdentityHandler) SetLockout(lockout *ratelimit.Lockout) {
+	h.lockout = lockout
+}
+
+// identityProof 身份控制权证明
+// 邮箱证明的 code 为邮箱验证码；密码证明的 code / recoveryCode 为两步验证码或恢复码。
+type identityProof struct {
+	Type      string `json:"type"`
+	Address   string `json:"address,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Email     string `json:"email,omitempty"`
+	Code      string `json:"code,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Password  string `json:"password,omitempty"`
+
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+}
+
+type identityResp struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Subject   string `json:"subject"`
+	CreatedAt string `json:"createdAt"`
+}
+
+func toIdentityResp(ident *identity.Identity) identityResp {
+	return identityResp{
+		ID:        ident.ID,
+		Type:      string(ident.Type),
+		Subject:   ident.Subject,
+		CreatedAt: ident.CreatedAt.Format(timeLayout),
+	}
+}
+
+// HandleList 获取我的登录身份
+func (h *IdentityHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	items, err := h.identityService.List(r.Context(), u)
+	if err != nil {
+		h.writeError(w, "failed to list identities", err)
+		return
+	}
+	resp := struct {
+		Items []identityResp `json:"items"`
+	}{Items: make([]identityResp, 0, len(items))}
+	for _, ident := range items {
+		resp.Items = append(resp.Items, toIdentityResp(ident))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// HandleChallenge 为待证明的钱包签发挑战
+// 与登录挑战不同，钱包不需要已注册，也不会自动创建用户。
+func (h *IdentityHandler) HandleChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := middleware.GetUserFromContext(r.Context()); !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Address string `json:"address"`
+		ChainID uint64 `json:"chainId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	siweReq := siweRequestFrom(r)
+	siweReq.ChainID = req.ChainID
+	challenge, err := h.web3Auth.CreateChallenge(strings.TrimSpace(req.Address), siweReq)
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("failed to create identity challenge", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"address":   challenge.Address,
+		"challenge": challenge.Message,
+		"nonce":     challenge.Nonce,
+		"expiresAt": challenge.ExpiresAt.UnixMilli(),
+	})
+}
+
+// HandleSendEmailCode 向待证明的邮箱发送验证码
+func (h *IdentityHandler) HandleSendEmailCode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := middleware.GetUserFromContext(r.Context()); !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !h.emailConfig.Enabled || h.store == nil || h.mailer == nil {
+		http.Error(w, "Email verification is disabled", http.StatusForbidden)
+		return
+	}
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	emailAddr := strings.ToLower(strings.TrimSpace(req.Email))
+	if !user.IsValidEmail(emailAddr) {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+
+	code, expiresAt, _, err := h.store.Create(emailAddr, h.emailConfig.CodeLength, h.emailConfig.CodeTTL, h.emailConfig.SendInterval)
+	if err != nil {
+		if errors.Is(err, infraAuth.ErrEmailCodeTooFrequent) {
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to create email code", zap.Error(err))
+		http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		return
+	}
+	err = h.mailer.SendTemplate(emailAddr, service.MailTemplateEmailCodeLogin, r.Header.Get("Accept-Language"), map[string]any{
+		"code":      code,
+		"expiresIn": int(h.emailConfig.CodeTTL.Minutes()),
+	})
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to queue email code", zap.Error(err))
+		h.store.Delete(emailAddr)
+		http.Error(w, "Failed to send code", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"email":     emailAddr,
+		"expiresAt": expiresAt.UnixMilli(),
+	})
+}
+
+// HandleLink 关联钱包或邮箱
+// body: {"proof": {...}}，proof 证明对新身份的控制权。
+func (h *IdentityHandler) HandleLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Proof identityProof `json:"proof"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	t, subject, ok := h.verifyProof(w, r, req.Proof)
+	if !ok {
+		return
+	}
+	ident, err := h.identityService.Link(r.Context(), u, t, subject)
+	if err != nil {
+		h.writeError(w, "failed to link identity", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(toIdentityResp(ident))
+}
+
+// HandleUnlink 解除登录身份
+// body: {"id": "...", "proof": {...}}，proof 为当前账户任一身份的控制权证明。
+func (h *IdentityHandler) HandleUnlink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		ID    string        `json:"id"`
+		Proof identityProof `json:"proof"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.ID) == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	owner, ok := h.resolveProofOwner(w, r, req.Proof)
+	if !ok {
+		return
+	}
+	if owner.ID != u.ID {
+		http.Error(w, "Proof does not belong to this account", http.StatusForbidden)
+		return
+	}
+	if _, err := h.identityService.Unlink(r.Context(), u, strings.TrimSpace(req.ID)); err != nil {
+		h.writeError(w, "failed to unlink identity", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"message":"unlinked successfully"}`))
+}
+
+// HandleMerge 将另一个账户合并到当前账户
+// body: {"proof": {...}}，proof 为被合并账户任一身份的控制权证明。
+func (h *IdentityHandler) HandleMerge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		Proof identityProof `json:"proof"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	source, ok := h.resolveProofOwner(w, r, req.Proof)
+	if !ok {
+		return
+	}
+	mergedPath, err := h.identityService.Merge(r.Context(), u, source)
+	if err != nil {
+		h.writeError(w, "failed to merge account", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"mergedUsername": source.Username,
+		"path":           mergedPath,
+	})
+}
+
+// resolveProofOwner 校验证明并返回身份所属用户
+func (h *IdentityHandler) resolveProofOwner(w http.ResponseWriter, r *http.Request, proof identityProof) (*user.User, bool) {
+	t, subject, ok := h.verifyProof(w, r, proof)
+	if !ok {
+		return nil, false
+	}
+	owner, err := h.identityService.ResolveOwner(r.Context(), t, subject)
+	if err != nil {
+		if errors.Is(err, identity.ErrIdentityNotFound) || errors.Is(err, user.ErrUserNotFound) {
+			http.Error(w, "Identity is not linked to any account", http.StatusNotFound)
+			return nil, false
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to resolve identity owner", zap.Error(err))
+		http.Error(w, "Failed to process request", http.StatusInternalServerError)
+		return nil, false
+	}
+	return owner, true
+}
+
+// verifyProof 校验身份控制权证明，失败时直接写入响应
+func (h *IdentityHandler) verifyProof(w http.ResponseWriter, r *http.Request, proof identityProof) (identity.Type, string, bool) {
+	t, err := identity.ParseType(proof.Type)
+	if err != nil {
+		http.Error(w, "Invalid proof type", http.StatusBadRequest)
+		return "", "", false
+	}
+
+	switch t {
+	case identity.TypeWallet:
+		address, err := h.web3Auth.VerifyWalletControl(r.Context(), strings.TrimSpace(proof.Address), proof.Signature, proof.Message, siweRequestFrom(r))
+		if err != nil {
+			switch {
+			case errors.Is(err, crypto.ErrSmartWalletUnavailable):
+				http.Error(w, "Smart wallet signature verification unavailable", http.StatusBadGateway)
+			case errors.Is(err, authDomain.ErrChallengeExpired):
+				http.Error(w, "Challenge expired or not found", http.StatusUnauthorized)
+			default:
+				http.Error(w, "Signature verification failed", http.StatusUnauthorized)
+			}
+			return "", "", false
+		}
+		return t, address, true
+
+	case identity.TypeEmail:
+		emailAddr := strings.ToLower(strings.TrimSpace(proof.Email))
+		if !h.emailConfig.Enabled || h.store == nil {
+			http.Error(w, "Email verification is disabled", http.StatusForbidden)
+			return "", "", false
+		}
+		if !user.IsValidEmail(emailAddr) || strings.TrimSpace(proof.Code) == "" || !h.store.Verify(emailAddr, proof.Code) {
+			http.Error(w, "Invalid or expired code", http.StatusUnauthorized)
+			return "", "", false
+		}
+		return t, emailAddr, true
+
+	default:
+		username, ok := h.verifyPasswordProof(w, r, proof)
+		if !ok {
+			return "", "", false
+		}
+		return t, username, true
+	}
+}
+
+// verifyPasswordProof 校验用户名密码证明，失败时直接写入响应
+func (h *IdentityHandler) verifyPasswordProof(w http.ResponseWriter, r *http.Request, proof identityProof) (string, bool) {
+	ctx := r.Context()
+	username := strings.TrimSpace(proof.Username)
+	u, err := h.userRepo.FindByUsername(ctx, username)
+	if err != nil || username == "" || !u.HasPassword() {
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return "", false
+	}
+
+	if err := h.lockout.Check(u.ID); err != nil {
+		h.writeLocked(w, err)
+		return "", false
+	}
+	if err := crypto.NewPasswordHasher().Verify(u.Password, proof.Password); err != nil {
+		if err := h.lockout.Failure(u.ID); err != nil {
+			logger.Ctx(ctx, h.logger).Warn("account locked after repeated failures", zap.String("username", u.Username))
+			h.writeLocked(w, err)
+			return "", false
+		}
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+		return "", false
+	}
+	h.lockout.Success(u.ID)
+
+	// 启用或被要求启用两步验证的账户，密码之外还需要验证码或恢复码；尚未登记时不能用密码证明
+	if h.mfaService != nil {
+		status, err := h.mfaService.Status(ctx, u)
+		if err != nil {
+			logger.Ctx(ctx, h.logger).Error("failed to check mfa", zap.Error(err))
+			http.Error(w, "Failed to process request", http.StatusInternalServerError)
+			return "", false
+		}
+		if status.EnrollmentRequired {
+			http.Error(w, "Two-factor authentication required", http.StatusForbidden)
+			return "", false
+		}
+		if status.LoginSecondFactor {
+			if strings.TrimSpace(proof.Code) == "" && strings.TrimSpace(proof.RecoveryCode) == "" {
+				http.Error(w, "Two-factor code required", http.StatusUnauthorized)
+				return "", false
+			}
+			if err := h.mfaService.Verify(ctx, u, proof.Code, proof.RecoveryCode); err != nil {
+				status := mfaErrorStatus(err)
+				if status == http.StatusInternalServerError {
+					logger.Ctx(ctx, h.logger).Error("failed to verify mfa", zap.Error(err))
+					http.Error(w, "Failed to process request", status)
+					return "", false
+				}
+				http.Error(w, err.Error(), status)
+				return "", false
+			}
+		}
+	}
+	return u.Username, true
+}
+
+// writeError 将登录身份相关错误映射为 HTTP 状态码
+func (h *IdentityHandler) writeError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, identity.ErrIdentityNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, identity.ErrIdentityInUse):
+		// 身份已属于另一个账户，可改用合并接口
+		http.Error(w, err.Error()+"; merge the accounts instead", http.StatusConflict)
+	case errors.Is(err, identity.ErrLastIdentity),
+		errors.Is(err, identity.ErrMergeBlocked),
+		errors.Is(err, identity.ErrSameAccount):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, user.ErrQuotaExceeded):
+		http.Error(w, err.Error(), http.StatusInsufficientStorage)
+	case errors.Is(err, identity.ErrInvalidIdentity):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		h.logger.Error(msg, zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// writeLocked 账户处于锁定期
+func (h *IdentityHandler) writeLocked(w http.ResponseWriter, err error) {
+	var locked *authDomain.LockedError
+	if errors.As(err, &locked) {
+		middleware.WriteRetryAfter(w, locked.RetryAfter)
+	}
+	http.Error(w, "Too many failed attempts, try again later", http.StatusTooManyRequests)
+}
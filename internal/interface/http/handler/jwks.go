@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	infraAuth "github.com/yeying-community/warehouse/internal/infrastructure/auth"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"go.uber.org/zap"
+)
+
+// JWKSHandler JWT 验证公钥（JWKS）处理器
+type JWKSHandler struct {
+	jwtManager *infraAuth.JWTManager
+	logger     *zap.Logger
+}
+
+// NewJWKSHandler 创建 JWKS 处理器
+func NewJWKSHandler(jwtManager *infraAuth.JWTManager, logger *zap.Logger) *JWKSHandler {
+	return &JWKSHandler{
+		jwtManager: jwtManager,
+		logger:     logger,
+	}
+}
+
+// Handle 返回 /.well-known/jwks.json
+// 包含签名密钥与轮换中仍在验证期内的旧密钥；仅使用 HS256 时 keys 为空。
+func (h *JWKSHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	resp := struct {
+		Keys []infraAuth.JWK `json:"keys"`
+	}{Keys: h.jwtManager.JWKS()}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("failed to write jwks", zap.Error(err))
+	}
+}
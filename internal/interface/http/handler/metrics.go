@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/metrics"
+	"go.uber.org/zap"
+)
+
+// MetricsHandler Prometheus 指标处理器
+type MetricsHandler struct {
+	registry *metrics.Registry
+	username string
+	password string
+	logger   *zap.Logger
+}
+
+// NewMetricsHandler 创建指标处理器；配置了用户名密码时要求 Basic 认证
+func NewMetricsHandler(registry *metrics.Registry, cfg config.MetricsConfig, logger *zap.Logger) *MetricsHandler {
+	return &MetricsHandler{
+		registry: registry,
+		username: cfg.Username,
+		password: cfg.Password,
+		logger:   logger,
+	}
+}
+
+// Handle 以 Prometheus 文本格式输出指标
+func (h *MetricsHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := h.registry.WriteTo(w); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("failed to write metrics", zap.Error(err))
+	}
+}
+
+func (h *MetricsHandler) authorized(r *http.Request) bool {
+	if h.username == "" {
+		return true
+	}
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(h.username)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(h.password)) == 1
+	return userOK && passOK
+}
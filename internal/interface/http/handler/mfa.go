@@ -0,0 +1,515 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/mfa"
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	infraAuth "github.com/yeying-community/warehouse/internal/infrastructure/auth"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// MFAHandler 两步验证处理器
+// 登录第二步（/api/v1/public/auth/mfa/*）使用 SDK 响应格式，与登录接口一致；
+// 账户设置与管理员接口使用普通 JSON 响应。
+type MFAHandler struct {
+	mfaService     *service.MFAService
+	sessionService *service.SessionService
+	rbacService    *service.RBACService
+	web3Auth       *infraAuth.Web3Authenticator
+	userRepo       user.Repository
+	logger         *zap.Logger
+}
+
+// NewMFAHandler 创建两步验证处理器
+func NewMFAHandler(
+	mfaService *service.MFAService,
+	web3Auth *infraAuth.Web3Authenticator,
+	userRepo user.Repository,
+	rbacService *service.RBACService,
+	logger *zap.Logger,
+) *MFAHandler {
+	return &MFAHandler{
+		mfaService:  mfaService,
+		rbacService: rbacService,
+		web3Auth:    web3Auth,
+		userRepo:    userRepo,
+		logger:      logger,
+	}
+}
+
+// SetSessionService 设置会话服务，用于判断当前会话的登录方式
+func (h *MFAHandler) SetSessionService(sessionService *service.SessionService) {
+	h.sessionService = sessionService
+}
+
+// Challenge 第一步登录成功后检查是否需要两步验证
+// 需要时返回挑战响应数据，调用方直接返回给客户端而不签发令牌；不需要时返回 nil。
+func (h *MFAHandler) Challenge(ctx context.Context, u *user.User, subjectType string) (map[string]any, error) {
+	if h == nil {
+		return nil, nil
+	}
+	status, err := h.mfaService.Status(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if !status.LoginSecondFactor {
+		return nil, nil
+	}
+	if status.EnrollmentRequired {
+		// 只凭密码或邮箱验证码不能登记第二步，需先用钱包或通行密钥登录后在账户设置中登记
+		return map[string]any{
+			"mfaRequired":    true,
+			"enrollRequired": true,
+			"username":       u.Username,
+		}, nil
+	}
+	token, err := h.web3Auth.GetJWTManager().GenerateMFAChallenge(u.ID, subjectType, h.mfaService.ChallengeTTL())
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"mfaRequired":    true,
+		"enrollRequired": false,
+		"mfaToken":       token.Value,
+		"expiresAt":      token.ExpiresAt.UnixMilli(),
+		"username":       u.Username,
+	}, nil
+}
+
+// HandleLoginVerify 登录第二步：校验验证码或恢复码并签发令牌
+// body: {"mfaToken": "...", "code": "123456"} 或 {"mfaToken": "...", "recoveryCode": "abcd-efgh"}
+func (h *MFAHandler) HandleLoginVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+		return
+	}
+	var req struct {
+		MFAToken     string `json:"mfaToken"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recoveryCode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Code) == "" && strings.TrimSpace(req.RecoveryCode) == "" {
+		h.sendError(w, http.StatusBadRequest, "MISSING_CODE", "code or recoveryCode is required")
+		return
+	}
+	u, claims, ok := h.challengeUser(w, r, req.MFAToken)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	status, err := h.mfaService.Status(ctx, u)
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to get mfa status", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return
+	}
+	if !status.Enabled {
+		h.sendMFAError(w, mfa.ErrNotEnrolled)
+		return
+	}
+	if err := h.mfaService.Verify(ctx, u, req.Code, req.RecoveryCode); err != nil {
+		h.sendMFAError(w, err)
+		return
+	}
+
+	subject, subjectType := strings.TrimSpace(u.WalletAddress), "wallet"
+	if claims.SubjectType == "email" || subject == "" {
+		subject, subjectType = strings.ToLower(strings.TrimSpace(u.Email)), "email"
+	}
+	if subject == "" {
+		h.sendError(w, http.StatusBadRequest, "NO_WALLET", "Wallet address not bound")
+		return
+	}
+	middleware.SetAuditActor(ctx, u)
+	tokens, err := h.web3Auth.IssueTokens(ctx, u, subject, subjectType, session.MethodMFA, sessionClientFrom(r))
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to issue tokens", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED", "Failed to generate token")
+		return
+	}
+
+	h.setRefreshCookie(w, r, tokens.Refresh.Value, tokens.Refresh.ExpiresAt)
+
+	data := map[string]any{
+		"username":         u.Username,
+		"token":            tokens.Access.Value,
+		"expiresAt":        tokens.Access.ExpiresAt.UnixMilli(),
+		"refreshExpiresAt": tokens.Refresh.ExpiresAt.UnixMilli(),
+	}
+	if subjectType == "email" {
+		data["email"] = subject
+	} else {
+		data["address"] = subject
+	}
+	h.sendSDKSuccess(w, data)
+}
+
+// MFAStatusResponse 两步验证状态响应
+type MFAStatusResponse struct {
+	Enabled           bool   `json:"enabled"`
+	Pending           bool   `json:"pending"`
+	Required          bool   `json:"required"`
+	RecoveryCodesLeft int    `json:"recovery_codes_left"`
+	EnabledAt         string `json:"enabled_at,omitempty"`
+}
+
+// HandleStatus 获取我的两步验证状态
+func (h *MFAHandler) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	status, err := h.mfaService.Status(r.Context(), u)
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to get mfa status", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to get two-factor status")
+		return
+	}
+	resp := MFAStatusResponse{
+		Enabled:           status.Enabled,
+		Pending:           status.Pending,
+		Required:          status.Required || status.RequiredAsAdmin,
+		RecoveryCodesLeft: status.RecoveryCodesLeft,
+	}
+	if status.EnabledAt != nil {
+		resp.EnabledAt = status.EnabledAt.Format(timeLayout)
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleEnroll 开始登记两步验证，返回密钥与 otpauth:// 二维码 URI
+func (h *MFAHandler) HandleEnroll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	ctx := r.Context()
+	status, err := h.mfaService.Status(ctx, u)
+	if err != nil {
+		logger.Ctx(ctx, h.logger).Error("failed to get mfa status", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to start enrollment")
+		return
+	}
+	// 被要求启用的账户，第二步只能在钱包签名或通行密钥建立的会话中登记，
+	// 否则知道密码（或能收取邮箱验证码）的人就能抢先登记自己的密钥
+	if status.EnrollmentRequired && !h.strongSession(ctx, u) {
+		h.writeError(w, http.StatusForbidden, "Two-factor enrollment requires signing in with a wallet or passkey")
+		return
+	}
+	enrollment, err := h.mfaService.Enroll(ctx, u)
+	if err != nil {
+		if errors.Is(err, mfa.ErrAlreadyEnabled) {
+			h.writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to start mfa enrollment", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to start enrollment")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]string{
+		"secret":           enrollment.Secret,
+		"provisioning_uri": enrollment.ProvisioningURI,
+	})
+}
+
+// HandleConfirm 确认登记并启用两步验证，恢复码只在响应中返回一次
+// body: {"code": "123456"}
+func (h *MFAHandler) HandleConfirm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	codes, err := h.mfaService.Confirm(r.Context(), u, req.Code)
+	if err != nil {
+		h.writeMFAError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"recovery_codes": codes})
+}
+
+// HandleDisable 关闭两步验证
+// body: {"code": "123456"} 或 {"recovery_code": "abcd-efgh"}
+func (h *MFAHandler) HandleDisable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := h.mfaService.Disable(r.Context(), u, req.Code, req.RecoveryCode); err != nil {
+		h.writeMFAError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// HandleRecoveryCodes 重新生成恢复码
+// body: {"code": "123456"}
+func (h *MFAHandler) HandleRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	codes, err := h.mfaService.RegenerateRecoveryCodes(r.Context(), u, req.Code)
+	if err != nil {
+		h.writeMFAError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"recovery_codes": codes})
+}
+
+// HandleAdminSetRequired 管理员要求（或取消要求）用户启用两步验证
+// body: {"username": "alice", "required": true}
+func (h *MFAHandler) HandleAdminSetRequired(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+		Required bool   `json:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		h.writeError(w, http.StatusBadRequest, "username is required")
+		return
+	}
+	// 不能修改权限高于自己的用户，否则可以关闭超级管理员的两步验证要求
+	if !h.authorizeTarget(w, r, username) {
+		return
+	}
+	u, err := h.mfaService.SetRequired(r.Context(), username, req.Required)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			h.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to update mfa requirement", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to update two-factor requirement")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"username": u.Username,
+		"required": req.Required,
+	})
+}
+
+// authorizeTarget 校验当前管理员的权限覆盖目标用户的全部管理权限
+func (h *MFAHandler) authorizeTarget(w http.ResponseWriter, r *http.Request, username string) bool {
+	actor, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+	target, err := h.userRepo.FindByUsername(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			h.writeError(w, http.StatusNotFound, err.Error())
+			return false
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to find user")
+		return false
+	}
+	if err := h.rbacService.AuthorizeTarget(r.Context(), actor, target); err != nil {
+		if errors.Is(err, rbac.ErrPrivilegeEscalation) {
+			h.writeError(w, http.StatusForbidden, "Cannot modify a user with more permissions than your own")
+			return false
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to load admin permissions", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to load admin permissions")
+		return false
+	}
+	return true
+}
+
+// challengeUser 验证挑战令牌并加载用户
+func (h *MFAHandler) challengeUser(w http.ResponseWriter, r *http.Request, token string) (*user.User, *infraAuth.Claims, bool) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		h.sendError(w, http.StatusBadRequest, "MISSING_TOKEN", "mfaToken is required")
+		return nil, nil, false
+	}
+	claims, err := h.web3Auth.GetJWTManager().VerifyMFAChallenge(token)
+	if err != nil {
+		h.sendError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid or expired mfa token")
+		return nil, nil, false
+	}
+	u, err := h.userRepo.FindByID(r.Context(), claims.Subject)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			h.sendError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid or expired mfa token")
+			return nil, nil, false
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return nil, nil, false
+	}
+	return u, claims, true
+}
+
+// strongSession 当前请求是否来自钱包签名或通行密钥建立的登录会话
+func (h *MFAHandler) strongSession(ctx context.Context, u *user.User) bool {
+	id := middleware.GetSessionID(ctx)
+	if id == "" || h.sessionService == nil {
+		return false
+	}
+	s, err := h.sessionService.Get(ctx, u, id)
+	if err != nil {
+		return false
+	}
+	return s.StrongFactor()
+}
+
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, mfa.ErrInvalidCode), errors.Is(err, mfa.ErrInvalidRecoveryCode):
+		return http.StatusUnauthorized
+	case errors.Is(err, mfa.ErrTooManyAttempts):
+		return http.StatusTooManyRequests
+	case errors.Is(err, mfa.ErrNotEnrolled), errors.Is(err, mfa.ErrAlreadyEnabled):
+		return http.StatusConflict
+	case errors.Is(err, mfa.ErrRequired):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *MFAHandler) sendMFAError(w http.ResponseWriter, err error) {
+	status := mfaErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error("failed to verify mfa", zap.Error(err))
+		h.sendError(w, status, "INTERNAL_ERROR", "Failed to process request")
+		return
+	}
+	h.sendError(w, status, "MFA_FAILED", err.Error())
+}
+
+func (h *MFAHandler) writeMFAError(w http.ResponseWriter, err error) {
+	status := mfaErrorStatus(err)
+	if status == http.StatusInternalServerError {
+		h.logger.Error("two-factor operation failed", zap.Error(err))
+		h.writeError(w, status, "Two-factor operation failed")
+		return
+	}
+	h.writeError(w, status, err.Error())
+}
+
+func (h *MFAHandler) setRefreshCookie(w http.ResponseWriter, r *http.Request, token string, expiresAt time.Time) {
+	secure := isSecureRequest(r)
+	maxAge := int(time.Until(expiresAt).Seconds())
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   secure,
+	})
+}
+
+func (h *MFAHandler) sendSDKSuccess(w http.ResponseWriter, data interface{}) {
+	h.writeJSON(w, http.StatusOK, sdkResponse{
+		Code:      0,
+		Message:   "ok",
+		Data:      data,
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+func (h *MFAHandler) sendError(w http.ResponseWriter, status int, code, message string) {
+	h.writeJSON(w, status, sdkResponse{
+		Code:      status,
+		Message:   message,
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// writeJSON 写入 JSON 响应
+func (h *MFAHandler) writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// writeError 写入错误响应
+func (h *MFAHandler) writeError(w http.ResponseWriter, code int, message string) {
+	h.writeJSON(w, code, map[string]interface{}{
+		"error":   message,
+		"code":    code,
+		"success": false,
+	})
+}
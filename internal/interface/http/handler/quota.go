@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/yeying-community/warehouse/internal/domain/quota"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
@@ -43,7 +44,7 @@ func (h *QuotaHandler) GetUserQuota(w http.ResponseWriter, r *http.Request) {
 	// 从上下文获取用户
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
@@ -51,7 +52,7 @@ func (h *QuotaHandler) GetUserQuota(w http.ResponseWriter, r *http.Request) {
 	// 获取配额信息
 	quotaInfo, err := h.quotaService.GetQuota(r.Context(), u.ID)
 	if err != nil {
-		h.logger.Error("failed to get quota",
+		logger.Ctx(r.Context(), h.logger).Error("failed to get quota",
 			zap.String("username", u.Username),
 			zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to get quota information")
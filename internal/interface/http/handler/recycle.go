@@ -8,6 +8,7 @@ import (
 	"github.com/yeying-community/warehouse/internal/application/service"
 	"github.com/yeying-community/warehouse/internal/domain/auth"
 	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
@@ -41,7 +42,7 @@ func (h *RecycleHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -52,7 +53,7 @@ func (h *RecycleHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		h.logger.Error("failed to list recycle items",
+		logger.Ctx(r.Context(), h.logger).Error("failed to list recycle items",
 			zap.String("username", u.Username),
 			zap.Error(err))
 		http.Error(w, "Failed to list recycle items", http.StatusInternalServerError)
@@ -61,7 +62,7 @@ func (h *RecycleHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		h.logger.Error("failed to encode response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to encode response", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -76,7 +77,7 @@ func (h *RecycleHandler) HandleRecover(w http.ResponseWriter, r *http.Request) {
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -85,7 +86,7 @@ func (h *RecycleHandler) HandleRecover(w http.ResponseWriter, r *http.Request) {
 		Hash string `json:"hash"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -100,7 +101,7 @@ func (h *RecycleHandler) HandleRecover(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		h.logger.Error("failed to recover file",
+		logger.Ctx(r.Context(), h.logger).Error("failed to recover file",
 			zap.String("username", u.Username),
 			zap.String("hash", req.Hash),
 			zap.Error(err))
@@ -110,7 +111,7 @@ func (h *RecycleHandler) HandleRecover(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte(`{"message":"recovered successfully"}`)); err != nil {
-		h.logger.Error("failed to write response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to write response", zap.Error(err))
 	}
 }
 
@@ -123,7 +124,7 @@ func (h *RecycleHandler) HandleRemove(w http.ResponseWriter, r *http.Request) {
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -132,7 +133,7 @@ func (h *RecycleHandler) HandleRemove(w http.ResponseWriter, r *http.Request) {
 		Hash string `json:"hash"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -147,7 +148,7 @@ func (h *RecycleHandler) HandleRemove(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		h.logger.Error("failed to remove file",
+		logger.Ctx(r.Context(), h.logger).Error("failed to remove file",
 			zap.String("username", u.Username),
 			zap.String("hash", req.Hash),
 			zap.Error(err))
@@ -157,7 +158,7 @@ func (h *RecycleHandler) HandleRemove(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte(`{"message":"removed successfully"}`)); err != nil {
-		h.logger.Error("failed to write response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to write response", zap.Error(err))
 	}
 }
 
@@ -170,7 +171,7 @@ func (h *RecycleHandler) HandleClear(w http.ResponseWriter, r *http.Request) {
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -181,7 +182,7 @@ func (h *RecycleHandler) HandleClear(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		h.logger.Error("failed to clear recycle items",
+		logger.Ctx(r.Context(), h.logger).Error("failed to clear recycle items",
 			zap.String("username", u.Username),
 			zap.Error(err))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -192,7 +193,7 @@ func (h *RecycleHandler) HandleClear(w http.ResponseWriter, r *http.Request) {
 	if err := json.NewEncoder(w).Encode(map[string]any{
 		"deleted": deleted,
 	}); err != nil {
-		h.logger.Error("failed to encode response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to encode response", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
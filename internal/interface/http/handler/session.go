@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// SessionHandler 登录会话处理器
+type SessionHandler struct {
+	sessionService *service.SessionService
+	rbacService    *service.RBACService
+	userRepo       user.Repository
+	logger         *zap.Logger
+}
+
+// NewSessionHandler 创建登录会话处理器
+func NewSessionHandler(
+	sessionService *service.SessionService,
+	rbacService *service.RBACService,
+	userRepo user.Repository,
+	logger *zap.Logger,
+) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+		rbacService:    rbacService,
+		userRepo:       userRepo,
+		logger:         logger,
+	}
+}
+
+type sessionResp struct {
+	ID         string `json:"id"`
+	DeviceName string `json:"deviceName"`
+	IP         string `json:"ip"`
+	UserAgent  string `json:"userAgent"`
+	LoginType  string `json:"loginType"`
+	CreatedAt  string `json:"createdAt"`
+	LastSeenAt string `json:"lastSeenAt"`
+	ExpiresAt  string `json:"expiresAt"`
+	Current    bool   `json:"current"`
+}
+
+// HandleList 获取我的登录会话
+func (h *SessionHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	items, err := h.sessionService.List(r.Context(), u)
+	if err != nil {
+		h.writeError(w, "failed to list sessions", err)
+		return
+	}
+	currentID := middleware.GetSessionID(r.Context())
+	resp := struct {
+		Items []sessionResp `json:"items"`
+	}{Items: make([]sessionResp, 0, len(items))}
+	for _, s := range items {
+		resp.Items = append(resp.Items, sessionResp{
+			ID:         s.ID,
+			DeviceName: s.DeviceName,
+			IP:         s.IP,
+			UserAgent:  s.UserAgent,
+			LoginType:  s.SubjectType,
+			CreatedAt:  s.CreatedAt.Format(timeLayout),
+			LastSeenAt: s.LastSeenAt.Format(timeLayout),
+			ExpiresAt:  s.ExpiresAt.Format(timeLayout),
+			Current:    s.ID == currentID,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// HandleRevoke 吊销我的某个会话（远程退出登录）
+// body: {"id": "..."}
+func (h *SessionHandler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	id := strings.TrimSpace(req.ID)
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.sessionService.Revoke(r.Context(), u, id); err != nil {
+		h.writeError(w, "failed to revoke session", err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(`{"message":"revoked successfully"}`))
+}
+
+// HandleRevokeOthers 吊销除当前会话外的全部会话
+func (h *SessionHandler) HandleRevokeOthers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	count, err := h.sessionService.RevokeOthers(r.Context(), u, middleware.GetSessionID(r.Context()))
+	if err != nil {
+		h.writeError(w, "failed to revoke sessions", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"revoked": count})
+}
+
+// HandleAdminRevokeAll 管理员吊销指定用户的全部会话
+// body: {"username": "..."}
+func (h *SessionHandler) HandleAdminRevokeAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		http.Error(w, "username is required", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	target, err := h.userRepo.FindByUsername(r.Context(), username)
+	if err != nil {
+		h.writeError(w, "failed to find user", err)
+		return
+	}
+	// 不能吊销权限高于自己的用户的会话
+	if err := h.rbacService.AuthorizeTarget(r.Context(), actor, target); err != nil {
+		h.writeError(w, "failed to load admin permissions", err)
+		return
+	}
+
+	count, err := h.sessionService.RevokeAll(r.Context(), username)
+	if err != nil {
+		h.writeError(w, "failed to revoke user sessions", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"revoked": count})
+}
+
+func (h *SessionHandler) writeError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, session.ErrSessionNotFound), errors.Is(err, user.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, rbac.ErrPrivilegeEscalation):
+		http.Error(w, "Cannot modify a user with more permissions than your own", http.StatusForbidden)
+	default:
+		h.logger.Error(msg, zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
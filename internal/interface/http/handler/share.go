@@ -10,7 +10,13 @@ import (
 
 	"github.com/yeying-community/warehouse/internal/application/service"
 	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/e2ee"
+	"github.com/yeying-community/warehouse/internal/domain/filemeta"
 	"github.com/yeying-community/warehouse/internal/domain/share"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/metrics"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
@@ -38,17 +44,18 @@ func (h *ShareHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	var req struct {
-		Path      string `json:"path"`
-		ExpiresIn int64  `json:"expiresIn"`
+		Path      string               `json:"path"`
+		ExpiresIn int64                `json:"expiresIn"`
+		Condition *tokengate.Condition `json:"condition"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -57,17 +64,29 @@ func (h *ShareHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	item, err := h.shareService.Create(r.Context(), u, req.Path, req.ExpiresIn)
+	item, err := h.shareService.Create(r.Context(), u, req.Path, req.ExpiresIn, req.Condition)
 	if err != nil {
 		if errors.Is(err, auth.ErrAppScopeDenied) || errors.Is(err, auth.ErrAppScopeRequired) {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		h.logger.Error("failed to create share",
-			zap.String("username", u.Username),
-			zap.String("path", req.Path),
-			zap.Error(err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		if writeTokenGateError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, e2ee.ErrPlaintextOperation):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, share.ErrInvalidPath) || errors.Is(err, share.ErrDirectoryShare):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, os.ErrNotExist):
+			http.NotFound(w, r)
+		default:
+			logger.Ctx(r.Context(), h.logger).Error("failed to create share",
+				zap.String("username", u.Username),
+				zap.String("path", req.Path),
+				zap.Error(err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -79,13 +98,19 @@ func (h *ShareHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
 		"viewCount":     item.ViewCount,
 		"downloadCount": item.DownloadCount,
 	}
+	if item.ContentHash != "" {
+		resp["sha256"] = item.ContentHash
+	}
+	if item.Condition != nil {
+		resp["condition"] = item.Condition
+	}
 	if item.ExpiresAt != nil {
 		resp["expiresAt"] = item.ExpiresAt.Format(timeLayout)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.logger.Error("failed to encode response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to encode response", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -99,7 +124,7 @@ func (h *ShareHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -110,7 +135,7 @@ func (h *ShareHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		h.logger.Error("failed to list share items",
+		logger.Ctx(r.Context(), h.logger).Error("failed to list share items",
 			zap.String("username", u.Username),
 			zap.Error(err))
 		http.Error(w, "Failed to list share items", http.StatusInternalServerError)
@@ -118,14 +143,16 @@ func (h *ShareHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type itemResp struct {
-		Token         string `json:"token"`
-		Name          string `json:"name"`
-		Path          string `json:"path"`
-		URL           string `json:"url"`
-		ViewCount     int64  `json:"viewCount"`
-		DownloadCount int64  `json:"downloadCount"`
-		ExpiresAt     string `json:"expiresAt,omitempty"`
-		CreatedAt     string `json:"createdAt"`
+		Token         string               `json:"token"`
+		Name          string               `json:"name"`
+		Path          string               `json:"path"`
+		URL           string               `json:"url"`
+		ViewCount     int64                `json:"viewCount"`
+		DownloadCount int64                `json:"downloadCount"`
+		SHA256        string               `json:"sha256,omitempty"`
+		Condition     *tokengate.Condition `json:"condition,omitempty"`
+		ExpiresAt     string               `json:"expiresAt,omitempty"`
+		CreatedAt     string               `json:"createdAt"`
 	}
 
 	resp := struct {
@@ -142,6 +169,8 @@ func (h *ShareHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 			URL:           h.buildShareURL(r, item.Token, item.Name),
 			ViewCount:     item.ViewCount,
 			DownloadCount: item.DownloadCount,
+			SHA256:        item.ContentHash,
+			Condition:     item.Condition,
 			CreatedAt:     item.CreatedAt.Format(timeLayout),
 		}
 		if item.ExpiresAt != nil {
@@ -152,7 +181,7 @@ func (h *ShareHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.logger.Error("failed to encode response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to encode response", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -166,7 +195,7 @@ func (h *ShareHandler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -175,7 +204,7 @@ func (h *ShareHandler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
 		Token string `json:"token"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -185,25 +214,96 @@ func (h *ShareHandler) HandleRevoke(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.shareService.Revoke(r.Context(), u, req.Token); err != nil {
-		if errors.Is(err, auth.ErrAppScopeDenied) || errors.Is(err, auth.ErrAppScopeRequired) {
+		switch {
+		case errors.Is(err, auth.ErrAppScopeDenied) || errors.Is(err, auth.ErrAppScopeRequired) || errors.Is(err, share.ErrNotOwner):
 			http.Error(w, "Forbidden", http.StatusForbidden)
-			return
+		case errors.Is(err, share.ErrShareNotFound):
+			http.NotFound(w, r)
+		case errors.Is(err, share.ErrInvalidPath):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			logger.Ctx(r.Context(), h.logger).Error("failed to revoke share",
+				zap.String("username", u.Username),
+				zap.String("token", req.Token),
+				zap.Error(err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
 		}
-		h.logger.Error("failed to revoke share",
-			zap.String("username", u.Username),
-			zap.String("token", req.Token),
-			zap.Error(err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte(`{"message":"revoked successfully"}`)); err != nil {
-		h.logger.Error("failed to write response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to write response", zap.Error(err))
+	}
+}
+
+// HandleSave 将分享文件保存到我的空间
+func (h *ShareHandler) HandleSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+		Path  string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Token) == "" {
+		http.Error(w, "token is required", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Path) == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	savedPath, err := h.shareService.SaveToDrive(r.Context(), u, req.Token, req.Path)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrAppScopeDenied) || errors.Is(err, auth.ErrAppScopeRequired) || errors.Is(err, share.ErrPermissionDenied):
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		case errors.Is(err, share.ErrInvalidPath):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, share.ErrShareNotFound) || errors.Is(err, share.ErrInvalidShare) || errors.Is(err, os.ErrNotExist):
+			http.NotFound(w, r)
+		case errors.Is(err, share.ErrShareExpired):
+			http.Error(w, "share expired", http.StatusGone)
+		case errors.Is(err, share.ErrTargetExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, user.ErrQuotaExceeded):
+			metrics.QuotaRejections.Inc("share")
+			http.Error(w, "Insufficient Storage", http.StatusInsufficientStorage)
+		case writeTokenGateError(w, err):
+		default:
+			logger.Ctx(r.Context(), h.logger).Error("failed to save share",
+				zap.String("username", u.Username),
+				zap.String("token", req.Token),
+				zap.Error(err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"path": savedPath}); err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to encode response", zap.Error(err))
 	}
 }
 
 // HandleAccess 访问分享链接（公开）
+// 设置了链上访问条件的分享需携带访问者的登录凭证。
 func (h *ShareHandler) HandleAccess(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet && r.Method != http.MethodHead {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -215,8 +315,10 @@ func (h *ShareHandler) HandleAccess(w http.ResponseWriter, r *http.Request) {
 		http.NotFound(w, r)
 		return
 	}
+	middleware.SetAuditTarget(r.Context(), token)
 
-	item, file, info, err := h.shareService.Resolve(r.Context(), token)
+	caller, _ := middleware.GetUserFromContext(r.Context())
+	item, file, info, err := h.shareService.Resolve(r.Context(), token, caller)
 	if err != nil {
 		if err == share.ErrShareNotFound || err == share.ErrInvalidShare || errors.Is(err, os.ErrNotExist) {
 			http.NotFound(w, r)
@@ -226,7 +328,10 @@ func (h *ShareHandler) HandleAccess(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "share expired", http.StatusGone)
 			return
 		}
-		h.logger.Error("failed to resolve share", zap.Error(err))
+		if writeTokenGateError(w, err) {
+			return
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to resolve share", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -247,8 +352,10 @@ func (h *ShareHandler) HandleAccess(w http.ResponseWriter, r *http.Request) {
 			_ = h.shareService.IncrementDownload(r.Context(), token)
 		}
 	}
+	metrics.ShareAccesses.Inc("public")
 
 	setAttachmentContentDisposition(w, item.Name)
+	setContentHashHeaders(w, item.ContentHash)
 
 	http.ServeContent(w, r, item.Name, info.ModTime(), file)
 }
@@ -273,6 +380,34 @@ func (h *ShareHandler) buildShareURL(r *http.Request, token, fileName string) st
 
 const timeLayout = "2006-01-02 15:04:05"
 
+// writeTokenGateError 将链上门槛相关错误映射为 HTTP 状态码，已处理返回 true
+func writeTokenGateError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, tokengate.ErrWalletRequired):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, tokengate.ErrConditionNotMet):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, tokengate.ErrInvalidCondition),
+		errors.Is(err, tokengate.ErrChainNotConfigured),
+		errors.Is(err, tokengate.ErrGateDisabled):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		return false
+	}
+	return true
+}
+
+// setContentHashHeaders 设置强 ETag 与 Digest / OC-Checksum 头，便于客户端校验下载内容
+func setContentHashHeaders(w http.ResponseWriter, sum string) {
+	meta := &filemeta.FileMetadata{SHA256: sum}
+	if !filemeta.IsValidSHA256(sum) {
+		return
+	}
+	w.Header().Set("ETag", meta.ETag())
+	w.Header().Set("Digest", meta.Digest())
+	w.Header().Set("OC-Checksum", meta.OCChecksum())
+}
+
 func shouldCountAccess(r *http.Request) bool {
 	rangeHeader := r.Header.Get("Range")
 	if rangeHeader == "" {
@@ -4,17 +4,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path"
-	"path/filepath"
 	"strings"
 
 	"github.com/yeying-community/warehouse/internal/application/service"
 	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/e2ee"
 	"github.com/yeying-community/warehouse/internal/domain/shareuser"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
 	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/metrics"
+	webdavfs "github.com/yeying-community/warehouse/internal/infrastructure/webdav"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
@@ -44,19 +47,21 @@ func (h *ShareUserHandler) HandleCreate(w http.ResponseWriter, r *http.Request)
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	var req struct {
-		Path          string   `json:"path"`
-		TargetAddress string   `json:"targetAddress"`
-		Permissions   []string `json:"permissions"`
-		ExpiresIn     int64    `json:"expiresIn"`
+		Path          string               `json:"path"`
+		TargetAddress string               `json:"targetAddress"`
+		Permissions   []string             `json:"permissions"`
+		ExpiresIn     int64                `json:"expiresIn"`
+		KeyEnvelope   string               `json:"keyEnvelope"`
+		Condition     *tokengate.Condition `json:"condition"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -75,13 +80,20 @@ func (h *ShareUserHandler) HandleCreate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	item, err := h.shareUserService.Create(r.Context(), u, req.TargetAddress, req.Path, perms.String(), req.ExpiresIn)
+	item, err := h.shareUserService.Create(r.Context(), u, req.TargetAddress, req.Path, perms.String(), req.ExpiresIn, req.KeyEnvelope, req.Condition)
 	if err != nil {
 		if errors.Is(err, auth.ErrAppScopeDenied) || errors.Is(err, auth.ErrAppScopeRequired) {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		h.logger.Error("failed to create share user",
+		if errors.Is(err, e2ee.ErrEnvelopeRequired) {
+			http.Error(w, err.Error(), http.StatusPreconditionRequired)
+			return
+		}
+		if writeTokenGateError(w, err) {
+			return
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to create share user",
 			zap.String("owner", u.Username),
 			zap.String("path", req.Path),
 			zap.Error(err))
@@ -101,10 +113,17 @@ func (h *ShareUserHandler) HandleCreate(w http.ResponseWriter, r *http.Request)
 	if item.ExpiresAt != nil {
 		resp["expiresAt"] = item.ExpiresAt.Format(timeLayout)
 	}
+	if item.Condition != nil {
+		resp["condition"] = item.Condition
+	}
+	if item.E2EEFolderID != "" {
+		resp["e2eeFolderId"] = item.E2EEFolderID
+		resp["keyEnvelope"] = item.KeyEnvelope
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.logger.Error("failed to encode response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to encode response", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -118,7 +137,7 @@ func (h *ShareUserHandler) HandleListMine(w http.ResponseWriter, r *http.Request
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -129,7 +148,7 @@ func (h *ShareUserHandler) HandleListMine(w http.ResponseWriter, r *http.Request
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		h.logger.Error("failed to list share user items",
+		logger.Ctx(r.Context(), h.logger).Error("failed to list share user items",
 			zap.String("owner", u.Username),
 			zap.Error(err))
 		http.Error(w, "Failed to list share items", http.StatusInternalServerError)
@@ -137,16 +156,19 @@ func (h *ShareUserHandler) HandleListMine(w http.ResponseWriter, r *http.Request
 	}
 
 	type itemResp struct {
-		ID           string   `json:"id"`
-		Name         string   `json:"name"`
-		Path         string   `json:"path"`
-		IsDir        bool     `json:"isDir"`
-		Permissions  []string `json:"permissions"`
-		TargetWallet string   `json:"targetWallet"`
-		OwnerWallet  string   `json:"ownerWallet,omitempty"`
-		OwnerName    string   `json:"ownerName,omitempty"`
-		ExpiresAt    string   `json:"expiresAt,omitempty"`
-		CreatedAt    string   `json:"createdAt"`
+		ID           string               `json:"id"`
+		Name         string               `json:"name"`
+		Path         string               `json:"path"`
+		IsDir        bool                 `json:"isDir"`
+		Permissions  []string             `json:"permissions"`
+		TargetWallet string               `json:"targetWallet"`
+		OwnerWallet  string               `json:"ownerWallet,omitempty"`
+		OwnerName    string               `json:"ownerName,omitempty"`
+		ExpiresAt    string               `json:"expiresAt,omitempty"`
+		CreatedAt    string               `json:"createdAt"`
+		E2EEFolderID string               `json:"e2eeFolderId,omitempty"`
+		KeyEnvelope  string               `json:"keyEnvelope,omitempty"`
+		Condition    *tokengate.Condition `json:"condition,omitempty"`
 	}
 
 	resp := struct {
@@ -167,6 +189,9 @@ func (h *ShareUserHandler) HandleListMine(w http.ResponseWriter, r *http.Request
 			OwnerWallet:  u.WalletAddress,
 			OwnerName:    u.Username,
 			CreatedAt:    item.CreatedAt.Format(timeLayout),
+			E2EEFolderID: item.E2EEFolderID,
+			KeyEnvelope:  item.KeyEnvelope,
+			Condition:    item.Condition,
 		}
 		if item.ExpiresAt != nil {
 			row.ExpiresAt = item.ExpiresAt.Format(timeLayout)
@@ -176,7 +201,7 @@ func (h *ShareUserHandler) HandleListMine(w http.ResponseWriter, r *http.Request
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.logger.Error("failed to encode response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to encode response", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -190,7 +215,7 @@ func (h *ShareUserHandler) HandleListReceived(w http.ResponseWriter, r *http.Req
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -201,7 +226,7 @@ func (h *ShareUserHandler) HandleListReceived(w http.ResponseWriter, r *http.Req
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		h.logger.Error("failed to list received share items",
+		logger.Ctx(r.Context(), h.logger).Error("failed to list received share items",
 			zap.String("target", u.Username),
 			zap.Error(err))
 		http.Error(w, "Failed to list share items", http.StatusInternalServerError)
@@ -209,16 +234,19 @@ func (h *ShareUserHandler) HandleListReceived(w http.ResponseWriter, r *http.Req
 	}
 
 	type itemResp struct {
-		ID           string   `json:"id"`
-		Name         string   `json:"name"`
-		Path         string   `json:"path"`
-		IsDir        bool     `json:"isDir"`
-		Permissions  []string `json:"permissions"`
-		TargetWallet string   `json:"targetWallet,omitempty"`
-		OwnerWallet  string   `json:"ownerWallet,omitempty"`
-		OwnerName    string   `json:"ownerName,omitempty"`
-		ExpiresAt    string   `json:"expiresAt,omitempty"`
-		CreatedAt    string   `json:"createdAt"`
+		ID           string               `json:"id"`
+		Name         string               `json:"name"`
+		Path         string               `json:"path"`
+		IsDir        bool                 `json:"isDir"`
+		Permissions  []string             `json:"permissions"`
+		TargetWallet string               `json:"targetWallet,omitempty"`
+		OwnerWallet  string               `json:"ownerWallet,omitempty"`
+		OwnerName    string               `json:"ownerName,omitempty"`
+		ExpiresAt    string               `json:"expiresAt,omitempty"`
+		CreatedAt    string               `json:"createdAt"`
+		E2EEFolderID string               `json:"e2eeFolderId,omitempty"`
+		KeyEnvelope  string               `json:"keyEnvelope,omitempty"`
+		Condition    *tokengate.Condition `json:"condition,omitempty"`
 	}
 
 	resp := struct {
@@ -238,6 +266,9 @@ func (h *ShareUserHandler) HandleListReceived(w http.ResponseWriter, r *http.Req
 			TargetWallet: u.WalletAddress,
 			OwnerName:    item.OwnerUsername,
 			CreatedAt:    item.CreatedAt.Format(timeLayout),
+			E2EEFolderID: item.E2EEFolderID,
+			KeyEnvelope:  item.KeyEnvelope,
+			Condition:    item.Condition,
 		}
 		if item.ExpiresAt != nil {
 			row.ExpiresAt = item.ExpiresAt.Format(timeLayout)
@@ -250,7 +281,7 @@ func (h *ShareUserHandler) HandleListReceived(w http.ResponseWriter, r *http.Req
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.logger.Error("failed to encode response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to encode response", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -264,7 +295,7 @@ func (h *ShareUserHandler) HandleRevoke(w http.ResponseWriter, r *http.Request)
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -273,7 +304,7 @@ func (h *ShareUserHandler) HandleRevoke(w http.ResponseWriter, r *http.Request)
 		ID string `json:"id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -287,7 +318,7 @@ func (h *ShareUserHandler) HandleRevoke(w http.ResponseWriter, r *http.Request)
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
-		h.logger.Error("failed to revoke share user",
+		logger.Ctx(r.Context(), h.logger).Error("failed to revoke share user",
 			zap.String("owner", u.Username),
 			zap.String("share_id", req.ID),
 			zap.Error(err))
@@ -297,7 +328,7 @@ func (h *ShareUserHandler) HandleRevoke(w http.ResponseWriter, r *http.Request)
 
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte(`{"message":"revoked successfully"}`)); err != nil {
-		h.logger.Error("failed to write response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to write response", zap.Error(err))
 	}
 }
 
@@ -310,7 +341,7 @@ func (h *ShareUserHandler) HandleEntries(w http.ResponseWriter, r *http.Request)
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -340,7 +371,7 @@ func (h *ShareUserHandler) HandleEntries(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	info, err := os.Stat(fullPath)
+	info, err := h.shareUserService.Stat(r.Context(), owner, fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			http.Error(w, "Not Found", http.StatusNotFound)
@@ -365,19 +396,15 @@ func (h *ShareUserHandler) HandleEntries(w http.ResponseWriter, r *http.Request)
 	}
 
 	if info.IsDir() {
-		entries, err := os.ReadDir(fullPath)
+		entries, err := h.shareUserService.ReadDir(r.Context(), owner, fullPath)
 		if err != nil {
 			http.Error(w, "Failed to read directory", http.StatusInternalServerError)
 			return
 		}
 
 		prefix := normalizeRelPath(relPath)
-		for _, entry := range entries {
-			entryInfo, err := entry.Info()
-			if err != nil {
-				continue
-			}
-			entryPath := buildShareEntryPath(prefix, entry.Name(), entryInfo.IsDir())
+		for _, entryInfo := range entries {
+			entryPath := buildShareEntryPath(prefix, entryInfo.Name(), entryInfo.IsDir())
 			resp.Items = append(resp.Items, entryResp{
 				Name:     entryInfo.Name(),
 				Path:     entryPath,
@@ -398,7 +425,7 @@ func (h *ShareUserHandler) HandleEntries(w http.ResponseWriter, r *http.Request)
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		h.logger.Error("failed to encode response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to encode response", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
@@ -412,7 +439,7 @@ func (h *ShareUserHandler) HandleDownload(w http.ResponseWriter, r *http.Request
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -442,7 +469,7 @@ func (h *ShareUserHandler) HandleDownload(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	file, err := os.Open(fullPath)
+	file, err := h.shareUserService.OpenFile(r.Context(), owner, fullPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			http.Error(w, "Not Found", http.StatusNotFound)
@@ -464,6 +491,8 @@ func (h *ShareUserHandler) HandleDownload(w http.ResponseWriter, r *http.Request
 	}
 
 	setAttachmentContentDisposition(w, info.Name())
+	setContentHashHeaders(w, webdavfs.ContentHashOf(info))
+	metrics.ShareAccesses.Inc("user")
 
 	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
 }
@@ -477,7 +506,7 @@ func (h *ShareUserHandler) HandleUpload(w http.ResponseWriter, r *http.Request)
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -522,26 +551,15 @@ func (h *ShareUserHandler) HandleUpload(w http.ResponseWriter, r *http.Request)
 	}
 	defer file.Close()
 
-	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
-		http.Error(w, "Failed to create directory", http.StatusInternalServerError)
-		return
-	}
-
-	dst, err := os.OpenFile(fullPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
-	if err != nil {
-		http.Error(w, "Failed to write file", http.StatusInternalServerError)
-		return
-	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, file); err != nil {
+	if err := h.shareUserService.WriteFile(r.Context(), owner, fullPath, file); err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to write shared file", zap.String("path", fullPath), zap.Error(err))
 		http.Error(w, "Failed to write file", http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte(`{"message":"uploaded successfully"}`)); err != nil {
-		h.logger.Error("failed to write response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to write response", zap.Error(err))
 	}
 }
 
@@ -554,7 +572,7 @@ func (h *ShareUserHandler) HandleCreateFolder(w http.ResponseWriter, r *http.Req
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -564,7 +582,7 @@ func (h *ShareUserHandler) HandleCreateFolder(w http.ResponseWriter, r *http.Req
 		Path    string `json:"path"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -602,7 +620,7 @@ func (h *ShareUserHandler) HandleCreateFolder(w http.ResponseWriter, r *http.Req
 
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte(`{"message":"created successfully"}`)); err != nil {
-		h.logger.Error("failed to write response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to write response", zap.Error(err))
 	}
 }
 
@@ -615,7 +633,7 @@ func (h *ShareUserHandler) HandleRename(w http.ResponseWriter, r *http.Request)
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -626,7 +644,7 @@ func (h *ShareUserHandler) HandleRename(w http.ResponseWriter, r *http.Request)
 		To      string `json:"to"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -669,7 +687,7 @@ func (h *ShareUserHandler) HandleRename(w http.ResponseWriter, r *http.Request)
 
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte(`{"message":"renamed successfully"}`)); err != nil {
-		h.logger.Error("failed to write response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to write response", zap.Error(err))
 	}
 }
 
@@ -682,7 +700,7 @@ func (h *ShareUserHandler) HandleDelete(w http.ResponseWriter, r *http.Request)
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -692,7 +710,7 @@ func (h *ShareUserHandler) HandleDelete(w http.ResponseWriter, r *http.Request)
 		Path    string `json:"path"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -730,7 +748,7 @@ func (h *ShareUserHandler) HandleDelete(w http.ResponseWriter, r *http.Request)
 
 	w.WriteHeader(http.StatusOK)
 	if _, err := w.Write([]byte(`{"message":"deleted successfully"}`)); err != nil {
-		h.logger.Error("failed to write response", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to write response", zap.Error(err))
 	}
 }
 
@@ -747,6 +765,9 @@ func writeShareUserError(w http.ResponseWriter, err error) {
 		http.Error(w, "share expired", http.StatusGone)
 		return
 	}
+	if writeTokenGateError(w, err) {
+		return
+	}
 	http.Error(w, err.Error(), http.StatusBadRequest)
 }
 
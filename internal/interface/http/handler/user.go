@@ -8,6 +8,7 @@ import (
 
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
@@ -33,6 +34,7 @@ type UserInfoResponse struct {
 	Username      string   `json:"username"`
 	WalletAddress string   `json:"wallet_address,omitempty"`
 	Email         string   `json:"email,omitempty"`
+	EmailVerified bool     `json:"email_verified"`
 	Permissions   []string `json:"permissions"`
 	CreatedAt     string   `json:"created_at,omitempty"`
 	UpdatedAt     string   `json:"updated_at,omitempty"`
@@ -50,7 +52,7 @@ func (h *UserHandler) GetUserInfo(w http.ResponseWriter, r *http.Request) {
 	// 从上下文获取用户
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
@@ -59,6 +61,7 @@ func (h *UserHandler) GetUserInfo(w http.ResponseWriter, r *http.Request) {
 		Username:      u.Username,
 		WalletAddress: u.WalletAddress,
 		Email:         u.Email,
+		EmailVerified: u.IsEmailVerified(),
 		Permissions:   permissionsToStrings(u.Permissions),
 		HasPassword:   u.HasPassword(),
 	}
@@ -82,7 +85,7 @@ func (h *UserHandler) UpdateUsername(w http.ResponseWriter, r *http.Request) {
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
@@ -91,7 +94,7 @@ func (h *UserHandler) UpdateUsername(w http.ResponseWriter, r *http.Request) {
 		Username string `json:"username"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		h.writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -104,7 +107,7 @@ func (h *UserHandler) UpdateUsername(w http.ResponseWriter, r *http.Request) {
 
 	current, err := h.userRepository.FindByID(r.Context(), u.ID)
 	if err != nil {
-		h.logger.Error("failed to find user", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to find user")
 		return
 	}
@@ -129,7 +132,7 @@ func (h *UserHandler) UpdateUsername(w http.ResponseWriter, r *http.Request) {
 			h.writeError(w, http.StatusConflict, "Username already exists")
 			return
 		}
-		h.logger.Error("failed to update username", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to update username", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to update username")
 		return
 	}
@@ -146,7 +149,7 @@ func (h *UserHandler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
@@ -156,7 +159,7 @@ func (h *UserHandler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 		NewPassword string `json:"newPassword"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		h.writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -169,7 +172,7 @@ func (h *UserHandler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 
 	current, err := h.userRepository.FindByID(r.Context(), u.ID)
 	if err != nil {
-		h.logger.Error("failed to find user", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to find user")
 		return
 	}
@@ -187,14 +190,14 @@ func (h *UserHandler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
 
 	hashed, err := h.passwordHasher.Hash(newPassword)
 	if err != nil {
-		h.logger.Error("failed to hash password", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to hash password", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to update password")
 		return
 	}
 
 	current.SetPassword(hashed)
 	if err := h.userRepository.Save(r.Context(), current); err != nil {
-		h.logger.Error("failed to update password", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to update password", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "Failed to update password")
 		return
 	}
@@ -2,11 +2,20 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/yeying-community/warehouse/internal/application/assetspace"
+	"github.com/yeying-community/warehouse/internal/application/service"
+	"github.com/yeying-community/warehouse/internal/domain/account"
+	authDomain "github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/tokengate"
 	"github.com/yeying-community/warehouse/internal/domain/user"
 	"github.com/yeying-community/warehouse/internal/infrastructure/auth"
 	"github.com/yeying-community/warehouse/internal/infrastructure/crypto"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/ratelimit"
 	"github.com/yeying-community/warehouse/internal/interface/http/dto"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/sha3"
 	"net/http"
@@ -21,6 +30,9 @@ type Web3Handler struct {
 	web3Auth              *auth.Web3Authenticator
 	userRepo              user.Repository
 	assetSpaceManager     *assetspace.Manager
+	tokenGate             *service.TokenGateService
+	mfaHandler            *MFAHandler
+	lockout               *ratelimit.Lockout
 	logger                *zap.Logger
 	autoCreateOnChallenge bool
 }
@@ -39,6 +51,7 @@ func NewWeb3Handler(
 	web3Auth *auth.Web3Authenticator,
 	userRepo user.Repository,
 	assetSpaceManager *assetspace.Manager,
+	tokenGate *service.TokenGateService,
 	logger *zap.Logger,
 	autoCreateOnChallenge bool,
 ) *Web3Handler {
@@ -46,37 +59,20 @@ func NewWeb3Handler(
 		web3Auth:              web3Auth,
 		userRepo:              userRepo,
 		assetSpaceManager:     assetSpaceManager,
+		tokenGate:             tokenGate,
 		logger:                logger,
 		autoCreateOnChallenge: autoCreateOnChallenge,
 	}
 }
 
-type AddressInfo struct {
-	CoinBalance string `json:"coin_balance"`
+// SetMFAHandler 设置两步验证处理器；未设置时密码登录直接签发令牌
+func (h *Web3Handler) SetMFAHandler(mfaHandler *MFAHandler) {
+	h.mfaHandler = mfaHandler
 }
 
-// 获取以太坊钱包的账户余额是否大于0
-func HasBalance(address string) bool {
-	url := "https://blockscout.yeying.pub/backend/api/v2/addresses/" + address
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(url)
-	if err != nil {
-		return false
-	}
-	defer resp.Body.Close()
-
-	var info AddressInfo
-	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
-		return false
-	}
-
-	balance, err := strconv.Atoi(info.CoinBalance)
-	if err != nil {
-		return false
-	}
-
-	return balance > 0
+// SetLockout 设置账户锁定器，用户名密码登录连续失败后锁定账户
+func (h *Web3Handler) SetLockout(lockout *ratelimit.Lockout) {
+	h.lockout = lockout
 }
 
 // 验证以太坊地址合法性
@@ -123,15 +119,33 @@ func verifyChecksum(address string) bool {
 	return true
 }
 
+// normalizeWalletParam 请求中的钱包地址转换为存储键，无法解析时原样小写
+func normalizeWalletParam(raw string) string {
+	if key, err := account.Normalize(raw); err == nil {
+		return key
+	}
+	return strings.ToLower(strings.TrimSpace(raw))
+}
+
 // HandleChallenge 处理挑战请求
 // GET /api/auth/challenge?address=0x123...
+// address 也可以是 CAIP-10 账户（eip155:137:0x...、solana:<ref>:<base58>）或 did:pkh
 func (h *Web3Handler) HandleChallenge(w http.ResponseWriter, r *http.Request) {
 	var address string
+	var chainID uint64
 
 	// 获取地址参数
 	switch r.Method {
 	case http.MethodGet:
 		address = r.URL.Query().Get("address")
+		if raw := r.URL.Query().Get("chainId"); raw != "" {
+			id, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				h.sendError(w, http.StatusBadRequest, "INVALID_CHAIN_ID", "chainId is invalid")
+				return
+			}
+			chainID = id
+		}
 
 	case http.MethodPost:
 		var req dto.ChallengeRequest
@@ -140,6 +154,7 @@ func (h *Web3Handler) HandleChallenge(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		address = req.Address
+		chainID = req.ChainID
 
 	default:
 		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only GET and POST methods are allowed")
@@ -151,29 +166,38 @@ func (h *Web3Handler) HandleChallenge(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !IsValidAddress(address) {
+	address = strings.TrimSpace(address)
+	accountID, err := account.Parse(address)
+	// 裸以太坊地址仍要求通过 EIP-55 校验
+	if err != nil || (!strings.Contains(address, ":") && accountID.IsEVM() && !IsValidAddress(address)) {
 		h.sendError(w, http.StatusBadRequest, "MISSING_ADDRESS", "Address parameter is invalid, address "+address)
 		return
 	}
+	if chainID == 0 && strings.Contains(address, ":") {
+		chainID = accountID.ChainID()
+	}
 
 	// 规范化地址
-	address = strings.ToLower(strings.TrimSpace(address))
-
-	// 短期内不验证余额，先跳过
-	skip := false
-	// 检查当前钱包账户地址是否有余额
-	if skip && !HasBalance(address) {
-		h.logger.Error("The balance of the web3 wallet account is 0", zap.String("address", address))
-		h.sendError(w, http.StatusInternalServerError, "BALANCE_FETCH_FAIL", "The balance of the web3 wallet account is 0")
-		return
-	} else {
-		if h.autoCreateOnChallenge {
-			// 注册钱包账户（不存在则自动创建）
-			if _, err := h.web3Auth.EnsureUserByWallet(r.Context(), address, true); err != nil {
-				h.logger.Error("failed to ensure wallet user", zap.String("address", address), zap.Error(err))
-				h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
-				return
-			}
+	address = accountID.Key()
+
+	// 链上登录门槛（token_gate.challenge）
+	if err := h.tokenGate.CheckChallenge(r.Context(), address); err != nil {
+		if errors.Is(err, tokengate.ErrConditionNotMet) {
+			logger.Ctx(r.Context(), h.logger).Warn("wallet does not meet challenge token gate", zap.String("address", address))
+			h.sendError(w, http.StatusForbidden, "TOKEN_GATE_DENIED", "Wallet does not meet the token gate condition")
+			return
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to check challenge token gate", zap.String("address", address), zap.Error(err))
+		h.sendError(w, http.StatusBadGateway, "TOKEN_GATE_UNAVAILABLE", "Failed to check token gate condition")
+		return
+	}
+
+	if h.autoCreateOnChallenge {
+		// 注册钱包账户（不存在则自动创建）
+		if _, err := h.web3Auth.EnsureUserByWallet(r.Context(), address, true); err != nil {
+			logger.Ctx(r.Context(), h.logger).Error("failed to ensure wallet user", zap.String("address", address), zap.Error(err))
+			h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+			return
 		}
 	}
 
@@ -182,25 +206,31 @@ func (h *Web3Handler) HandleChallenge(w http.ResponseWriter, r *http.Request) {
 	u, err := h.userRepo.FindByWalletAddress(ctx, address)
 	if err != nil {
 		if err == user.ErrUserNotFound {
-			h.logger.Info("wallet address not registered", zap.String("address", address))
+			logger.Ctx(r.Context(), h.logger).Info("wallet address not registered", zap.String("address", address))
 			h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "Wallet address not registered")
 			return
 		}
 
-		h.logger.Error("failed to find user", zap.String("address", address), zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.String("address", address), zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
 		return
 	}
 
 	// 创建挑战
-	challenge, err := h.web3Auth.CreateChallenge(address)
+	siweReq := siweRequestFrom(r)
+	siweReq.ChainID = chainID
+	challenge, err := h.web3Auth.CreateChallenge(address, siweReq)
 	if err != nil {
-		h.logger.Error("failed to create challenge", zap.String("address", address), zap.Error(err))
+		if errors.Is(err, authDomain.ErrInvalidChallenge) {
+			h.sendError(w, http.StatusBadRequest, "INVALID_CHAIN_ID", err.Error())
+			return
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to create challenge", zap.String("address", address), zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, "CHALLENGE_CREATION_FAILED", "Failed to create challenge")
 		return
 	}
 
-	h.logger.Info("challenge created",
+	logger.Ctx(r.Context(), h.logger).Info("challenge created",
 		zap.String("address", address),
 		zap.String("username", u.Username),
 		zap.String("nonce", challenge.Nonce))
@@ -210,9 +240,23 @@ func (h *Web3Handler) HandleChallenge(w http.ResponseWriter, r *http.Request) {
 		"address":   address,
 		"challenge": challenge.Message,
 		"nonce":     challenge.Nonce,
-		"issuedAt":  time.Now().UnixMilli(),
+		"issuedAt":  challenge.IssuedAt.UnixMilli(),
 		"expiresAt": challenge.ExpiresAt.UnixMilli(),
 	}
+	if challenge.Domain != "" {
+		data["domain"] = challenge.Domain
+		if challenge.ChainRef != "" {
+			data["format"] = "siws"
+			data["chainRef"] = challenge.ChainRef
+		} else {
+			data["format"] = "siwe"
+			data["chainId"] = challenge.ChainID
+		}
+	}
+	if accountID.IsEVM() && challenge.ChainID != 0 {
+		accountID.Reference = strconv.FormatUint(challenge.ChainID, 10)
+	}
+	data["accountId"] = accountID.String()
 
 	h.sendSDKSuccess(w, data)
 }
@@ -228,7 +272,7 @@ func (h *Web3Handler) HandleVerify(w http.ResponseWriter, r *http.Request) {
 	// 解析请求
 	var req dto.VerifyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
 		return
 	}
@@ -245,34 +289,42 @@ func (h *Web3Handler) HandleVerify(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 规范化地址
-	req.Address = strings.ToLower(strings.TrimSpace(req.Address))
+	req.Address = normalizeWalletParam(req.Address)
 
 	// 查找用户
 	ctx := r.Context()
 	u, err := h.userRepo.FindByWalletAddress(ctx, req.Address)
 	if err != nil {
 		if err == user.ErrUserNotFound {
-			h.logger.Info("wallet address not registered", zap.String("address", req.Address))
+			logger.Ctx(r.Context(), h.logger).Info("wallet address not registered", zap.String("address", req.Address))
 			h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "Wallet address not registered")
 			return
 		}
 
-		h.logger.Error("failed to find user", zap.String("address", req.Address), zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.String("address", req.Address), zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
 		return
 	}
 
-	// 验证签名并生成 token
-	token, err := h.web3Auth.VerifySignature(ctx, req.Address, req.Signature)
-	if err != nil {
-		h.logger.Warn("signature verification failed",
+	// 验证签名
+	if _, err := h.web3Auth.VerifyWalletControl(ctx, req.Address, req.Signature, req.Message, siweRequestFrom(r)); err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("signature verification failed",
 			zap.String("address", req.Address),
 			zap.Error(err))
-		h.sendError(w, http.StatusUnauthorized, "INVALID_SIGNATURE", "Signature verification failed")
+		switch {
+		case errors.Is(err, authDomain.ErrChallengeExpired):
+			h.sendError(w, http.StatusUnauthorized, "CHALLENGE_EXPIRED", "Challenge expired or already used")
+		case errors.Is(err, authDomain.ErrInvalidChallenge):
+			h.sendError(w, http.StatusUnauthorized, "INVALID_MESSAGE", "Sign-in message verification failed")
+		case errors.Is(err, crypto.ErrSmartWalletUnavailable):
+			h.sendError(w, http.StatusBadGateway, "SIGNATURE_VERIFIER_UNAVAILABLE", "Smart wallet signature verification unavailable")
+		default:
+			h.sendError(w, http.StatusUnauthorized, "INVALID_SIGNATURE", "Signature verification failed")
+		}
 		return
 	}
 
-	h.logger.Info("user authenticated via web3",
+	logger.Ctx(r.Context(), h.logger).Info("user authenticated via web3",
 		zap.String("address", req.Address),
 		zap.String("username", u.Username))
 
@@ -281,20 +333,22 @@ func (h *Web3Handler) HandleVerify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	refreshToken, err := h.web3Auth.GenerateRefreshToken(req.Address)
+	// 创建会话并签发令牌
+	middleware.SetAuditActor(ctx, u)
+	tokens, err := h.web3Auth.IssueTokens(ctx, u, req.Address, "wallet", session.MethodWallet, sessionClientFrom(r))
 	if err != nil {
-		h.logger.Error("failed to generate refresh token", zap.Error(err))
-		h.sendError(w, http.StatusInternalServerError, "REFRESH_TOKEN_FAILED", "Failed to generate refresh token")
+		logger.Ctx(r.Context(), h.logger).Error("failed to issue tokens", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED", "Failed to generate token")
 		return
 	}
 
-	h.setRefreshCookie(w, r, refreshToken.Value, refreshToken.ExpiresAt)
+	h.setRefreshCookie(w, r, tokens.Refresh.Value, tokens.Refresh.ExpiresAt)
 
 	data := map[string]interface{}{
 		"address":          req.Address,
-		"token":            token.Value,
-		"expiresAt":        token.ExpiresAt.UnixMilli(),
-		"refreshExpiresAt": refreshToken.ExpiresAt.UnixMilli(),
+		"token":            tokens.Access.Value,
+		"expiresAt":        tokens.Access.ExpiresAt.UnixMilli(),
+		"refreshExpiresAt": tokens.Refresh.ExpiresAt.UnixMilli(),
 	}
 
 	h.sendSDKSuccess(w, data)
@@ -312,7 +366,7 @@ func (h *Web3Handler) HandlePasswordLogin(w http.ResponseWriter, r *http.Request
 		Password string `json:"password"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid request body", zap.Error(err))
 		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
 		return
 	}
@@ -330,7 +384,7 @@ func (h *Web3Handler) HandlePasswordLogin(w http.ResponseWriter, r *http.Request
 			h.sendError(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "Invalid username or password")
 			return
 		}
-		h.logger.Error("failed to find user", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.Error(err))
 		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
 		return
 	}
@@ -340,13 +394,27 @@ func (h *Web3Handler) HandlePasswordLogin(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if err := h.lockout.Check(u.ID); err != nil {
+		h.sendLocked(w, err)
+		return
+	}
+
 	hasher := crypto.NewPasswordHasher()
 	if err := hasher.Verify(u.Password, req.Password); err != nil {
+		if err := h.lockout.Failure(u.ID); err != nil {
+			logger.Ctx(r.Context(), h.logger).Warn("account locked after repeated failures", zap.String("username", u.Username))
+			h.sendLocked(w, err)
+			return
+		}
 		h.sendError(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "Invalid username or password")
 		return
 	}
+	h.lockout.Success(u.ID)
 
-	if strings.TrimSpace(u.WalletAddress) == "" {
+	// 令牌主体为账户的主钱包；只关联了邮箱的账户使用邮箱令牌
+	wallet := strings.TrimSpace(u.WalletAddress)
+	emailAddr := strings.TrimSpace(u.Email)
+	if wallet == "" && emailAddr == "" {
 		h.sendError(w, http.StatusBadRequest, "NO_WALLET", "Wallet address not bound")
 		return
 	}
@@ -356,28 +424,42 @@ func (h *Web3Handler) HandlePasswordLogin(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	accessToken, err := h.web3Auth.GenerateAccessToken(u.WalletAddress)
+	subject, subjectType := wallet, "wallet"
+	if wallet == "" {
+		subject, subjectType = emailAddr, "email"
+	}
+
+	// 启用或被要求启用两步验证时只返回挑战令牌，由 /auth/mfa/verify 完成登录
+	challenge, err := h.mfaHandler.Challenge(ctx, u, subjectType)
 	if err != nil {
-		h.logger.Error("failed to generate access token", zap.Error(err))
-		h.sendError(w, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED", "Failed to generate token")
+		logger.Ctx(r.Context(), h.logger).Error("failed to check mfa", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return
+	}
+	if challenge != nil {
+		h.sendSDKSuccess(w, challenge)
 		return
 	}
 
-	refreshToken, err := h.web3Auth.GenerateRefreshToken(u.WalletAddress)
+	middleware.SetAuditActor(ctx, u)
+	tokens, err := h.web3Auth.IssueTokens(ctx, u, subject, subjectType, session.MethodPassword, sessionClientFrom(r))
 	if err != nil {
-		h.logger.Error("failed to generate refresh token", zap.Error(err))
-		h.sendError(w, http.StatusInternalServerError, "REFRESH_TOKEN_FAILED", "Failed to generate refresh token")
+		logger.Ctx(r.Context(), h.logger).Error("failed to issue tokens", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED", "Failed to generate token")
 		return
 	}
 
-	h.setRefreshCookie(w, r, refreshToken.Value, refreshToken.ExpiresAt)
+	h.setRefreshCookie(w, r, tokens.Refresh.Value, tokens.Refresh.ExpiresAt)
 
 	data := map[string]interface{}{
-		"address":          u.WalletAddress,
+		"address":          wallet,
 		"username":         u.Username,
-		"token":            accessToken.Value,
-		"expiresAt":        accessToken.ExpiresAt.UnixMilli(),
-		"refreshExpiresAt": refreshToken.ExpiresAt.UnixMilli(),
+		"token":            tokens.Access.Value,
+		"expiresAt":        tokens.Access.ExpiresAt.UnixMilli(),
+		"refreshExpiresAt": tokens.Refresh.ExpiresAt.UnixMilli(),
+	}
+	if wallet == "" {
+		data["email"] = emailAddr
 	}
 
 	h.sendSDKSuccess(w, data)
@@ -397,7 +479,7 @@ func (h *Web3Handler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 
 	subject, subjectType, err := h.web3Auth.VerifyRefreshTokenWithSubject(cookie.Value)
 	if err != nil {
-		h.logger.Warn("invalid refresh token", zap.Error(err))
+		logger.Ctx(r.Context(), h.logger).Warn("invalid refresh token", zap.Error(err))
 		h.sendError(w, http.StatusUnauthorized, "INVALID_REFRESH_TOKEN", "Invalid refresh token")
 		return
 	}
@@ -406,6 +488,17 @@ func (h *Web3Handler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var currentUser *user.User
 	switch subjectType {
+	case "passkey":
+		currentUser, err = h.userRepo.FindByID(ctx, subject)
+		if err != nil {
+			if err == user.ErrUserNotFound {
+				h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "User not found")
+				return
+			}
+			logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.String("user_id", subject), zap.Error(err))
+			h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+			return
+		}
 	case "email":
 		currentUser, err = h.userRepo.FindByEmail(ctx, subject)
 		if err != nil {
@@ -413,7 +506,7 @@ func (h *Web3Handler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 				h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "Email not registered")
 				return
 			}
-			h.logger.Error("failed to find user", zap.String("email", subject), zap.Error(err))
+			logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.String("email", subject), zap.Error(err))
 			h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
 			return
 		}
@@ -424,7 +517,7 @@ func (h *Web3Handler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 				h.sendError(w, http.StatusNotFound, "USER_NOT_FOUND", "Wallet address not registered")
 				return
 			}
-			h.logger.Error("failed to find user", zap.String("address", subject), zap.Error(err))
+			logger.Ctx(r.Context(), h.logger).Error("failed to find user", zap.String("address", subject), zap.Error(err))
 			h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
 			return
 		}
@@ -435,51 +528,35 @@ func (h *Web3Handler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if subjectType == "email" {
-		accessToken, err := h.web3Auth.GenerateAccessTokenForEmail(subject)
-		if err != nil {
-			h.logger.Error("failed to generate access token", zap.Error(err))
+	// 轮换 refresh token；重放已轮换的 refresh token 会吊销整个会话
+	middleware.SetAuditActor(ctx, currentUser)
+	tokens, err := h.web3Auth.RefreshTokens(ctx, cookie.Value, currentUser, sessionClientFrom(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, session.ErrRefreshTokenReused), errors.Is(err, session.ErrSessionRevoked):
+			logger.Ctx(r.Context(), h.logger).Warn("refresh rejected", zap.String("subject", subject), zap.Error(err))
+			h.clearRefreshCookie(w, r)
+			h.sendError(w, http.StatusUnauthorized, "SESSION_REVOKED", "Session has been revoked")
+		case errors.Is(err, authDomain.ErrInvalidToken), errors.Is(err, authDomain.ErrTokenExpired):
+			h.sendError(w, http.StatusUnauthorized, "INVALID_REFRESH_TOKEN", "Invalid refresh token")
+		default:
+			logger.Ctx(r.Context(), h.logger).Error("failed to refresh tokens", zap.Error(err))
 			h.sendError(w, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED", "Failed to generate token")
-			return
-		}
-		refreshToken, err := h.web3Auth.GenerateRefreshTokenForEmail(subject)
-		if err != nil {
-			h.logger.Error("failed to generate refresh token", zap.Error(err))
-			h.sendError(w, http.StatusInternalServerError, "REFRESH_TOKEN_FAILED", "Failed to generate refresh token")
-			return
-		}
-		h.setRefreshCookie(w, r, refreshToken.Value, refreshToken.ExpiresAt)
-
-		data := map[string]interface{}{
-			"address":          subject,
-			"token":            accessToken.Value,
-			"expiresAt":        accessToken.ExpiresAt.UnixMilli(),
-			"refreshExpiresAt": refreshToken.ExpiresAt.UnixMilli(),
 		}
-		h.sendSDKSuccess(w, data)
 		return
 	}
 
-	accessToken, err := h.web3Auth.GenerateAccessToken(subject)
-	if err != nil {
-		h.logger.Error("failed to generate access token", zap.Error(err))
-		h.sendError(w, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED", "Failed to generate token")
-		return
-	}
-	refreshToken, err := h.web3Auth.GenerateRefreshToken(subject)
-	if err != nil {
-		h.logger.Error("failed to generate refresh token", zap.Error(err))
-		h.sendError(w, http.StatusInternalServerError, "REFRESH_TOKEN_FAILED", "Failed to generate refresh token")
-		return
-	}
-
-	h.setRefreshCookie(w, r, refreshToken.Value, refreshToken.ExpiresAt)
+	h.setRefreshCookie(w, r, tokens.Refresh.Value, tokens.Refresh.ExpiresAt)
 
+	address := subject
+	if subjectType == "passkey" {
+		address = currentUser.WalletAddress
+	}
 	data := map[string]interface{}{
-		"address":          subject,
-		"token":            accessToken.Value,
-		"expiresAt":        accessToken.ExpiresAt.UnixMilli(),
-		"refreshExpiresAt": refreshToken.ExpiresAt.UnixMilli(),
+		"address":          address,
+		"token":            tokens.Access.Value,
+		"expiresAt":        tokens.Access.ExpiresAt.UnixMilli(),
+		"refreshExpiresAt": tokens.Refresh.ExpiresAt.UnixMilli(),
 	}
 
 	h.sendSDKSuccess(w, data)
@@ -491,10 +568,54 @@ func (h *Web3Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 吊销会话：优先使用 refresh cookie，其次使用 Authorization 中的 access token
+	token := ""
+	if cookie, err := r.Cookie(refreshTokenCookieName); err == nil {
+		token = cookie.Value
+	}
+	if token == "" {
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = strings.TrimSpace(bearer)
+		}
+	}
+	if err := h.web3Auth.RevokeToken(r.Context(), token); err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to revoke session", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to revoke session")
+		return
+	}
+
 	h.clearRefreshCookie(w, r)
 	h.sendSDKSuccess(w, map[string]bool{"logout": true})
 }
 
+// sessionClientFrom 提取登录会话的客户端信息；客户端可通过 X-Device-Name 命名设备
+func sessionClientFrom(r *http.Request) session.Client {
+	deviceName := []rune(strings.TrimSpace(r.Header.Get("X-Device-Name")))
+	if len(deviceName) > 255 {
+		deviceName = deviceName[:255]
+	}
+	return session.Client{
+		DeviceName: string(deviceName),
+		IP:         middleware.GetClientIP(r),
+		UserAgent:  r.UserAgent(),
+	}
+}
+
+// siweRequestFrom 提取签发/校验 EIP-4361 消息所需的请求上下文
+func siweRequestFrom(r *http.Request) auth.SIWERequest {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := r.Header.Get("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	return auth.SIWERequest{
+		Scheme: scheme,
+		Origin: r.Header.Get("Origin"),
+	}
+}
+
 func (h *Web3Handler) ensureAssetSpaces(u *user.User) error {
 	if h == nil || h.assetSpaceManager == nil || u == nil {
 		return nil
@@ -600,3 +721,12 @@ func (h *Web3Handler) sendSDKResponse(w http.ResponseWriter, status int, code in
 func (h *Web3Handler) sendError(w http.ResponseWriter, status int, code, message string) {
 	h.sendSDKResponse(w, status, status, message, nil)
 }
+
+// sendLocked 账户锁定时返回 429 与 Retry-After
+func (h *Web3Handler) sendLocked(w http.ResponseWriter, err error) {
+	var locked *authDomain.LockedError
+	if errors.As(err, &locked) {
+		middleware.WriteRetryAfter(w, locked.RetryAfter)
+	}
+	h.sendError(w, http.StatusTooManyRequests, "ACCOUNT_LOCKED", "Too many failed attempts, try again later")
+}
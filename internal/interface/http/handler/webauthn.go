@@ -0,0 +1,428 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/application/assetspace"
+	"github.com/yeying-community/warehouse/internal/application/service"
+	authDomain "github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/domain/webauthn"
+	infraAuth "github.com/yeying-community/warehouse/internal/infrastructure/auth"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
+	"go.uber.org/zap"
+)
+
+// WebAuthnHandler 通行密钥处理器
+// 注册与登录（/api/v1/public/auth/webauthn/*）使用 SDK 响应格式，与其他登录接口一致；
+// 通行密钥管理接口使用普通 JSON 响应。
+type WebAuthnHandler struct {
+	passkeyAuth       *infraAuth.WebAuthnAuthenticator
+	passkeyService    *service.PasskeyService
+	web3Auth          *infraAuth.Web3Authenticator
+	assetSpaceManager *assetspace.Manager
+	logger            *zap.Logger
+}
+
+// NewWebAuthnHandler 创建通行密钥处理器
+func NewWebAuthnHandler(
+	passkeyAuth *infraAuth.WebAuthnAuthenticator,
+	passkeyService *service.PasskeyService,
+	web3Auth *infraAuth.Web3Authenticator,
+	assetSpaceManager *assetspace.Manager,
+	logger *zap.Logger,
+) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		passkeyAuth:       passkeyAuth,
+		passkeyService:    passkeyService,
+		web3Auth:          web3Auth,
+		assetSpaceManager: assetSpaceManager,
+		logger:            logger,
+	}
+}
+
+// publicKeyCredential 浏览器 PublicKeyCredential 的 JSON 形式（字节字段为 base64url）
+type publicKeyCredential struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+		AuthenticatorData string   `json:"authenticatorData"`
+		Signature         string   `json:"signature"`
+		UserHandle        string   `json:"userHandle"`
+	} `json:"response"`
+}
+
+// PasskeyResponse 通行密钥响应
+type PasskeyResponse struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Algorithm      int      `json:"algorithm"`
+	AAGUID         string   `json:"aaguid"`
+	Transports     []string `json:"transports"`
+	BackupEligible bool     `json:"backup_eligible"`
+	BackupState    bool     `json:"backup_state"`
+	CreatedAt      string   `json:"created_at"`
+	LastUsedAt     string   `json:"last_used_at,omitempty"`
+}
+
+func toPasskeyResponse(c *webauthn.Credential) PasskeyResponse {
+	resp := PasskeyResponse{
+		ID:             c.ID,
+		Name:           c.Name,
+		Algorithm:      c.Algorithm,
+		AAGUID:         c.AAGUID,
+		Transports:     c.Transports,
+		BackupEligible: c.BackupEligible,
+		BackupState:    c.BackupState,
+		CreatedAt:      c.CreatedAt.Format(timeLayout),
+	}
+	if resp.Transports == nil {
+		resp.Transports = []string{}
+	}
+	if c.LastUsedAt != nil {
+		resp.LastUsedAt = c.LastUsedAt.Format(timeLayout)
+	}
+	return resp
+}
+
+// HandleRegisterOptions 获取注册参数
+// 已登录时为当前账户添加通行密钥；未登录时注册即创建新账户（需开启 auto_create_on_register）。
+func (h *WebAuthnHandler) HandleRegisterOptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+		return
+	}
+	u, _ := middleware.GetUserFromContext(r.Context())
+
+	sessionID, options, err := h.passkeyAuth.BeginRegistration(r.Context(), u)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignUpDisabled) {
+			h.sendError(w, http.StatusForbidden, "SIGN_UP_DISABLED", "Passkey sign-up is disabled")
+			return
+		}
+		logger.Ctx(r.Context(), h.logger).Error("failed to begin passkey registration", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return
+	}
+	h.sendSDKSuccess(w, map[string]any{
+		"sessionId": sessionID,
+		"publicKey": options,
+	})
+}
+
+// HandleRegisterVerify 提交注册结果
+// body: {"sessionId": "...", "name": "MacBook Touch ID", "credential": PublicKeyCredential}
+// 新建账户时同时签发登录令牌。
+func (h *WebAuthnHandler) HandleRegisterVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+		return
+	}
+	var req struct {
+		SessionID  string              `json:"sessionId"`
+		Name       string              `json:"name"`
+		Credential publicKeyCredential `json:"credential"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+	clientData, err1 := webauthn.DecodeID(req.Credential.Response.ClientDataJSON)
+	attestation, err2 := webauthn.DecodeID(req.Credential.Response.AttestationObject)
+	if req.SessionID == "" || err1 != nil || err2 != nil || len(clientData) == 0 || len(attestation) == 0 {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "sessionId and credential are required")
+		return
+	}
+
+	// 仪式在获取参数时已绑定账户（或预分配的新账户），这里无需再次认证
+	u, cred, created, err := h.passkeyAuth.FinishRegistration(r.Context(), req.SessionID, clientData, attestation,
+		req.Credential.Response.Transports, req.Name)
+	if err != nil {
+		h.sendPasskeyError(w, err)
+		return
+	}
+
+	data := map[string]any{
+		"created":  created,
+		"username": u.Username,
+		"passkey":  toPasskeyResponse(cred),
+	}
+	if created {
+		if err := h.ensureAssetSpaces(u); err != nil {
+			h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to initialize user spaces")
+			return
+		}
+		if !h.issueTokens(w, r, u, data) {
+			return
+		}
+	}
+	h.sendSDKSuccess(w, data)
+}
+
+// HandleLoginOptions 获取登录参数
+// body: {"username": "..."}，用户名可省略，省略时由认证器列出可用的通行密钥。
+func (h *WebAuthnHandler) HandleLoginOptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+			return
+		}
+	}
+
+	sessionID, options, err := h.passkeyAuth.BeginLogin(r.Context(), strings.TrimSpace(req.Username))
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to begin passkey login", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+		return
+	}
+	h.sendSDKSuccess(w, map[string]any{
+		"sessionId": sessionID,
+		"publicKey": options,
+	})
+}
+
+// HandleLoginVerify 提交登录断言，成功后签发与钱包登录相同的 access / refresh token
+// body: {"sessionId": "...", "credential": PublicKeyCredential}
+func (h *WebAuthnHandler) HandleLoginVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Only POST method is allowed")
+		return
+	}
+	var req struct {
+		SessionID  string              `json:"sessionId"`
+		Credential publicKeyCredential `json:"credential"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		return
+	}
+	resp := req.Credential.Response
+	clientData, err1 := webauthn.DecodeID(resp.ClientDataJSON)
+	authData, err2 := webauthn.DecodeID(resp.AuthenticatorData)
+	signature, err3 := webauthn.DecodeID(resp.Signature)
+	userHandle, err4 := webauthn.DecodeID(resp.UserHandle)
+	if req.SessionID == "" || req.Credential.ID == "" || err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", "sessionId and credential are required")
+		return
+	}
+
+	u, err := h.passkeyAuth.Authenticate(r.Context(), &authDomain.WebAuthnCredentials{
+		SessionID:         req.SessionID,
+		CredentialID:      req.Credential.ID,
+		ClientDataJSON:    clientData,
+		AuthenticatorData: authData,
+		Signature:         signature,
+		UserHandle:        userHandle,
+	})
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Warn("passkey login failed", zap.String("credential_id", req.Credential.ID), zap.Error(err))
+		h.sendPasskeyError(w, err)
+		return
+	}
+	if err := h.ensureAssetSpaces(u); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to initialize user spaces")
+		return
+	}
+
+	data := map[string]any{
+		"username": u.Username,
+	}
+	if !h.issueTokens(w, r, u, data) {
+		return
+	}
+	h.sendSDKSuccess(w, data)
+}
+
+// HandleList 获取我的通行密钥
+func (h *WebAuthnHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	items, err := h.passkeyService.List(r.Context(), u)
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to list passkeys", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "Failed to list passkeys")
+		return
+	}
+	resp := make([]PasskeyResponse, 0, len(items))
+	for _, c := range items {
+		resp = append(resp, toPasskeyResponse(c))
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"items": resp})
+}
+
+// HandleDelete 删除通行密钥
+// body: {"id": "..."}
+func (h *WebAuthnHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	u, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	id := strings.TrimSpace(req.ID)
+	if id == "" {
+		h.writeError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	if err := h.passkeyService.Delete(r.Context(), u, id); err != nil {
+		switch {
+		case errors.Is(err, webauthn.ErrCredentialNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, webauthn.ErrLastCredential):
+			h.writeError(w, http.StatusConflict, err.Error())
+		default:
+			logger.Ctx(r.Context(), h.logger).Error("failed to delete passkey", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "Failed to delete passkey")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+// issueTokens 以用户 ID 为主体签发令牌并写入响应数据；失败时已写出错误响应
+func (h *WebAuthnHandler) issueTokens(w http.ResponseWriter, r *http.Request, u *user.User, data map[string]any) bool {
+	middleware.SetAuditActor(r.Context(), u)
+	tokens, err := h.web3Auth.IssueTokens(r.Context(), u, u.ID, "passkey", session.MethodPasskey, sessionClientFrom(r))
+	if err != nil {
+		logger.Ctx(r.Context(), h.logger).Error("failed to issue tokens", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "TOKEN_GENERATION_FAILED", "Failed to generate token")
+		return false
+	}
+	h.setRefreshCookie(w, r, tokens.Refresh.Value, tokens.Refresh.ExpiresAt)
+	data["address"] = u.WalletAddress
+	data["token"] = tokens.Access.Value
+	data["expiresAt"] = tokens.Access.ExpiresAt.UnixMilli()
+	data["refreshExpiresAt"] = tokens.Refresh.ExpiresAt.UnixMilli()
+	return true
+}
+
+func (h *WebAuthnHandler) sendPasskeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, authDomain.ErrChallengeExpired):
+		h.sendError(w, http.StatusUnauthorized, "CHALLENGE_EXPIRED", "Challenge expired or already used")
+	case errors.Is(err, webauthn.ErrCredentialExists):
+		h.sendError(w, http.StatusConflict, "PASSKEY_EXISTS", err.Error())
+	case errors.Is(err, webauthn.ErrInvalidName):
+		h.sendError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+	case errors.Is(err, authDomain.ErrInvalidCredentials),
+		errors.Is(err, user.ErrUserNotFound),
+		errors.Is(err, webauthn.ErrInvalidClientData),
+		errors.Is(err, webauthn.ErrInvalidOrigin),
+		errors.Is(err, webauthn.ErrChallengeMismatch),
+		errors.Is(err, webauthn.ErrInvalidAuthData),
+		errors.Is(err, webauthn.ErrRPIDMismatch),
+		errors.Is(err, webauthn.ErrUserNotPresent),
+		errors.Is(err, webauthn.ErrUserNotVerified),
+		errors.Is(err, webauthn.ErrInvalidPublicKey),
+		errors.Is(err, webauthn.ErrUnsupportedAlgorithm),
+		errors.Is(err, webauthn.ErrInvalidSignature),
+		errors.Is(err, webauthn.ErrUserHandleMismatch),
+		errors.Is(err, webauthn.ErrCloneDetected):
+		h.sendError(w, http.StatusUnauthorized, "INVALID_PASSKEY", err.Error())
+	default:
+		h.logger.Error("passkey ceremony failed", zap.Error(err))
+		h.sendError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to process request")
+	}
+}
+
+func (h *WebAuthnHandler) ensureAssetSpaces(u *user.User) error {
+	if h == nil || h.assetSpaceManager == nil || u == nil {
+		return nil
+	}
+	if err := h.assetSpaceManager.EnsureForUser(u); err != nil {
+		h.logger.Error("failed to ensure user asset spaces",
+			zap.String("username", u.Username),
+			zap.String("directory", u.Directory),
+			zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (h *WebAuthnHandler) setRefreshCookie(w http.ResponseWriter, r *http.Request, token string, expiresAt time.Time) {
+	secure := isSecureRequest(r)
+	maxAge := int(time.Until(expiresAt).Seconds())
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshTokenCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   secure,
+	})
+}
+
+func (h *WebAuthnHandler) sendSDKSuccess(w http.ResponseWriter, data interface{}) {
+	h.writeJSON(w, http.StatusOK, sdkResponse{
+		Code:      0,
+		Message:   "ok",
+		Data:      data,
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+func (h *WebAuthnHandler) sendError(w http.ResponseWriter, status int, code, message string) {
+	h.writeJSON(w, status, sdkResponse{
+		Code:      status,
+		Message:   message,
+		Timestamp: time.Now().UnixMilli(),
+	})
+}
+
+// writeJSON 写入 JSON 响应
+func (h *WebAuthnHandler) writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("failed to encode response", zap.Error(err))
+	}
+}
+
+// writeError 写入错误响应
+func (h *WebAuthnHandler) writeError(w http.ResponseWriter, code int, message string) {
+	h.writeJSON(w, code, map[string]interface{}{
+		"error":   message,
+		"code":    code,
+		"success": false,
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/yeying-community/warehouse/internal/application/service"
 	"github.com/yeying-community/warehouse/internal/domain/quota"
 	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
 )
@@ -46,13 +47,13 @@ func (h *WebDAVHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	// 从上下文获取用户信息（用于日志和监控）
 	u, ok := middleware.GetUserFromContext(r.Context())
 	if !ok {
-		h.logger.Error("user not found in context")
+		logger.Ctx(r.Context(), h.logger).Error("user not found in context")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// 记录访问日志
-	h.logger.Debug("webdav request",
+	logger.Ctx(r.Context(), h.logger).Debug("webdav request",
 		zap.String("username", u.Username),
 		zap.String("method", r.Method),
 		zap.String("path", r.URL.Path),
@@ -1,64 +1,57 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"strings"
 
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"go.uber.org/zap"
 )
 
-// AdminMiddleware restricts access to configured admin wallet addresses.
+// Authorizer checks whether a user holds an admin permission.
+type Authorizer interface {
+	Authorize(ctx context.Context, u *user.User, perm rbac.Permission) error
+}
+
+// AdminMiddleware restricts admin endpoints to users holding a specific permission.
 type AdminMiddleware struct {
-	allowed map[string]struct{}
-	logger  *zap.Logger
+	authorizer Authorizer
+	logger     *zap.Logger
 }
 
 // NewAdminMiddleware creates a new admin middleware.
-func NewAdminMiddleware(addresses []string, logger *zap.Logger) *AdminMiddleware {
-	allowed := make(map[string]struct{}, len(addresses))
-	for _, raw := range addresses {
-		addr := strings.ToLower(strings.TrimSpace(raw))
-		if addr == "" {
-			continue
-		}
-		allowed[addr] = struct{}{}
-	}
+func NewAdminMiddleware(authorizer Authorizer, logger *zap.Logger) *AdminMiddleware {
 	return &AdminMiddleware{
-		allowed: allowed,
-		logger:  logger,
+		authorizer: authorizer,
+		logger:     logger,
 	}
 }
 
-// Handle enforces admin access.
-func (m *AdminMiddleware) Handle(next http.Handler) http.Handler {
+// Handle enforces that the authenticated user holds perm.
+func (m *AdminMiddleware) Handle(perm rbac.Permission, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		u, ok := GetUserFromContext(r.Context())
 		if !ok {
-			m.logger.Warn("admin access denied: user not in context")
+			logger.Ctx(r.Context(), m.logger).Warn("admin access denied: user not in context")
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		if len(m.allowed) == 0 {
-			m.logger.Warn("admin access denied: no admin addresses configured",
-				zap.String("username", u.Username))
-			http.Error(w, "Forbidden", http.StatusForbidden)
-			return
-		}
-
-		addr := strings.ToLower(strings.TrimSpace(u.WalletAddress))
-		if addr == "" {
-			m.logger.Warn("admin access denied: missing wallet address",
-				zap.String("username", u.Username))
-			http.Error(w, "Forbidden", http.StatusForbidden)
-			return
-		}
-
-		if _, ok := m.allowed[addr]; !ok {
-			m.logger.Warn("admin access denied: address not allowed",
+		if err := m.authorizer.Authorize(r.Context(), u, perm); err != nil {
+			if errors.Is(err, rbac.ErrPermissionDenied) {
+				logger.Ctx(r.Context(), m.logger).Warn("admin access denied: missing permission",
+					zap.String("username", u.Username),
+					zap.String("permission", string(perm)))
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			logger.Ctx(r.Context(), m.logger).Error("failed to check admin permission",
 				zap.String("username", u.Username),
-				zap.String("wallet_address", addr))
-			http.Error(w, "Forbidden", http.StatusForbidden)
+				zap.Error(err))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
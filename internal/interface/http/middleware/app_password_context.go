@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/yeying-community/warehouse/internal/domain/apppassword"
+)
+
+// AppPasswordContextKey 应用专用密码上下文键
+const AppPasswordContextKey contextKey = "app_password"
+
+// WithAppPassword 记录请求使用的应用专用密码
+func WithAppPassword(ctx context.Context, p *apppassword.AppPassword) context.Context {
+	if ctx == nil || p == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, AppPasswordContextKey, p)
+}
+
+// GetAppPassword 获取请求使用的应用专用密码
+func GetAppPassword(ctx context.Context) (*apppassword.AppPassword, bool) {
+	p, ok := ctx.Value(AppPasswordContextKey).(*apppassword.AppPassword)
+	return p, ok
+}
+
+// RejectAppPassword 拒绝应用专用密码登录的请求
+// 应用专用密码只用于 WebDAV，不能访问账户、分享等管理接口。
+func RejectAppPassword(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetAppPassword(r.Context()); ok {
+			http.Error(w, "App passwords can only be used for WebDAV", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/domain/audit"
+	"github.com/yeying-community/warehouse/internal/domain/user"
+	"go.uber.org/zap"
+)
+
+// AuditContextKey 审计事件上下文键
+const AuditContextKey contextKey = "audit"
+
+// adminAuditPrefix 管理接口路径前缀，管理操作的审计动作由其后的路径推导
+const adminAuditPrefix = "/api/v1/public/admin/"
+
+// AuditRecorder 审计事件记录器
+type AuditRecorder interface {
+	Record(ctx context.Context, e *audit.Event)
+}
+
+// AuditMiddleware 审计中间件
+// 位于认证中间件外层：请求结束后根据响应状态码记录一条审计事件，
+// 认证中间件与处理器通过 SetAuditActor/SetAuditTarget/SetAuditDetail 补充操作者与目标。
+type AuditMiddleware struct {
+	recorder AuditRecorder
+	logger   *zap.Logger
+}
+
+// NewAuditMiddleware 创建审计中间件
+func NewAuditMiddleware(recorder AuditRecorder, logger *zap.Logger) *AuditMiddleware {
+	return &AuditMiddleware{
+		recorder: recorder,
+		logger:   logger,
+	}
+}
+
+// defaultTargetFields 未指定时作为审计目标的请求字段，按优先级排列
+// 只包含路径、账户、记录 ID 等标识；令牌、密码、验证码等凭证字段不能出现在这里，
+// 审计日志对所有 audit.read 持有者可见，记录下来的重置链接或登录令牌可以被直接使用。
+var defaultTargetFields = []string{"path", "from", "username", "hash", "id", "name", "email", "address"}
+
+// Handle 记录固定动作的接口调用
+// targetFields 为该路由允许作为审计目标的请求字段，未指定时使用 defaultTargetFields。
+func (m *AuditMiddleware) Handle(action string, next http.Handler, targetFields ...string) http.Handler {
+	if m == nil || m.recorder == nil {
+		return next
+	}
+	if len(targetFields) == 0 {
+		targetFields = defaultTargetFields
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		m.serve(w, r, next, m.newEvent(r, action), targetFields)
+	})
+}
+
+// HandleAdmin 记录管理接口调用，动作为 admin.<路径>，如 admin.users.reset-password
+func (m *AuditMiddleware) HandleAdmin(next http.Handler) http.Handler {
+	if m == nil || m.recorder == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		name := strings.Trim(strings.TrimPrefix(r.URL.Path, adminAuditPrefix), "/")
+		m.serve(w, r, next, m.newEvent(r, "admin."+strings.ReplaceAll(name, "/", ".")), defaultTargetFields)
+	})
+}
+
+// HandleWebDAV 记录 WebDAV 写操作（webdav.put、webdav.move 等）与认证失败（auth.webdav）
+// 读操作成功时不记录，避免审计日志被大量浏览请求淹没。
+func (m *AuditMiddleware) HandleWebDAV(prefix string, next http.Handler) http.Handler {
+	if m == nil || m.recorder == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutation := isWebDAVMutation(r.Method)
+		if !mutation && !hasCredentials(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		action := "auth.webdav"
+		if mutation {
+			action = "webdav." + strings.ToLower(r.Method)
+		}
+		e := m.newEvent(r, action)
+		e.Target = webdavTarget(prefix, r.URL.Path)
+		if dest := r.Header.Get("Destination"); dest != "" {
+			if u, err := url.Parse(dest); err == nil {
+				dest = u.Path
+			}
+			e.Detail = "destination=" + webdavTarget(prefix, dest)
+		}
+
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), AuditContextKey, e)))
+
+		if !mutation && wrapped.statusCode != http.StatusUnauthorized && wrapped.statusCode != http.StatusTooManyRequests {
+			return
+		}
+		m.finish(r, e, wrapped.statusCode)
+	})
+}
+
+func (m *AuditMiddleware) serve(w http.ResponseWriter, r *http.Request, next http.Handler, e *audit.Event, targetFields []string) {
+	e.Target, e.Detail = requestTarget(r, targetFields)
+
+	wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), AuditContextKey, e)))
+
+	m.finish(r, e, wrapped.statusCode)
+}
+
+func (m *AuditMiddleware) newEvent(r *http.Request, action string) *audit.Event {
+	return &audit.Event{
+		CreatedAt: time.Now(),
+		Action:    action,
+		IP:        GetClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// finish 补全操作者与结果后写入审计事件
+func (m *AuditMiddleware) finish(r *http.Request, e *audit.Event, status int) {
+	if e.Actor == "" {
+		// 未认证的请求（登录接口、认证失败）记录提交的账户
+		e.Actor = loginAccount(r)
+	}
+	if e.Actor == "" && status == http.StatusUnauthorized {
+		// 未携带任何凭证的 401 只是客户端的认证质询，不记录
+		return
+	}
+	e.Status = status
+	e.Outcome = audit.OutcomeFromStatus(status)
+	m.recorder.Record(context.WithoutCancel(r.Context()), e)
+}
+
+// SetAuditActor 记录当前请求的操作者及其 UCAN 应用
+func SetAuditActor(ctx context.Context, u *user.User) {
+	e, ok := ctx.Value(AuditContextKey).(*audit.Event)
+	if !ok || u == nil {
+		return
+	}
+	e.ActorID = u.ID
+	e.Actor = u.Username
+	if ucan, ok := GetUcanContext(ctx); ok && len(ucan.AppCaps) > 0 {
+		apps := make([]string, 0, len(ucan.AppCaps))
+		for app := range ucan.AppCaps {
+			apps = append(apps, app)
+		}
+		sort.Strings(apps)
+		e.AppID = strings.Join(apps, ",")
+	}
+}
+
+// SetAuditTarget 覆盖当前请求的审计目标
+func SetAuditTarget(ctx context.Context, target string) {
+	if e, ok := ctx.Value(AuditContextKey).(*audit.Event); ok {
+		e.Target = target
+	}
+}
+
+// SetAuditDetail 覆盖当前请求的审计附加信息
+func SetAuditDetail(ctx context.Context, detail string) {
+	if e, ok := ctx.Value(AuditContextKey).(*audit.Event); ok {
+		e.Detail = detail
+	}
+}
+
+// requestTarget 从 JSON 请求体或查询参数中提取审计目标与附加信息
+// 目标取 fields 中第一个非空的字段，不在 fields 中的字段（密码、令牌、验证码等）不会被读取。
+func requestTarget(r *http.Request, fields []string) (string, string) {
+	var body map[string]any
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		peekJSON(r, &body)
+	}
+	query := r.URL.Query()
+	field := func(name string) string {
+		if v, ok := body[name].(string); ok && strings.TrimSpace(v) != "" {
+			return v
+		}
+		return ""
+	}
+
+	var target string
+	for _, name := range fields {
+		v := field(name)
+		if v == "" && name == "path" {
+			v = query.Get("path")
+		}
+		if v = strings.TrimSpace(v); v != "" {
+			target = v
+			break
+		}
+	}
+	var detail []string
+	for _, kv := range [][2]string{
+		{"share_id", firstNonEmpty(field("shareId"), query.Get("shareId"))},
+		{"to", field("to")},
+		{"role", field("role")},
+		{"target_address", field("targetAddress")},
+	} {
+		if v := strings.TrimSpace(kv[1]); v != "" {
+			detail = append(detail, kv[0]+"="+v)
+		}
+	}
+	return target, strings.Join(detail, " ")
+}
+
+func isWebDAVMutation(method string) bool {
+	switch method {
+	case http.MethodPut, http.MethodDelete, "MKCOL", "MOVE", "COPY", "PROPPATCH":
+		return true
+	}
+	return false
+}
+
+func hasCredentials(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return true
+	}
+	_, err := r.Cookie("authToken")
+	return err == nil
+}
+
+// webdavTarget 将请求路径转换为相对 WebDAV 根目录的路径
+func webdavTarget(prefix, p string) string {
+	if prefix != "/" {
+		p = strings.TrimPrefix(p, strings.TrimSuffix(prefix, "/"))
+	}
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return p
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yeying-community/warehouse/internal/domain/audit"
+	"go.uber.org/zap"
+)
+
+type stubAuditRecorder struct {
+	events []*audit.Event
+}
+
+func (s *stubAuditRecorder) Record(_ context.Context, e *audit.Event) {
+	s.events = append(s.events, e)
+}
+
+func TestAuditDoesNotRecordResetToken(t *testing.T) {
+	recorder := &stubAuditRecorder{}
+	m := NewAuditMiddleware(recorder, zap.NewNop())
+	// 密码过短时重置在消费令牌之前失败，令牌仍然有效
+	var body string
+	handler := m.Handle("auth.password_reset", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		http.Error(w, "password too short", http.StatusBadRequest)
+	}))
+
+	const token = "eyJhbGciOiJIUzI1NiJ9.reset.signature"
+	payload := `{"token":"` + token + `","password":"x"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/public/auth/password/reset", strings.NewReader(payload))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if body != payload {
+		t.Fatalf("handler should receive the full body, got %q", body)
+	}
+	if len(recorder.events) != 1 {
+		t.Fatalf("expected one event, got %d", len(recorder.events))
+	}
+	e := recorder.events[0]
+	if strings.Contains(e.Target, token) || strings.Contains(e.Detail, token) {
+		t.Fatalf("reset token leaked into audit event: target=%q detail=%q", e.Target, e.Detail)
+	}
+	if e.Status != http.StatusBadRequest {
+		t.Fatalf("unexpected status %d", e.Status)
+	}
+}
+
+func TestAuditRecordsAllowedTargetFields(t *testing.T) {
+	recorder := &stubAuditRecorder{}
+	m := NewAuditMiddleware(recorder, zap.NewNop())
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/public/share/revoke", strings.NewReader(`{"token":"share-token"}`))
+	m.Handle("share.revoke", ok, "token").ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/public/share/create", strings.NewReader(`{"path":"/docs/a.txt","token":"ignored"}`))
+	m.Handle("share.create", ok).ServeHTTP(httptest.NewRecorder(), req)
+
+	if got := recorder.events[0].Target; got != "share-token" {
+		t.Fatalf("explicit target field not recorded, got %q", got)
+	}
+	if got := recorder.events[1].Target; got != "/docs/a.txt" {
+		t.Fatalf("default target fields not applied, got %q", got)
+	}
+}
@@ -2,11 +2,17 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/yeying-community/warehouse/internal/domain/auth"
 	"github.com/yeying-community/warehouse/internal/domain/user"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"github.com/yeying-community/warehouse/internal/infrastructure/metrics"
+	"github.com/yeying-community/warehouse/internal/infrastructure/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -49,7 +55,7 @@ func (m *AuthMiddleware) Handle(next http.Handler) http.Handler {
 		credentials := m.extractCredentials(r)
 		if credentials == nil {
 			if m.required {
-				m.logger.Debug("no credentials provided")
+				logger.Ctx(r.Context(), m.logger).Debug("no credentials provided")
 				m.sendUnauthorized(w, r, "Authentication required")
 				return
 			}
@@ -61,7 +67,13 @@ func (m *AuthMiddleware) Handle(next http.Handler) http.Handler {
 		// 尝试使用所有认证器进行认证
 		u, authenticator, err := m.authenticate(ctx, credentials)
 		if err != nil {
-			m.logger.Warn("authentication failed", zap.Error(err))
+			logger.Ctx(r.Context(), m.logger).Warn("authentication failed", zap.Error(err))
+			var locked *auth.LockedError
+			if errors.As(err, &locked) {
+				WriteRetryAfter(w, locked.RetryAfter)
+				http.Error(w, "Account temporarily locked", http.StatusTooManyRequests)
+				return
+			}
 			m.sendUnauthorized(w, r, "Authentication failed")
 			return
 		}
@@ -73,8 +85,9 @@ func (m *AuthMiddleware) Handle(next http.Handler) http.Handler {
 		// 将用户信息放入上下文
 		ctx = context.WithValue(ctx, UserContextKey, u)
 		r = r.WithContext(ctx)
+		SetAuditActor(ctx, u)
 
-		m.logger.Debug("user authenticated", zap.String("username", u.Username))
+		logger.Ctx(r.Context(), m.logger).Debug("user authenticated", zap.String("username", u.Username))
 
 		next.ServeHTTP(w, r)
 	})
@@ -89,19 +102,25 @@ func (m *AuthMiddleware) authenticate(ctx context.Context, credentials interface
 			continue
 		}
 
-		m.logger.Debug("trying authenticator",
+		logger.Ctx(ctx, m.logger).Debug("trying authenticator",
 			zap.String("authenticator", authenticator.Name()))
 
 		// 尝试认证
-		u, err := authenticator.Authenticate(ctx, credentials)
+		spanCtx, span := tracing.Start(ctx, "auth.authenticate",
+			trace.WithAttributes(attribute.String("auth.authenticator", authenticator.Name())))
+		u, err := authenticator.Authenticate(spanCtx, credentials)
+		tracing.RecordError(span, err)
+		span.End()
 		if err != nil {
-			m.logger.Debug("authentication failed",
+			metrics.AuthAttempts.Inc(authenticator.Name(), "failure")
+			logger.Ctx(ctx, m.logger).Debug("authentication failed",
 				zap.String("authenticator", authenticator.Name()),
 				zap.Error(err))
 			return nil, nil, err
 		}
+		metrics.AuthAttempts.Inc(authenticator.Name(), "success")
 
-		m.logger.Debug("authentication successful",
+		logger.Ctx(ctx, m.logger).Debug("authentication successful",
 			zap.String("authenticator", authenticator.Name()),
 			zap.String("username", u.Username))
 
@@ -1,23 +1,25 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"time"
 
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"go.uber.org/zap"
 )
 
 // LoggerMiddleware 日志中间件
 type LoggerMiddleware struct {
-	logger      *zap.Logger
-	behindProxy bool
+	logger  *zap.Logger
+	proxies *TrustedProxies
 }
 
-// NewLoggerMiddleware 创建日志中间件
-func NewLoggerMiddleware(logger *zap.Logger, behindProxy bool) *LoggerMiddleware {
+// NewLoggerMiddleware 创建日志中间件；proxies 为 nil 时不信任代理头
+func NewLoggerMiddleware(logger *zap.Logger, proxies *TrustedProxies) *LoggerMiddleware {
 	return &LoggerMiddleware{
-		logger:      logger,
-		behindProxy: behindProxy,
+		logger:  logger,
+		proxies: proxies,
 	}
 }
 
@@ -26,6 +28,9 @@ func (m *LoggerMiddleware) Handle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		// 记录客户端地址，供登录会话等使用
+		r = r.WithContext(context.WithValue(r.Context(), ClientIPContextKey, ClientIP(r, m.proxies)))
+
 		// 包装 ResponseWriter 以捕获状态码
 		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
@@ -52,13 +57,13 @@ func (m *LoggerMiddleware) Handle(next http.Handler) http.Handler {
 			fields = append(fields, zap.String("username", u.Username))
 		}
 
-		m.logger.Debug("http request", fields...)
+		logger.Ctx(r.Context(), m.logger).Debug("http request", fields...)
 	})
 }
 
 // getRemoteAddr 获取远程地址
 func (m *LoggerMiddleware) getRemoteAddr(r *http.Request) string {
-	if m.behindProxy {
+	if m.proxies != nil {
 		// 尝试从 X-Forwarded-For 或 X-Real-IP 获取
 		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 			return xff
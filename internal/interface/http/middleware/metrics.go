@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/metrics"
+)
+
+// MetricsMiddleware 请求指标中间件
+// 路由标签取自 ServeMux 匹配到的路由模式，避免按原始路径产生无限多的序列；WebDAV 请求统一标记为 webdav。
+type MetricsMiddleware struct {
+	mux          *http.ServeMux
+	webdavPrefix string
+}
+
+// NewMetricsMiddleware 创建请求指标中间件
+func NewMetricsMiddleware(mux *http.ServeMux, webdavPrefix string) *MetricsMiddleware {
+	return &MetricsMiddleware{
+		mux:          mux,
+		webdavPrefix: webdavPrefix,
+	}
+}
+
+// Handle 记录请求数、耗时与收发字节数
+func (m *MetricsMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := routeLabel(m.mux, r, m.webdavPrefix)
+
+		body := &countingBody{ReadCloser: r.Body}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = body
+		}
+		wrapped := &countingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(wrapped, r)
+
+		method := metricsMethod(r.Method)
+		metrics.HTTPRequests.Inc(route, method, strconv.Itoa(wrapped.statusCode))
+		metrics.HTTPDuration.Observe(time.Since(start).Seconds(), route, method)
+		metrics.UploadBytes.Add(float64(body.n), route)
+		metrics.DownloadBytes.Add(float64(wrapped.n), route)
+	})
+}
+
+// routeLabel 返回请求匹配的路由模式，用作指标标签与跨度名，避免按原始路径产生无限多的取值
+func routeLabel(mux *http.ServeMux, r *http.Request, webdavPrefix string) string {
+	_, pattern := mux.Handler(r)
+	switch pattern {
+	case "":
+		return "unmatched"
+	case webdavPrefix:
+		return "webdav"
+	}
+	return pattern
+}
+
+// metricsMethod 将非标准方法归为 OTHER，限制标签取值
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions,
+		"PROPFIND", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK":
+		return method
+	}
+	return "OTHER"
+}
+
+// countingBody 统计已读取的请求体字节数
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// countingResponseWriter 记录状态码与响应体字节数
+type countingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	n          int64
+}
+
+func (w *countingResponseWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func (w *countingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
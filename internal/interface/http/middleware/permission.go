@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/yeying-community/warehouse/internal/domain/permission"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"go.uber.org/zap"
 )
 
@@ -29,7 +30,7 @@ func (m *PermissionMiddleware) Handle(next http.Handler) http.Handler {
 		// 从上下文获取用户
 		u, ok := GetUserFromContext(ctx)
 		if !ok {
-			m.logger.Error("user not found in context")
+			logger.Ctx(r.Context(), m.logger).Error("user not found in context")
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -39,7 +40,7 @@ func (m *PermissionMiddleware) Handle(next http.Handler) http.Handler {
 
 		// 检查权限
 		if err := m.checker.Check(ctx, u, r.URL.Path, operation); err != nil {
-			m.logger.Warn("permission denied",
+			logger.Ctx(r.Context(), m.logger).Warn("permission denied",
 				zap.String("username", u.Username),
 				zap.String("method", r.Method),
 				zap.String("path", r.URL.Path),
@@ -49,7 +50,7 @@ func (m *PermissionMiddleware) Handle(next http.Handler) http.Handler {
 			return
 		}
 
-		m.logger.Debug("permission granted",
+		logger.Ctx(r.Context(), m.logger).Debug("permission granted",
 			zap.String("username", u.Username),
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
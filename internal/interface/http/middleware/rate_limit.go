@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/ratelimit"
+	"github.com/yeying-community/warehouse/internal/infrastructure/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// loginBodyLimit 解析登录请求体中账户字段时最多读取的字节数
+const loginBodyLimit = 64 << 10
+
+// RateLimitGroup 按路径前缀划分的限流分组
+type RateLimitGroup struct {
+	Name    string
+	Prefix  string
+	IP      *ratelimit.Limiter
+	Account *ratelimit.Limiter
+	// LoginFromBody 为 true 时在认证前按提交的账户（Basic 用户名或 JSON 中的 username/email/address）限流
+	LoginFromBody bool
+}
+
+// RateLimitMiddleware 限流中间件
+type RateLimitMiddleware struct {
+	groups []RateLimitGroup
+	logger *zap.Logger
+}
+
+// NewRateLimitMiddleware 创建限流中间件；groups 按顺序匹配，前缀更具体的分组应排在前面
+func NewRateLimitMiddleware(groups []RateLimitGroup, logger *zap.Logger) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		groups: groups,
+		logger: logger,
+	}
+}
+
+// Handle 按客户端 IP 限流，登录分组同时按提交的账户限流
+// 需要放在日志中间件内层，以便取得解析后的客户端 IP。
+func (m *RateLimitMiddleware) Handle(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		group := m.match(r.URL.Path)
+		if group == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		_, span := tracing.Start(r.Context(), "middleware.rate_limit",
+			trace.WithAttributes(attribute.String("rate_limit.group", group.Name)))
+		ip := GetClientIP(r)
+		if ok, wait := group.IP.Allow(ip); !ok {
+			span.SetAttributes(attribute.String("rate_limit.rejected", "ip"))
+			span.End()
+			m.reject(w, group.Name, "ip", ip, wait)
+			return
+		}
+		if group.LoginFromBody && group.Account != nil {
+			if account := loginAccount(r); account != "" {
+				if ok, wait := group.Account.Allow(strings.ToLower(account)); !ok {
+					span.SetAttributes(attribute.String("rate_limit.rejected", "account"))
+					span.End()
+					m.reject(w, group.Name, "account", account, wait)
+					return
+				}
+			}
+		}
+		span.End()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandleAccount 按已认证用户限流，需要放在认证中间件内层
+func (m *RateLimitMiddleware) HandleAccount(next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := m.match(r.URL.Path)
+		u, ok := GetUserFromContext(r.Context())
+		if group == nil || group.LoginFromBody || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ok, wait := group.Account.Allow(u.ID); !ok {
+			m.reject(w, group.Name, "account", u.Username, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *RateLimitMiddleware) match(path string) *RateLimitGroup {
+	for i := range m.groups {
+		if strings.HasPrefix(path, m.groups[i].Prefix) {
+			return &m.groups[i]
+		}
+	}
+	return nil
+}
+
+func (m *RateLimitMiddleware) reject(w http.ResponseWriter, group, scope, key string, wait time.Duration) {
+	m.logger.Warn("rate limit exceeded",
+		zap.String("group", group),
+		zap.String("scope", scope),
+		zap.String("key", key),
+		zap.Duration("retry_after", wait))
+	WriteRetryAfter(w, wait)
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+}
+
+// WriteRetryAfter 设置 Retry-After 响应头（向上取整到秒，至少 1 秒）
+func WriteRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// loginAccount 提取登录请求提交的账户，读取后还原请求体
+func loginAccount(r *http.Request) string {
+	if username, _, ok := r.BasicAuth(); ok {
+		return username
+	}
+	if r.Method != http.MethodPost {
+		return ""
+	}
+
+	var body struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+		Address  string `json:"address"`
+	}
+	if !peekJSON(r, &body) {
+		return ""
+	}
+	return firstNonEmpty(body.Username, body.Email, body.Address)
+}
+
+// peekJSON 读取请求体开头（最多 loginBodyLimit 字节）解析为 JSON，并还原请求体供后续处理器读取
+func peekJSON(r *http.Request, v interface{}) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	head, err := io.ReadAll(io.LimitReader(r.Body, loginBodyLimit))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), r.Body), r.Body}
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(head, v) == nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
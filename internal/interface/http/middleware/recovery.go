@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"runtime/debug"
 	
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
 	"go.uber.org/zap"
 )
 
@@ -26,7 +27,7 @@ func (m *RecoveryMiddleware) Handle(next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				// 记录 panic 信息
-				m.logger.Error("panic recovered",
+				logger.Ctx(r.Context(), m.logger).Error("panic recovered",
 					zap.Any("error", err),
 					zap.String("stack", string(debug.Stack())))
 				
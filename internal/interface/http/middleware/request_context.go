@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	// SessionContextKey 登录会话上下文键
+	SessionContextKey contextKey = "session"
+	// ClientIPContextKey 客户端地址上下文键
+	ClientIPContextKey contextKey = "client_ip"
+)
+
+// WithSessionID 将当前请求所属的登录会话 ID 放入上下文
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	if ctx == nil || sessionID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, SessionContextKey, sessionID)
+}
+
+// GetSessionID 获取当前请求所属的登录会话 ID（UCAN 与无状态令牌为空）
+func GetSessionID(ctx context.Context) string {
+	sessionID, _ := ctx.Value(SessionContextKey).(string)
+	return sessionID
+}
+
+// TrustedProxies 反向代理配置
+// 启用时连接的对端视为可信代理，nets 为更外层的可信代理（如 CDN 或多级负载均衡）。
+type TrustedProxies struct {
+	nets []*net.IPNet
+}
+
+// NewTrustedProxies 创建反向代理配置；behindProxy 为 false 时返回 nil，只使用连接的对端地址
+// entries 为 IP 或 CIDR，无效项被忽略（配置加载时已校验）。
+func NewTrustedProxies(behindProxy bool, entries []string) *TrustedProxies {
+	if !behindProxy {
+		return nil
+	}
+	p := &TrustedProxies{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			p.nets = append(p.nets, ipNet)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * len(ip.To16())
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 32
+			}
+			p.nets = append(p.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return p
+}
+
+func (p *TrustedProxies) trusted(ip net.IP) bool {
+	for _, n := range p.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP 解析客户端 IP；proxies 为 nil 时使用连接的对端地址
+// 经过代理时从 X-Forwarded-For 右侧向左跳过可信代理，取第一个不可信的地址：左侧的条目由客户端提供，不能信任。
+// 没有 X-Forwarded-For 时使用代理设置的 X-Real-IP。
+func ClientIP(r *http.Request, proxies *TrustedProxies) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remote = host
+	}
+	if proxies == nil {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) == 0 {
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip.String()
+		}
+		return remote
+	}
+
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			// 无法解析的条目来自代理链之外，停在最后一个可信的地址
+			break
+		}
+		client = ip.String()
+		if !proxies.trusted(ip) {
+			break
+		}
+	}
+	return client
+}
+
+// GetClientIP 获取日志中间件记录的客户端 IP，未记录时回退到 RemoteAddr
+func GetClientIP(r *http.Request) string {
+	if ip := ClientIPFromContext(r.Context()); ip != "" {
+		return ip
+	}
+	return ClientIP(r, nil)
+}
+
+// ClientIPFromContext 获取日志中间件记录的客户端 IP
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(ClientIPContextKey).(string)
+	return ip
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPIgnoresSpoofedForwardedFor(t *testing.T) {
+	cases := []struct {
+		name    string
+		proxies *TrustedProxies
+		xff     []string
+		realIP  string
+		want    string
+	}{
+		{"not behind proxy", nil, []string{"198.51.100.1"}, "", "10.0.0.2"},
+		{"single proxy", NewTrustedProxies(true, nil), []string{"203.0.113.7"}, "", "203.0.113.7"},
+		// 客户端伪造的左侧条目被忽略，取代理追加的地址
+		{"spoofed left entry", NewTrustedProxies(true, nil), []string{"198.51.100.1, 203.0.113.7"}, "", "203.0.113.7"},
+		{"spoofed header line", NewTrustedProxies(true, nil), []string{"198.51.100.1", "203.0.113.7"}, "", "203.0.113.7"},
+		{"garbage entry", NewTrustedProxies(true, nil), []string{"not-an-ip"}, "", "10.0.0.2"},
+		// 多级代理：跳过可信的 CDN 节点
+		{"trusted chain", NewTrustedProxies(true, []string{"192.0.2.0/24"}), []string{"198.51.100.1, 203.0.113.7, 192.0.2.10"}, "", "203.0.113.7"},
+		{"untrusted hop", NewTrustedProxies(true, []string{"192.0.2.10"}), []string{"203.0.113.7, 192.0.2.11"}, "", "192.0.2.11"},
+		{"real ip without xff", NewTrustedProxies(true, nil), nil, "203.0.113.8", "203.0.113.8"},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.2:51234"
+		for _, v := range tc.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if tc.realIP != "" {
+			r.Header.Set("X-Real-IP", tc.realIP)
+		}
+		if got := ClientIP(r, tc.proxies); got != tc.want {
+			t.Fatalf("%s: ClientIP = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+)
+
+// RequestIDHeader 请求 ID 头，客户端或上游代理传入时沿用，否则生成
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 沿用上游请求 ID 的最大长度
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware 请求 ID 中间件
+type RequestIDMiddleware struct{}
+
+// NewRequestIDMiddleware 创建请求 ID 中间件
+func NewRequestIDMiddleware() *RequestIDMiddleware {
+	return &RequestIDMiddleware{}
+}
+
+// Handle 为请求分配 ID，写入响应头与上下文，供日志关联
+func (m *RequestIDMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logger.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID 只接受长度受限的可见 ASCII 字符，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if c <= ' ' || c > '~' || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/yeying-community/warehouse/internal/infrastructure/logger"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware 链路追踪中间件
+// 基于 otelhttp 为每个请求创建 Server 跨度并沿用请求头 traceparent 中的上游链路；跨度名使用路由模式，与指标中间件一致。
+type TracingMiddleware struct {
+	mux          *http.ServeMux
+	webdavPrefix string
+}
+
+// NewTracingMiddleware 创建链路追踪中间件
+func NewTracingMiddleware(mux *http.ServeMux, webdavPrefix string) *TracingMiddleware {
+	return &TracingMiddleware{
+		mux:          mux,
+		webdavPrefix: webdavPrefix,
+	}
+}
+
+// Handle 创建请求跨度并放入上下文
+func (m *TracingMiddleware) Handle(next http.Handler) http.Handler {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		span.SetAttributes(attribute.String("http.route", routeLabel(m.mux, r, m.webdavPrefix)))
+		if id := logger.RequestID(r.Context()); id != "" {
+			span.SetAttributes(attribute.String("request.id", id))
+		}
+		next.ServeHTTP(w, r)
+	})
+	return otelhttp.NewHandler(inner, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + routeLabel(m.mux, r, m.webdavPrefix)
+		}))
+}
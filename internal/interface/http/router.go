@@ -5,7 +5,9 @@ import (
 	"strings"
 
 	"github.com/yeying-community/warehouse/internal/domain/auth"
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
 	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/ratelimit"
 	"github.com/yeying-community/warehouse/internal/interface/http/handler"
 	"github.com/yeying-community/warehouse/internal/interface/http/middleware"
 	"go.uber.org/zap"
@@ -15,9 +17,13 @@ import (
 type Router struct {
 	config             *config.Config
 	authenticators     []auth.Authenticator
+	authorizer         middleware.Authorizer
+	auditRecorder      middleware.AuditRecorder
 	healthHandler      *handler.HealthHandler
+	jwksHandler        *handler.JWKSHandler
 	web3Handler        *handler.Web3Handler
 	emailAuthHandler   *handler.EmailAuthHandler
+	accountMailHandler *handler.AccountMailHandler
 	assetsHandler      *handler.AssetsHandler
 	webdavHandler      *handler.WebDAVHandler
 	quotaHandler       *handler.QuotaHandler
@@ -27,6 +33,20 @@ type Router struct {
 	shareHandler       *handler.ShareHandler
 	shareUserHandler   *handler.ShareUserHandler
 	addressBookHandler *handler.AddressBookHandler
+	e2eeHandler        *handler.E2EEHandler
+	identityHandler    *handler.IdentityHandler
+	sessionHandler     *handler.SessionHandler
+	appPasswordHandler *handler.AppPasswordHandler
+	mfaHandler         *handler.MFAHandler
+	webauthnHandler    *handler.WebAuthnHandler
+	adminRoleHandler   *handler.AdminRoleHandler
+	adminAuditHandler  *handler.AdminAuditHandler
+	adminMailHandler   *handler.AdminMailHandler
+	metricsHandler     *handler.MetricsHandler
+	rateLimit          *middleware.RateLimitMiddleware
+	audit              *middleware.AuditMiddleware
+	metrics            *middleware.MetricsMiddleware
+	tracing            *middleware.TracingMiddleware
 	logger             *zap.Logger
 }
 
@@ -34,9 +54,13 @@ type Router struct {
 func NewRouter(
 	cfg *config.Config,
 	authenticators []auth.Authenticator,
+	authorizer middleware.Authorizer,
+	auditRecorder middleware.AuditRecorder,
 	healthHandler *handler.HealthHandler,
+	jwksHandler *handler.JWKSHandler,
 	web3Handler *handler.Web3Handler,
 	emailAuthHandler *handler.EmailAuthHandler,
+	accountMailHandler *handler.AccountMailHandler,
 	assetsHandler *handler.AssetsHandler,
 	webdavHandler *handler.WebDAVHandler,
 	quotaHandler *handler.QuotaHandler,
@@ -46,14 +70,28 @@ func NewRouter(
 	shareHandler *handler.ShareHandler,
 	shareUserHandler *handler.ShareUserHandler,
 	addressBookHandler *handler.AddressBookHandler,
+	e2eeHandler *handler.E2EEHandler,
+	identityHandler *handler.IdentityHandler,
+	sessionHandler *handler.SessionHandler,
+	appPasswordHandler *handler.AppPasswordHandler,
+	mfaHandler *handler.MFAHandler,
+	webauthnHandler *handler.WebAuthnHandler,
+	adminRoleHandler *handler.AdminRoleHandler,
+	adminAuditHandler *handler.AdminAuditHandler,
+	adminMailHandler *handler.AdminMailHandler,
+	metricsHandler *handler.MetricsHandler,
 	logger *zap.Logger,
 ) *Router {
 	return &Router{
 		config:             cfg,
 		authenticators:     authenticators,
+		authorizer:         authorizer,
+		auditRecorder:      auditRecorder,
 		healthHandler:      healthHandler,
+		jwksHandler:        jwksHandler,
 		web3Handler:        web3Handler,
 		emailAuthHandler:   emailAuthHandler,
+		accountMailHandler: accountMailHandler,
 		assetsHandler:      assetsHandler,
 		webdavHandler:      webdavHandler,
 		quotaHandler:       quotaHandler,
@@ -63,6 +101,16 @@ func NewRouter(
 		shareHandler:       shareHandler,
 		shareUserHandler:   shareUserHandler,
 		addressBookHandler: addressBookHandler,
+		e2eeHandler:        e2eeHandler,
+		identityHandler:    identityHandler,
+		sessionHandler:     sessionHandler,
+		appPasswordHandler: appPasswordHandler,
+		mfaHandler:         mfaHandler,
+		webauthnHandler:    webauthnHandler,
+		adminRoleHandler:   adminRoleHandler,
+		adminAuditHandler:  adminAuditHandler,
+		adminMailHandler:   adminMailHandler,
+		metricsHandler:     metricsHandler,
 		logger:             logger,
 	}
 }
@@ -70,19 +118,57 @@ func NewRouter(
 // Setup 设置路由
 func (r *Router) Setup() http.Handler {
 	mux := http.NewServeMux()
+	webdavPrefix := r.normalizePrefix(r.config.WebDAV.Prefix)
+	r.rateLimit = r.newRateLimitMiddleware(webdavPrefix)
+	if r.config.Audit.Enabled {
+		r.audit = middleware.NewAuditMiddleware(r.auditRecorder, r.logger)
+	}
 
-	// 健康检查路由（无需认证）
+	// 健康检查路由（无需认证）：live 为存活检查，ready 为就绪检查，heartbeat 为兼容旧版的存活检查
 	mux.HandleFunc("/api/v1/public/health/heartbeat", r.healthHandler.Handle)
+	mux.HandleFunc("/api/v1/public/health/live", r.healthHandler.Handle)
+	mux.HandleFunc("/api/v1/public/health/ready", r.healthHandler.HandleReady)
+
+	// JWT 验证公钥（无需认证）
+	mux.HandleFunc("/.well-known/jwks.json", r.jwksHandler.Handle)
+
+	// 链路追踪
+	if r.config.Tracing.Enabled {
+		r.tracing = middleware.NewTracingMiddleware(mux, webdavPrefix)
+	}
+
+	// Prometheus 指标（未配置独立监听地址时挂载到主服务）
+	if r.config.Metrics.Enabled {
+		r.metrics = middleware.NewMetricsMiddleware(mux, webdavPrefix)
+		if r.config.Metrics.Address == "" {
+			mux.HandleFunc(r.config.Metrics.Path, r.metricsHandler.Handle)
+		}
+	}
 
 	// Web3 认证路由（无需认证）
 	mux.HandleFunc("/api/v1/public/auth/challenge", r.web3Handler.HandleChallenge)
-	mux.HandleFunc("/api/v1/public/auth/verify", r.web3Handler.HandleVerify)
-	mux.HandleFunc("/api/v1/public/auth/refresh", r.web3Handler.HandleRefresh)
-	mux.HandleFunc("/api/v1/public/auth/logout", r.web3Handler.HandleLogout)
-	mux.HandleFunc("/api/v1/public/auth/password/login", r.web3Handler.HandlePasswordLogin)
+	mux.Handle("/api/v1/public/auth/verify", r.audit.Handle("auth.wallet_login", http.HandlerFunc(r.web3Handler.HandleVerify)))
+	mux.Handle("/api/v1/public/auth/refresh", r.audit.Handle("auth.refresh", http.HandlerFunc(r.web3Handler.HandleRefresh)))
+	mux.Handle("/api/v1/public/auth/logout", r.audit.Handle("auth.logout", http.HandlerFunc(r.web3Handler.HandleLogout)))
+	mux.Handle("/api/v1/public/auth/password/login", r.audit.Handle("auth.password_login", http.HandlerFunc(r.web3Handler.HandlePasswordLogin)))
 	if r.emailAuthHandler != nil {
-		mux.HandleFunc("/api/v1/public/auth/email/code", r.emailAuthHandler.HandleSendCode)
-		mux.HandleFunc("/api/v1/public/auth/email/login", r.emailAuthHandler.HandleLogin)
+		mux.Handle("/api/v1/public/auth/email/code", r.audit.Handle("auth.email_code", http.HandlerFunc(r.emailAuthHandler.HandleSendCode)))
+		mux.Handle("/api/v1/public/auth/email/login", r.audit.Handle("auth.email_login", http.HandlerFunc(r.emailAuthHandler.HandleLogin)))
+	}
+	// 重置密码与验证邮箱（凭邮件链接中的一次性令牌访问）
+	if r.accountMailHandler != nil {
+		mux.Handle("/api/v1/public/auth/password/forgot", r.audit.Handle("auth.password_forgot", http.HandlerFunc(r.accountMailHandler.HandleForgotPassword)))
+		mux.Handle("/api/v1/public/auth/password/reset", r.audit.Handle("auth.password_reset", http.HandlerFunc(r.accountMailHandler.HandleResetPassword)))
+		mux.Handle("/api/v1/public/auth/email/confirm", r.audit.Handle("auth.email_confirm", http.HandlerFunc(r.accountMailHandler.HandleConfirmEmail)))
+	}
+	// 两步验证登录第二步（凭挑战令牌访问）
+	mux.Handle("/api/v1/public/auth/mfa/verify", r.audit.Handle("auth.mfa_verify", http.HandlerFunc(r.mfaHandler.HandleLoginVerify)))
+	// 通行密钥（WebAuthn）注册与登录；已登录时注册为当前账户添加通行密钥
+	if r.webauthnHandler != nil {
+		mux.Handle("/api/v1/public/auth/webauthn/register/options", r.createOptionalAuthHandler(http.HandlerFunc(r.webauthnHandler.HandleRegisterOptions)))
+		mux.Handle("/api/v1/public/auth/webauthn/register/verify", r.audit.Handle("auth.passkey_register", http.HandlerFunc(r.webauthnHandler.HandleRegisterVerify)))
+		mux.HandleFunc("/api/v1/public/auth/webauthn/login/options", r.webauthnHandler.HandleLoginOptions)
+		mux.Handle("/api/v1/public/auth/webauthn/login/verify", r.audit.Handle("auth.passkey_login", http.HandlerFunc(r.webauthnHandler.HandleLoginVerify)))
 	}
 
 	// API 路由（需要认证）
@@ -91,21 +177,58 @@ func (r *Router) Setup() http.Handler {
 	}
 	mux.Handle("/api/v1/public/webdav/quota", r.createAuthenticatedHandler(http.HandlerFunc(r.quotaHandler.GetUserQuota)))
 	mux.Handle("/api/v1/public/webdav/user/info", r.createAuthenticatedHandler(http.HandlerFunc(r.userHandler.GetUserInfo)))
-	mux.Handle("/api/v1/public/webdav/user/update", r.createAuthenticatedHandler(http.HandlerFunc(r.userHandler.UpdateUsername)))
-	mux.Handle("/api/v1/public/webdav/user/password", r.createAuthenticatedHandler(http.HandlerFunc(r.userHandler.UpdatePassword)))
+	mux.Handle("/api/v1/public/webdav/user/update", r.audit.Handle("account.username_update", r.createAuthenticatedHandler(http.HandlerFunc(r.userHandler.UpdateUsername))))
+	mux.Handle("/api/v1/public/webdav/user/password", r.audit.Handle("account.password_change", r.createAuthenticatedHandler(http.HandlerFunc(r.userHandler.UpdatePassword))))
+	if r.accountMailHandler != nil {
+		mux.Handle("/api/v1/public/webdav/user/email", r.audit.Handle("account.email_change", r.createAuthenticatedHandler(http.HandlerFunc(r.accountMailHandler.HandleChangeEmail))))
+		mux.Handle("/api/v1/public/webdav/user/email/verify", r.createAuthenticatedHandler(http.HandlerFunc(r.accountMailHandler.HandleSendVerification)))
+	}
+	mux.Handle("/api/v1/public/webdav/user/app-passwords", r.createAuthenticatedHandler(http.HandlerFunc(r.appPasswordHandler.HandleList)))
+	mux.Handle("/api/v1/public/webdav/user/app-passwords/create", r.audit.Handle("account.app_password_create", r.createAuthenticatedHandler(http.HandlerFunc(r.appPasswordHandler.HandleCreate))))
+	mux.Handle("/api/v1/public/webdav/user/app-passwords/revoke", r.audit.Handle("account.app_password_revoke", r.createAuthenticatedHandler(http.HandlerFunc(r.appPasswordHandler.HandleRevoke))))
+	mux.Handle("/api/v1/public/webdav/user/mfa", r.createAuthenticatedHandler(http.HandlerFunc(r.mfaHandler.HandleStatus)))
+	mux.Handle("/api/v1/public/webdav/user/mfa/enroll", r.audit.Handle("account.mfa_enroll", r.createAuthenticatedHandler(http.HandlerFunc(r.mfaHandler.HandleEnroll))))
+	mux.Handle("/api/v1/public/webdav/user/mfa/confirm", r.audit.Handle("account.mfa_confirm", r.createAuthenticatedHandler(http.HandlerFunc(r.mfaHandler.HandleConfirm))))
+	mux.Handle("/api/v1/public/webdav/user/mfa/disable", r.audit.Handle("account.mfa_disable", r.createAuthenticatedHandler(http.HandlerFunc(r.mfaHandler.HandleDisable))))
+	mux.Handle("/api/v1/public/webdav/user/mfa/recovery-codes", r.audit.Handle("account.mfa_recovery_codes", r.createAuthenticatedHandler(http.HandlerFunc(r.mfaHandler.HandleRecoveryCodes))))
+	if r.webauthnHandler != nil {
+		mux.Handle("/api/v1/public/webdav/user/passkeys", r.createAuthenticatedHandler(http.HandlerFunc(r.webauthnHandler.HandleList)))
+		mux.Handle("/api/v1/public/webdav/user/passkeys/delete", r.audit.Handle("account.passkey_delete", r.createAuthenticatedHandler(http.HandlerFunc(r.webauthnHandler.HandleDelete))))
+	}
+
+	// 管理员用户管理（需要认证 + 对应的管理权限）
+	mux.Handle("/api/v1/public/admin/users/list", r.createAdminHandler(rbac.PermUsersRead, http.HandlerFunc(r.adminUserHandler.HandleList)))
+	mux.Handle("/api/v1/public/admin/users/create", r.createAdminHandler(rbac.PermUsersWrite, http.HandlerFunc(r.adminUserHandler.HandleCreate)))
+	mux.Handle("/api/v1/public/admin/users/update", r.createAdminHandler(rbac.PermUsersWrite, http.HandlerFunc(r.adminUserHandler.HandleUpdate)))
+	mux.Handle("/api/v1/public/admin/users/delete", r.createAdminHandler(rbac.PermUsersWrite, http.HandlerFunc(r.adminUserHandler.HandleDelete)))
+	mux.Handle("/api/v1/public/admin/users/reset-password", r.createAdminHandler(rbac.PermUsersSecurity, http.HandlerFunc(r.adminUserHandler.HandleResetPassword)))
+	mux.Handle("/api/v1/public/admin/users/sessions/revoke", r.createAdminHandler(rbac.PermUsersSecurity, http.HandlerFunc(r.sessionHandler.HandleAdminRevokeAll)))
+	mux.Handle("/api/v1/public/admin/users/mfa", r.createAdminHandler(rbac.PermUsersSecurity, http.HandlerFunc(r.mfaHandler.HandleAdminSetRequired)))
+	mux.Handle("/api/v1/public/admin/users/locked", r.createAdminHandler(rbac.PermUsersRead, http.HandlerFunc(r.adminUserHandler.HandleLocked)))
+	mux.Handle("/api/v1/public/admin/users/unlock", r.createAdminHandler(rbac.PermUsersSecurity, http.HandlerFunc(r.adminUserHandler.HandleUnlock)))
+
+	// 管理角色（当前用户的角色对所有登录用户开放，其余按权限控制）
+	mux.Handle("/api/v1/public/admin/me", r.createAuthenticatedHandler(http.HandlerFunc(r.adminRoleHandler.HandleMe)))
+	mux.Handle("/api/v1/public/admin/roles", r.createAdminHandler(rbac.PermRolesRead, http.HandlerFunc(r.adminRoleHandler.HandleList)))
+	mux.Handle("/api/v1/public/admin/roles/save", r.createAdminHandler(rbac.PermRolesManage, http.HandlerFunc(r.adminRoleHandler.HandleSave)))
+	mux.Handle("/api/v1/public/admin/roles/delete", r.createAdminHandler(rbac.PermRolesManage, http.HandlerFunc(r.adminRoleHandler.HandleDelete)))
+	mux.Handle("/api/v1/public/admin/roles/assignments", r.createAdminHandler(rbac.PermRolesRead, http.HandlerFunc(r.adminRoleHandler.HandleAssignments)))
+	mux.Handle("/api/v1/public/admin/roles/assign", r.createAdminHandler(rbac.PermRolesAssign, http.HandlerFunc(r.adminRoleHandler.HandleAssign)))
+	mux.Handle("/api/v1/public/admin/roles/revoke", r.createAdminHandler(rbac.PermRolesAssign, http.HandlerFunc(r.adminRoleHandler.HandleRevoke)))
+
+	// 审计日志
+	mux.Handle("/api/v1/public/admin/audit", r.createAdminHandler(rbac.PermAuditRead, http.HandlerFunc(r.adminAuditHandler.HandleList)))
+	mux.Handle("/api/v1/public/admin/audit/export", r.createAdminHandler(rbac.PermAuditRead, http.HandlerFunc(r.adminAuditHandler.HandleExport)))
 
-	// 管理员用户管理（需要认证 + 管理员权限）
-	mux.Handle("/api/v1/public/admin/users/list", r.createAdminHandler(http.HandlerFunc(r.adminUserHandler.HandleList)))
-	mux.Handle("/api/v1/public/admin/users/create", r.createAdminHandler(http.HandlerFunc(r.adminUserHandler.HandleCreate)))
-	mux.Handle("/api/v1/public/admin/users/update", r.createAdminHandler(http.HandlerFunc(r.adminUserHandler.HandleUpdate)))
-	mux.Handle("/api/v1/public/admin/users/delete", r.createAdminHandler(http.HandlerFunc(r.adminUserHandler.HandleDelete)))
-	mux.Handle("/api/v1/public/admin/users/reset-password", r.createAdminHandler(http.HandlerFunc(r.adminUserHandler.HandleResetPassword)))
+	// 邮件发件箱
+	mux.Handle("/api/v1/public/admin/mail/outbox", r.createAdminHandler(rbac.PermMailRead, http.HandlerFunc(r.adminMailHandler.HandleList)))
+	mux.Handle("/api/v1/public/admin/mail/outbox/retry", r.createAdminHandler(rbac.PermMailWrite, http.HandlerFunc(r.adminMailHandler.HandleRetry)))
 
 	// 回收站路由
 	mux.Handle("/api/v1/public/webdav/recycle/list", r.createAuthenticatedHandler(http.HandlerFunc(r.recycleHandler.HandleList)))
-	mux.Handle("/api/v1/public/webdav/recycle/recover", r.createAuthenticatedHandler(http.HandlerFunc(r.recycleHandler.HandleRecover)))
-	mux.Handle("/api/v1/public/webdav/recycle/permanent", r.createAuthenticatedHandler(http.HandlerFunc(r.recycleHandler.HandleRemove)))
-	mux.Handle("/api/v1/public/webdav/recycle/clear", r.createAuthenticatedHandler(http.HandlerFunc(r.recycleHandler.HandleClear)))
+	mux.Handle("/api/v1/public/webdav/recycle/recover", r.audit.Handle("recycle.recover", r.createAuthenticatedHandler(http.HandlerFunc(r.recycleHandler.HandleRecover))))
+	mux.Handle("/api/v1/public/webdav/recycle/permanent", r.audit.Handle("recycle.remove", r.createAuthenticatedHandler(http.HandlerFunc(r.recycleHandler.HandleRemove))))
+	mux.Handle("/api/v1/public/webdav/recycle/clear", r.audit.Handle("recycle.clear", r.createAuthenticatedHandler(http.HandlerFunc(r.recycleHandler.HandleClear))))
 
 	// 好友地址簿
 	mux.Handle("/api/v1/public/webdav/address/groups", r.createAuthenticatedHandler(http.HandlerFunc(r.addressBookHandler.HandleGroupList)))
@@ -117,27 +240,50 @@ func (r *Router) Setup() http.Handler {
 	mux.Handle("/api/v1/public/webdav/address/contacts/update", r.createAuthenticatedHandler(http.HandlerFunc(r.addressBookHandler.HandleContactUpdate)))
 	mux.Handle("/api/v1/public/webdav/address/contacts/delete", r.createAuthenticatedHandler(http.HandlerFunc(r.addressBookHandler.HandleContactDelete)))
 
+	// 端到端加密目录
+	mux.Handle("/api/v1/public/webdav/e2ee/public-key", r.createAuthenticatedHandler(http.HandlerFunc(r.e2eeHandler.HandlePublicKey)))
+	mux.Handle("/api/v1/public/webdav/e2ee/folders", r.createAuthenticatedHandler(http.HandlerFunc(r.e2eeHandler.HandleFolderList)))
+	mux.Handle("/api/v1/public/webdav/e2ee/folders/create", r.createAuthenticatedHandler(http.HandlerFunc(r.e2eeHandler.HandleFolderCreate)))
+	mux.Handle("/api/v1/public/webdav/e2ee/folders/delete", r.createAuthenticatedHandler(http.HandlerFunc(r.e2eeHandler.HandleFolderDelete)))
+	mux.Handle("/api/v1/public/webdav/e2ee/envelopes", r.createAuthenticatedHandler(http.HandlerFunc(r.e2eeHandler.HandleEnvelopeList)))
+	mux.Handle("/api/v1/public/webdav/e2ee/envelopes/put", r.createAuthenticatedHandler(http.HandlerFunc(r.e2eeHandler.HandleEnvelopePut)))
+	mux.Handle("/api/v1/public/webdav/e2ee/envelopes/delete", r.createAuthenticatedHandler(http.HandlerFunc(r.e2eeHandler.HandleEnvelopeDelete)))
+
+	// 登录身份
+	mux.Handle("/api/v1/public/webdav/identities", r.createAuthenticatedHandler(http.HandlerFunc(r.identityHandler.HandleList)))
+	mux.Handle("/api/v1/public/webdav/identities/challenge", r.createAuthenticatedHandler(http.HandlerFunc(r.identityHandler.HandleChallenge)))
+	mux.Handle("/api/v1/public/webdav/identities/email/code", r.createAuthenticatedHandler(http.HandlerFunc(r.identityHandler.HandleSendEmailCode)))
+	mux.Handle("/api/v1/public/webdav/identities/link", r.audit.Handle("account.identity_link", r.createAuthenticatedHandler(http.HandlerFunc(r.identityHandler.HandleLink))))
+	mux.Handle("/api/v1/public/webdav/identities/unlink", r.audit.Handle("account.identity_unlink", r.createAuthenticatedHandler(http.HandlerFunc(r.identityHandler.HandleUnlink))))
+	mux.Handle("/api/v1/public/webdav/identities/merge", r.audit.Handle("account.identity_merge", r.createAuthenticatedHandler(http.HandlerFunc(r.identityHandler.HandleMerge))))
+
+	// 登录会话
+	mux.Handle("/api/v1/public/webdav/sessions", r.createAuthenticatedHandler(http.HandlerFunc(r.sessionHandler.HandleList)))
+	mux.Handle("/api/v1/public/webdav/sessions/revoke", r.audit.Handle("account.session_revoke", r.createAuthenticatedHandler(http.HandlerFunc(r.sessionHandler.HandleRevoke))))
+	mux.Handle("/api/v1/public/webdav/sessions/revoke-others", r.audit.Handle("account.session_revoke_others", r.createAuthenticatedHandler(http.HandlerFunc(r.sessionHandler.HandleRevokeOthers))))
+
 	// 分享路由
-	mux.Handle("/api/v1/public/share/create", r.createAuthenticatedHandler(http.HandlerFunc(r.shareHandler.HandleCreate)))
+	mux.Handle("/api/v1/public/share/create", r.audit.Handle("share.create", r.createAuthenticatedHandler(http.HandlerFunc(r.shareHandler.HandleCreate))))
 	mux.Handle("/api/v1/public/share/list", r.createAuthenticatedHandler(http.HandlerFunc(r.shareHandler.HandleList)))
-	mux.Handle("/api/v1/public/share/revoke", r.createAuthenticatedHandler(http.HandlerFunc(r.shareHandler.HandleRevoke)))
-	mux.HandleFunc("/api/v1/public/share/", r.shareHandler.HandleAccess)
+	// 分享令牌本身就是分享的标识（share.access 同样记录），撤销时作为审计目标
+	mux.Handle("/api/v1/public/share/revoke", r.audit.Handle("share.revoke", r.createAuthenticatedHandler(http.HandlerFunc(r.shareHandler.HandleRevoke)), "token"))
+	mux.Handle("/api/v1/public/share/save", r.audit.Handle("share.save", r.createAuthenticatedHandler(http.HandlerFunc(r.shareHandler.HandleSave))))
+	mux.Handle("/api/v1/public/share/", r.audit.Handle("share.access", r.createOptionalAuthHandler(http.HandlerFunc(r.shareHandler.HandleAccess))))
 
 	// 定向分享路由（需要认证）
-	mux.Handle("/api/v1/public/share/user/create", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleCreate)))
+	mux.Handle("/api/v1/public/share/user/create", r.audit.Handle("share_user.create", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleCreate))))
 	mux.Handle("/api/v1/public/share/user/list", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleListMine)))
 	mux.Handle("/api/v1/public/share/user/received", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleListReceived)))
-	mux.Handle("/api/v1/public/share/user/revoke", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleRevoke)))
+	mux.Handle("/api/v1/public/share/user/revoke", r.audit.Handle("share_user.revoke", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleRevoke))))
 	mux.Handle("/api/v1/public/share/user/entries", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleEntries)))
 	mux.Handle("/api/v1/public/share/user/download", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleDownload)))
-	mux.Handle("/api/v1/public/share/user/upload", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleUpload)))
-	mux.Handle("/api/v1/public/share/user/folder", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleCreateFolder)))
-	mux.Handle("/api/v1/public/share/user/rename", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleRename)))
-	mux.Handle("/api/v1/public/share/user/item", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleDelete)))
+	mux.Handle("/api/v1/public/share/user/upload", r.audit.Handle("share_user.upload", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleUpload))))
+	mux.Handle("/api/v1/public/share/user/folder", r.audit.Handle("share_user.folder", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleCreateFolder))))
+	mux.Handle("/api/v1/public/share/user/rename", r.audit.Handle("share_user.rename", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleRename))))
+	mux.Handle("/api/v1/public/share/user/item", r.audit.Handle("share_user.delete", r.createAuthenticatedHandler(http.HandlerFunc(r.shareUserHandler.HandleDelete))))
 
 	// WebDAV 路由（需要认证）
-	webdavPrefix := r.normalizePrefix(r.config.WebDAV.Prefix)
-	mux.Handle(webdavPrefix, r.createAuthenticatedHandler(http.HandlerFunc(r.webdavHandler.Handle)))
+	mux.Handle(webdavPrefix, r.audit.HandleWebDAV(webdavPrefix, r.createWebDAVHandler(http.HandlerFunc(r.webdavHandler.Handle))))
 
 	// 应用全局中间件
 	handler := r.applyMiddlewares(mux)
@@ -145,30 +291,69 @@ func (r *Router) Setup() http.Handler {
 	return handler
 }
 
-// createAuthenticatedHandler 创建需要认证的处理器
+// MetricsHandler 返回独立监听地址上的指标处理器；未启用或挂载在主服务时返回 nil
+func (r *Router) MetricsHandler() http.Handler {
+	if !r.config.Metrics.Enabled || r.config.Metrics.Address == "" {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(r.config.Metrics.Path, r.metricsHandler.Handle)
+	return mux
+}
+
+// createAuthenticatedHandler 创建需要认证的处理器（不接受应用专用密码）
 func (r *Router) createAuthenticatedHandler(handler http.Handler) http.Handler {
 	// 应用认证中间件
 	authMiddleware := middleware.NewAuthMiddleware(r.authenticators, true, r.logger)
-	return authMiddleware.Handle(handler)
+	return authMiddleware.Handle(middleware.RejectAppPassword(r.rateLimit.HandleAccount(handler)))
+}
+
+// createWebDAVHandler 创建 WebDAV 处理器（接受应用专用密码）
+func (r *Router) createWebDAVHandler(handler http.Handler) http.Handler {
+	authMiddleware := middleware.NewAuthMiddleware(r.authenticators, true, r.logger)
+	return authMiddleware.Handle(r.rateLimit.HandleAccount(handler))
+}
+
+// createOptionalAuthHandler 创建可选认证的处理器（携带凭证时解析用户，否则匿名访问）
+func (r *Router) createOptionalAuthHandler(handler http.Handler) http.Handler {
+	authMiddleware := middleware.NewAuthMiddleware(r.authenticators, false, r.logger)
+	return authMiddleware.Handle(middleware.RejectAppPassword(handler))
 }
 
-// createAdminHandler 创建管理员处理器
-func (r *Router) createAdminHandler(handler http.Handler) http.Handler {
-	adminMiddleware := middleware.NewAdminMiddleware(r.config.Security.AdminAddresses, r.logger)
+// createAdminHandler 创建管理员处理器，要求当前用户拥有 perm 权限；每次调用都写入审计日志
+func (r *Router) createAdminHandler(perm rbac.Permission, handler http.Handler) http.Handler {
+	adminMiddleware := middleware.NewAdminMiddleware(r.authorizer, r.logger)
 	authMiddleware := middleware.NewAuthMiddleware(r.authenticators, true, r.logger)
-	return authMiddleware.Handle(adminMiddleware.Handle(handler))
+	return r.audit.HandleAdmin(authMiddleware.Handle(middleware.RejectAppPassword(adminMiddleware.Handle(perm, r.rateLimit.HandleAccount(handler)))))
 }
 
 // applyMiddlewares 应用全局中间件
 func (r *Router) applyMiddlewares(handler http.Handler) http.Handler {
+	// 限流中间件（最内层，依赖日志中间件记录的客户端 IP）
+	handler = r.rateLimit.Handle(handler)
+
 	// 1. 恢复中间件（最外层）
 	recoveryMiddleware := middleware.NewRecoveryMiddleware(r.logger)
 	handler = recoveryMiddleware.Handle(handler)
 
 	// 2. 日志中间件
-	loggerMiddleware := middleware.NewLoggerMiddleware(r.logger, r.config.Security.BehindProxy)
+	proxies := middleware.NewTrustedProxies(r.config.Security.BehindProxy, r.config.Security.TrustedProxies)
+	loggerMiddleware := middleware.NewLoggerMiddleware(r.logger, proxies)
 	handler = loggerMiddleware.Handle(handler)
 
+	// 链路追踪（位于日志中间件外层，使请求日志带上 trace_id）
+	if r.tracing != nil {
+		handler = r.tracing.Handle(handler)
+	}
+
+	// 请求 ID（写入响应头 X-Request-ID，并关联该请求的全部日志）
+	handler = middleware.NewRequestIDMiddleware().Handle(handler)
+
+	// 请求指标（位于日志中间件外层，统计包括限流拒绝在内的全部请求）
+	if r.metrics != nil {
+		handler = r.metrics.Handle(handler)
+	}
+
 	// 3. CORS 中间件
 	if r.config.CORS.Enabled {
 		corsConfig := &middleware.CORSConfig{
@@ -186,6 +371,31 @@ func (r *Router) applyMiddlewares(handler http.Handler) http.Handler {
 	return handler
 }
 
+// newRateLimitMiddleware 按配置创建限流中间件，未启用时返回 nil
+// 登录接口在认证前按提交的账户限流，其余分组在认证后按用户限流。
+func (r *Router) newRateLimitMiddleware(webdavPrefix string) *middleware.RateLimitMiddleware {
+	cfg := r.config.Security.RateLimit
+	if !cfg.Enabled {
+		return nil
+	}
+	newGroup := func(name, prefix string, rule config.RateLimitRule) middleware.RateLimitGroup {
+		return middleware.RateLimitGroup{
+			Name:    name,
+			Prefix:  prefix,
+			IP:      ratelimit.NewLimiter(rule.IPPerMinute, rule.IPBurst),
+			Account: ratelimit.NewLimiter(rule.AccountPerMinute, rule.AccountBurst),
+		}
+	}
+	authGroup := newGroup("auth", "/api/v1/public/auth/", cfg.Auth)
+	authGroup.LoginFromBody = true
+	groups := []middleware.RateLimitGroup{
+		authGroup,
+		newGroup("api", "/api/", cfg.API),
+		newGroup("webdav", webdavPrefix, cfg.WebDAV),
+	}
+	return middleware.NewRateLimitMiddleware(groups, r.logger)
+}
+
 // normalizePrefix 规范化前缀
 func (r *Router) normalizePrefix(prefix string) string {
 	if prefix == "" {
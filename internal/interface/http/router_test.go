@@ -0,0 +1,624 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yeying-community/warehouse/internal/container/containertest"
+	"github.com/yeying-community/warehouse/internal/domain/mfa"
+	"github.com/yeying-community/warehouse/internal/domain/outbox"
+	"github.com/yeying-community/warehouse/internal/domain/rbac"
+	"github.com/yeying-community/warehouse/internal/domain/session"
+	"github.com/yeying-community/warehouse/internal/infrastructure/config"
+	"github.com/yeying-community/warehouse/internal/infrastructure/email/emailtest"
+)
+
+func TestPasswordLoginIssuesBearerToken(t *testing.T) {
+	h := containertest.New(t)
+	h.CreateUser(t, "alice", "s3cret-pass")
+
+	resp, _ := h.DoJSON(t, http.MethodPost, "/api/v1/public/auth/password/login", "", map[string]string{
+		"username": "alice",
+		"password": "wrong",
+	})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for wrong password, got %d", resp.StatusCode)
+	}
+
+	token := h.Login(t, "alice", "s3cret-pass")
+	resp, body := h.DoJSON(t, http.MethodGet, "/api/v1/public/webdav/user/info", token, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for user info, got %d: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(string(body), "alice") {
+		t.Fatalf("user info does not mention alice: %s", body)
+	}
+
+	resp, _ = h.DoJSON(t, http.MethodGet, "/api/v1/public/webdav/user/info", "", nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebDAVRoundTrip(t *testing.T) {
+	h := containertest.New(t)
+	h.CreateUser(t, "alice", "s3cret-pass")
+
+	resp, _ := h.Do(t, h.NewRequest(t, http.MethodGet, "/dav/personal/", nil))
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without credentials, got %d", resp.StatusCode)
+	}
+
+	put(t, h, "alice", "s3cret-pass", "/dav/personal/hello.txt", "hello warehouse")
+	if got := get(t, h, "alice", "s3cret-pass", "/dav/personal/hello.txt"); got != "hello warehouse" {
+		t.Fatalf("unexpected content: %q", got)
+	}
+
+	req := h.NewRequest(t, "PROPFIND", "/dav/personal/", nil)
+	req.SetBasicAuth("alice", "s3cret-pass")
+	req.Header.Set("Depth", "1")
+	resp, body := h.Do(t, req)
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("expected 207 for PROPFIND, got %d: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(string(body), "hello.txt") {
+		t.Fatalf("PROPFIND does not list hello.txt: %s", body)
+	}
+}
+
+func TestDeleteMovesToRecycleAndRecovers(t *testing.T) {
+	h := containertest.New(t)
+	h.CreateUser(t, "alice", "s3cret-pass")
+	token := h.Login(t, "alice", "s3cret-pass")
+
+	put(t, h, "alice", "s3cret-pass", "/dav/personal/notes.txt", "remember me")
+	req := h.NewRequest(t, http.MethodDelete, "/dav/personal/notes.txt", nil)
+	req.SetBasicAuth("alice", "s3cret-pass")
+	if resp, body := h.Do(t, req); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for DELETE, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body := h.DoJSON(t, http.MethodGet, "/api/v1/public/webdav/recycle/list", token, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for recycle list, got %d: %s", resp.StatusCode, body)
+	}
+	var list struct {
+		Items []struct {
+			Hash string `json:"hash"`
+			Name string `json:"name"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		t.Fatalf("failed to decode recycle list: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "notes.txt" {
+		t.Fatalf("unexpected recycle items: %s", body)
+	}
+
+	resp, body = h.DoJSON(t, http.MethodPost, "/api/v1/public/webdav/recycle/recover", token, map[string]string{
+		"hash": list.Items[0].Hash,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for recover, got %d: %s", resp.StatusCode, body)
+	}
+	if got := get(t, h, "alice", "s3cret-pass", "/dav/personal/notes.txt"); got != "remember me" {
+		t.Fatalf("unexpected recovered content: %q", got)
+	}
+}
+
+func TestShareLinkPublicDownload(t *testing.T) {
+	h := containertest.New(t)
+	h.CreateUser(t, "alice", "s3cret-pass")
+	token := h.Login(t, "alice", "s3cret-pass")
+
+	put(t, h, "alice", "s3cret-pass", "/dav/personal/report.txt", "quarterly numbers")
+	resp, body := h.DoJSON(t, http.MethodPost, "/api/v1/public/share/create", token, map[string]any{
+		"path": "/personal/report.txt",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for share create, got %d: %s", resp.StatusCode, body)
+	}
+	var created struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil || created.Token == "" {
+		t.Fatalf("unexpected share response: %s", body)
+	}
+
+	resp, _ = h.Do(t, h.NewRequest(t, http.MethodGet, "/api/v1/public/share/"+created.Token, nil))
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected redirect to named share URL, got %d", resp.StatusCode)
+	}
+	location := resp.Header.Get("Location")
+	if !strings.HasSuffix(location, "/report.txt") {
+		t.Fatalf("unexpected share location: %s", location)
+	}
+
+	resp, body = h.Do(t, h.NewRequest(t, http.MethodGet, "/api/v1/public/share/"+created.Token+"/report.txt", nil))
+	if resp.StatusCode != http.StatusOK || string(body) != "quarterly numbers" {
+		t.Fatalf("unexpected public download: %d %q", resp.StatusCode, body)
+	}
+
+	resp, _ = h.DoJSON(t, http.MethodPost, "/api/v1/public/share/revoke", token, map[string]string{
+		"token": created.Token,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for share revoke, got %d", resp.StatusCode)
+	}
+	resp, _ = h.Do(t, h.NewRequest(t, http.MethodGet, "/api/v1/public/share/"+created.Token+"/report.txt", nil))
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 after revoke, got %d", resp.StatusCode)
+	}
+
+	// 客户端错误按类型返回，不回显内部错误
+	for _, tc := range []struct {
+		path string
+		want int
+	}{
+		{"/personal/missing.txt", http.StatusNotFound},
+		{"/personal", http.StatusBadRequest},
+		{"/..", http.StatusBadRequest},
+	} {
+		resp, body = h.DoJSON(t, http.MethodPost, "/api/v1/public/share/create", token, map[string]any{"path": tc.path})
+		if resp.StatusCode != tc.want {
+			t.Fatalf("share create %s: expected %d, got %d: %s", tc.path, tc.want, resp.StatusCode, body)
+		}
+	}
+	resp, _ = h.DoJSON(t, http.MethodPost, "/api/v1/public/share/revoke", token, map[string]string{"token": created.Token})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for revoking a deleted share, got %d", resp.StatusCode)
+	}
+}
+
+func TestEmailCodeLoginThroughOutbox(t *testing.T) {
+	smtp := emailtest.NewServer(t)
+	h := containertest.New(t, func(cfg *config.Config) {
+		cfg.Email.Enabled = true
+		cfg.Email.SMTPHost = smtp.Host
+		cfg.Email.SMTPPort = smtp.Port
+		cfg.Email.From = "noreply@example.com"
+		cfg.Email.TemplateDir = "../../../resources/email"
+	})
+
+	// 第一次投递失败，验证码仍然先返回成功，稍后由后台任务重试
+	smtp.FailNext(1)
+	resp, body := h.DoJSON(t, http.MethodPost, "/api/v1/public/auth/email/code", "", map[string]string{
+		"email": "carol@example.com",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for code request, got %d: %s", resp.StatusCode, body)
+	}
+
+	// 等待后台任务完成第一次投递
+	items, _, err := h.Container.MailOutboxService.List(context.Background(), outbox.Filter{})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected one outbox message, got %d (%v)", len(items), err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for items[0].Attempts == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		items, _, _ = h.Container.MailOutboxService.List(context.Background(), outbox.Filter{})
+	}
+	if items[0].Status != outbox.StatusPending || items[0].Attempts != 1 {
+		t.Fatalf("expected a pending retry, got %+v", items[0])
+	}
+	// 跳过退避等待，直接重发
+	items[0].NextAttemptAt = time.Now()
+	if err := h.Container.OutboxRepository.Update(context.Background(), items[0]); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if sent, err := h.Container.MailOutboxService.ProcessDue(context.Background()); err != nil || sent != 1 {
+		t.Fatalf("retry: sent=%d err=%v", sent, err)
+	}
+
+	msgs := smtp.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("expected one delivered mail, got %d", len(msgs))
+	}
+	code := regexp.MustCompile(`class="code">(\d+)<`).FindStringSubmatch(msgs[0].Body())
+	if code == nil {
+		t.Fatalf("no code in mail: %s", msgs[0].Body())
+	}
+	resp, body = h.DoJSON(t, http.MethodPost, "/api/v1/public/auth/email/login", "", map[string]string{
+		"email": "carol@example.com",
+		"code":  code[1],
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for code login, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestAdminCannotModifyMorePrivilegedUser(t *testing.T) {
+	ctx := context.Background()
+	h := containertest.New(t)
+	root := h.CreateUser(t, "root", "root-pass-123")
+	manager := h.CreateUser(t, "manager", "manager-pass-123")
+	h.CreateUser(t, "dave", "dave-pass-123")
+	if err := h.Container.RBACService.Assign(ctx, nil, root, rbac.RoleSuperadmin); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	if err := h.Container.RBACService.Assign(ctx, nil, manager, rbac.RoleUserManager); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	token := h.Login(t, "manager", "manager-pass-123")
+
+	// user-manager 不能重置、修改或删除 superadmin
+	for path, payload := range map[string]map[string]string{
+		"/api/v1/public/admin/users/reset-password": {"username": "root", "password": "taken-over-123"},
+		"/api/v1/public/admin/users/update":         {"username": "root", "email": "attacker@example.com"},
+		"/api/v1/public/admin/users/delete":         {"username": "root"},
+	} {
+		if resp, body := h.DoJSON(t, http.MethodPost, path, token, payload); resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected 403 for %s, got %d: %s", path, resp.StatusCode, body)
+		}
+	}
+	h.Login(t, "root", "root-pass-123")
+
+	// 普通用户仍然可以管理
+	resp, body := h.DoJSON(t, http.MethodPost, "/api/v1/public/admin/users/reset-password", token, map[string]string{
+		"username": "dave",
+		"password": "new-dave-pass-123",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for reset of a regular user, got %d: %s", resp.StatusCode, body)
+	}
+	h.Login(t, "dave", "new-dave-pass-123")
+}
+
+// newSecurityAdmin 创建 superadmin root 与仅持有 users.security 的管理员 helpdesk，返回后者的令牌
+func newSecurityAdmin(t *testing.T) (*containertest.Harness, string) {
+	t.Helper()
+	ctx := context.Background()
+	h := containertest.New(t)
+	root := h.CreateUser(t, "root", "root-pass-123")
+	helpdesk := h.CreateUser(t, "helpdesk", "helpdesk-pass-123")
+	if err := h.Container.RBACService.Assign(ctx, nil, root, rbac.RoleSuperadmin); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	if _, err := h.Container.RBACService.SaveRole(ctx, nil, "helpdesk", "", []string{"users.read", "users.security"}); err != nil {
+		t.Fatalf("SaveRole: %v", err)
+	}
+	if err := h.Container.RBACService.Assign(ctx, nil, helpdesk, "helpdesk"); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	return h, h.Login(t, "helpdesk", "helpdesk-pass-123")
+}
+
+func TestSecurityAdminCannotDisableSuperadminMFA(t *testing.T) {
+	h, token := newSecurityAdmin(t)
+	if _, err := h.Container.MFAService.SetRequired(context.Background(), "root", true); err != nil {
+		t.Fatalf("SetRequired: %v", err)
+	}
+	resp, body := h.DoJSON(t, http.MethodPost, "/api/v1/public/admin/users/mfa", token, map[string]any{
+		"username": "root",
+		"required": false,
+	})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestSecurityAdminCannotRevokeSuperadminSessions(t *testing.T) {
+	h, token := newSecurityAdmin(t)
+	rootToken := h.Login(t, "root", "root-pass-123")
+	resp, body := h.DoJSON(t, http.MethodPost, "/api/v1/public/admin/users/sessions/revoke", token, map[string]string{
+		"username": "root",
+	})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", resp.StatusCode, body)
+	}
+	if resp, body := h.DoJSON(t, http.MethodGet, "/api/v1/public/webdav/sessions", rootToken, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("superadmin session should stay valid, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestSecurityAdminCannotUnlockSuperadmin(t *testing.T) {
+	h, token := newSecurityAdmin(t)
+	resp, body := h.DoJSON(t, http.MethodPost, "/api/v1/public/admin/users/unlock", token, map[string]string{
+		"username": "root",
+	})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestMergeRequiresSecondFactorOfSourceAccount(t *testing.T) {
+	ctx := context.Background()
+	h := containertest.New(t)
+	h.CreateUser(t, "mallory", "mallory-pass-123")
+	victim := h.CreateUser(t, "victim", "victim-pass-123")
+	token := h.Login(t, "mallory", "mallory-pass-123")
+	merge := func(proof map[string]string) (*http.Response, []byte) {
+		return h.DoJSON(t, http.MethodPost, "/api/v1/public/webdav/identities/merge", token, map[string]any{"proof": proof})
+	}
+
+	// 被要求启用但尚未登记两步验证：密码不能证明控制权
+	if _, err := h.Container.MFAService.SetRequired(ctx, "victim", true); err != nil {
+		t.Fatalf("SetRequired: %v", err)
+	}
+	password := map[string]string{"type": "password", "username": "victim", "password": "victim-pass-123"}
+	if resp, body := merge(password); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for unenrolled source account, got %d: %s", resp.StatusCode, body)
+	}
+
+	enrollment, err := h.Container.MFAService.Enroll(ctx, victim)
+	if err != nil {
+		t.Fatalf("Enroll: %v", err)
+	}
+	code, err := mfa.Code(enrollment.Secret, mfa.Counter(time.Now()))
+	if err != nil {
+		t.Fatalf("Code: %v", err)
+	}
+	if _, err := h.Container.MFAService.Confirm(ctx, victim, code); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+
+	// 只有密码或验证码错误都不能合并
+	if resp, body := merge(password); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a second factor, got %d: %s", resp.StatusCode, body)
+	}
+	password["code"] = "000000"
+	if code == "000000" {
+		password["code"] = "111111"
+	}
+	if resp, body := merge(password); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong second factor, got %d: %s", resp.StatusCode, body)
+	}
+	if _, err := h.Container.UserRepository.FindByUsername(ctx, "victim"); err != nil {
+		t.Fatalf("victim account must survive rejected merges: %v", err)
+	}
+}
+
+func TestMergeMovesSourceAccountIntoTarget(t *testing.T) {
+	ctx := context.Background()
+	h := containertest.New(t)
+	h.CreateUser(t, "alice", "alice-pass-123")
+	bob := h.CreateUser(t, "bob", "bob-pass-123")
+	carol := h.CreateUser(t, "carol", "carol-pass-123")
+	aliceToken := h.Login(t, "alice", "alice-pass-123")
+	bobToken := h.Login(t, "bob", "bob-pass-123")
+	carolToken := h.Login(t, "carol", "carol-pass-123")
+	decode := func(body []byte, v any) {
+		t.Helper()
+		if err := json.Unmarshal(body, v); err != nil {
+			t.Fatalf("failed to decode %s: %v", body, err)
+		}
+	}
+	mustJSON := func(method, path, token string, payload any, v any) {
+		t.Helper()
+		resp, body := h.DoJSON(t, method, path, token, payload)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s: expected 200, got %d: %s", method, path, resp.StatusCode, body)
+		}
+		if v != nil {
+			decode(body, v)
+		}
+	}
+
+	// bob：文件、分享链接、给 carol 的定向分享、地址簿分组与联系人
+	put(t, h, "bob", "bob-pass-123", "/dav/personal/notes.txt", "bob notes")
+	var link struct {
+		Token string `json:"token"`
+	}
+	mustJSON(http.MethodPost, "/api/v1/public/share/create", bobToken, map[string]any{"path": "/personal/notes.txt"}, &link)
+	mustJSON(http.MethodPost, "/api/v1/public/share/user/create", bobToken, map[string]any{
+		"path":          "/personal/notes.txt",
+		"targetAddress": carol.WalletAddress,
+		"permissions":   []string{"read"},
+	}, nil)
+	var bobGroup, aliceGroup struct {
+		ID string `json:"id"`
+	}
+	mustJSON(http.MethodPost, "/api/v1/public/webdav/address/groups/create", bobToken, map[string]string{"name": "friends"}, &bobGroup)
+	mustJSON(http.MethodPost, "/api/v1/public/webdav/address/contacts/create", bobToken, map[string]any{
+		"name":          "dave",
+		"walletAddress": "0x00000000000000000000000000000000000000da",
+		"groupId":       bobGroup.ID,
+	}, nil)
+	mustJSON(http.MethodPost, "/api/v1/public/webdav/address/groups/create", aliceToken, map[string]string{"name": "friends"}, &aliceGroup)
+
+	var merged struct {
+		MergedUsername string `json:"mergedUsername"`
+		Path           string `json:"path"`
+	}
+	mustJSON(http.MethodPost, "/api/v1/public/webdav/identities/merge", aliceToken, map[string]any{
+		"proof": map[string]string{"type": "password", "username": "bob", "password": "bob-pass-123"},
+	}, &merged)
+	if merged.MergedUsername != "bob" || merged.Path != "/merged/bob" {
+		t.Fatalf("unexpected merge response: %+v", merged)
+	}
+
+	// source 账户已删除
+	if _, err := h.Container.UserRepository.FindByUsername(ctx, "bob"); err == nil {
+		t.Fatalf("source account should be deleted")
+	}
+	if resp, _ := h.DoJSON(t, http.MethodPost, "/api/v1/public/auth/password/login", "", map[string]string{
+		"username": "bob",
+		"password": "bob-pass-123",
+	}); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for merged account login, got %d", resp.StatusCode)
+	}
+
+	// 文件复制到 target 的 /merged/bob 下
+	if got := get(t, h, "alice", "alice-pass-123", "/dav/merged/bob/personal/notes.txt"); got != "bob notes" {
+		t.Fatalf("unexpected merged file content: %q", got)
+	}
+
+	// 分享链接转给 target，路径随文件改写，公开链接继续可用
+	var shares struct {
+		Items []struct {
+			Token string `json:"token"`
+			Path  string `json:"path"`
+		} `json:"items"`
+	}
+	mustJSON(http.MethodGet, "/api/v1/public/share/list", aliceToken, nil, &shares)
+	if len(shares.Items) != 1 || shares.Items[0].Token != link.Token || shares.Items[0].Path != "/merged/bob/personal/notes.txt" {
+		t.Fatalf("unexpected shares after merge: %+v", shares.Items)
+	}
+	resp, body := h.Do(t, h.NewRequest(t, http.MethodGet, "/api/v1/public/share/"+link.Token+"/notes.txt", nil))
+	if resp.StatusCode != http.StatusOK || string(body) != "bob notes" {
+		t.Fatalf("share link should keep working after merge: %d %q", resp.StatusCode, body)
+	}
+
+	// 定向分享的拥有者变为 target
+	var directed struct {
+		Items []struct {
+			Path string `json:"path"`
+		} `json:"items"`
+	}
+	mustJSON(http.MethodGet, "/api/v1/public/share/user/list", aliceToken, nil, &directed)
+	if len(directed.Items) != 1 || directed.Items[0].Path != "/merged/bob/personal/notes.txt" {
+		t.Fatalf("unexpected directed shares after merge: %+v", directed.Items)
+	}
+	var received struct {
+		Items []struct {
+			OwnerName string `json:"ownerName"`
+		} `json:"items"`
+	}
+	mustJSON(http.MethodGet, "/api/v1/public/share/user/received", carolToken, nil, &received)
+	if len(received.Items) != 1 {
+		t.Fatalf("carol should still receive the directed share: %+v", received.Items)
+	}
+
+	// 地址簿：同名分组合并，联系人转到 target 的分组
+	var groups struct {
+		Items []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"items"`
+	}
+	mustJSON(http.MethodGet, "/api/v1/public/webdav/address/groups", aliceToken, nil, &groups)
+	if len(groups.Items) != 1 || groups.Items[0].ID != aliceGroup.ID {
+		t.Fatalf("unexpected groups after merge: %+v", groups.Items)
+	}
+	var contacts struct {
+		Items []struct {
+			Name    string `json:"name"`
+			GroupID string `json:"groupId"`
+		} `json:"items"`
+	}
+	mustJSON(http.MethodGet, "/api/v1/public/webdav/address/contacts", aliceToken, nil, &contacts)
+	movedContacts := make(map[string]string)
+	for _, c := range contacts.Items {
+		movedContacts[c.Name] = c.GroupID
+	}
+	// 创建定向分享时 carol 被自动加入 bob 的地址簿
+	if len(movedContacts) != 2 || movedContacts["dave"] != aliceGroup.ID || movedContacts["carol"] != "" {
+		t.Fatalf("unexpected contacts after merge: %+v", contacts.Items)
+	}
+
+	// 登录身份：bob 的钱包转给 target，密码身份随用户名删除
+	var identities struct {
+		Items []struct {
+			Type    string `json:"type"`
+			Subject string `json:"subject"`
+		} `json:"items"`
+	}
+	mustJSON(http.MethodGet, "/api/v1/public/webdav/identities", aliceToken, nil, &identities)
+	var hasBobWallet bool
+	for _, ident := range identities.Items {
+		if ident.Type == "wallet" && strings.EqualFold(ident.Subject, bob.WalletAddress) {
+			hasBobWallet = true
+		}
+		if ident.Type == "password" && ident.Subject == "bob" {
+			t.Fatalf("password identity of the source account should be removed")
+		}
+	}
+	if !hasBobWallet {
+		t.Fatalf("source wallet should be linked to target: %+v", identities.Items)
+	}
+	owner, err := h.Container.IdentityService.ResolveOwner(ctx, "wallet", bob.WalletAddress)
+	if err != nil || owner.Username != "alice" {
+		t.Fatalf("source wallet should resolve to target, got %v %v", owner, err)
+	}
+}
+
+func TestRequiredMFAEnrollsOnlyFromStrongSession(t *testing.T) {
+	ctx := context.Background()
+	h := containertest.New(t)
+	alice := h.CreateUser(t, "alice", "alice-pass-123")
+	if _, err := h.Container.MFAService.SetRequired(ctx, "alice", true); err != nil {
+		t.Fatalf("SetRequired: %v", err)
+	}
+
+	// 密码登录不签发可用于登记的挑战令牌
+	resp, body := h.DoJSON(t, http.MethodPost, "/api/v1/public/auth/password/login", "", map[string]string{
+		"username": "alice",
+		"password": "alice-pass-123",
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for password login, got %d: %s", resp.StatusCode, body)
+	}
+	var login struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &login); err != nil {
+		t.Fatalf("failed to decode login response: %v", err)
+	}
+	if login.Data["enrollRequired"] != true || login.Data["mfaToken"] != nil || login.Data["token"] != nil {
+		t.Fatalf("unexpected login response: %s", body)
+	}
+
+	enroll := func(method string) int {
+		pair, err := h.Container.Web3Auth.IssueTokens(ctx, alice, alice.WalletAddress, "wallet", method, session.Client{})
+		if err != nil {
+			t.Fatalf("IssueTokens: %v", err)
+		}
+		resp, _ := h.DoJSON(t, http.MethodPost, "/api/v1/public/webdav/user/mfa/enroll", pair.Access.Value, map[string]string{})
+		return resp.StatusCode
+	}
+	if status := enroll(session.MethodPassword); status != http.StatusForbidden {
+		t.Fatalf("expected 403 for enrolment from a password session, got %d", status)
+	}
+	if status := enroll(session.MethodWallet); status != http.StatusOK {
+		t.Fatalf("expected 200 for enrolment from a wallet session, got %d", status)
+	}
+}
+
+func put(t *testing.T, h *containertest.Harness, username, password, path, content string) {
+	t.Helper()
+
+	req := h.NewRequest(t, http.MethodPut, path, strings.NewReader(content))
+	req.SetBasicAuth(username, password)
+	if resp, body := h.Do(t, req); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 for PUT %s, got %d: %s", path, resp.StatusCode, body)
+	}
+}
+
+func get(t *testing.T, h *containertest.Harness, username, password, path string) string {
+	t.Helper()
+
+	req := h.NewRequest(t, http.MethodGet, path, nil)
+	req.SetBasicAuth(username, password)
+	resp, body := h.Do(t, req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for GET %s, got %d: %s", path, resp.StatusCode, body)
+	}
+	return string(body)
+}
+
+func TestReadinessHidesCheckErrors(t *testing.T) {
+	var dir string
+	h := containertest.New(t, func(cfg *config.Config) {
+		// 可用空间不可能满足，storage 检查必然失败
+		cfg.Health.MinFreeBytes = 1 << 62
+		dir = cfg.WebDAV.Directory
+	})
+	resp, body := h.DoJSON(t, http.MethodGet, "/api/v1/public/health/ready", "", nil)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", resp.StatusCode, body)
+	}
+	if !strings.Contains(string(body), `"fail"`) {
+		t.Fatalf("failed check not reported: %s", body)
+	}
+	for _, leak := range []string{dir, "free space", `"error"`, `"detail"`} {
+		if strings.Contains(string(body), leak) {
+			t.Fatalf("readiness response exposes %q: %s", leak, body)
+		}
+	}
+}
@@ -14,7 +14,9 @@ type Server struct {
 	config     *config.Config
 	router     *Router
 	httpServer *http.Server
-	logger     *zap.Logger
+	// metricsServer 独立监听地址上的指标服务（metrics.address 非空时）
+	metricsServer *http.Server
+	logger        *zap.Logger
 }
 
 // NewServer 创建 HTTP 服务器
@@ -42,6 +44,8 @@ func (s *Server) Start() error {
 		IdleTimeout:  s.config.Server.IdleTimeout,
 	}
 
+	s.startMetrics()
+
 	// 启动服务器
 	s.logger.Info("starting http server",
 		zap.String("address", addr),
@@ -54,6 +58,25 @@ func (s *Server) Start() error {
 	return s.start()
 }
 
+// startMetrics 在独立地址上启动指标服务，失败只记录日志
+func (s *Server) startMetrics() {
+	handler := s.router.MetricsHandler()
+	if handler == nil {
+		return
+	}
+	s.metricsServer = &http.Server{
+		Addr:              s.config.Metrics.Address,
+		Handler:           handler,
+		ReadHeaderTimeout: s.config.Server.ReadTimeout,
+	}
+	s.logger.Info("starting metrics server", zap.String("address", s.config.Metrics.Address))
+	go func() {
+		if err := s.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("metrics server failed", zap.Error(err))
+		}
+	}()
+}
+
 // start 启动 HTTP 服务器
 func (s *Server) start() error {
 	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -85,6 +108,12 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	shutdownCtx, cancel := context.WithTimeout(ctx, s.config.Server.ShutdownTimeout)
 	defer cancel()
 
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(shutdownCtx); err != nil {
+			s.logger.Warn("failed to shutdown metrics server", zap.Error(err))
+		}
+	}
+
 	// 关闭服务器
 	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
 		return fmt.Errorf("failed to shutdown server: %w", err)
@@ -135,11 +135,12 @@ build_artifacts() {
   (cd "${web_dir}" && npm run build)
 
   echo "Building backend binary..."
-  local version build_time git_commit ldflags
+  local version build_time git_commit buildinfo ldflags
   version="$(git -C "${ROOT_DIR}" describe --tags --always --dirty)"
   build_time="$(date -u '+%Y-%m-%d_%H:%M:%S')"
   git_commit="$(git -C "${ROOT_DIR}" rev-parse --short HEAD)"
-  ldflags="-X main.version=${version} -X main.buildTime=${build_time} -X main.gitCommit=${git_commit}"
+  buildinfo="github.com/yeying-community/warehouse/internal/infrastructure/buildinfo"
+  ldflags="-X ${buildinfo}.Version=${version} -X ${buildinfo}.BuildTime=${build_time} -X ${buildinfo}.Commit=${git_commit}"
   mkdir -p "${ROOT_DIR}/build"
   (cd "${ROOT_DIR}" && go build -ldflags "${ldflags}" -o build/warehouse cmd/server/main.go)
 